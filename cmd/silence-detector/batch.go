@@ -0,0 +1,620 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// checkTimeout bounds the ffmpeg capability check run before any input is analysed.
+const checkTimeout = time.Minute
+
+// Per-input statuses reported in batch output.
+const (
+	statusOK            = "ok"
+	statusNoAudioStream = "no_audio_stream"
+	statusCheckFailed   = "check_failed"
+	statusPartial       = "partial"
+	statusError         = "error"
+)
+
+// analysisConfig holds the settings shared by every input of a run.
+type analysisConfig struct {
+	fetchers   map[detector.InputKind]remoteFetcher
+	download   detector.DownloadOptions
+	noDownload bool
+	// forceDownload downloads HLS and DASH manifests too, which are otherwise read by ffmpeg from their URL.
+	forceDownload bool
+	detection     detector.DetectionOptions
+	reportMin     float64
+	reportMax     float64
+	padStart      float64
+	padEnd        float64
+	trailing      bool
+	minCoverage   float64
+	fullTol       float64
+	maxLeading    float64
+	maxTrailing   float64
+	// timeout is the --timeout limit on the whole run, used to explain failures caused by its deadline.
+	timeout time.Duration
+
+	// failOnSilence, failOnFullySilent and failIfNotFullySilent are the verdict gates selected on the command line.
+	failOnSilence        bool
+	failOnFullySilent    bool
+	failIfNotFullySilent bool
+
+	// sweep lists the --silence-noise thresholds to compare when more than one was given.
+	sweep []float64
+	// allStreams analyses every audio stream of the input for --all-audio-streams.
+	allStreams bool
+	// black enables black frame detection after silence detection; nil disables it.
+	black *detector.BlackDetectionOptions
+	// freeze enables frozen video detection after silence detection; nil disables it.
+	freeze *detector.FreezeDetectionOptions
+	// energyWindow enables the --energy-timeline pass after silence detection, with windows of this many seconds;
+	// zero disables it.
+	energyWindow float64
+	// quality enables the --quality pass after silence detection. maxClipped and maxDCOffset are its
+	// --max-clipped-samples and --max-dc-offset limits; a negative maxClipped and a zero maxDCOffset disable them.
+	quality     bool
+	maxClipped  int64
+	maxDCOffset float64
+}
+
+// streams reports whether ffmpeg reads a network input from its URL rather than from a downloaded copy.
+func (cfg analysisConfig) streams(input detector.Input) bool {
+	return input.Kind == detector.InputStream || cfg.noDownload || (input.IsManifest() && !cfg.forceDownload)
+}
+
+// fileResult is the outcome of analysing a single input.
+type fileResult struct {
+	input         string
+	result        detector.DetectionResult
+	noAudioStream bool
+	// violations are the verdict gates and silence limits the input failed.
+	violations []violation
+	err        error
+	// partial is the ffmpeg failure behind a result that --allow-partial kept.
+	partial error
+	// rawOutput is the ffmpeg output captured for --raw-output-file, kept even when a later check fails.
+	rawOutput string
+	// sweep holds the result of every threshold of a --silence-noise sweep; result is the first threshold's.
+	sweep map[float64]detector.DetectionResult
+	// streams holds the outcome for every audio stream with --all-audio-streams, in stream order; result is that of
+	// the first stream that was analysed.
+	streams []streamResult
+	// black and freeze hold the intervals found with --detect-black and --detect-freeze, and noVideoStream is set
+	// when the input had no video to analyse.
+	black         []detector.Interval
+	freeze        []detector.Interval
+	noVideoStream bool
+	// energy holds the --energy-timeline samples.
+	energy []detector.EnergySample
+	// quality holds the --quality report.
+	quality *detector.QualityReport
+}
+
+// violation is a failed verdict gate or silence limit and the exit code it maps to.
+type violation struct {
+	code    int
+	message string
+}
+
+// status classifies the outcome for batch reports.
+func (r fileResult) status() string {
+	switch {
+	case r.err != nil:
+		return statusError
+	case r.noAudioStream:
+		return statusNoAudioStream
+	case r.partial != nil:
+		return statusPartial
+	case len(r.violations) > 0:
+		return statusCheckFailed
+	default:
+		return statusOK
+	}
+}
+
+// exitCode is the process exit code for the outcome when it is the only input.
+func (r fileResult) exitCode() int {
+	switch r.status() {
+	case statusError:
+		var timeoutErr timeoutError
+		if errors.As(r.err, &timeoutErr) {
+			return exitCodeTimeout
+		}
+		var unreachableErr streamUnreachableError
+		if errors.As(r.err, &unreachableErr) {
+			return exitCodeStreamUnreachable
+		}
+		var detErr detectionError
+		if errors.As(r.err, &detErr) && !errors.Is(r.err, detector.ErrNativeUnsupported) {
+			return exitCodeFFmpeg
+		}
+		return exitCodeInput
+	case statusNoAudioStream:
+		return exitCodeNoAudioStream
+	case statusCheckFailed, statusPartial:
+		code := exitCodeOK
+		if r.partial != nil {
+			code = exitCodePartial
+		}
+		for _, v := range r.violations {
+			code = worseExitCode(code, v.code)
+		}
+		return code
+	default:
+		return exitCodeOK
+	}
+}
+
+// messages returns the descriptions of the input's violations.
+func (r fileResult) messages() []string {
+	var messages []string
+	for _, v := range r.violations {
+		messages = append(messages, v.message)
+	}
+	return messages
+}
+
+// detectionError marks failures reported by the detector, which are explained with reportDetectionError.
+type detectionError struct {
+	err error
+}
+
+func (e detectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e detectionError) Unwrap() error {
+	return e.err
+}
+
+// timeoutError reports an input that failed because the --timeout deadline passed.
+type timeoutError struct {
+	input   string
+	timeout time.Duration
+	err     error
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("analysis of %q timed out after %s", displayInputPath(e.input), e.timeout)
+}
+
+func (e timeoutError) Unwrap() error {
+	return e.err
+}
+
+// timedOut reports whether ctx ended because the --timeout deadline passed.
+func timedOut(ctx context.Context, cfg analysisConfig) bool {
+	return cfg.timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// reportFailure prints why an input could not be analysed.
+func reportFailure(err error) {
+	var timeoutErr timeoutError
+	if errors.As(err, &timeoutErr) {
+		fmt.Fprintln(os.Stderr, timeoutErr)
+		return
+	}
+
+	var unreachableErr streamUnreachableError
+	if errors.As(err, &unreachableErr) {
+		fmt.Fprintln(os.Stderr, unreachableErr)
+		return
+	}
+
+	var detErr detectionError
+	if errors.As(err, &detErr) {
+		reportDetectionError(detErr.err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// resolveFetcher resolves a network input with its fetcher from cfg into the HTTP(S) URL to read, and the request
+// headers to send with it. Inputs without a fetcher are returned as they are.
+func resolveFetcher(ctx context.Context, cfg analysisConfig, input detector.Input) (detector.Input, http.Header, error) {
+	fetcher, ok := cfg.fetchers[input.Kind]
+	if !ok {
+		return input, nil, nil
+	}
+	// The segments of a manifest in cloud storage would need signed URLs of their own.
+	if input.Kind != detector.InputRemote && input.IsManifest() && !cfg.forceDownload {
+		return input, nil, fmt.Errorf("cannot access %q: HLS and DASH manifests must be served over HTTP(S)", input.Raw)
+	}
+	location, header, err := fetcher.resolve(ctx, input)
+	if err != nil {
+		return input, nil, fmt.Errorf("cannot access %q: %w", input.Raw, err)
+	}
+	return detector.Input{Raw: input.Raw, Kind: detector.InputRemote, Location: location}, header, nil
+}
+
+// analyzeInput resolves, fetches and analyses a single input.
+func analyzeInput(ctx context.Context, det *detector.Detector, cfg analysisConfig, raw string) (res fileResult) {
+	res = fileResult{input: raw}
+	defer func() {
+		if res.err != nil && timedOut(ctx, cfg) {
+			res.err = timeoutError{input: raw, timeout: cfg.timeout, err: res.err}
+		}
+	}()
+
+	originalInput := strings.TrimSpace(raw)
+	readStdin := originalInput == "-"
+	input, err := detector.ResolveInput(originalInput)
+	if err != nil {
+		res.err = fmt.Errorf("invalid input %q: %v", originalInput, err)
+		return res
+	}
+
+	input, requestHeaders, err := resolveFetcher(ctx, cfg, input)
+	if err != nil {
+		res.err = err
+		return res
+	}
+
+	download := cfg.download
+	download.Headers = requestHeaders
+	download.AllowManifests = cfg.forceDownload
+
+	// Manifests are read by ffmpeg, which resolves their segment URIs against the manifest URL.
+	streamRemote := input.IsRemote() && cfg.streams(input)
+	resolvedInput, cleanup, err := prepareInput(ctx, input, streamRemote, download)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if errors.Is(err, detector.ErrManifest) {
+		// The server identified a manifest that has no manifest extension by its Content-Type.
+		streamRemote = true
+		resolvedInput, err = input.Location, nil
+	}
+	if err != nil {
+		res.err = err
+		return res
+	}
+
+	options := cfg.detection
+	options.InputHeaders = requestHeaders
+	if readStdin {
+		options.Stdin = os.Stdin
+	}
+
+	detect := func(path string) (detector.DetectionResult, error) {
+		if cfg.allStreams {
+			return detectAllStreams(ctx, det, path, options, &res)
+		}
+		if len(cfg.sweep) == 0 {
+			return det.DetectSilence(ctx, path, options)
+		}
+		sweep := make([]detector.DetectionOptions, len(cfg.sweep))
+		for i, level := range cfg.sweep {
+			sweep[i] = options
+			sweep[i].NoiseLevel = level
+		}
+		results, err := det.DetectSilenceSweep(ctx, path, sweep)
+		if err != nil {
+			return detector.DetectionResult{}, err
+		}
+		res.sweep = results
+		return results[cfg.sweep[0]], nil
+	}
+
+	analysedPath := resolvedInput
+	result, err := detect(resolvedInput)
+	if streamRemote && cfg.noDownload && !input.IsManifest() && errors.Is(err, detector.ErrProtocolUnsupported) {
+		notef("ffmpeg cannot read %q directly (%v); downloading it instead\n", originalInput, err)
+
+		downloadedPath, downloadCleanup, downloadErr := prepareInput(ctx, input, false, download)
+		if downloadCleanup != nil {
+			defer downloadCleanup()
+		}
+		if downloadErr != nil {
+			res.err = downloadErr
+			return res
+		}
+
+		analysedPath = downloadedPath
+		result, err = detect(downloadedPath)
+	}
+	if errors.Is(err, detector.ErrNoAudioStream) {
+		res.noAudioStream = true
+		return res
+	}
+	if errors.Is(err, detector.ErrPartialResult) {
+		notef("ffmpeg failed partway through %q; only its first %.3fs were analysed\n", displayInputPath(originalInput), result.InputDuration)
+		res.partial, err = err, nil
+	}
+	if err != nil {
+		res.err = detectionError{err: err}
+		return res
+	}
+	res.rawOutput = result.RawOutput
+	if cfg.detection.MeasureLoudness && result.Loudness == nil {
+		notef("ffmpeg reported no loudness for %q; its build may lack the ebur128 filter\n", displayInputPath(originalInput))
+	}
+	if result.ProbeRetried {
+		notef("ffmpeg could not find the codec parameters of %q; analysed it again with larger -analyzeduration and -probesize\n", displayInputPath(originalInput))
+	}
+
+	if cfg.reportMin > 0 || cfg.reportMax > 0 {
+		result = result.FilterIntervals(cfg.reportMin, cfg.reportMax)
+		for level, swept := range res.sweep {
+			res.sweep[level] = swept.FilterIntervals(cfg.reportMin, cfg.reportMax)
+		}
+		for i := range res.streams {
+			res.streams[i].result = res.streams[i].result.FilterIntervals(cfg.reportMin, cfg.reportMax)
+		}
+	}
+	if cfg.padStart != 0 || cfg.padEnd != 0 {
+		result = padResult(result, cfg.padStart, cfg.padEnd)
+		for level, swept := range res.sweep {
+			res.sweep[level] = padResult(swept, cfg.padStart, cfg.padEnd)
+		}
+		for i := range res.streams {
+			res.streams[i].result = padResult(res.streams[i].result, cfg.padStart, cfg.padEnd)
+		}
+	}
+
+	// A pipe cannot be probed, so the duration of stdin input is only known if ffmpeg reported progress.
+	var durationHint string
+	if readStdin {
+		durationHint = " (stdin input cannot be probed for its duration)"
+	}
+
+	if cfg.trailing && result.InputDuration <= 0 {
+		res.err = fmt.Errorf("ffmpeg output did not include duration information%s; cannot determine trailing silence", durationHint)
+		return res
+	}
+
+	res.result = result
+
+	if cfg.black != nil {
+		blackOptions := *cfg.black
+		blackOptions.InputHeaders = requestHeaders
+		black, err := det.DetectBlack(ctx, analysedPath, blackOptions)
+		switch {
+		case errors.Is(err, detector.ErrNoVideoStream):
+			res.noVideoStream = true
+		case err != nil:
+			res.err = detectionError{err: err}
+			return res
+		default:
+			res.black = black
+		}
+	}
+
+	if cfg.freeze != nil && !res.noVideoStream {
+		freezeOptions := *cfg.freeze
+		freezeOptions.InputHeaders = requestHeaders
+		freeze, err := det.DetectFreeze(ctx, analysedPath, freezeOptions)
+		switch {
+		case errors.Is(err, detector.ErrNoVideoStream):
+			res.noVideoStream = true
+		case err != nil:
+			res.err = detectionError{err: err}
+			return res
+		default:
+			res.freeze = freeze
+		}
+	}
+
+	if cfg.energyWindow > 0 {
+		samples, _, err := det.EnergyTimeline(ctx, analysedPath, cfg.energyWindow)
+		if err != nil {
+			res.err = detectionError{err: err}
+			return res
+		}
+		res.energy = samples
+	}
+
+	if cfg.quality {
+		quality, err := det.AnalyzeQuality(ctx, analysedPath)
+		if err != nil {
+			res.err = detectionError{err: err}
+			return res
+		}
+		res.quality = &quality
+	}
+
+	if cfg.failOnSilence && len(result.Intervals) > 0 {
+		res.violations = append(res.violations, violation{exitCodeSilenceDetected, fmt.Sprintf("%d silence interval(s) detected", len(result.Intervals))})
+	}
+	silentMessage, verdict := "entire input is silent", "entirely silent"
+	if cfg.minCoverage > 0 {
+		silentMessage, verdict = "input is effectively silent", "effectively silent"
+	}
+	silent, determinate := fullSilenceStatus(result, cfg.minCoverage, cfg.fullTol)
+	if cfg.failOnFullySilent && silent && determinate {
+		res.violations = append(res.violations, violation{exitCodeFullSilence, silentMessage})
+	}
+	if cfg.failIfNotFullySilent && !silent {
+		message := "input is not " + verdict
+		if !determinate {
+			message = fmt.Sprintf("cannot tell whether the input is %s: %s", verdict, fullSilenceUnknownReason(result))
+		}
+		res.violations = append(res.violations, violation{exitCodeFullSilence, message})
+	}
+	if leading := result.LeadingSilence(silenceTolerance); cfg.maxLeading > 0 && leading > cfg.maxLeading {
+		res.violations = append(res.violations, violation{exitCodeLimitExceeded, fmt.Sprintf("leading silence %.3fs exceeds maximum of %.3fs", leading, cfg.maxLeading)})
+	}
+	if trailing := result.TrailingSilence(silenceTolerance); cfg.maxTrailing > 0 && trailing > cfg.maxTrailing {
+		res.violations = append(res.violations, violation{exitCodeLimitExceeded, fmt.Sprintf("trailing silence %.3fs exceeds maximum of %.3fs", trailing, cfg.maxTrailing)})
+	}
+	if res.quality != nil && cfg.maxClipped >= 0 && res.quality.ClippedSamples > cfg.maxClipped {
+		res.violations = append(res.violations, violation{exitCodeLimitExceeded, fmt.Sprintf("%d clipped sample(s) exceed maximum of %d", res.quality.ClippedSamples, cfg.maxClipped)})
+	}
+	if res.quality != nil && cfg.maxDCOffset > 0 && res.quality.DCOffset > cfg.maxDCOffset {
+		res.violations = append(res.violations, violation{exitCodeLimitExceeded, fmt.Sprintf("DC offset %.6f exceeds maximum of %.6f", res.quality.DCOffset, cfg.maxDCOffset)})
+	}
+
+	return res
+}
+
+// analyzeAll analyses inputs with up to workers concurrent ffmpeg runs and returns the results in input order. When
+// onResult is non-nil it is also called with each result and its input index as soon as it is available, in
+// completion order.
+//
+// Once ctx is cancelled no further inputs are started, in-flight runs are killed through their contexts, and inputs
+// that were never started are reported as failed.
+func analyzeAll(ctx context.Context, det *detector.Detector, cfg analysisConfig, inputs []string, workers int, onResult func(int, fileResult)) []fileResult {
+	results := make([]fileResult, len(inputs))
+	jobs := make(chan int)
+
+	finish := func(i int, res fileResult) {
+		results[i] = res
+		if onResult != nil {
+			onResult(i, res)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for range min(workers, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					finish(i, notAnalysed(ctx, cfg, inputs[i]))
+					continue
+				}
+				finish(i, analyzeInput(ctx, det, cfg, inputs[i]))
+			}
+		}()
+	}
+
+	scheduled := 0
+schedule:
+	for ; scheduled < len(inputs); scheduled++ {
+		select {
+		case jobs <- scheduled:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := scheduled; i < len(inputs); i++ {
+		finish(i, notAnalysed(ctx, cfg, inputs[i]))
+	}
+
+	return results
+}
+
+// notAnalysed is the result for an input skipped because ctx was cancelled or timed out.
+func notAnalysed(ctx context.Context, cfg analysisConfig, input string) fileResult {
+	err := fmt.Errorf("input %q was not analysed: %w", input, ctx.Err())
+	if timedOut(ctx, cfg) {
+		err = timeoutError{input: input, timeout: cfg.timeout, err: err}
+	}
+	return fileResult{input: input, err: err}
+}
+
+// batchReport is the JSON representation of one input in a multi-input run.
+type batchReport struct {
+	// SchemaVersion shadows that of the embedded report, so that records of failed inputs carry it too.
+	SchemaVersion int      `json:"schema_version"`
+	Input         string   `json:"input"`
+	Status        string   `json:"status"`
+	Error         string   `json:"error,omitempty"`
+	Violations    []string `json:"violations,omitempty"`
+	*detector.Report
+}
+
+// newBatchReport builds the JSON record for one input of a multi-input run.
+func newBatchReport(res fileResult, opts reportOptions) batchReport {
+	opts.inputPath = res.input
+
+	report := batchReport{
+		SchemaVersion: detector.ReportSchemaVersion,
+		Input:         displayInputPath(res.input),
+		Status:        res.status(),
+		Violations:    res.messages(),
+	}
+	if res.err != nil {
+		report.Error = res.err.Error()
+	} else if !res.noAudioStream {
+		report.Report = newFileReport(res, opts)
+	}
+	return report
+}
+
+// reportBatch renders the results of a multi-input run in input order to w and returns the process exit code: the
+// most severe of the inputs' exit codes. NDJSON records are streamed by analyzeAll, so only errors are printed for that
+// format.
+func reportBatch(w io.Writer, results []fileResult, format outputFormat, opts reportOptions) int {
+	exitCode := exitCodeOK
+	reports := make([]batchReport, 0, len(results))
+
+	for i, res := range results {
+		if res.err != nil {
+			fmt.Fprintf(os.Stderr, "%s:\n", displayInputPath(res.input))
+			reportFailure(res.err)
+		}
+		for _, message := range res.messages() {
+			notef("%s: %s\n", displayInputPath(res.input), message)
+		}
+
+		exitCode = worseExitCode(exitCode, res.exitCode())
+
+		switch format {
+		case outputFormatJSON:
+			reports = append(reports, newBatchReport(res, opts))
+		case outputFormatNDJSON:
+		default:
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			emitBatchText(w, res, opts)
+		}
+	}
+
+	if format == outputFormatJSON {
+		emitReport(newReportEncoder(w, format, opts.legacyJSON), reports)
+	}
+	return exitCode
+}
+
+// emitBatchText writes the text section for one input of a multi-input run, ending with its status.
+func emitBatchText(w io.Writer, res fileResult, opts reportOptions) {
+	opts.inputPath = res.input
+
+	switch {
+	case res.err != nil:
+		fmt.Fprintf(w, "Silence detection for %s\n", displayInputPath(res.input))
+		fmt.Fprintf(w, "Status: %s: %v\n", res.status(), res.err)
+	case res.noAudioStream:
+		fmt.Fprintf(w, "Silence detection for %s\n", displayInputPath(res.input))
+		fmt.Fprintf(w, "Status: %s\n", res.status())
+	default:
+		emitFileText(w, res, opts)
+		fmt.Fprintf(w, "Status: %s\n", res.status())
+	}
+}
+
+// padResult returns a copy of result whose intervals, including those of every channel, are padded by
+// detector.PadIntervals and clamped to the end of the analysed span when the input duration is known.
+func padResult(result detector.DetectionResult, startPad, endPad float64) detector.DetectionResult {
+	var end float64
+	if result.InputDuration > 0 {
+		end = result.WindowStart + result.InputDuration
+	}
+	padded := result
+	padded.Intervals = detector.PadIntervals(result.Intervals, startPad, endPad, end)
+	if result.ChannelIntervals != nil {
+		padded.ChannelIntervals = make(map[int][]detector.SilenceInterval, len(result.ChannelIntervals))
+		for channel, intervals := range result.ChannelIntervals {
+			padded.ChannelIntervals[channel] = detector.PadIntervals(intervals, startPad, endPad, end)
+		}
+	}
+	return padded
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestAnalyzeInputPassesManifestsThrough(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/live" {
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		}
+		fmt.Fprint(w, "#EXTM3U\n")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		forceDownload bool
+		wantRequests  int32
+		wantURL       bool
+	}{
+		{name: "HLS playlist", url: server.URL + "/live/index.m3u8?token=a%2Fb", wantURL: true},
+		{name: "DASH manifest", url: server.URL + "/vod/manifest.mpd", wantURL: true},
+		{name: "manifest content type", url: server.URL + "/live", wantRequests: 1, wantURL: true},
+		{name: "forced download", url: server.URL + "/live/index.m3u8", forceDownload: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			var gotArgs []string
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				gotArgs = args
+				return []byte("size=N/A time=00:00:10.00 bitrate=N/A\n"), nil
+			}
+			det := detector.NewDetector(detector.WithCommandRunner(runner), detector.WithFFprobePath(""))
+			cfg := analysisConfig{
+				fetchers:      map[detector.InputKind]remoteFetcher{detector.InputRemote: httpFetcher{}},
+				forceDownload: tt.forceDownload,
+				detection:     detector.DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5},
+			}
+
+			res := analyzeInput(context.Background(), det, cfg, tt.url)
+			if res.err != nil {
+				t.Fatalf("analyzeInput returned error: %v", res.err)
+			}
+			if n := requests.Load(); n != tt.wantRequests {
+				t.Fatalf("expected %d request(s), got %d", tt.wantRequests, n)
+			}
+			if got := slices.Contains(gotArgs, tt.url); got != tt.wantURL {
+				t.Fatalf("expected the URL to reach ffmpeg unchanged: %v, got args %q", tt.wantURL, gotArgs)
+			}
+		})
+	}
+
+	cfg := analysisConfig{fetchers: map[detector.InputKind]remoteFetcher{detector.InputS3: s3Fetcher{}}}
+	if res := analyzeInput(context.Background(), detector.NewDetector(), cfg, "s3://bucket/live/index.m3u8"); res.err == nil {
+		t.Fatal("expected manifests in cloud storage to be rejected")
+	}
+}
+
+// inputArg returns the base name of the input file among ffmpeg's arguments.
+func inputArg(args []string) string {
+	if i := slices.Index(args, "-i"); i >= 0 && i+1 < len(args) {
+		return filepath.Base(args[i+1])
+	}
+	return ""
+}
+
+func TestAnalyzeAll(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"a.wav", "silent.wav", "missing.wav", "b.wav", "c.wav", "d.wav"}
+	inputs := make([]string, len(names))
+	for i, name := range names {
+		inputs[i] = filepath.Join(dir, name)
+		if name == "missing.wav" {
+			continue
+		}
+		if err := os.WriteFile(inputs[i], nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantStatus := []string{statusOK, statusCheckFailed, statusError, statusOK, statusOK, statusOK}
+
+	for _, workers := range []int{1, 3, 8} {
+		t.Run(fmt.Sprintf("parallel %d", workers), func(t *testing.T) {
+			var mu sync.Mutex
+			var analysed []string
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				input := inputArg(args)
+				mu.Lock()
+				analysed = append(analysed, input)
+				mu.Unlock()
+				// Earlier inputs finish last, so that completion order differs from input order.
+				time.Sleep(time.Duration(len(names)-slices.Index(names, input)) * time.Millisecond)
+				if input == "silent.wav" {
+					return []byte(silentOutput), nil
+				}
+				return []byte("size=N/A time=00:00:10.00 bitrate=N/A\n"), nil
+			}
+			det := detector.NewDetector(detector.WithCommandRunner(runner), detector.WithFFprobePath(""))
+			cfg := analysisConfig{
+				detection:     detector.DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5},
+				failOnSilence: true,
+			}
+
+			reported := make([]int, len(inputs))
+			results := analyzeAll(context.Background(), det, cfg, inputs, workers, func(i int, res fileResult) {
+				mu.Lock()
+				defer mu.Unlock()
+				reported[i]++
+				if res.input != inputs[i] {
+					t.Errorf("result for %s reported under index %d", res.input, i)
+				}
+			})
+
+			for i, res := range results {
+				if res.input != inputs[i] {
+					t.Errorf("result %d is for %s, want %s", i, res.input, inputs[i])
+				}
+				if got := res.status(); got != wantStatus[i] {
+					t.Errorf("%s: got status %s, want %s (error %v)", names[i], got, wantStatus[i], res.err)
+				}
+				if reported[i] != 1 {
+					t.Errorf("%s: reported %d times, want once", names[i], reported[i])
+				}
+			}
+			if len(analysed) != len(names)-1 || slices.Contains(analysed, "missing.wav") {
+				t.Errorf("expected every readable input to be analysed after the failure, got %q", analysed)
+			}
+			if code := reportBatch(io.Discard, results, outputFormatJSON, reportOptions{}); code != exitCodeInput {
+				t.Errorf("got exit code %d, want %d", code, exitCodeInput)
+			}
+		})
+	}
+}
+
+func TestAnalyzeAllStopsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	inputs := make([]string, 5)
+	for i := range inputs {
+		inputs[i] = filepath.Join(dir, fmt.Sprintf("%d.wav", i))
+		if err := os.WriteFile(inputs[i], nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var runs atomic.Int32
+	runner := func(runCtx context.Context, name string, args ...string) ([]byte, error) {
+		runs.Add(1)
+		cancel()
+		<-runCtx.Done()
+		return nil, runCtx.Err()
+	}
+	det := detector.NewDetector(detector.WithCommandRunner(runner), detector.WithFFprobePath(""))
+	cfg := analysisConfig{detection: detector.DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}}
+
+	results := analyzeAll(ctx, det, cfg, inputs, 1, nil)
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected no input to start after cancellation, got %d run(s)", n)
+	}
+	if results[0].err == nil {
+		t.Error("expected the interrupted input to fail")
+	}
+	for i, res := range results[1:] {
+		if !errors.Is(res.err, context.Canceled) || !strings.Contains(res.err.Error(), "was not analysed") {
+			t.Errorf("%s: expected a not-analysed error, got %v", inputs[i+1], res.err)
+		}
+		if res.input != inputs[i+1] {
+			t.Errorf("result %d is for %s, want %s", i+1, res.input, inputs[i+1])
+		}
+	}
+}
+
+func TestPadResultClampsToAnalysedSpan(t *testing.T) {
+	result := detector.DetectionResult{
+		WindowStart:      5,
+		InputDuration:    10,
+		Intervals:        []detector.SilenceInterval{{Start: 5, End: 8, Duration: 3}, {Start: 13, End: 15, Duration: 2}},
+		ChannelIntervals: map[int][]detector.SilenceInterval{1: {{Start: 6, End: 6.5, Duration: 0.5}}},
+	}
+
+	padded := padResult(result, -1, -1)
+	want := []detector.SilenceInterval{{Start: 4, End: 9, Duration: 5}, {Start: 12, End: 15, Duration: 3}}
+	if !slices.Equal(padded.Intervals, want) {
+		t.Errorf("unexpected padded intervals %v, want %v", padded.Intervals, want)
+	}
+	if shrunk := padResult(result, 0.5, 0.5); len(shrunk.ChannelIntervals[1]) != 0 {
+		t.Errorf("expected the channel interval to be dropped, got %v", shrunk.ChannelIntervals[1])
+	}
+	if result.Intervals[0].Start != 5 {
+		t.Errorf("padResult modified its input: %v", result.Intervals)
+	}
+}
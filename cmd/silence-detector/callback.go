@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Callback events, sent in the callbackEventHeader so that receivers can tell results from failures without parsing
+// the body.
+const (
+	callbackEventSucceeded = "detection.succeeded"
+	callbackEventFailed    = "detection.failed"
+)
+
+const (
+	callbackEventHeader = "X-Silence-Detector-Event"
+	// callbackSignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body, keyed with the
+	// callback secret.
+	callbackSignatureHeader = "X-Silence-Detector-Signature"
+)
+
+// callbackAttempts is the number of times a callback is sent before it is given up on. Only network errors and 5xx
+// responses are retried.
+const callbackAttempts = 3
+
+// callbackAttemptTimeout bounds each attempt to deliver a callback.
+const callbackAttemptTimeout = 30 * time.Second
+
+// callbackSender POSTs detection results to callback URLs.
+type callbackSender struct {
+	client *http.Client
+	// secret signs the body of every callback; callbacks are unsigned when it is empty.
+	secret []byte
+	// backoff is the delay before the first retry; it doubles after every subsequent failure.
+	backoff time.Duration
+}
+
+func newCallbackSender(secret string) *callbackSender {
+	return &callbackSender{
+		client:  &http.Client{Timeout: callbackAttemptTimeout},
+		secret:  []byte(secret),
+		backoff: time.Second,
+	}
+}
+
+// validateCallbackURL checks that a callback URL is an absolute HTTP(S) URL.
+func validateCallbackURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid callback URL %q: must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+// deliver sends payload to target and logs a delivery that fails. Delivery never affects the outcome of the
+// detection it reports, so the error is not returned.
+func (c *callbackSender) deliver(ctx context.Context, target, event string, payload any) {
+	if err := c.send(ctx, target, event, payload); err != nil {
+		// Causes such as a malformed URL can still quote it, token included.
+		message := strings.ReplaceAll(err.Error(), target, callbackOrigin(target))
+		fmt.Fprintf(os.Stderr, "callback to %s failed: %s\n", callbackOrigin(target), message)
+	}
+}
+
+// callbackOrigin returns the scheme and host of a callback URL for logs, leaving out any token in its path or query.
+func callbackOrigin(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "an invalid URL"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// send POSTs payload as JSON to target, retrying network errors and 5xx responses with exponential backoff.
+func (c *callbackSender) send(ctx context.Context, target, event string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := c.post(ctx, target, event, body)
+		if err == nil {
+			return nil
+		}
+		if attempt >= callbackAttempts || !retry || ctx.Err() != nil {
+			return fmt.Errorf("%w (after %d attempt(s))", err, attempt)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (after %d attempt(s))", err, attempt)
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying.
+func (c *callbackSender) post(ctx context.Context, target, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, hideCallbackURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(callbackEventHeader, event)
+	if len(c.secret) > 0 {
+		req.Header.Set(callbackSignatureHeader, signCallback(c.secret, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, hideCallbackURL(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+	return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+}
+
+// hideCallbackURL strips the *url.Error that net/http wraps around failures. Its URL, the callback URL or that of a
+// redirect, may carry a token, so only the cause is kept; the log line names the origin.
+func hideCallbackURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// signCallback returns the callbackSignatureHeader value for body.
+func signCallback(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// resultEvent returns the callback event reporting res.
+func resultEvent(res fileResult) string {
+	if res.err != nil {
+		return callbackEventFailed
+	}
+	return callbackEventSucceeded
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallbackSenderSignsAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var gotBody, gotEvent, gotSignature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotEvent, gotSignature = string(body), r.Header.Get(callbackEventHeader), r.Header.Get(callbackSignatureHeader)
+	}))
+	defer receiver.Close()
+
+	sender := newCallbackSender("s3cret")
+	sender.backoff = time.Millisecond
+	if err := sender.send(context.Background(), receiver.URL, callbackEventSucceeded, map[string]string{"input": "a.wav"}); err != nil {
+		t.Fatalf("send returned error: %v", err)
+	}
+
+	if attempts.Load() != 2 {
+		t.Fatalf("expected the 502 to be retried once, got %d attempts", attempts.Load())
+	}
+	if gotBody != `{"input":"a.wav"}` || gotEvent != callbackEventSucceeded {
+		t.Fatalf("unexpected callback %q with event %q", gotBody, gotEvent)
+	}
+	// printf '{"input":"a.wav"}' | openssl dgst -sha256 -hmac s3cret
+	if want := "sha256=7e07264861e7ac6ab1821a967f85500192414550c88e659595ad55d026209d56"; gotSignature != want {
+		t.Fatalf("expected signature %q, got %q", want, gotSignature)
+	}
+}
+
+func TestCallbackSenderGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+	}{
+		{name: "server error", status: http.StatusServiceUnavailable, attempts: callbackAttempts},
+		{name: "client error", status: http.StatusNotFound, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				if r.Header.Get(callbackSignatureHeader) != "" {
+					t.Errorf("expected an unsigned callback without a secret")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			sender := newCallbackSender("")
+			sender.backoff = time.Millisecond
+			err := sender.send(context.Background(), receiver.URL, callbackEventFailed, struct{}{})
+			if err == nil || attempts.Load() != tt.attempts {
+				t.Fatalf("expected failure after %d attempt(s), got %d: %v", tt.attempts, attempts.Load(), err)
+			}
+		})
+	}
+}
+
+func TestCallbackSenderHidesURLInErrors(t *testing.T) {
+	sender := newCallbackSender("")
+	sender.backoff = time.Millisecond
+	sender.client.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	err := sender.send(context.Background(), "https://hooks.example.com/notify?token=abc", callbackEventFailed, struct{}{})
+	if err == nil || strings.Contains(err.Error(), "token") {
+		t.Fatalf("expected an error without the callback URL, got %v", err)
+	}
+	err = sender.send(context.Background(), "https://hooks.example.com/notify/abc\x7f?token=abc", callbackEventFailed, struct{}{})
+	if err == nil || strings.Contains(err.Error(), "token") || strings.Contains(err.Error(), "notify") {
+		t.Fatalf("expected an error without the malformed callback URL, got %v", err)
+	}
+	if origin := callbackOrigin("https://hooks.example.com/notify?token=abc"); origin != "https://hooks.example.com" {
+		t.Fatalf("unexpected origin %q", origin)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// compareReport is the JSON report of `silence-detector compare`.
+type compareReport struct {
+	A                string  `json:"a"`
+	B                string  `json:"b"`
+	Tolerance        float64 `json:"tolerance"`
+	MaxCountMismatch int     `json:"max_count_mismatch"`
+	detector.Diff
+}
+
+// runCompare implements `silence-detector compare`, which detects the silence of two inputs, such as the source and
+// output of a transcode, and reports how they differ.
+func runCompare(args []string) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	var (
+		inputA           = flags.String("a", "", "Reference input, such as the source of a transcode")
+		inputB           = flags.String("b", "", "Input compared with the reference, such as the transcoded output")
+		noiseLevel       = flags.Float64("silence-noise", -30, "Silence noise threshold in dB")
+		minDuration      = flags.Float64("silence-duration", 0.5, "Minimum silence duration in seconds")
+		tolerance        = flags.Float64("tolerance", 0.05, "Seconds the start or end of a silence may move before it counts as a difference")
+		maxCountMismatch = flags.Int("max-count-mismatch", 0, "Number of silence intervals that may be missing from or extra in --b")
+		format           = flags.String("output", string(outputFormatText), "Output format: text or json")
+		ffmpegBinary     = flags.String("ffmpeg", "ffmpeg", "Path to the ffmpeg binary")
+		ffprobeBinary    = flags.String("ffprobe", "ffprobe", "Path to the ffprobe binary used to determine the input duration (empty to disable)")
+		timeout          = flags.Duration("timeout", 0, "Time limit for both analyses, including downloads (0 disables the limit)")
+		verbose          = flags.Bool("verbose", false, "Log ffmpeg and ffprobe runs, with secrets redacted, to stderr")
+	)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCodeOK
+		}
+		return exitCodeUsage
+	}
+	switch {
+	case flags.NArg() > 0:
+		fmt.Fprintf(os.Stderr, "compare takes its inputs from --a and --b, got arguments %q\n", flags.Args())
+		return exitCodeUsage
+	case *inputA == "" || *inputB == "":
+		fmt.Fprintln(os.Stderr, "compare requires both --a and --b")
+		return exitCodeUsage
+	case *inputA == "-" || *inputB == "-":
+		fmt.Fprintln(os.Stderr, "compare analyses both inputs at once and cannot read stdin (-)")
+		return exitCodeUsage
+	case *tolerance < 0 || *maxCountMismatch < 0 || *timeout < 0:
+		fmt.Fprintln(os.Stderr, "--tolerance, --max-count-mismatch and --timeout must not be negative")
+		return exitCodeUsage
+	case outputFormat(*format) != outputFormatText && outputFormat(*format) != outputFormatJSON:
+		fmt.Fprintf(os.Stderr, "unsupported output format %q: compare writes text or json\n", *format)
+		return exitCodeUsage
+	}
+
+	options := detector.DetectionOptions{NoiseLevel: *noiseLevel, MinSilenceDuration: *minDuration}
+	if !validOptions(options, nil) {
+		return exitCodeUsage
+	}
+
+	detectorOptions := []detector.Option{
+		detector.WithFFmpegPath(*ffmpegBinary),
+		detector.WithFFprobePath(*ffprobeBinary),
+	}
+	if *verbose {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		detectorOptions = append(detectorOptions, detector.WithLogger(logger))
+	}
+	det := detector.NewDetector(detectorOptions...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	_, err := det.Check(checkCtx)
+	cancel()
+	if err != nil {
+		reportDetectionError(err)
+		return exitCodeFFmpeg
+	}
+
+	cfg := analysisConfig{
+		fetchers: map[detector.InputKind]remoteFetcher{
+			detector.InputRemote: httpFetcher{},
+			detector.InputS3:     s3Fetcher{},
+			detector.InputGCS:    gcsFetcher{},
+		},
+		download:  detector.DownloadOptions{Retries: 3, Timeout: 10 * time.Minute},
+		detection: options,
+		timeout:   *timeout,
+	}
+	results := make([]fileResult, 2)
+	var wg sync.WaitGroup
+	for i, input := range []string{*inputA, *inputB} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = analyzeInput(ctx, det, cfg, input)
+		}()
+	}
+	wg.Wait()
+
+	code := exitCodeOK
+	for _, res := range results {
+		switch {
+		case res.err != nil:
+			reportFailure(res.err)
+			code = worseExitCode(code, res.exitCode())
+		case res.noAudioStream:
+			fmt.Fprintf(os.Stderr, "%s has no audio stream\n", res.input)
+			code = worseExitCode(code, exitCodeNoAudioStream)
+		}
+	}
+	if code != exitCodeOK {
+		return code
+	}
+
+	compareOptions := detector.CompareOptions{Tolerance: *tolerance, MaxCountMismatch: *maxCountMismatch}
+	report := compareReport{
+		A:                *inputA,
+		B:                *inputB,
+		Tolerance:        *tolerance,
+		MaxCountMismatch: *maxCountMismatch,
+		Diff:             detector.CompareResults(results[0].result, results[1].result, compareOptions),
+	}
+	if outputFormat(*format) == outputFormatJSON {
+		emitReport(newReportEncoder(os.Stdout, outputFormatJSON, false), report)
+	} else {
+		emitCompareText(os.Stdout, report, timeFormat{precision: defaultPrecision})
+	}
+
+	if !report.Match {
+		return exitCodeDifferent
+	}
+	return exitCodeOK
+}
+
+// emitCompareText writes the text report of `silence-detector compare`.
+func emitCompareText(w io.Writer, report compareReport, times timeFormat) {
+	fmt.Fprintf(w, "Silence comparison of %s (a) and %s (b)\n", displayInputPath(report.A), displayInputPath(report.B))
+	fmt.Fprintf(w, "Tolerance: %s, Allowed count mismatch: %d\n", times.seconds(report.Tolerance), report.MaxCountMismatch)
+
+	if len(report.Missing) > 0 {
+		fmt.Fprintf(w, "Missing from b (%d):\n", len(report.Missing))
+		printIntervals(w, report.Missing, times)
+	}
+	if len(report.Extra) > 0 {
+		fmt.Fprintf(w, "Extra in b (%d):\n", len(report.Extra))
+		printIntervals(w, report.Extra, times)
+	}
+	if len(report.Drifts) > 0 {
+		fmt.Fprintf(w, "Drifted beyond the tolerance (%d):\n", len(report.Drifts))
+		for i, drift := range report.Drifts {
+			fmt.Fprintf(w, "%d. a start=%s end=%s, b start=%s end=%s (start %+.*fs, end %+.*fs)\n", i+1,
+				times.seconds(drift.A.Start), times.seconds(drift.A.End), times.seconds(drift.B.Start), times.seconds(drift.B.End),
+				times.precision, drift.StartDrift, times.precision, drift.EndDrift)
+		}
+	}
+
+	if report.Match {
+		fmt.Fprintln(w, "Silence profiles match.")
+	} else {
+		fmt.Fprintln(w, "Silence profiles differ.")
+	}
+}
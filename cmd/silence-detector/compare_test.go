@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// fakeCompareFFmpeg stands in for ffmpeg in compare tests: inputs named drifted.wav report their silence half a
+// second late.
+const fakeCompareFFmpeg = `#!/bin/sh
+case "$*" in
+  *-version*) echo "ffmpeg version 6.1-fake"; exit 0;;
+  *-filters*) echo " ... silencedetect     A->A       Detect silence."; exit 0;;
+  *drifted.wav*) echo "[silencedetect @ 0x1] silence_start: 2.5" >&2;;
+  *) echo "[silencedetect @ 0x1] silence_start: 2" >&2;;
+esac
+echo "[silencedetect @ 0x1] silence_end: 4 | silence_duration: 2" >&2
+echo "size=N/A time=00:00:10.00 bitrate=N/A speed=1x" >&2
+`
+
+func TestCompareCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeCompareFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for _, name := range []string{"source.wav", "output.wav", "drifted.wav"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, path)
+	}
+	source, output, drifted := inputs[0], inputs[1], inputs[2]
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+		text string
+	}{
+		{name: "match", args: []string{"--a", source, "--b", output}, want: exitCodeOK, text: "Silence profiles match."},
+		{name: "drift", args: []string{"--a", source, "--b", drifted}, want: exitCodeDifferent, text: "1. a start=2.000s end=4.000s, b start=2.500s end=4.000s (start +0.500s, end +0.000s)"},
+		{name: "drift within tolerance", args: []string{"--a", source, "--b", drifted, "--tolerance", "0.5"}, want: exitCodeOK},
+		{name: "missing input", args: []string{"--a", source}, want: exitCodeUsage},
+		{name: "stdin", args: []string{"--a", source, "--b", "-"}, want: exitCodeUsage},
+		{name: "unsupported output", args: []string{"--a", source, "--b", output, "--output", "edl"}, want: exitCodeUsage},
+		{name: "unreadable input", args: []string{"--a", source, "--b", filepath.Join(dir, "missing.wav")}, want: exitCodeInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"compare", "--ffmpeg", ffmpeg, "--ffprobe", ""}, tt.args...)
+			cmd := exec.Command(os.Args[0], args...)
+			cmd.Env = append(os.Environ(), runMainEnv+"=1")
+
+			out, err := cmd.CombinedOutput()
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if code != tt.want {
+				t.Fatalf("expected exit code %d, got %d; output:\n%s", tt.want, code, out)
+			}
+			if !strings.Contains(string(out), tt.text) {
+				t.Fatalf("expected %q in the output:\n%s", tt.text, out)
+			}
+		})
+	}
+}
+
+func TestCompareReportJSON(t *testing.T) {
+	a := detector.DetectionResult{Intervals: []detector.SilenceInterval{{Start: 1, End: 2, Duration: 1}}}
+	report := compareReport{A: "a.wav", B: "b.wav", Tolerance: 0.05, Diff: detector.CompareResults(a, detector.DetectionResult{}, detector.CompareOptions{Tolerance: 0.05})}
+
+	var buf bytes.Buffer
+	emitReport(newReportEncoder(&buf, outputFormatJSON, false), report)
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if decoded["match"] != false || decoded["a"] != "a.wav" || len(decoded["missing"].([]any)) != 1 {
+		t.Fatalf("unexpected report %s", buf.String())
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+const (
+	// defaultConfigFile is the config file read from the working directory when --config is not given.
+	defaultConfigFile = "silence-detector.yaml"
+	// envPrefix starts the environment variables that set flags, such as SILENCE_DETECTOR_SILENCE_NOISE for
+	// --silence-noise.
+	envPrefix = "SILENCE_DETECTOR_"
+)
+
+// configSetting is one key of a config file: a flag name and its value, or its values when given as a list.
+type configSetting struct {
+	key    string
+	values []string
+	line   int
+}
+
+// repeatableFlag is a flag that may be given several times, whose config file value may be a list of values.
+type repeatableFlag interface {
+	flag.Value
+	values() []string
+}
+
+func (l *inputList) values() []string  { return *l }
+func (h *headerList) values() []string { return *h }
+
+func (l *ffmpegArgList) values() []string {
+	var values []string
+	for _, arg := range l.input {
+		values = append(values, "input:"+arg)
+	}
+	for _, arg := range l.output {
+		values = append(values, "output:"+arg)
+	}
+	return values
+}
+
+// settingAliases are the short names of common flags. A config file may use an alias as the key, and the alias's
+// environment variable, such as SILENCE_DETECTOR_NOISE_DB, sets the flag like its own one does.
+var settingAliases = []struct{ alias, flag string }{
+	{alias: "noise-db", flag: "silence-noise"},
+	{alias: "min-duration", flag: "silence-duration"},
+}
+
+// envName returns the environment variable that sets the flag, or alias, name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// settingNames returns the names that set the flag name in a config file or, through envName, in the environment:
+// the flag name itself, then its aliases.
+func settingNames(name string) []string {
+	names := []string{name}
+	for _, a := range settingAliases {
+		if a.flag == name {
+			names = append(names, a.alias)
+		}
+	}
+	return names
+}
+
+// flagForKey returns the flag a config file key sets, resolving aliases.
+func flagForKey(key string) string {
+	for _, a := range settingAliases {
+		if a.alias == key {
+			return a.flag
+		}
+	}
+	return key
+}
+
+// findConfig returns the config file to read: the --config flag when it was given, even empty to read none, then
+// SILENCE_DETECTOR_CONFIG, then silence-detector.yaml in the working directory when it exists. It returns an empty
+// path when there is no config file.
+func findConfig(flagValue string, given bool, lookupEnv func(string) (string, bool)) string {
+	if given {
+		return flagValue
+	}
+	if path, ok := lookupEnv(envName("config")); ok {
+		return path
+	}
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		return defaultConfigFile
+	}
+	return ""
+}
+
+// loadConfig reads the config file at path. An empty path reads nothing.
+func loadConfig(path string) ([]configSetting, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+	return parseConfig(file, path)
+}
+
+// parseConfig parses a config file: a YAML mapping of flag names to values, such as
+//
+//	silence-noise: -40
+//	output: json
+//	header:
+//	  - "Authorization: Bearer abc"
+//
+// Values are scalars, plain or quoted, or lists of scalars, in block or [flow] style. Nested mappings and other YAML
+// features are not supported. Errors name the file and line.
+func parseConfig(r io.Reader, name string) ([]configSetting, error) {
+	var settings []configSetting
+	seen := map[string]bool{}
+	// list is the index of the setting whose block list items follow, or -1.
+	list := -1
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(stripComment(scanner.Text()), " \t")
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || line == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("%s:%d: tabs are not allowed for indentation", name, number)
+		}
+
+		if trimmed != line || strings.HasPrefix(line, "- ") {
+			item, ok := strings.CutPrefix(trimmed, "- ")
+			if !ok || list < 0 {
+				return nil, fmt.Errorf("%s:%d: expected a list item (- value) under a key without a value; nested mappings are not supported", name, number)
+			}
+			value, err := parseScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, number, err)
+			}
+			settings[list].values = append(settings[list].values, value)
+			continue
+		}
+
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"'") || (rest != "" && rest[0] != ' ') {
+			return nil, fmt.Errorf("%s:%d: expected key: value", name, number)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s:%d: %s is set more than once", name, number, key)
+		}
+		seen[key] = true
+
+		setting := configSetting{key: key, line: number}
+		list = -1
+		switch rest = strings.TrimSpace(rest); {
+		case rest == "":
+			// The values follow as a block list.
+			list = len(settings)
+		case strings.HasPrefix(rest, "["):
+			values, err := parseFlowList(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, number, err)
+			}
+			setting.values = values
+		default:
+			value, err := parseScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, number, err)
+			}
+			setting.values = []string{value}
+		}
+		settings = append(settings, setting)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	for _, setting := range settings {
+		if len(setting.values) == 0 {
+			return nil, fmt.Errorf("%s:%d: %s has no value", name, setting.line, setting.key)
+		}
+	}
+	return settings, nil
+}
+
+// stripComment removes a # comment, which starts a line or follows whitespace, outside a quoted value.
+func stripComment(line string) string {
+	var quote byte
+	escaped := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			// '' is a quote inside a single-quoted value.
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && startsValue(line[:i]):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// startsValue reports whether a value starts after prefix: at the start of a line, or after a key, list item or
+// flow list separator. Quotes only open a quoted value there.
+func startsValue(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " \t")
+	return prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], ":-[,")
+}
+
+// parseScalar returns the value of a plain, 'single-quoted' or "double-quoted" YAML scalar.
+func parseScalar(text string) (string, error) {
+	switch {
+	case text == "":
+		return "", errors.New("empty value")
+	case strings.HasPrefix(text, `"`):
+		var value string
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return "", fmt.Errorf("invalid double-quoted value %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", fmt.Errorf("invalid single-quoted value %s", text)
+		}
+		inner := text[1 : len(text)-1]
+		if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+			return "", fmt.Errorf("invalid single-quoted value %s", text)
+		}
+		return strings.ReplaceAll(inner, "''", "'"), nil
+	case strings.ContainsAny(text[:1], "[]{}&*!|>%@`"):
+		return "", fmt.Errorf("unsupported value %s; quote it", text)
+	}
+	return text, nil
+}
+
+// parseFlowList returns the values of a [flow, style] YAML list of scalars.
+func parseFlowList(text string) ([]string, error) {
+	if !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("unterminated list %s", text)
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return nil, nil
+	}
+
+	var values []string
+	var quote rune
+	start := 0
+	split := func(end int) error {
+		value, err := parseScalar(strings.TrimSpace(inner[start:end]))
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+		start = end + 1
+		return nil
+	}
+	for i, r := range inner {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			if err := split(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := split(len(inner)); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// exclusiveFlags are pairs of flags that choose between alternatives for one setting, such as a threshold in dB or
+// as a ratio. Set at the same level, say both on the command line, they are a usage error, but a flag set at a level
+// of higher precedence overrides its counterpart from a lower one, like it overrides the flag itself.
+var exclusiveFlags = [][2]string{
+	{"silence-noise", "silence-noise-ratio"},
+	{"fail-on-fully-silent", "fail-if-not-fully-silent"},
+	{"downmix-mono", "per-channel"},
+	{"force-download", "no-download"},
+	{"audio-stream", "hls-audio-rendition"},
+	{"audio-stream", "audio-language"},
+	{"hls-audio-rendition", "audio-language"},
+	{"quiet", "verbose"},
+}
+
+// Precedence levels of the sources of a flag's value.
+const (
+	levelConfig = iota + 1
+	levelEnv
+	levelCommandLine
+)
+
+// mergeSettings applies the environment variables, looked up with lookupEnv, and the config file settings to the
+// flags of fs that given does not list, which were set on the command line. Environment variables take precedence
+// over the config file, and a setting is skipped when an exclusiveFlags counterpart was set at a level of higher
+// precedence. It returns where each flag that did not keep its default got its value: "command line", the
+// environment variable or the config file path. Unknown config keys and invalid values are errors that name them.
+func mergeSettings(fs *flag.FlagSet, given map[string]bool, settings []configSetting, configPath string, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	sources := map[string]string{}
+	levels := map[string]int{}
+	for name := range given {
+		sources[name], levels[name] = "command line", levelCommandLine
+	}
+	overridden := func(name string, level int) bool {
+		for _, pair := range exclusiveFlags {
+			if (pair[0] == name && levels[pair[1]] > level) || (pair[1] == name && levels[pair[0]] > level) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var problems []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || f.Name == "config" || overridden(f.Name, levelEnv) {
+			return
+		}
+		// The flag's own variable wins over its aliases.
+		for _, setting := range settingNames(f.Name) {
+			name := envName(setting)
+			value, ok := lookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := fs.Set(f.Name, value); err != nil {
+				problems = append(problems, fmt.Errorf("%s: invalid value %q: %w", name, value, err))
+				return
+			}
+			sources[f.Name], levels[f.Name] = name, levelEnv
+			return
+		}
+	})
+
+	keys := map[string]string{}
+	for _, setting := range settings {
+		name := flagForKey(setting.key)
+		f := fs.Lookup(name)
+		switch {
+		case name == "config":
+			problems = append(problems, fmt.Errorf("%s:%d: config cannot be set in a config file", configPath, setting.line))
+			continue
+		case f == nil:
+			problems = append(problems, fmt.Errorf("%s:%d: unknown key %q", configPath, setting.line, setting.key))
+			continue
+		case keys[name] != "":
+			problems = append(problems, fmt.Errorf("%s:%d: %s is already set as %s", configPath, setting.line, setting.key, keys[name]))
+			continue
+		}
+		keys[name] = setting.key
+		if sources[name] != "" || overridden(name, levelConfig) {
+			continue
+		}
+
+		values := setting.values
+		if _, ok := f.Value.(repeatableFlag); !ok {
+			// Flags such as --silence-noise and --histogram take their list as comma-separated values.
+			values = []string{strings.Join(values, ",")}
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				problems = append(problems, fmt.Errorf("%s:%d: %s: %w", configPath, setting.line, setting.key, err))
+				break
+			}
+		}
+		sources[name], levels[name] = configPath, levelConfig
+	}
+	return sources, errors.Join(problems...)
+}
+
+// emitConfig writes the effective configuration of `silence-detector config print`: every flag of fs as a config
+// file key, in name order, with a comment naming the source of the values that are not defaults. Secrets are
+// redacted: the callback secret, header values, the passwords and query parameters of URLs such as presigned inputs,
+// and everything but the origin of the callback URL, whose path may carry a token too.
+func emitConfig(w io.Writer, fs *flag.FlagSet, sources map[string]string, configPath string) {
+	if configPath != "" {
+		fmt.Fprintf(w, "# config file: %s\n", configPath)
+	} else {
+		fmt.Fprintln(w, "# no config file")
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		var value string
+		if repeatable, ok := f.Value.(repeatableFlag); ok {
+			items := repeatable.values()
+			quoted := make([]string, len(items))
+			for i, item := range items {
+				if f.Name == "header" {
+					name, _, _ := strings.Cut(item, ":")
+					item = name + ": <redacted>"
+				}
+				quoted[i] = configValue(detector.RedactText(item))
+			}
+			value = "[" + strings.Join(quoted, ", ") + "]"
+		} else {
+			value = configValue(detector.RedactText(f.Value.String()))
+			switch {
+			case f.Name == "callback-secret" && f.Value.String() != "":
+				value = configValue("<redacted>")
+			case f.Name == "callback-url" && f.Value.String() != "":
+				value = configValue(callbackOrigin(f.Value.String()) + "/<redacted>")
+			}
+		}
+
+		if source := sources[f.Name]; source != "" {
+			fmt.Fprintf(w, "%s: %s # %s\n", f.Name, value, source)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", f.Name, value)
+		}
+	})
+}
+
+// configValue renders a flag value for a config file: numbers and booleans as they are, and strings quoted.
+func configValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil || value == "true" || value == "false" {
+		return value
+	}
+	var quoted strings.Builder
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(quoted.String(), "\n")
+}
+
+// usage prints the --help text: the flags of fs, then the config file keys and environment variables that set each
+// of them, aliases included.
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintf(w, "\nEvery flag can also be set in a config file (--config, default ./%s) or the environment.\n", defaultConfigFile)
+		fmt.Fprintln(w, "The command line wins over the environment, which wins over the config file.")
+		fmt.Fprintln(w, "\nConfig file keys and environment variables:")
+		fs.VisitAll(func(f *flag.Flag) {
+			if f.Name == "config" {
+				fmt.Fprintf(w, "  %-32s %s\n", "(none)", envName(f.Name))
+				return
+			}
+			names := settingNames(f.Name)
+			vars := make([]string, len(names))
+			for i, name := range names {
+				vars[i] = envName(name)
+			}
+			fmt.Fprintf(w, "  %-32s %s\n", strings.Join(names, ", "), strings.Join(vars, ", "))
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	config := `# shared settings
+---
+silence-noise: -40
+silence-duration: 1.5   # seconds
+cue-text: 'it''s # silent'
+output-file: "reports/out.json"
+header:
+  - "Authorization: Bearer abc"
+  - X-Team: audio
+histogram: [2, "10"]
+`
+	settings, err := parseConfig(strings.NewReader(config), "test.yaml")
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	want := []string{
+		"silence-noise=[-40]",
+		"silence-duration=[1.5]",
+		"cue-text=[it's # silent]",
+		"output-file=[reports/out.json]",
+		"header=[Authorization: Bearer abc X-Team: audio]",
+		"histogram=[2 10]",
+	}
+	if len(settings) != len(want) {
+		t.Fatalf("expected %d settings, got %+v", len(want), settings)
+	}
+	for i, setting := range settings {
+		if got := fmt.Sprintf("%s=%v", setting.key, setting.values); got != want[i] {
+			t.Errorf("setting %d: got %s, want %s", i, got, want[i])
+		}
+	}
+
+	for config, want := range map[string]string{
+		"output: json\noutput: text\n": "test.yaml:2: output is set more than once",
+		"header:\n":                    "test.yaml:1: header has no value",
+		"download:\n  retries: 3\n":    "test.yaml:2: expected a list item",
+		"output json\n":                "test.yaml:1: expected key: value",
+		"cue-text: \"unterminated\n":   "test.yaml:1: invalid double-quoted value",
+	} {
+		if _, err := parseConfig(strings.NewReader(config), "test.yaml"); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("parseConfig(%q) = %v, want an error starting with %q", config, err, want)
+		}
+	}
+}
+
+// TestMergeSettingsPrecedence checks every combination of command-line flag, environment variable and config file
+// for three options: the command line wins over the environment, which wins over the config file, which wins over
+// the default.
+func TestMergeSettingsPrecedence(t *testing.T) {
+	type option struct {
+		name                     string
+		flagValue, env, config   string
+		wantDefault, wantFlagArg string
+	}
+	options := []option{
+		{name: "silence-noise", flagValue: "-20", env: "-25", config: "-35", wantDefault: "-30"},
+		{name: "ffmpeg", flagValue: "/flag/ffmpeg", env: "/env/ffmpeg", config: "/config/ffmpeg", wantDefault: "ffmpeg"},
+		{name: "timeout", flagValue: "1m0s", env: "2m0s", config: "3m0s", wantDefault: "0s"},
+	}
+
+	for _, opt := range options {
+		for mask := range 8 {
+			inFlag, inEnv, inConfig := mask&1 != 0, mask&2 != 0, mask&4 != 0
+			name := fmt.Sprintf("%s/flag=%t,env=%t,config=%t", opt.name, inFlag, inEnv, inConfig)
+			t.Run(name, func(t *testing.T) {
+				fs := flag.NewFlagSet("test", flag.ContinueOnError)
+				noise := thresholdList{-30}
+				fs.Var(&noise, "silence-noise", "")
+				fs.String("ffmpeg", "ffmpeg", "")
+				fs.Duration("timeout", 0, "")
+
+				var args []string
+				if inFlag {
+					args = []string{"--" + opt.name, opt.flagValue}
+				}
+				if err := fs.Parse(args); err != nil {
+					t.Fatal(err)
+				}
+				given := map[string]bool{}
+				fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+				env := map[string]string{}
+				if inEnv {
+					env[envName(opt.name)] = opt.env
+				}
+				var settings []configSetting
+				if inConfig {
+					settings = []configSetting{{key: opt.name, values: []string{opt.config}, line: 1}}
+				}
+				lookupEnv := func(name string) (string, bool) {
+					value, ok := env[name]
+					return value, ok
+				}
+				sources, err := mergeSettings(fs, given, settings, "test.yaml", lookupEnv)
+				if err != nil {
+					t.Fatalf("mergeSettings: %v", err)
+				}
+
+				want, wantSource := opt.wantDefault, ""
+				switch {
+				case inFlag:
+					want, wantSource = opt.flagValue, "command line"
+				case inEnv:
+					want, wantSource = opt.env, envName(opt.name)
+				case inConfig:
+					want, wantSource = opt.config, "test.yaml"
+				}
+				if got := fs.Lookup(opt.name).Value.String(); got != want {
+					t.Errorf("got %s, want %s", got, want)
+				}
+				if sources[opt.name] != wantSource {
+					t.Errorf("got source %q, want %q", sources[opt.name], wantSource)
+				}
+			})
+		}
+	}
+}
+
+// TestMergeSettingsExclusiveFlags checks every combination of levels at which --silence-noise, under its own name
+// and its noise-db alias, and --silence-noise-ratio, which excludes it, are set: the one set at the higher level wins,
+// and only when both are set at the same level are both kept, for run to reject.
+func TestMergeSettingsExclusiveFlags(t *testing.T) {
+	levels := []string{"default", "config", "env", "flag"}
+	for _, alias := range []string{"silence-noise", "noise-db"} {
+		for noise := range levels {
+			for ratio := range levels {
+				name := fmt.Sprintf("%s=%s,ratio=%s", alias, levels[noise], levels[ratio])
+				t.Run(name, func(t *testing.T) {
+					fs := flag.NewFlagSet("test", flag.ContinueOnError)
+					noiseLevels := thresholdList{-30}
+					fs.Var(&noiseLevels, "silence-noise", "")
+					fs.Float64("silence-noise-ratio", 0, "")
+
+					var args []string
+					env := map[string]string{}
+					var settings []configSetting
+					set := func(level int, flagName, alias, value string) {
+						switch levels[level] {
+						case "config":
+							settings = append(settings, configSetting{key: alias, values: []string{value}, line: len(settings) + 1})
+						case "env":
+							env[envName(alias)] = value
+						case "flag":
+							args = append(args, "--"+flagName, value)
+						}
+					}
+					set(noise, "silence-noise", alias, "-35")
+					set(ratio, "silence-noise-ratio", "silence-noise-ratio", "0.001")
+
+					if err := fs.Parse(args); err != nil {
+						t.Fatal(err)
+					}
+					given := map[string]bool{}
+					fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+					lookupEnv := func(name string) (string, bool) {
+						value, ok := env[name]
+						return value, ok
+					}
+					sources, err := mergeSettings(fs, given, settings, "test.yaml", lookupEnv)
+					if err != nil {
+						t.Fatalf("mergeSettings: %v", err)
+					}
+
+					wantNoise := noise > 0 && noise >= ratio
+					wantRatio := ratio > 0 && ratio >= noise
+					if got := sources["silence-noise"] != ""; got != wantNoise {
+						t.Errorf("silence-noise set: got %t, want %t (sources %v)", got, wantNoise, sources)
+					}
+					if got := sources["silence-noise-ratio"] != ""; got != wantRatio {
+						t.Errorf("silence-noise-ratio set: got %t, want %t (sources %v)", got, wantRatio, sources)
+					}
+					if !wantNoise && noiseLevels.String() != "-30" {
+						t.Errorf("expected the overridden threshold to keep its default, got %s", noiseLevels.String())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestMergeSettingsErrors(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Duration("timeout", 0, "")
+	var headers headerList
+	fs.Var(&headers, "header", "")
+
+	settings := []configSetting{
+		{key: "header", values: []string{"A: 1", "B: 2"}, line: 1},
+		{key: "silence-noize", values: []string{"-40"}, line: 3},
+		{key: "timeout", values: []string{"soon"}, line: 4},
+	}
+	_, err := mergeSettings(fs, map[string]bool{}, settings, "test.yaml", func(string) (string, bool) { return "", false })
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`test.yaml:3: unknown key "silence-noize"`, "test.yaml:4: timeout: "} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+	if len(headers) != 2 {
+		t.Errorf("expected both listed headers to be set, got %q", headers)
+	}
+
+	env := func(name string) (string, bool) { return "never", name == envName("timeout") }
+	if _, err := mergeSettings(fs, map[string]bool{}, nil, "", env); err == nil || !strings.Contains(err.Error(), "SILENCE_DETECTOR_TIMEOUT") {
+		t.Errorf("expected an error naming the environment variable, got %v", err)
+	}
+}
+
+func TestEmitConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.String("callback-secret", "", "")
+	fs.String("callback-url", "", "")
+	fs.String("input-list", "", "")
+	fs.Duration("timeout", time.Minute, "")
+	headers := headerList{"Authorization: Bearer abc"}
+	fs.Var(&headers, "header", "")
+	var inputs inputList
+	fs.Var(&inputs, "input", "")
+	args := []string{
+		"--callback-secret", "s3cret",
+		"--callback-url", "https://hook.example/x/abc?token=SECRET",
+		"--input", "https://bucket.example/a.mp4?X-Amz-Signature=SECRET",
+		"--input", "local.wav",
+		"--input-list", "https://lists.example/inputs.txt?sig=SECRET",
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	emitConfig(&buf, fs, map[string]string{"callback-secret": "command line", "header": "test.yaml"}, "test.yaml")
+	want := `# config file: test.yaml
+callback-secret: "<redacted>" # command line
+callback-url: "https://hook.example/<redacted>"
+header: ["Authorization: <redacted>"] # test.yaml
+input: ["https://bucket.example/a.mp4?X-Amz-Signature=REDACTED", "local.wav"]
+input-list: "https://lists.example/inputs.txt?sig=REDACTED"
+timeout: "1m0s"
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected config:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSettingAliases(t *testing.T) {
+	newFlagSet := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("silence-detector", flag.ContinueOnError)
+		noise := thresholdList{-30}
+		fs.Var(&noise, "silence-noise", "Silence noise threshold in dB")
+		fs.Float64("silence-duration", 0.5, "Minimum silence duration in seconds")
+		fs.String("config", "", "Config file")
+		return fs
+	}
+	envOf := func(env map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		}
+	}
+
+	fs := newFlagSet()
+	settings := []configSetting{{key: "min-duration", values: []string{"2"}, line: 1}}
+	env := map[string]string{"SILENCE_DETECTOR_NOISE_DB": "-35", "SILENCE_DETECTOR_SILENCE_NOISE": "-20"}
+	sources, err := mergeSettings(fs, map[string]bool{}, settings, "test.yaml", envOf(env))
+	if err != nil {
+		t.Fatalf("mergeSettings: %v", err)
+	}
+	if got := fs.Lookup("silence-noise").Value.String(); got != "-20" || sources["silence-noise"] != "SILENCE_DETECTOR_SILENCE_NOISE" {
+		t.Errorf("expected the flag's own variable to win over its alias, got %s from %s", got, sources["silence-noise"])
+	}
+	if got := fs.Lookup("silence-duration").Value.String(); got != "2" || sources["silence-duration"] != "test.yaml" {
+		t.Errorf("expected the aliased config key to set --silence-duration, got %s from %s", got, sources["silence-duration"])
+	}
+
+	fs = newFlagSet()
+	_, err = mergeSettings(fs, map[string]bool{}, nil, "", envOf(map[string]string{"SILENCE_DETECTOR_NOISE_DB": "loud"}))
+	if err == nil || !strings.HasPrefix(err.Error(), `SILENCE_DETECTOR_NOISE_DB: invalid value "loud"`) {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+
+	fs = newFlagSet()
+	settings = []configSetting{
+		{key: "silence-noise", values: []string{"-40"}, line: 1},
+		{key: "noise-db", values: []string{"-45"}, line: 2},
+	}
+	_, err = mergeSettings(fs, map[string]bool{}, settings, "test.yaml", envOf(nil))
+	if err == nil || err.Error() != "test.yaml:2: noise-db is already set as silence-noise" {
+		t.Errorf("expected an error for a flag set under both names, got %v", err)
+	}
+
+	fs = newFlagSet()
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	usage(fs)()
+	for _, want := range []string{
+		"  silence-duration, min-duration   SILENCE_DETECTOR_SILENCE_DURATION, SILENCE_DETECTOR_MIN_DURATION\n",
+		"  silence-noise, noise-db          SILENCE_DETECTOR_SILENCE_NOISE, SILENCE_DETECTOR_NOISE_DB\n",
+		"  (none)                           SILENCE_DETECTOR_CONFIG\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in the usage:\n%s", want, buf.String())
+		}
+	}
+}
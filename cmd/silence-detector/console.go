@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// notices receives informational messages: fallbacks and retries, gate violations that the exit code already
+// reports, and progress such as the batch summary. Results go to the report output, and errors, which explain a
+// failed run, always go to stderr. --quiet discards notices, and tests replace the writer to capture them.
+var notices io.Writer = os.Stderr
+
+// notef writes an informational message to notices.
+func notef(format string, args ...any) {
+	fmt.Fprintf(notices, format, args...)
+}
+
+// emitPorcelain writes the --porcelain report: one start, end and duration line per silence interval, separated by
+// tabs, in seconds with --precision decimals, and nothing else.
+func emitPorcelain(w io.Writer, result detector.DetectionResult, opts reportOptions) {
+	precision := opts.timeFormat().precision
+	for _, interval := range result.Intervals {
+		fmt.Fprintf(w, "%.*f\t%.*f\t%.*f\n", precision, interval.Start, precision, interval.End, precision, interval.Duration)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestPorcelainSeparatesResultsFromNotices(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{name: "porcelain", args: []string{"--porcelain", "--fail-on-silence"}, wantStderr: "1 silence interval(s) detected\n"},
+		{name: "quiet", args: []string{"--porcelain", "--quiet", "--fail-on-silence"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--ffmpeg", ffmpeg, "--ffprobe", ""}, tt.args...)
+			cmd := exec.Command(os.Args[0], append(args, input)...)
+			cmd.Env = append(os.Environ(), runMainEnv+"=1", "FAKE_FFMPEG_MODE=partial")
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+			var exitErr *exec.ExitError
+			if err := cmd.Run(); !errors.As(err, &exitErr) || exitErr.ExitCode() != exitCodeSilenceDetected {
+				t.Fatalf("expected exit code %d, got %v; stderr:\n%s", exitCodeSilenceDetected, err, stderr.String())
+			}
+			if want := "7.000\t10.000\t3.000\n"; stdout.String() != want {
+				t.Errorf("unexpected stdout %q, want %q", stdout.String(), want)
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("unexpected stderr %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestQuietDiscardsInputAndDeprecationNotices(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantNotice string
+	}{
+		{name: "glob without match", args: []string{filepath.Join(dir, "*.flac"), input}, wantNotice: "no files match"},
+		{name: "directory without media", args: []string{filepath.Join(dir, "empty"), input}, wantNotice: "no media files"},
+		{name: "legacy json", args: []string{"--output", "json", "--legacy-json", input}, wantNotice: "--legacy-json is deprecated"},
+	}
+	for _, tt := range tests {
+		for _, quiet := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s quiet %t", tt.name, quiet), func(t *testing.T) {
+				args := []string{"--ffmpeg", ffmpeg, "--ffprobe", ""}
+				if quiet {
+					args = append(args, "--quiet")
+				}
+				cmd := exec.Command(os.Args[0], append(args, tt.args...)...)
+				cmd.Env = append(os.Environ(), runMainEnv+"=1", "FAKE_FFMPEG_MODE=partial")
+				var stderr bytes.Buffer
+				cmd.Stderr = &stderr
+
+				if err := cmd.Run(); err != nil {
+					t.Fatalf("run: %v; stderr:\n%s", err, stderr.String())
+				}
+				switch {
+				case quiet && stderr.Len() > 0:
+					t.Errorf("expected --quiet to discard notices, got %q", stderr.String())
+				case !quiet && !strings.Contains(stderr.String(), tt.wantNotice):
+					t.Errorf("expected a notice containing %q, got %q", tt.wantNotice, stderr.String())
+				}
+			})
+		}
+	}
+}
+
+func TestEmitPorcelain(t *testing.T) {
+	result := detector.DetectionResult{Intervals: []detector.Interval{{Start: 0, End: 1.25, Duration: 1.25}, {Start: 61, End: 62.5, Duration: 1.5}}}
+
+	var buf bytes.Buffer
+	emitPorcelain(&buf, result, reportOptions{times: &timeFormat{precision: 2, style: timeStyleClock}})
+	if want := "0.00\t1.25\t1.25\n61.00\t62.50\t1.50\n"; buf.String() != want {
+		t.Fatalf("unexpected porcelain output %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	emitPorcelain(&buf, detector.DetectionResult{}, reportOptions{})
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output without silence, got %q", buf.String())
+	}
+}
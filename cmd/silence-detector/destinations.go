@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errPrivateDestination is returned for callback URLs and remote inputs that address a loopback, private or
+// link-local host, such as the instance metadata service, which clients of serve are not allowed to reach.
+var errPrivateDestination = errors.New("destination is not a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is no more reachable from the internet than
+// the private ranges.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress reports whether ip is a public unicast address: not loopback, private, link-local, multicast or
+// unspecified.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// publicOnlyClient returns an HTTP client that refuses to connect to addresses that are not public. The address is
+// checked on every connection, so neither redirects nor DNS answers that change after checkDestination reach an
+// internal service. The client does not use a proxy, which would hide the destination. A timeout of zero leaves
+// requests bounded by their context alone.
+func publicOnlyClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errPrivateDestination, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// checkDestination fails when the host of the URL raw is, or resolves to, an address that is not public, so that
+// requests naming an internal service are rejected before they run. A host that cannot be resolved passes: the
+// request fails when it connects instead.
+func checkDestination(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+
+	var addrs []netip.Addr
+	if ip, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{ip}
+	} else if addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host); err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return fmt.Errorf("%w: %s is %s", errPrivateDestination, host, addr.Unmap())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:4700::1111", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "100.64.0.1"},
+		{addr: "169.254.169.254"},
+		{addr: "169.254.170.2"},
+		{addr: "fe80::1"},
+		{addr: "fd00:ec2::254"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "224.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPublicOnlyClientRefusesLoopback(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	_, err := publicOnlyClient(time.Second).Get(target.URL)
+	if !errors.Is(err, errPrivateDestination) {
+		t.Fatalf("expected the connection to be refused, got %v", err)
+	}
+}
+
+func TestServeRejectsPrivateDestinations(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(silentOutput), nil
+	}
+	s := newServer([]detector.Option{detector.WithCommandRunner(runner), detector.WithFFprobePath("")}, 1, time.Minute, time.Hour)
+	s.restrictDestinations()
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		srv.Close()
+		s.close()
+	})
+
+	for _, input := range []string{"http://127.0.0.1/a.wav", "http://169.254.169.254/latest/meta-data/", "http://[::1]/a.wav", "rtmp://10.0.0.5/live/key"} {
+		if status, body := postDetect(t, srv, `{"input":"`+input+`"}`); status != http.StatusBadRequest {
+			t.Errorf("expected 400 for input %s, got %d: %v", input, status, body)
+		}
+	}
+
+	body := `{"input":"https://203.0.113.7/a.wav","callback_url":"http://169.254.169.254/hook?token=abc"}`
+	if status, _ := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", body); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for a link-local callback URL, got %d", status)
+	}
+}
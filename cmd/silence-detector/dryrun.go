@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// printDryRun writes the ffmpeg commands that would analyse each input, one per line, without running anything.
+// Network inputs are resolved like analyzeInput resolves them, so that a command shows the URL and request headers
+// ffmpeg would be given, with their secrets redacted.
+//
+// Remote and cloud storage inputs are shown as given when, unless --no-download is set or they are HLS or DASH
+// manifests, they are downloaded to a temporary file first, which a comment line before the command points out.
+func printDryRun(ctx context.Context, w io.Writer, det *detector.Detector, ffmpegPath string, cfg analysisConfig, inputs []string) error {
+	printCommand := func(args []string) {
+		fmt.Fprintln(w, shellJoin(detector.RedactArgs(append([]string{ffmpegPath}, args...))))
+	}
+	for _, raw := range inputs {
+		input, err := detector.ResolveInput(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid input %q: %v", raw, err)
+		}
+		resolved, headers, err := resolveFetcher(ctx, cfg, input)
+		if err != nil {
+			return err
+		}
+
+		path := input.Location
+		options := cfg.detection
+		if input.Raw == "-" {
+			options.Stdin = os.Stdin
+		}
+		switch {
+		case resolved.Kind != detector.InputLocal && !cfg.streams(resolved):
+			fmt.Fprintf(w, "# %s is downloaded to a temporary file before analysis\n", input.Raw)
+			headers = nil
+		case resolved.IsRemote():
+			path = resolved.Location
+		}
+		options.InputHeaders = headers
+
+		if cfg.allStreams {
+			fmt.Fprintln(w, "# ffmpeg runs once per audio stream found by ffprobe; the command for the first stream is shown")
+			first := 0
+			options.AudioStreamIndex = &first
+		}
+
+		// A sweep runs ffmpeg once per threshold.
+		levels := cfg.sweep
+		if len(levels) == 0 {
+			levels = []float64{options.NoiseLevel}
+		}
+		for _, level := range levels {
+			options.NoiseLevel = level
+			args, err := det.BuildArgs(path, options)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayInputPath(input.Raw), err)
+			}
+			if options.Engine == detector.EngineNative {
+				fmt.Fprintf(w, "# %s is analysed natively, without ffmpeg\n", input.Raw)
+				continue
+			}
+			printCommand(args)
+		}
+
+		if cfg.black != nil {
+			blackOptions := *cfg.black
+			blackOptions.InputHeaders = headers
+			args, err := det.BuildBlackArgs(path, blackOptions)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayInputPath(input.Raw), err)
+			}
+			printCommand(args)
+		}
+		if cfg.freeze != nil {
+			freezeOptions := *cfg.freeze
+			freezeOptions.InputHeaders = headers
+			args, err := det.BuildFreezeArgs(path, freezeOptions)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayInputPath(input.Raw), err)
+			}
+			printCommand(args)
+		}
+		if cfg.energyWindow > 0 {
+			args, err := det.BuildEnergyTimelineArgs(path, cfg.energyWindow)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayInputPath(input.Raw), err)
+			}
+			printCommand(args)
+		}
+		if cfg.quality {
+			args, err := det.BuildQualityArgs(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayInputPath(input.Raw), err)
+			}
+			printCommand(args)
+		}
+	}
+	return nil
+}
+
+// shellJoin joins args into a command line that POSIX shells split back into the same arguments.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote returns arg unchanged when it consists only of characters that are never special to the shell, and
+// wrapped in single quotes otherwise.
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if strings.IndexFunc(arg, func(r rune) bool { return !isShellSafe(r) }) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_./:,+=@%", r)
+}
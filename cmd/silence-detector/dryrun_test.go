@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestShellJoinRoundTrips(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	args := []string{"plain", "with space", "it's", "", "$HOME", "a;b", "silencedetect=noise=-30dB:d=0.5", `back\slash`, "*.wav"}
+	script := "for a in " + shellJoin(args) + `; do printf '[%s]\n' "$a"; done`
+	got, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("sh: %v", err)
+	}
+
+	var want strings.Builder
+	for _, arg := range args {
+		want.WriteString("[" + arg + "]\n")
+	}
+	if string(got) != want.String() {
+		t.Fatalf("shell split %q into\n%s\nwant\n%s", shellJoin(args), got, want.String())
+	}
+}
+
+func TestPrintDryRunIncludesExtraArgs(t *testing.T) {
+	cfg := analysisConfig{
+		detection: detector.DetectionOptions{
+			NoiseLevel:         -30,
+			MinSilenceDuration: 0.5,
+			ExtraInputArgs:     []string{"-fflags", "+genpts"},
+			ExtraOutputArgs:    []string{"-metadata", "title=a b"},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := printDryRun(context.Background(), &out, detector.NewDetector(), "/opt/ffmpeg", cfg, []string{"my clip.mp4", "https://example.com/a.mp3"}); err != nil {
+		t.Fatalf("printDryRun returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected two commands and a download note, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "/opt/ffmpeg ") || !strings.Contains(lines[0], "-fflags +genpts -i 'my clip.mp4' ") ||
+		!strings.Contains(lines[0], " -metadata 'title=a b' -f null -") {
+		t.Fatalf("unexpected command %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "# https://example.com/a.mp3 is downloaded") {
+		t.Fatalf("expected a download note, got %q", lines[1])
+	}
+
+	out.Reset()
+	if err := printDryRun(context.Background(), &out, detector.NewDetector(), "ffmpeg", cfg, []string{"https://example.com/live/index.m3u8"}); err != nil {
+		t.Fatalf("printDryRun returned error: %v", err)
+	}
+	if strings.Contains(out.String(), "downloaded") {
+		t.Fatalf("expected HLS playlists to be read directly, got %q", out.String())
+	}
+
+	cfg.detection.MinSilenceDuration = 0
+	if err := printDryRun(context.Background(), &out, detector.NewDetector(), "ffmpeg", cfg, []string{"a.wav"}); err == nil {
+		t.Fatal("expected invalid options to be rejected")
+	}
+}
+
+func TestPrintDryRunSendsRedactedHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer s3cret")
+	headers.Set("X-Team", "audio")
+	cfg := analysisConfig{
+		detection:  detector.DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5},
+		fetchers:   map[detector.InputKind]remoteFetcher{detector.InputRemote: httpFetcher{headers: headers}},
+		noDownload: true,
+	}
+
+	var out bytes.Buffer
+	if err := printDryRun(context.Background(), &out, detector.NewDetector(), "ffmpeg", cfg, []string{"https://example.com/a.mp3?token=abc"}); err != nil {
+		t.Fatalf("printDryRun returned error: %v", err)
+	}
+	command := out.String()
+	if !strings.Contains(command, "-headers ") || !strings.Contains(command, "X-Team: audio") {
+		t.Fatalf("expected the request headers the run sends, got %q", command)
+	}
+	for _, secret := range []string{"s3cret", "token=abc"} {
+		if strings.Contains(command, secret) {
+			t.Errorf("expected %q to be redacted from %q", secret, command)
+		}
+	}
+
+	// A downloaded input is read from a local file, without headers.
+	cfg.noDownload = false
+	out.Reset()
+	if err := printDryRun(context.Background(), &out, detector.NewDetector(), "ffmpeg", cfg, []string{"https://example.com/a.mp3"}); err != nil {
+		t.Fatalf("printDryRun returned error: %v", err)
+	}
+	if strings.Contains(out.String(), "-headers") {
+		t.Errorf("expected no headers for a downloaded input, got %q", out.String())
+	}
+}
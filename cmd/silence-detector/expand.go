@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// defaultMediaExtensions lists the file extensions picked up when scanning directories.
+const defaultMediaExtensions = "aac,flac,m4a,mkv,mov,mp3,mp4,mxf,ogg,opus,wav,webm"
+
+// readInputList reads one input per line from the file at path, or from stdin when path is "-". Blank lines and
+// lines starting with # are ignored.
+func readInputList(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input list: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var inputs []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input list %q: %w", path, err)
+	}
+
+	return inputs, nil
+}
+
+// parseExtensions normalises a comma-separated extension list such as "mp3, .WAV" into lower-case extensions with
+// a leading dot.
+func parseExtensions(list string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+// expandInputs replaces local directories and glob patterns among inputs with the media files they match, in
+// lexical order. Other inputs are passed through unchanged. It reports whether any input was expanded.
+//
+// Directories are scanned for files with one of the given extensions, descending into subdirectories only when
+// recursive is set. Hidden files and directories are skipped, and symlinked directories are not followed so that
+// link cycles cannot cause endless scans.
+func expandInputs(inputs []string, recursive bool, extensions []string) ([]string, bool, error) {
+	var (
+		expanded  []string
+		didExpand bool
+	)
+
+	for _, raw := range inputs {
+		trimmed := strings.TrimSpace(raw)
+		input, err := detector.ResolveInput(trimmed)
+		if trimmed == "-" || err != nil || input.Kind != detector.InputLocal {
+			expanded = append(expanded, raw)
+			continue
+		}
+
+		info, statErr := os.Stat(input.Location)
+		switch {
+		case statErr == nil && info.IsDir():
+			files, err := scanDirectory(input.Location, recursive, extensions)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to scan %q: %w", input.Raw, err)
+			}
+			if len(files) == 0 {
+				notef("no media files with extensions %s found in %q\n", strings.Join(extensions, ","), input.Raw)
+			}
+			expanded = append(expanded, files...)
+			didExpand = true
+		case statErr != nil && hasGlobMeta(input.Location):
+			files, err := globFiles(input.Location)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid pattern %q: %w", input.Raw, err)
+			}
+			if len(files) == 0 {
+				notef("no files match %q\n", input.Raw)
+			}
+			expanded = append(expanded, files...)
+			didExpand = true
+		default:
+			expanded = append(expanded, raw)
+		}
+	}
+
+	return expanded, didExpand, nil
+}
+
+func scanDirectory(root string, recursive bool, extensions []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			// Follow links to files, but not to directories.
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		} else if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		if hasExtension(path, extensions) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// globFiles expands a glob pattern into the regular files it matches.
+func globFiles(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func hasExtension(path string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, candidate := range extensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
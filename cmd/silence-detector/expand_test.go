@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// mediaTree creates a directory of media files for the expansion tests, with hidden entries, a file of another type,
+// a symlinked file and, in a subdirectory, a symlink back to the root that would loop a scan following it.
+func mediaTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.wav", "B.MP3", "notes.txt", ".hidden.wav", ".cache/d.wav", "sub/c.wav", "empty/.keep"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(filepath.Join(root, "a.wav"), filepath.Join(root, "link.wav")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(root, filepath.Join(root, "sub", "loop")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestExpandInputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires privileges")
+	}
+	root := mediaTree(t)
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(root, name)
+		}
+		return paths
+	}
+	media := parseExtensions(defaultMediaExtensions)
+
+	tests := []struct {
+		name         string
+		inputs       []string
+		recursive    bool
+		extensions   []string
+		want         []string
+		wantExpanded bool
+	}{
+		{name: "directory", inputs: []string{root}, extensions: media, want: join("B.MP3", "a.wav", "link.wav"), wantExpanded: true},
+		{name: "recursive", inputs: []string{root}, recursive: true, extensions: media, want: join("B.MP3", "a.wav", "link.wav", "sub/c.wav"), wantExpanded: true},
+		{name: "extension filter", inputs: []string{root}, recursive: true, extensions: parseExtensions("WAV"), want: join("a.wav", "link.wav", "sub/c.wav"), wantExpanded: true},
+		{name: "symlink cycle", inputs: []string{filepath.Join(root, "sub")}, recursive: true, extensions: media, want: join("sub/c.wav"), wantExpanded: true},
+		{name: "empty directory", inputs: []string{filepath.Join(root, "empty")}, recursive: true, extensions: media, want: nil, wantExpanded: true},
+		{name: "glob", inputs: []string{filepath.Join(root, "sub", "*.wav")}, extensions: media, want: join("sub/c.wav"), wantExpanded: true},
+		{name: "glob skips directories", inputs: []string{filepath.Join(root, "s*")}, extensions: media, want: nil, wantExpanded: true},
+		{name: "glob without match", inputs: []string{filepath.Join(root, "*.flac")}, extensions: media, want: nil, wantExpanded: true},
+		{
+			name:       "passed through",
+			inputs:     []string{filepath.Join(root, "a.wav"), filepath.Join(root, "missing.wav"), "-", "https://example.com/*.mp3"},
+			extensions: media,
+			want:       []string{filepath.Join(root, "a.wav"), filepath.Join(root, "missing.wav"), "-", "https://example.com/*.mp3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, expanded, err := expandInputs(tt.inputs, tt.recursive, tt.extensions)
+			if err != nil {
+				t.Fatalf("expandInputs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if expanded != tt.wantExpanded {
+				t.Errorf("got expanded %t, want %t", expanded, tt.wantExpanded)
+			}
+		})
+	}
+}
+
+func TestExpandInputsRejectsInvalidPattern(t *testing.T) {
+	if _, _, err := expandInputs([]string{filepath.Join(t.TempDir(), "[")}, false, parseExtensions("wav")); err == nil {
+		t.Fatal("expected an error for a malformed glob pattern")
+	}
+}
+
+func TestReadInputList(t *testing.T) {
+	list := "# uploads for review\n\na.wav\n  b.mp3  \n\t# indented comment\nhttps://example.com/c.mp4\n-\n"
+	want := []string{"a.wav", "b.mp3", "https://example.com/c.mp4", "-"}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "inputs.txt")
+		if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readInputList(path)
+		if err != nil {
+			t.Fatalf("readInputList: %v", err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "stdin.txt")
+		if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stdin, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+		saved := os.Stdin
+		os.Stdin = stdin
+		t.Cleanup(func() { os.Stdin = saved })
+
+		got, err := readInputList("-")
+		if err != nil {
+			t.Fatalf("readInputList: %v", err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("only comments", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "inputs.txt")
+		if err := os.WriteFile(path, []byte("# nothing yet\n\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readInputList(path)
+		if err != nil || len(got) != 0 {
+			t.Fatalf("got %q, %v; want no inputs", got, err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := readInputList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+			t.Fatal("expected an error for a missing input list")
+		}
+	})
+}
+
+func TestInputListRecordsFailedLines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list := filepath.Join(dir, "inputs.txt")
+	if err := os.WriteFile(list, []byte("https://\n"+input+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "--ffmpeg", ffmpeg, "--ffprobe", "", "--output", "ndjson", "--input-list", list)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "FAKE_FFMPEG_MODE=partial")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != exitCodeInput {
+		t.Fatalf("expected exit code %d, got %v", exitCodeInput, err)
+	}
+
+	type record struct {
+		Input  string `json:"input"`
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	var records []record
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a record per listed input, got %+v", records)
+	}
+	if got := records[0]; got.Input != "https://" || got.Status != statusError || !strings.Contains(got.Error, "missing a host") {
+		t.Errorf("unexpected record for the malformed line: %+v", got)
+	}
+	if got := records[1]; got.Input != input || got.Status != statusOK {
+		t.Errorf("unexpected record for %s: %+v", input, got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// expectation holds the settings of --expect: the silence an input is expected to have, and how far its boundaries
+// may move.
+type expectation struct {
+	// path is the JSON report the expectation was loaded from.
+	path      string
+	expected  detector.DetectionResult
+	tolerance float64
+}
+
+// loadExpectation reads the JSON report at path, such as one written by --write-expect, as the expected silence.
+func loadExpectation(path string, tolerance float64) (expectation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return expectation{}, err
+	}
+	var report detector.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return expectation{}, fmt.Errorf("%s is not a JSON report: %w", path, err)
+	}
+	if report.SchemaVersion != detector.ReportSchemaVersion {
+		return expectation{}, fmt.Errorf("%s has schema version %d, not %d; regenerate it with --write-expect", path, report.SchemaVersion, detector.ReportSchemaVersion)
+	}
+
+	expected := detector.DetectionResult{
+		Intervals:     report.Intervals,
+		InputDuration: report.Duration,
+		WindowStart:   report.WindowStart,
+	}
+	return expectation{path: path, expected: expected, tolerance: tolerance}, nil
+}
+
+// writeExpectation writes the JSON report of res to the --write-expect target, for later --expect runs.
+func writeExpectation(target string, mkdir bool, res fileResult, opts reportOptions) error {
+	out, err := openReportOutput(target, mkdir)
+	if err != nil {
+		return err
+	}
+	opts.inputPath = res.input
+	emitReport(newReportEncoder(out, outputFormatJSON, false), newFileReport(res, opts))
+	return out.Close()
+}
+
+// emitExpectation compares the silence of res with the --expect report, writes the differences in the style of a
+// unified diff, expected intervals with "-" and detected ones with "+", and returns the exit code.
+func emitExpectation(w io.Writer, res fileResult, opts reportOptions) int {
+	expect := opts.expect
+	times := opts.timeFormat()
+	diff := detector.CompareResults(expect.expected, res.result, detector.CompareOptions{Tolerance: expect.tolerance})
+
+	type hunk struct {
+		start float64
+		lines []string
+	}
+	var hunks []hunk
+	for _, interval := range diff.Missing {
+		hunks = append(hunks, hunk{interval.Start, []string{"-" + formatInterval(interval, times)}})
+	}
+	for _, interval := range diff.Extra {
+		hunks = append(hunks, hunk{interval.Start, []string{"+" + formatInterval(interval, times)}})
+	}
+	for _, drift := range diff.Drifts {
+		hunks = append(hunks, hunk{drift.A.Start, []string{"-" + formatInterval(drift.A, times), "+" + formatInterval(drift.B, times)}})
+	}
+	sort.SliceStable(hunks, func(i, j int) bool { return hunks[i].start < hunks[j].start })
+
+	if diff.Match {
+		fmt.Fprintf(w, "Silence of %s matches %s within %s.\n", displayInputPath(res.input), expect.path, times.seconds(expect.tolerance))
+		return res.exitCode()
+	}
+
+	fmt.Fprintf(w, "--- %s\n", expect.path)
+	fmt.Fprintf(w, "+++ %s\n", displayInputPath(res.input))
+	for _, h := range hunks {
+		for _, line := range h.lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+	fmt.Fprintf(w, "Silence differs from %s: %d missing, %d extra, %d moved by more than %s.\n",
+		expect.path, len(diff.Missing), len(diff.Extra), len(diff.Drifts), times.seconds(expect.tolerance))
+	return exitCodeDifferent
+}
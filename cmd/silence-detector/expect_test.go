@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpectRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join(dir, "testdata", "expected.json")
+
+	tests := []struct {
+		name string
+		mode string
+		args []string
+		want int
+		text string
+	}{
+		{name: "write", mode: "partial", args: []string{"--write-expect", golden, "--mkdir"}, want: exitCodeOK},
+		{name: "match", mode: "partial", args: []string{"--expect", golden}, want: exitCodeOK, text: "matches " + golden},
+		{name: "differ", mode: "full", args: []string{"--expect", golden}, want: exitCodeDifferent, text: "-start=7.000s end=10.000s duration=3.000s\n+start=0.000s end=10.000s duration=10.000s"},
+		{name: "gate still applies", mode: "partial", args: []string{"--expect", golden, "--fail-on-silence"}, want: exitCodeSilenceDetected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--ffmpeg", ffmpeg, "--ffprobe", ""}, tt.args...)
+			cmd := exec.Command(os.Args[0], append(args, input)...)
+			cmd.Env = append(os.Environ(), runMainEnv+"=1", "FAKE_FFMPEG_MODE="+tt.mode)
+
+			out, err := cmd.CombinedOutput()
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if code != tt.want {
+				t.Fatalf("expected exit code %d, got %d; output:\n%s", tt.want, code, out)
+			}
+			if !strings.Contains(string(out), tt.text) {
+				t.Fatalf("expected %q in the output:\n%s", tt.text, out)
+			}
+		})
+	}
+}
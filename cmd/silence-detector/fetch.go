@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// remoteFetcher resolves a network input into an HTTP(S) URL, plus any request headers, that can be downloaded with
+// detector.FetchRemoteInput or handed to ffmpeg directly.
+type remoteFetcher interface {
+	resolve(ctx context.Context, input detector.Input) (string, http.Header, error)
+}
+
+// httpFetcher passes HTTP(S) URLs through with the headers supplied on the command line.
+type httpFetcher struct {
+	headers http.Header
+}
+
+func (f httpFetcher) resolve(ctx context.Context, input detector.Input) (string, http.Header, error) {
+	return input.Location, f.headers, nil
+}
+
+// s3Fetcher presigns s3:// URLs.
+type s3Fetcher struct {
+	options detector.S3Options
+}
+
+func (f s3Fetcher) resolve(ctx context.Context, input detector.Input) (string, http.Header, error) {
+	presigned, err := detector.PresignS3URL(ctx, input.Location, f.options)
+	return presigned, nil, err
+}
+
+// gcsFetcher resolves gs:// URLs to authenticated Cloud Storage API requests.
+type gcsFetcher struct {
+	options detector.GCSOptions
+}
+
+func (f gcsFetcher) resolve(ctx context.Context, input detector.Input) (string, http.Header, error) {
+	return detector.GCSObjectRequest(ctx, input.Location, f.options)
+}
+
+// describeDownloadError explains a failed download. Failures for cloud storage inputs are reported against the
+// original URL rather than the resolved one, which is long and may carry a signature.
+func describeDownloadError(input detector.Input, err error) error {
+	var downloadErr *detector.DownloadError
+	if !errors.As(err, &downloadErr) || input.Raw == input.Location {
+		return fmt.Errorf("failed to download input %q: %w", input.Raw, err)
+	}
+
+	var regionHint string
+	if strings.HasPrefix(strings.ToLower(input.Raw), "s3://") {
+		regionHint = " and --s3-region"
+	}
+
+	switch {
+	case errors.Is(err, detector.ErrObjectNotFound):
+		return fmt.Errorf("%w: %s", detector.ErrObjectNotFound, input.Raw)
+	case downloadErr.StatusCode == http.StatusForbidden || downloadErr.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("access to %q denied; check the credentials%s", input.Raw, regionHint)
+	case downloadErr.StatusCode == http.StatusMovedPermanently || downloadErr.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("request for %q was rejected (%s); check the bucket location%s", input.Raw, downloadErr.Status, regionHint)
+	case downloadErr.Err != nil:
+		// Network errors repeat the resolved URL, signature included.
+		cause := downloadErr.Err
+		var urlErr *url.Error
+		if errors.As(cause, &urlErr) {
+			cause = urlErr.Err
+		}
+		return fmt.Errorf("failed to download input %q: %w", input.Raw, cause)
+	default:
+		return fmt.Errorf("failed to download input %q: unexpected HTTP status %s", input.Raw, downloadErr.Status)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestDescribeDownloadErrorHidesResolvedURL(t *testing.T) {
+	presigned := "http://127.0.0.1:1/bucket/a.wav?X-Amz-Credential=AKIA&X-Amz-Security-Token=SESSIONTOKEN123&X-Amz-Signature=SIG"
+	input := detector.Input{Raw: "s3://bucket/a.wav", Location: presigned}
+	err := describeDownloadError(input, &detector.DownloadError{
+		URL:      presigned,
+		Attempts: 1,
+		Err:      &url.Error{Op: "Get", URL: presigned, Err: errors.New("connection refused")},
+	})
+
+	message := err.Error()
+	for _, secret := range []string{"AKIA", "SESSIONTOKEN123", "SIG"} {
+		if strings.Contains(message, secret) {
+			t.Errorf("expected %q to be hidden in %q", secret, message)
+		}
+	}
+	if want := `failed to download input "s3://bucket/a.wav": connection refused`; message != want {
+		t.Errorf("got %q, want %q", message, want)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// histogramFlag holds the comma-separated bounds of --histogram, in seconds.
+type histogramFlag []float64
+
+func (h *histogramFlag) String() string {
+	values := make([]string, len(*h))
+	for i, bound := range *h {
+		values[i] = strconv.FormatFloat(bound, 'f', -1, 64)
+	}
+	return strings.Join(values, ",")
+}
+
+func (h *histogramFlag) Set(value string) error {
+	var bounds histogramFlag
+	for _, field := range strings.Split(value, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return fmt.Errorf("invalid histogram bound %q", field)
+		}
+		bounds = append(bounds, bound)
+	}
+	if _, err := (detector.DetectionResult{}).Histogram(bounds); err != nil {
+		return err
+	}
+	*h = bounds
+	return nil
+}
+
+// printHistogram writes the --histogram duration classes of a text report, one line per class.
+func printHistogram(w io.Writer, buckets []detector.Bucket, times timeFormat) {
+	fmt.Fprintln(w, "Silence durations:")
+	for _, bucket := range buckets {
+		var class string
+		switch {
+		case bucket.Max == nil:
+			class = fmt.Sprintf("%s or longer", times.seconds(bucket.Min))
+		case bucket.Min == 0:
+			class = fmt.Sprintf("under %s", times.seconds(*bucket.Max))
+		default:
+			class = fmt.Sprintf("%s to %s", times.seconds(bucket.Min), times.seconds(*bucket.Max))
+		}
+		fmt.Fprintf(w, "  %s: %d interval(s), %s in total\n", class, bucket.Count, times.seconds(bucket.TotalDuration))
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// jobQueueSize bounds the jobs waiting for a worker; submissions beyond it are refused.
+const jobQueueSize = 1000
+
+// jobState is the status of a job. Jobs start queued and end succeeded, failed or cancelled.
+type jobState string
+
+const (
+	jobQueued    jobState = "queued"
+	jobRunning   jobState = "running"
+	jobSucceeded jobState = "succeeded"
+	jobFailed    jobState = "failed"
+	jobCancelled jobState = "cancelled"
+)
+
+func (s jobState) finished() bool {
+	return s == jobSucceeded || s == jobFailed || s == jobCancelled
+}
+
+// job is a detection submitted to POST /v1/jobs and run in the background.
+type job struct {
+	id      string
+	request detectRequest
+	options detector.DetectionOptions
+	ctx     context.Context
+	// cancel stops the job, killing ffmpeg if it is running.
+	cancel context.CancelFunc
+
+	// The fields below are guarded by the mutex of the jobStore holding the job.
+	state    jobState
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	result   any
+	err      string
+}
+
+// jobResponse is the JSON body describing a job. Result holds the report of a succeeded job, in the shape of the
+// POST /v1/detect response, and Error the reason a job failed.
+type jobResponse struct {
+	ID         string   `json:"id"`
+	Status     jobState `json:"status"`
+	CreatedAt  string   `json:"created_at"`
+	StartedAt  string   `json:"started_at,omitempty"`
+	FinishedAt string   `json:"finished_at,omitempty"`
+	Result     any      `json:"result,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// jobStore keeps jobs in memory. Finished jobs are evicted once ttl has passed since they finished; jobs that are
+// queued or running are kept until they finish.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func newJobStore(ttl time.Duration) *jobStore {
+	return &jobStore{jobs: make(map[string]*job), ttl: ttl, now: time.Now}
+}
+
+// add stores a new queued job.
+func (s *jobStore) add(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict()
+	j.state = jobQueued
+	j.created = s.now()
+	s.jobs[j.id] = j
+}
+
+// remove forgets a job that could not be queued.
+func (s *jobStore) remove(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, j.id)
+}
+
+// get describes the job with the given ID, or returns false if there is none or it was evicted.
+func (s *jobStore) get(id string) (jobResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict()
+	j, ok := s.jobs[id]
+	if !ok {
+		return jobResponse{}, false
+	}
+	return j.response(), true
+}
+
+// start marks a job as running, unless it was cancelled while queued.
+func (s *jobStore) start(j *job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.state != jobQueued {
+		return false
+	}
+	j.state = jobRunning
+	j.started = s.now()
+	return true
+}
+
+// finish records the outcome of a job and describes the finished job. A job that was cancelled keeps that state
+// whatever its detection returned.
+func (s *jobStore) finish(j *job, result any, err error) jobResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.state.finished() {
+		return j.response()
+	}
+	j.finished = s.now()
+	switch {
+	case j.ctx.Err() != nil:
+		j.state = jobCancelled
+	case err != nil:
+		j.state, j.err = jobFailed, err.Error()
+	default:
+		j.state, j.result = jobSucceeded, result
+	}
+	return j.response()
+}
+
+// cancel cancels the job with the given ID and describes it. It returns false if there is no such job, and an error
+// if the job had already finished.
+func (s *jobStore) cancel(id string) (jobResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict()
+	j, ok := s.jobs[id]
+	if !ok {
+		return jobResponse{}, false, nil
+	}
+	if j.state.finished() {
+		return j.response(), true, errors.New("job " + id + " has already finished")
+	}
+	j.cancel()
+	j.state = jobCancelled
+	j.finished = s.now()
+	return j.response(), true, nil
+}
+
+// evict forgets the jobs that finished at least ttl ago. The caller holds s.mu.
+func (s *jobStore) evict() {
+	now := s.now()
+	for id, j := range s.jobs {
+		if j.state.finished() && now.Sub(j.finished) >= s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// response describes j. The caller holds the mutex of its store.
+func (j *job) response() jobResponse {
+	return jobResponse{
+		ID:         j.id,
+		Status:     j.state,
+		CreatedAt:  formatJobTime(j.created),
+		StartedAt:  formatJobTime(j.started),
+		FinishedAt: formatJobTime(j.finished),
+		Result:     j.result,
+		Error:      j.err,
+	}
+}
+
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func newJobID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// submitJob queues the detection in the request and responds with 202 and the ID to poll. Invalid requests fail with
+// 400, as they do for POST /v1/detect, and a full queue with 503.
+func (s *server) submitJob(w http.ResponseWriter, r *http.Request) {
+	req, options, ok := s.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := validateCallbackURL(req.CallbackURL); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		if s.publicOnly {
+			if err := checkDestination(r.Context(), req.CallbackURL); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid callback URL: %v", err)})
+				return
+			}
+		}
+	}
+
+	// The job outlives the request, but its spans still belong to the caller's trace.
+	ctx, cancel := context.WithCancel(contextWithTraceparent(s.jobsCtx, r.Header.Get("traceparent")))
+	j := &job{id: newJobID(), request: req, options: options, ctx: ctx, cancel: cancel}
+	s.jobs.add(j)
+
+	select {
+	case s.queue <- j:
+	default:
+		s.jobs.remove(j)
+		cancel()
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "too many jobs queued; try again later"})
+		return
+	}
+
+	response, _ := s.jobs.get(j.id)
+	w.Header().Set("Location", "/v1/jobs/"+j.id)
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// getJob responds with the status of a job, and its result once it has finished.
+func (s *server) getJob(w http.ResponseWriter, r *http.Request) {
+	response, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no such job"})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// cancelJob cancels a queued or running job, killing its ffmpeg run. Cancelling a finished job fails with 409.
+func (s *server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	response, ok, err := s.jobs.cancel(r.PathValue("id"))
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no such job"})
+	case err != nil:
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// work runs queued jobs until the server is closed. Each job takes a detection slot, so that jobs and synchronous
+// detections together stay within --max-concurrent ffmpeg runs. Jobs that succeed or fail are then delivered to
+// their callback URL, if they have one; cancelled jobs are not.
+func (s *server) work() {
+	for {
+		select {
+		case <-s.jobsCtx.Done():
+			return
+		case j := <-s.queue:
+			response := s.runJob(j)
+			if j.request.CallbackURL == "" {
+				continue
+			}
+			switch response.Status {
+			case jobSucceeded:
+				s.deliver(j.request.CallbackURL, callbackEventSucceeded, response)
+			case jobFailed:
+				s.deliver(j.request.CallbackURL, callbackEventFailed, response)
+			}
+		}
+	}
+}
+
+// deliver sends a finished job to its callback URL without waiting for the receiver, whose retries could otherwise
+// keep the worker from the next queued job for minutes.
+func (s *server) deliver(target, event string, response jobResponse) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		s.callbacks.deliver(s.jobsCtx, target, event, response)
+	}()
+}
+
+// runJob runs a queued job and describes it once it has finished.
+func (s *server) runJob(j *job) jobResponse {
+	defer j.cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-j.ctx.Done():
+		return s.jobs.finish(j, nil, j.ctx.Err())
+	}
+
+	if !s.jobs.start(j) {
+		return s.jobs.finish(j, nil, nil)
+	}
+	report, _, err := s.analyse(j.ctx, j.request, j.options, 0)
+	return s.jobs.finish(j, report, err)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func doJobRequest(t *testing.T, method, url, body string) (int, jobResponse) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded jobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp.StatusCode, decoded
+}
+
+func submitJob(t *testing.T, srv *httptest.Server, input string) string {
+	t.Helper()
+	status, job := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", `{"input":"`+filepath.ToSlash(input)+`","check_full_silence":true}`)
+	if status != http.StatusAccepted || job.ID == "" || job.Status != jobQueued {
+		t.Fatalf("expected a queued job, got %d: %+v", status, job)
+	}
+	return job.ID
+}
+
+// waitForJob polls a job until it finishes.
+func waitForJob(t *testing.T, srv *httptest.Server, id string) jobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		status, job := doJobRequest(t, http.MethodGet, srv.URL+"/v1/jobs/"+id, "")
+		if status != http.StatusOK {
+			t.Fatalf("expected 200 polling job %s, got %d", id, status)
+		}
+		if job.Status.finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return jobResponse{}
+}
+
+func TestJobsRunInBackground(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(silentOutput), nil
+	}
+	srv := newTestServer(t, runner, 1, time.Minute)
+
+	job := waitForJob(t, srv, submitJob(t, srv, input))
+	result, _ := job.Result.(map[string]any)
+	if job.Status != jobSucceeded || job.StartedAt == "" || job.FinishedAt == "" || result["fully_silent"] != true {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	job = waitForJob(t, srv, submitJob(t, srv, filepath.Join(filepath.Dir(input), "missing.wav")))
+	if job.Status != jobFailed || job.Error == "" || job.Result != nil {
+		t.Fatalf("expected the job to fail, got %+v", job)
+	}
+
+	if status, _ := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", `{}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid job, got %d", status)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if status, _ := doJobRequest(t, method, srv.URL+"/v1/jobs/unknown", ""); status != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s of an unknown job, got %d", method, status)
+		}
+	}
+}
+
+func TestJobsCancelKillsFFmpeg(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	killed := make(chan struct{})
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		close(killed)
+		return nil, ctx.Err()
+	}
+	srv := newTestServer(t, runner, 1, time.Minute)
+
+	id := submitJob(t, srv, input)
+	<-started
+
+	status, job := doJobRequest(t, http.MethodDelete, srv.URL+"/v1/jobs/"+id, "")
+	if status != http.StatusOK || job.Status != jobCancelled {
+		t.Fatalf("expected the job to be cancelled, got %d: %+v", status, job)
+	}
+	select {
+	case <-killed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling the job did not stop ffmpeg")
+	}
+
+	if job := waitForJob(t, srv, id); job.Status != jobCancelled || job.Error != "" {
+		t.Fatalf("expected the job to stay cancelled, got %+v", job)
+	}
+	if status, _ := doJobRequest(t, http.MethodDelete, srv.URL+"/v1/jobs/"+id, ""); status != http.StatusConflict {
+		t.Fatalf("expected 409 cancelling a finished job, got %d", status)
+	}
+}
+
+func TestJobsDeliverCallbacks(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type delivery struct {
+		event string
+		job   jobResponse
+	}
+	deliveries := make(chan delivery, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job jobResponse
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			t.Errorf("decode callback: %v", err)
+		}
+		deliveries <- delivery{event: r.Header.Get(callbackEventHeader), job: job}
+	}))
+	defer receiver.Close()
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(silentOutput), nil
+	}
+	srv := newTestServer(t, runner, 1, time.Minute)
+
+	for _, tt := range []struct {
+		input string
+		event string
+		state jobState
+	}{
+		{input: input, event: callbackEventSucceeded, state: jobSucceeded},
+		{input: filepath.Join(filepath.Dir(input), "missing.wav"), event: callbackEventFailed, state: jobFailed},
+	} {
+		body := `{"input":"` + filepath.ToSlash(tt.input) + `","callback_url":"` + receiver.URL + `/hook"}`
+		status, job := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", body)
+		if status != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", status)
+		}
+
+		select {
+		case got := <-deliveries:
+			if got.event != tt.event || got.job.ID != job.ID || got.job.Status != tt.state {
+				t.Fatalf("unexpected callback %s for %+v", got.event, got.job)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no callback for job %s", job.ID)
+		}
+	}
+
+	if status, _ := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", `{"input":"a.wav","callback_url":"ftp://example.com"}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid callback URL, got %d", status)
+	}
+	if status, _ := postDetect(t, srv, `{"input":"a.wav","callback_url":"`+receiver.URL+`"}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a callback URL on a synchronous detection, got %d", status)
+	}
+}
+
+func TestJobsDoNotWaitForCallbacks(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(silentOutput), nil
+	}
+	srv := newTestServer(t, runner, 1, time.Minute)
+
+	body := `{"input":"` + filepath.ToSlash(input) + `","callback_url":"` + receiver.URL + `/hook"}`
+	if status, _ := doJobRequest(t, http.MethodPost, srv.URL+"/v1/jobs", body); status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback for the first job")
+	}
+
+	// The receiver still holds the first callback, and the only worker must be free for the next job.
+	if job := waitForJob(t, srv, submitJob(t, srv, input)); job.Status != jobSucceeded {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
+
+func TestJobStoreEvictsFinishedJobs(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newJobStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	newJob := func(id string) *job {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		j := &job{id: id, ctx: ctx, cancel: cancel}
+		store.add(j)
+		return j
+	}
+	finished := newJob("finished")
+	store.start(finished)
+	store.finish(finished, detector.DetectionResult{}, nil)
+	running := newJob("running")
+	store.start(running)
+
+	now = now.Add(59 * time.Minute)
+	if _, ok := store.get("finished"); !ok {
+		t.Fatal("expected the finished job to be kept until its TTL passes")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := store.get("finished"); ok {
+		t.Fatal("expected the finished job to be evicted once its TTL passed")
+	}
+	if job, ok := store.get("running"); !ok || job.Status != jobRunning {
+		t.Fatalf("expected the running job to be kept, got %+v", job)
+	}
+}
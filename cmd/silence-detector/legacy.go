@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// legacyIntervalFields maps the JSON field names of detector.Interval to the untagged names written before the
+// report schema was versioned.
+var legacyIntervalFields = map[string]string{"start": "Start", "end": "End", "duration": "Duration"}
+
+// legacyJSON encodes report in the layout of the releases before schema_version was introduced, for --legacy-json:
+// intervals use the Go field names Start, End and Duration, and schema_version is omitted. Field order is kept.
+func legacyJSON(report any) (json.RawMessage, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	if err := rewriteLegacy(decoder, &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// rewriteLegacy copies the next JSON value from decoder to out, rewriting the objects it contains.
+func rewriteLegacy(decoder *json.Decoder, out *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		var keys []string
+		var values [][]byte
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			var value bytes.Buffer
+			if err := rewriteLegacy(decoder, &value); err != nil {
+				return err
+			}
+			if key == "schema_version" {
+				continue
+			}
+			keys = append(keys, key.(string))
+			values = append(values, value.Bytes())
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+
+		// Objects with exactly the fields of an interval are intervals; no other report object has them all.
+		if slices.Equal(keys, []string{"start", "end", "duration"}) {
+			for i, key := range keys {
+				keys[i] = legacyIntervalFields[key]
+			}
+		}
+		out.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			name, _ := json.Marshal(key)
+			out.Write(name)
+			out.WriteByte(':')
+			out.Write(values[i])
+		}
+		out.WriteByte('}')
+
+	case json.Delim('['):
+		out.WriteByte('[')
+		for i := 0; decoder.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := rewriteLegacy(decoder, out); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+		out.WriteByte(']')
+
+	default:
+		value, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		out.Write(value)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestLegacyJSONRestoresIntervalFieldNames(t *testing.T) {
+	res := fileResult{
+		input: "a.mp4",
+		result: detector.DetectionResult{
+			InputDuration: 10,
+			Intervals:     []detector.Interval{{Start: 0, End: 2.5, Duration: 2.5}},
+		},
+		black: []detector.Interval{{Start: 1, End: 2, Duration: 1}},
+	}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, detectBlack: true, legacyJSON: true}
+
+	var out bytes.Buffer
+	encoder := newReportEncoder(&out, outputFormatNDJSON, opts.legacyJSON)
+	for _, res := range []fileResult{res, {input: "b.wav", err: errors.New("failed to stat input")}} {
+		if err := encoder.Encode(newBatchReport(res, opts)); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{
+		`{"input":"a.mp4","status":"ok","noise_db":-30,"min_duration":0.5,"duration":10,"interval_count":1,` +
+			`"total_silence":2.5,"silence_ratio":0.25,"longest_interval":{"Start":0,"End":2.5,"Duration":2.5},` +
+			`"intervals":[{"Start":0,"End":2.5,"Duration":2.5}],"black":{"count":1,"total":1,"intervals":[{"Start":1,"End":2,"Duration":1}]}}`,
+		`{"input":"b.wav","status":"error","error":"failed to stat input"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d records, got %q", len(want), out.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("record %d:\n got %s\nwant %s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestLegacyJSONIndentsDocuments(t *testing.T) {
+	var out bytes.Buffer
+	report := newJSONReport(detector.DetectionResult{InputDuration: 5}, reportOptions{inputPath: "a.wav"})
+	if err := newReportEncoder(&out, outputFormatJSON, true).Encode(report); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "{\n  \"input\": \"a.wav\",\n") {
+		t.Fatalf("expected an indented document without schema_version, got %q", out.String())
+	}
+}
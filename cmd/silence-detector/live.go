@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// liveGrace is how long a --live check may run past its window, to connect to the stream and fill the window,
+// before the stream is considered stalled and ffmpeg is stopped.
+const liveGrace = 30 * time.Second
+
+// liveSchedule is the --window analysed by each --live check and the --interval between the starts of checks.
+type liveSchedule struct {
+	window   time.Duration
+	interval time.Duration
+}
+
+// streamUnreachableError reports a --live stream that could not be connected to or stalled.
+type streamUnreachableError struct {
+	input string
+	err   error
+}
+
+func (e streamUnreachableError) Error() string {
+	return fmt.Sprintf("stream %q is unreachable: %v", displayInputPath(e.input), e.err)
+}
+
+func (e streamUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// runLive checks a live stream once, or every schedule.interval until ctx is done, and reports each check. It
+// returns the last reported result and its exit code. A check cut short by the end of a monitoring loop is not
+// reported.
+func runLive(ctx context.Context, w io.Writer, det *detector.Detector, cfg analysisConfig, raw string, schedule liveSchedule, format outputFormat, opts reportOptions) (fileResult, int) {
+	var (
+		last     fileResult
+		exitCode int
+	)
+	for checks := 0; ; checks++ {
+		started := time.Now()
+		res := checkLive(ctx, det, cfg, raw, schedule.window)
+		if checks > 0 && ctx.Err() != nil {
+			return last, exitCode
+		}
+
+		opts.checkedAt = started
+		last, exitCode = res, reportSingle(w, res, format, opts)
+		if schedule.interval == 0 {
+			return last, exitCode
+		}
+
+		timer := time.NewTimer(time.Until(started.Add(schedule.interval)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, exitCode
+		case <-timer.C:
+		}
+	}
+}
+
+// checkLive analyses one window of a live stream. ffmpeg is stopped once the window plus liveGrace has passed, so
+// that a stalled stream fails the check instead of hanging it; like a stream ffmpeg cannot connect to, it is
+// reported as a streamUnreachableError.
+func checkLive(ctx context.Context, det *detector.Detector, cfg analysisConfig, raw string, window time.Duration) fileResult {
+	limit := window + liveGrace
+	checkCtx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	res := analyzeInput(checkCtx, det, cfg, raw)
+	switch {
+	case res.err == nil:
+	case ctx.Err() == nil && errors.Is(checkCtx.Err(), context.DeadlineExceeded):
+		res.err = streamUnreachableError{input: raw, err: fmt.Errorf("no %s of audio received within %s", window, limit)}
+	case errors.Is(res.err, detector.ErrInputUnreachable):
+		res.err = streamUnreachableError{input: raw, err: res.err}
+	}
+	return res
+}
+
+// formatCheckedAt renders the time of a --live check for reports, or "" outside live mode.
+func formatCheckedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
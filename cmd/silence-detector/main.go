@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/wistia/silence-detector/pkg/detector"
@@ -21,216 +26,1848 @@ type outputFormat string
 const (
 	outputFormatText outputFormat = "text"
 	outputFormatJSON outputFormat = "json"
+	// outputFormatNDJSON writes one compact JSON record per line, streamed as each input finishes.
+	outputFormatNDJSON outputFormat = "ndjson"
+	// outputFormatAudacity writes an Audacity label track of the silence intervals.
+	outputFormatAudacity outputFormat = "audacity"
+	// outputFormatEDL writes a CMX3600 edit decision list of the silence intervals.
+	outputFormatEDL outputFormat = "edl"
+	// outputFormatSRT and outputFormatVTT write a SubRip or WebVTT subtitle cue per silence interval.
+	outputFormatSRT outputFormat = "srt"
+	outputFormatVTT outputFormat = "vtt"
+	// outputFormatChapters and outputFormatFFMetadata write chapters split at long silences, as YouTube description
+	// lines or as an FFMETADATA1 file.
+	outputFormatChapters   outputFormat = "chapters"
+	outputFormatFFMetadata outputFormat = "ffmetadata"
+	// outputFormatFCPXML and outputFormatResolveMarkers write a marker per silence interval for video editors, as
+	// FCPXML or as the marker EDL of DaVinci Resolve.
+	outputFormatFCPXML         outputFormat = "fcpxml"
+	outputFormatResolveMarkers outputFormat = "resolve-markers"
+	// outputFormatTrimCommand prints the ffmpeg command of --emit-trim-command instead of a report. It cannot be
+	// selected with --output.
+	outputFormatTrimCommand outputFormat = "trim-command"
+	// outputFormatSegments writes the concat list of --emit-segments and prints the command that plays it. It cannot
+	// be selected with --output.
+	outputFormatSegments outputFormat = "segments"
+	// outputFormatExpect compares the silence with the --expect report instead of reporting it. It cannot be selected
+	// with --output.
+	outputFormatExpect outputFormat = "expect"
+	// outputFormatPorcelain prints only the intervals of --porcelain, one tab-separated line each. It cannot be
+	// selected with --output.
+	outputFormatPorcelain outputFormat = "porcelain"
 )
 
+// Process exit codes. These values are part of the command-line interface and must not change; see the README.
+const (
+	// exitCodeOK means every input was analysed and passed every requested gate.
+	exitCodeOK = 0
+	// exitCodeError is used for unexpected failures, such as a report that cannot be written.
+	exitCodeError = 1
+	// exitCodeSilenceDetected is used with --fail-on-silence when any silence interval is detected.
+	exitCodeSilenceDetected = 2
+	// exitCodeFullSilence is used when --fail-on-fully-silent or --fail-if-not-fully-silent fails.
+	exitCodeFullSilence = 3
+	// exitCodeNoAudioStream is used when an input has no audio stream to analyse.
+	exitCodeNoAudioStream = 4
+	// exitCodeNoInputs is used when directory, glob and input-list inputs name no files.
+	exitCodeNoInputs = 5
+	// exitCodeLimitExceeded is used when a silence limit, --max-clipped-samples or --max-dc-offset is exceeded.
+	exitCodeLimitExceeded = 6
+	// exitCodeUsage is used for invalid flags and flag combinations.
+	exitCodeUsage = 7
+	// exitCodeInput is used when an input cannot be resolved, read or downloaded.
+	exitCodeInput = 8
+	// exitCodeFFmpeg is used when ffmpeg is missing or fails to analyse an input.
+	exitCodeFFmpeg = 9
+	// exitCodeTimeout is used when the --timeout limit is reached.
+	exitCodeTimeout = 10
+	// exitCodeStreamUnreachable is used when a --live stream cannot be connected to or stalls.
+	exitCodeStreamUnreachable = 11
+	// exitCodePartial is used with --allow-partial when ffmpeg failed partway and only part of an input was analysed.
+	exitCodePartial = 12
+	// exitCodeDifferent is used by compare when the silence of the two inputs differs.
+	exitCodeDifferent = 13
+)
+
+// exitCodeSeverity orders exit codes so that a multi-input run reports its most severe outcome: failures take
+// precedence over missing audio, which takes precedence over partial analyses and then verdicts.
+var exitCodeSeverity = map[int]int{
+	exitCodeOK:                0,
+	exitCodeSilenceDetected:   1,
+	exitCodeFullSilence:       2,
+	exitCodeLimitExceeded:     3,
+	exitCodePartial:           4,
+	exitCodeNoAudioStream:     5,
+	exitCodeInput:             6,
+	exitCodeStreamUnreachable: 7,
+	exitCodeFFmpeg:            8,
+	exitCodeTimeout:           9,
+	exitCodeError:             10,
+}
+
+// worseExitCode returns the more severe of two exit codes.
+func worseExitCode(a, b int) int {
+	if exitCodeSeverity[b] > exitCodeSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// fastSampleRate is the sample rate --fast resamples to.
+const fastSampleRate = 16000
+
+// silenceTolerance is the slack, in seconds, used when comparing interval boundaries against the input duration.
+const silenceTolerance = 1e-3
+
+// reportOptions carries the CLI settings that influence how a detection result is rendered.
+type reportOptions struct {
+	inputPath            string
+	noiseLevel           float64
+	noiseRatio           *float64
+	hysteresisDB         float64
+	minDuration          float64
+	checkFullSilence     bool
+	minCoverage          float64
+	fullSilenceTol       float64
+	checkLeadingSilence  bool
+	checkTrailingSilence bool
+	perChannel           bool
+	measureVolume        bool
+	measureLoudness      bool
+	adaptive             bool
+	detectBlack          bool
+	deadAir              bool
+	detectFreeze         bool
+	energyWindow         float64
+	quality              bool
+	noAudioStream        bool
+	// legacyJSON writes JSON in the layout of --legacy-json.
+	legacyJSON bool
+	// invertLabels labels the non-silent regions instead of the silence intervals with --output audacity and edl.
+	invertLabels bool
+	// edl holds the --reel-name and --drop-frame settings of --output edl.
+	edl detector.EDLOptions
+	// markers holds the timeline settings of --output fcpxml and resolve-markers.
+	markers detector.MarkerOptions
+	// subtitles holds the --cue-text of --output srt and vtt.
+	subtitles detector.SubtitleOptions
+	// chapterMinSilence and chapterSpacing are the --chapter-min-silence and --chapter-spacing of --output chapters
+	// and ffmetadata.
+	chapterMinSilence float64
+	chapterSpacing    float64
+	// trim holds the settings of --emit-trim-command and --emit-segments.
+	trim trimCommand
+	// histogram holds the bounds of --histogram, whose duration classes are added to text and JSON reports; nil
+	// without --histogram.
+	histogram []float64
+	// timeline holds the width and style of --timeline, drawn under text reports; nil without --timeline.
+	timeline *detector.TimelineOptions
+	// at is the position of --at, reported after the report of a single input; nil without --at.
+	at *float64
+	// expect holds the expected silence of --expect.
+	expect expectation
+	// times is how times and durations are rendered; nil selects the defaults.
+	times *timeFormat
+	// live reports on the window of a --live stream, checked at checkedAt.
+	live      bool
+	checkedAt time.Time
+}
+
+// inputList collects repeated --input flags.
+type inputList []string
+
+func (l *inputList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *inputList) Set(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("input must not be empty")
+	}
+	*l = append(*l, value)
+	return nil
+}
+
+// headerList collects repeated --header flags.
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Set(value string) error {
+	name, _, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q must have the form \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// httpHeader converts the collected flags into an http.Header.
+func (h headerList) httpHeader() http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	header := make(http.Header, len(h))
+	for _, entry := range h {
+		name, value, _ := strings.Cut(entry, ":")
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return header
+}
+
+// ffmpegArgList collects repeated --ffmpeg-arg flags. Each flag is a single ffmpeg argument prefixed with input: or
+// output: to place it before -i or before the null output.
+type ffmpegArgList struct {
+	input  []string
+	output []string
+}
+
+func (l *ffmpegArgList) String() string {
+	return strings.Join(append(append([]string(nil), l.input...), l.output...), " ")
+}
+
+func (l *ffmpegArgList) Set(value string) error {
+	position, arg, ok := strings.Cut(value, ":")
+	switch {
+	case ok && position == "input":
+		l.input = append(l.input, arg)
+	case ok && position == "output":
+		l.output = append(l.output, arg)
+	default:
+		return fmt.Errorf("ffmpeg argument %q must start with input: or output:", value)
+	}
+	return nil
+}
+
+// windowFlag holds --window, given as a duration such as 30s or in seconds such as 0.5: --live checks last tens of
+// seconds, while --energy-timeline windows are fractions of a second.
+type windowFlag time.Duration
+
+func (w *windowFlag) String() string {
+	return time.Duration(*w).String()
+}
+
+func (w *windowFlag) Set(value string) error {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		*w = windowFlag(seconds * float64(time.Second))
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid window %q: give a duration such as 30s or seconds such as 0.5", value)
+	}
+	*w = windowFlag(duration)
+	return nil
+}
+
+// positionFlag holds --at, a position in the input given in seconds such as 754.5 or as a clock time such as
+// 00:12:34.5 or 12:34.5.
+type positionFlag struct {
+	seconds float64
+	set     bool
+}
+
+func (p *positionFlag) String() string {
+	if !p.set {
+		return ""
+	}
+	return strconv.FormatFloat(p.seconds, 'f', -1, 64)
+}
+
+func (p *positionFlag) Set(value string) error {
+	invalid := fmt.Errorf("invalid position %q: give seconds such as 754.5 or a time such as 00:12:34.5", value)
+	fields := strings.Split(value, ":")
+	if len(fields) > 3 {
+		return invalid
+	}
+
+	var seconds float64
+	for i, field := range fields {
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 {
+			return invalid
+		}
+		last := i == len(fields)-1
+		if (!last && n != math.Trunc(n)) || (i > 0 && n >= 60) {
+			return invalid
+		}
+		seconds = seconds*60 + n
+	}
+	*p = positionFlag{seconds: seconds, set: true}
+	return nil
+}
+
+// needsFFmpeg reports whether a run with cfg may execute ffmpeg, and so whether it is checked first. Only
+// --engine native without extra passes does without it.
+func needsFFmpeg(cfg analysisConfig) bool {
+	return cfg.detection.Engine != detector.EngineNative || cfg.black != nil || cfg.freeze != nil || cfg.energyWindow > 0 || cfg.quality
+}
+
+// validOptions validates the detection options, once per threshold of a --silence-noise sweep, and prints every
+// problem found on its own line.
+func validOptions(options detector.DetectionOptions, sweep []float64) bool {
+	err := options.Validate()
+	for _, level := range sweep {
+		if err != nil {
+			break
+		}
+		options.NoiseLevel = level
+		err = options.Validate()
+	}
+	if err == nil {
+		return true
+	}
+
+	problems := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		problems = joined.Unwrap()
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "invalid options: %v\n", problem)
+	}
+	return false
+}
+
+// flagSources records where each flag that did not keep its default got its value, as returned by mergeSettings.
+// flag.Visit cannot tell, since settings from the environment and the config file are applied with flag.Set too.
+var flagSources map[string]string
+
+// flagGiven reports whether the named flag was set, on the command line, in the environment or in the config file,
+// for flags whose default is a valid value.
+func flagGiven(name string) bool {
+	return flagSources[name] != ""
+}
+
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		return runServe(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		return runCompare(os.Args[2:])
+	}
+	args := os.Args[1:]
+	printConfig := len(args) > 0 && args[0] == "config"
+	if printConfig {
+		if len(args) < 2 || args[1] != "print" {
+			fmt.Fprintln(os.Stderr, "usage: silence-detector config print [flags]")
+			return exitCodeUsage
+		}
+		args = args[2:]
+	}
+
 	var (
-		inputPath        = flag.String("input", "", "Path to the input media file (required)")
-		noiseLevel       = flag.Float64("silence-noise", -30, "Silence noise threshold in dB")
+		inputs           inputList
+		inputFormat      = flag.String("input-format", "", "Force the input container format (ffmpeg -f), e.g. for raw audio piped on stdin")
+		pcmFormat        = flag.String("pcm-format", "", "Read the input as headerless PCM with this sample format, such as s16le or f32le; requires --pcm-rate and --pcm-channels")
+		pcmRate          = flag.Int("pcm-rate", 0, "Sample rate in Hz of --pcm-format input")
+		pcmChannels      = flag.Int("pcm-channels", 0, "Channel count of --pcm-format input")
+		hysteresisDB     = flag.Float64("hysteresis-db", 0, "Close a silence only once the audio rises this many dB above the silence threshold, so that audio hovering around it does not split the silence (reads the input twice)")
+		adaptive         = flag.Bool("adaptive", false, "Measure the noise floor in a first pass and detect silence below floor + --adaptive-offset-db (--silence-noise is the fallback for digital silence)")
+		adaptiveOffset   = flag.Float64("adaptive-offset-db", 10, "Offset above the measured noise floor used as the threshold with --adaptive")
+		noiseRatio       = flag.Float64("silence-noise-ratio", 0, "Silence noise threshold as an amplitude ratio in (0, 1], such as 0.001 for -60dB, instead of --silence-noise")
 		minDuration      = flag.Float64("silence-duration", 0.5, "Minimum silence duration in seconds")
-		format           = flag.String("output", string(outputFormatText), "Output format: text or json")
+		format           = flag.String("output", string(outputFormatText), "Output format: text, json, ndjson, audacity (a label track of the silence intervals), edl (a CMX3600 EDL, requires --fps), srt or vtt (a subtitle cue per silence interval), fcpxml or resolve-markers (editor markers, require --fps and --timeline-name), chapters (YouTube chapters split at long silences) or ffmetadata (the same chapters for ffmpeg)")
+		chapterSilence   = flag.Float64("chapter-min-silence", 2, "Seconds a silence must exceed to start a chapter with --output chapters and ffmetadata")
+		chapterSpacing   = flag.Float64("chapter-spacing", 10, "Minimum seconds between chapter starts with --output chapters and ffmetadata")
+		emitTrim         = flag.Bool("emit-trim-command", false, "Print an ffmpeg command that removes the detected silence from the input instead of a report")
+		emitSegments     = flag.String("emit-segments", "", "Write an ffmpeg concat list of the non-silent segments of the input to this file, and print the ffmpeg command that joins them, instead of a report")
+		minSegment       = flag.Float64("min-segment-duration", 0, "Seconds a non-silent segment must last to be kept by --emit-segments")
+		trimPadding      = flag.Float64("trim-padding", 0, "Seconds of silence --emit-trim-command and --emit-segments keep around each non-silent region")
+		expectFile       = flag.String("expect", "", "Compare the detected silence with this JSON report, such as one written by --write-expect, print the differences instead of a report and exit with code 13 when they differ")
+		expectTolerance  = flag.Float64("expect-tolerance", 0.05, "Seconds the start or end of a silence may move from --expect before it counts as a difference")
+		writeExpect      = flag.String("write-expect", "", "Also write the JSON report of the input to this file, to be checked later with --expect")
+		cueText          = flag.String("cue-text", "SILENCE", "Text of --output srt and vtt cues; {index} is replaced by the cue number and {duration} by the silence duration in seconds")
+		labels           = flag.String("labels", "silence", "Regions exported by --output audacity and edl: silence, or invert for the non-silent regions")
+		reelName         = flag.String("reel-name", "AX", "Source reel name of --output edl events, at most 8 characters")
+		dropFrame        = flag.Bool("drop-frame", false, "Write drop-frame timecode in --output edl, fcpxml and resolve-markers; requires --fps 29.97 or 59.94")
+		timelineName     = flag.String("timeline-name", "", "Name of the timeline --output fcpxml and resolve-markers create markers for")
+		timelineStart    = flag.String("timeline-start", "01:00:00:00", "Timecode of the first frame of the --timeline-name timeline")
+		showTimeline     = flag.Bool("timeline", false, "Draw where the silence falls as a bar under the text report, with a scale in minutes")
+		timelineWidth    = flag.Int("timeline-width", detector.DefaultTimelineWidth, "Width in characters of the --timeline bar")
+		timelineStyle    = flag.String("timeline-style", string(detector.TimelineASCII), "Characters of the --timeline bar: ascii (. for silence, # for sound) or blocks (Unicode shades)")
+		precision        = flag.Int("precision", defaultPrecision, "Decimal places of times and durations in text and JSON reports")
+		timeFormatFlag   = flag.String("time-format", string(timeStyleSeconds), "Format of times and durations in text reports: seconds, clock (HH:MM:SS.mmm) or smpte (HH:MM:SS:FF, requires --fps)")
+		fps              = flag.Float64("fps", 0, "Frame rate of --time-format smpte timecodes and --output edl, fcpxml and resolve-markers, e.g. 25 or 29.97")
+		legacyJSON       = flag.Bool("legacy-json", false, "Write JSON reports in the layout of earlier releases, with Start, End and Duration interval fields and no schema_version (deprecated; will be removed in the next release)")
 		ffmpegBinary     = flag.String("ffmpeg", "ffmpeg", "Path to the ffmpeg binary")
+		ffprobeBinary    = flag.String("ffprobe", "ffprobe", "Path to the ffprobe binary used to determine the input duration (empty to disable)")
 		checkFullSilence = flag.Bool("check-full-silence", false, "Report whether the entire input is silent")
+		fullSilenceTol   = flag.Float64("full-silence-tolerance", silenceTolerance, "Seconds of sound tolerated before, between and after the silence of a fully silent input, such as an encoder's priming delay")
+		minCoverage      = flag.Float64("min-silence-coverage", 0, "With --check-full-silence, also count an input as silent when silence covers at least this fraction of it (such as 0.99); the --fail-* full-silence gates then use this verdict")
+		failOnSilence    = flag.Bool("fail-on-silence", false, "Exit with code 2 when any silence interval is detected")
+		failFullySilent  = flag.Bool("fail-on-fully-silent", false, "Exit with code 3 when the entire input is silent (implies --check-full-silence)")
+		failNotSilent    = flag.Bool("fail-if-not-fully-silent", false, "Exit with code 3 unless the entire input is silent (implies --check-full-silence)")
+		checkLeading     = flag.Bool("check-leading-silence", false, "Report the duration of silence at the start of the input")
+		checkTrailing    = flag.Bool("check-trailing-silence", false, "Report the duration of silence at the end of the input")
+		maxLeading       = flag.Float64("max-leading-silence", 0, "Fail when leading silence exceeds this many seconds (0 disables the check)")
+		maxTrailing      = flag.Float64("max-trailing-silence", 0, "Fail when trailing silence exceeds this many seconds (0 disables the check)")
+		startOffset      = flag.Float64("start", 0, "Skip this many seconds of the input before analysing")
+		analyzeDuration  = flag.Float64("analyze-duration", 0, "Analyse at most this many seconds of the input (0 analyses to the end)")
+		knownDuration    = flag.Float64("duration", 0, "Duration of the input in seconds, if already known: used instead of ffprobe and ffmpeg's progress, with a warning when ffmpeg reads past it (0 determines it)")
+		audioStream      = flag.Int("audio-stream", -1, "Index of the audio stream to analyse (default: ffmpeg's choice)")
+		audioLanguage    = flag.String("audio-language", "", "Language tag of the audio stream to analyse, such as eng, resolved with ffprobe (default: ffmpeg's choice)")
+		allStreams       = flag.Bool("all-audio-streams", false, "Analyse every audio stream of the input, as listed by ffprobe, and report each one in its own section")
+		hlsRendition     = flag.Int("hls-audio-rendition", -1, "Index of the audio rendition of an HLS playlist to analyse, in playlist order (default: ffmpeg's choice)")
+		measureVolume    = flag.Bool("measure-volume", false, "Report the mean and max volume (ffmpeg volumedetect) in the same pass")
+		measureLevels    = flag.Bool("measure-intervals", false, fmt.Sprintf("Measure the mean and peak volume of each silence interval (the first %d) in extra ffmpeg runs that seek to it", detector.MaxMeasuredIntervals))
+		measureLoudness  = flag.Bool("measure-loudness", false, "Report EBU R128 integrated loudness, loudness range and true peak (ffmpeg ebur128) in the same pass")
+		detectBlack      = flag.Bool("detect-black", false, "Also detect black video frames (ffmpeg blackdetect) and report them")
+		deadAir          = flag.Bool("dead-air", false, "Report dead air, the periods that are both silent and black (implies --detect-black)")
+		blackDuration    = flag.Float64("black-duration", 2, "Minimum black duration in seconds for --detect-black")
+		blackRatio       = flag.Float64("black-ratio", 0.98, "Fraction of black pixels above which a frame counts as black for --detect-black")
+		energyTimeline   = flag.Bool("energy-timeline", false, "Also measure the RMS level of consecutive --window windows (0.5 seconds unless given) and report them as a timeline (samples in JSON)")
+		quality          = flag.Bool("quality", false, "Also report clipping and DC offset (ffmpeg astats) in an extra ffmpeg run")
+		maxClipped       = flag.Int64("max-clipped-samples", -1, "Fail when more samples than this are clipped at 0 dBFS (negative disables the check; implies --quality)")
+		maxDCOffset      = flag.Float64("max-dc-offset", 0, "Fail when the DC offset of any channel exceeds this fraction of full scale (0 disables the check; implies --quality)")
+		detectFreeze     = flag.Bool("detect-freeze", false, "Also detect frozen video (ffmpeg freezedetect) and report it")
+		freezeDuration   = flag.Float64("freeze-duration", 2, "Minimum freeze duration in seconds for --detect-freeze")
+		freezeNoise      = flag.Float64("freeze-noise", -60, "Noise tolerance in dB below which frames count as identical for --detect-freeze")
+		perChannel       = flag.Bool("per-channel", false, "Detect silence on every audio channel independently")
+		downmixMono      = flag.Bool("downmix-mono", false, "Mix all channels down to mono before detection")
+		sampleRate       = flag.Int("sample-rate", 0, "Resample the audio to this many Hz before detection (0 keeps the input rate)")
+		fast             = flag.Bool("fast", false, "Trade a little precision for speed: implies --downmix-mono (unless --per-channel) and --sample-rate 16000")
+		engine           = flag.String("engine", string(detector.EngineAuto), "Silence detection engine: ffmpeg, native (reads PCM and float WAV files in Go, without ffmpeg) or auto (native when the input and options allow it, ffmpeg otherwise)")
+		maxIntervals     = flag.Int("max-intervals", 0, "Stop ffmpeg once this many silence intervals were found and mark the report as truncated (0 means no limit)")
+		allowPartial     = flag.Bool("allow-partial", false, "When ffmpeg fails partway, report the silence found up to that point with a warning and exit with code 12 instead of failing")
+		mergeGap         = flag.Float64("merge-gap", 0, "Merge silence intervals separated by at most this many seconds")
+		noNormalize      = flag.Bool("no-normalize", false, "Report silence intervals exactly as ffmpeg printed them, without sorting them, clamping them to the input duration or dropping empty and overlapping ones")
+		reportMin        = flag.Float64("report-min-duration", 0, "Only report silence intervals lasting at least this many seconds")
+		reportMax        = flag.Float64("report-max-duration", 0, "Only report silence intervals lasting at most this many seconds (0 means no upper bound)")
+		padStart         = flag.Float64("pad-start", 0, "Shrink every reported silence interval by this many seconds at its start, so cuts do not clip speech (negative values grow it)")
+		padEnd           = flag.Float64("pad-end", 0, "Shrink every reported silence interval by this many seconds at its end (negative values grow it)")
+		noDownload       = flag.Bool("no-download", false, "Let ffmpeg read HTTP(S) inputs directly instead of downloading them first")
+		live             = flag.Bool("live", false, "Check whether a live stream is silent right now: analyse --window seconds of it and fail with exit code 3 when all of it is silent")
+		interval         = flag.Duration("interval", 0, "Repeat the --live check this often until interrupted (0 checks once)")
+		forceDownload    = flag.Bool("force-download", false, "Download HLS (.m3u8) and DASH (.mpd) manifests too instead of letting ffmpeg read them from their URL")
+		downloadRetries  = flag.Int("download-retries", 3, "Number of times to retry a failed download of a remote input")
+		rawOutputFile    = flag.String("raw-output-file", "", "Write the last 256 KB of ffmpeg's output for each input to this file")
+		verbose          = flag.Bool("verbose", false, "Log ffmpeg and ffprobe runs, with secrets redacted, to stderr")
+		quiet            = flag.Bool("quiet", false, "Print only results and errors: no notices about fallbacks, gate violations or progress on stderr")
+		porcelain        = flag.Bool("porcelain", false, "Print only the silence intervals of a single input, one start<TAB>end<TAB>duration line each in seconds, instead of the text report")
+		dryRun           = flag.Bool("dry-run", false, "Print the ffmpeg command for each input without running it")
+		timeout          = flag.Duration("timeout", 0, "Time limit for the whole run, including downloads and ffmpeg (0 disables the limit)")
+		downloadTimeout  = flag.Duration("download-timeout", 10*time.Minute, "Overall time limit for downloading a remote input, including retries (0 disables the limit)")
+		maxDownloadSize  = flag.Int64("max-download-size", 0, "Abort downloads of remote inputs larger than this many bytes (0 means unlimited)")
+		s3Endpoint       = flag.String("s3-endpoint", "", "Base URL of an S3-compatible store (e.g. MinIO) used for s3:// inputs")
+		s3Region         = flag.String("s3-region", "", "Region of the bucket for s3:// inputs (default: discovered from the bucket)")
+		gcsEndpoint      = flag.String("gcs-endpoint", "", "Base URL of a Cloud Storage emulator used for gs:// inputs")
+		inputListPath    = flag.String("input-list", "", "File listing one input per line (- reads the list from stdin); blank lines and # comments are ignored")
+		recursive        = flag.Bool("recursive", false, "Descend into subdirectories of directory inputs")
+		watchDir         = flag.String("watch", "", "Watch this directory and analyse files with an --ext extension as they arrive, until interrupted")
+		settle           = flag.Duration("settle", 5*time.Second, "How long a --watch file must stop changing before it is analysed")
+		extensions       = flag.String("ext", defaultMediaExtensions, "Comma-separated file extensions picked up from directory inputs")
+		outputFile       = flag.String("output-file", "-", "Write the report to this file instead of stdout (- means stdout); the file is replaced atomically")
+		outputDir        = flag.String("output-dir", "", "Write one report file per input into this directory, named after the input")
+		mkdir            = flag.Bool("mkdir", false, "Create missing parent directories of --output-file and --output-dir")
+		parallel         = flag.Int("parallel", 1, "Number of inputs to analyse concurrently when several are given")
+		bearerTokenEnv   = flag.String("bearer-token-env", "", "Name of an environment variable holding a bearer token to send with remote input requests")
+		callbackURL      = flag.String("callback-url", "", "POST the JSON result of each input to this URL as it is analysed")
+		callbackSecret   = flag.String("callback-secret", "", "Sign --callback-url bodies with HMAC-SHA256 using this secret (X-Silence-Detector-Signature header)")
+		metricsListen    = flag.String("metrics-listen", "", "Serve Prometheus metrics on /metrics at this address (e.g. :9090) while the command runs")
+		configFile       = flag.String("config", "", "Read flags from this YAML file of flag: value keys (default ./"+defaultConfigFile+" when it exists; empty reads none); command-line flags and SILENCE_DETECTOR_* environment variables take precedence")
+		trace            = flag.Bool("trace", false, "Export OpenTelemetry traces over OTLP/HTTP (JSON) as configured by the standard OTEL_* environment variables")
+		headers          headerList
+		ffmpegArgs       ffmpegArgList
+		noiseLevels      = thresholdList{-30}
+		window           = windowFlag(30 * time.Second)
+		at               positionFlag
+		histogram        histogramFlag
 	)
+	flag.Var(&inputs, "input", "Path or URL of the input media, or - to read from stdin; repeatable, and further inputs may be given as arguments")
+	flag.Var(&noiseLevels, "silence-noise", "Silence noise threshold in dB; a comma-separated list such as -20,-30,-40 compares several thresholds")
+	flag.Var(&ffmpegArgs, "ffmpeg-arg", "Extra ffmpeg argument, prefixed with input: (placed before -i) or output: (placed before the output); repeatable, one argument per flag")
+	flag.Var(&histogram, "histogram", "Also count the silence intervals, and their total duration, in duration classes split at these comma-separated seconds, such as 2,10 for pauses, long pauses and dead air")
+	flag.Var(&at, "at", "Also report whether this position of the input, in seconds such as 754.5 or as a time such as 00:12:34.5, lies in a silence interval")
+	flag.Var(&window, "window", "Length of the stream analysed by each --live check, or of the windows of --energy-timeline; a duration such as 30s or seconds such as 0.5")
+	flag.Var(&headers, "header", "HTTP request header (\"Name: value\") sent with remote input requests; repeatable")
 
-	flag.Parse()
+	// Parse errors are reported with exitCodeUsage rather than the flag package's default of 2, which is reserved for
+	// --fail-on-silence.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.CommandLine.Usage = usage(flag.CommandLine)
+	if err := flag.CommandLine.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCodeOK
+		}
+		return exitCodeUsage
+	}
 
-	if *inputPath == "" {
-		fmt.Fprintln(os.Stderr, "--input flag is required")
-		flag.Usage()
-		os.Exit(1)
+	// Flags not given on the command line fall back to SILENCE_DETECTOR_* environment variables, then to the config
+	// file, then to their defaults. Positional inputs count as giving --input.
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if flag.NArg() > 0 {
+		given["input"] = true
+	}
+	configPath := findConfig(*configFile, given["config"], os.LookupEnv)
+	settings, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeUsage
+	}
+	sources, err := mergeSettings(flag.CommandLine, given, settings, configPath, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeUsage
+	}
+	flagSources = sources
+	if printConfig {
+		emitConfig(os.Stdout, flag.CommandLine, sources, configPath)
+		return exitCodeOK
 	}
 
-	originalInput := strings.TrimSpace(*inputPath)
-	resolvedInput := originalInput
-	var cleanup func()
+	// Set before inputs are expanded, whose notices --quiet discards too.
+	if *quiet {
+		notices = io.Discard
+	}
 
-	if isRemoteInput(resolvedInput) {
-		downloadedPath, c, err := downloadRemoteInput(resolvedInput)
+	inputs = append(inputs, flag.Args()...)
+	if *inputListPath != "" {
+		listed, err := readInputList(*inputListPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to download input %q: %v\n", originalInput, err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeInput
+		}
+		if len(listed) == 0 {
+			fmt.Fprintf(os.Stderr, "input list %q contains no inputs\n", *inputListPath)
+			return exitCodeNoInputs
 		}
-		resolvedInput = downloadedPath
-		cleanup = c
+		inputs = append(inputs, listed...)
+	}
+	if *watchDir != "" && len(inputs) > 0 {
+		fmt.Fprintln(os.Stderr, "--watch analyses the files arriving in a directory and cannot be combined with other inputs")
+		return exitCodeUsage
+	}
+	if len(inputs) == 0 && *watchDir == "" {
+		fmt.Fprintln(os.Stderr, "--input, --input-list or --watch is required")
+		flag.Usage()
+		return exitCodeUsage
 	}
 
-	if cleanup != nil {
-		defer cleanup()
+	mediaExtensions := parseExtensions(*extensions)
+	if len(mediaExtensions) == 0 {
+		fmt.Fprintln(os.Stderr, "--ext must list at least one extension")
+		return exitCodeUsage
+	}
+
+	inputs, expanded, err := expandInputs(inputs, *recursive, mediaExtensions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeInput
+	}
+	if len(inputs) == 0 && *watchDir == "" {
+		fmt.Fprintln(os.Stderr, "no inputs to analyse")
+		return exitCodeNoInputs
 	}
 
-	if info, err := os.Stat(resolvedInput); err != nil {
-		if cleanup != nil {
-			cleanup()
+	stdinInputs := 0
+	for _, input := range inputs {
+		if strings.TrimSpace(input) == "-" {
+			stdinInputs++
 		}
-		fmt.Fprintf(os.Stderr, "failed to stat input %q: %v\n", originalInput, err)
-		os.Exit(1)
-	} else if info.IsDir() {
-		if cleanup != nil {
-			cleanup()
+	}
+	if stdinInputs > 1 || (stdinInputs > 0 && *inputListPath == "-") {
+		fmt.Fprintln(os.Stderr, "stdin (-) may only be used once, either as an input or as the input list")
+		return exitCodeUsage
+	}
+	if stdinInputs > 0 && *adaptive {
+		fmt.Fprintln(os.Stderr, "--adaptive reads the input twice and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+	if stdinInputs > 0 && *energyTimeline {
+		fmt.Fprintln(os.Stderr, "--energy-timeline reads the input again and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+	checkQuality := *quality || *maxClipped >= 0 || *maxDCOffset > 0
+	if stdinInputs > 0 && checkQuality {
+		fmt.Fprintln(os.Stderr, "--quality reads the input again and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+	if stdinInputs > 0 && *measureLevels {
+		fmt.Fprintln(os.Stderr, "--measure-intervals seeks to each interval and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+	if stdinInputs > 0 && *hysteresisDB > 0 {
+		fmt.Fprintln(os.Stderr, "--hysteresis-db reads the input twice and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+
+	black := *detectBlack || *deadAir
+	if stdinInputs > 0 && (black || *detectFreeze) {
+		fmt.Fprintln(os.Stderr, "--detect-black, --dead-air and --detect-freeze read the input again and cannot be used with stdin (-)")
+		return exitCodeUsage
+	}
+
+	noiseLevel := noiseLevels[0]
+	var sweep []float64
+	if len(noiseLevels) > 1 {
+		sweep = noiseLevels
+		switch {
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "":
+			fmt.Fprintln(os.Stderr, "a --silence-noise sweep analyses a single input")
+			return exitCodeUsage
+		case stdinInputs > 0 || *adaptive:
+			fmt.Fprintln(os.Stderr, "a --silence-noise sweep reads the input once per threshold and cannot be used with stdin (-) or --adaptive")
+			return exitCodeUsage
+		case black || *detectFreeze || checkQuality:
+			fmt.Fprintln(os.Stderr, "a --silence-noise sweep cannot be combined with --detect-black, --dead-air, --detect-freeze or --quality")
+			return exitCodeUsage
+		case *failOnSilence || *failFullySilent || *failNotSilent || *maxLeading > 0 || *maxTrailing > 0:
+			fmt.Fprintln(os.Stderr, "a --silence-noise sweep compares thresholds and cannot be combined with --fail-* gates or silence limits")
+			return exitCodeUsage
+		}
+	}
+	var noiseRatioValue *float64
+	if flagGiven("silence-noise-ratio") {
+		if flagGiven("silence-noise") {
+			fmt.Fprintln(os.Stderr, "--silence-noise-ratio cannot be combined with --silence-noise")
+			return exitCodeUsage
+		}
+		noiseLevel, noiseRatioValue = 0, noiseRatio
+	}
+	if *allStreams {
+		switch {
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "":
+			fmt.Fprintln(os.Stderr, "--all-audio-streams analyses a single input")
+			return exitCodeUsage
+		case stdinInputs > 0 || *live:
+			fmt.Fprintln(os.Stderr, "--all-audio-streams reads the input once per stream and cannot be used with stdin (-) or --live")
+			return exitCodeUsage
+		case *ffprobeBinary == "":
+			fmt.Fprintln(os.Stderr, "--all-audio-streams lists the audio streams with ffprobe, which --ffprobe \"\" disables")
+			return exitCodeUsage
+		case *audioStream >= 0 || *hlsRendition >= 0 || *audioLanguage != "" || len(sweep) > 0:
+			fmt.Fprintln(os.Stderr, "--all-audio-streams cannot be combined with --audio-stream, --audio-language, --hls-audio-rendition or a --silence-noise sweep")
+			return exitCodeUsage
+		case black || *detectFreeze || checkQuality:
+			fmt.Fprintln(os.Stderr, "--all-audio-streams cannot be combined with --detect-black, --dead-air, --detect-freeze or --quality")
+			return exitCodeUsage
+		case *failOnSilence || *failFullySilent || *failNotSilent || *maxLeading > 0 || *maxTrailing > 0:
+			fmt.Fprintln(os.Stderr, "--all-audio-streams reports every stream and cannot be combined with --fail-* gates or silence limits")
+			return exitCodeUsage
+		}
+	}
+	if detector.Engine(*engine) == detector.EngineNative {
+		switch {
+		case stdinInputs > 0:
+			fmt.Fprintln(os.Stderr, "--engine native reads WAV files and cannot be used with stdin (-)")
+			return exitCodeUsage
+		case *allStreams:
+			fmt.Fprintln(os.Stderr, "--engine native reads the first audio stream only and cannot be combined with --all-audio-streams")
+			return exitCodeUsage
+		}
+	}
+
+	var rawPCM *detector.RawPCMFormat
+	if flagGiven("pcm-format") || flagGiven("pcm-rate") || flagGiven("pcm-channels") {
+		switch {
+		case black || *detectFreeze || *energyTimeline || checkQuality:
+			fmt.Fprintln(os.Stderr, "--pcm-format input cannot be combined with --detect-black, --dead-air, --detect-freeze, --energy-timeline or --quality")
+			return exitCodeUsage
+		case *allStreams:
+			fmt.Fprintln(os.Stderr, "--pcm-format input holds a single audio stream and cannot be combined with --all-audio-streams")
+			return exitCodeUsage
+		}
+		rawPCM = &detector.RawPCMFormat{SampleRate: *pcmRate, Channels: *pcmChannels, SampleFormat: *pcmFormat}
+	}
+
+	if *knownDuration != 0 {
+		switch {
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "" || *watchDir != "":
+			fmt.Fprintln(os.Stderr, "--duration is the duration of a single input")
+			return exitCodeUsage
+		case *live:
+			fmt.Fprintln(os.Stderr, "--duration cannot be combined with --live, whose --window sets the duration")
+			return exitCodeUsage
+		}
+	}
+
+	if *parallel < 1 {
+		fmt.Fprintln(os.Stderr, "--parallel must be at least 1")
+		return exitCodeUsage
+	}
+
+	if *noDownload && *forceDownload {
+		fmt.Fprintln(os.Stderr, "--no-download cannot be used with --force-download")
+		return exitCodeUsage
+	}
+
+	if *watchDir != "" {
+		switch {
+		case *settle < 0:
+			fmt.Fprintln(os.Stderr, "--settle must not be negative")
+			return exitCodeUsage
+		case *live || *dryRun || len(noiseLevels) > 1 || *outputDir != "":
+			fmt.Fprintln(os.Stderr, "--watch cannot be combined with --live, --dry-run, a --silence-noise sweep or --output-dir")
+			return exitCodeUsage
+		case *timeout > 0:
+			// --timeout bounds the whole run, which a watch never finishes; it would only end the watch, silently.
+			fmt.Fprintln(os.Stderr, "--timeout limits the whole run and cannot be combined with --watch, which runs until interrupted")
+			return exitCodeUsage
+		}
+	}
+
+	if *live {
+		switch {
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "":
+			fmt.Fprintln(os.Stderr, "--live monitors a single input")
+			return exitCodeUsage
+		case window <= 0 || *interval < 0:
+			fmt.Fprintln(os.Stderr, "--window must be greater than zero and --interval must not be negative")
+			return exitCodeUsage
+		case *startOffset > 0 || *analyzeDuration > 0:
+			fmt.Fprintln(os.Stderr, "--live analyses --window seconds from the live edge and cannot be combined with --start or --analyze-duration")
+			return exitCodeUsage
+		case *adaptive || len(noiseLevels) > 1 || black || *detectFreeze || *energyTimeline || checkQuality || *forceDownload:
+			fmt.Fprintln(os.Stderr, "--live reads the stream once and cannot be combined with --adaptive, a --silence-noise sweep, --detect-black, --dead-air, --detect-freeze, --energy-timeline, --quality or --force-download")
+			return exitCodeUsage
+		}
+		*analyzeDuration = time.Duration(window).Seconds()
+		if !*failNotSilent {
+			*failFullySilent = true
+		}
+	} else if *interval != 0 {
+		fmt.Fprintln(os.Stderr, "--interval requires --live")
+		return exitCodeUsage
+	}
+
+	if *callbackURL != "" {
+		if err := validateCallbackURL(*callbackURL); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeUsage
+		}
+		if *live || len(noiseLevels) > 1 {
+			fmt.Fprintln(os.Stderr, "--callback-url cannot be combined with --live or a --silence-noise sweep")
+			return exitCodeUsage
+		}
+	} else if *callbackSecret != "" {
+		fmt.Fprintln(os.Stderr, "--callback-secret requires --callback-url")
+		return exitCodeUsage
+	}
+
+	if *blackDuration <= 0 || *blackRatio <= 0 || *blackRatio > 1 {
+		fmt.Fprintln(os.Stderr, "--black-duration must be greater than zero and --black-ratio between 0 and 1")
+		return exitCodeUsage
+	}
+
+	var energyWindow float64
+	if *energyTimeline {
+		energyWindow = 0.5
+		if flagGiven("window") {
+			energyWindow = time.Duration(window).Seconds()
+		}
+		if energyWindow <= detector.MinEnergyWindow {
+			fmt.Fprintf(os.Stderr, "--window must be longer than %gs with --energy-timeline\n", detector.MinEnergyWindow)
+			return exitCodeUsage
 		}
-		fmt.Fprintf(os.Stderr, "input %q is a directory, expected a file\n", resolvedInput)
-		os.Exit(1)
 	}
 
-	if *minDuration <= 0 {
-		fmt.Fprintln(os.Stderr, "--silence-duration must be greater than zero")
-		os.Exit(1)
+	if *maxDCOffset < 0 || *maxDCOffset > 1 {
+		fmt.Fprintln(os.Stderr, "--max-dc-offset must be between 0 and 1")
+		return exitCodeUsage
+	}
+
+	if *freezeDuration <= 0 || *freezeNoise >= 0 {
+		fmt.Fprintln(os.Stderr, "--freeze-duration must be greater than zero and --freeze-noise negative")
+		return exitCodeUsage
+	}
+
+	if *mergeGap < 0 {
+		fmt.Fprintln(os.Stderr, "--merge-gap must not be negative")
+		return exitCodeUsage
+	}
+
+	if *maxIntervals < 0 {
+		fmt.Fprintln(os.Stderr, "--max-intervals must not be negative")
+		return exitCodeUsage
+	}
+
+	if *reportMin < 0 || *reportMax < 0 {
+		fmt.Fprintln(os.Stderr, "--report-min-duration and --report-max-duration must not be negative")
+		return exitCodeUsage
+	}
+
+	if *failFullySilent && *failNotSilent {
+		fmt.Fprintln(os.Stderr, "--fail-on-fully-silent and --fail-if-not-fully-silent cannot be used together")
+		return exitCodeUsage
+	}
+
+	if *fullSilenceTol < 0 {
+		fmt.Fprintln(os.Stderr, "--full-silence-tolerance must not be negative")
+		return exitCodeUsage
+	}
+
+	if *minCoverage < 0 || *minCoverage > 1 {
+		fmt.Fprintln(os.Stderr, "--min-silence-coverage must be between 0 and 1")
+		return exitCodeUsage
+	}
+	if *minCoverage > 0 && !*checkFullSilence && !*failFullySilent && !*failNotSilent {
+		fmt.Fprintln(os.Stderr, "--min-silence-coverage requires --check-full-silence, --fail-on-fully-silent or --fail-if-not-fully-silent")
+		return exitCodeUsage
+	}
+
+	if *reportMax > 0 && *reportMin > *reportMax {
+		fmt.Fprintln(os.Stderr, "--report-min-duration must not exceed --report-max-duration")
+		return exitCodeUsage
+	}
+
+	if *maxLeading < 0 || *maxTrailing < 0 {
+		fmt.Fprintln(os.Stderr, "--max-leading-silence and --max-trailing-silence must not be negative")
+		return exitCodeUsage
+	}
+
+	// A NaN or infinite pad would drop every interval and so pass --fail-on-silence.
+	if math.IsNaN(*padStart) || math.IsInf(*padStart, 0) || math.IsNaN(*padEnd) || math.IsInf(*padEnd, 0) {
+		fmt.Fprintln(os.Stderr, "--pad-start and --pad-end must be finite")
+		return exitCodeUsage
+	}
+
+	if *chapterSilence < 0 || *chapterSpacing < 0 {
+		fmt.Fprintln(os.Stderr, "--chapter-min-silence and --chapter-spacing must not be negative")
+		return exitCodeUsage
+	}
+
+	if *timeout < 0 {
+		fmt.Fprintln(os.Stderr, "--timeout must not be negative")
+		return exitCodeUsage
+	}
+
+	if *downloadRetries < 0 || *downloadTimeout < 0 || *maxDownloadSize < 0 {
+		fmt.Fprintln(os.Stderr, "--download-retries, --download-timeout and --max-download-size must not be negative")
+		return exitCodeUsage
 	}
 
 	requestedFormat := outputFormat(strings.ToLower(strings.TrimSpace(*format)))
-	if requestedFormat != outputFormatText && requestedFormat != outputFormatJSON {
+	switch requestedFormat {
+	case outputFormatText, outputFormatJSON, outputFormatNDJSON:
+	case outputFormatAudacity, outputFormatEDL, outputFormatSRT, outputFormatVTT, outputFormatFCPXML, outputFormatResolveMarkers,
+		outputFormatChapters, outputFormatFFMetadata:
+		switch {
+		case *watchDir != "" || *live || len(sweep) > 0 || *allStreams:
+			fmt.Fprintf(os.Stderr, "--output %s cannot be combined with --watch, --live, a --silence-noise sweep or --all-audio-streams\n", requestedFormat)
+			return exitCodeUsage
+		case (len(inputs) > 1 || expanded || *inputListPath != "") && *outputDir == "":
+			fmt.Fprintf(os.Stderr, "--output %s exports a single input; use --output-dir to export several\n", requestedFormat)
+			return exitCodeUsage
+		}
+	default:
 		fmt.Fprintf(os.Stderr, "unsupported output format %q\n", *format)
-		os.Exit(1)
+		return exitCodeUsage
+	}
+	switch *labels {
+	case "silence":
+	case "invert":
+		if requestedFormat != outputFormatAudacity && requestedFormat != outputFormatEDL {
+			fmt.Fprintln(os.Stderr, "--labels invert requires --output audacity or edl")
+			return exitCodeUsage
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported --labels value %q: use silence or invert\n", *labels)
+		return exitCodeUsage
+	}
+	if *watchDir != "" && requestedFormat == outputFormatJSON {
+		fmt.Fprintln(os.Stderr, "--watch reports each file as it is analysed; use --output ndjson or text")
+		return exitCodeUsage
+	}
+	if *emitTrim || *emitSegments != "" {
+		switch {
+		case *emitSegments == "-":
+			fmt.Fprintln(os.Stderr, "--emit-segments must name a file")
+			return exitCodeUsage
+		case *emitTrim && *emitSegments != "":
+			fmt.Fprintln(os.Stderr, "--emit-trim-command and --emit-segments cannot be used together")
+			return exitCodeUsage
+		case requestedFormat != outputFormatText:
+			fmt.Fprintln(os.Stderr, "--emit-trim-command and --emit-segments print a command instead of a report and cannot be combined with --output")
+			return exitCodeUsage
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "" || *watchDir != "" || *live || len(sweep) > 0 || stdinInputs > 0 || *allStreams:
+			fmt.Fprintln(os.Stderr, "--emit-trim-command and --emit-segments print the command for a single input file or URL")
+			return exitCodeUsage
+		case *trimPadding < 0 || *minSegment < 0:
+			fmt.Fprintln(os.Stderr, "--trim-padding and --min-segment-duration must not be negative")
+			return exitCodeUsage
+		case *emitTrim && *minSegment != 0:
+			fmt.Fprintln(os.Stderr, "--min-segment-duration requires --emit-segments")
+			return exitCodeUsage
+		}
+		requestedFormat = outputFormatTrimCommand
+		if *emitSegments != "" {
+			requestedFormat = outputFormatSegments
+		}
+	} else if *trimPadding != 0 || *minSegment != 0 {
+		fmt.Fprintln(os.Stderr, "--trim-padding and --min-segment-duration require --emit-trim-command or --emit-segments")
+		return exitCodeUsage
+	}
+	var expected expectation
+	if *expectFile != "" || *writeExpect != "" {
+		switch {
+		case *expectFile != "" && *writeExpect != "":
+			fmt.Fprintln(os.Stderr, "--expect and --write-expect cannot be used together")
+			return exitCodeUsage
+		case *expectFile == "-" || *writeExpect == "-":
+			fmt.Fprintln(os.Stderr, "--expect and --write-expect must name a file")
+			return exitCodeUsage
+		case *expectFile != "" && (requestedFormat != outputFormatText || *emitTrim || *emitSegments != ""):
+			fmt.Fprintln(os.Stderr, "--expect prints the differences instead of a report and cannot be combined with --output, --emit-trim-command or --emit-segments")
+			return exitCodeUsage
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "" || *watchDir != "" || *live || len(sweep) > 0 || *allStreams:
+			fmt.Fprintln(os.Stderr, "--expect and --write-expect check a single input")
+			return exitCodeUsage
+		case *expectTolerance < 0:
+			fmt.Fprintln(os.Stderr, "--expect-tolerance must not be negative")
+			return exitCodeUsage
+		}
+		if *expectFile != "" {
+			expected, err = loadExpectation(*expectFile, *expectTolerance)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "--expect: %v\n", err)
+				return exitCodeUsage
+			}
+			requestedFormat = outputFormatExpect
+		}
+	}
+	if *quiet && *verbose {
+		fmt.Fprintln(os.Stderr, "--quiet and --verbose cannot be used together")
+		return exitCodeUsage
+	}
+	if *porcelain {
+		switch {
+		case requestedFormat != outputFormatText:
+			fmt.Fprintln(os.Stderr, "--porcelain replaces the text report and cannot be combined with --output, --emit-trim-command, --emit-segments or --expect")
+			return exitCodeUsage
+		case len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "" || *watchDir != "" || *live || len(sweep) > 0 || *allStreams:
+			fmt.Fprintln(os.Stderr, "--porcelain prints the intervals of a single input")
+			return exitCodeUsage
+		case *showTimeline || histogram != nil || at.set:
+			fmt.Fprintln(os.Stderr, "--porcelain prints nothing but the intervals and cannot be combined with --timeline, --histogram or --at")
+			return exitCodeUsage
+		}
+		requestedFormat = outputFormatPorcelain
+	}
+	if *showTimeline {
+		switch {
+		case requestedFormat != outputFormatText:
+			fmt.Fprintln(os.Stderr, "--timeline is drawn under the text report and requires --output text")
+			return exitCodeUsage
+		case len(sweep) > 0:
+			fmt.Fprintln(os.Stderr, "--timeline cannot be combined with a --silence-noise sweep")
+			return exitCodeUsage
+		case *timelineWidth <= 0:
+			fmt.Fprintln(os.Stderr, "--timeline-width must be greater than zero")
+			return exitCodeUsage
+		}
+		switch detector.TimelineStyle(*timelineStyle) {
+		case detector.TimelineASCII, detector.TimelineBlocks:
+		default:
+			fmt.Fprintf(os.Stderr, "unsupported --timeline-style %q: use ascii or blocks\n", *timelineStyle)
+			return exitCodeUsage
+		}
+	} else if flagGiven("timeline-width") || flagGiven("timeline-style") {
+		fmt.Fprintln(os.Stderr, "--timeline-width and --timeline-style require --timeline")
+		return exitCodeUsage
+	}
+	if histogram != nil {
+		switch requestedFormat {
+		case outputFormatText, outputFormatJSON, outputFormatNDJSON:
+		default:
+			fmt.Fprintln(os.Stderr, "--histogram is added to text and JSON reports and requires --output text, json or ndjson")
+			return exitCodeUsage
+		}
+	}
+	if at.set && (len(inputs) > 1 || expanded || *inputListPath != "" || *outputDir != "" || *watchDir != "" || *live || len(sweep) > 0 || *allStreams) {
+		fmt.Fprintln(os.Stderr, "--at reports on a position of a single input")
+		return exitCodeUsage
+	}
+	if *expectFile == "" && flagGiven("expect-tolerance") {
+		fmt.Fprintln(os.Stderr, "--expect-tolerance requires --expect")
+		return exitCodeUsage
+	}
+	times, err := newTimeFormat(*precision, *timeFormatFlag, *fps)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeUsage
+	}
+	edl := detector.EDLOptions{ReelName: *reelName, DropFrame: *dropFrame}
+	markers := detector.MarkerOptions{TimelineName: *timelineName, TimelineStart: *timelineStart, DropFrame: *dropFrame}
+	subtitles := detector.SubtitleOptions{CueText: *cueText}
+	// Writing an empty document validates the settings of an export up front.
+	if err := detector.WriteVTT(io.Discard, nil, subtitles); err != nil {
+		fmt.Fprintf(os.Stderr, "--cue-text: %v\n", err)
+		return exitCodeUsage
+	}
+	switch requestedFormat {
+	case outputFormatEDL, outputFormatFCPXML, outputFormatResolveMarkers:
+		if !validFPS(*fps) {
+			fmt.Fprintf(os.Stderr, "--output %s requires a positive, finite --fps\n", requestedFormat)
+			return exitCodeUsage
+		}
+		switch requestedFormat {
+		case outputFormatEDL:
+			err = detector.WriteEDL(io.Discard, nil, *fps, edl)
+		case outputFormatFCPXML:
+			err = detector.WriteFCPXMLMarkers(io.Discard, nil, *fps, markers)
+		default:
+			err = detector.WriteResolveMarkers(io.Discard, nil, *fps, markers)
+		}
+		if err == nil && requestedFormat != outputFormatEDL && strings.TrimSpace(*timelineName) == "" {
+			err = fmt.Errorf("--output %s requires --timeline-name", requestedFormat)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeUsage
+		}
+	default:
+		switch {
+		case *fps != 0 && times.style != timeStyleSMPTE:
+			fmt.Fprintln(os.Stderr, "--fps is only used with --time-format smpte and --output edl, fcpxml or resolve-markers")
+			return exitCodeUsage
+		case *dropFrame:
+			fmt.Fprintln(os.Stderr, "--drop-frame requires --output edl, fcpxml or resolve-markers")
+			return exitCodeUsage
+		}
+	}
+	if *legacyJSON {
+		notef("warning: --legacy-json is deprecated and will be removed in the next release\n")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	if *outputDir != "" {
+		if *outputFile != "-" && *outputFile != "" {
+			fmt.Fprintln(os.Stderr, "--output-file and --output-dir cannot be used together")
+			return exitCodeUsage
+		}
+		if err := prepareOutputDir(*outputDir, *mkdir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+	}
 
-	det := detector.NewDetector(detector.WithFFmpegPath(*ffmpegBinary))
+	if *rawOutputFile == "-" {
+		fmt.Fprintln(os.Stderr, "--raw-output-file must name a file")
+		return exitCodeUsage
+	}
 
-	result, err := det.DetectSilence(ctx, resolvedInput, detector.DetectionOptions{
-		NoiseLevel:         *noiseLevel,
-		MinSilenceDuration: *minDuration,
-	})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "silence detection failed: %v\n", err)
-		os.Exit(1)
+	requestHeaders := headers.httpHeader()
+	if *bearerTokenEnv != "" {
+		token := strings.TrimSpace(os.Getenv(*bearerTokenEnv))
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "environment variable %s is empty or not set\n", *bearerTokenEnv)
+			return exitCodeUsage
+		}
+		if requestHeaders == nil {
+			requestHeaders = make(http.Header)
+		}
+		requestHeaders.Set("Authorization", "Bearer "+token)
+	}
+
+	var audioStreamIndex *int
+	if *audioStream >= 0 {
+		audioStreamIndex = audioStream
+	}
+	if *hlsRendition >= 0 {
+		if audioStreamIndex != nil {
+			fmt.Fprintln(os.Stderr, "--hls-audio-rendition and --audio-stream both select the audio stream; use only one")
+			return exitCodeUsage
+		}
+		audioStreamIndex = hlsRendition
+	}
+	if *audioLanguage != "" && audioStreamIndex != nil {
+		fmt.Fprintln(os.Stderr, "--audio-language selects the audio stream like --audio-stream and --hls-audio-rendition; use only one")
+		return exitCodeUsage
 	}
 
-	if *checkFullSilence && result.InputDuration <= 0 {
-		fmt.Fprintln(os.Stderr, "ffmpeg output did not include duration information; cannot determine full silence")
-		os.Exit(1)
+	fullSilence := *checkFullSilence || *failFullySilent || *failNotSilent
+
+	downmix, resampleRate := *downmixMono, *sampleRate
+	if *fast {
+		downmix = downmix || !*perChannel
+		if resampleRate == 0 {
+			resampleRate = fastSampleRate
+		}
 	}
 
-	switch requestedFormat {
-	case outputFormatJSON:
-		emitJSON(result, *inputPath, *noiseLevel, *minDuration, *checkFullSilence)
+	cfg := analysisConfig{
+		fetchers: map[detector.InputKind]remoteFetcher{
+			detector.InputRemote: httpFetcher{headers: requestHeaders},
+			detector.InputS3:     s3Fetcher{options: detector.S3Options{Region: *s3Region, Endpoint: *s3Endpoint}},
+			detector.InputGCS:    gcsFetcher{options: detector.GCSOptions{Endpoint: *gcsEndpoint}},
+		},
+		download: detector.DownloadOptions{
+			Retries:  *downloadRetries,
+			Timeout:  *downloadTimeout,
+			MaxBytes: *maxDownloadSize,
+		},
+		noDownload:    *noDownload || *live,
+		forceDownload: *forceDownload,
+		detection: detector.DetectionOptions{
+			NoiseLevel:            noiseLevel,
+			NoiseRatio:            noiseRatioValue,
+			MinSilenceDuration:    *minDuration,
+			MergeGap:              *mergeGap,
+			DisableNormalize:      *noNormalize,
+			PerChannel:            *perChannel,
+			StartOffset:           *startOffset,
+			AnalyzeDuration:       *analyzeDuration,
+			KnownDuration:         *knownDuration,
+			AudioStreamIndex:      audioStreamIndex,
+			AudioLanguage:         *audioLanguage,
+			InputFormat:           *inputFormat,
+			RawPCM:                rawPCM,
+			DownmixMono:           downmix,
+			SampleRate:            resampleRate,
+			ExtraInputArgs:        ffmpegArgs.input,
+			ExtraOutputArgs:       ffmpegArgs.output,
+			MeasureVolume:         *measureVolume,
+			MeasureLoudness:       *measureLoudness,
+			AdaptiveThreshold:     *adaptive,
+			AdaptiveOffsetDB:      *adaptiveOffset,
+			HysteresisDB:          *hysteresisDB,
+			MeasureIntervalLevels: *measureLevels,
+			CaptureRawOutput:      *rawOutputFile != "",
+			MaxIntervals:          *maxIntervals,
+			AllowPartial:          *allowPartial,
+			Live:                  *live,
+			Engine:                detector.Engine(*engine),
+		},
+		reportMin:   *reportMin,
+		reportMax:   *reportMax,
+		padStart:    *padStart,
+		padEnd:      *padEnd,
+		trailing:    *checkTrailing || *maxTrailing > 0,
+		minCoverage: *minCoverage,
+		fullTol:     *fullSilenceTol,
+		maxLeading:  *maxLeading,
+		maxTrailing: *maxTrailing,
+		timeout:     *timeout,
+
+		quality:     checkQuality,
+		maxClipped:  *maxClipped,
+		maxDCOffset: *maxDCOffset,
+
+		failOnSilence:        *failOnSilence,
+		failOnFullySilent:    *failFullySilent,
+		failIfNotFullySilent: *failNotSilent,
+
+		sweep:        sweep,
+		allStreams:   *allStreams,
+		energyWindow: energyWindow,
+	}
+	if black {
+		cfg.black = &detector.BlackDetectionOptions{
+			MinDuration:     *blackDuration,
+			PictureRatio:    *blackRatio,
+			StartOffset:     *startOffset,
+			AnalyzeDuration: *analyzeDuration,
+		}
+	}
+	if *detectFreeze {
+		cfg.freeze = &detector.FreezeDetectionOptions{
+			NoiseDB:         *freezeNoise,
+			MinDuration:     *freezeDuration,
+			StartOffset:     *startOffset,
+			AnalyzeDuration: *analyzeDuration,
+		}
+	}
+
+	if !validOptions(cfg.detection, sweep) {
+		return exitCodeUsage
+	}
+
+	opts := reportOptions{
+		noiseLevel:           noiseLevel,
+		noiseRatio:           noiseRatioValue,
+		hysteresisDB:         *hysteresisDB,
+		minDuration:          *minDuration,
+		checkFullSilence:     fullSilence,
+		minCoverage:          *minCoverage,
+		fullSilenceTol:       *fullSilenceTol,
+		checkLeadingSilence:  *checkLeading || *maxLeading > 0,
+		checkTrailingSilence: *checkTrailing || *maxTrailing > 0,
+		perChannel:           *perChannel,
+		measureVolume:        *measureVolume,
+		measureLoudness:      *measureLoudness,
+		adaptive:             *adaptive,
+		detectBlack:          black,
+		deadAir:              *deadAir,
+		detectFreeze:         *detectFreeze,
+		energyWindow:         energyWindow,
+		quality:              checkQuality,
+		live:                 *live,
+		legacyJSON:           *legacyJSON,
+		invertLabels:         *labels == "invert",
+		edl:                  edl,
+		markers:              markers,
+		subtitles:            subtitles,
+		chapterMinSilence:    *chapterSilence,
+		chapterSpacing:       *chapterSpacing,
+		trim:                 trimCommand{ffmpeg: *ffmpegBinary, padding: *trimPadding, segmentsFile: *emitSegments, minSegment: *minSegment, mkdir: *mkdir},
+		expect:               expected,
+		times:                &times,
+	}
+	if at.set {
+		opts.at = &at.seconds
+	}
+	if histogram != nil {
+		opts.histogram = histogram
+	}
+	if *showTimeline {
+		opts.timeline = &detector.TimelineOptions{Width: *timelineWidth, Style: detector.TimelineStyle(*timelineStyle)}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	detectorOptions := []detector.Option{
+		detector.WithFFmpegPath(*ffmpegBinary),
+		detector.WithFFprobePath(*ffprobeBinary),
+	}
+	if *verbose {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		detectorOptions = append(detectorOptions, detector.WithLogger(logger))
+	}
+	var recorder *prometheusRecorder
+	if *metricsListen != "" && !*dryRun {
+		recorder = newPrometheusRecorder()
+		detectorOptions = append(detectorOptions, detector.WithMetrics(recorder))
+		cfg.download.Recorder = recorder
+	}
+	var tracing *otlpExporter
+	if *trace && !*dryRun {
+		tracing, err = newOTLPExporter(os.Getenv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--trace: %v\n", err)
+			return exitCodeUsage
+		}
+		if tracing != nil {
+			detectorOptions = append(detectorOptions, detector.WithTracerProvider(tracing))
+			cfg.download.TracerProvider = tracing
+			ctx = contextWithTraceparent(ctx, os.Getenv("TRACEPARENT"))
+			defer tracing.shutdown(context.Background())
+		}
+	}
+	det := detector.NewDetector(detectorOptions...)
+
+	if *dryRun {
+		if err := printDryRun(ctx, os.Stdout, det, *ffmpegBinary, cfg, inputs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeUsage
+		}
+		return exitCodeOK
+	}
+
+	if recorder != nil {
+		stopMetrics, err := serveMetrics(*metricsListen, recorder)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		defer stopMetrics()
+	}
+
+	if needsFFmpeg(cfg) {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		_, err = det.Check(checkCtx)
+		cancel()
+		if err != nil {
+			if timedOut(ctx, cfg) {
+				fmt.Fprintf(os.Stderr, "checking ffmpeg timed out after %s\n", *timeout)
+				return exitCodeTimeout
+			}
+			reportDetectionError(err)
+			return exitCodeFFmpeg
+		}
+	}
+
+	// notify delivers the result of an input to --callback-url. Callbacks for inputs cut short by --timeout or a
+	// signal are still delivered.
+	notify := func(fileResult) {}
+	if *callbackURL != "" {
+		callbacks := newCallbackSender(*callbackSecret)
+		notify = func(res fileResult) {
+			callbacks.deliver(context.WithoutCancel(ctx), *callbackURL, resultEvent(res), newBatchReport(res, opts))
+		}
+	}
+
+	if *watchDir != "" {
+		folder, err := newDropFolder(*watchDir, *recursive, mediaExtensions, *settle)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeInput
+		}
+		w, closeOutput, err := openWatchOutput(*outputFile, *mkdir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+
+		// The first signal stops watching once the file being analysed is reported; a second one terminates.
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+
+		encoder := newReportEncoder(w, outputFormatNDJSON, opts.legacyJSON)
+		analyse := func(ctx context.Context, path string) fileResult {
+			return analyzeInput(ctx, det, cfg, path)
+		}
+		emit := func(res fileResult) {
+			if res.err != nil {
+				fmt.Fprintf(os.Stderr, "%s:\n", displayInputPath(res.input))
+				reportFailure(res.err)
+			}
+			if requestedFormat == outputFormatNDJSON {
+				emitReport(encoder, newBatchReport(res, opts))
+			} else {
+				emitBatchText(w, res, opts)
+				fmt.Fprintln(w)
+			}
+			notify(res)
+		}
+		notef("watching %s for %s files\n", *watchDir, strings.Join(mediaExtensions, ","))
+		watchErr := runWatch(ctx, folder, watchPollInterval, analyse, emit)
+		if err := closeOutput(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		if watchErr != nil {
+			fmt.Fprintln(os.Stderr, watchErr)
+			return exitCodeInput
+		}
+		return exitCodeOK
+	}
+
+	if *live {
+		out, err := openReportOutput(*outputFile, *mkdir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		res, exitCode := runLive(ctx, out, det, cfg, inputs[0], liveSchedule{window: time.Duration(window), interval: *interval}, requestedFormat, opts)
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		if *rawOutputFile != "" {
+			if err := writeRawOutput(*rawOutputFile, *mkdir, []fileResult{res}); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitCodeError
+			}
+		}
+		return exitCode
+	}
+
+	if len(inputs) == 1 && !expanded && *inputListPath == "" && *outputDir == "" {
+		out, err := openReportOutput(*outputFile, *mkdir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		res := analyzeInput(ctx, det, cfg, inputs[0])
+		var exitCode int
+		if len(sweep) > 0 {
+			exitCode = reportSweep(out, res, sweep, requestedFormat, opts)
+		} else if *allStreams {
+			exitCode = reportStreams(out, res, requestedFormat, opts)
+		} else {
+			exitCode = reportSingle(out, res, requestedFormat, opts)
+		}
+		notify(res)
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		if *rawOutputFile != "" {
+			if err := writeRawOutput(*rawOutputFile, *mkdir, []fileResult{res}); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitCodeError
+			}
+		}
+		if *writeExpect != "" && res.err == nil && !res.noAudioStream {
+			if err := writeExpectation(*writeExpect, *mkdir, res, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitCodeError
+			}
+		}
+		return exitCode
+	}
+
+	var (
+		w           io.Writer = io.Discard
+		out         *reportOutput
+		onResult    func(int, fileResult)
+		writeFailed atomic.Bool
+	)
+	if *outputDir != "" {
+		names := outputFileNames(inputs, requestedFormat)
+		onResult = func(i int, res fileResult) {
+			if err := writeResultFile(filepath.Join(*outputDir, names[i]), res, requestedFormat, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				writeFailed.Store(true)
+			}
+		}
+	} else {
+		out, err = openReportOutput(*outputFile, *mkdir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		w = out
+		if requestedFormat == outputFormatNDJSON {
+			encoder := newReportEncoder(out, outputFormatNDJSON, opts.legacyJSON)
+			onResult = func(_ int, res fileResult) {
+				emitReport(encoder, newBatchReport(res, opts))
+			}
+		}
+	}
+
+	write := onResult
+	onResult = func(i int, res fileResult) {
+		if write != nil {
+			write(i, res)
+		}
+		notify(res)
+	}
+
+	started := time.Now()
+	results := analyzeAll(ctx, det, cfg, inputs, *parallel, onResult)
+	exitCode := reportBatch(w, results, requestedFormat, opts)
+	if out != nil {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = exitCodeError
+		}
+	}
+	if *rawOutputFile != "" {
+		if err := writeRawOutput(*rawOutputFile, *mkdir, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = exitCodeError
+		}
+	}
+	if writeFailed.Load() {
+		exitCode = exitCodeError
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.status() != statusOK {
+			failed++
+		}
+	}
+	notef("processed %d input(s), %d failed, in %s\n", len(results), failed, time.Since(started).Round(time.Millisecond))
+
+	return exitCode
+}
+
+// reportSingle renders the result of a single-input run and returns the process exit code.
+func reportSingle(w io.Writer, res fileResult, format outputFormat, opts reportOptions) int {
+	opts.inputPath = res.input
+
+	if res.noAudioStream {
+		fmt.Fprintf(os.Stderr, "input %q has no audio stream; nothing to analyse\n", displayInputPath(res.input))
+		if format == outputFormatJSON || format == outputFormatNDJSON {
+			opts.noAudioStream = true
+			emitReport(newReportEncoder(w, format, opts.legacyJSON), newJSONReport(detector.DetectionResult{}, opts))
+		}
+		return exitCodeNoAudioStream
+	}
+	if res.err != nil {
+		reportFailure(res.err)
+		return res.exitCode()
+	}
+
+	exitCode := res.exitCode()
+	switch format {
+	case outputFormatJSON, outputFormatNDJSON:
+		emitReport(newReportEncoder(w, format, opts.legacyJSON), newFileReport(res, opts))
+	case outputFormatAudacity:
+		emitLabels(w, res.result, opts)
+	case outputFormatEDL:
+		emitEDL(w, res, opts)
+	case outputFormatSRT, outputFormatVTT:
+		emitSubtitles(w, res.result, format, opts)
+	case outputFormatFCPXML, outputFormatResolveMarkers:
+		emitMarkers(w, res.result, format, opts)
+	case outputFormatChapters, outputFormatFFMetadata:
+		emitChapters(w, res.result, format, opts)
+	case outputFormatTrimCommand:
+		exitCode = emitTrimCommand(w, res, opts)
+	case outputFormatSegments:
+		exitCode = emitSegments(w, res, opts)
+	case outputFormatExpect:
+		exitCode = emitExpectation(w, res, opts)
+	case outputFormatPorcelain:
+		emitPorcelain(w, res.result, opts)
 	default:
-		emitText(result, *inputPath, *noiseLevel, *minDuration, *checkFullSilence)
+		emitFileText(w, res, opts)
 	}
+	if opts.at != nil {
+		// Other formats are read by programs, or piped to a shell, so the answer goes to stderr beside them.
+		atOut := w
+		if format != outputFormatText {
+			atOut = os.Stderr
+		}
+		emitSilentAt(atOut, res.result, *opts.at, opts.timeFormat())
+	}
+
+	for _, message := range res.messages() {
+		notef("%s\n", message)
+	}
+	return exitCode
 }
 
-func emitJSON(result detector.DetectionResult, inputPath string, noiseLevel, minDuration float64, checkFullSilence bool) {
-	report := struct {
-		Input       string                     `json:"input"`
-		NoiseDB     float64                    `json:"noise_db"`
-		MinDur      float64                    `json:"min_duration"`
-		Duration    float64                    `json:"duration"`
-		FullySilent *bool                      `json:"fully_silent,omitempty"`
-		Intervals   []detector.SilenceInterval `json:"intervals"`
-	}{
-		Input:     displayInputPath(inputPath),
-		NoiseDB:   noiseLevel,
-		MinDur:    minDuration,
-		Duration:  result.InputDuration,
-		Intervals: result.Intervals,
+// prepareInput returns the location ffmpeg should read for the given input.
+//
+// Remote inputs are downloaded to a temporary file unless streamRemote is set, in which case the URL is passed
+// through unchanged. Stream URLs and "-" (stdin) are passed through as is. The returned cleanup function, when non-nil, removes
+// any temporary file.
+func prepareInput(ctx context.Context, input detector.Input, streamRemote bool, download detector.DownloadOptions) (string, func(), error) {
+	if input.IsRemote() {
+		if streamRemote {
+			return input.Location, nil, nil
+		}
+
+		file, err := detector.FetchRemoteInput(ctx, input.Location, download)
+		if err != nil {
+			return "", nil, describeDownloadError(input, err)
+		}
+		return file.Path, func() { file.Remove() }, nil
+	}
+
+	if input.Kind == detector.InputStream || input.Location == "-" {
+		return input.Location, nil, nil
 	}
 
-	if checkFullSilence {
-		fullySilent := result.FullySilent(1e-3)
-		report.FullySilent = &fullySilent
+	info, err := os.Stat(input.Location)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat input %q: %w", input.Raw, err)
+	}
+	if info.IsDir() {
+		return "", nil, fmt.Errorf("input %q is a directory, expected a file", input.Location)
 	}
 
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(report); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
-		os.Exit(1)
+	return input.Location, nil, nil
+}
+
+// newJSONReport builds the JSON report of a silence detection result, with times rounded to --precision.
+func newJSONReport(result detector.DetectionResult, opts reportOptions) *detector.Report {
+	times := opts.timeFormat()
+	options := detector.DetectionOptions{
+		NoiseLevel:         opts.noiseLevel,
+		NoiseRatio:         opts.noiseRatio,
+		HysteresisDB:       opts.hysteresisDB,
+		MinSilenceDuration: opts.minDuration,
+		PerChannel:         opts.perChannel,
+		MeasureVolume:      opts.measureVolume,
+		MeasureLoudness:    opts.measureLoudness,
+		AdaptiveThreshold:  opts.adaptive,
+	}
+	report := detector.NewReport(displayInputPath(opts.inputPath), options, result, detector.ReportOptions{
+		FullSilence:        opts.checkFullSilence,
+		LeadingSilence:     opts.checkLeadingSilence,
+		TrailingSilence:    opts.checkTrailingSilence,
+		Tolerance:          silenceTolerance,
+		MinSilenceCoverage: opts.minCoverage,
+		FullSilenceTolerance: &detector.FullySilentOpts{
+			Head: opts.fullSilenceTol,
+			Tail: opts.fullSilenceTol,
+			Gap:  opts.fullSilenceTol,
+		},
+	})
+	report.CheckedAt = formatCheckedAt(opts.checkedAt)
+	report.NoAudioStream = opts.noAudioStream
+	if opts.histogram != nil {
+		report.Histogram, _ = result.Histogram(opts.histogram)
 	}
+	report.Round(times.precision)
+	return report
 }
 
-func emitText(result detector.DetectionResult, inputPath string, noiseLevel, minDuration float64, checkFullSilence bool) {
-	fmt.Printf("Silence detection for %s\n", displayInputPath(inputPath))
-	fmt.Printf("Noise threshold: %.2fdB, Minimum duration: %.2fs\n", noiseLevel, minDuration)
-	if result.InputDuration > 0 {
-		fmt.Printf("Input duration: %.3fs\n", result.InputDuration)
+// formatVolume renders a volumedetect measurement, which is nil when ffmpeg did not report one.
+func formatVolume(volume *float64) string {
+	if volume == nil {
+		return "n/a"
 	}
+	return fmt.Sprintf("%.1fdB", *volume)
+}
 
-	if len(result.Intervals) == 0 {
-		fmt.Println("No silence intervals detected.")
-		if checkFullSilence {
-			fmt.Println("Entire file is not silent.")
-		}
+// printLoudness writes the EBU R128 summary block of a text report.
+func printLoudness(w io.Writer, loudness *detector.Loudness) {
+	if loudness == nil {
+		fmt.Fprintln(w, "Loudness (EBU R128): n/a")
 		return
 	}
 
-	fmt.Printf("Detected %d silence interval(s):\n", len(result.Intervals))
-	for i, interval := range result.Intervals {
-		fmt.Printf("%d. start=%.3fs end=%.3fs duration=%.3fs\n", i+1, interval.Start, interval.End, interval.Duration)
+	fmt.Fprintln(w, "Loudness (EBU R128):")
+	fmt.Fprintf(w, "  Integrated: %.1f LUFS\n", loudness.IntegratedLUFS)
+	fmt.Fprintf(w, "  Range: %.1f LU\n", loudness.RangeLU)
+	if loudness.TruePeakDBFS != nil {
+		fmt.Fprintf(w, "  True peak: %.1f dBFS\n", *loudness.TruePeakDBFS)
+	}
+}
+
+// describeAudio summarises the analysed audio stream for a text report, such as
+// "aac, 48000 Hz, stereo (2 channels), 128 kb/s, stream #0:1". Details that are unknown are left out.
+func describeAudio(info detector.AudioInfo) string {
+	var details []string
+	if info.Codec != "" {
+		details = append(details, info.Codec)
+	}
+	if info.SampleRate > 0 {
+		details = append(details, fmt.Sprintf("%d Hz", info.SampleRate))
+	}
+	switch {
+	case info.ChannelLayout != "" && info.Channels > 0:
+		details = append(details, fmt.Sprintf("%s (%d channels)", info.ChannelLayout, info.Channels))
+	case info.ChannelLayout != "":
+		details = append(details, info.ChannelLayout)
+	case info.Channels > 0:
+		details = append(details, fmt.Sprintf("%d channels", info.Channels))
+	}
+	if info.BitRate > 0 {
+		details = append(details, fmt.Sprintf("%d kb/s", info.BitRate/1000))
+	}
+	return strings.Join(append(details, fmt.Sprintf("stream #0:%d", info.StreamIndex)), ", ")
+}
+
+func emitText(w io.Writer, result detector.DetectionResult, opts reportOptions) {
+	// Verdicts are evaluated on the measured times, which also decide the exit code; only listings are rounded.
+	times := opts.timeFormat()
+	rounded := times.round(result)
+
+	fmt.Fprintf(w, "Silence detection for %s\n", displayInputPath(opts.inputPath))
+	if !opts.checkedAt.IsZero() {
+		fmt.Fprintf(w, "Checked at: %s\n", formatCheckedAt(opts.checkedAt))
+	}
+	if opts.adaptive && result.NoiseFloorDB != nil {
+		fmt.Fprintf(w, "Noise threshold: %.2fdB (adaptive, noise floor %.2fdB), Minimum duration: %.2fs\n", result.ThresholdDB, *result.NoiseFloorDB, opts.minDuration)
+	} else if opts.noiseRatio != nil {
+		fmt.Fprintf(w, "Noise threshold: %g (%.2fdB), Minimum duration: %.2fs\n", *opts.noiseRatio, result.ThresholdDB, opts.minDuration)
+	} else {
+		fmt.Fprintf(w, "Noise threshold: %.2fdB, Minimum duration: %.2fs\n", opts.noiseLevel, opts.minDuration)
+	}
+	if opts.hysteresisDB > 0 {
+		fmt.Fprintf(w, "Hysteresis: %.2fdB, silences close above %.2fdB\n", opts.hysteresisDB, min(result.ThresholdDB+opts.hysteresisDB, 0))
+	}
+	if result.WindowStart > 0 {
+		fmt.Fprintf(w, "Analysis window: start=%s duration=%s\n", times.seconds(result.WindowStart), times.seconds(result.InputDuration))
+	} else if result.InputDuration > 0 {
+		fmt.Fprintf(w, "Input duration: %s\n", times.seconds(result.InputDuration))
+	}
+	if result.AudioInfo != nil {
+		fmt.Fprintf(w, "Audio: %s\n", describeAudio(*result.AudioInfo))
+	}
+	if opts.checkLeadingSilence {
+		fmt.Fprintf(w, "Leading silence: %s\n", times.seconds(result.LeadingSilence(silenceTolerance)))
+	}
+	if opts.checkTrailingSilence {
+		fmt.Fprintf(w, "Trailing silence: %s\n", times.seconds(result.TrailingSilence(silenceTolerance)))
+	}
+	if opts.measureVolume {
+		fmt.Fprintf(w, "Mean volume: %s, Max volume: %s\n", formatVolume(result.MeanVolumeDB), formatVolume(result.MaxVolumeDB))
+	}
+	if opts.measureLoudness {
+		printLoudness(w, result.Loudness)
+	}
+
+	for _, warning := range result.Warnings {
+		if warning.Code == detector.WarningDurationMismatch {
+			fmt.Fprintf(w, "Warning: %s\n", warning.Message)
+			continue
+		}
+		fmt.Fprintf(w, "Adjusted ffmpeg output: %s\n", warning.Message)
 	}
 
-	if checkFullSilence {
-		if result.FullySilent(1e-3) {
-			fmt.Println("Entire file is silent.")
+	if len(result.Intervals) == 0 {
+		fmt.Fprintln(w, "No silence intervals detected.")
+	} else {
+		fmt.Fprintf(w, "Detected %d silence interval(s):\n", len(result.Intervals))
+		printIntervals(w, rounded.Intervals, times)
+		if result.Truncated {
+			fmt.Fprintln(w, "Stopped at --max-intervals; later silence was not analysed.")
+		}
+
+		if result.InputDuration > 0 {
+			fmt.Fprintf(w, "Total silence: %s (%.1f%% of input)\n", times.seconds(result.TotalSilence()), result.SilenceRatio()*100)
 		} else {
-			fmt.Println("Entire file is not silent.")
+			fmt.Fprintf(w, "Total silence: %s\n", times.seconds(result.TotalSilence()))
+		}
+		if longest, ok := rounded.LongestInterval(); ok {
+			fmt.Fprintf(w, "Longest interval: %s\n", formatInterval(longest, times))
+		}
+	}
+	if opts.histogram != nil {
+		// The bounds were checked when --histogram was parsed.
+		buckets, _ := result.Histogram(opts.histogram)
+		printHistogram(w, buckets, times)
+	}
+	if opts.timeline != nil {
+		if timeline := detector.RenderTimelineWith(result, *opts.timeline); timeline != "" {
+			fmt.Fprint(w, timeline)
+		} else {
+			fmt.Fprintln(w, "No timeline: the input duration is unknown and no silence was detected.")
 		}
 	}
-}
 
-func isRemoteInput(path string) bool {
-	if path == "" {
-		return false
+	if opts.checkFullSilence {
+		switch silent, determinate := result.FullySilentStatus(opts.fullSilenceTol); {
+		case !determinate:
+			fmt.Fprintf(w, "Cannot tell whether the input is entirely silent: %s.\n", fullSilenceUnknownReason(result))
+		case opts.live && silent:
+			fmt.Fprintln(w, "Stream is silent for the whole window.")
+		case opts.live:
+			fmt.Fprintln(w, "Stream is not silent.")
+		case silent:
+			fmt.Fprintln(w, "Entire file is silent.")
+		default:
+			fmt.Fprintln(w, "Entire file is not silent.")
+		}
+		if opts.minCoverage > 0 {
+			coverage := fmt.Sprintf("%.2f%% silence, at least %.2f%% required", result.SilenceCoverage()*100, opts.minCoverage*100)
+			switch silent, determinate := result.EffectivelySilentStatus(opts.minCoverage); {
+			case !determinate:
+				fmt.Fprintf(w, "Cannot tell whether the input is effectively silent: %s.\n", fullSilenceUnknownReason(result))
+			case silent:
+				fmt.Fprintf(w, "Input is effectively silent (%s).\n", coverage)
+			default:
+				fmt.Fprintf(w, "Input is not effectively silent (%s).\n", coverage)
+			}
+		}
 	}
 
-	parsed, err := url.Parse(path)
-	if err != nil {
-		return false
+	if opts.perChannel {
+		channels := result.Channels()
+		if len(channels) == 0 {
+			fmt.Fprintln(w, "No per-channel silence detected.")
+		}
+		for _, channel := range channels {
+			intervals := rounded.ChannelIntervals[channel]
+			fmt.Fprintf(w, "Channel %d: %d silence interval(s)\n", channel, len(intervals))
+			printIntervals(w, intervals, times)
+			if opts.checkFullSilence {
+				if result.ChannelFullySilent(channel, opts.fullSilenceTol) {
+					fmt.Fprintf(w, "Channel %d is entirely silent.\n", channel)
+				} else {
+					fmt.Fprintf(w, "Channel %d is not entirely silent.\n", channel)
+				}
+			}
+		}
 	}
+}
 
-	switch strings.ToLower(parsed.Scheme) {
-	case "http", "https":
-		return true
+// fullSilenceStatus returns the full-silence verdict the --fail-* gates use: whether the input is effectively
+// silent with --min-silence-coverage, and otherwise whether it is entirely silent within the tolerance.
+func fullSilenceStatus(result detector.DetectionResult, minCoverage, tolerance float64) (silent, determinate bool) {
+	if minCoverage > 0 {
+		return result.EffectivelySilentStatus(minCoverage)
+	}
+	return result.FullySilentStatus(tolerance)
+}
+
+// fullSilenceUnknownReason explains why result.FullySilentStatus could not determine the full-silence verdict.
+func fullSilenceUnknownReason(result detector.DetectionResult) string {
+	switch {
+	case result.InputDuration <= 0:
+		return "its duration is unknown"
+	case result.Truncated:
+		return "analysis stopped at --max-intervals"
 	default:
-		return false
+		return "ffmpeg failed partway"
 	}
 }
 
-func displayInputPath(path string) string {
-	if isRemoteInput(path) {
-		return path
+func printIntervals(w io.Writer, intervals []detector.SilenceInterval, times timeFormat) {
+	for i, interval := range intervals {
+		fmt.Fprintf(w, "%d. %s\n", i+1, formatInterval(interval, times))
 	}
-	return filepath.Clean(path)
 }
 
-func downloadRemoteInput(rawURL string) (string, func(), error) {
-	parsed, err := url.Parse(rawURL)
-	if err != nil {
-		return "", nil, fmt.Errorf("invalid URL: %w", err)
+// emitSilentAt writes the answer of --at: whether position lies in a silence interval of result, and which.
+func emitSilentAt(w io.Writer, result detector.DetectionResult, position float64, times timeFormat) {
+	interval, ok := result.IntervalAt(position)
+	if !ok {
+		fmt.Fprintf(w, "Silent at %s: no\n", times.seconds(position))
+		return
 	}
+	fmt.Fprintf(w, "Silent at %s: yes, %s\n", times.seconds(position), formatInterval(interval, times))
+}
 
-	client := &http.Client{Timeout: 2 * time.Minute}
-	resp, err := client.Get(rawURL)
-	if err != nil {
-		return "", nil, err
+// formatInterval renders an interval for the text report, with its levels when they were measured.
+func formatInterval(interval detector.Interval, times timeFormat) string {
+	text := fmt.Sprintf("start=%s end=%s duration=%s", times.seconds(interval.Start), times.seconds(interval.End), times.seconds(interval.Duration))
+	if interval.MeanVolumeDB != nil || interval.MaxVolumeDB != nil {
+		text += fmt.Sprintf(" mean_volume=%s max_volume=%s", formatVolume(interval.MeanVolumeDB), formatVolume(interval.MaxVolumeDB))
 	}
-	defer resp.Body.Close()
+	return text
+}
+
+// errorExcerptLines is the number of trailing ffmpeg output lines printed when detection fails.
+const errorExcerptLines = 10
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return "", nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+func reportDetectionError(err error) {
+	if errors.Is(err, detector.ErrFFmpegNotFound) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		fmt.Fprintln(os.Stderr, "install ffmpeg or pass its location with --ffmpeg /path/to/ffmpeg")
+		return
 	}
 
-	ext := filepath.Ext(parsed.Path)
-	tmpFile, err := os.CreateTemp("", "silence-detector-*"+ext)
-	if err != nil {
-		return "", nil, err
+	if errors.Is(err, detector.ErrFilterUnavailable) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		fmt.Fprintln(os.Stderr, "use an ffmpeg build that includes the silencedetect audio filter (see --ffmpeg)")
+		return
 	}
 
-	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return "", nil, err
+	if errors.Is(err, detector.ErrUnrecognizedOutput) {
+		fmt.Fprintf(os.Stderr, "silence detection failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, "check that --ffmpeg points to ffmpeg and that no --ffmpeg-arg lowers its -loglevel")
+		return
 	}
 
-	if err := tmpFile.Close(); err != nil {
-		os.Remove(tmpFile.Name())
-		return "", nil, err
+	if errors.Is(err, detector.ErrTimeout) || errors.Is(err, detector.ErrCanceled) {
+		fmt.Fprintf(os.Stderr, "silence detection failed: %v\n", err)
+		return
 	}
 
-	cleanup := func() {
-		os.Remove(tmpFile.Name())
+	var ffErr *detector.FFmpegError
+	if !errors.As(err, &ffErr) {
+		fmt.Fprintf(os.Stderr, "silence detection failed: %v\n", err)
+		return
 	}
 
-	return tmpFile.Name(), cleanup, nil
+	if ffErr.ExitCode >= 0 {
+		fmt.Fprintf(os.Stderr, "silence detection failed: ffmpeg exited with code %d\n", ffErr.ExitCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "silence detection failed: %v\n", ffErr.Err)
+	}
+
+	if excerpt := lastLines(ffErr.Stderr, errorExcerptLines); excerpt != "" {
+		fmt.Fprintf(os.Stderr, "ffmpeg output (last %d lines):\n%s\n", errorExcerptLines, excerpt)
+	}
+}
+
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func displayInputPath(path string) string {
+	input, err := detector.ResolveInput(path)
+	if err != nil {
+		return path
+	}
+	return input.Display()
 }
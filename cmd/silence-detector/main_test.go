@@ -0,0 +1,336 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// runMainEnv makes the test binary behave as the silence-detector command, so that tests can run it end to end.
+const runMainEnv = "SILENCE_DETECTOR_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		os.Exit(run())
+	}
+	os.Exit(m.Run())
+}
+
+// fakeFFmpeg is a shell script standing in for ffmpeg. FAKE_FFMPEG_MODE selects the silencedetect output it prints.
+const fakeFFmpeg = `#!/bin/sh
+case "$*" in
+  *-version*) echo "ffmpeg version 6.1-fake"; exit 0;;
+  *-filters*) echo " ... silencedetect     A->A       Detect silence."; exit 0;;
+  *blackdetect*) echo "[blackdetect @ 0x2] black_start:8 black_end:10 black_duration:2" >&2; exit 0;;
+  *ametadata*) echo "[Parsed_ametadata_3 @ 0x4] frame:0 pts:0 pts_time:0" >&2; echo "[Parsed_ametadata_3 @ 0x4] lavfi.astats.Overall.RMS_level=-20.5" >&2; exit 0;;
+  *"-af astats -f"*) printf '[Parsed_astats_0 @ 0x5] Channel: 1\n[Parsed_astats_0 @ 0x5] DC offset: 0.002000\n[Parsed_astats_0 @ 0x5] Peak level dB: 0.000000\n[Parsed_astats_0 @ 0x5] Flat factor: 6.020600\n[Parsed_astats_0 @ 0x5] Peak count: 12\n' >&2; exit 0;;
+  *freezedetect*) echo "[freezedetect @ 0x3] lavfi.freezedetect.freeze_start: 9" >&2; echo "size=N/A time=00:00:10.00 bitrate=N/A" >&2; exit 0;;
+esac
+echo "  Duration: 00:00:10.00, start: 0.000000, bitrate: 128 kb/s" >&2
+case "$FAKE_FFMPEG_MODE" in
+  partial)
+    echo "[silencedetect @ 0x1] silence_start: 7" >&2
+    echo "[silencedetect @ 0x1] silence_end: 10 | silence_duration: 3" >&2;;
+  full)
+    echo "[silencedetect @ 0x1] silence_start: 0" >&2
+    echo "[silencedetect @ 0x1] silence_end: 10 | silence_duration: 10" >&2;;
+  primed)
+    echo "[silencedetect @ 0x1] silence_start: 0.03" >&2
+    echo "[silencedetect @ 0x1] silence_end: 10 | silence_duration: 9.97" >&2;;
+  noduration)
+    echo "[silencedetect @ 0x1] silence_start: 0" >&2; exit 0;;
+  noaudio)
+    echo "Output file #0 does not contain any stream" >&2; exit 1;;
+  corrupt)
+    echo "[silencedetect @ 0x1] silence_start: 2" >&2
+    echo "[silencedetect @ 0x1] silence_end: 4 | silence_duration: 2" >&2
+    echo "size=N/A time=00:00:06.00 bitrate=N/A speed=1x" >&2
+    echo "input.wav: Invalid data found when processing input" >&2; exit 1;;
+  fail)
+    echo "input.wav: Invalid data found when processing input" >&2; exit 1;;
+  unreachable)
+    echo "rtmp://live.example.com/app/key: Connection refused" >&2; exit 1;;
+  hang)
+    exec sleep 10;;
+esac
+echo "size=N/A time=00:00:10.00 bitrate=N/A speed=1x" >&2
+`
+
+func TestExitCodes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "other.wav")
+	if err := os.WriteFile(other, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gated := filepath.Join(dir, "gated.yaml")
+	if err := os.WriteFile(gated, []byte("fail-on-silence: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	quietNoise := filepath.Join(dir, "quiet-noise.yaml")
+	if err := os.WriteFile(quietNoise, []byte("silence-noise: -35\nquiet: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	aliasedNoise := filepath.Join(dir, "aliased-noise.yaml")
+	if err := os.WriteFile(aliasedNoise, []byte("noise-db: -35\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	misspelled := filepath.Join(dir, "misspelled.yaml")
+	if err := os.WriteFile(misspelled, []byte("fail-on-silense: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	emptyDir := filepath.Join(dir, "empty")
+	if err := os.Mkdir(emptyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	emptyList := filepath.Join(dir, "empty-list.txt")
+	if err := os.WriteFile(emptyList, []byte("# nothing to analyse\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A callback receiver that rejects every delivery.
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	defer receiver.Close()
+
+	tests := []struct {
+		name string
+		mode string
+		args []string
+		env  []string
+		want int
+	}{
+		{name: "no silence", mode: "none", args: []string{"--fail-on-silence", input}, want: exitCodeOK},
+		{name: "silence detected", mode: "partial", args: []string{"--fail-on-silence", input}, want: exitCodeSilenceDetected},
+		{name: "silence without gate", mode: "partial", args: []string{input}, want: exitCodeOK},
+		{name: "fully silent", mode: "full", args: []string{"--fail-on-fully-silent", input}, want: exitCodeFullSilence},
+		{name: "not fully silent passes", mode: "partial", args: []string{"--fail-on-fully-silent", input}, want: exitCodeOK},
+		{name: "not fully silent", mode: "partial", args: []string{"--fail-if-not-fully-silent", input}, want: exitCodeFullSilence},
+		{name: "fully silent asset", mode: "full", args: []string{"--fail-if-not-fully-silent", input}, want: exitCodeOK},
+		{name: "full silence unknown", mode: "noduration", args: []string{"--check-full-silence", input}, want: exitCodeOK},
+		{name: "full silence unknown fails gate", mode: "noduration", args: []string{"--fail-if-not-fully-silent", input}, want: exitCodeFullSilence},
+		{name: "priming delay", mode: "primed", args: []string{"--fail-if-not-fully-silent", input}, want: exitCodeFullSilence},
+		{name: "priming delay within tolerance", mode: "primed", args: []string{"--fail-if-not-fully-silent", "--full-silence-tolerance", "0.05", input}, want: exitCodeOK},
+		{name: "negative full silence tolerance", mode: "none", args: []string{"--full-silence-tolerance", "-1", input}, want: exitCodeUsage},
+		{name: "effectively silent", mode: "partial", args: []string{"--fail-on-fully-silent", "--min-silence-coverage", "0.25", input}, want: exitCodeFullSilence},
+		{name: "effectively silent passes", mode: "partial", args: []string{"--fail-if-not-fully-silent", "--min-silence-coverage", "0.25", input}, want: exitCodeOK},
+		{name: "not effectively silent", mode: "partial", args: []string{"--fail-if-not-fully-silent", "--min-silence-coverage", "0.5", input}, want: exitCodeFullSilence},
+		{name: "effective silence unknown", mode: "noduration", args: []string{"--fail-on-fully-silent", "--min-silence-coverage", "0.5", input}, want: exitCodeOK},
+		{name: "coverage without full silence", mode: "none", args: []string{"--min-silence-coverage", "0.99", input}, want: exitCodeUsage},
+		{name: "coverage out of range", mode: "none", args: []string{"--check-full-silence", "--min-silence-coverage", "1.5", input}, want: exitCodeUsage},
+		{name: "trailing limit", mode: "partial", args: []string{"--max-trailing-silence", "1", input}, want: exitCodeLimitExceeded},
+		{name: "no audio stream", mode: "noaudio", args: []string{input}, want: exitCodeNoAudioStream},
+		{name: "ffmpeg failure", mode: "fail", args: []string{input}, want: exitCodeFFmpeg},
+		{name: "partial analysis", mode: "corrupt", args: []string{"--allow-partial", input}, want: exitCodePartial},
+		{name: "partial analysis not allowed", mode: "corrupt", args: []string{input}, want: exitCodeFFmpeg},
+		{name: "partial analysis of nothing", mode: "fail", args: []string{"--allow-partial", input}, want: exitCodeFFmpeg},
+		{name: "partial analysis with gate", mode: "corrupt", args: []string{"--allow-partial", "--fail-on-silence", input, other}, want: exitCodePartial},
+		{name: "missing input", mode: "none", args: []string{filepath.Join(dir, "missing.wav")}, want: exitCodeInput},
+		{name: "unknown flag", mode: "none", args: []string{"--no-such-flag", input}, want: exitCodeUsage},
+		{name: "invalid value", mode: "none", args: []string{"--silence-duration", "0", input}, want: exitCodeUsage},
+		{name: "noise ratio", mode: "partial", args: []string{"--silence-noise-ratio", "0.001", "--fail-on-silence", input}, want: exitCodeSilenceDetected},
+		{name: "noise ratio out of range", mode: "none", args: []string{"--silence-noise-ratio", "1.5", input}, want: exitCodeUsage},
+		{name: "zero noise ratio", mode: "none", args: []string{"--silence-noise-ratio", "0", input}, want: exitCodeUsage},
+		{name: "noise ratio with noise level", mode: "none", args: []string{"--silence-noise-ratio", "0.01", "--silence-noise", "-40", input}, want: exitCodeUsage},
+		{name: "noise ratio with adaptive", mode: "none", args: []string{"--silence-noise-ratio", "0.01", "--adaptive", input}, want: exitCodeUsage},
+		{name: "positive noise level", mode: "none", args: []string{"--silence-noise", "30", input}, want: exitCodeUsage},
+		{name: "positive sweep threshold", mode: "none", args: []string{"--silence-noise", "-30,10", input}, want: exitCodeUsage},
+		{name: "invalid precision", mode: "none", args: []string{"--precision", "-1", input}, want: exitCodeUsage},
+		{name: "invalid time format", mode: "none", args: []string{"--time-format", "frames", input}, want: exitCodeUsage},
+		{name: "smpte without fps", mode: "none", args: []string{"--time-format", "smpte", input}, want: exitCodeUsage},
+		{name: "smpte with infinite fps", mode: "partial", args: []string{"--time-format", "smpte", "--fps", "inf", input}, want: exitCodeUsage},
+		{name: "edl with infinite fps", mode: "none", args: []string{"--output", "edl", "--fps", "inf", input}, want: exitCodeUsage},
+		{name: "fps without smpte", mode: "none", args: []string{"--fps", "25", input}, want: exitCodeUsage},
+		{name: "audacity labels", mode: "partial", args: []string{"--output", "audacity", "--labels", "invert", input}, want: exitCodeOK},
+		{name: "audacity with several inputs", mode: "none", args: []string{"--output", "audacity", input, other}, want: exitCodeUsage},
+		{name: "inverted labels without audacity", mode: "none", args: []string{"--labels", "invert", input}, want: exitCodeUsage},
+		{name: "edl", mode: "partial", args: []string{"--output", "edl", "--fps", "29.97", "--drop-frame", input}, want: exitCodeOK},
+		{name: "edl without fps", mode: "none", args: []string{"--output", "edl", input}, want: exitCodeUsage},
+		{name: "drop frame at 25 fps", mode: "none", args: []string{"--output", "edl", "--fps", "25", "--drop-frame", input}, want: exitCodeUsage},
+		{name: "drop frame without edl", mode: "none", args: []string{"--drop-frame", input}, want: exitCodeUsage},
+		{name: "all streams of several inputs", mode: "none", args: []string{"--all-audio-streams", input, other}, want: exitCodeUsage},
+		{name: "all streams with audio stream", mode: "none", args: []string{"--all-audio-streams", "--audio-stream", "1", input}, want: exitCodeUsage},
+		{name: "all streams without ffprobe", mode: "none", args: []string{"--all-audio-streams", "--ffprobe", "", input}, want: exitCodeUsage},
+		{name: "all streams with gate", mode: "none", args: []string{"--all-audio-streams", "--fail-on-silence", input}, want: exitCodeUsage},
+		{name: "all streams with export", mode: "none", args: []string{"--all-audio-streams", "--output", "srt", input}, want: exitCodeUsage},
+		{name: "fcpxml", mode: "partial", args: []string{"--output", "fcpxml", "--fps", "25", "--timeline-name", "Interview", input}, want: exitCodeOK},
+		{name: "resolve markers", mode: "partial", args: []string{"--output", "resolve-markers", "--fps", "29.97", "--drop-frame", "--timeline-name", "Interview", "--timeline-start", "01:00:00;00", input}, want: exitCodeOK},
+		{name: "fcpxml without timeline name", mode: "none", args: []string{"--output", "fcpxml", "--fps", "25", input}, want: exitCodeUsage},
+		{name: "resolve markers without fps", mode: "none", args: []string{"--output", "resolve-markers", "--timeline-name", "Interview", input}, want: exitCodeUsage},
+		{name: "invalid timeline start", mode: "none", args: []string{"--output", "fcpxml", "--fps", "25", "--timeline-name", "Interview", "--timeline-start", "01:00:00:25", input}, want: exitCodeUsage},
+		{name: "vtt", mode: "partial", args: []string{"--output", "vtt", "--cue-text", "silence {index}", input}, want: exitCodeOK},
+		{name: "invalid cue text", mode: "none", args: []string{"--output", "srt", "--cue-text", "a --> b", input}, want: exitCodeUsage},
+		{name: "chapters", mode: "partial", args: []string{"--output", "ffmetadata", input}, want: exitCodeOK},
+		{name: "negative chapter spacing", mode: "none", args: []string{"--output", "chapters", "--chapter-spacing", "-1", input}, want: exitCodeUsage},
+		{name: "trim command", mode: "partial", args: []string{"--emit-trim-command", "--trim-padding", "0.2", input}, want: exitCodeOK},
+		{name: "trim command for a silent input", mode: "full", args: []string{"--emit-trim-command", input}, want: exitCodeFullSilence},
+		{name: "trim command for several inputs", mode: "none", args: []string{"--emit-trim-command", input, other}, want: exitCodeUsage},
+		{name: "trim padding without command", mode: "none", args: []string{"--trim-padding", "1", input}, want: exitCodeUsage},
+		{name: "segments", mode: "partial", args: []string{"--emit-segments", filepath.Join(dir, "segments.ffconcat"), input}, want: exitCodeOK},
+		{name: "segments for a silent input", mode: "full", args: []string{"--emit-segments", filepath.Join(dir, "silent.ffconcat"), input}, want: exitCodeFullSilence},
+		{name: "segments to stdout", mode: "none", args: []string{"--emit-segments", "-", input}, want: exitCodeUsage},
+		{name: "minimum segment without segments", mode: "none", args: []string{"--emit-trim-command", "--min-segment-duration", "1", input}, want: exitCodeUsage},
+		{name: "expect missing file", mode: "none", args: []string{"--expect", filepath.Join(dir, "missing.json"), input}, want: exitCodeUsage},
+		{name: "expect with write expect", mode: "none", args: []string{"--expect", input, "--write-expect", filepath.Join(dir, "expected.json"), input}, want: exitCodeUsage},
+		{name: "expect tolerance without expect", mode: "none", args: []string{"--expect-tolerance", "0.1", input}, want: exitCodeUsage},
+		{name: "write expect for several inputs", mode: "none", args: []string{"--write-expect", filepath.Join(dir, "expected.json"), input, other}, want: exitCodeUsage},
+		{name: "at position", mode: "partial", args: []string{"--at", "00:00:08", "--output", "json", input}, want: exitCodeOK},
+		{name: "at with several inputs", mode: "none", args: []string{"--at", "8", input, other}, want: exitCodeUsage},
+		{name: "invalid at", mode: "none", args: []string{"--at", "8s", input}, want: exitCodeUsage},
+		{name: "histogram", mode: "partial", args: []string{"--histogram", "2,10", "--output", "json", input}, want: exitCodeOK},
+		{name: "unsorted histogram bounds", mode: "none", args: []string{"--histogram", "10,2", input}, want: exitCodeUsage},
+		{name: "histogram with export", mode: "none", args: []string{"--histogram", "2", "--output", "srt", input}, want: exitCodeUsage},
+		{name: "timeline", mode: "partial", args: []string{"--timeline", "--timeline-width", "40", "--timeline-style", "blocks", input}, want: exitCodeOK},
+		{name: "timeline with json", mode: "none", args: []string{"--timeline", "--output", "json", input}, want: exitCodeUsage},
+		{name: "unknown timeline style", mode: "none", args: []string{"--timeline", "--timeline-style", "braille", input}, want: exitCodeUsage},
+		{name: "timeline width without timeline", mode: "none", args: []string{"--timeline-width", "40", input}, want: exitCodeUsage},
+		{name: "porcelain with json", mode: "none", args: []string{"--porcelain", "--output", "json", input}, want: exitCodeUsage},
+		{name: "porcelain for several inputs", mode: "none", args: []string{"--porcelain", input, other}, want: exitCodeUsage},
+		{name: "porcelain with timeline", mode: "none", args: []string{"--porcelain", "--timeline", input}, want: exitCodeUsage},
+		{name: "quiet batch", mode: "partial", args: []string{"--quiet", "--fail-on-silence", input, other}, want: exitCodeSilenceDetected},
+		{name: "quiet and verbose", mode: "none", args: []string{"--quiet", "--verbose", input}, want: exitCodeUsage},
+		{name: "gate from config", mode: "partial", args: []string{"--config", gated, input}, want: exitCodeSilenceDetected},
+		{name: "gate overridden on command line", mode: "partial", args: []string{"--config", gated, "--fail-on-silence=false", input}, want: exitCodeOK},
+		{name: "unknown config key", mode: "none", args: []string{"--config", misspelled, input}, want: exitCodeUsage},
+		{name: "missing config file", mode: "none", args: []string{"--config", filepath.Join(dir, "missing.yaml"), input}, want: exitCodeUsage},
+		{name: "noise ratio over noise from environment", mode: "partial", args: []string{"--silence-noise-ratio", "0.001", "--fail-on-silence", input}, env: []string{"SILENCE_DETECTOR_SILENCE_NOISE=-35"}, want: exitCodeSilenceDetected},
+		{name: "noise ratio and noise from environment", mode: "none", args: []string{input}, env: []string{"SILENCE_DETECTOR_SILENCE_NOISE=-35", "SILENCE_DETECTOR_SILENCE_NOISE_RATIO=0.001"}, want: exitCodeUsage},
+		{name: "noise ratio from environment over config", mode: "partial", args: []string{"--config", quietNoise, "--fail-on-silence", input}, env: []string{"SILENCE_DETECTOR_SILENCE_NOISE_RATIO=0.001"}, want: exitCodeSilenceDetected},
+		{name: "verbose over quiet from config", mode: "none", args: []string{"--config", quietNoise, "--verbose", input}, want: exitCodeOK},
+		{name: "gate from environment over command line gate", mode: "partial", args: []string{"--fail-on-fully-silent", input}, env: []string{"SILENCE_DETECTOR_FAIL_IF_NOT_FULLY_SILENT=true"}, want: exitCodeOK},
+		{name: "noise ratio over aliased noise from environment", mode: "partial", args: []string{"--silence-noise-ratio", "0.001", "--fail-on-silence", input}, env: []string{"SILENCE_DETECTOR_NOISE_DB=-35"}, want: exitCodeSilenceDetected},
+		{name: "noise ratio and aliased noise from environment", mode: "none", args: []string{input}, env: []string{"SILENCE_DETECTOR_NOISE_DB=-35", "SILENCE_DETECTOR_SILENCE_NOISE_RATIO=0.001"}, want: exitCodeUsage},
+		{name: "noise ratio over aliased noise from config", mode: "partial", args: []string{"--config", aliasedNoise, "--silence-noise-ratio", "0.001", "--fail-on-silence", input}, want: exitCodeSilenceDetected},
+		{name: "directory without media", mode: "none", args: []string{emptyDir}, want: exitCodeNoInputs},
+		{name: "glob without match", mode: "none", args: []string{filepath.Join(dir, "*.flac")}, want: exitCodeNoInputs},
+		{name: "empty input list", mode: "none", args: []string{"--input-list", emptyList}, want: exitCodeNoInputs},
+		{name: "conflicting gates", mode: "none", args: []string{"--fail-on-fully-silent", "--fail-if-not-fully-silent", input}, want: exitCodeUsage},
+		{name: "downmix per channel", mode: "none", args: []string{"--downmix-mono", "--per-channel", input}, want: exitCodeUsage},
+		{name: "fast preset", mode: "partial", args: []string{"--fast", "--per-channel", input}, want: exitCodeOK},
+		{name: "dry run does not execute", mode: "fail", args: []string{"--dry-run", input}, want: exitCodeOK},
+		{name: "adaptive with stdin", mode: "none", args: []string{"--adaptive", "-"}, want: exitCodeUsage},
+		{name: "energy timeline", mode: "partial", args: []string{"--energy-timeline", "--window", "0.25", input}, want: exitCodeOK},
+		{name: "energy timeline with stdin", mode: "none", args: []string{"--energy-timeline", "-"}, want: exitCodeUsage},
+		{name: "energy timeline window too short", mode: "none", args: []string{"--energy-timeline", "--window", "0.01", input}, want: exitCodeUsage},
+		{name: "energy timeline window as duration", mode: "none", args: []string{"--energy-timeline", "--window", "250ms", input}, want: exitCodeOK},
+		{name: "live energy timeline", mode: "none", args: []string{"--live", "--energy-timeline", input}, want: exitCodeUsage},
+		{name: "quality", mode: "partial", args: []string{"--quality", input}, want: exitCodeOK},
+		{name: "clipped samples within limit", mode: "partial", args: []string{"--max-clipped-samples", "12", input}, want: exitCodeOK},
+		{name: "clipped samples exceed limit", mode: "partial", args: []string{"--max-clipped-samples", "0", input}, want: exitCodeLimitExceeded},
+		{name: "dc offset exceeds limit", mode: "partial", args: []string{"--max-dc-offset", "0.001", input}, want: exitCodeLimitExceeded},
+		{name: "dc offset above full scale", mode: "none", args: []string{"--max-dc-offset", "2", input}, want: exitCodeUsage},
+		{name: "quality with stdin", mode: "none", args: []string{"--quality", "-"}, want: exitCodeUsage},
+		{name: "live quality", mode: "none", args: []string{"--live", "--quality", input}, want: exitCodeUsage},
+		{name: "interval levels with stdin", mode: "none", args: []string{"--measure-intervals", "-"}, want: exitCodeUsage},
+		{name: "hysteresis with stdin", mode: "none", args: []string{"--hysteresis-db", "10", "-"}, want: exitCodeUsage},
+		{name: "hysteresis above 0 dB", mode: "none", args: []string{"--silence-noise", "-5", "--hysteresis-db", "10", input}, want: exitCodeUsage},
+		{name: "hysteresis", mode: "partial", args: []string{"--hysteresis-db", "10", "--fail-on-silence", input}, want: exitCodeSilenceDetected},
+		{name: "dead air", mode: "partial", args: []string{"--dead-air", "--fail-on-silence", input}, want: exitCodeSilenceDetected},
+		{name: "black with stdin", mode: "none", args: []string{"--detect-black", "-"}, want: exitCodeUsage},
+		{name: "freeze", mode: "none", args: []string{"--detect-freeze", "--detect-black", input}, want: exitCodeOK},
+		{name: "freeze with stdin", mode: "none", args: []string{"--detect-freeze", "-"}, want: exitCodeUsage},
+		{name: "invalid freeze noise", mode: "none", args: []string{"--detect-freeze", "--freeze-noise", "5", input}, want: exitCodeUsage},
+		{name: "invalid black ratio", mode: "none", args: []string{"--detect-black", "--black-ratio", "2", input}, want: exitCodeUsage},
+		{name: "max intervals", mode: "partial", args: []string{"--max-intervals", "1", input}, want: exitCodeOK},
+		{name: "hls audio rendition", mode: "partial", args: []string{"--hls-audio-rendition", "1", input}, want: exitCodeOK},
+		{name: "audio language", mode: "partial", args: []string{"--audio-language", "eng", input}, want: exitCodeOK},
+		{name: "audio language with audio stream", mode: "none", args: []string{"--audio-language", "eng", "--audio-stream", "0", input}, want: exitCodeUsage},
+		{name: "all streams with audio language", mode: "none", args: []string{"--all-audio-streams", "--audio-language", "eng", input}, want: exitCodeUsage},
+		{name: "hls rendition with audio stream", mode: "none", args: []string{"--hls-audio-rendition", "1", "--audio-stream", "0", input}, want: exitCodeUsage},
+		{name: "force and no download", mode: "none", args: []string{"--force-download", "--no-download", input}, want: exitCodeUsage},
+		{name: "failed callback keeps verdict", mode: "partial", args: []string{"--callback-url", receiver.URL, "--fail-on-silence", input, other}, want: exitCodeSilenceDetected},
+		{name: "invalid callback url", mode: "none", args: []string{"--callback-url", "hooks.example.com", input}, want: exitCodeUsage},
+		{name: "callback secret without url", mode: "none", args: []string{"--callback-secret", "s3cret", input}, want: exitCodeUsage},
+		{name: "live silent", mode: "full", args: []string{"--live", "--window", "10s", "rtmp://live.example.com/app/key"}, want: exitCodeFullSilence},
+		{name: "live with sound", mode: "partial", args: []string{"--live", "rtmp://live.example.com/app/key"}, want: exitCodeOK},
+		{name: "live unreachable", mode: "unreachable", args: []string{"--live", "rtmp://live.example.com/app/key"}, want: exitCodeStreamUnreachable},
+		{name: "live unreachable in loop", mode: "unreachable", args: []string{"--live", "--interval", "1m", "--timeout", "500ms", "rtmp://live.example.com/app/key"}, want: exitCodeStreamUnreachable},
+		{name: "live with start", mode: "none", args: []string{"--live", "--start", "5", input}, want: exitCodeUsage},
+		{name: "live with several inputs", mode: "none", args: []string{"--live", input, other}, want: exitCodeUsage},
+		{name: "interval without live", mode: "none", args: []string{"--interval", "1m", input}, want: exitCodeUsage},
+		{name: "watch with inputs", mode: "none", args: []string{"--watch", dir, input}, want: exitCodeUsage},
+		{name: "watch with timeout", mode: "none", args: []string{"--watch", dir, "--timeout", "1m"}, want: exitCodeUsage},
+		{name: "watch with json", mode: "none", args: []string{"--watch", dir, "--output", "json"}, want: exitCodeUsage},
+		{name: "watch missing directory", mode: "none", args: []string{"--watch", filepath.Join(dir, "missing")}, want: exitCodeInput},
+		{name: "negative max intervals", mode: "none", args: []string{"--max-intervals", "-1", input}, want: exitCodeUsage},
+		{name: "sweep", mode: "partial", args: []string{"--silence-noise", "-20,-30,-40", input}, want: exitCodeOK},
+		{name: "sweep with gate", mode: "none", args: []string{"--silence-noise", "-20,-30", "--fail-on-silence", input}, want: exitCodeUsage},
+		{name: "sweep with black", mode: "none", args: []string{"--silence-noise", "-20,-30", "--dead-air", input}, want: exitCodeUsage},
+		{name: "sweep with several inputs", mode: "none", args: []string{"--silence-noise", "-20,-30", input, other}, want: exitCodeUsage},
+		{name: "missing ffmpeg", mode: "none", args: []string{"--ffmpeg", filepath.Join(dir, "no-ffmpeg"), input}, want: exitCodeFFmpeg},
+		{name: "timeout", mode: "hang", args: []string{"--timeout", "200ms", input}, want: exitCodeTimeout},
+		{name: "negative timeout", mode: "none", args: []string{"--timeout", "-1s", input}, want: exitCodeUsage},
+		{name: "unknown engine", mode: "none", args: []string{"--engine", "bogus", input}, want: exitCodeUsage},
+		{name: "native engine with stdin", mode: "none", args: []string{"--engine", "native", "-"}, want: exitCodeUsage},
+		{name: "native engine with measure volume", mode: "none", args: []string{"--engine", "native", "--measure-volume", input}, want: exitCodeUsage},
+		{name: "native engine with non-wav input", mode: "fail", args: []string{"--engine", "native", input}, want: exitCodeInput},
+		{name: "incomplete raw pcm format", mode: "none", args: []string{"--pcm-format", "s16le", "--pcm-rate", "48000", input}, want: exitCodeUsage},
+		{name: "raw pcm with quality", mode: "none", args: []string{"--pcm-format", "s16le", "--pcm-rate", "48000", "--pcm-channels", "2", "--quality", input}, want: exitCodeUsage},
+		{name: "duration with several inputs", mode: "none", args: []string{"--duration", "10", input, other}, want: exitCodeUsage},
+		{name: "negative duration", mode: "none", args: []string{"--duration", "-1", input}, want: exitCodeUsage},
+		{name: "NaN silence duration", mode: "none", args: []string{"--silence-duration", "NaN", input}, want: exitCodeUsage},
+		{name: "infinite merge gap", mode: "none", args: []string{"--merge-gap", "inf", input}, want: exitCodeUsage},
+		{name: "NaN start", mode: "none", args: []string{"--start", "NaN", input}, want: exitCodeUsage},
+		{name: "NaN pad", mode: "partial", args: []string{"--fail-on-silence", "--pad-start", "NaN", input}, want: exitCodeUsage},
+		{name: "infinite pad", mode: "partial", args: []string{"--fail-on-silence", "--pad-end", "inf", input}, want: exitCodeUsage},
+		{name: "infinite duration", mode: "none", args: []string{"--duration", "inf", input}, want: exitCodeUsage},
+		{name: "NaN duration", mode: "none", args: []string{"--duration", "NaN", input}, want: exitCodeUsage},
+		{name: "batch reports most severe", mode: "partial", args: []string{"--fail-on-silence", input, filepath.Join(dir, "missing.wav"), other}, want: exitCodeInput},
+		{name: "batch verdict", mode: "partial", args: []string{"--fail-on-silence", input, other}, want: exitCodeSilenceDetected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--ffmpeg", ffmpeg, "--ffprobe", ""}, tt.args...)
+			cmd := exec.Command(os.Args[0], args...)
+			cmd.Env = append(os.Environ(), runMainEnv+"=1", "FAKE_FFMPEG_MODE="+tt.mode)
+			cmd.Env = append(cmd.Env, tt.env...)
+
+			output, err := cmd.CombinedOutput()
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			if code != tt.want {
+				t.Fatalf("expected exit code %d, got %d; output:\n%s", tt.want, code, output)
+			}
+		})
+	}
+}
+
+func TestInvalidOptionsListsEveryProblem(t *testing.T) {
+	args := []string{"--silence-duration", "0", "--silence-noise", "+30", "--downmix-mono", "--per-channel", "input.wav"}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != exitCodeUsage {
+		t.Fatalf("expected exit code %d, got %v; output:\n%s", exitCodeUsage, err, output)
+	}
+	if got := strings.Count(string(output), "invalid options: "); got != 3 {
+		t.Fatalf("expected three problems on their own lines, got %d; output:\n%s", got, output)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// detectionBuckets are the upper bounds, in seconds, of the detection wall time histogram.
+var detectionBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// metricsOutcomes are the detection outcomes always exported, so that their counters exist before the first detection.
+var metricsOutcomes = []detector.Outcome{
+	detector.OutcomeOK,
+	detector.OutcomeNoAudio,
+	detector.OutcomeTimeout,
+	detector.OutcomeCanceled,
+	detector.OutcomeUnrecognizedOutput,
+	detector.OutcomeFFmpegError,
+}
+
+// prometheusRecorder is the detector.Recorder behind --metrics-listen. It serves what it has recorded in the
+// Prometheus text exposition format, which is simple enough to write without a client library.
+type prometheusRecorder struct {
+	mu sync.Mutex
+	// bucketCounts counts the detections whose wall time falls into each of detectionBuckets, not cumulatively;
+	// the last element counts those beyond the largest bucket.
+	bucketCounts   []uint64
+	durationSum    float64
+	outcomes       map[detector.Outcome]uint64
+	inFlight       int64
+	silenceSeconds float64
+	downloadBytes  int64
+}
+
+func newPrometheusRecorder() *prometheusRecorder {
+	return &prometheusRecorder{
+		bucketCounts: make([]uint64, len(detectionBuckets)+1),
+		outcomes:     make(map[detector.Outcome]uint64),
+	}
+}
+
+func (r *prometheusRecorder) FFmpegStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight++
+}
+
+func (r *prometheusRecorder) FFmpegFinished() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+}
+
+func (r *prometheusRecorder) DetectionFinished(elapsed time.Duration, outcome detector.Outcome, silence float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seconds := elapsed.Seconds()
+	bucket := len(detectionBuckets)
+	for i, bound := range detectionBuckets {
+		if seconds <= bound {
+			bucket = i
+			break
+		}
+	}
+	r.bucketCounts[bucket]++
+	r.durationSum += seconds
+	r.outcomes[outcome]++
+	r.silenceSeconds += silence
+}
+
+func (r *prometheusRecorder) Downloaded(bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.downloadBytes += bytes
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (r *prometheusRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.write(w)
+}
+
+func (r *prometheusRecorder) write(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	const duration = "silence_detector_detection_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Wall time of silence detections.\n# TYPE %s histogram\n", duration, duration)
+	var count uint64
+	for i, bound := range detectionBuckets {
+		count += r.bucketCounts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", duration, formatMetric(bound), count)
+	}
+	count += r.bucketCounts[len(detectionBuckets)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", duration, count, duration, formatMetric(r.durationSum), duration, count)
+
+	const detections = "silence_detector_detections_total"
+	fmt.Fprintf(w, "# HELP %s Silence detections by outcome.\n# TYPE %s counter\n", detections, detections)
+	for _, outcome := range metricsOutcomes {
+		fmt.Fprintf(w, "%s{outcome=%q} %d\n", detections, outcome, r.outcomes[outcome])
+	}
+
+	writeMetric(w, "silence_detector_ffmpeg_processes_in_flight", "gauge", "ffmpeg processes currently running.", strconv.FormatInt(r.inFlight, 10))
+	writeMetric(w, "silence_detector_silence_seconds_total", "counter", "Seconds of silence detected.", formatMetric(r.silenceSeconds))
+	writeMetric(w, "silence_detector_download_bytes_total", "counter", "Bytes downloaded for remote inputs.", strconv.FormatInt(r.downloadBytes, 10))
+}
+
+func writeMetric(w io.Writer, name, kind, help, value string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, value)
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// serveMetrics serves the metrics of recorder on /metrics at addr until the returned function is called. The
+// address is bound before serveMetrics returns, so that a port already in use is reported at startup.
+func serveMetrics(addr string, recorder *prometheusRecorder) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serve metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", recorder)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
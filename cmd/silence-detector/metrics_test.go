@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestPrometheusRecorderExposition(t *testing.T) {
+	recorder := newPrometheusRecorder()
+	recorder.FFmpegStarted()
+	recorder.FFmpegStarted()
+	recorder.FFmpegFinished()
+	recorder.DetectionFinished(3*time.Second, detector.OutcomeOK, 2.5)
+	recorder.DetectionFinished(90*time.Second, detector.OutcomeOK, 10)
+	recorder.DetectionFinished(2*time.Hour, detector.OutcomeTimeout, 0)
+	recorder.Downloaded(1024)
+	recorder.Downloaded(512)
+
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	for _, line := range []string{
+		"# TYPE silence_detector_detection_duration_seconds histogram",
+		`silence_detector_detection_duration_seconds_bucket{le="1"} 0`,
+		`silence_detector_detection_duration_seconds_bucket{le="5"} 1`,
+		`silence_detector_detection_duration_seconds_bucket{le="60"} 1`,
+		`silence_detector_detection_duration_seconds_bucket{le="120"} 2`,
+		`silence_detector_detection_duration_seconds_bucket{le="3600"} 2`,
+		`silence_detector_detection_duration_seconds_bucket{le="+Inf"} 3`,
+		"silence_detector_detection_duration_seconds_sum 7293",
+		"silence_detector_detection_duration_seconds_count 3",
+		`silence_detector_detections_total{outcome="ok"} 2`,
+		`silence_detector_detections_total{outcome="timeout"} 1`,
+		`silence_detector_detections_total{outcome="no_audio"} 0`,
+		"silence_detector_ffmpeg_processes_in_flight 1",
+		"silence_detector_silence_seconds_total 12.5",
+		"silence_detector_download_bytes_total 1536",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// reportEncoder writes JSON reports either as a single indented document or as line-delimited records. It is safe
+// for concurrent use, so workers can emit NDJSON records as their inputs finish.
+type reportEncoder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	legacy  bool
+}
+
+// newReportEncoder returns an encoder for the given format: outputFormatNDJSON writes one compact record per line,
+// any other format writes indented JSON. legacy selects the layout of --legacy-json.
+func newReportEncoder(w io.Writer, format outputFormat, legacy bool) *reportEncoder {
+	encoder := json.NewEncoder(w)
+	if format != outputFormatNDJSON {
+		encoder.SetIndent("", "  ")
+	}
+	return &reportEncoder{encoder: encoder, legacy: legacy}
+}
+
+// Encode writes report followed by a newline.
+func (e *reportEncoder) Encode(report any) error {
+	if e.legacy {
+		data, err := legacyJSON(report)
+		if err != nil {
+			return err
+		}
+		report = data
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(report)
+}
+
+// exportedRegions returns the regions of result that --output audacity and edl export, and their name: the silence
+// intervals, or the non-silent regions with --labels invert.
+func exportedRegions(result detector.DetectionResult, opts reportOptions) ([]detector.Interval, string) {
+	if opts.invertLabels {
+		return result.NonSilentIntervals(), "audio"
+	}
+	return result.Intervals, "silence"
+}
+
+// emitLabels writes the Audacity label track of result and exits if it cannot be written.
+func emitLabels(w io.Writer, result detector.DetectionResult, opts reportOptions) {
+	intervals, name := exportedRegions(result, opts)
+	if err := detector.WriteAudacityLabelsNamed(w, intervals, name); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write labels: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// emitEDL writes the CMX3600 EDL of res, titled with the input's base name, and exits if it cannot be written.
+func emitEDL(w io.Writer, res fileResult, opts reportOptions) {
+	intervals, name := exportedRegions(res.result, opts)
+	edl := opts.edl
+	edl.Title = inputBaseName(res.input)
+	edl.ClipName = name
+	if err := detector.WriteEDL(w, intervals, opts.timeFormat().fps, edl); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write EDL: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// emitSubtitles writes a SubRip or WebVTT cue per silence interval of result, depending on format, and exits if the
+// subtitles cannot be written.
+func emitSubtitles(w io.Writer, result detector.DetectionResult, format outputFormat, opts reportOptions) {
+	write := detector.WriteSRT
+	if format == outputFormatVTT {
+		write = detector.WriteVTT
+	}
+	if err := write(w, result.Intervals, opts.subtitles); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write subtitles: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// emitMarkers writes a marker per silence interval of result as FCPXML or, with outputFormatResolveMarkers, as a
+// Resolve marker EDL, and exits if they cannot be written. The timeline lasts as long as the analysed span.
+func emitMarkers(w io.Writer, result detector.DetectionResult, format outputFormat, opts reportOptions) {
+	write := detector.WriteFCPXMLMarkers
+	if format == outputFormatResolveMarkers {
+		write = detector.WriteResolveMarkers
+	}
+	markers := opts.markers
+	markers.Duration = result.WindowStart + result.InputDuration
+	if err := write(w, result.Intervals, opts.timeFormat().fps, markers); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write markers: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// emitChapters writes the chapters of result as YouTube description lines or, with outputFormatFFMetadata, as an
+// FFMETADATA1 file, and exits if they cannot be written.
+func emitChapters(w io.Writer, result detector.DetectionResult, format outputFormat, opts reportOptions) {
+	write := detector.WriteYouTubeChapters
+	if format == outputFormatFFMetadata {
+		write = detector.WriteFFMetadataChapters
+	}
+	if err := write(w, result.Chapters(opts.chapterMinSilence, opts.chapterSpacing)); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write chapters: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// emitReport writes report with encoder and exits if it cannot be encoded.
+func emitReport(encoder *reportEncoder, report any) {
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// reportOutput is where a report is written: stdout, or a file that is written to a temporary file and renamed into
+// place by Close, so readers never observe a partial report.
+type reportOutput struct {
+	io.Writer
+	file *os.File
+	path string
+}
+
+// openReportOutput returns the destination for --output-file. An empty path or - selects stdout. When mkdir is set,
+// missing parent directories are created.
+func openReportOutput(target string, mkdir bool) (*reportOutput, error) {
+	if target == "" || target == "-" {
+		return &reportOutput{Writer: os.Stdout}, nil
+	}
+
+	dir := filepath.Dir(target)
+	if mkdir {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory %q: %w", dir, err)
+		}
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file %q: %w", target, err)
+	}
+	return &reportOutput{Writer: file, file: file, path: target}, nil
+}
+
+// Close moves a file report into place. It does nothing for stdout.
+func (o *reportOutput) Close() error {
+	if o.file == nil {
+		return nil
+	}
+
+	err := o.file.Chmod(0o644)
+	if err == nil {
+		err = o.file.Sync()
+	}
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(o.file.Name(), o.path)
+	}
+	if err != nil {
+		os.Remove(o.file.Name())
+		return fmt.Errorf("failed to write output file %q: %w", o.path, err)
+	}
+	return nil
+}
+
+// prepareOutputDir checks that the --output-dir directory exists, creating it when mkdir is set.
+func prepareOutputDir(dir string, mkdir bool) error {
+	if mkdir {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory %q: %w", dir, err)
+		}
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("output directory %q: %w (use --mkdir to create it)", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output directory %q is not a directory", dir)
+	}
+	return nil
+}
+
+// writeResultFile writes the report for a single input of an --output-dir run to target.
+func writeResultFile(target string, res fileResult, format outputFormat, opts reportOptions) error {
+	out, err := openReportOutput(target, false)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case outputFormatJSON, outputFormatNDJSON:
+		emitReport(newReportEncoder(out, format, opts.legacyJSON), newBatchReport(res, opts))
+	case outputFormatAudacity:
+		emitLabels(out, res.result, opts)
+	case outputFormatEDL:
+		emitEDL(out, res, opts)
+	case outputFormatSRT, outputFormatVTT:
+		emitSubtitles(out, res.result, format, opts)
+	case outputFormatFCPXML, outputFormatResolveMarkers:
+		emitMarkers(out, res.result, format, opts)
+	case outputFormatChapters, outputFormatFFMetadata:
+		emitChapters(out, res.result, format, opts)
+	default:
+		emitBatchText(out, res, opts)
+	}
+	return out.Close()
+}
+
+// writeRawOutput writes the ffmpeg output captured for each input to the --raw-output-file target. With several
+// inputs every capture is preceded by a "==> input <==" header line.
+func writeRawOutput(target string, mkdir bool, results []fileResult) error {
+	out, err := openReportOutput(target, mkdir)
+	if err != nil {
+		return err
+	}
+
+	for i, res := range results {
+		if len(results) > 1 {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "==> %s <==\n", displayInputPath(res.input))
+		}
+		io.WriteString(out, capturedOutput(res))
+	}
+	return out.Close()
+}
+
+// capturedOutput returns the raw ffmpeg output of res. When ffmpeg failed there is no result to capture into, so
+// the output tail kept with the error is returned instead.
+func capturedOutput(res fileResult) string {
+	if res.rawOutput != "" {
+		return res.rawOutput
+	}
+
+	var ffErr *detector.FFmpegError
+	if errors.As(res.err, &ffErr) && ffErr.Stderr != "" {
+		return ffErr.Stderr + "\n"
+	}
+	return ""
+}
+
+// formatExtension is the file extension used for reports of the given format in --output-dir runs.
+func formatExtension(format outputFormat) string {
+	switch format {
+	case outputFormatJSON:
+		return ".json"
+	case outputFormatNDJSON:
+		return ".ndjson"
+	case outputFormatEDL, outputFormatResolveMarkers:
+		return ".edl"
+	case outputFormatFCPXML:
+		return ".fcpxml"
+	case outputFormatSRT:
+		return ".srt"
+	case outputFormatVTT:
+		return ".vtt"
+	case outputFormatFFMetadata:
+		return ".ffmetadata"
+	default:
+		return ".txt"
+	}
+}
+
+// outputFileNames assigns each input a report file name made of its base name, without the media extension, and
+// the format's extension. Inputs sharing a base name are numbered in input order (a.json, a-2.json, ...), comparing
+// names case-insensitively so that the result is the same on every filesystem.
+func outputFileNames(inputs []string, format outputFormat) []string {
+	ext := formatExtension(format)
+	used := make(map[string]bool, len(inputs))
+	names := make([]string, len(inputs))
+
+	for i, raw := range inputs {
+		base := inputBaseName(raw)
+		name := base + ext
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// inputBaseName returns the last path element of an input without its extension, for example "a" for
+// /media/a.mp4 or https://example.com/media/a.mp4?token=x.
+func inputBaseName(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "-" {
+		return "stdin"
+	}
+
+	name := raw
+	if input, err := detector.ResolveInput(raw); err == nil {
+		name = filepath.ToSlash(input.Location)
+		if input.Kind != detector.InputLocal {
+			if parsed, err := url.Parse(input.Raw); err == nil {
+				name = parsed.Path
+			}
+		}
+	}
+
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		return "input"
+	}
+	return name
+}
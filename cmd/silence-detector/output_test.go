@@ -0,0 +1,928 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestReportEncoderWritesNDJSONRecords(t *testing.T) {
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, checkFullSilence: true}
+	results := []fileResult{
+		{
+			input: "a.wav",
+			result: detector.DetectionResult{
+				InputDuration: 10,
+				Intervals:     []detector.SilenceInterval{{Start: 0, End: 10, Duration: 10}},
+			},
+		},
+		{input: "b.wav", err: errors.New("failed to stat input")},
+		{input: "c.mp4", noAudioStream: true},
+	}
+
+	var out bytes.Buffer
+	encoder := newReportEncoder(&out, outputFormatNDJSON, false)
+	for _, res := range results {
+		if err := encoder.Encode(newBatchReport(res, opts)); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+
+	type record struct {
+		Input       string                     `json:"input"`
+		Status      string                     `json:"status"`
+		Error       string                     `json:"error"`
+		NoiseDB     float64                    `json:"noise_db"`
+		Duration    float64                    `json:"duration"`
+		FullySilent *bool                      `json:"fully_silent"`
+		Intervals   []detector.SilenceInterval `json:"intervals"`
+	}
+
+	var records []record
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != len(results) {
+		t.Fatalf("expected %d records, got %d", len(results), len(records))
+	}
+
+	if got := records[0]; got.Input != "a.wav" || got.Status != statusOK || got.NoiseDB != -30 || got.Duration != 10 ||
+		len(got.Intervals) != 1 || got.FullySilent == nil || !*got.FullySilent {
+		t.Fatalf("unexpected record for a.wav: %+v", got)
+	}
+	if got := records[1]; got.Status != statusError || got.Error != "failed to stat input" || got.Intervals != nil {
+		t.Fatalf("unexpected record for b.wav: %+v", got)
+	}
+	if got := records[2]; got.Status != statusNoAudioStream || got.Error != "" {
+		t.Fatalf("unexpected record for c.mp4: %+v", got)
+	}
+}
+
+func TestReportEncoderConcurrentRecordsStayOnSeparateLines(t *testing.T) {
+	var out bytes.Buffer
+	encoder := newReportEncoder(&out, outputFormatNDJSON, false)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := fileResult{input: "a.wav", result: detector.DetectionResult{InputDuration: 5}}
+			if err := encoder.Encode(newBatchReport(res, reportOptions{})); err != nil {
+				t.Errorf("encode: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("expected 50 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("invalid JSON line %q", line)
+		}
+	}
+}
+
+func TestReportEncoderIndentsJSONDocument(t *testing.T) {
+	var out bytes.Buffer
+	encoder := newReportEncoder(&out, outputFormatJSON, false)
+	if err := encoder.Encode(newJSONReport(detector.DetectionResult{InputDuration: 5}, reportOptions{inputPath: "a.wav"})); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "\n  \"input\": \"a.wav\"") {
+		t.Fatalf("expected an indented document, got %q", out.String())
+	}
+
+	var report detector.Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if report.Input != "a.wav" || report.Duration != 5 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestJSONReportsRoundTrip(t *testing.T) {
+	res := fileResult{
+		input: "a.mp4",
+		result: detector.DetectionResult{
+			InputDuration: 10,
+			Intervals:     []detector.Interval{{Start: 0, End: 2, Duration: 2}, {Start: 5, End: 8, Duration: 3}},
+		},
+		black: []detector.Interval{{Start: 1, End: 2, Duration: 1}},
+	}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, checkTrailingSilence: true, detectBlack: true, deadAir: true, detectFreeze: true}
+
+	want := newFileReport(res, opts)
+	var out bytes.Buffer
+	if err := newReportEncoder(&out, outputFormatJSON, false).Encode(want); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var got detector.Report
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Fatalf("report changed in a round trip:\n got %+v\nwant %+v", got, *want)
+	}
+	if got.SchemaVersion != detector.ReportSchemaVersion || got.Freeze == nil || got.Freeze.Count != 0 {
+		t.Fatalf("unexpected report %+v", got)
+	}
+
+	out.Reset()
+	failed := fileResult{input: "b.wav", err: errors.New("failed to stat input")}
+	encoder := newReportEncoder(&out, outputFormatNDJSON, false)
+	for _, res := range []fileResult{res, failed} {
+		if err := encoder.Encode(newBatchReport(res, opts)); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+	decoder := json.NewDecoder(&out)
+	var records []batchReport
+	for decoder.More() {
+		var record batchReport
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("invalid record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 || records[0].Report == nil || !reflect.DeepEqual(records[0].Intervals, want.Intervals) {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	for _, record := range records {
+		if record.SchemaVersion != detector.ReportSchemaVersion {
+			t.Errorf("expected schema version %d in %+v", detector.ReportSchemaVersion, record)
+		}
+	}
+}
+
+func TestOutputFileNamesAreDeterministic(t *testing.T) {
+	inputs := []string{
+		"media/a.mp4",
+		"other/a.wav",
+		"https://example.com/clips/A.mp3?token=x",
+		"-",
+		"s3://bucket/path/b.flac",
+		"a-2.wav",
+	}
+
+	got := outputFileNames(inputs, outputFormatJSON)
+	want := []string{"a.json", "a-2.json", "A-3.json", "stdin.json", "b.json", "a-2-2.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if again := outputFileNames(inputs, outputFormatJSON); !reflect.DeepEqual(again, got) {
+		t.Fatalf("names changed between runs: %v vs %v", got, again)
+	}
+	if text := outputFileNames([]string{"a.wav"}, outputFormatText); text[0] != "a.txt" {
+		t.Fatalf("expected a.txt for text output, got %q", text[0])
+	}
+}
+
+func TestReportOutputReplacesFileOnClose(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "nested", "report.json")
+
+	if _, err := openReportOutput(target, false); err == nil {
+		t.Fatal("expected an error for a missing parent directory without mkdir")
+	}
+
+	out, err := openReportOutput(target, true)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := out.Write([]byte("{}\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected the report to be hidden until Close, got %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "{}\n" {
+		t.Fatalf("unexpected report contents %q: %v", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the report in the directory, got %d entries", len(entries))
+	}
+}
+
+func TestWriteRawOutput(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "ffmpeg.log")
+	results := []fileResult{
+		{input: "a.wav", rawOutput: "size=N/A time=00:00:01.00\n"},
+		{input: "b.wav", err: detectionError{err: &detector.FFmpegError{Stderr: "b.wav: Invalid data found when processing input"}}},
+	}
+
+	if err := writeRawOutput(target, false, results[:1]); err != nil {
+		t.Fatalf("writeRawOutput: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "size=N/A time=00:00:01.00\n" {
+		t.Fatalf("unexpected single-input capture %q", data)
+	}
+
+	if err := writeRawOutput(target, false, results); err != nil {
+		t.Fatalf("writeRawOutput: %v", err)
+	}
+	want := "==> a.wav <==\nsize=N/A time=00:00:01.00\n\n==> b.wav <==\nb.wav: Invalid data found when processing input\n"
+	if data, _ := os.ReadFile(target); string(data) != want {
+		t.Fatalf("unexpected batch capture %q, want %q", data, want)
+	}
+}
+
+func TestReportsIncludeVolume(t *testing.T) {
+	mean, peak := -27.3, -4.0
+	result := detector.DetectionResult{InputDuration: 10, MeanVolumeDB: &mean, MaxVolumeDB: &peak}
+
+	data, err := json.Marshal(newJSONReport(result, reportOptions{measureVolume: true}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"mean_volume_db":-27.3,"max_volume_db":-4`) {
+		t.Fatalf("expected volume fields, got %s", data)
+	}
+	if data, _ := json.Marshal(newJSONReport(result, reportOptions{})); strings.Contains(string(data), "volume") {
+		t.Fatalf("expected no volume fields without --measure-volume, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, reportOptions{measureVolume: true})
+	if !strings.Contains(text.String(), "Mean volume: -27.3dB, Max volume: -4.0dB\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	text.Reset()
+	emitText(&text, detector.DetectionResult{InputDuration: 10}, reportOptions{measureVolume: true})
+	if !strings.Contains(text.String(), "Mean volume: n/a, Max volume: n/a\n") {
+		t.Fatalf("unexpected text report without measurements:\n%s", text.String())
+	}
+}
+
+func TestReportsIncludeLoudness(t *testing.T) {
+	peak := -1.5
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		Loudness:      &detector.Loudness{IntegratedLUFS: -23, RangeLU: 5.2, TruePeakDBFS: &peak},
+	}
+
+	data, err := json.Marshal(newJSONReport(result, reportOptions{measureLoudness: true}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"loudness":{"integrated_lufs":-23,"range_lu":5.2,"true_peak_dbfs":-1.5}`) {
+		t.Fatalf("expected a nested loudness object, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, reportOptions{measureLoudness: true})
+	want := "Loudness (EBU R128):\n  Integrated: -23.0 LUFS\n  Range: 5.2 LU\n  True peak: -1.5 dBFS\n"
+	if !strings.Contains(text.String(), want) {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+}
+
+func TestReportsShowAdaptiveThreshold(t *testing.T) {
+	floor := -62.5
+	result := detector.DetectionResult{InputDuration: 10, ThresholdDB: -52.5, NoiseFloorDB: &floor}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, adaptive: true}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"noise_db":-52.5,"noise_floor_db":-62.5,`) {
+		t.Fatalf("expected the effective threshold and noise floor, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Noise threshold: -52.50dB (adaptive, noise floor -62.50dB), Minimum duration: 0.50s\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	digitalSilence := math.Inf(-1)
+	result = detector.DetectionResult{InputDuration: 10, ThresholdDB: -30, NoiseFloorDB: &digitalSilence}
+	data, err = json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal with an infinite noise floor: %v", err)
+	}
+	if strings.Contains(string(data), "noise_floor_db") || !strings.Contains(string(data), `"noise_db":-30`) {
+		t.Fatalf("expected the fallback threshold without a noise floor, got %s", data)
+	}
+}
+
+func TestReportsIncludeBlackAndDeadAir(t *testing.T) {
+	res := fileResult{
+		result: detector.DetectionResult{InputDuration: 10, Intervals: []detector.SilenceInterval{{Start: 7, End: 10, Duration: 3}}},
+		black:  []detector.Interval{{Start: 8, End: 10, Duration: 2}, {Start: 0, End: 1, Duration: 1}},
+	}
+	opts := reportOptions{detectBlack: true, deadAir: true}
+
+	report := newFileReport(res, opts)
+	if report.Black == nil || report.Black.Count != 2 || report.Black.Total != 3 {
+		t.Fatalf("unexpected black section %+v", report.Black)
+	}
+	if report.DeadAir == nil || report.DeadAir.Count != 1 || report.DeadAir.Intervals[0].Start != 8 || report.DeadAir.Total != 2 {
+		t.Fatalf("unexpected dead air section %+v", report.DeadAir)
+	}
+
+	var text bytes.Buffer
+	emitFileText(&text, res, opts)
+	for _, want := range []string{"Detected 2 black interval(s):\n", "Total black: 3.000s\n", "Detected 1 dead air interval(s):\n1. start=8.000s end=10.000s duration=2.000s\n"} {
+		if !strings.Contains(text.String(), want) {
+			t.Fatalf("expected %q in text report:\n%s", want, text.String())
+		}
+	}
+
+	data, err := json.Marshal(newFileReport(res, reportOptions{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), "black") || strings.Contains(string(data), "dead_air") {
+		t.Fatalf("expected no black sections without --detect-black, got %s", data)
+	}
+
+	data, err = json.Marshal(newFileReport(fileResult{noVideoStream: true}, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"no_video_stream":true`) || strings.Contains(string(data), `"black"`) {
+		t.Fatalf("expected an input without video to be flagged, got %s", data)
+	}
+}
+
+func TestReportsIncludeFreeze(t *testing.T) {
+	res := fileResult{
+		result: detector.DetectionResult{InputDuration: 10},
+		freeze: []detector.Interval{{Start: 9, End: 10, Duration: 1}},
+	}
+	opts := reportOptions{detectFreeze: true}
+
+	report := newFileReport(res, opts)
+	if report.Freeze == nil || report.Freeze.Count != 1 || report.Black != nil {
+		t.Fatalf("expected only a freeze section, got %+v", report)
+	}
+
+	var text bytes.Buffer
+	emitFileText(&text, res, opts)
+	if !strings.Contains(text.String(), "Detected 1 freeze interval(s):\n1. start=9.000s end=10.000s duration=1.000s\nTotal freeze: 1.000s\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	text.Reset()
+	emitFileText(&text, fileResult{noVideoStream: true}, opts)
+	if !strings.Contains(text.String(), "Input has no video stream") {
+		t.Fatalf("expected a note about the missing video, got:\n%s", text.String())
+	}
+}
+
+func TestReportsMarkTruncatedResults(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		Intervals:     []detector.SilenceInterval{{Start: 0, End: 10, Duration: 10}},
+		Truncated:     true,
+	}
+	opts := reportOptions{checkFullSilence: true}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"interval_count":1,"truncated":true`) || !strings.Contains(string(data), `"full_silence_unknown":true`) || strings.Contains(string(data), "fully_silent") {
+		t.Fatalf("expected a truncated report whose full silence is unknown, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Stopped at --max-intervals") || !strings.Contains(text.String(), "Cannot tell whether the input is entirely silent: analysis stopped at --max-intervals.") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+}
+
+func TestReportsListNormalizationWarnings(t *testing.T) {
+	result, _ := detector.Normalize(detector.DetectionResult{
+		InputDuration: 10,
+		Intervals:     []detector.SilenceInterval{{Start: 8, End: 10.004, Duration: 2.004}},
+	})
+	opts := reportOptions{}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"warnings":[{"code":"clamped_end","interval":{"start":8,"end":10.004,"duration":2.004}`) {
+		t.Fatalf("expected the warning in the JSON report, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Adjusted ffmpeg output: silence from 8.000s to 10.004s ends after the input at 10.000s\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	result.Warnings = []detector.Warning{{Code: detector.WarningDurationMismatch, Message: "the audio lasts at least 12.000s, past the known duration of 10.000s"}}
+	text.Reset()
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Warning: the audio lasts at least 12.000s, past the known duration of 10.000s\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+}
+
+func TestReportsDescribeAudio(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		AudioInfo:     &detector.AudioInfo{StreamIndex: 1, Codec: "aac", SampleRate: 48000, Channels: 2, ChannelLayout: "stereo", BitRate: 128000},
+	}
+	opts := reportOptions{}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"audio":{"stream_index":1,"codec":"aac","sample_rate":48000,"channels":2,"channel_layout":"stereo","bit_rate":128000}`) {
+		t.Fatalf("expected the audio stream in the JSON report, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Audio: aac, 48000 Hz, stereo (2 channels), 128 kb/s, stream #0:1\n") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	result.AudioInfo = nil
+	if data, _ := json.Marshal(newJSONReport(result, opts)); strings.Contains(string(data), `"audio"`) {
+		t.Fatalf("expected no audio section without audio info, got %s", data)
+	}
+}
+
+func TestReportsDescribeLiveChecks(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 30,
+		Intervals:     []detector.SilenceInterval{{Start: 0, End: 30, Duration: 30}},
+	}
+	opts := reportOptions{checkFullSilence: true, live: true, checkedAt: time.Date(2026, 3, 1, 12, 0, 5, 0, time.UTC)}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"checked_at":"2026-03-01T12:00:05Z"`) || !strings.Contains(string(data), `"fully_silent":true`) {
+		t.Fatalf("expected the check time and verdict, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Checked at: 2026-03-01T12:00:05Z") || !strings.Contains(text.String(), "Stream is silent for the whole window.") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+}
+
+func TestReportsRoundToPrecision(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 10.0004,
+		Intervals: []detector.Interval{
+			{Start: 6.1, End: 9.6000000000000005, Duration: 3.5000000000000004},
+			{Start: 0.2, End: 2.3, Duration: 2.0999999999999996},
+		},
+	}
+
+	data, err := json.Marshal(newJSONReport(result, reportOptions{checkTrailingSilence: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{
+		`"duration":10,`,
+		`"total_silence":5.6,`,
+		`"intervals":[{"start":0.2,"end":2.3,"duration":2.1},{"start":6.1,"end":9.6,"duration":3.5}]`,
+	} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("expected %s in %s", field, data)
+		}
+	}
+
+	opts := reportOptions{times: &timeFormat{precision: 1}}
+	var report detector.Report
+	data, _ = json.Marshal(newJSONReport(result, opts))
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Intervals[0] != (detector.Interval{Start: 0.2, End: 2.3, Duration: 2.1}) || report.Longest.Duration != 3.5 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	for _, line := range []string{
+		"Input duration: 10.0s\n",
+		"1. start=0.2s end=2.3s duration=2.1s\n",
+		"2. start=6.1s end=9.6s duration=3.5s\n",
+		"Total silence: 5.6s (56.0% of input)\n",
+	} {
+		if !strings.Contains(text.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, text.String())
+		}
+	}
+}
+
+func TestTextReportTimeFormats(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 3723.5,
+		Intervals:     []detector.Interval{{Start: 62.04, End: 3723.5, Duration: 3661.46}},
+	}
+
+	var clock bytes.Buffer
+	emitText(&clock, result, reportOptions{times: &timeFormat{precision: 3, style: timeStyleClock}})
+	for _, line := range []string{
+		"Input duration: 01:02:03.500\n",
+		"1. start=00:01:02.040 end=01:02:03.500 duration=01:01:01.460\n",
+	} {
+		if !strings.Contains(clock.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, clock.String())
+		}
+	}
+
+	var smpte bytes.Buffer
+	emitText(&smpte, result, reportOptions{times: &timeFormat{precision: 3, style: timeStyleSMPTE, fps: 25}})
+	if line := "1. start=00:01:02:01 end=01:02:03:13 duration=01:01:01:12\n"; !strings.Contains(smpte.String(), line) {
+		t.Errorf("expected %q in:\n%s", line, smpte.String())
+	}
+
+	data, err := json.Marshal(newJSONReport(result, reportOptions{times: &timeFormat{precision: 3, style: timeStyleClock}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"intervals":[{"start":62.04,"end":3723.5,"duration":3661.46}]`) {
+		t.Errorf("expected JSON times in seconds, got %s", data)
+	}
+}
+
+func TestEmitLabels(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		Intervals:     []detector.Interval{{Start: 6, End: 10, Duration: 4}, {Start: 0, End: 1.5, Duration: 1.5}},
+	}
+
+	var silence bytes.Buffer
+	emitLabels(&silence, result, reportOptions{})
+	if want := "0.000000\t1.500000\tsilence 1\n6.000000\t10.000000\tsilence 2\n"; silence.String() != want {
+		t.Errorf("unexpected labels %q, want %q", silence.String(), want)
+	}
+
+	var audio bytes.Buffer
+	emitLabels(&audio, result, reportOptions{invertLabels: true})
+	if want := "1.500000\t6.000000\taudio 1\n"; audio.String() != want {
+		t.Errorf("unexpected inverted labels %q, want %q", audio.String(), want)
+	}
+}
+
+func TestEmitSilentAt(t *testing.T) {
+	result := detector.DetectionResult{Intervals: []detector.Interval{{Start: 750, End: 760, Duration: 10}}}
+	times := timeFormat{precision: defaultPrecision}
+
+	var silent, audible bytes.Buffer
+	emitSilentAt(&silent, result, 754.5, times)
+	emitSilentAt(&audible, result, 12, times)
+	if want := "Silent at 754.500s: yes, start=750.000s end=760.000s duration=10.000s\n"; silent.String() != want {
+		t.Errorf("unexpected answer %q, want %q", silent.String(), want)
+	}
+	if want := "Silent at 12.000s: no\n"; audible.String() != want {
+		t.Errorf("unexpected answer %q, want %q", audible.String(), want)
+	}
+}
+
+func TestPositionFlag(t *testing.T) {
+	for value, want := range map[string]float64{"754.5": 754.5, "00:12:34.5": 754.5, "12:34.5": 754.5, "1:00:00": 3600} {
+		var at positionFlag
+		if err := at.Set(value); err != nil || at.seconds != want {
+			t.Errorf("Set(%q) = %v, %v; want %v", value, at.seconds, err, want)
+		}
+	}
+	for _, invalid := range []string{"", "-1", "abc", "1:60", "1.5:00", "1:2:3:4"} {
+		var at positionFlag
+		if err := at.Set(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestReportsIncludeHistogram(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 60,
+		Intervals:     []detector.Interval{{Start: 1, End: 2, Duration: 1}, {Start: 10, End: 22, Duration: 12}, {Start: 30, End: 32, Duration: 2}},
+	}
+	opts := reportOptions{inputPath: "talk.wav", histogram: []float64{2, 10}}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	for _, want := range []string{
+		"Silence durations:\n",
+		"  under 2.000s: 1 interval(s), 1.000s in total\n",
+		"  2.000s to 10.000s: 1 interval(s), 2.000s in total\n",
+		"  10.000s or longer: 1 interval(s), 12.000s in total\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected %q in the text report:\n%s", want, text.String())
+		}
+	}
+
+	report := newJSONReport(result, opts)
+	if len(report.Histogram) != 3 || report.Histogram[2].Count != 1 || report.Histogram[2].Max != nil {
+		t.Fatalf("unexpected histogram %+v", report.Histogram)
+	}
+	if report := newJSONReport(result, reportOptions{}); report.Histogram != nil {
+		t.Fatalf("expected no histogram without --histogram, got %+v", report.Histogram)
+	}
+}
+
+func TestTextReportTimeline(t *testing.T) {
+	result := detector.DetectionResult{InputDuration: 120, Intervals: []detector.Interval{{Start: 60, End: 120, Duration: 60}}}
+	opts := reportOptions{inputPath: "talk.wav", timeline: &detector.TimelineOptions{Width: 10}}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if want := "#####.....\n|0m  |1m\n"; !strings.Contains(text.String(), want) {
+		t.Errorf("expected %q in the text report:\n%s", want, text.String())
+	}
+
+	var unknown bytes.Buffer
+	emitText(&unknown, detector.DetectionResult{}, opts)
+	if want := "No timeline: the input duration is unknown"; !strings.Contains(unknown.String(), want) {
+		t.Errorf("expected %q in the text report:\n%s", want, unknown.String())
+	}
+}
+
+func TestHistogramFlag(t *testing.T) {
+	var histogram histogramFlag
+	if err := histogram.Set("2, 10"); err != nil || histogram.String() != "2,10" {
+		t.Fatalf("Set returned %q, %v", histogram.String(), err)
+	}
+	for _, invalid := range []string{"", "2,", "10,2", "0,2", "abc"} {
+		if err := histogram.Set(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestEmitEDL(t *testing.T) {
+	res := fileResult{
+		input: "/media/interview.mov",
+		result: detector.DetectionResult{
+			InputDuration: 10,
+			Intervals:     []detector.Interval{{Start: 0, End: 1.5, Duration: 1.5}},
+		},
+	}
+	opts := reportOptions{
+		invertLabels: true,
+		edl:          detector.EDLOptions{ReelName: "TAPE01"},
+		times:        &timeFormat{precision: 3, fps: 25},
+	}
+
+	var buf bytes.Buffer
+	emitEDL(&buf, res, opts)
+	want := "TITLE: interview\nFCM: NON-DROP FRAME\n\n" +
+		"001  TAPE01   A     C        00:00:01:13 00:00:10:00 00:00:00:00 00:00:08:12\n" +
+		"* FROM CLIP NAME: audio 1\n"
+	if buf.String() != want {
+		t.Errorf("unexpected EDL:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestEmitSubtitles(t *testing.T) {
+	result := detector.DetectionResult{Intervals: []detector.Interval{{Start: 1, End: 3.25, Duration: 2.25}}}
+	opts := reportOptions{subtitles: detector.SubtitleOptions{CueText: "silent for {duration}s"}}
+
+	var srt, vtt bytes.Buffer
+	emitSubtitles(&srt, result, outputFormatSRT, opts)
+	emitSubtitles(&vtt, result, outputFormatVTT, opts)
+	if want := "1\n00:00:01,000 --> 00:00:03,250\nsilent for 2.25s\n\n"; srt.String() != want {
+		t.Errorf("unexpected SRT %q, want %q", srt.String(), want)
+	}
+	if want := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.250\nsilent for 2.25s\n\n"; vtt.String() != want {
+		t.Errorf("unexpected WebVTT %q, want %q", vtt.String(), want)
+	}
+}
+
+func TestEmitChapters(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 300,
+		Intervals:     []detector.Interval{{Start: 88, End: 92, Duration: 4}, {Start: 150, End: 151, Duration: 1}},
+	}
+	opts := reportOptions{chapterMinSilence: 2, chapterSpacing: 10}
+
+	var youtube, ffmetadata bytes.Buffer
+	emitChapters(&youtube, result, outputFormatChapters, opts)
+	emitChapters(&ffmetadata, result, outputFormatFFMetadata, opts)
+	if want := "00:00 Chapter 1\n01:30 Chapter 2\n"; youtube.String() != want {
+		t.Errorf("unexpected chapters %q, want %q", youtube.String(), want)
+	}
+	if !strings.HasPrefix(ffmetadata.String(), ";FFMETADATA1\n") || !strings.Contains(ffmetadata.String(), "START=90000\nEND=300000\ntitle=Chapter 2\n") {
+		t.Errorf("unexpected FFMETADATA1 chapters:\n%s", ffmetadata.String())
+	}
+}
+
+func TestEmitMarkers(t *testing.T) {
+	result := detector.DetectionResult{
+		WindowStart:   10,
+		InputDuration: 20,
+		Intervals:     []detector.Interval{{Start: 12, End: 14.5, Duration: 2.5}},
+	}
+	opts := reportOptions{
+		markers: detector.MarkerOptions{TimelineName: "Interview", TimelineStart: "01:00:00:00"},
+		times:   &timeFormat{precision: 3, fps: 25},
+	}
+
+	var fcpxml, resolve bytes.Buffer
+	emitMarkers(&fcpxml, result, outputFormatFCPXML, opts)
+	emitMarkers(&resolve, result, outputFormatResolveMarkers, opts)
+	for _, want := range []string{`<project name="Interview">`, `duration="750/25s"`, `<marker start="300/25s" duration="63/25s" value="silence 1" note="silence 2.5s">`} {
+		if !strings.Contains(fcpxml.String(), want) {
+			t.Errorf("FCPXML does not contain %s:\n%s", want, fcpxml.String())
+		}
+	}
+	want := "TITLE: Interview\nFCM: NON-DROP FRAME\n\n" +
+		"001  001      V     C        01:00:12:00 01:00:12:01 01:00:12:00 01:00:12:01\n" +
+		" |C:ResolveColorBlue |M:silence 2.5s |D:63\n"
+	if resolve.String() != want {
+		t.Errorf("unexpected Resolve markers:\n%s\nwant:\n%s", resolve.String(), want)
+	}
+}
+
+func TestReportsEffectiveSilence(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 100,
+		Intervals:     []detector.SilenceInterval{{Start: 0, End: 50, Duration: 50}, {Start: 50.5, End: 100, Duration: 49.5}},
+	}
+	opts := reportOptions{checkFullSilence: true, minCoverage: 0.99}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"min_silence_coverage":0.99,"effectively_silent":true`) || !strings.Contains(string(data), `"fully_silent":false`) {
+		t.Fatalf("expected an effectively silent report, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Entire file is not silent.\nInput is effectively silent (99.50% silence, at least 99.00% required).") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+
+	text.Reset()
+	emitText(&text, detector.DetectionResult{Intervals: result.Intervals}, opts)
+	if !strings.Contains(text.String(), "Cannot tell whether the input is effectively silent: its duration is unknown.") {
+		t.Fatalf("expected an unknown verdict, got:\n%s", text.String())
+	}
+}
+
+func TestReportsUseFullSilenceTolerance(t *testing.T) {
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		Intervals:     []detector.SilenceInterval{{Start: 0.03, End: 10, Duration: 9.97}},
+	}
+
+	for _, tt := range []struct {
+		tolerance float64
+		json      string
+		text      string
+	}{
+		{tolerance: silenceTolerance, json: `"fully_silent":false`, text: "Entire file is not silent."},
+		{tolerance: 0.05, json: `"fully_silent":true`, text: "Entire file is silent."},
+	} {
+		opts := reportOptions{checkFullSilence: true, fullSilenceTol: tt.tolerance}
+		data, err := json.Marshal(newJSONReport(result, opts))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if !strings.Contains(string(data), tt.json) {
+			t.Errorf("tolerance %g: expected %s, got %s", tt.tolerance, tt.json, data)
+		}
+
+		var text bytes.Buffer
+		emitText(&text, result, opts)
+		if !strings.Contains(text.String(), tt.text) {
+			t.Errorf("tolerance %g: expected %q, got:\n%s", tt.tolerance, tt.text, text.String())
+		}
+	}
+}
+
+func TestReportsShowHysteresis(t *testing.T) {
+	result := detector.DetectionResult{InputDuration: 10, ThresholdDB: -35}
+	opts := reportOptions{noiseLevel: -35, hysteresisDB: 10}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "Hysteresis: 10.00dB, silences close above -25.00dB") {
+		t.Errorf("expected the closing threshold, got:\n%s", text.String())
+	}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"hysteresis_db":10`) {
+		t.Errorf("expected hysteresis_db, got %s", data)
+	}
+}
+
+func TestReportsShowIntervalLevels(t *testing.T) {
+	mean, peak := -61.2, -48.0
+	result := detector.DetectionResult{
+		InputDuration: 10,
+		Intervals:     []detector.SilenceInterval{{Start: 1, End: 3, Duration: 2, MeanVolumeDB: &mean, MaxVolumeDB: &peak}},
+	}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5}
+
+	var text bytes.Buffer
+	emitText(&text, result, opts)
+	if !strings.Contains(text.String(), "mean_volume=-61.2dB max_volume=-48.0dB") {
+		t.Errorf("expected the interval levels, got:\n%s", text.String())
+	}
+
+	data, err := json.Marshal(newJSONReport(result, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"mean_volume_db":-61.2,"max_volume_db":-48`) {
+		t.Errorf("expected the interval levels, got %s", data)
+	}
+}
+
+func TestReportsIncludeEnergyTimeline(t *testing.T) {
+	level := -20.5
+	res := fileResult{
+		result: detector.DetectionResult{InputDuration: 1},
+		energy: []detector.EnergySample{{Time: 0, RMSdB: &level}, {Time: 0.5}},
+	}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, energyWindow: 0.5}
+
+	data, err := json.Marshal(newFileReport(res, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"energy_window":0.5,"samples":[{"time":0,"rms_db":-20.5},{"time":0.5,"rms_db":null}]`) {
+		t.Errorf("expected the samples, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitFileText(&text, res, opts)
+	if !strings.Contains(text.String(), "Energy timeline: 2 window(s) of 0.500s, RMS level from -20.5dB to -20.5dB") {
+		t.Errorf("expected the energy summary, got:\n%s", text.String())
+	}
+
+	data, err = json.Marshal(newFileReport(res, reportOptions{noiseLevel: -30, minDuration: 0.5}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), "samples") {
+		t.Errorf("expected no samples without --energy-timeline, got %s", data)
+	}
+}
+
+func TestReportsIncludeQuality(t *testing.T) {
+	peak := 0.0
+	quality := &detector.QualityReport{PeakDB: &peak, ClippedSamples: 12, DCOffset: 0.002, FlatFactor: 6.0206}
+	res := fileResult{result: detector.DetectionResult{InputDuration: 1}, quality: quality}
+	opts := reportOptions{noiseLevel: -30, minDuration: 0.5, quality: true}
+
+	data, err := json.Marshal(newFileReport(res, opts))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"quality":{"peak_db":0,"clipped_samples":12,"dc_offset":0.002,"flat_factor":6.0206`) {
+		t.Errorf("expected the quality report, got %s", data)
+	}
+
+	var text bytes.Buffer
+	emitFileText(&text, res, opts)
+	for _, want := range []string{"Clipping: 12 sample(s) at full scale, peak 0.0dB, flat factor 6.02", "DC offset: 0.002000 of full scale"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected %q, got:\n%s", want, text.String())
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// maxRequestBytes bounds the body of a detection request.
+const maxRequestBytes = 1 << 20
+
+// shutdownTimeout bounds how long the server waits for running detections when it is asked to stop.
+const shutdownTimeout = time.Minute
+
+// detectRequest is the JSON body of POST /v1/detect and POST /v1/jobs. Omitted thresholds take the CLI defaults.
+// CallbackURL only applies to jobs, which POST their final state to it once they succeed or fail.
+type detectRequest struct {
+	Input            string   `json:"input"`
+	NoiseDB          *float64 `json:"noise_db"`
+	MinDuration      *float64 `json:"min_duration"`
+	CheckFullSilence bool     `json:"check_full_silence"`
+	CallbackURL      string   `json:"callback_url"`
+}
+
+// errorResponse is the JSON body of a failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// healthResponse is the JSON body of GET /healthz.
+type healthResponse struct {
+	Status        string `json:"status"`
+	FFmpegVersion string `json:"ffmpeg_version,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// server exposes silence detection over HTTP.
+type server struct {
+	// options build the detector; health checks build a fresh one so that ffmpeg is checked on every request.
+	options []detector.Option
+	det     *detector.Detector
+	// timeout bounds each detection, including the wait for a free slot and any download.
+	timeout time.Duration
+	// slots limits the number of detections, and so of ffmpeg runs, in progress at once.
+	slots chan struct{}
+
+	// jobs holds the detections submitted to POST /v1/jobs, and queue those waiting for a worker. Jobs run with
+	// contexts derived from jobsCtx, so that closing the server cancels them all.
+	jobs     *jobStore
+	queue    chan *job
+	jobsCtx  context.Context
+	stopJobs context.CancelFunc
+	// callbacks delivers finished jobs to their callback URL, in the background so that a slow receiver does not hold
+	// up a worker.
+	callbacks *callbackSender
+	// background tracks the workers and the callback deliveries in flight.
+	background sync.WaitGroup
+	// download configures the downloads of remote inputs.
+	download detector.DownloadOptions
+	// publicOnly rejects remote inputs and callback URLs whose host is not a public address; see
+	// restrictDestinations.
+	publicOnly bool
+}
+
+// newServer returns a server whose detections run on a detector built from options, at most maxConcurrent at a
+// time. It starts maxConcurrent workers for background jobs, which are kept for jobTTL after they finish; close stops
+// them.
+func newServer(options []detector.Option, maxConcurrent int, timeout, jobTTL time.Duration) *server {
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	s := &server{
+		options:   options,
+		det:       detector.NewDetector(options...),
+		timeout:   timeout,
+		slots:     make(chan struct{}, maxConcurrent),
+		jobs:      newJobStore(jobTTL),
+		queue:     make(chan *job, jobQueueSize),
+		jobsCtx:   jobsCtx,
+		stopJobs:  stopJobs,
+		callbacks: newCallbackSender(""),
+	}
+	for range maxConcurrent {
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			s.work()
+		}()
+	}
+	return s
+}
+
+// restrictDestinations keeps the requests of clients from reaching loopback, private and link-local addresses, such
+// as the instance metadata service: remote inputs and callback URLs naming one are rejected with 400, and downloads
+// and callbacks refuse to connect to one, redirects included.
+func (s *server) restrictDestinations() {
+	s.publicOnly = true
+	s.download.Client = publicOnlyClient(0)
+	s.callbacks.client = publicOnlyClient(callbackAttemptTimeout)
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/detect", s.detect)
+	mux.HandleFunc("POST /v1/jobs", s.submitJob)
+	mux.HandleFunc("GET /v1/jobs/{id}", s.getJob)
+	mux.HandleFunc("DELETE /v1/jobs/{id}", s.cancelJob)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	return mux
+}
+
+// close cancels the background jobs, killing their ffmpeg runs, and callback deliveries, and waits for the workers
+// to stop.
+func (s *server) close() {
+	s.stopJobs()
+	s.background.Wait()
+}
+
+// detect analyses the input named in the request and responds with the JSON report of the CLI. Invalid requests
+// fail with 400 and inputs that cannot be read or downloaded with 422. ffmpeg failures are reported as 500, and
+// detections that run out of time, waiting for a slot or analysing, as 503 and 504.
+func (s *server) detect(w http.ResponseWriter, r *http.Request) {
+	req, options, ok := s.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.CallbackURL != "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "callback_url is only supported by POST /v1/jobs"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(contextWithTraceparent(r.Context(), r.Header.Get("traceparent")), s.timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "too many detections in progress; try again later"})
+		return
+	}
+
+	report, status, err := s.analyse(ctx, req, options, s.timeout)
+	if err != nil {
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// decodeRequest reads and validates the detection request in the body of r. It responds with 400 and returns false
+// when the request is invalid.
+func (s *server) decodeRequest(w http.ResponseWriter, r *http.Request) (detectRequest, detector.DetectionOptions, bool) {
+	var req detectRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return req, detector.DetectionOptions{}, false
+	}
+
+	options := detector.DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}
+	if req.NoiseDB != nil {
+		options.NoiseLevel = *req.NoiseDB
+	}
+	if req.MinDuration != nil {
+		options.MinSilenceDuration = *req.MinDuration
+	}
+	if err := s.validate(r.Context(), req.Input, options); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return req, options, false
+	}
+	return req, options, true
+}
+
+// analyse runs the detection of a request and returns its JSON report, or the error of a failed detection and the
+// HTTP status it maps to. A timeout of zero leaves the detection bounded by ctx alone.
+func (s *server) analyse(ctx context.Context, req detectRequest, options detector.DetectionOptions, timeout time.Duration) (any, int, error) {
+	cfg := analysisConfig{
+		fetchers: map[detector.InputKind]remoteFetcher{
+			detector.InputRemote: httpFetcher{},
+			detector.InputS3:     s3Fetcher{},
+			detector.InputGCS:    gcsFetcher{},
+		},
+		download:  s.download,
+		detection: options,
+		timeout:   timeout,
+	}
+	res := analyzeInput(ctx, s.det, cfg, req.Input)
+
+	opts := reportOptions{
+		inputPath:        req.Input,
+		noiseLevel:       options.NoiseLevel,
+		minDuration:      options.MinSilenceDuration,
+		checkFullSilence: req.CheckFullSilence,
+		fullSilenceTol:   silenceTolerance,
+		noAudioStream:    res.noAudioStream,
+	}
+	switch {
+	case res.err != nil:
+		return nil, errorStatus(res), res.err
+	case res.noAudioStream:
+		return newJSONReport(detector.DetectionResult{}, opts), http.StatusOK, nil
+	default:
+		return newFileReport(res, opts), http.StatusOK, nil
+	}
+}
+
+// validate checks a request before any slot is taken, with the same validation ffmpeg arguments are built with.
+func (s *server) validate(ctx context.Context, raw string, options detector.DetectionOptions) error {
+	if strings.TrimSpace(raw) == "" {
+		return errors.New("input is required")
+	}
+	input, err := detector.ResolveInput(raw)
+	if err != nil {
+		return fmt.Errorf("invalid input %q: %v", raw, err)
+	}
+	if input.Location == "-" {
+		return errors.New("the server cannot read from stdin")
+	}
+	if s.publicOnly && (input.Kind == detector.InputRemote || input.Kind == detector.InputStream) {
+		if err := checkDestination(ctx, input.Location); err != nil {
+			return fmt.Errorf("invalid input %q: %v", raw, err)
+		}
+	}
+	_, err = s.det.BuildArgs(input.Location, options)
+	return err
+}
+
+// errorStatus maps a failed analysis to an HTTP status.
+func errorStatus(res fileResult) int {
+	switch res.exitCode() {
+	case exitCodeTimeout:
+		return http.StatusGatewayTimeout
+	case exitCodeInput:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// healthz reports whether ffmpeg is runnable and supports silencedetect.
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	info, err := detector.NewDetector(s.options...).Check(ctx)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok", FFmpegVersion: info.Version})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// runServe implements `silence-detector serve`, which serves detections over HTTP until interrupted.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var (
+		listen          = flags.String("listen", ":8080", "Address to listen on")
+		ffmpegBinary    = flags.String("ffmpeg", "ffmpeg", "Path to the ffmpeg binary")
+		ffprobeBinary   = flags.String("ffprobe", "ffprobe", "Path to the ffprobe binary used to determine the input duration (empty to disable)")
+		maxConcurrent   = flags.Int("max-concurrent", runtime.NumCPU(), "Maximum number of detections, and so of ffmpeg runs, in progress at once")
+		requestTimeout  = flags.Duration("request-timeout", 5*time.Minute, "Time limit for each detection, including waiting for a free slot and downloading the input")
+		jobTTL          = flags.Duration("job-ttl", time.Hour, "How long the result of a background job is kept after it finishes")
+		downloadTimeout = flags.Duration("download-timeout", 0, "Overall time limit for downloading a remote input, including retries (0 leaves downloads bounded by --request-timeout alone)")
+		maxDownloadSize = flags.Int64("max-download-size", 0, "Abort downloads of remote inputs larger than this many bytes (0 means unlimited)")
+		allowPrivate    = flags.Bool("allow-private-destinations", false, "Let requests name remote inputs and callback URLs on loopback, private and link-local addresses, such as the instance metadata service; only for servers whose clients are all trusted")
+		callbackSecret  = flags.String("callback-secret", "", "Sign job callback bodies with HMAC-SHA256 using this secret (X-Silence-Detector-Signature header)")
+		metricsListen   = flags.String("metrics-listen", "", "Serve Prometheus metrics on /metrics at this address (e.g. :9090)")
+		trace           = flags.Bool("trace", false, "Export OpenTelemetry traces over OTLP/HTTP (JSON) as configured by the standard OTEL_* environment variables; requests may carry a traceparent header")
+		verbose         = flags.Bool("verbose", false, "Log ffmpeg and ffprobe runs, with secrets redacted, to stderr")
+	)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCodeOK
+		}
+		return exitCodeUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "serve takes no arguments, got %q\n", flags.Args())
+		return exitCodeUsage
+	}
+	if *maxConcurrent < 1 || *requestTimeout <= 0 || *jobTTL <= 0 {
+		fmt.Fprintln(os.Stderr, "--max-concurrent must be at least 1, and --request-timeout and --job-ttl greater than zero")
+		return exitCodeUsage
+	}
+	if *downloadTimeout < 0 || *maxDownloadSize < 0 {
+		fmt.Fprintln(os.Stderr, "--download-timeout and --max-download-size must not be negative")
+		return exitCodeUsage
+	}
+
+	options := []detector.Option{
+		detector.WithFFmpegPath(*ffmpegBinary),
+		detector.WithFFprobePath(*ffprobeBinary),
+	}
+	if *verbose {
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		options = append(options, detector.WithLogger(logger))
+	}
+
+	var recorder *prometheusRecorder
+	if *metricsListen != "" {
+		recorder = newPrometheusRecorder()
+		options = append(options, detector.WithMetrics(recorder))
+		stopMetrics, err := serveMetrics(*metricsListen, recorder)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCodeError
+		}
+		defer stopMetrics()
+	}
+
+	var tracing *otlpExporter
+	if *trace {
+		var err error
+		tracing, err = newOTLPExporter(os.Getenv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--trace: %v\n", err)
+			return exitCodeUsage
+		}
+		if tracing != nil {
+			options = append(options, detector.WithTracerProvider(tracing))
+			defer tracing.shutdown(context.Background())
+		}
+	}
+
+	srv := newServer(options, *maxConcurrent, *requestTimeout, *jobTTL)
+	srv.callbacks = newCallbackSender(*callbackSecret)
+	srv.download.Timeout = *downloadTimeout
+	srv.download.MaxBytes = *maxDownloadSize
+	if !*allowPrivate {
+		srv.restrictDestinations()
+	}
+	if recorder != nil {
+		srv.download.Recorder = recorder
+	}
+	if tracing != nil {
+		srv.download.TracerProvider = tracing
+	}
+	defer srv.close()
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() { served <- httpServer.ListenAndServe() }()
+	fmt.Fprintf(os.Stderr, "listening on %s\n", *listen)
+
+	select {
+	case err := <-served:
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeError
+	case <-ctx.Done():
+	}
+
+	// Running detections are given time to finish before the server exits. Background jobs are cancelled, as their
+	// results could not be fetched once it has.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeError
+	}
+	return exitCodeOK
+}
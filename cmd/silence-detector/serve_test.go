@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+const silentOutput = "[silencedetect @ 0x1] silence_start: 0\n[silencedetect @ 0x1] silence_end: 10 | silence_duration: 10\nsize=N/A time=00:00:10.00 bitrate=N/A\n"
+
+// newTestServer serves detections that run ffmpeg through runner.
+func newTestServer(t *testing.T, runner detector.CommandRunner, maxConcurrent int, timeout time.Duration, extra ...detector.Option) *httptest.Server {
+	t.Helper()
+	options := append([]detector.Option{detector.WithCommandRunner(runner), detector.WithFFprobePath("")}, extra...)
+	s := newServer(options, maxConcurrent, timeout, time.Hour)
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		srv.Close()
+		s.close()
+	})
+	return srv
+}
+
+func postDetect(t *testing.T, srv *httptest.Server, body string) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/v1/detect", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /v1/detect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp.StatusCode, decoded
+}
+
+func TestServeDetect(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(silentOutput), nil
+	}
+	srv := newTestServer(t, runner, 2, time.Minute)
+
+	body, _ := json.Marshal(map[string]any{"input": input, "noise_db": -45, "min_duration": 1, "check_full_silence": true})
+	status, report := postDetect(t, srv, string(body))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", status, report)
+	}
+	if report["input"] != input || report["noise_db"] != -45.0 || report["fully_silent"] != true || report["interval_count"] != 1.0 {
+		t.Fatalf("unexpected report %v", report)
+	}
+	if !strings.Contains(strings.Join(gotArgs, " "), "silencedetect=noise=-45dB:d=1") {
+		t.Fatalf("expected the requested thresholds, got %q", gotArgs)
+	}
+}
+
+func TestServeDetectErrors(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("input.wav: Invalid data found when processing input"), errors.New("exit status 1")
+	}
+	srv := newTestServer(t, runner, 2, time.Minute)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "malformed JSON", body: `{"input":`, want: http.StatusBadRequest},
+		{name: "unknown field", body: `{"input":"a.wav","noise":-30}`, want: http.StatusBadRequest},
+		{name: "missing input", body: `{}`, want: http.StatusBadRequest},
+		{name: "stdin", body: `{"input":"-"}`, want: http.StatusBadRequest},
+		{name: "invalid duration", body: `{"input":"a.wav","min_duration":0}`, want: http.StatusBadRequest},
+		{name: "missing file", body: `{"input":"` + filepath.ToSlash(filepath.Join(dir, "missing.wav")) + `"}`, want: http.StatusUnprocessableEntity},
+		{name: "ffmpeg failure", body: `{"input":"` + filepath.ToSlash(input) + `"}`, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postDetect(t, srv, tt.body)
+			if status != tt.want {
+				t.Fatalf("expected %d, got %d: %v", tt.want, status, body)
+			}
+			if body["error"] == "" || body["error"] == nil {
+				t.Fatalf("expected an error message, got %v", body)
+			}
+		})
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/detect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestServeDetectTimesOut(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	srv := newTestServer(t, runner, 1, 100*time.Millisecond)
+
+	if status, body := postDetect(t, srv, `{"input":"`+filepath.ToSlash(input)+`"}`); status != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d: %v", status, body)
+	}
+}
+
+func TestServeLimitsConcurrentDetections(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte(silentOutput), nil
+	}
+	srv := newTestServer(t, runner, 1, 300*time.Millisecond)
+	body := `{"input":"` + filepath.ToSlash(input) + `"}`
+
+	first := make(chan int, 1)
+	go func() {
+		status, _ := postDetect(t, srv, body)
+		first <- status
+	}()
+	<-started
+
+	// The only slot is taken, so the second request gives up once its timeout passes.
+	if status, response := postDetect(t, srv, body); status != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while the slot is taken, got %d: %v", status, response)
+	}
+
+	close(release)
+	if status := <-first; status != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d", status)
+	}
+}
+
+func TestServeLimitsDownloadSize(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1024))
+	}))
+	defer origin.Close()
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(silentOutput), nil
+	}
+	s := newServer([]detector.Option{detector.WithCommandRunner(runner), detector.WithFFprobePath("")}, 1, time.Minute, time.Hour)
+	s.download.MaxBytes = 512
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		srv.Close()
+		s.close()
+	})
+
+	if status, body := postDetect(t, srv, `{"input":"`+origin.URL+`/episode.wav"}`); status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a download over --max-download-size, got %d: %v", status, body)
+	}
+	s.download.MaxBytes = 0
+	if status, body := postDetect(t, srv, `{"input":"`+origin.URL+`/episode.wav"}`); status != http.StatusOK {
+		t.Fatalf("expected an unlimited download to succeed, got %d: %v", status, body)
+	}
+}
+
+func TestServeHealthz(t *testing.T) {
+	healthy := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if len(args) > 0 && args[0] == "-version" {
+			return []byte("ffmpeg version 6.1-fake\n"), nil
+		}
+		return []byte(" ... silencedetect     A->A       Detect silence.\n"), nil
+	}
+	broken := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("exit status 127")
+	}
+
+	for _, tt := range []struct {
+		name   string
+		runner detector.CommandRunner
+		want   int
+	}{
+		{name: "healthy", runner: healthy, want: http.StatusOK},
+		{name: "broken", runner: broken, want: http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The health check looks the binary up before running it, so it names one that exists.
+			srv := newTestServer(t, tt.runner, 1, time.Minute, detector.WithFFmpegPath(os.Args[0]))
+			resp, err := http.Get(srv.URL + "/healthz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body healthResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d: %+v", tt.want, resp.StatusCode, body)
+			}
+			if tt.want == http.StatusOK && body.FFmpegVersion != "6.1-fake" {
+				t.Fatalf("expected the ffmpeg version, got %+v", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// streamResult is the outcome of analysing one audio stream with --all-audio-streams.
+type streamResult struct {
+	info   detector.StreamInfo
+	result detector.DetectionResult
+	// err is the *detector.StreamError that stopped the stream's analysis.
+	err error
+}
+
+// detectAllStreams analyses every audio stream of path and records the outcome of each in res.streams, in stream
+// order. It returns the result of the first stream that was analysed, or an error if none was.
+func detectAllStreams(ctx context.Context, det *detector.Detector, path string, options detector.DetectionOptions, res *fileResult) (detector.DetectionResult, error) {
+	results, err := det.DetectSilenceAllStreams(ctx, path, options)
+	res.streams = nil
+	for info, result := range results {
+		res.streams = append(res.streams, streamResult{info: info, result: result})
+	}
+	if len(results) == 0 {
+		return detector.DetectionResult{}, err
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, failure := range joined.Unwrap() {
+			var streamErr *detector.StreamError
+			if errors.As(failure, &streamErr) {
+				res.streams = append(res.streams, streamResult{info: streamErr.Stream, err: streamErr})
+			}
+		}
+	}
+	slices.SortFunc(res.streams, func(a, b streamResult) int { return a.info.Index - b.info.Index })
+
+	for _, stream := range res.streams {
+		if stream.err == nil {
+			return stream.result, nil
+		}
+	}
+	return detector.DetectionResult{}, err
+}
+
+// streamsReport is the JSON representation of an --all-audio-streams analysis: a section per audio stream, in stream
+// order.
+type streamsReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Input         string         `json:"input"`
+	Streams       []streamReport `json:"streams"`
+}
+
+// streamReport is the section of an audio stream: its report, or the error that stopped its analysis.
+type streamReport struct {
+	Stream detector.StreamInfo `json:"stream"`
+	Error  string              `json:"error,omitempty"`
+	Report *detector.Report    `json:"report,omitempty"`
+}
+
+// reportStreams renders the result of an --all-audio-streams analysis and returns the process exit code. Streams that
+// could not be analysed are reported on stderr and in their section, and make the run fail with exit code 9 once the
+// other streams have been reported. Failures of the whole input are reported as without --all-audio-streams.
+func reportStreams(w io.Writer, res fileResult, format outputFormat, opts reportOptions) int {
+	if res.err != nil || res.noAudioStream {
+		return reportSingle(w, res, format, opts)
+	}
+	opts.inputPath = res.input
+
+	exitCode := res.exitCode()
+	sections := make([]streamReport, len(res.streams))
+	for i, stream := range res.streams {
+		sections[i] = streamReport{Stream: stream.info}
+		if stream.err != nil {
+			fmt.Fprintf(os.Stderr, "audio stream %s:\n", describeStream(stream.info))
+			reportFailure(detectionError{err: stream.err})
+			sections[i].Error = stream.err.Error()
+			exitCode = worseExitCode(exitCode, exitCodeFFmpeg)
+			continue
+		}
+		sections[i].Report = newJSONReport(stream.result, opts)
+	}
+
+	switch format {
+	case outputFormatJSON:
+		emitReport(newReportEncoder(w, format, opts.legacyJSON), streamsReport{SchemaVersion: detector.ReportSchemaVersion, Input: displayInputPath(res.input), Streams: sections})
+	case outputFormatNDJSON:
+		encoder := newReportEncoder(w, format, opts.legacyJSON)
+		for _, section := range sections {
+			emitReport(encoder, section)
+		}
+	default:
+		for i, stream := range res.streams {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "Audio stream %s\n", describeStream(stream.info))
+			if stream.err != nil {
+				fmt.Fprintf(w, "Analysis failed: %v\n", errors.Unwrap(stream.err))
+				continue
+			}
+			emitText(w, stream.result, opts)
+		}
+	}
+	return exitCode
+}
+
+// describeStream names an audio stream by its index, codec, channel count and language, such as
+// "1 (aac, 2 channels, eng)".
+func describeStream(info detector.StreamInfo) string {
+	var details []string
+	if info.Codec != "" {
+		details = append(details, info.Codec)
+	}
+	if info.Channels > 0 {
+		details = append(details, fmt.Sprintf("%d channels", info.Channels))
+	}
+	if info.Language != "" {
+		details = append(details, info.Language)
+	}
+	if len(details) == 0 {
+		return fmt.Sprint(info.Index)
+	}
+	return fmt.Sprintf("%d (%s)", info.Index, strings.Join(details, ", "))
+}
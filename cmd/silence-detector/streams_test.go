@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestReportStreams(t *testing.T) {
+	english := detector.StreamInfo{Index: 0, Codec: "aac", Channels: 2, Language: "eng"}
+	surround := detector.StreamInfo{Index: 1, Codec: "ac3", Channels: 6}
+	res := fileResult{
+		input: "master.mov",
+		streams: []streamResult{
+			{info: english, result: detector.DetectionResult{InputDuration: 10, Intervals: []detector.SilenceInterval{{Start: 2, End: 4, Duration: 2}}}},
+			{info: surround, err: &detector.StreamError{Stream: surround, Err: errors.New("decoding failed")}},
+		},
+	}
+	res.result = res.streams[0].result
+	opts := reportOptions{minDuration: 0.5}
+
+	var text bytes.Buffer
+	if code := reportStreams(&text, res, outputFormatText, opts); code != exitCodeFFmpeg {
+		t.Fatalf("expected exit code %d for a failed stream, got %d", exitCodeFFmpeg, code)
+	}
+	for _, line := range []string{
+		"Audio stream 0 (aac, 2 channels, eng)\nSilence detection for master.mov\n",
+		"1. start=2.000s end=4.000s duration=2.000s\n",
+		"\nAudio stream 1 (ac3, 6 channels)\nAnalysis failed: decoding failed\n",
+	} {
+		if !strings.Contains(text.String(), line) {
+			t.Fatalf("expected %q in:\n%s", line, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	reportStreams(&out, res, outputFormatJSON, opts)
+	var report streamsReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(report.Streams) != 2 || report.Streams[0].Stream != english || report.Streams[0].Report.IntervalCount != 1 ||
+		report.Streams[1].Report != nil || report.Streams[1].Error != "audio stream 1: decoding failed" {
+		t.Fatalf("unexpected streams report %s", out.String())
+	}
+
+	out.Reset()
+	res.streams = res.streams[:1]
+	if code := reportStreams(&out, res, outputFormatNDJSON, opts); code != exitCodeOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	if !strings.HasPrefix(out.String(), `{"stream":{"index":0,"codec":"aac","channels":2,"language":"eng"},"report":{`) {
+		t.Fatalf("unexpected NDJSON record %q", out.String())
+	}
+}
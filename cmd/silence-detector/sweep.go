@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// thresholdList holds the --silence-noise thresholds. Several comma-separated values run a sweep that compares
+// them.
+type thresholdList []float64
+
+func (l *thresholdList) String() string {
+	values := make([]string, len(*l))
+	for i, level := range *l {
+		values[i] = strconv.FormatFloat(level, 'f', -1, 64)
+	}
+	return strings.Join(values, ",")
+}
+
+func (l *thresholdList) Set(value string) error {
+	var levels thresholdList
+	for _, field := range strings.Split(value, ",") {
+		level, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return fmt.Errorf("invalid threshold %q", field)
+		}
+		for _, previous := range levels {
+			if previous == level {
+				return fmt.Errorf("threshold %g is listed more than once", level)
+			}
+		}
+		levels = append(levels, level)
+	}
+	*l = levels
+	return nil
+}
+
+// sweepReport is the JSON representation of a --silence-noise sweep: one report per threshold, in the order the
+// thresholds were given.
+type sweepReport struct {
+	SchemaVersion int                `json:"schema_version"`
+	Input         string             `json:"input"`
+	Thresholds    []*detector.Report `json:"thresholds"`
+}
+
+// reportSweep renders the result of a single-input threshold sweep and returns the process exit code. Failures are
+// reported as for a single threshold.
+func reportSweep(w io.Writer, res fileResult, levels []float64, format outputFormat, opts reportOptions) int {
+	if res.err != nil || res.noAudioStream {
+		return reportSingle(w, res, format, opts)
+	}
+	opts.inputPath = res.input
+
+	reports := make([]*detector.Report, len(levels))
+	for i, level := range levels {
+		levelOpts := opts
+		levelOpts.noiseLevel = level
+		reports[i] = newJSONReport(res.sweep[level], levelOpts)
+	}
+
+	switch format {
+	case outputFormatJSON:
+		emitReport(newReportEncoder(w, format, opts.legacyJSON), sweepReport{SchemaVersion: detector.ReportSchemaVersion, Input: displayInputPath(res.input), Thresholds: reports})
+	case outputFormatNDJSON:
+		encoder := newReportEncoder(w, format, opts.legacyJSON)
+		for _, report := range reports {
+			emitReport(encoder, report)
+		}
+	default:
+		emitSweepText(w, res, levels, opts)
+	}
+	return res.exitCode()
+}
+
+// emitSweepText prints a table comparing the silence found at each threshold of a sweep.
+func emitSweepText(w io.Writer, res fileResult, levels []float64, opts reportOptions) {
+	fmt.Fprintf(w, "Silence detection sweep for %s\n", displayInputPath(opts.inputPath))
+	fmt.Fprintf(w, "Minimum duration: %.2fs\n", opts.minDuration)
+	if duration := res.result.InputDuration; duration > 0 {
+		fmt.Fprintf(w, "Input duration: %s\n", opts.timeFormat().seconds(duration))
+	}
+
+	fmt.Fprintf(w, "%10s  %9s  %13s  %7s\n", "Threshold", "Intervals", "Total silence", "Silence")
+	for _, level := range levels {
+		result := res.sweep[level]
+		ratio := "-"
+		if result.InputDuration > 0 {
+			ratio = fmt.Sprintf("%.1f%%", result.SilenceRatio()*100)
+		}
+		fmt.Fprintf(w, "%8.2fdB  %9d  %13s  %7s\n", level, len(result.Intervals), opts.timeFormat().seconds(result.TotalSilence()), ratio)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestThresholdListParsesCommaSeparatedValues(t *testing.T) {
+	levels := thresholdList{-30}
+	if err := levels.Set("-20, -30,-40.5"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if levels.String() != "-20,-30,-40.5" {
+		t.Fatalf("unexpected thresholds %q", levels.String())
+	}
+
+	for _, invalid := range []string{"", "-20,", "-20,abc", "-20,-20"} {
+		if err := levels.Set(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestReportSweep(t *testing.T) {
+	levels := []float64{-20, -40}
+	res := fileResult{
+		input: "a.wav",
+		sweep: map[float64]detector.DetectionResult{
+			-20: {InputDuration: 10, Intervals: []detector.SilenceInterval{{Start: 0, End: 2, Duration: 2}, {Start: 5, End: 8, Duration: 3}}},
+			-40: {InputDuration: 10, Intervals: []detector.SilenceInterval{{Start: 5, End: 6, Duration: 1}}},
+		},
+	}
+	res.result = res.sweep[-20]
+	opts := reportOptions{minDuration: 0.5}
+
+	var text bytes.Buffer
+	if code := reportSweep(&text, res, levels, outputFormatText, opts); code != exitCodeOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	for _, row := range []string{
+		" Threshold  Intervals  Total silence  Silence\n",
+		"  -20.00dB          2         5.000s    50.0%\n",
+		"  -40.00dB          1         1.000s    10.0%\n",
+	} {
+		if !strings.Contains(text.String(), row) {
+			t.Fatalf("expected row %q in:\n%s", row, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	reportSweep(&out, res, levels, outputFormatJSON, opts)
+	var report sweepReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.SchemaVersion != detector.ReportSchemaVersion || len(report.Thresholds) != 2 || report.Thresholds[0].NoiseDB != -20 || report.Thresholds[1].IntervalCount != 1 {
+		t.Fatalf("unexpected sweep report %+v", report)
+	}
+
+	out.Reset()
+	reportSweep(&out, res, levels, outputFormatNDJSON, opts)
+	if lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); len(lines) != 2 {
+		t.Fatalf("expected one NDJSON record per threshold, got %q", out.String())
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// defaultPrecision is the default of --precision.
+const defaultPrecision = 3
+
+// maxPrecision bounds --precision; ffmpeg reports times to the microsecond.
+const maxPrecision = 6
+
+// timeStyle is a --time-format value.
+type timeStyle string
+
+const (
+	timeStyleSeconds timeStyle = "seconds"
+	timeStyleClock   timeStyle = "clock"
+	timeStyleSMPTE   timeStyle = "smpte"
+)
+
+// timeFormat controls how reports render times and durations.
+type timeFormat struct {
+	// precision is the number of decimal places of seconds, set by --precision. JSON reports are rounded to it.
+	precision int
+	// style is how the text report renders times, set by --time-format. The zero value renders seconds.
+	style timeStyle
+	// fps is the frame rate of SMPTE timecodes and EDLs.
+	fps float64
+}
+
+// newTimeFormat validates the --precision, --time-format and --fps flags.
+func newTimeFormat(precision int, style string, fps float64) (timeFormat, error) {
+	if precision < 0 || precision > maxPrecision {
+		return timeFormat{}, fmt.Errorf("--precision must be between 0 and %d", maxPrecision)
+	}
+	switch timeStyle(style) {
+	case timeStyleSeconds, timeStyleClock:
+	case timeStyleSMPTE:
+		if !validFPS(fps) {
+			return timeFormat{}, fmt.Errorf("--time-format %s requires a positive, finite --fps", timeStyleSMPTE)
+		}
+	default:
+		return timeFormat{}, fmt.Errorf("unsupported time format %q: use seconds, clock or smpte", style)
+	}
+	return timeFormat{precision: precision, style: timeStyle(style), fps: fps}, nil
+}
+
+// validFPS reports whether fps is a usable --fps: positive and finite.
+func validFPS(fps float64) bool {
+	return fps > 0 && !math.IsInf(fps, 0)
+}
+
+// timeFormat returns the time format of the reports, which is the default when opts was built without one.
+func (o reportOptions) timeFormat() timeFormat {
+	if o.times == nil {
+		return timeFormat{precision: defaultPrecision}
+	}
+	return *o.times
+}
+
+// seconds renders a time or duration for the text report.
+func (f timeFormat) seconds(v float64) string {
+	switch f.style {
+	case timeStyleClock:
+		return detector.FormatClock(v)
+	case timeStyleSMPTE:
+		// newTimeFormat checked the rate.
+		if timecode, err := detector.FormatSMPTE(v, f.fps); err == nil {
+			return timecode
+		}
+		fallthrough
+	default:
+		return fmt.Sprintf("%.*fs", f.precision, v)
+	}
+}
+
+// round rounds the times of result for a listing.
+func (f timeFormat) round(result detector.DetectionResult) detector.DetectionResult {
+	return result.Round(f.precision)
+}
+
+// roundIntervals rounds and sorts intervals, such as those of a video section, for a listing.
+func (f timeFormat) roundIntervals(intervals []detector.Interval) []detector.Interval {
+	return f.round(detector.DetectionResult{Intervals: intervals}).Intervals
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+const (
+	// defaultTracesEndpoint is where spans are exported when no OTLP endpoint is configured.
+	defaultTracesEndpoint = "http://localhost:4318/v1/traces"
+	// traceExportInterval is how often finished spans are exported.
+	traceExportInterval = 5 * time.Second
+	// maxQueuedSpans bounds the finished spans waiting for export; further spans are dropped.
+	maxQueuedSpans = 2048
+	// traceExportTimeout bounds a single export request.
+	traceExportTimeout = 10 * time.Second
+)
+
+// otlpExporter is the detector.TracerProvider behind --trace. It exports spans to an OpenTelemetry collector as
+// OTLP/HTTP with JSON encoding, which is simple enough to write without the OpenTelemetry SDK, and is configured
+// by the SDK's standard environment variables.
+type otlpExporter struct {
+	client   *http.Client
+	endpoint string
+	headers  http.Header
+	resource []otlpKeyValue
+
+	mu      sync.Mutex
+	pending []*otlpSpan
+	dropped int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newOTLPExporter configures an exporter from the environment, as read by getenv. It returns nil when tracing is
+// disabled by OTEL_SDK_DISABLED or OTEL_TRACES_EXPORTER, and an error for settings it cannot honour. Spans are
+// exported in the background until shutdown is called.
+func newOTLPExporter(getenv func(string) string) (*otlpExporter, error) {
+	if disabled, _ := strconv.ParseBool(getenv("OTEL_SDK_DISABLED")); disabled {
+		return nil, nil
+	}
+	switch exporter := getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "otlp":
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q: only otlp is supported", exporter)
+	}
+
+	protocol := firstEnv(getenv, "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL")
+	if protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q: only http/json is supported", protocol)
+	}
+
+	endpoint := getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultTracesEndpoint
+		if base := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP traces endpoint %q: must be an http or https URL", endpoint)
+	}
+
+	headers := http.Header{}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		pairs, err := parseOTELPairs(getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		for _, pair := range pairs {
+			headers.Set(pair[0], pair[1])
+		}
+	}
+
+	serviceName := "silence-detector"
+	resourcePairs, err := parseOTELPairs(getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+	var resource []otlpKeyValue
+	for _, pair := range resourcePairs {
+		if pair[0] == "service.name" {
+			serviceName = pair[1]
+			continue
+		}
+		resource = append(resource, otlpAttr(slog.String(pair[0], pair[1])))
+	}
+	if name := getenv("OTEL_SERVICE_NAME"); name != "" {
+		serviceName = name
+	}
+	resource = append([]otlpKeyValue{otlpAttr(slog.String("service.name", serviceName))}, resource...)
+
+	e := &otlpExporter{
+		client:   &http.Client{Timeout: traceExportTimeout},
+		endpoint: endpoint,
+		headers:  headers,
+		resource: resource,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+func firstEnv(getenv func(string) string, names ...string) string {
+	for _, name := range names {
+		if value := getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseOTELPairs parses the comma-separated key=value lists of OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_RESOURCE_ATTRIBUTES, whose values are percent-encoded.
+func parseOTELPairs(s string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("entry %q is not of the form key=value", item)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", key, err)
+		}
+		pairs = append(pairs, [2]string{key, decoded})
+	}
+	return pairs, nil
+}
+
+func (e *otlpExporter) Tracer(name string) detector.Tracer {
+	return otlpTracer{exporter: e, scope: name}
+}
+
+// run exports finished spans every traceExportInterval until shutdown.
+func (e *otlpExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(traceExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush(context.Background())
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// shutdown stops the background export and exports the spans still queued.
+func (e *otlpExporter) shutdown(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	e.flush(ctx)
+}
+
+// flush exports the queued spans. Export failures are reported on stderr and never affect the detection.
+func (e *otlpExporter) flush(ctx context.Context) {
+	e.mu.Lock()
+	spans, dropped := e.pending, e.dropped
+	e.pending, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "trace export: dropped %d span(s) because the export queue was full\n", dropped)
+	}
+	if len(spans) == 0 {
+		return
+	}
+	if err := e.export(ctx, spans); err != nil {
+		fmt.Fprintf(os.Stderr, "trace export: %v\n", err)
+	}
+}
+
+func (e *otlpExporter) export(ctx context.Context, spans []*otlpSpan) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range e.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// enqueue queues a finished span for export.
+func (e *otlpExporter) enqueue(span *otlpSpan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) >= maxQueuedSpans {
+		e.dropped++
+		return
+	}
+	e.pending = append(e.pending, span)
+}
+
+// request builds the ExportTraceServiceRequest for spans, grouped by instrumentation scope.
+func (e *otlpExporter) request(spans []*otlpSpan) otlpRequest {
+	var scopes []otlpScopeSpans
+	index := make(map[string]int)
+	for _, span := range spans {
+		i, ok := index[span.scope]
+		if !ok {
+			i = len(scopes)
+			index[span.scope] = i
+			scopes = append(scopes, otlpScopeSpans{Scope: otlpScope{Name: span.scope}})
+		}
+		scopes[i].Spans = append(scopes[i].Spans, span.data())
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: e.resource},
+		ScopeSpans: scopes,
+	}}}
+}
+
+// spanContext identifies a span, as hex-encoded W3C trace context IDs.
+type spanContext struct {
+	traceID string
+	spanID  string
+}
+
+type spanContextKey struct{}
+
+// contextWithTraceparent makes spans started from the returned context children of the span identified by a W3C
+// traceparent value, such as the traceparent header of a request or the TRACEPARENT environment variable. Invalid
+// values are ignored.
+func contextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return ctx
+	}
+	traceID, spanID := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !validTraceID(traceID, 16) || !validTraceID(spanID, 8) {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, spanContext{traceID: traceID, spanID: spanID})
+}
+
+// validTraceID reports whether id is the hex encoding of size bytes that are not all zero.
+func validTraceID(id string, size int) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == size && strings.Trim(id, "0") != ""
+}
+
+func randomID(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type otlpTracer struct {
+	exporter *otlpExporter
+	scope    string
+}
+
+func (t otlpTracer) Start(ctx context.Context, spanName string) (context.Context, detector.Span) {
+	span := &otlpSpan{
+		exporter: t.exporter,
+		scope:    t.scope,
+		name:     spanName,
+		spanID:   randomID(8),
+		start:    time.Now(),
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(spanContext); ok {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		span.traceID = randomID(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, spanContext{traceID: span.traceID, spanID: span.spanID}), span
+}
+
+// otlpSpan is a span recorded by otlpTracer. Its methods may be called from several goroutines, such as the one
+// waiting for an ffmpeg process.
+type otlpSpan struct {
+	exporter *otlpExporter
+	scope    string
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []otlpKeyValue
+	events []otlpEvent
+	status otlpStatus
+	ended  bool
+}
+
+func (s *otlpSpan) SetAttributes(attrs ...slog.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, attr := range attrs {
+		s.attrs = append(s.attrs, otlpAttr(attr))
+	}
+}
+
+func (s *otlpSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, otlpEvent{
+		TimeUnixNano: unixNano(time.Now()),
+		Name:         "exception",
+		Attributes:   []otlpKeyValue{otlpAttr(slog.String("exception.message", err.Error()))},
+	})
+	s.status = otlpStatus{Code: otlpStatusError, Message: err.Error()}
+}
+
+func (s *otlpSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.exporter.enqueue(s)
+}
+
+func (s *otlpSpan) data() otlpSpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return otlpSpanData{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+		Attributes:        s.attrs,
+		Events:            s.events,
+		Status:            s.status,
+	}
+}
+
+// unixNano formats t as the string of nanoseconds that the JSON encoding of OTLP uses for 64-bit integers.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// The JSON encoding of an OTLP ExportTraceServiceRequest, limited to the fields the exporter sets.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope      `json:"scope"`
+		Spans []otlpSpanData `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpanData struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+// otlpAttr converts a span attribute to OTLP. Kinds without an OTLP equivalent, and infinite or NaN floats, which
+// JSON cannot represent, are recorded as strings.
+func otlpAttr(attr slog.Attr) otlpKeyValue {
+	value := attr.Value.Resolve()
+	var v otlpValue
+	switch value.Kind() {
+	case slog.KindBool:
+		b := value.Bool()
+		v.BoolValue = &b
+	case slog.KindInt64:
+		i := strconv.FormatInt(value.Int64(), 10)
+		v.IntValue = &i
+	case slog.KindUint64:
+		i := strconv.FormatUint(value.Uint64(), 10)
+		v.IntValue = &i
+	case slog.KindFloat64:
+		f := value.Float64()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			s := value.String()
+			v.StringValue = &s
+			break
+		}
+		v.DoubleValue = &f
+	default:
+		s := value.String()
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: attr.Key, Value: v}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// otlpCollector records the export requests it receives.
+type otlpCollector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	headers  []http.Header
+}
+
+func (c *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header.Clone())
+}
+
+func envMap(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestOTLPExporterExportsSpans(t *testing.T) {
+	collector := &otlpCollector{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	exporter, err := newOTLPExporter(envMap(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": srv.URL + "/",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "authorization=Bearer%20abc",
+		"OTEL_SERVICE_NAME":           "detector-test",
+		"OTEL_RESOURCE_ATTRIBUTES":    "deployment.environment=test",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := exporter.Tracer("scope")
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttributes(slog.Int("ffmpeg.exit_code", 1), slog.Bool("live", true), slog.Float64("noise_db", -30))
+	child.RecordError(errors.New("exit status 1"))
+	child.End()
+	parent.End()
+	exporter.shutdown(context.Background())
+
+	if len(collector.requests) != 1 {
+		t.Fatalf("expected one export request, got %d", len(collector.requests))
+	}
+	if got := collector.headers[0].Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("expected the configured header, got %q", got)
+	}
+	resourceSpans := collector.requests[0].ResourceSpans[0]
+	resource := resourceSpans.Resource.Attributes
+	if len(resource) != 2 || *resource[0].Value.StringValue != "detector-test" || resource[1].Key != "deployment.environment" {
+		t.Errorf("unexpected resource attributes %+v", resource)
+	}
+	scope := resourceSpans.ScopeSpans[0]
+	if scope.Scope.Name != "scope" || len(scope.Spans) != 2 {
+		t.Fatalf("expected two spans in scope %q, got %+v", "scope", resourceSpans.ScopeSpans)
+	}
+
+	childData, parentData := scope.Spans[0], scope.Spans[1]
+	if childData.TraceID != parentData.TraceID || childData.ParentSpanID != parentData.SpanID || parentData.ParentSpanID != "" {
+		t.Errorf("expected child to be nested under parent, got %+v and %+v", childData, parentData)
+	}
+	if len(childData.TraceID) != 32 || len(childData.SpanID) != 16 {
+		t.Errorf("expected hex trace and span IDs, got %q and %q", childData.TraceID, childData.SpanID)
+	}
+	if childData.Status.Code != otlpStatusError || len(childData.Events) != 1 || childData.Events[0].Name != "exception" {
+		t.Errorf("expected the child to record an error, got %+v", childData)
+	}
+	if parentData.Status.Code != 0 {
+		t.Errorf("expected the parent to succeed, got %+v", parentData.Status)
+	}
+	attrs := childData.Attributes
+	if *attrs[0].Value.IntValue != "1" || !*attrs[1].Value.BoolValue || *attrs[2].Value.DoubleValue != -30 {
+		t.Errorf("unexpected attributes %+v", attrs)
+	}
+}
+
+func TestNewOTLPExporterEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		endpoint string
+		disabled bool
+		wantErr  bool
+	}{
+		{name: "default", endpoint: defaultTracesEndpoint},
+		{
+			name:     "traces endpoint",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://b:4318/traces"},
+			endpoint: "http://b:4318/traces",
+		},
+		{name: "sdk disabled", env: map[string]string{"OTEL_SDK_DISABLED": "true"}, disabled: true},
+		{name: "exporter none", env: map[string]string{"OTEL_TRACES_EXPORTER": "none"}, disabled: true},
+		{name: "json protocol", env: map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "http/json"}, endpoint: defaultTracesEndpoint},
+		{name: "protobuf protocol", env: map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf"}, wantErr: true},
+		{name: "grpc traces protocol", env: map[string]string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "grpc"}, wantErr: true},
+		{name: "other exporter", env: map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"}, wantErr: true},
+		{name: "bad endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "localhost:4318"}, wantErr: true},
+		{name: "bad headers", env: map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "authorization"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := newOTLPExporter(envMap(tt.env))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.disabled {
+				if exporter != nil {
+					t.Fatal("expected tracing to be disabled")
+				}
+				return
+			}
+			defer exporter.shutdown(context.Background())
+			if exporter.endpoint != tt.endpoint {
+				t.Errorf("expected endpoint %q, got %q", tt.endpoint, exporter.endpoint)
+			}
+		})
+	}
+}
+
+func TestContextWithTraceparent(t *testing.T) {
+	exporter := &otlpExporter{}
+	tracer := exporter.Tracer("scope")
+
+	const traceID, spanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	ctx := contextWithTraceparent(context.Background(), "00-"+traceID+"-"+spanID+"-01")
+	_, span := tracer.Start(ctx, "detect")
+	if data := span.(*otlpSpan).data(); data.TraceID != traceID || data.ParentSpanID != spanID {
+		t.Errorf("expected the span to continue the trace, got %+v", data)
+	}
+
+	for _, invalid := range []string{"", "00-" + traceID, "00-00000000000000000000000000000000-" + spanID + "-01", "ff-" + traceID + "-" + spanID + "-01"} {
+		_, span := tracer.Start(contextWithTraceparent(context.Background(), invalid), "detect")
+		if data := span.(*otlpSpan).data(); data.TraceID == traceID || data.ParentSpanID != "" {
+			t.Errorf("expected traceparent %q to be ignored, got %+v", invalid, data)
+		}
+	}
+}
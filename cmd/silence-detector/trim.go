@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// trimCommand holds the settings of --emit-trim-command and --emit-segments.
+type trimCommand struct {
+	// ffmpeg is the ffmpeg binary the command runs.
+	ffmpeg string
+	// padding is the --trim-padding kept around non-silent regions, in seconds.
+	padding float64
+	// segmentsFile is the concat list written by --emit-segments, and minSegment its --min-segment-duration.
+	segmentsFile string
+	minSegment   float64
+	// mkdir creates the missing parent directories of segmentsFile.
+	mkdir bool
+}
+
+// emitTrimCommand writes the ffmpeg command that removes the silence of res and returns the exit code. A fully
+// silent input has nothing to keep, so no command is written and it is reported with exitCodeFullSilence.
+func emitTrimCommand(w io.Writer, res fileResult, opts reportOptions) int {
+	filter, err := res.result.RemovalFilter(opts.trim.padding)
+	switch {
+	case errors.Is(err, detector.ErrNothingToKeep):
+		fmt.Fprintf(os.Stderr, "%s: %v\n", displayInputPath(res.input), err)
+		return worseExitCode(res.exitCode(), exitCodeFullSilence)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: cannot remove the silence: %v\n", displayInputPath(res.input), err)
+		return exitCodeError
+	}
+
+	args := []string{opts.trim.ffmpeg, "-i", res.input, "-vn", "-af", filter, trimmedOutputPath(res.input)}
+	fmt.Fprintln(w, shellJoin(args))
+	return res.exitCode()
+}
+
+// emitSegments writes the concat list of the non-silent segments of res to the --emit-segments file, prints the
+// ffmpeg command that joins them, and returns the exit code. Joining stream-copies the segments instead of
+// re-encoding the input. When no segment is left to keep, because the input is silent or every segment is shorter
+// than --min-segment-duration, nothing is written and the input is reported with exitCodeFullSilence.
+func emitSegments(w io.Writer, res fileResult, opts reportOptions) int {
+	if res.result.InputDuration <= 0 {
+		fmt.Fprintf(os.Stderr, "%s: cannot remove the silence: the input duration is unknown\n", displayInputPath(res.input))
+		return exitCodeError
+	}
+	segments := res.result.KeepSegments(opts.trim.padding)
+	if len(segments) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", displayInputPath(res.input), detector.ErrNothingToKeep)
+		return worseExitCode(res.exitCode(), exitCodeFullSilence)
+	}
+	segments = detector.DropShortSegments(segments, opts.trim.minSegment)
+	if len(segments) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no non-silent segment lasts --min-segment-duration; nothing to keep\n", displayInputPath(res.input))
+		return worseExitCode(res.exitCode(), exitCodeFullSilence)
+	}
+
+	// The concat demuxer resolves relative paths against the directory of the list, not the working directory.
+	input := strings.TrimSpace(res.input)
+	args := []string{opts.trim.ffmpeg, "-f", "concat", "-safe", "0"}
+	if resolved, err := detector.ResolveInput(input); err == nil && resolved.Kind != detector.InputLocal {
+		args = append(args, "-protocol_whitelist", "file,http,https,tcp,tls,crypto")
+	} else if abs, err := filepath.Abs(input); err == nil {
+		input = abs
+	}
+
+	out, err := openReportOutput(opts.trim.segmentsFile, opts.trim.mkdir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeError
+	}
+	writeErr := detector.WriteConcatList(out, input, segments)
+	if err := errors.Join(writeErr, out.Close()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCodeError
+	}
+
+	args = append(args, "-i", opts.trim.segmentsFile, "-vn", "-c", "copy", trimmedOutputPath(res.input))
+	fmt.Fprintln(w, shellJoin(args))
+	return res.exitCode()
+}
+
+// trimmedOutputPath is the output of the command of --emit-trim-command: the input with a -trimmed suffix and the
+// same extension, next to a local input and in the working directory for a remote one. Inputs without an extension
+// are written as WAV.
+func trimmedOutputPath(input string) string {
+	input = strings.TrimSpace(input)
+	dir, name := filepath.Dir(input), input
+	if resolved, err := detector.ResolveInput(input); err == nil && resolved.Kind != detector.InputLocal {
+		dir = ""
+		if parsed, err := url.Parse(resolved.Raw); err == nil {
+			name = parsed.Path
+		}
+	}
+
+	ext := path.Ext(strings.ReplaceAll(name, `\`, "/"))
+	if ext == "" {
+		ext = ".wav"
+	}
+	return filepath.Join(dir, inputBaseName(input)+"-trimmed"+ext)
+}
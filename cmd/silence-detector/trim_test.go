@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+func TestEmitTrimCommand(t *testing.T) {
+	res := fileResult{
+		input: "/media/my talk.mp4",
+		result: detector.DetectionResult{
+			InputDuration: 10,
+			Intervals:     []detector.Interval{{Start: 4, End: 6, Duration: 2}},
+		},
+	}
+	opts := reportOptions{trim: trimCommand{ffmpeg: "ffmpeg", padding: 0.5}}
+
+	var buf bytes.Buffer
+	if code := emitTrimCommand(&buf, res, opts); code != exitCodeOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	want := `ffmpeg -i '/media/my talk.mp4' -vn -af 'aselect='\''between(t,0,4.5)+between(t,5.5,10)'\'',asetpts=N/SR/TB' '/media/my talk-trimmed.mp4'` + "\n"
+	if buf.String() != want {
+		t.Errorf("unexpected command:\n got %s\nwant %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	res.result.Intervals = []detector.Interval{{Start: 0, End: 10, Duration: 10}}
+	if code := emitTrimCommand(&buf, res, opts); code != exitCodeFullSilence || buf.Len() != 0 {
+		t.Errorf("expected no command for a silent input, got %d and %q", code, buf.String())
+	}
+}
+
+func TestTrimmedOutputPath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "episode.mp3", want: "episode-trimmed.mp3"},
+		{input: filepath.Join("media", "raw", "take.v2.wav"), want: filepath.Join("media", "raw", "take.v2-trimmed.wav")},
+		{input: "https://cdn.example.com/audio/show.m4a?token=x", want: "show-trimmed.m4a"},
+		{input: "https://cdn.example.com/audio/stream", want: "stream-trimmed.wav"},
+	}
+	for _, tt := range tests {
+		if got := trimmedOutputPath(tt.input); got != tt.want {
+			t.Errorf("trimmedOutputPath(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEmitSegments(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "show.wav")
+	list := filepath.Join(dir, "segments.ffconcat")
+	res := fileResult{
+		input: input,
+		result: detector.DetectionResult{
+			InputDuration: 20,
+			Intervals:     []detector.Interval{{Start: 4, End: 6, Duration: 2}, {Start: 6.5, End: 10, Duration: 3.5}},
+		},
+	}
+	opts := reportOptions{trim: trimCommand{ffmpeg: "ffmpeg", segmentsFile: list, minSegment: 1}}
+
+	var buf bytes.Buffer
+	if code := emitSegments(&buf, res, opts); code != exitCodeOK {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	if want := shellJoin([]string{"ffmpeg", "-f", "concat", "-safe", "0", "-i", list, "-vn", "-c", "copy", filepath.Join(dir, "show-trimmed.wav")}) + "\n"; buf.String() != want {
+		t.Errorf("unexpected command:\n got %s\nwant %s", buf.String(), want)
+	}
+
+	data, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The half-second segment between the silences is dropped.
+	file := "file '" + input + "'\n"
+	if want := "ffconcat version 1.0\n" + file + "inpoint 0\noutpoint 4\n" + file + "inpoint 10\noutpoint 20\n"; string(data) != want {
+		t.Errorf("unexpected concat list:\n%s\nwant:\n%s", data, want)
+	}
+
+	opts.trim.minSegment = 15
+	if code := emitSegments(&buf, res, opts); code != exitCodeFullSilence {
+		t.Errorf("expected exit code %d when every segment is too short, got %d", exitCodeFullSilence, code)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wistia/silence-detector/pkg/detector"
+)
+
+// totalDuration sums the durations of intervals.
+func totalDuration(intervals []detector.Interval) float64 {
+	var total float64
+	for _, interval := range intervals {
+		total += interval.Duration
+	}
+	return total
+}
+
+// newFileReport builds the JSON report of an analysed input, adding the --energy-timeline samples, the --quality
+// report and the --detect-black, --dead-air and --detect-freeze sections to the silence detection result.
+func newFileReport(res fileResult, opts reportOptions) *detector.Report {
+	report := newJSONReport(res.result, opts)
+	if opts.energyWindow > 0 {
+		report.EnergyWindow = opts.energyWindow
+		report.Samples = res.energy
+		report.Round(opts.timeFormat().precision)
+	}
+	if opts.quality {
+		report.Quality = res.quality
+	}
+	if !opts.detectBlack && !opts.detectFreeze {
+		return report
+	}
+
+	if res.noVideoStream {
+		report.NoVideoStream = true
+		return report
+	}
+	if opts.detectBlack {
+		report.Black = detector.NewIntervalsReport(res.black)
+	}
+	if opts.deadAir {
+		report.DeadAir = detector.NewIntervalsReport(detector.DeadAir(res.result.Intervals, res.black))
+	}
+	if opts.detectFreeze {
+		report.Freeze = detector.NewIntervalsReport(res.freeze)
+	}
+	// The video sections are rounded like the silence already is.
+	report.Round(opts.timeFormat().precision)
+	return report
+}
+
+// emitFileText writes the text report of an analysed input, followed by a summary of the --energy-timeline samples,
+// the --quality report and the --detect-black, --dead-air and --detect-freeze sections.
+func emitFileText(w io.Writer, res fileResult, opts reportOptions) {
+	emitText(w, res.result, opts)
+	if opts.energyWindow > 0 {
+		printEnergySummary(w, res.energy, opts)
+	}
+	if opts.quality && res.quality != nil {
+		printQuality(w, *res.quality)
+	}
+	if !opts.detectBlack && !opts.detectFreeze {
+		return
+	}
+
+	if res.noVideoStream {
+		fmt.Fprintln(w, "Input has no video stream; video was not analysed.")
+		return
+	}
+	if opts.detectBlack {
+		printIntervalSection(w, "black", res.black, opts.timeFormat())
+	}
+	if opts.deadAir {
+		printIntervalSection(w, "dead air", detector.DeadAir(res.result.Intervals, res.black), opts.timeFormat())
+	}
+	if opts.detectFreeze {
+		printIntervalSection(w, "freeze", res.freeze, opts.timeFormat())
+	}
+}
+
+// printIntervalSection writes the intervals of kind with their total duration, or a note that there were none.
+func printIntervalSection(w io.Writer, kind string, intervals []detector.Interval, times timeFormat) {
+	intervals = times.roundIntervals(intervals)
+	if len(intervals) == 0 {
+		fmt.Fprintf(w, "No %s intervals detected.\n", kind)
+		return
+	}
+	fmt.Fprintf(w, "Detected %d %s interval(s):\n", len(intervals), kind)
+	printIntervals(w, intervals, times)
+	fmt.Fprintf(w, "Total %s: %s\n", kind, times.seconds(totalDuration(intervals)))
+}
+
+// printEnergySummary writes the number of --energy-timeline windows and the range of their RMS levels; the samples
+// themselves are only listed in JSON.
+func printEnergySummary(w io.Writer, samples []detector.EnergySample, opts reportOptions) {
+	var quietest, loudest *float64
+	for _, sample := range samples {
+		if sample.RMSdB == nil {
+			continue
+		}
+		if quietest == nil || *sample.RMSdB < *quietest {
+			quietest = sample.RMSdB
+		}
+		if loudest == nil || *sample.RMSdB > *loudest {
+			loudest = sample.RMSdB
+		}
+	}
+	window := opts.timeFormat().seconds(opts.energyWindow)
+	fmt.Fprintf(w, "Energy timeline: %d window(s) of %s, RMS level from %s to %s\n", len(samples), window, formatVolume(quietest), formatVolume(loudest))
+}
+
+// printQuality writes the --quality report: the clipping figures on one line and the DC offset on the next. The
+// per-channel measurements are only listed in JSON.
+func printQuality(w io.Writer, quality detector.QualityReport) {
+	fmt.Fprintf(w, "Clipping: %d sample(s) at full scale, peak %s, flat factor %.2f\n", quality.ClippedSamples, formatVolume(quality.PeakDB), quality.FlatFactor)
+	fmt.Fprintf(w, "DC offset: %.6f of full scale\n", quality.DCOffset)
+}
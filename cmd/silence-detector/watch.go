@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// watchPollInterval is how often --watch lists the watched directory.
+const watchPollInterval = time.Second
+
+// fileStamp identifies a version of a file by its size and modification time.
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+// arrival is a file that appeared or changed in a watched directory and has not settled yet.
+type arrival struct {
+	stamp fileStamp
+	since time.Time
+}
+
+// dropFolder finds the files that have finished arriving in a watched directory.
+//
+// Every poll lists the directory. A file is ready once its size and modification time have not changed for the
+// settle period, so files that are still being copied are skipped, as are partial copies that are removed or renamed
+// before they settle. Files already present when watching starts are not analysed, and each version of a file is
+// reported once: a file is only analysed again when it changes or is dropped again after being removed.
+type dropFolder struct {
+	dir        string
+	recursive  bool
+	extensions []string
+	settle     time.Duration
+
+	// pending holds the files waiting to settle, and done the version of each file that was reported.
+	pending map[string]arrival
+	done    map[string]fileStamp
+}
+
+// newDropFolder starts watching dir for files with one of extensions, in subdirectories too when recursive is set.
+func newDropFolder(dir string, recursive bool, extensions []string, settle time.Duration) (*dropFolder, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot watch %q: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cannot watch %q: not a directory", dir)
+	}
+
+	folder := &dropFolder{
+		dir:        dir,
+		recursive:  recursive,
+		extensions: extensions,
+		settle:     settle,
+		pending:    make(map[string]arrival),
+	}
+	folder.done, err = folder.scan()
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// scan lists the matching files with their current size and modification time.
+func (f *dropFolder) scan() (map[string]fileStamp, error) {
+	paths, err := scanDirectory(f.dir, f.recursive, f.extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %q: %w", f.dir, err)
+	}
+
+	files := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Removed since it was listed.
+			continue
+		}
+		files[path] = fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+	}
+	return files, nil
+}
+
+// poll lists the directory at now and returns the files that have settled since the last poll, sorted by path.
+func (f *dropFolder) poll(now time.Time) ([]string, error) {
+	files, err := f.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var ready []string
+	for path, stamp := range files {
+		if done, ok := f.done[path]; ok && done == stamp {
+			continue
+		}
+		pending, ok := f.pending[path]
+		if !ok || pending.stamp != stamp {
+			f.pending[path] = arrival{stamp: stamp, since: now}
+			continue
+		}
+		if now.Sub(pending.since) >= f.settle {
+			ready = append(ready, path)
+			delete(f.pending, path)
+			f.done[path] = stamp
+		}
+	}
+
+	for path := range f.pending {
+		if _, ok := files[path]; !ok {
+			delete(f.pending, path)
+		}
+	}
+	for path := range f.done {
+		if _, ok := files[path]; !ok {
+			delete(f.done, path)
+		}
+	}
+
+	slices.Sort(ready)
+	return ready, nil
+}
+
+// runWatch analyses the files that settle in folder, one at a time, and passes each result to emit until ctx is
+// done. An analysis that is running when ctx is done is finished and emitted, so that shutting down never drops or
+// truncates a report; no further files are started.
+func runWatch(ctx context.Context, folder *dropFolder, poll time.Duration, analyse func(context.Context, string) fileResult, emit func(fileResult)) error {
+	analyseCtx := context.WithoutCancel(ctx)
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		ready, err := folder.poll(time.Now())
+		if err != nil {
+			return err
+		}
+		for _, path := range ready {
+			if ctx.Err() != nil {
+				return nil
+			}
+			emit(analyse(analyseCtx, path))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// openWatchOutput returns the destination of --watch reports and a function that closes it. Records are appended to
+// an --output-file as files are analysed, instead of replacing the file at the end of the run as other modes do.
+func openWatchOutput(target string, mkdir bool) (io.Writer, func() error, error) {
+	if target == "" || target == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	if mkdir {
+		dir := filepath.Dir(target)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create output directory %q: %w", dir, err)
+		}
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open output file %q: %w", target, err)
+	}
+	return file, file.Close, nil
+}
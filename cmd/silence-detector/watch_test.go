@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeDropFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendDropFile(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertReady(t *testing.T, folder *dropFolder, now time.Time, want ...string) {
+	t.Helper()
+	ready, err := folder.poll(now)
+	if err != nil {
+		t.Fatalf("poll returned error: %v", err)
+	}
+	if !slices.Equal(ready, want) {
+		t.Fatalf("unexpected ready files at %s: got %q want %q", now.Format(time.TimeOnly), ready, want)
+	}
+}
+
+func TestDropFolderWaitsForFilesToSettle(t *testing.T) {
+	dir := t.TempDir()
+	writeDropFile(t, filepath.Join(dir, "existing.wav"), "old")
+
+	folder, err := newDropFolder(dir, false, []string{".wav"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("newDropFolder returned error: %v", err)
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	arriving := filepath.Join(dir, "arriving.wav")
+	writeDropFile(t, arriving, "part")
+	writeDropFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+	writeDropFile(t, filepath.Join(dir, ".hidden.wav"), "ignored")
+	assertReady(t, folder, start)
+
+	// The copy is still growing, which restarts the settle period.
+	appendDropFile(t, arriving, " and more")
+	assertReady(t, folder, start.Add(3*time.Second))
+	assertReady(t, folder, start.Add(7*time.Second))
+
+	assertReady(t, folder, start.Add(8*time.Second), arriving)
+	// Every version of a file is reported once.
+	assertReady(t, folder, start.Add(20*time.Second))
+}
+
+func TestDropFolderReanalysesReplacedFiles(t *testing.T) {
+	dir := t.TempDir()
+	folder, err := newDropFolder(dir, false, []string{".wav"}, time.Second)
+	if err != nil {
+		t.Fatalf("newDropFolder returned error: %v", err)
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	partial := filepath.Join(dir, "partial.wav")
+	writeDropFile(t, partial, "part")
+	assertReady(t, folder, start)
+
+	// A partial copy that is removed before it settles is never analysed; the file it is renamed to is.
+	final := filepath.Join(dir, "final.wav")
+	if err := os.Rename(partial, final); err != nil {
+		t.Fatal(err)
+	}
+	assertReady(t, folder, start.Add(2*time.Second))
+	assertReady(t, folder, start.Add(4*time.Second), final)
+
+	writeDropFile(t, final, "a new take")
+	assertReady(t, folder, start.Add(5*time.Second))
+	assertReady(t, folder, start.Add(6*time.Second), final)
+
+	// A file dropped again after being removed is a new arrival.
+	if err := os.Remove(final); err != nil {
+		t.Fatal(err)
+	}
+	assertReady(t, folder, start.Add(7*time.Second))
+	writeDropFile(t, final, "a new take")
+	assertReady(t, folder, start.Add(8*time.Second))
+	assertReady(t, folder, start.Add(9*time.Second), final)
+}
+
+func TestRunWatchFinishesAnalysisOnShutdown(t *testing.T) {
+	dir := t.TempDir()
+	folder, err := newDropFolder(dir, false, []string{".wav"}, 0)
+	if err != nil {
+		t.Fatalf("newDropFolder returned error: %v", err)
+	}
+	writeDropFile(t, filepath.Join(dir, "a.wav"), "a")
+	writeDropFile(t, filepath.Join(dir, "b.wav"), "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var analysed, emitted []string
+	analyse := func(ctx context.Context, path string) fileResult {
+		analysed = append(analysed, filepath.Base(path))
+		// Shutdown is requested while the first file is being analysed.
+		cancel()
+		if ctx.Err() != nil {
+			t.Errorf("expected the running analysis to outlive the shutdown")
+		}
+		return fileResult{input: path}
+	}
+	emit := func(res fileResult) {
+		emitted = append(emitted, filepath.Base(res.input))
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- runWatch(ctx, folder, 10*time.Millisecond, analyse, emit) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runWatch returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWatch did not stop after shutdown")
+	}
+
+	if !slices.Equal(analysed, []string{"a.wav"}) || !slices.Equal(emitted, []string{"a.wav"}) {
+		t.Fatalf("expected only the running analysis to finish, analysed %q emitted %q", analysed, emitted)
+	}
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// noiseFloorFilter reports audio statistics, including the noise floor, once the input has been read.
+const noiseFloorFilter = "astats=metadata=0"
+
+// measureNoiseFloor runs the first pass of an adaptive detection: ffmpeg's astats filter over the same audio that
+// silencedetect will analyse. It returns the overall noise floor in dB, which is -Inf when the audio contains
+// digital silence.
+func (d *Detector) measureNoiseFloor(ctx context.Context, inputPath string, options DetectionOptions) (float64, error) {
+	filter := noiseFloorFilter
+	if format := audioFormat(options); format != "" {
+		filter = format + "," + filter
+	}
+
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin", "-nostats")
+	}
+	args = append(args, filterArgs(inputPath, options, filter)...)
+
+	d.debug(ctx, "measuring noise floor", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	output, err := d.run(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return 0, newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
+	}
+
+	floor, err := parseNoiseFloor(string(output))
+	if err != nil {
+		return 0, err
+	}
+
+	d.debug(ctx, "measured noise floor", "elapsed", time.Since(start), "noise_floor_db", floor)
+	return floor, nil
+}
+
+// parseNoiseFloor returns the "Noise floor dB" value of the Overall section of astats output, falling back to the
+// lowest per-channel value for builds that do not print an Overall section.
+func parseNoiseFloor(output string) (float64, error) {
+	var overall, lowest *float64
+	inOverall := false
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(logPrefixPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		switch {
+		case line == "Overall":
+			inOverall = true
+		case strings.HasPrefix(line, "Channel:"):
+			inOverall = false
+		case strings.HasPrefix(line, "Noise floor dB:"):
+			value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(line, "Noise floor dB:")), 64)
+			if err != nil {
+				continue
+			}
+			if inOverall {
+				overall = &value
+			} else if lowest == nil || value < *lowest {
+				lowest = &value
+			}
+		}
+	}
+
+	switch {
+	case overall != nil:
+		return *overall, nil
+	case lowest != nil:
+		return *lowest, nil
+	default:
+		return 0, errors.New("ffmpeg astats output did not include a noise floor")
+	}
+}
+
+// adaptiveThreshold returns the silencedetect threshold for a measured noise floor, capped at 0 dB. A floor that is
+// not finite, as reported for digital silence, carries no information about the room tone, so the fixed fallback
+// is used.
+func adaptiveThreshold(floor, offset, fallback float64) float64 {
+	if math.IsInf(floor, 0) || math.IsNaN(floor) {
+		return fallback
+	}
+	return math.Min(floor+offset, 0)
+}
@@ -0,0 +1,130 @@
+package detector
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+const astatsOutput = `[Parsed_astats_0 @ 0x5580] Channel: 1
+[Parsed_astats_0 @ 0x5580] DC offset: 0.000012
+[Parsed_astats_0 @ 0x5580] RMS level dB: -24.512345
+[Parsed_astats_0 @ 0x5580] Noise floor dB: -61.250000
+[Parsed_astats_0 @ 0x5580] Channel: 2
+[Parsed_astats_0 @ 0x5580] Noise floor dB: -58.750000
+[Parsed_astats_0 @ 0x5580] Overall
+[Parsed_astats_0 @ 0x5580] DC offset: 0.000010
+[Parsed_astats_0 @ 0x5580] Noise floor dB: -60.000000
+[Parsed_astats_0 @ 0x5580] Number of samples: 441000
+`
+
+func TestParseNoiseFloor(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    float64
+		wantErr bool
+	}{
+		{name: "overall section", output: astatsOutput, want: -60},
+		{name: "lowest channel without overall", output: "[Parsed_astats_0 @ 0x1] Channel: 1\n[Parsed_astats_0 @ 0x1] Noise floor dB: -50.5\n[Parsed_astats_0 @ 0x1] Channel: 2\n[Parsed_astats_0 @ 0x1] Noise floor dB: -52\n", want: -52},
+		{name: "digital silence", output: "[Parsed_astats_0 @ 0x1] Overall\n[Parsed_astats_0 @ 0x1] Noise floor dB: -inf\n", want: math.Inf(-1)},
+		{name: "missing", output: "size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNoiseFloor(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %f", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNoiseFloor returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSilenceAdaptiveThreshold(t *testing.T) {
+	tests := []struct {
+		name          string
+		floorOutput   string
+		offset        float64
+		wantThreshold float64
+		wantFilter    string
+	}{
+		{name: "floor plus offset", floorOutput: astatsOutput, offset: 10, wantThreshold: -50, wantFilter: "silencedetect=noise=-50dB:d=1"},
+		{name: "capped at 0 dB", floorOutput: astatsOutput, offset: 75, wantThreshold: 0, wantFilter: "silencedetect=noise=0dB:d=1"},
+		{name: "digital silence falls back", floorOutput: "[Parsed_astats_0 @ 0x1] Overall\n[Parsed_astats_0 @ 0x1] Noise floor dB: -inf\n", offset: 10, wantThreshold: -35, wantFilter: "silencedetect=noise=-35dB:d=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters []string
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				filter := args[len(args)-4]
+				filters = append(filters, filter)
+				if strings.Contains(filter, "astats") {
+					return []byte(tt.floorOutput), nil
+				}
+				return []byte("size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+			}
+			d := NewDetector(WithCommandRunner(runner))
+
+			result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+				NoiseLevel:         -35,
+				MinSilenceDuration: 1,
+				AdaptiveThreshold:  true,
+				AdaptiveOffsetDB:   tt.offset,
+			})
+			if err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+
+			if len(filters) != 2 || filters[0] != "astats=metadata=0" || filters[1] != tt.wantFilter {
+				t.Fatalf("unexpected filter passes %v", filters)
+			}
+			assertFloatEqual(t, result.ThresholdDB, tt.wantThreshold)
+			if result.NoiseFloorDB == nil {
+				t.Fatal("expected the measured noise floor in the result")
+			}
+		})
+	}
+}
+
+func TestDetectSilenceReportsFixedThreshold(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{NoiseLevel: -42, MinSilenceDuration: 1})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.ThresholdDB != -42 || result.NoiseFloorDB != nil {
+		t.Fatalf("unexpected threshold %f and noise floor %v", result.ThresholdDB, result.NoiseFloorDB)
+	}
+}
+
+func TestDetectSilenceAdaptiveRejectsStdin(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg should not run")
+		return nil, nil
+	}))
+
+	_, err := d.DetectSilence(context.Background(), "-", DetectionOptions{
+		MinSilenceDuration: 1,
+		AdaptiveThreshold:  true,
+		Stdin:              strings.NewReader(""),
+	})
+	if err == nil || !strings.Contains(err.Error(), "seekable") {
+		t.Fatalf("expected stdin to be rejected, got %v", err)
+	}
+}
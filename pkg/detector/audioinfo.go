@@ -0,0 +1,201 @@
+package detector
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AudioInfo describes the audio stream that was analysed. Fields that could not be determined are left at their
+// zero value.
+type AudioInfo struct {
+	// StreamIndex is the index of the stream among all streams of the input, as in ffmpeg's "Stream #0:1". Unlike
+	// DetectionOptions.AudioStreamIndex, it counts video and data streams too.
+	StreamIndex int    `json:"stream_index"`
+	Codec       string `json:"codec,omitempty"`
+	// SampleRate is in Hz.
+	SampleRate    int    `json:"sample_rate,omitempty"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	// BitRate is in bits per second, and zero for streams that do not declare one.
+	BitRate int64 `json:"bit_rate,omitempty"`
+}
+
+// probeStreamEntries are the ffprobe -show_entries stream fields that describe the audio streams of an input.
+const probeStreamEntries = "stream=index,codec_type,codec_name,sample_rate,channels,channel_layout,bit_rate"
+
+// parseProbeAudioInfo extracts the audio streams from ffprobe output requested with probeStreamEntries, in order.
+// Output it cannot parse yields no streams, since the description of the audio is optional.
+func parseProbeAudioInfo(output []byte) []AudioInfo {
+	var probe struct {
+		Streams []struct {
+			Index         int    `json:"index"`
+			CodecType     string `json:"codec_type"`
+			CodecName     string `json:"codec_name"`
+			SampleRate    string `json:"sample_rate"`
+			Channels      int    `json:"channels"`
+			ChannelLayout string `json:"channel_layout"`
+			BitRate       string `json:"bit_rate"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil
+	}
+
+	var streams []AudioInfo
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		// ffprobe reports unknown values as "N/A", which leaves them at zero.
+		sampleRate, _ := strconv.Atoi(stream.SampleRate)
+		bitRate, _ := strconv.ParseInt(stream.BitRate, 10, 64)
+		streams = append(streams, AudioInfo{
+			StreamIndex:   stream.Index,
+			Codec:         stream.CodecName,
+			SampleRate:    sampleRate,
+			Channels:      stream.Channels,
+			ChannelLayout: stream.ChannelLayout,
+			BitRate:       bitRate,
+		})
+	}
+	return streams
+}
+
+// selectAudioInfo picks the description of the analysed stream. The stream ffmpeg reported in its banner is
+// described with ffprobe's details when ffprobe listed it. Without a banner, options.AudioStreamIndex selects among
+// the probed streams; when it is unset, a single audio stream is the one ffmpeg chose, while ffmpeg's choice among
+// several is not known and nil is returned.
+func selectAudioInfo(banner *AudioInfo, probed []AudioInfo, options DetectionOptions) *AudioInfo {
+	if banner != nil {
+		for _, stream := range probed {
+			if stream.StreamIndex == banner.StreamIndex {
+				return &stream
+			}
+		}
+		return banner
+	}
+
+	switch {
+	case options.AudioStreamIndex != nil && *options.AudioStreamIndex < len(probed):
+		return &probed[*options.AudioStreamIndex]
+	case options.AudioStreamIndex == nil && options.AudioLanguage == "" && len(probed) == 1:
+		return &probed[0]
+	}
+	return nil
+}
+
+var (
+	// bannerAudioPattern matches the input audio streams ffmpeg lists before processing, such as
+	// "Stream #0:1[0x101](eng): Audio: aac (LC), 48000 Hz, stereo, fltp, 130 kb/s (default)".
+	bannerAudioPattern = regexp.MustCompile(`^Stream #0:([0-9]+)[^:]*: Audio: (.+)$`)
+	// bannerMappingPattern matches the "Stream mapping:" entry of an input stream, such as
+	// "Stream #0:1 -> #0:0 (aac (native) -> pcm_s16le (native))".
+	bannerMappingPattern = regexp.MustCompile(`^Stream #0:([0-9]+)(?: \([^)]*\))? -> `)
+	// bannerDispositionPattern matches the dispositions ffmpeg appends to a stream, such as " (default)".
+	bannerDispositionPattern = regexp.MustCompile(`(\s+\([a-z_ ]+\))+$`)
+	bannerChannelsPattern    = regexp.MustCompile(`^([0-9]+) channels$`)
+	bannerBitRatePattern     = regexp.MustCompile(`^([0-9]+) kb/s$`)
+)
+
+// layoutChannels maps ffmpeg's channel layout names, without variants such as "(side)", to their channel count.
+var layoutChannels = map[string]int{
+	"mono": 1, "stereo": 2, "downmix": 2, "2.1": 3, "3.0": 3, "3.1": 4, "4.0": 4, "quad": 4, "4.1": 5, "5.0": 5,
+	"5.1": 6, "6.0": 6, "hexagonal": 6, "6.1": 7, "7.0": 7, "7.1": 8, "octagonal": 8, "hexadecagonal": 16, "22.2": 24,
+}
+
+// audioInfoParser describes the analysed audio stream from the input listing and stream mapping that ffmpeg prints
+// before processing. The output section, which lists ffmpeg's own streams in the same format, is ignored.
+type audioInfoParser struct {
+	streams  []AudioInfo
+	mapped   *int
+	inOutput bool
+}
+
+// parseLine consumes a trimmed line of the banner and reports whether it belonged to it.
+func (p *audioInfoParser) parseLine(line string) bool {
+	if !strings.HasPrefix(line, "Stream #") && !strings.HasPrefix(line, "Output #") {
+		return false
+	}
+	if strings.HasPrefix(line, "Output #") {
+		p.inOutput = true
+		return true
+	}
+
+	if matches := bannerMappingPattern.FindStringSubmatch(line); len(matches) == 2 {
+		if index, err := strconv.Atoi(matches[1]); err == nil && p.mapped == nil && p.stream(index) != nil {
+			p.mapped = &index
+		}
+		return true
+	}
+	if p.inOutput {
+		return true
+	}
+	matches := bannerAudioPattern.FindStringSubmatch(line)
+	if len(matches) != 3 {
+		return false
+	}
+	index, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return false
+	}
+	p.streams = append(p.streams, parseBannerAudio(index, matches[2]))
+	return true
+}
+
+// result returns the stream ffmpeg mapped to its output, or the first audio stream when the banner had no stream
+// mapping, or nil when it listed no audio stream.
+func (p *audioInfoParser) result() *AudioInfo {
+	if p.mapped != nil {
+		return p.stream(*p.mapped)
+	}
+	if len(p.streams) == 0 {
+		return nil
+	}
+	return &p.streams[0]
+}
+
+func (p *audioInfoParser) stream(index int) *AudioInfo {
+	for i := range p.streams {
+		if p.streams[i].StreamIndex == index {
+			return &p.streams[i]
+		}
+	}
+	return nil
+}
+
+// parseBannerAudio parses the description of an audio stream, such as "aac (LC) (mp4a / 0x6134706D), 48000 Hz,
+// stereo, fltp, 128 kb/s (default)". The channel layout follows the sample rate; inputs without one report a
+// channel count such as "6 channels" instead.
+func parseBannerAudio(index int, description string) AudioInfo {
+	info := AudioInfo{StreamIndex: index}
+	fields := strings.Split(bannerDispositionPattern.ReplaceAllString(description, ""), ", ")
+	if codec, _, _ := strings.Cut(fields[0], " "); codec != "" {
+		info.Codec = codec
+	}
+
+	afterRate := false
+	for _, field := range fields[1:] {
+		field = strings.TrimSpace(field)
+		switch {
+		case strings.HasSuffix(field, " Hz"):
+			info.SampleRate, _ = strconv.Atoi(strings.TrimSuffix(field, " Hz"))
+			afterRate = true
+			continue
+		case bannerBitRatePattern.MatchString(field):
+			kbps, _ := strconv.ParseInt(bannerBitRatePattern.FindStringSubmatch(field)[1], 10, 64)
+			info.BitRate = kbps * 1000
+		case afterRate:
+			if matches := bannerChannelsPattern.FindStringSubmatch(field); len(matches) == 2 {
+				info.Channels, _ = strconv.Atoi(matches[1])
+			} else {
+				info.ChannelLayout = field
+				name, _, _ := strings.Cut(field, "(")
+				info.Channels = layoutChannels[name]
+			}
+		}
+		afterRate = false
+	}
+	return info
+}
@@ -0,0 +1,101 @@
+package detector
+
+import (
+	"context"
+	"testing"
+)
+
+const bannerOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'master.mov':
+  Duration: 00:00:10.00, start: 0.000000, bitrate: 2000 kb/s
+  Stream #0:0[0x1](und): Video: h264 (High) (avc1 / 0x31637661), yuv420p, 1920x1080, 30 fps (default)
+  Stream #0:1[0x2](eng): Audio: aac (LC) (mp4a / 0x6134706D), 48000 Hz, stereo, fltp, 128 kb/s (default)
+  Stream #0:2[0x3](fra): Audio: ac3 (ac-3 / 0x332D6361), 48000 Hz, 5.1(side), fltp, 384 kb/s
+Stream mapping:
+  Stream #0:2 -> #0:0 (ac3 (native) -> pcm_s16le (native))
+Output #0, null, to 'pipe:':
+  Stream #0:0(fra): Audio: pcm_s16le, 48000 Hz, 5.1(side), s16, 4608 kb/s
+[silencedetect @ 0x1] silence_start: 2
+[silencedetect @ 0x1] silence_end: 4 | silence_duration: 2
+`
+
+func TestSilenceParserDescribesAudioFromBanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   *AudioInfo
+	}{
+		{
+			name:   "mapped stream",
+			output: bannerOutput,
+			want:   &AudioInfo{StreamIndex: 2, Codec: "ac3", SampleRate: 48000, Channels: 6, ChannelLayout: "5.1(side)", BitRate: 384000},
+		},
+		{
+			name:   "channel count without layout",
+			output: "  Stream #0:0: Audio: pcm_s24le, 96000 Hz, 10 channels, s32\n",
+			want:   &AudioInfo{StreamIndex: 0, Codec: "pcm_s24le", SampleRate: 96000, Channels: 10},
+		},
+		{
+			name:   "stripped banner",
+			output: "[silencedetect @ 0x1] silence_start: 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parser silenceParser
+			if err := parser.parseOutput(tt.output); err != nil {
+				t.Fatalf("parseOutput returned error: %v", err)
+			}
+			got := parser.finish().AudioInfo
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("unexpected audio info %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSilenceDescribesAudioWithFFprobe(t *testing.T) {
+	const probeOutput = `{"format":{"duration":"10.0"},"streams":[
+		{"index":0,"codec_type":"video","codec_name":"h264"},
+		{"index":1,"codec_type":"audio","codec_name":"aac","sample_rate":"48000","channels":2,"channel_layout":"stereo","bit_rate":"127999"},
+		{"index":2,"codec_type":"audio","codec_name":"ac3","sample_rate":"48000","channels":6,"channel_layout":"5.1(side)","bit_rate":"N/A"}]}`
+	ffmpegOutput := bannerOutput
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" {
+			return []byte(probeOutput), nil
+		}
+		return []byte(ffmpegOutput), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}
+
+	// ffprobe's details win for the stream ffmpeg mapped.
+	result, err := d.DetectSilence(context.Background(), "master.mov", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if want := (AudioInfo{StreamIndex: 2, Codec: "ac3", SampleRate: 48000, Channels: 6, ChannelLayout: "5.1(side)"}); result.AudioInfo == nil || *result.AudioInfo != want {
+		t.Fatalf("unexpected audio info %+v, want %+v", result.AudioInfo, want)
+	}
+
+	// Without a banner, the selected audio stream is described.
+	ffmpegOutput = "[silencedetect @ 0x1] silence_start: 2\n"
+	options.AudioStreamIndex = intPtr(0)
+	result, err = d.DetectSilence(context.Background(), "master.mov", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.AudioInfo == nil || result.AudioInfo.StreamIndex != 1 || result.AudioInfo.BitRate != 127999 {
+		t.Fatalf("expected the first audio stream, got %+v", result.AudioInfo)
+	}
+
+	// ffmpeg's choice among several streams is unknown.
+	options.AudioStreamIndex = nil
+	result, err = d.DetectSilence(context.Background(), "master.mov", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.AudioInfo != nil {
+		t.Fatalf("expected no audio info, got %+v", result.AudioInfo)
+	}
+}
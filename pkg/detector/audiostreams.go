@@ -0,0 +1,94 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// languageTagPattern matches the language tags DetectionOptions.AudioLanguage accepts, such as "eng" or "pt-BR".
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]+(-[A-Za-z0-9]+)*$`)
+
+// StreamInfo describes an audio stream of an input, as reported by ffprobe.
+type StreamInfo struct {
+	// Index is the position of the stream among the audio streams of the input, as selected by
+	// DetectionOptions.AudioStreamIndex.
+	Index int `json:"index"`
+	// Codec is the name of the stream's codec, such as "aac".
+	Codec    string `json:"codec,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+	// Language is the stream's language tag, such as "eng", or empty when it has none.
+	Language string `json:"language,omitempty"`
+}
+
+// DetectSilenceAllStreams lists the audio streams of the input with ffprobe and runs DetectSilence on each of them,
+// with options.AudioStreamIndex set to select it in place of any AudioLanguage. It is meant for masters with several
+// audio tracks, such as one per language. Each stream gets its own ffmpeg run, so the input must be seekable and
+// cannot be a live stream, and the detector needs ffprobe (see WithFFprobePath).
+//
+// A stream that fails does not stop the others: the results of the streams that were analysed are returned along
+// with an error joining a *StreamError for each failed stream. Inputs without audio fail with ErrNoAudioStream.
+func (d *Detector) DetectSilenceAllStreams(ctx context.Context, inputPath string, options DetectionOptions) (map[StreamInfo]DetectionResult, error) {
+	if d.ffprobePath == "" {
+		return nil, errors.New("finding the audio streams of an input requires ffprobe; see WithFFprobePath")
+	}
+	if isPipeInput(inputPath) || options.Live {
+		return nil, errors.New("analysing every audio stream reads the input once per stream, which stdin and live inputs do not allow")
+	}
+	inputPath, err := prepareInput(inputPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	streams, err := d.probeAudioStreams(ctx, inputPath, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, ErrNoAudioStream
+	}
+
+	results := make(map[StreamInfo]DetectionResult, len(streams))
+	var errs []error
+	for _, stream := range streams {
+		streamOptions := options
+		streamOptions.AudioStreamIndex = &stream.Index
+		streamOptions.AudioLanguage = ""
+		result, err := d.DetectSilence(ctx, inputPath, streamOptions)
+		if err != nil {
+			errs = append(errs, &StreamError{Stream: stream, Err: err})
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		results[stream] = result
+	}
+	return results, errors.Join(errs...)
+}
+
+// resolveAudioLanguage returns the index of the first audio stream tagged with options.AudioLanguage, compared
+// case-insensitively. It fails with ErrLanguageNotFound, listing the available languages, when no stream has the
+// tag, and returns -1 when ffprobe could not list the streams, so that ffmpeg selects the stream by its metadata.
+func (d *Detector) resolveAudioLanguage(ctx context.Context, inputPath string, options DetectionOptions) (int, error) {
+	streams, err := d.probeAudioStreams(ctx, inputPath, options)
+	if err != nil {
+		return -1, nil
+	}
+
+	var available []string
+	for _, stream := range streams {
+		if strings.EqualFold(stream.Language, options.AudioLanguage) {
+			return stream.Index, nil
+		}
+		if stream.Language != "" {
+			available = append(available, stream.Language)
+		}
+	}
+	if len(available) == 0 {
+		return 0, fmt.Errorf("%w: %q (the audio streams have no language tags)", ErrLanguageNotFound, options.AudioLanguage)
+	}
+	return 0, fmt.Errorf("%w: %q (available: %s)", ErrLanguageNotFound, options.AudioLanguage, strings.Join(available, ", "))
+}
@@ -0,0 +1,108 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeAudioStreamsRunner answers ffprobe with two audio streams and a duration of 10s, and ffmpeg with a silence on
+// the first stream and a missing second stream.
+func fakeAudioStreamsRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	switch {
+	case name == "ffprobe" && slices.Contains(args, "-select_streams"):
+		return []byte(`{"streams":[{"codec_name":"aac","channels":2,"tags":{"language":"eng"}},{"codec_name":"ac3","channels":6}]}`), nil
+	case name == "ffprobe":
+		return []byte(`{"format":{"duration":"10.0"}}`), nil
+	case slices.Contains(args, "0:a:1"):
+		return []byte("Stream map '0:a:1' matches no streams.\n"), errors.New("exit status 1")
+	}
+	return []byte("[silencedetect @ 0x1] silence_start: 2\n[silencedetect @ 0x1] silence_end: 4 | silence_duration: 2\n"), nil
+}
+
+func TestDetectSilenceAllStreams(t *testing.T) {
+	d := NewDetector(WithCommandRunner(fakeAudioStreamsRunner), WithFFprobePath("ffprobe"))
+
+	results, err := d.DetectSilenceAllStreams(context.Background(), "master.mov", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5})
+	english := StreamInfo{Index: 0, Codec: "aac", Channels: 2, Language: "eng"}
+	if len(results) != 1 {
+		t.Fatalf("expected the result of the first stream, got %v", results)
+	}
+	assertIntervals(t, results[english].Intervals, []SilenceInterval{{Start: 2, End: 4, Duration: 2}})
+
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Stream != (StreamInfo{Index: 1, Codec: "ac3", Channels: 6}) {
+		t.Fatalf("expected a StreamError for the second stream, got %v", err)
+	}
+	if !errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("expected the stream's error to be wrapped, got %v", err)
+	}
+}
+
+func TestDetectSilenceAllStreamsRequirements(t *testing.T) {
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}
+
+	if _, err := NewDetector(WithCommandRunner(fakeAudioStreamsRunner)).DetectSilenceAllStreams(context.Background(), "master.mov", options); err == nil {
+		t.Errorf("expected an error without ffprobe")
+	}
+
+	d := NewDetector(WithCommandRunner(fakeAudioStreamsRunner), WithFFprobePath("ffprobe"))
+	if _, err := d.DetectSilenceAllStreams(context.Background(), "-", options); err == nil {
+		t.Errorf("expected an error for stdin")
+	}
+
+	silent := NewDetector(WithFFprobePath("ffprobe"), WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(`{"streams":[]}`), nil
+	}))
+	if _, err := silent.DetectSilenceAllStreams(context.Background(), "video.mp4", options); !errors.Is(err, ErrNoAudioStream) {
+		t.Errorf("expected ErrNoAudioStream for an input without audio, got %v", err)
+	}
+}
+
+func TestDetectSilenceSelectsAudioLanguage(t *testing.T) {
+	var ffmpegArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" && slices.Contains(args, "-select_streams") {
+			return []byte(`{"streams":[{"codec_name":"aac","channels":2,"tags":{"language":"eng"}},{"codec_name":"aac","channels":2,"tags":{"language":"fra"}},{"codec_name":"aac","channels":2}]}`), nil
+		}
+		if name == "ffprobe" {
+			return []byte(`{"format":{"duration":"10.0"}}`), nil
+		}
+		ffmpegArgs = args
+		return []byte(statsOutput), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, AudioLanguage: "FRA"}
+
+	if _, err := d.DetectSilence(context.Background(), "master.mov", options); err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if i := slices.Index(ffmpegArgs, "-map"); i < 0 || ffmpegArgs[i+1] != "0:a:1" {
+		t.Fatalf("expected the French stream to be mapped by index, got %v", ffmpegArgs)
+	}
+
+	options.AudioLanguage = "deu"
+	_, err := d.DetectSilence(context.Background(), "master.mov", options)
+	if !errors.Is(err, ErrLanguageNotFound) || err.Error() != `no audio stream with the requested language: "deu" (available: eng, fra)` {
+		t.Fatalf("expected ErrLanguageNotFound listing the languages, got %v", err)
+	}
+
+	// Without ffprobe, ffmpeg selects the stream by its metadata.
+	args, err := NewDetector().BuildArgs("master.mov", options)
+	if err != nil {
+		t.Fatalf("BuildArgs returned error: %v", err)
+	}
+	if i := slices.Index(args, "-map"); i < 0 || args[i+1] != "0:a:m:language:deu" {
+		t.Fatalf("expected a metadata stream specifier, got %v", args)
+	}
+
+	for _, invalid := range []DetectionOptions{
+		{NoiseLevel: -30, MinSilenceDuration: 0.5, AudioLanguage: "eng:x"},
+		{NoiseLevel: -30, MinSilenceDuration: 0.5, AudioLanguage: "eng", AudioStreamIndex: intPtr(0)},
+	} {
+		if _, err := d.DetectSilence(context.Background(), "master.mov", invalid); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
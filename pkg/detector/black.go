@@ -0,0 +1,195 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlackDetectionOptions configures how ffmpeg's blackdetect filter finds black picture. Zero values select
+// ffmpeg's defaults.
+type BlackDetectionOptions struct {
+	// MinDuration is the minimum length, in seconds, of a reported black period (blackdetect's d, 2 by default).
+	MinDuration float64
+	// PictureRatio is the fraction of black pixels, in (0, 1], above which a frame counts as black (pic_th, 0.98 by
+	// default).
+	PictureRatio float64
+	// PixelThreshold is the luminance, in (0, 1], below which a pixel counts as black (pix_th, 0.10 by default).
+	PixelThreshold float64
+	// StartOffset skips this many seconds of the input before analysis begins.
+	StartOffset float64
+	// AnalyzeDuration limits analysis to this many seconds of input. Zero analyses until the end of the input.
+	AnalyzeDuration float64
+	// InputHeaders are sent as HTTP request headers when inputPath is an HTTP(S) URL that ffmpeg reads directly.
+	InputHeaders http.Header
+}
+
+// DetectBlack executes ffmpeg with the blackdetect video filter and returns the periods of black picture, with
+// absolute timestamps. Inputs without video fail with ErrNoVideoStream. Standard input is not supported.
+func (d *Detector) DetectBlack(ctx context.Context, inputPath string, options BlackDetectionOptions) ([]Interval, error) {
+	if err := validateBlackOptions(inputPath, options); err != nil {
+		return nil, err
+	}
+
+	args := d.blackArgs(inputPath, options)
+	start := time.Now()
+	output, err := d.runVideoFilter(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals, err := parseBlackOutput(string(output))
+	if err != nil {
+		return nil, err
+	}
+	d.debug(ctx, "ffmpeg finished", "elapsed", time.Since(start), "black_intervals", len(intervals))
+
+	if options.StartOffset > 0 {
+		intervals = shiftIntervals(intervals, options.StartOffset)
+	}
+	return intervals, nil
+}
+
+// runVideoFilter runs ffmpeg with a video analysis filter. ffmpeg's "does not contain any stream" error is reported
+// as ErrNoVideoStream, since these runs disable audio and the video stream is what is missing.
+func (d *Detector) runVideoFilter(ctx context.Context, args []string) ([]byte, error) {
+	d.debug(ctx, "running ffmpeg", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	output, err := d.run(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		ffErr := newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
+		if errors.Is(ffErr, ErrNoAudioStream) {
+			ffErr.kind = ErrNoVideoStream
+		}
+		d.debug(ctx, "ffmpeg failed", "elapsed", time.Since(start), "error", RedactText(ffErr.Error()))
+		return nil, ffErr
+	}
+	return output, nil
+}
+
+// BuildBlackArgs returns the ffmpeg arguments DetectBlack would run for inputPath and options, after the same
+// validation. The ffmpeg binary itself is not included.
+func (d *Detector) BuildBlackArgs(inputPath string, options BlackDetectionOptions) ([]string, error) {
+	if err := validateBlackOptions(inputPath, options); err != nil {
+		return nil, err
+	}
+	return d.blackArgs(inputPath, options), nil
+}
+
+// validateBlackOptions checks the input and options of a black detection before ffmpeg runs.
+func validateBlackOptions(inputPath string, options BlackDetectionOptions) error {
+	if inputPath == "" {
+		return errors.New("input path is required")
+	}
+	if isPipeInput(inputPath) {
+		return errors.New("black detection does not support reading from stdin")
+	}
+	if options.MinDuration < 0 {
+		return fmt.Errorf("minimum black duration must not be negative, got %f", options.MinDuration)
+	}
+	if options.PictureRatio < 0 || options.PictureRatio > 1 {
+		return fmt.Errorf("black picture ratio must be between 0 and 1, got %f", options.PictureRatio)
+	}
+	if options.PixelThreshold < 0 || options.PixelThreshold > 1 {
+		return fmt.Errorf("black pixel threshold must be between 0 and 1, got %f", options.PixelThreshold)
+	}
+	if options.StartOffset < 0 {
+		return fmt.Errorf("start offset must not be negative, got %f", options.StartOffset)
+	}
+	if options.AnalyzeDuration < 0 {
+		return fmt.Errorf("analyze duration must not be negative, got %f", options.AnalyzeDuration)
+	}
+	return validateHeaders(options.InputHeaders)
+}
+
+// blackArgs constructs the ffmpeg arguments of a blackdetect run.
+func (d *Detector) blackArgs(inputPath string, options BlackDetectionOptions) []string {
+	params := []string{}
+	if options.MinDuration > 0 {
+		params = append(params, "d="+strconv.FormatFloat(options.MinDuration, 'f', -1, 64))
+	}
+	if options.PictureRatio > 0 {
+		params = append(params, "pic_th="+strconv.FormatFloat(options.PictureRatio, 'f', -1, 64))
+	}
+	if options.PixelThreshold > 0 {
+		params = append(params, "pix_th="+strconv.FormatFloat(options.PixelThreshold, 'f', -1, 64))
+	}
+	filter := "blackdetect"
+	if len(params) > 0 {
+		filter += "=" + strings.Join(params, ":")
+	}
+
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin", "-nostats")
+	}
+	return append(args, videoFilterArgs(inputPath, options.InputHeaders, options.StartOffset, options.AnalyzeDuration, filter)...)
+}
+
+// videoFilterArgs constructs the ffmpeg input, filter and output arguments of a run that passes the video of
+// inputPath through filter and discards the result.
+func videoFilterArgs(inputPath string, header http.Header, startOffset, analyzeDuration float64, filter string) []string {
+	args := headerArgs(inputPath, header)
+	if startOffset > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(startOffset, 'f', -1, 64))
+	}
+	args = append(args, "-i", inputPath)
+	if analyzeDuration > 0 {
+		args = append(args, "-t", strconv.FormatFloat(analyzeDuration, 'f', -1, 64))
+	}
+	return append(args, "-an", "-sn", "-dn", "-vf", filter, "-f", "null", "-")
+}
+
+var blackPattern = regexp.MustCompile(`black_start:\s*(` + ffmpegNumber + `)\s+black_end:\s*(` + ffmpegNumber + `)\s+black_duration:\s*(` + ffmpegNumber + `)`)
+
+// parseBlackOutput extracts the black periods blackdetect reports, one per line in the form
+// "black_start:0 black_end:2.002 black_duration:2.002".
+func parseBlackOutput(output string) ([]Interval, error) {
+	var intervals []Interval
+	for _, line := range strings.Split(output, "\n") {
+		matches := blackPattern.FindStringSubmatch(line)
+		if len(matches) != 4 {
+			continue
+		}
+
+		var values [3]float64
+		for i, match := range matches[1:] {
+			value, err := parseFFmpegNumber(match, fmt.Sprintf("black interval %q", strings.TrimSpace(line)))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		intervals = append(intervals, clampedInterval(values[0], values[1], values[2]))
+	}
+	return intervals, nil
+}
+
+// DeadAir returns the periods that are both silent and black, which is what "dead air" means for video. The
+// inputs may be unsorted or overlapping; the result is ordered by start time and contains no empty intervals.
+func DeadAir(silence, black []Interval) []Interval {
+	silence = unionIntervals(silence)
+	black = unionIntervals(black)
+
+	var dead []Interval
+	for i, j := 0, 0; i < len(silence) && j < len(black); {
+		start := max(silence[i].Start, black[j].Start)
+		end := min(silence[i].End, black[j].End)
+		if end > start {
+			dead = append(dead, Interval{Start: start, End: end, Duration: end - start})
+		}
+
+		if silence[i].End < black[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return dead
+}
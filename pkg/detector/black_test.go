@@ -0,0 +1,153 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+func TestParseBlackOutput(t *testing.T) {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'video.mp4':
+[blackdetect @ 0x55d0c3a0b2c0] black_start:0 black_end:2.002 black_duration:2.002
+frame=  250 fps=0.0 q=-0.0 size=N/A time=00:00:08.00 bitrate=N/A speed=16x
+[Parsed_blackdetect_0 @ 0x55d0c3a0b2c0] black_start:5.5 black_end:8.04 black_duration:2.54
+`
+
+	intervals, err := parseBlackOutput(output)
+	if err != nil {
+		t.Fatalf("parseBlackOutput returned error: %v", err)
+	}
+	assertIntervals(t, intervals, []Interval{
+		{Start: 0, End: 2.002, Duration: 2.002},
+		{Start: 5.5, End: 8.04, Duration: 2.54},
+	})
+
+	if intervals, err := parseBlackOutput("[silencedetect @ 0x1] silence_start: 1\n"); err != nil || intervals != nil {
+		t.Fatalf("expected no intervals without blackdetect output, got %v (%v)", intervals, err)
+	}
+}
+
+func TestDetectBlack(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("[blackdetect @ 0x1] black_start:1 black_end:3.5 black_duration:2.5\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	intervals, err := d.DetectBlack(context.Background(), "https://example.com/video.mp4", BlackDetectionOptions{
+		MinDuration:     0.5,
+		PictureRatio:    0.9,
+		StartOffset:     10,
+		AnalyzeDuration: 30,
+		InputHeaders:    http.Header{"Authorization": {"Bearer token"}},
+	})
+	if err != nil {
+		t.Fatalf("DetectBlack returned error: %v", err)
+	}
+
+	want := []string{
+		"-hide_banner", "-nostdin", "-nostats",
+		"-headers", "Authorization: Bearer token\r\n",
+		"-ss", "10", "-i", "https://example.com/video.mp4", "-t", "30",
+		"-an", "-sn", "-dn", "-vf", "blackdetect=d=0.5:pic_th=0.9", "-f", "null", "-",
+	}
+	if !slices.Equal(gotArgs, want) {
+		t.Fatalf("unexpected args\n got %q\nwant %q", gotArgs, want)
+	}
+	assertIntervals(t, intervals, []Interval{{Start: 11, End: 13.5, Duration: 2.5}})
+}
+
+func TestDetectBlackDefaults(t *testing.T) {
+	var filter string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		filter = args[len(args)-4]
+		return nil, nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	intervals, err := d.DetectBlack(context.Background(), "video.mp4", BlackDetectionOptions{})
+	if err != nil {
+		t.Fatalf("DetectBlack returned error: %v", err)
+	}
+	if filter != "blackdetect" {
+		t.Fatalf("expected ffmpeg's defaults, got filter %q", filter)
+	}
+	if len(intervals) != 0 {
+		t.Fatalf("expected no intervals, got %v", intervals)
+	}
+}
+
+func TestDetectBlackNoVideoStream(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Output file #0 does not contain any stream"), &exec.ExitError{}
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	_, err := d.DetectBlack(context.Background(), "audio.wav", BlackDetectionOptions{})
+	if !errors.Is(err, ErrNoVideoStream) {
+		t.Fatalf("expected ErrNoVideoStream, got %v", err)
+	}
+	if errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("a missing video stream should not be reported as missing audio: %v", err)
+	}
+}
+
+func TestDetectBlackValidation(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg should not run")
+		return nil, nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	tests := []struct {
+		name    string
+		input   string
+		options BlackDetectionOptions
+	}{
+		{name: "empty path"},
+		{name: "stdin", input: "-"},
+		{name: "negative duration", input: "video.mp4", options: BlackDetectionOptions{MinDuration: -1}},
+		{name: "picture ratio", input: "video.mp4", options: BlackDetectionOptions{PictureRatio: 1.5}},
+		{name: "pixel threshold", input: "video.mp4", options: BlackDetectionOptions{PixelThreshold: -0.1}},
+		{name: "negative offset", input: "video.mp4", options: BlackDetectionOptions{StartOffset: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.DetectBlack(context.Background(), tt.input, tt.options); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDeadAir(t *testing.T) {
+	silence := []Interval{
+		{Start: 20, End: 30, Duration: 10},
+		{Start: 0, End: 5, Duration: 5},
+		{Start: 8, End: 9, Duration: 1},
+	}
+	black := []Interval{
+		{Start: 3, End: 12, Duration: 9},
+		{Start: 25, End: 26, Duration: 1},
+		{Start: 28, End: 40, Duration: 12},
+		{Start: 5, End: 6, Duration: 1},
+	}
+
+	assertIntervals(t, DeadAir(silence, black), []Interval{
+		{Start: 3, End: 5, Duration: 2},
+		{Start: 8, End: 9, Duration: 1},
+		{Start: 25, End: 26, Duration: 1},
+		{Start: 28, End: 30, Duration: 2},
+	})
+
+	if dead := DeadAir(silence, nil); dead != nil {
+		t.Fatalf("expected no dead air without black intervals, got %v", dead)
+	}
+	if dead := DeadAir([]Interval{{Start: 0, End: 2, Duration: 2}}, []Interval{{Start: 2, End: 4, Duration: 2}}); dead != nil {
+		t.Fatalf("expected touching intervals not to overlap, got %v", dead)
+	}
+}
@@ -0,0 +1,91 @@
+package detector
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Chapter is a section of the input delimited by long silences.
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+// Chapters splits the analysed span into chapters at the midpoint of every silence longer than minSilence seconds,
+// for example at the pauses between the segments of a podcast. The first chapter always starts at WindowStart,
+// which is 0 unless the analysis started later, and a silence mark closer than minSpacing seconds to the previous
+// chapter start is skipped. Silences touching the start or the end of the span do not start a chapter. Overlapping
+// intervals count as one silence. Chapters are titled "Chapter N", numbered from 1.
+//
+// Each chapter ends where the next one starts and the last one at the end of the span. When InputDuration is
+// unknown, the last chapter ends at the end of the last silence instead, or where it starts if there is none after
+// it.
+func (r DetectionResult) Chapters(minSilence, minSpacing float64) []Chapter {
+	end := math.Inf(1)
+	if r.InputDuration > 0 {
+		end = r.WindowStart + r.InputDuration
+	}
+
+	starts := []float64{r.WindowStart}
+	for _, interval := range unionIntervals(r.Intervals) {
+		if interval.Duration <= minSilence || interval.Start-r.WindowStart <= fullSilenceTolerance || end-interval.End <= fullSilenceTolerance {
+			continue
+		}
+		mark := (interval.Start + interval.End) / 2
+		if mark-starts[len(starts)-1] < minSpacing {
+			continue
+		}
+		starts = append(starts, mark)
+	}
+	if math.IsInf(end, 1) {
+		end = starts[len(starts)-1]
+		for _, interval := range r.Intervals {
+			end = math.Max(end, interval.End)
+		}
+	}
+
+	chapters := make([]Chapter, len(starts))
+	for i, start := range starts {
+		chapters[i] = Chapter{Start: start, End: end, Title: fmt.Sprintf("Chapter %d", i+1)}
+		if i > 0 {
+			chapters[i-1].End = start
+		}
+	}
+	return chapters
+}
+
+// WriteYouTubeChapters writes chapters in the format of YouTube video descriptions, one "MM:SS Title" line per
+// chapter, or "H:MM:SS Title" from the first hour. Start times are truncated to the second.
+func WriteYouTubeChapters(w io.Writer, chapters []Chapter) error {
+	var b strings.Builder
+	for _, chapter := range chapters {
+		seconds := int64(math.Max(chapter.Start, 0))
+		if seconds >= 3600 {
+			fmt.Fprintf(&b, "%d:%02d:%02d %s\n", seconds/3600, seconds/60%60, seconds%60, chapter.Title)
+		} else {
+			fmt.Fprintf(&b, "%02d:%02d %s\n", seconds/60, seconds%60, chapter.Title)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFFMetadataChapters writes chapters as an FFMETADATA1 file with millisecond timestamps, which ffmpeg muxes
+// into an output with, for example, ffmpeg -i in.mp3 -i chapters.txt -map_metadata 1 -codec copy out.mp3.
+func WriteFFMetadataChapters(w io.Writer, chapters []Chapter) error {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, chapter := range chapters {
+		start := int64(math.Round(math.Max(chapter.Start, 0) * 1000))
+		end := max(int64(math.Round(chapter.End*1000)), start)
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n", start, end, ffmetadataEscaper.Replace(chapter.Title))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ffmetadataEscaper escapes the characters that are special in FFMETADATA1 values.
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
@@ -0,0 +1,76 @@
+package detector
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestChapters(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 4000,
+		Intervals: []SilenceInterval{
+			// Leading silence, which the first chapter already covers.
+			{Start: 0, End: 4, Duration: 4},
+			{Start: 119, End: 121, Duration: 2},
+			// Too short to mark a chapter.
+			{Start: 300, End: 300.5, Duration: 0.5},
+			// Overlapping intervals count as one silence, from 598 to 602.
+			{Start: 598, End: 601, Duration: 3},
+			{Start: 600, End: 602, Duration: 2},
+			// Too close to the previous chapter.
+			{Start: 605, End: 608, Duration: 3},
+			{Start: 3700, End: 3702, Duration: 2},
+			// Trailing silence, which nothing follows.
+			{Start: 3990, End: 4000, Duration: 10},
+		},
+	}
+
+	got := result.Chapters(1, 30)
+	want := []Chapter{
+		{Start: 0, End: 120, Title: "Chapter 1"},
+		{Start: 120, End: 600, Title: "Chapter 2"},
+		{Start: 600, End: 3701, Title: "Chapter 3"},
+		{Start: 3701, End: 4000, Title: "Chapter 4"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected chapters:\n got %+v\nwant %+v", got, want)
+	}
+
+	var youtube bytes.Buffer
+	if err := WriteYouTubeChapters(&youtube, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := "00:00 Chapter 1\n02:00 Chapter 2\n10:00 Chapter 3\n1:01:41 Chapter 4\n"; youtube.String() != want {
+		t.Errorf("unexpected YouTube chapters %q, want %q", youtube.String(), want)
+	}
+}
+
+func TestChaptersWithoutQualifyingSilence(t *testing.T) {
+	result := DetectionResult{InputDuration: 60, Intervals: []SilenceInterval{{Start: 10, End: 10.5, Duration: 0.5}}}
+	if got, want := result.Chapters(1, 10), []Chapter{{Start: 0, End: 60, Title: "Chapter 1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a single chapter, got %+v", got)
+	}
+
+	// A later window without a known duration ends at the last silence.
+	unknown := DetectionResult{WindowStart: 30, Intervals: []SilenceInterval{{Start: 50, End: 55, Duration: 5}}}
+	want := []Chapter{{Start: 30, End: 52.5, Title: "Chapter 1"}, {Start: 52.5, End: 55, Title: "Chapter 2"}}
+	if got := unknown.Chapters(1, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected chapters %+v, want %+v", got, want)
+	}
+	if got := (DetectionResult{}).Chapters(1, 10); len(got) != 1 || got[0] != (Chapter{Title: "Chapter 1"}) {
+		t.Errorf("expected a single empty chapter, got %+v", got)
+	}
+}
+
+func TestWriteFFMetadataChapters(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteFFMetadataChapters(&buf, []Chapter{
+		{Start: 0, End: 120.0004, Title: "Chapter 1"},
+		{Start: 120.0004, End: 300, Title: "Q&A; part=2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "chapters.ffmetadata", buf.Bytes())
+}
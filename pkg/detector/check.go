@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FFmpegInfo describes the ffmpeg binary used by a Detector.
+type FFmpegInfo struct {
+	// Path is the resolved location of the ffmpeg binary.
+	Path string
+	// Version is the version string reported by "ffmpeg -version".
+	Version string
+}
+
+var ffmpegVersionPattern = regexp.MustCompile(`(?m)^ffmpeg version (\S+)`)
+
+// Check verifies that ffmpeg is installed and supports the silencedetect filter.
+//
+// It resolves the ffmpeg path, runs "ffmpeg -version" and "ffmpeg -filters" through the detector's CommandRunner,
+// and caches the result on the Detector so subsequent calls are free. A missing binary is reported as
+// ErrFFmpegNotFound and a build without silencedetect as ErrFilterUnavailable.
+func (d *Detector) Check(ctx context.Context) (FFmpegInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.info != nil {
+		return *d.info, nil
+	}
+
+	path, err := d.lookPath(d.ffmpegPath)
+	if err != nil {
+		return FFmpegInfo{}, fmt.Errorf("%w: %q: %v", ErrFFmpegNotFound, d.ffmpegPath, err)
+	}
+
+	output, err := d.run(ctx, d.ffmpegPath, "-version")
+	if err != nil {
+		return FFmpegInfo{}, newFFmpegError(ctx, d.ffmpegPath, []string{"-version"}, err, string(output))
+	}
+
+	matches := ffmpegVersionPattern.FindSubmatch(output)
+	if len(matches) != 2 {
+		return FFmpegInfo{}, fmt.Errorf("unrecognised ffmpeg version output: %s", truncateOutput(strings.TrimSpace(string(output)), 256))
+	}
+
+	output, err = d.run(ctx, d.ffmpegPath, "-hide_banner", "-filters")
+	if err != nil {
+		return FFmpegInfo{}, newFFmpegError(ctx, d.ffmpegPath, []string{"-hide_banner", "-filters"}, err, string(output))
+	}
+
+	if !hasFilter(string(output), "silencedetect") {
+		return FFmpegInfo{}, fmt.Errorf("%w: %q", ErrFilterUnavailable, d.ffmpegPath)
+	}
+
+	d.info = &FFmpegInfo{Path: path, Version: string(matches[1])}
+	return *d.info, nil
+}
+
+// hasFilter reports whether the output of "ffmpeg -filters" lists the named filter.
+func hasFilter(output, name string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == name {
+			return true
+		}
+	}
+	return false
+}
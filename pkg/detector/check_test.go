@@ -0,0 +1,93 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const fakeFiltersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+ ... silencedetect     A->A       Detect silence.
+ ... silenceremove     A->A       Remove silence.
+`
+
+func TestCheckReportsVersionAndCachesResult(t *testing.T) {
+	var calls int
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls++
+		switch strings.Join(args, " ") {
+		case "-version":
+			return []byte("ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13\n"), nil
+		case "-hide_banner -filters":
+			return []byte(fakeFiltersOutput), nil
+		}
+		t.Fatalf("unexpected args %v", args)
+		return nil, nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFmpegPath("ffmpeg-custom"))
+	d.lookPath = func(file string) (string, error) { return "/opt/bin/" + file, nil }
+
+	info, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if info.Path != "/opt/bin/ffmpeg-custom" || info.Version != "6.1.1-3ubuntu5" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+
+	if _, err := d.Check(context.Background()); err != nil {
+		t.Fatalf("second Check returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected cached result to avoid re-running ffmpeg, got %d calls", calls)
+	}
+}
+
+func TestCheckReportsMissingBinary(t *testing.T) {
+	d := NewDetector(WithFFmpegPath("/nonexistent/ffmpeg"))
+	d.lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+
+	_, err := d.Check(context.Background())
+	if !errors.Is(err, ErrFFmpegNotFound) {
+		t.Fatalf("expected ErrFFmpegNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "/nonexistent/ffmpeg") {
+		t.Fatalf("expected error to name the attempted path, got %v", err)
+	}
+}
+
+func TestCheckReportsMissingFilter(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if args[0] == "-version" {
+			return []byte("ffmpeg version n5.1 Copyright (c) 2000-2022 the FFmpeg developers\n"), nil
+		}
+		return []byte(" ... volume            A->A       Change input volume.\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	d.lookPath = func(file string) (string, error) { return file, nil }
+
+	if _, err := d.Check(context.Background()); !errors.Is(err, ErrFilterUnavailable) {
+		t.Fatalf("expected ErrFilterUnavailable, got %v", err)
+	}
+}
+
+func TestDetectSilenceClassifiesMissingBinary(t *testing.T) {
+	d := NewDetector(WithFFmpegPath("silence-detector-missing-ffmpeg"))
+
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if !errors.Is(err, ErrFFmpegNotFound) {
+		t.Fatalf("expected ErrFFmpegNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "silence-detector-missing-ffmpeg") {
+		t.Fatalf("expected error to name the attempted path, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package detector
+
+import "math"
+
+// CompareOptions sets what CompareResults tolerates before two results count as different.
+type CompareOptions struct {
+	// Tolerance is how many seconds the start or end of a matching interval may move before it counts as a drift.
+	Tolerance float64
+	// MaxCountMismatch is how many intervals, missing and extra together, are tolerated, such as a short silence that
+	// an encoder's priming delay adds at the start.
+	MaxCountMismatch int
+}
+
+// Diff describes how the silence of one result, b, differs from that of a reference, a.
+type Diff struct {
+	// Match reports that the results agree within the CompareOptions: no interval drifted and at most
+	// MaxCountMismatch are missing or extra.
+	Match bool `json:"match"`
+	// Missing lists the intervals of a that match none of b, and Extra those of b that match none of a.
+	Missing []SilenceInterval `json:"missing"`
+	Extra   []SilenceInterval `json:"extra"`
+	// Drifts lists the matching intervals whose start or end moved by more than the tolerance.
+	Drifts []IntervalDrift `json:"drifts"`
+}
+
+// IntervalDrift is a pair of matching intervals whose boundaries moved.
+type IntervalDrift struct {
+	A SilenceInterval `json:"a"`
+	B SilenceInterval `json:"b"`
+	// StartDrift and EndDrift are how many seconds later the boundaries of B are than those of A; negative values
+	// are earlier.
+	StartDrift float64 `json:"start_drift"`
+	EndDrift   float64 `json:"end_drift"`
+}
+
+// CompareResults compares the silence intervals of b with those of the reference a, such as the output of a
+// transcode with its source, to check that the silence profile survived.
+//
+// An interval of a matches the interval of b that overlaps it, or whose boundaries lie within opts.Tolerance of its
+// own, with the smallest total boundary movement; each interval matches at most once. Only Intervals are compared,
+// not those of channels or streams. Negative options count as zero.
+func CompareResults(a, b DetectionResult, opts CompareOptions) Diff {
+	tolerance := max(opts.Tolerance, 0)
+	left, right := sortedIntervals(a.Intervals), sortedIntervals(b.Intervals)
+	matched := make([]bool, len(right))
+
+	diff := Diff{Missing: []SilenceInterval{}, Extra: []SilenceInterval{}, Drifts: []IntervalDrift{}}
+	for _, interval := range left {
+		best, bestMovement := -1, math.Inf(1)
+		for j, candidate := range right {
+			if matched[j] || !intervalsCorrespond(interval, candidate, tolerance) {
+				continue
+			}
+			movement := math.Abs(candidate.Start-interval.Start) + math.Abs(candidate.End-interval.End)
+			if movement < bestMovement {
+				best, bestMovement = j, movement
+			}
+		}
+		if best < 0 {
+			diff.Missing = append(diff.Missing, interval)
+			continue
+		}
+
+		matched[best] = true
+		candidate := right[best]
+		drift := IntervalDrift{A: interval, B: candidate, StartDrift: candidate.Start - interval.Start, EndDrift: candidate.End - interval.End}
+		if math.Abs(drift.StartDrift) > tolerance || math.Abs(drift.EndDrift) > tolerance {
+			diff.Drifts = append(diff.Drifts, drift)
+		}
+	}
+	for j, interval := range right {
+		if !matched[j] {
+			diff.Extra = append(diff.Extra, interval)
+		}
+	}
+
+	diff.Match = len(diff.Drifts) == 0 && len(diff.Missing)+len(diff.Extra) <= max(opts.MaxCountMismatch, 0)
+	return diff
+}
+
+// intervalsCorrespond reports whether a and b can be the same silence: they overlap, or both of their boundaries lie
+// within tolerance of each other.
+func intervalsCorrespond(a, b SilenceInterval, tolerance float64) bool {
+	if a.Start < b.End && b.Start < a.End {
+		return true
+	}
+	return math.Abs(a.Start-b.Start) <= tolerance && math.Abs(a.End-b.End) <= tolerance
+}
@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCompareResults(t *testing.T) {
+	source := DetectionResult{Intervals: []SilenceInterval{
+		{Start: 0, End: 2, Duration: 2},
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 20, End: 21, Duration: 1},
+		{Start: 30, End: 30.03, Duration: 0.03},
+	}}
+	output := DetectionResult{Intervals: []SilenceInterval{
+		{Start: 0.02, End: 2.01, Duration: 1.99},
+		{Start: 10.5, End: 12, Duration: 1.5},
+		{Start: 40, End: 41, Duration: 1},
+		// A short silence that moved past its own length still matches, within the tolerance.
+		{Start: 30.04, End: 30.07, Duration: 0.03},
+	}}
+
+	diff := CompareResults(source, output, CompareOptions{Tolerance: 0.05})
+	if diff.Match {
+		t.Fatal("expected the results to differ")
+	}
+	assertIntervals(t, diff.Missing, []SilenceInterval{{Start: 20, End: 21, Duration: 1}})
+	assertIntervals(t, diff.Extra, []SilenceInterval{{Start: 40, End: 41, Duration: 1}})
+	if len(diff.Drifts) != 1 {
+		t.Fatalf("expected one drift, got %+v", diff.Drifts)
+	}
+	drift := diff.Drifts[0]
+	assertFloatEqual(t, drift.A.Start, 10)
+	assertFloatEqual(t, drift.StartDrift, 0.5)
+	assertFloatEqual(t, drift.EndDrift, 0)
+}
+
+func TestCompareResultsToleratesCountMismatch(t *testing.T) {
+	source := DetectionResult{Intervals: []SilenceInterval{{Start: 5, End: 6, Duration: 1}}}
+	output := DetectionResult{Intervals: []SilenceInterval{{Start: 0, End: 0.05, Duration: 0.05}, {Start: 5, End: 6, Duration: 1}}}
+
+	if diff := CompareResults(source, output, CompareOptions{Tolerance: 0.01}); diff.Match {
+		t.Fatalf("expected the extra interval to count as a difference, got %+v", diff)
+	}
+	diff := CompareResults(source, output, CompareOptions{Tolerance: 0.01, MaxCountMismatch: 1})
+	if !diff.Match || len(diff.Extra) != 1 {
+		t.Fatalf("expected a match that still lists the extra interval, got %+v", diff)
+	}
+
+	if diff := CompareResults(DetectionResult{}, DetectionResult{}, CompareOptions{}); !diff.Match {
+		t.Fatalf("expected two results without silence to match, got %+v", diff)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	data, err := json.Marshal(CompareResults(DetectionResult{}, DetectionResult{}, CompareOptions{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `{"match":true,"missing":[],"extra":[],"drifts":[]}`; string(data) != want {
+		t.Fatalf("unexpected JSON: got %s want %s", data, want)
+	}
+}
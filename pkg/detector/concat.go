@@ -0,0 +1,103 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConcatResults stitches the results of consecutive parts of one recording, such as hour-long chunks analysed
+// separately, into a single timeline. Each part's analysed span, from WindowStart for InputDuration seconds, is moved
+// to begin where the previous part's span ended; the combined span begins at the WindowStart of the first part and
+// lasts the sum of the durations. A silence that lasts to the end of one part and one that starts the next are merged
+// into a single interval, and so are those of each channel.
+//
+// Every part needs a known, positive duration that its intervals lie within, since the parts could not be placed
+// otherwise. A part whose duration is unknown or does not match its intervals, or a partial result, fails
+// concatenation with an error naming its index.
+//
+// The combined result is Truncated when any part is, and Normalized when all of them are. Warnings, OutputLines and
+// ParsedLines are collected from all parts, while ThresholdDB and AudioInfo are those of the first part. Measurements
+// that cannot be combined, such as volume, loudness and RawOutput, are left out, and so are StreamIntervals.
+func ConcatResults(results ...DetectionResult) (DetectionResult, error) {
+	if len(results) == 0 {
+		return DetectionResult{}, errors.New("concatenation requires at least one result")
+	}
+	for i, part := range results {
+		if err := checkConcatPart(part); err != nil {
+			return DetectionResult{}, fmt.Errorf("part %d: %w", i, err)
+		}
+	}
+
+	combined := DetectionResult{
+		WindowStart:   results[0].WindowStart,
+		ThresholdDB:   results[0].ThresholdDB,
+		AudioInfo:     results[0].AudioInfo,
+		Normalized:    true,
+		DurationKnown: true,
+	}
+	for _, part := range results {
+		shifted := part.Shift(combined.WindowStart + combined.InputDuration - part.WindowStart)
+		boundary := combined.WindowStart + combined.InputDuration
+
+		combined.Intervals = joinIntervals(combined.Intervals, shifted.Intervals, boundary)
+		for channel, intervals := range shifted.ChannelIntervals {
+			if combined.ChannelIntervals == nil {
+				combined.ChannelIntervals = make(map[int][]SilenceInterval)
+			}
+			combined.ChannelIntervals[channel] = joinIntervals(combined.ChannelIntervals[channel], intervals, boundary)
+		}
+
+		combined.InputDuration += part.InputDuration
+		combined.Truncated = combined.Truncated || part.Truncated
+		combined.Normalized = combined.Normalized && part.Normalized
+		combined.Warnings = append(combined.Warnings, part.Warnings...)
+		combined.OutputLines += part.OutputLines
+		combined.ParsedLines += part.ParsedLines
+	}
+	return combined, nil
+}
+
+// checkConcatPart reports why part cannot be placed on a combined timeline, if it cannot.
+func checkConcatPart(part DetectionResult) error {
+	switch {
+	case part.Partial:
+		return errors.New("a partial result covers only part of its input")
+	case !part.DurationKnown || part.InputDuration <= 0:
+		return errors.New("the duration is unknown")
+	}
+
+	start, end := part.WindowStart, part.WindowStart+part.InputDuration
+	check := func(intervals []SilenceInterval) error {
+		for _, interval := range intervals {
+			if interval.Start < start-fullSilenceTolerance || interval.End > end+fullSilenceTolerance {
+				return fmt.Errorf("silence from %.3fs to %.3fs lies outside the analysed span from %.3fs to %.3fs; the duration does not match", interval.Start, interval.End, start, end)
+			}
+		}
+		return nil
+	}
+	if err := check(part.Intervals); err != nil {
+		return err
+	}
+	for _, channel := range part.Channels() {
+		if err := check(part.ChannelIntervals[channel]); err != nil {
+			return fmt.Errorf("channel %d: %w", channel, err)
+		}
+	}
+	return nil
+}
+
+// joinIntervals appends the intervals of the next part to those of the previous parts, which end at boundary. A
+// silence reaching boundary from both sides becomes a single interval.
+func joinIntervals(previous, next []SilenceInterval, boundary float64) []SilenceInterval {
+	joined := append([]SilenceInterval(nil), previous...)
+	next = sortedIntervals(next)
+
+	if n := len(joined); n > 0 && len(next) > 0 &&
+		boundary-joined[n-1].End <= fullSilenceTolerance && next[0].Start-boundary <= fullSilenceTolerance {
+		last := &joined[n-1]
+		last.End = max(last.End, next[0].End)
+		last.Duration = last.End - last.Start
+		next = next[1:]
+	}
+	return append(joined, next...)
+}
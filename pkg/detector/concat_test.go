@@ -0,0 +1,113 @@
+package detector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShift(t *testing.T) {
+	result := DetectionResult{
+		InputDuration:    10,
+		WindowStart:      2,
+		Intervals:        []SilenceInterval{{Start: 3, End: 5, Duration: 2}},
+		ChannelIntervals: map[int][]SilenceInterval{1: {{Start: 4, End: 6, Duration: 2}}},
+	}
+
+	shifted := result.Shift(3600)
+	assertFloatEqual(t, shifted.WindowStart, 3602)
+	assertFloatEqual(t, shifted.InputDuration, 10)
+	assertIntervals(t, shifted.Intervals, []SilenceInterval{{Start: 3603, End: 3605, Duration: 2}})
+	assertIntervals(t, shifted.ChannelIntervals[1], []SilenceInterval{{Start: 3604, End: 3606, Duration: 2}})
+	// The original is not modified.
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 3, End: 5, Duration: 2}})
+}
+
+func TestConcatResults(t *testing.T) {
+	parts := []DetectionResult{
+		{
+			InputDuration: 60,
+			DurationKnown: true,
+			Normalized:    true,
+			ThresholdDB:   -30,
+			Intervals:     []SilenceInterval{{Start: 10, End: 12, Duration: 2}, {Start: 55, End: 60, Duration: 5}},
+		},
+		{
+			InputDuration: 60,
+			DurationKnown: true,
+			Normalized:    true,
+			ThresholdDB:   -30,
+			Intervals:     []SilenceInterval{{Start: 0, End: 3, Duration: 3}, {Start: 30, End: 31, Duration: 1}},
+		},
+		{
+			// A window of the third part, starting 5 seconds into it.
+			InputDuration: 20,
+			WindowStart:   5,
+			DurationKnown: true,
+			Truncated:     true,
+			Intervals:     []SilenceInterval{{Start: 6, End: 7, Duration: 1}},
+		},
+	}
+
+	combined, err := ConcatResults(parts...)
+	if err != nil {
+		t.Fatalf("ConcatResults returned error: %v", err)
+	}
+	assertFloatEqual(t, combined.InputDuration, 140)
+	assertFloatEqual(t, combined.WindowStart, 0)
+	assertIntervals(t, combined.Intervals, []SilenceInterval{
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 55, End: 63, Duration: 8},
+		{Start: 90, End: 91, Duration: 1},
+		{Start: 121, End: 122, Duration: 1},
+	})
+	if !combined.DurationKnown || !combined.Truncated || combined.Normalized {
+		t.Fatalf("unexpected flags: known %t, truncated %t, normalized %t", combined.DurationKnown, combined.Truncated, combined.Normalized)
+	}
+	if combined.ThresholdDB != -30 {
+		t.Fatalf("expected the threshold of the first part, got %g", combined.ThresholdDB)
+	}
+}
+
+func TestConcatResultsMergesChannels(t *testing.T) {
+	parts := []DetectionResult{
+		{InputDuration: 10, DurationKnown: true, ChannelIntervals: map[int][]SilenceInterval{0: {{Start: 8, End: 10, Duration: 2}}}},
+		{InputDuration: 10, DurationKnown: true, ChannelIntervals: map[int][]SilenceInterval{
+			0: {{Start: 0, End: 1, Duration: 1}},
+			1: {{Start: 0, End: 4, Duration: 4}},
+		}},
+	}
+
+	combined, err := ConcatResults(parts...)
+	if err != nil {
+		t.Fatalf("ConcatResults returned error: %v", err)
+	}
+	assertIntervals(t, combined.ChannelIntervals[0], []SilenceInterval{{Start: 8, End: 11, Duration: 3}})
+	assertIntervals(t, combined.ChannelIntervals[1], []SilenceInterval{{Start: 10, End: 14, Duration: 4}})
+}
+
+func TestConcatResultsRejectsUnplaceableParts(t *testing.T) {
+	valid := DetectionResult{InputDuration: 10, DurationKnown: true}
+	tests := []struct {
+		name string
+		part DetectionResult
+		want string
+	}{
+		{name: "unknown duration", part: DetectionResult{Intervals: []SilenceInterval{{Start: 0, End: 1, Duration: 1}}}, want: "part 1: the duration is unknown"},
+		{name: "zero duration", part: DetectionResult{DurationKnown: true}, want: "part 1: the duration is unknown"},
+		{name: "partial", part: DetectionResult{InputDuration: 5, DurationKnown: true, Partial: true}, want: "part 1: a partial result"},
+		{name: "interval past the end", part: DetectionResult{InputDuration: 5, DurationKnown: true, Intervals: []SilenceInterval{{Start: 4, End: 8, Duration: 4}}}, want: "part 1: silence from 4.000s to 8.000s lies outside"},
+		{name: "channel before the window", part: DetectionResult{InputDuration: 5, WindowStart: 2, DurationKnown: true, ChannelIntervals: map[int][]SilenceInterval{1: {{Start: 0, End: 1, Duration: 1}}}}, want: "part 1: channel 1: silence from"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ConcatResults(valid, tt.part, valid)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ConcatResults(); err == nil {
+		t.Fatal("expected an error without results")
+	}
+}
@@ -1,71 +1,366 @@
 package detector
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math"
+	"net/http"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // CommandRunner defines a function capable of executing an external command and returning its combined output.
 type CommandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
 
-// SilenceInterval captures the start, end, and duration of a detected silent period.
-type SilenceInterval struct {
-	Start    float64
-	End      float64
-	Duration float64
+// Interval captures the start, end, and duration, in seconds, of a detected period such as silence or black
+// picture.
+type Interval struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+	// MeanVolumeDB and MaxVolumeDB are the volume of a silence interval in dBFS, when
+	// DetectionOptions.MeasureIntervalLevels measured it.
+	MeanVolumeDB *float64 `json:"mean_volume_db,omitempty"`
+	MaxVolumeDB  *float64 `json:"max_volume_db,omitempty"`
 }
 
+// SilenceInterval captures the start, end, and duration of a detected silent period.
+type SilenceInterval = Interval
+
 // DetectionOptions configures how ffmpeg performs silence detection.
 type DetectionOptions struct {
-	NoiseLevel         float64
+	// NoiseLevel is the silencedetect noise threshold in dB.
+	NoiseLevel float64
+	// NoiseRatio, when set, expresses the noise threshold as an amplitude ratio in (0, 1] instead, such as 0.001
+	// for -60dB. NoiseLevel must then be left at zero.
+	NoiseRatio         *float64
 	MinSilenceDuration float64
+	// MergeGap, when positive, coalesces detected intervals separated by at most this many seconds.
+	MergeGap float64
+	// PerChannel runs silencedetect in mono mode so that every audio channel is analysed independently.
+	PerChannel bool
+	// StartOffset skips this many seconds of the input before analysis begins.
+	StartOffset float64
+	// AnalyzeDuration limits analysis to this many seconds of input. Zero analyses until the end of the input.
+	AnalyzeDuration float64
+	// InputHeaders are sent as HTTP request headers (via ffmpeg's -headers option) when inputPath is an HTTP(S)
+	// URL that ffmpeg reads directly. They are ignored for local inputs.
+	InputHeaders http.Header
+	// AudioStreamIndex selects the audio stream to analyse (the N in "-map 0:a:N"). When nil, ffmpeg picks its
+	// default audio stream. For HLS playlists, ffmpeg numbers the audio streams of all renditions in playlist order,
+	// so this also selects the audio rendition.
+	AudioStreamIndex *int
+	// AudioLanguage selects the audio stream tagged with this language, such as "eng", instead of AudioStreamIndex.
+	// When ffprobe is enabled and the input can be probed, the tag is resolved to a stream index first, and a
+	// missing language fails with ErrLanguageNotFound. Otherwise ffmpeg selects the stream with
+	// "-map 0:a:m:language:eng".
+	AudioLanguage string
+	// Stdin is connected to ffmpeg's standard input. It is required when inputPath is "-" or "pipe:0", which
+	// analyses media streamed from another process. Custom runners obtain it with CommandStdin.
+	Stdin io.Reader
+	// InputFormat forces the input demuxer (ffmpeg's -f option), which is needed for raw or otherwise ambiguous
+	// streams that cannot be probed, such as those read from stdin.
+	InputFormat string
+	// KnownDuration, when positive, is the duration of the input in seconds as the caller already knows it, such as
+	// from a media catalog. It replaces ffprobe, which is then not run at all, and ffmpeg's progress as the duration
+	// of the result and the end of a trailing silence. ffmpeg's progress is still read to check it: a run that
+	// progresses more than KnownDurationTolerance past it adds a WarningDurationMismatch to the result. The native
+	// engine reads the exact duration from the samples and only checks it against KnownDuration the same way.
+	KnownDuration float64
+	// RawPCM describes headerless PCM input, which ffmpeg cannot probe. It selects the demuxer and passes the sample
+	// rate and channel count (as in -f s16le -ar 48000 -ac 2), so InputFormat must be empty. ffprobe is skipped for
+	// such input, since it could not read it either.
+	RawPCM *RawPCMFormat
+	// ExtraInputArgs are passed to ffmpeg immediately before "-i", for input options such as -analyzeduration or
+	// -probesize. Each element is a single argument; nothing is split on whitespace.
+	ExtraInputArgs []string
+	// ExtraOutputArgs are passed to ffmpeg immediately before the null output ("-f null -").
+	ExtraOutputArgs []string
+	// DecodeAllStreams keeps video, subtitle and data streams in the ffmpeg run. By default they are disabled with
+	// -vn -sn -dn so that only audio is decoded, which makes detection on video files considerably faster.
+	DecodeAllStreams bool
+	// DownmixMono mixes all channels down to one before detection, reducing the work silencedetect does on
+	// multichannel audio. It cannot be combined with PerChannel.
+	DownmixMono bool
+	// SampleRate, when positive, resamples the audio to this many Hz before detection. Low rates such as 16000
+	// are considerably cheaper to analyse and rarely change which periods are silent.
+	SampleRate int
+	// DisableProbeRetry stops DetectSilence from analysing an input a second time with raised -analyzeduration
+	// and -probesize limits when ffmpeg cannot find its codec parameters (ErrCodecParameters).
+	DisableProbeRetry bool
+	// DisableNormalize returns the intervals as ffmpeg reported them instead of cleaning them up with Normalize.
+	DisableNormalize bool
+	// Stats controls ffmpeg's periodic progress line. It has no effect on detectors created with WithRawFFmpegOutput.
+	Stats StatsMode
+	// MeasureVolume chains ffmpeg's volumedetect filter after silencedetect, so that the same pass reports the mean
+	// and peak volume in DetectionResult.MeanVolumeDB and MaxVolumeDB. The volume is measured after any DownmixMono
+	// or SampleRate conversion.
+	MeasureVolume bool
+	// MeasureLoudness chains ffmpeg's ebur128 filter into the same pass and reports the EBU R128 summary in
+	// DetectionResult.Loudness. If the ffmpeg build lacks the filter, the run is repeated without it and Loudness
+	// stays nil. Like the volume, loudness is measured after any DownmixMono or SampleRate conversion, which skews
+	// it; leave both unset for broadcast QC.
+	MeasureLoudness bool
+	// AdaptiveThreshold runs a first ffmpeg pass with the astats filter to measure the noise floor of the selected
+	// audio, and then detects silence below the floor plus AdaptiveOffsetDB instead of below NoiseLevel. NoiseLevel
+	// remains the threshold when the floor is not finite, as for inputs containing digital silence. The input must
+	// be seekable, so stdin cannot be analysed adaptively.
+	AdaptiveThreshold bool
+	// AdaptiveOffsetDB is added to the measured noise floor in adaptive mode. Zero places the threshold at the floor.
+	AdaptiveOffsetDB float64
+	// MeasureIntervalLevels measures the mean and peak volume of each silence interval once detection has finished,
+	// telling room tone far below the threshold apart from hum just under it. Each interval is read again in an
+	// ffmpeg run of its own that seeks to it, with up to MaxMeasuredIntervals intervals measured, in start order, by
+	// a few concurrent runs. The input must therefore be seekable and cannot be live. Partial results are not
+	// measured.
+	MeasureIntervalLevels bool
+	// HysteresisDB, when positive, detects silence with hysteresis to stop audio hovering around the threshold from
+	// splitting a silence into many short intervals. A silence then opens when the audio falls below the noise
+	// threshold and closes only when it rises above the threshold plus HysteresisDB. It costs a second ffmpeg run
+	// at the higher threshold, whose intervals HysteresisIntervals combines with the first, so the input must be
+	// seekable and cannot be live.
+	HysteresisDB float64
+	// CaptureRawOutput keeps the last MaxRawOutputBytes of ffmpeg's diagnostic output in DetectionResult.RawOutput.
+	CaptureRawOutput bool
+	// MaxIntervals, when positive, stops ffmpeg once this many silence intervals have been reported and returns them
+	// with DetectionResult.Truncated set. It bounds the work spent on damaged inputs that produce thousands of tiny
+	// silences. Detectors created with WithCommandRunner still run ffmpeg to completion and drop the excess.
+	MaxIntervals int
+	// Live marks the input as a live stream, which has no total duration. ffprobe is not run, and the result covers
+	// what ffmpeg read, so AnalyzeDuration should bound the analysis. A live stream cannot be sought or read twice,
+	// so StartOffset and AdaptiveThreshold are rejected.
+	Live bool
+	// Engine selects what analyses the audio. The default, EngineAuto, reads local PCM and float WAV files in Go
+	// instead of running ffmpeg, as long as no option needs ffmpeg; the intervals match silencedetect's.
+	Engine Engine
+	// AllowPartial keeps what ffmpeg reported before it failed partway, for instance on a corrupt tail or when it was
+	// killed. DetectSilence then returns the result, with DetectionResult.Partial set and InputDuration covering the
+	// part that was analysed, together with an error wrapping ErrPartialResult and the ffmpeg failure. It also keeps
+	// ffmpeg's progress line under StatsAuto, so that the covered duration is known.
+	AllowPartial bool
+}
+
+// MaxRawOutputBytes bounds DetectionResult.RawOutput. Older output is discarded while ffmpeg runs, so memory use
+// stays bounded in streaming mode as well.
+const MaxRawOutputBytes = 256 * 1024
+
+// StatsMode selects whether ffmpeg prints its periodic progress line ("size=... time=... speed=...").
+type StatsMode int
+
+const (
+	// StatsAuto passes -nostats when the duration is available without the progress line, because ffprobe has
+	// determined it or ffmpeg reports progress with -progress, and otherwise keeps the line to read it from.
+	StatsAuto StatsMode = iota
+	// StatsKeep always keeps the progress line.
+	StatsKeep
+	// StatsDrop always passes -nostats. Without ffprobe the input duration is then unknown.
+	StatsDrop
+)
+
+// stdinInput is the ffmpeg input that reads from standard input.
+const stdinInput = "pipe:0"
+
+type stdinKey struct{}
+
+// CommandStdin returns the reader that should be connected to the standard input of a command started by a
+// CommandRunner or StreamingRunner, or nil if the command should not read standard input.
+func CommandStdin(ctx context.Context) io.Reader {
+	stdin, _ := ctx.Value(stdinKey{}).(io.Reader)
+	return stdin
+}
+
+// isPipeInput reports whether inputPath reads from a pipe rather than a seekable file or URL.
+func isPipeInput(inputPath string) bool {
+	return inputPath == "-" || strings.HasPrefix(inputPath, "pipe:")
 }
 
 // DetectionResult captures the detected silence intervals alongside metadata about the input file.
 type DetectionResult struct {
 	Intervals     []SilenceInterval
 	InputDuration float64
+	// ChannelIntervals holds the silence intervals of each channel, keyed by channel index, when per-channel
+	// detection was requested. Intervals then contains the periods where every channel is silent, as silencedetect
+	// reports without mono mode, so that one dead channel does not make the input silent.
+	ChannelIntervals map[int][]SilenceInterval
+	// StreamIntervals holds the intervals reported by each silencedetect instance when ffmpeg's output interleaves
+	// several, as it can for inputs with more than one audio stream unless DetectionOptions.AudioStreamIndex selects
+	// one. The instances are listed in the order in which they first reported silence, and Intervals holds the
+	// intervals of the first, the primary stream. StreamIntervals is nil when a single instance reported silence.
+	// Unlike Intervals, they are neither normalized nor merged.
+	StreamIntervals [][]SilenceInterval
+	// WindowStart is the absolute offset, in seconds, at which analysis began when only a window of the input was
+	// analysed. Interval timestamps are absolute, while InputDuration is the length of the analysed window, so the
+	// analysed span is [WindowStart, WindowStart+InputDuration].
+	WindowStart float64
+	// ProbeRetried reports whether the input was analysed a second time with raised probing limits because ffmpeg
+	// could not find its codec parameters at first.
+	ProbeRetried bool
+	// MeanVolumeDB and MaxVolumeDB are the mean and peak volume in dBFS reported by volumedetect when
+	// DetectionOptions.MeasureVolume is set. They are nil when ffmpeg did not report a finite value, for instance
+	// because the input holds no samples.
+	MeanVolumeDB *float64
+	MaxVolumeDB  *float64
+	// ThresholdDB is the silencedetect noise threshold that was used: DetectionOptions.NoiseLevel, the dB value of
+	// DetectionOptions.NoiseRatio, or the adaptive threshold derived from NoiseFloorDB.
+	ThresholdDB float64
+	// NoiseFloorDB is the noise floor measured in adaptive mode. It is -Inf when the audio contains digital silence.
+	NoiseFloorDB *float64
+	// Loudness is the EBU R128 summary when DetectionOptions.MeasureLoudness is set and ffmpeg reported one.
+	Loudness *Loudness
+	// RawOutput holds the tail of ffmpeg's diagnostic output (standard error), at most MaxRawOutputBytes, when
+	// DetectionOptions.CaptureRawOutput is set. Truncated output starts with "...".
+	RawOutput string
+	// Truncated reports that detection stopped at DetectionOptions.MaxIntervals, so Intervals covers only the start
+	// of the input and later silence is missing.
+	Truncated bool
+	// Normalized reports that the intervals have been cleaned up by Normalize, and Warnings lists the adjustments it
+	// made to them, along with any WarningDurationMismatch.
+	Normalized bool
+	Warnings   []Warning
+	// AudioInfo describes the analysed audio stream, from ffprobe when the detector has it and otherwise from the
+	// stream listing ffmpeg prints before processing. It is nil when neither described the stream, for instance
+	// because ffprobe is not configured and a -loglevel in DetectionOptions.ExtraInputArgs hid the listing.
+	AudioInfo *AudioInfo
+	// OutputLines and ParsedLines count the non-empty lines of ffmpeg's diagnostic output and those that were
+	// recognised, such as silencedetect, progress and stream listing lines. A fleet whose ParsedLines drops to zero
+	// runs an ffmpeg whose output the detector no longer understands.
+	OutputLines int
+	ParsedLines int
+	// Partial reports that ffmpeg failed partway and DetectionOptions.AllowPartial kept the intervals it reported
+	// before. InputDuration is then the part of the input that was analysed.
+	Partial bool
+	// DurationKnown reports that InputDuration was determined, by ffprobe or from ffmpeg's progress, so that zero
+	// means an empty input rather than an unknown duration.
+	DurationKnown bool
+}
+
+// FullySilentOpts are the tolerances, in seconds, of a full-silence check. Head is the sound allowed before the
+// first silence, such as the priming delay some encoders add before silencedetect reports anything; Tail is the
+// slack between the end of the last silence and the end of the input; Gap is the sound allowed between two
+// silences.
+type FullySilentOpts struct {
+	Head float64
+	Tail float64
+	Gap  float64
+}
+
+// uniformTolerance returns FullySilentOpts with the same tolerance everywhere.
+func uniformTolerance(tolerance float64) FullySilentOpts {
+	return FullySilentOpts{Head: tolerance, Tail: tolerance, Gap: tolerance}
 }
 
 // FullySilent reports whether the detected silence intervals span the entire input duration.
 //
-// The tolerance parameter allows a small slack when comparing floating point timestamps and durations.
+// The tolerance parameter allows a small slack when comparing floating point timestamps and durations. A truncated
+// result is never fully silent, since it does not describe the whole input. FullySilent returns false when the
+// answer is unknown; FullySilentStatus tells the two apart.
 func (r DetectionResult) FullySilent(tolerance float64) bool {
-	if r.InputDuration <= 0 || len(r.Intervals) == 0 {
-		return false
+	return r.FullySilentWithin(uniformTolerance(tolerance))
+}
+
+// FullySilentWithin is FullySilent with separate tolerances for the head, the tail and the gaps of the input.
+func (r DetectionResult) FullySilentWithin(opts FullySilentOpts) bool {
+	silent, determinate := r.FullySilentStatusWithin(opts)
+	return silent && determinate
+}
+
+// FullySilentStatus reports whether the detected silence intervals span the entire input duration, and whether
+// that could be determined at all. determinate is false, and silent with it, when the input duration is unknown, or
+// when a truncated or partial result found no sound in the part of the input it describes. An input whose duration
+// is known to be zero holds no sound and counts as silent.
+func (r DetectionResult) FullySilentStatus(tolerance float64) (silent, determinate bool) {
+	return r.FullySilentStatusWithin(uniformTolerance(tolerance))
+}
+
+// FullySilentStatusWithin is FullySilentStatus with separate tolerances for the head, the tail and the gaps of the
+// input.
+func (r DetectionResult) FullySilentStatusWithin(opts FullySilentOpts) (silent, determinate bool) {
+	if r.InputDuration <= 0 {
+		return r.DurationKnown, r.DurationKnown
+	}
+	if len(r.Intervals) == 0 {
+		return false, true
 	}
 
 	first := r.Intervals[0]
-	if first.Start > tolerance {
-		return false
+	if first.Start-r.WindowStart > opts.Head {
+		return false, true
 	}
 
 	prevEnd := first.End
 	for _, interval := range r.Intervals[1:] {
-		if interval.Start-prevEnd > tolerance {
-			return false
+		if interval.Start-prevEnd > opts.Gap {
+			return false, true
 		}
 		prevEnd = interval.End
 	}
 
+	// A truncated result stops at its last interval, so the rest of the input may hold sound or not.
 	last := r.Intervals[len(r.Intervals)-1]
-	if math.Abs(last.End-r.InputDuration) > tolerance {
-		return false
+	if end := r.WindowStart + r.InputDuration; last.End < end-opts.Tail && !r.Truncated || last.End > end+opts.Tail {
+		return false, true
 	}
+	if r.Truncated || r.Partial {
+		return false, false
+	}
+	return true, true
+}
+
+// ChannelFullySilent reports whether the given channel is silent for the entire input duration.
+//
+// It returns false when per-channel detection was not requested or the channel has no recorded silence.
+func (r DetectionResult) ChannelFullySilent(channel int, tolerance float64) bool {
+	return r.ChannelFullySilentWithin(channel, uniformTolerance(tolerance))
+}
+
+// ChannelFullySilentWithin is ChannelFullySilent with separate tolerances for the head, the tail and the gaps of the
+// input.
+func (r DetectionResult) ChannelFullySilentWithin(channel int, opts FullySilentOpts) bool {
+	channelResult := DetectionResult{
+		Intervals:     r.ChannelIntervals[channel],
+		InputDuration: r.InputDuration,
+		DurationKnown: r.DurationKnown,
+		WindowStart:   r.WindowStart,
+		Truncated:     r.Truncated,
+		Partial:       r.Partial,
+	}
+	return channelResult.FullySilentWithin(opts)
+}
 
-	return true
+// Channels returns the indices of the channels with per-channel results, in ascending order.
+func (r DetectionResult) Channels() []int {
+	channels := make([]int, 0, len(r.ChannelIntervals))
+	for channel := range r.ChannelIntervals {
+		channels = append(channels, channel)
+	}
+	sort.Ints(channels)
+	return channels
 }
 
 // Detector orchestrates executing ffmpeg and parsing its silence detection output.
 type Detector struct {
-	ffmpegPath string
-	run        CommandRunner
+	ffmpegPath  string
+	ffprobePath string
+	run         CommandRunner
+	stream      StreamingRunner
+	split       SplitStreamingRunner
+	lookPath    func(file string) (string, error)
+	rawOutput   bool
+	logger      *slog.Logger
+	recorder    Recorder
+	tracer      Tracer
+
+	mu   sync.Mutex
+	info *FFmpegInfo
 }
 
 // Option customises the Detector during construction.
@@ -78,10 +373,45 @@ func WithFFmpegPath(path string) Option {
 	}
 }
 
+// WithFFprobePath enables ffprobe, at the given path, to determine the input duration.
+//
+// ffprobe runs through the detector's CommandRunner. When it fails, the duration falls back to the progress
+// reported by ffmpeg.
+func WithFFprobePath(path string) Option {
+	return func(d *Detector) {
+		d.ffprobePath = path
+	}
+}
+
+// WithRawFFmpegOutput stops the detector from adding -hide_banner, -nostdin and -nostats to silencedetect runs, so
+// that ffmpeg prints its banner and progress exactly as it does by default. This suits custom runners that parse
+// the raw output themselves.
+func WithRawFFmpegOutput() Option {
+	return func(d *Detector) {
+		d.rawOutput = true
+	}
+}
+
 // WithCommandRunner overrides the command execution function used by the detector.
+//
+// Installing a CommandRunner switches the detector to buffered parsing, so the runner receives every invocation.
 func WithCommandRunner(runner CommandRunner) Option {
 	return func(d *Detector) {
 		d.run = runner
+		d.stream = nil
+		d.split = nil
+	}
+}
+
+// WithStreamingRunner overrides the streaming command execution function used by the detector.
+//
+// When set, ffmpeg output is parsed line by line as it is produced instead of being buffered in memory. Since the
+// runner combines standard output and standard error, the duration is read from ffmpeg's progress line rather than
+// from -progress records.
+func WithStreamingRunner(runner StreamingRunner) Option {
+	return func(d *Detector) {
+		d.stream = runner
+		d.split = nil
 	}
 }
 
@@ -90,138 +420,1125 @@ func NewDetector(opts ...Option) *Detector {
 	d := &Detector{
 		ffmpegPath: "ffmpeg",
 		run:        defaultCommandRunner,
+		stream:     defaultStreamingRunner,
+		split:      defaultSplitStreamingRunner,
+		lookPath:   exec.LookPath,
 	}
 
 	for _, opt := range opts {
 		opt(d)
 	}
+	d.instrumentRunners()
 
 	return d
 }
 
 // DetectSilence executes ffmpeg with the silencedetect audio filter and parses the resulting intervals.
+//
+// inputPath may be a local path or an HTTP(S) URL, which ffmpeg then reads directly using its own protocol support.
+// Builds without that support fail with ErrProtocolUnsupported, in which case callers can download the input and
+// retry with the local copy. An inputPath of "-" reads from DetectionOptions.Stdin; since a pipe cannot be probed,
+// the duration then comes from ffmpeg's progress output.
+//
+// When ffmpeg cannot find the codec parameters of a seekable input, the run is retried once within the same
+// context with -analyzeduration and -probesize raised to their maximum, unless DisableProbeRetry is set.
 func (d *Detector) DetectSilence(ctx context.Context, inputPath string, options DetectionOptions) (DetectionResult, error) {
+	inputPath, err := prepareInput(inputPath, options)
+	if err != nil {
+		return DetectionResult{}, err
+	}
+
+	ctx, span := startSpan(ctx, d.tracer, spanDetect)
+	span.SetAttributes(detectionAttrs(inputPath, options)...)
+
+	start := time.Now()
+	result, err := d.detectSilence(ctx, inputPath, options)
+	d.recordDetection(start, result, err)
+	endDetectSpan(span, result, err)
+	return result, err
+}
+
+// detectSilence runs DetectSilence for a validated inputPath.
+func (d *Detector) detectSilence(ctx context.Context, inputPath string, options DetectionOptions) (DetectionResult, error) {
+	pipeInput := isPipeInput(inputPath)
+	if options.Stdin != nil {
+		ctx = context.WithValue(ctx, stdinKey{}, options.Stdin)
+	}
+
+	if options.triesNative(inputPath) {
+		result, err := d.detectNative(ctx, inputPath, options)
+		switch {
+		case err == nil:
+			if options.KnownDuration > 0 {
+				d.checkKnownDuration(ctx, &result, result.InputDuration, windowDuration(options.KnownDuration, options))
+			}
+			result.ThresholdDB = options.thresholdDB()
+			return d.finishResult(ctx, result, options), nil
+		case options.Engine == EngineNative || !errors.Is(err, ErrNativeUnsupported):
+			return DetectionResult{}, err
+		}
+		d.debug(ctx, "analysing with ffmpeg", "reason", err.Error())
+	}
+
+	if options.AudioLanguage != "" && d.probesDuration(inputPath, options) {
+		index, err := d.resolveAudioLanguage(ctx, inputPath, options)
+		if err != nil {
+			return DetectionResult{}, err
+		}
+		if index >= 0 {
+			options.AudioStreamIndex = &index
+			options.AudioLanguage = ""
+		}
+	}
+
+	var noiseFloor *float64
+	if options.AdaptiveThreshold {
+		floor, err := d.measureNoiseFloor(ctx, inputPath, options)
+		if err != nil {
+			return DetectionResult{}, err
+		}
+		noiseFloor = &floor
+		options.NoiseLevel = adaptiveThreshold(floor, options.AdaptiveOffsetDB, options.NoiseLevel)
+	}
+
+	knownDuration := windowDuration(options.KnownDuration, options)
+	var probedAudio []AudioInfo
+	if d.probesDuration(inputPath, options) {
+		duration, audio, err := d.probeInput(ctx, inputPath, options)
+		if err == nil {
+			knownDuration = windowDuration(duration, options)
+		}
+		probedAudio = audio
+	}
+
+	result, err := d.runDetection(ctx, inputPath, options, knownDuration)
+
+	// Loudness is an optional extra, so a build without ebur128 still yields the silence intervals. A pipe cannot be
+	// read twice, though.
+	if options.MeasureLoudness && missingFilter(err, "ebur128") && !pipeInput {
+		d.warn(ctx, "ffmpeg lacks the ebur128 filter; measuring without loudness", "path", d.ffmpegPath)
+		options.MeasureLoudness = false
+		result, err = d.runDetection(ctx, inputPath, options, knownDuration)
+	}
+
+	// A pipe has already been consumed, so only seekable inputs can be analysed again.
+	probeRetried := false
+	if errors.Is(err, ErrCodecParameters) && !options.DisableProbeRetry && !pipeInput && ctx.Err() == nil {
+		d.debug(ctx, "retrying with raised probing limits", "input", RedactText(inputPath))
+		retryOptions := options
+		retryOptions.ExtraInputArgs = append(append([]string(nil), probeRetryArgs...), options.ExtraInputArgs...)
+		result, err = d.runDetection(ctx, inputPath, retryOptions, knownDuration)
+		probeRetried = true
+	}
+	if err != nil && !result.Partial {
+		return DetectionResult{}, err
+	}
+	// A partial result is returned as it is, since the closing pass would fail at the same point.
+	if options.HysteresisDB > 0 && err == nil {
+		closingOptions := options
+		if probeRetried {
+			closingOptions.ExtraInputArgs = append(append([]string(nil), probeRetryArgs...), options.ExtraInputArgs...)
+		}
+		result, err = d.applyHysteresis(ctx, inputPath, closingOptions, knownDuration, result)
+		if err != nil {
+			return DetectionResult{}, err
+		}
+	}
+	result.ProbeRetried = probeRetried
+	result.AudioInfo = selectAudioInfo(result.AudioInfo, probedAudio, options)
+	result.ThresholdDB = options.thresholdDB()
+	result.NoiseFloorDB = noiseFloor
+	result = d.finishResult(ctx, result, options)
+
+	if options.MeasureIntervalLevels && err == nil {
+		if err := d.measureIntervalLevels(ctx, inputPath, options, result.Intervals); err != nil {
+			return DetectionResult{}, err
+		}
+	}
+
+	// err is the failure behind a partial result, if any.
+	return result, err
+}
+
+// finishResult turns the intervals of a run, relative to the analysed window, into the absolute, normalized and
+// merged intervals DetectSilence returns.
+func (d *Detector) finishResult(ctx context.Context, result DetectionResult, options DetectionOptions) DetectionResult {
+	if options.StartOffset > 0 {
+		result = result.Shift(options.StartOffset)
+	}
+
+	if !options.DisableNormalize {
+		var warnings []Warning
+		result, warnings = Normalize(result)
+		for _, warning := range warnings {
+			d.debug(ctx, "normalized silence intervals", "code", string(warning.Code), "message", warning.Message)
+		}
+	}
+
+	if options.MergeGap > 0 {
+		result.Intervals = MergeIntervals(result.Intervals, options.MergeGap)
+		for channel, intervals := range result.ChannelIntervals {
+			result.ChannelIntervals[channel] = MergeIntervals(intervals, options.MergeGap)
+		}
+	}
+	return result
+}
+
+// probesDuration reports whether ffprobe should determine the duration of inputPath before detection. Pipes cannot
+// be read twice, ffprobe may not report a duration for live streams and HLS or DASH manifests or may wait for them
+// to end, and it cannot read raw PCM, so all of them rely on ffmpeg's progress instead. A
+// DetectionOptions.KnownDuration makes probing unnecessary.
+func (d *Detector) probesDuration(inputPath string, options DetectionOptions) bool {
+	return d.ffprobePath != "" && !isPipeInput(inputPath) && !isManifest(inputPath) && !options.Live && options.RawPCM == nil &&
+		options.KnownDuration <= 0
+}
+
+// BuildArgs returns the ffmpeg arguments DetectSilence would run for inputPath and options, after the same
+// validation, without executing anything. Since ffprobe is not consulted, the arguments are those of a first run
+// whose duration is not known in advance. In adaptive mode the threshold is only known after the noise floor has
+// been measured, so the silencedetect arguments use options.NoiseLevel.
+func (d *Detector) BuildArgs(inputPath string, options DetectionOptions) ([]string, error) {
+	inputPath, err := prepareInput(inputPath, options)
+	if err != nil {
+		return nil, err
+	}
+	return d.commandArgs(inputPath, options, false), nil
+}
+
+// maxNoiseLevel is the highest NoiseLevel Validate accepts. Above 0 dB, the threshold exceeds full scale and every
+// sample counts as silence, which is almost always a sign flipped by mistake.
+const maxNoiseLevel = 0
+
+// isFinite reports whether v is neither NaN nor infinite. Comparisons such as v <= 0 are false for NaN, so every
+// float option is checked with it before its range.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Validate checks options for values and combinations that DetectSilence rejects, without regard to the input. The
+// returned error joins every problem found, one per line, so that all of them can be fixed at once.
+func (o DetectionOptions) Validate() error {
+	var problems []error
+	problemf := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if !isFinite(o.MinSilenceDuration) {
+		problemf("minimum silence duration must be finite, got %f", o.MinSilenceDuration)
+	} else if o.MinSilenceDuration <= 0 {
+		problemf("minimum silence duration must be greater than zero, got %f", o.MinSilenceDuration)
+	}
+
+	if !isFinite(o.NoiseLevel) {
+		problemf("noise level must be finite, got %f", o.NoiseLevel)
+	} else if o.NoiseLevel > maxNoiseLevel {
+		problemf("noise level must be at most %ddB, got %gdB; a positive level counts everything as silence", maxNoiseLevel, o.NoiseLevel)
+	}
+
+	if o.NoiseRatio != nil {
+		if o.NoiseLevel != 0 {
+			problems = append(problems, errors.New("noise level and noise ratio both set the noise threshold; set only one"))
+		}
+		if ratio := *o.NoiseRatio; !(ratio > 0 && ratio <= 1) {
+			problemf("noise ratio must be greater than zero and at most 1, got %f", ratio)
+		}
+		if o.AdaptiveThreshold {
+			problems = append(problems, errors.New("adaptive threshold derives the noise threshold in dB and cannot be combined with a noise ratio"))
+		}
+	}
+
+	if !isFinite(o.AdaptiveOffsetDB) {
+		problemf("adaptive offset must be finite, got %f", o.AdaptiveOffsetDB)
+	}
+
+	if !isFinite(o.HysteresisDB) || o.HysteresisDB < 0 {
+		problemf("hysteresis must be a finite, non-negative number of dB, got %f", o.HysteresisDB)
+	} else if o.HysteresisDB > 0 && !o.AdaptiveThreshold && o.thresholdDB()+o.HysteresisDB > maxNoiseLevel {
+		problemf("hysteresis of %gdB raises the closing threshold above %ddB", o.HysteresisDB, maxNoiseLevel)
+	}
+
+	if !isFinite(o.MergeGap) {
+		problemf("merge gap must be finite, got %f", o.MergeGap)
+	} else if o.MergeGap < 0 {
+		problemf("merge gap must not be negative, got %f", o.MergeGap)
+	}
+
+	if !isFinite(o.StartOffset) {
+		problemf("start offset must be finite, got %f", o.StartOffset)
+	} else if o.StartOffset < 0 {
+		problemf("start offset must not be negative, got %f", o.StartOffset)
+	}
+
+	if !isFinite(o.KnownDuration) {
+		problemf("known duration must be finite, got %f", o.KnownDuration)
+	} else if o.KnownDuration < 0 {
+		problemf("known duration must not be negative, got %f", o.KnownDuration)
+	}
+
+	if !isFinite(o.AnalyzeDuration) {
+		problemf("analyze duration must be finite, got %f", o.AnalyzeDuration)
+	} else if o.AnalyzeDuration < 0 {
+		problemf("analyze duration must not be negative, got %f", o.AnalyzeDuration)
+	}
+
+	if o.AudioStreamIndex != nil && *o.AudioStreamIndex < 0 {
+		problemf("audio stream index must not be negative, got %d", *o.AudioStreamIndex)
+	}
+
+	if o.AudioLanguage != "" {
+		if o.AudioStreamIndex != nil {
+			problems = append(problems, errors.New("audio stream index and audio language both select the audio stream; set only one"))
+		}
+		if !languageTagPattern.MatchString(o.AudioLanguage) {
+			problemf("invalid audio language %q", o.AudioLanguage)
+		}
+	}
+
+	if o.SampleRate < 0 {
+		problemf("sample rate must not be negative, got %d", o.SampleRate)
+	}
+
+	if o.MaxIntervals < 0 {
+		problemf("maximum interval count must not be negative, got %d", o.MaxIntervals)
+	}
+
+	if o.Live && (o.StartOffset > 0 || o.AdaptiveThreshold || o.HysteresisDB > 0) {
+		problems = append(problems, errors.New("live inputs cannot be sought or read twice, so they support neither a start offset, an adaptive threshold nor hysteresis"))
+	}
+
+	if o.Live && o.MeasureIntervalLevels {
+		problems = append(problems, errors.New("interval levels are measured by seeking to each interval, which live inputs do not support"))
+	}
+
+	switch o.Engine {
+	case "", EngineAuto, EngineFFmpeg:
+	case EngineNative:
+		if unsupported := o.nativeUnsupported(); len(unsupported) > 0 {
+			problemf("the native engine does not support %s", strings.Join(unsupported, ", "))
+		}
+	default:
+		problemf("unknown engine %q; use %s, %s or %s", o.Engine, EngineAuto, EngineFFmpeg, EngineNative)
+	}
+
+	if o.DownmixMono && o.PerChannel {
+		problems = append(problems, errors.New("per-channel detection cannot be combined with downmixing to mono"))
+	}
+
+	if err := validateHeaders(o.InputHeaders); err != nil {
+		problems = append(problems, err)
+	}
+
+	if o.InputFormat != "" && (strings.HasPrefix(o.InputFormat, "-") || strings.ContainsAny(o.InputFormat, " \t\r\n")) {
+		problemf("invalid input format %q", o.InputFormat)
+	}
+
+	if o.RawPCM != nil {
+		problems = append(problems, o.RawPCM.validate()...)
+		if o.InputFormat != "" {
+			problems = append(problems, errors.New("raw PCM input selects the input format itself; do not also set the input format"))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// prepareInput validates inputPath and options and returns the input path to hand to ffmpeg.
+func prepareInput(inputPath string, options DetectionOptions) (string, error) {
 	if inputPath == "" {
-		return DetectionResult{}, errors.New("input path is required")
+		return "", errors.New("input path is required")
+	}
+
+	if err := options.Validate(); err != nil {
+		return "", err
+	}
+
+	if options.AdaptiveThreshold && isPipeInput(inputPath) {
+		return "", errors.New("adaptive threshold needs a seekable input, which stdin is not")
+	}
+
+	if options.HysteresisDB > 0 && isPipeInput(inputPath) {
+		return "", errors.New("hysteresis reads the input twice and needs a seekable input, which stdin is not")
 	}
 
-	if options.MinSilenceDuration <= 0 {
-		return DetectionResult{}, fmt.Errorf("minimum silence duration must be greater than zero, got %f", options.MinSilenceDuration)
+	if options.MeasureIntervalLevels && isPipeInput(inputPath) {
+		return "", errors.New("measuring interval levels seeks to each interval and needs a seekable input, which stdin is not")
 	}
 
-	noiseLevel := strconv.FormatFloat(options.NoiseLevel, 'f', -1, 64)
+	if options.Engine == EngineNative && isPipeInput(inputPath) {
+		return "", errors.New("the native engine reads WAV files and cannot read stdin")
+	}
+
+	if isPipeInput(inputPath) {
+		if options.Stdin == nil {
+			return "", errors.New("reading from stdin requires DetectionOptions.Stdin")
+		}
+		if inputPath == "-" {
+			inputPath = stdinInput
+		}
+	}
+	return inputPath, nil
+}
+
+// probeRetryArgs raise ffmpeg's probing limits to their maximum for inputs, such as MPEG-TS captures with late
+// audio, whose codec parameters cannot be found within the default limits.
+var probeRetryArgs = []string{"-analyzeduration", "2147483647", "-probesize", "2147483647"}
+
+// runDetection executes a single ffmpeg silencedetect run and parses its output. knownDuration, when positive, is
+// the duration of the analysed window, from DetectionOptions.KnownDuration or ffprobe.
+func (d *Detector) runDetection(ctx context.Context, inputPath string, options DetectionOptions, knownDuration float64) (DetectionResult, error) {
+	parser := &silenceParser{knownDuration: knownDuration, parseOnFailure: options.AllowPartial}
+	if options.CaptureRawOutput {
+		parser.capture = &outputCapture{limit: MaxRawOutputBytes}
+	}
+	if options.MeasureLoudness {
+		parser.loudness = &loudnessParser{}
+	}
+	args := d.commandArgs(inputPath, options, knownDuration > 0)
+
+	// Streaming runs are killed through runCtx once MaxIntervals intervals have been parsed.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if options.MaxIntervals > 0 {
+		parser.stop = func() bool {
+			if parser.intervalCount() < options.MaxIntervals {
+				return false
+			}
+			d.debug(ctx, "stopping ffmpeg at the maximum interval count", "max_intervals", options.MaxIntervals)
+			cancel()
+			return true
+		}
+	}
+
+	d.debug(ctx, "running ffmpeg", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	var result DetectionResult
+	var err error
+	switch {
+	case d.split != nil:
+		result, err = d.splitStreamSilence(runCtx, args, parser)
+	case d.stream != nil:
+		result, err = d.streamSilence(runCtx, args, parser)
+	default:
+		result, err = d.runSilence(runCtx, args, parser)
+	}
+
+	if err != nil && options.AllowPartial {
+		if partial, ok := parser.partialResult(); ok {
+			d.debug(ctx, "keeping the partial result of a failed ffmpeg run", "covered", partial.InputDuration, "intervals", len(partial.Intervals))
+			result, err = partial, fmt.Errorf("%w: %w", ErrPartialResult, err)
+		}
+	}
+
+	if err == nil && options.KnownDuration > 0 && parser.progressSeen {
+		d.checkKnownDuration(ctx, &result, parser.lastProgress, knownDuration)
+	}
+
+	if err == nil && options.MaxIntervals > 0 && (parser.stopped || len(result.Intervals) > options.MaxIntervals) {
+		result.Intervals = result.Intervals[:min(len(result.Intervals), options.MaxIntervals)]
+		result.Truncated = true
+	}
+
+	d.logRunFinished(ctx, start, parser, result, err)
+	return result, err
+}
+
+// checkKnownDuration adds a WarningDurationMismatch to result when the analysis covered more than
+// KnownDurationTolerance seconds past known, the window duration derived from DetectionOptions.KnownDuration.
+func (d *Detector) checkKnownDuration(ctx context.Context, result *DetectionResult, covered, known float64) {
+	if covered <= known+KnownDurationTolerance {
+		return
+	}
+	result.Warnings = append(result.Warnings, Warning{
+		Code:    WarningDurationMismatch,
+		Message: fmt.Sprintf("the audio lasts at least %.3fs, past the known duration of %.3fs", covered, known),
+	})
+	d.warn(ctx, "the audio lasts past the known duration", "covered", covered, "known_duration", known)
+}
+
+// DetectSilenceFromReader analyses media read from r, which is connected to ffmpeg's standard input, so that
+// in-memory data does not have to be written to a temporary file first.
+//
+// format names the input container (ffmpeg's -f option, e.g. "wav" or "mp3"); it may be empty for formats ffmpeg
+// can recognise from their content. It overrides options.InputFormat, and options.Stdin is ignored.
+func (d *Detector) DetectSilenceFromReader(ctx context.Context, r io.Reader, format string, options DetectionOptions) (DetectionResult, error) {
+	if r == nil {
+		return DetectionResult{}, errors.New("reader is required")
+	}
+
+	options.Stdin = r
+	options.InputFormat = format
+	return d.DetectSilence(ctx, stdinInput, options)
+}
+
+// commandArgs returns the complete ffmpeg argument list of a silencedetect run. It is shared by runDetection and
+// BuildArgs so that dry runs show exactly what is executed.
+func (d *Detector) commandArgs(inputPath string, options DetectionOptions, durationKnown bool) []string {
+	return append(d.globalArgs(options, durationKnown), silenceArgs(inputPath, options)...)
+}
+
+// globalArgs returns the ffmpeg options that precede the input options of a silencedetect run.
+//
+// Runs through a SplitStreamingRunner report progress with -progress on standard output. Unless the detector was
+// created with WithRawFFmpegOutput, -hide_banner and -nostdin keep the output lean and stop ffmpeg from reading
+// commands from the terminal, and -nostats follows options.Stats; durationKnown reports whether ffprobe has
+// already determined the duration.
+func (d *Detector) globalArgs(options DetectionOptions, durationKnown bool) []string {
+	var args []string
+	if d.split != nil {
+		args = append(args, progressArgs...)
+	}
+	if d.rawOutput {
+		return args
+	}
+
+	args = append(args, "-hide_banner", "-nostdin")
+	// A partial result needs the progress line to know how far ffmpeg got, and a KnownDuration is checked against it.
+	progressAvailable := (durationKnown && !options.AllowPartial && options.KnownDuration <= 0) || d.split != nil
+	if options.Stats == StatsDrop || (options.Stats == StatsAuto && progressAvailable) {
+		args = append(args, "-nostats")
+	}
+	return args
+}
+
+// thresholdDB returns the noise threshold in dB, converting NoiseRatio when it is set.
+func (o DetectionOptions) thresholdDB() float64 {
+	if o.NoiseRatio != nil {
+		return 20 * math.Log10(*o.NoiseRatio)
+	}
+	return o.NoiseLevel
+}
+
+// silenceArgs constructs the ffmpeg input, filter and output arguments for a silencedetect run.
+func silenceArgs(inputPath string, options DetectionOptions) []string {
+	// silencedetect reads a noise value without the dB suffix as an amplitude ratio.
+	noise := strconv.FormatFloat(options.NoiseLevel, 'f', -1, 64) + "dB"
+	if options.NoiseRatio != nil {
+		noise = strconv.FormatFloat(*options.NoiseRatio, 'f', -1, 64)
+	}
 	minDuration := strconv.FormatFloat(options.MinSilenceDuration, 'f', -1, 64)
 
-	filter := fmt.Sprintf("silencedetect=noise=%sdB:d=%s", noiseLevel, minDuration)
+	filter := fmt.Sprintf("silencedetect=noise=%s:d=%s", noise, minDuration)
+	if options.PerChannel {
+		filter += ":mono=1"
+	}
+	if format := audioFormat(options); format != "" {
+		filter = format + "," + filter
+	}
+	if options.MeasureVolume {
+		filter += ",volumedetect"
+	}
+	if options.MeasureLoudness {
+		filter += "," + loudnessFilter
+	}
+
+	return filterArgs(inputPath, options, filter)
+}
+
+// filterArgs constructs the ffmpeg input, filter and output arguments of a run that passes the selected audio
+// through filter and discards the result.
+func filterArgs(inputPath string, options DetectionOptions, filter string) []string {
+	var args []string
+	args = append(args, headerArgs(inputPath, options.InputHeaders)...)
+	if options.StartOffset > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(options.StartOffset, 'f', -1, 64))
+	}
+	if options.InputFormat != "" {
+		args = append(args, "-f", options.InputFormat)
+	}
+	if options.RawPCM != nil {
+		args = append(args, options.RawPCM.inputArgs()...)
+	}
+	args = append(args, options.ExtraInputArgs...)
+	args = append(args, "-i", inputPath)
+	if options.AnalyzeDuration > 0 {
+		args = append(args, "-t", strconv.FormatFloat(options.AnalyzeDuration, 'f', -1, 64))
+	}
+	if options.AudioStreamIndex != nil {
+		args = append(args, "-map", fmt.Sprintf("0:a:%d", *options.AudioStreamIndex))
+	} else if options.AudioLanguage != "" {
+		args = append(args, "-map", "0:a:m:language:"+options.AudioLanguage)
+	}
+	if !options.DecodeAllStreams {
+		args = append(args, "-vn", "-sn", "-dn")
+	}
+	args = append(args, "-af", filter)
+	args = append(args, options.ExtraOutputArgs...)
+	args = append(args, "-f", "null", "-")
+
+	return args
+}
+
+// audioFormat returns the aformat filter that downmixes and resamples the audio ahead of silencedetect, or "" when
+// neither is requested.
+//
+// The conversion has to happen inside the filter chain: ffmpeg applies the -ac and -ar output options after the
+// -af filters, so silencedetect would still process the original audio.
+func audioFormat(options DetectionOptions) string {
+	var params []string
+	if options.SampleRate > 0 {
+		params = append(params, "sample_rates="+strconv.Itoa(options.SampleRate))
+	}
+	if options.DownmixMono {
+		params = append(params, "channel_layouts=mono")
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "aformat=" + strings.Join(params, ":")
+}
+
+// headerArgs returns the ffmpeg -headers input option for remote inputs with custom headers.
+func headerArgs(inputPath string, header http.Header) []string {
+	if len(header) == 0 {
+		return nil
+	}
+	if input, err := ResolveInput(inputPath); err != nil || !input.IsRemote() {
+		return nil
+	}
 
-	args := []string{"-i", inputPath, "-af", filter, "-f", "null", "-"}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
+	var b strings.Builder
+	for _, name := range names {
+		for _, value := range header[name] {
+			b.WriteString(name)
+			b.WriteString(": ")
+			b.WriteString(value)
+			b.WriteString("\r\n")
+		}
+	}
+
+	return []string{"-headers", b.String()}
+}
+
+// validateHeaders rejects header names or values that would corrupt the request sent by ffmpeg.
+func validateHeaders(header http.Header) error {
+	for name, values := range header {
+		if name == "" || strings.ContainsAny(name, ":\r\n") {
+			return fmt.Errorf("invalid HTTP header name %q", name)
+		}
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("invalid value for HTTP header %q: must not contain line breaks", name)
+			}
+		}
+	}
+	return nil
+}
+
+// runSilence executes ffmpeg through the buffered CommandRunner and parses its combined output.
+func (d *Detector) runSilence(ctx context.Context, args []string, parser *silenceParser) (DetectionResult, error) {
 	output, err := d.run(ctx, d.ffmpegPath, args...)
 	if err != nil {
-		return DetectionResult{}, fmt.Errorf("ffmpeg execution failed: %w: %s", err, strings.TrimSpace(string(output)))
+		if parser.parseOnFailure {
+			// The output is only kept for a partial result, so a line that fails to parse ends it.
+			_ = parser.parseOutput(string(output))
+		}
+		return DetectionResult{}, newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
 	}
 
-	intervals, duration, err := parseSilenceOutput(string(output))
-	if err != nil {
+	if err := parser.parseOutput(string(output)); err != nil {
+		return DetectionResult{}, err
+	}
+	if err := parser.checkRecognized(); err != nil {
 		return DetectionResult{}, err
 	}
 
-	return DetectionResult{Intervals: intervals, InputDuration: duration}, nil
+	return parser.finish(), nil
 }
 
+// ffmpegNumber matches the numbers ffmpeg prints in filter output: an optional sign, an integer, decimal or
+// fraction-only part and an optional exponent, such as "12", "-0.5", ".25" or "1.5e-05". Values matched with it are
+// converted by parseFFmpegNumber.
+const ffmpegNumber = `[-+]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[eE][-+]?[0-9]+)?`
+
 var (
-	silenceStartPattern = regexp.MustCompile(`silence_start:\s*([0-9]+(?:\.[0-9]+)?)`)
-	silenceEndPattern   = regexp.MustCompile(`silence_end:\s*([0-9]+(?:\.[0-9]+)?)\s*\|\s*silence_duration:\s*([0-9]+(?:\.[0-9]+)?)`)
-	progressTimePattern = regexp.MustCompile(`time=([0-9]{2}):([0-9]{2}):([0-9]+(?:\.[0-9]+)?)`)
+	silenceChannelPattern = regexp.MustCompile(`channel:\s*([0-9]+)\s*\|`)
+	silenceInstanceTag    = regexp.MustCompile(`\[silencedetect @ ([^\]]+)\]`)
+	silenceStartPattern   = regexp.MustCompile(`silence_start:\s*(` + ffmpegNumber + `)`)
+	silenceEndPattern     = regexp.MustCompile(`silence_end:\s*(` + ffmpegNumber + `)(?:\s*\|\s*silence_duration:\s*(` + ffmpegNumber + `))?`)
+	meanVolumePattern     = regexp.MustCompile(`mean_volume:\s*(` + ffmpegNumber + `) dB`)
+	maxVolumePattern      = regexp.MustCompile(`max_volume:\s*(` + ffmpegNumber + `) dB`)
+	progressTimePattern   = regexp.MustCompile(`time=(-?[0-9]+):([0-9]{2}):([0-9]+(?:\.[0-9]+)?)`)
 )
 
+// outputLineEndings turns the line endings of ffmpeg output into "\n".
+var outputLineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// parseFFmpegNumber converts a number matched by ffmpegNumber. name describes the value in the error, such as
+// "silence start".
+func parseFFmpegNumber(text, name string) (float64, error) {
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return value, nil
+}
+
 func parseSilenceOutput(output string) ([]SilenceInterval, float64, error) {
-	var intervals []SilenceInterval
-	var currentStart *float64
-	var lastProgress float64
-	var maxEnd float64
+	var parser silenceParser
+	if err := parser.parseOutput(output); err != nil {
+		return nil, 0, err
+	}
+	if err := parser.checkRecognized(); err != nil {
+		return nil, 0, err
+	}
+
+	result := parser.finish()
+	return result.Intervals, result.InputDuration, nil
+}
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+// silenceParser incrementally consumes ffmpeg silencedetect output one line at a time.
+//
+// Lines tagged with a channel index (emitted when silencedetect runs with mono=1) are tracked per channel. Other
+// lines are tracked per silencedetect instance, identified by the address in their "[silencedetect @ 0x...]" tag, so
+// that the interleaved output of instances filtering different audio streams is not mixed up. The first instance to
+// report silence, and untagged lines, fill intervals and currentStart; the others are kept in streams.
+type silenceParser struct {
+	intervals        []SilenceInterval
+	currentStart     *float64
+	primary          string
+	streams          []*silenceStream
+	channelIntervals map[int][]SilenceInterval
+	channelStarts    map[int]float64
+	lastProgress     float64
+	maxEnd           float64
+	// progressSeen records that ffmpeg reported its progress, which makes lastProgress a duration even when zero.
+	progressSeen bool
+	// knownDuration, when positive, is an authoritative duration that takes precedence over progress output.
+	knownDuration float64
+	// outputBytes counts the bytes of output passed to parseLine, including line terminators.
+	outputBytes int64
+	// capture, when non-nil, keeps the tail of the output passed to parseLine.
+	capture *outputCapture
+	// loudness, when non-nil, collects the ebur128 summary.
+	loudness *loudnessParser
+	// meanVolume and maxVolume are the volumedetect summary values, when reported.
+	meanVolume *float64
+	maxVolume  *float64
+	// audio describes the analysed audio stream from the stream listing that precedes processing.
+	audio audioInfoParser
+	// outputLines and parsedLines count the non-empty lines of output and those the parser recognised, and snippet
+	// keeps the first few for an UnrecognizedOutputError.
+	outputLines int
+	parsedLines int
+	snippet     []string
+	// parseOnFailure makes runSilence parse the output of a failed run too, for partialResult. Streaming runs parse the
+	// output as it arrives anyway.
+	parseOnFailure bool
+	// stop, when non-nil, is called after every streamed line; returning true stops reading the output, and
+	// stopped records that it did. The command is then expected to be killed, so its exit status is ignored.
+	stop    func() bool
+	stopped bool
+}
+
+// parseOutput parses buffered ffmpeg output. Like scanOutputLines for streamed output, it ends lines at "\n", at the
+// "\r\n" of Windows builds and at the lone "\r" ffmpeg writes between the progress updates it overwrites in place.
+func (p *silenceParser) parseOutput(output string) error {
+	if output == "" {
+		return nil
+	}
+	output = outputLineEndings.Replace(output)
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if err := p.parseLine(line); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if matches := silenceStartPattern.FindStringSubmatch(line); len(matches) == 2 {
-			start, err := strconv.ParseFloat(matches[1], 64)
-			if err != nil {
-				return nil, 0, fmt.Errorf("parse silence start: %w", err)
-			}
-			currentStart = &start
-			continue
+func (p *silenceParser) parseLine(line string) error {
+	p.outputBytes += int64(len(line)) + 1
+	if p.capture != nil {
+		p.capture.writeLine(line)
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	p.outputLines++
+	if len(p.snippet) < unrecognizedSnippetLines {
+		p.snippet = append(p.snippet, strings.TrimSpace(line))
+	}
+
+	recognized, err := p.parseEntry(line)
+	if recognized {
+		p.parsedLines++
+	}
+	return err
+}
+
+// parseEntry interprets a non-empty line of output and reports whether it was one the parser knows, such as a
+// silencedetect, volumedetect, ebur128, progress or stream listing line.
+func (p *silenceParser) parseEntry(line string) (bool, error) {
+	if p.loudness != nil && p.loudness.parseLine(line) {
+		return true, nil
+	}
+	line = strings.TrimSpace(line)
+	if p.audio.parseLine(line) {
+		return true, nil
+	}
+
+	channel := -1
+	if matches := silenceChannelPattern.FindStringSubmatch(line); len(matches) == 2 {
+		parsed, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return true, fmt.Errorf("parse silence channel: %w", err)
 		}
+		channel = parsed
+	}
 
-		if matches := silenceEndPattern.FindStringSubmatch(line); len(matches) == 3 {
-			end, err := strconv.ParseFloat(matches[1], 64)
-			if err != nil {
-				return nil, 0, fmt.Errorf("parse silence end: %w", err)
+	if matches := silenceStartPattern.FindStringSubmatch(line); len(matches) == 2 {
+		start, err := parseFFmpegNumber(matches[1], "silence start")
+		if err != nil {
+			return true, err
+		}
+		if channel >= 0 {
+			if p.channelStarts == nil {
+				p.channelStarts = make(map[int]float64)
 			}
-			duration, err := strconv.ParseFloat(matches[2], 64)
+			p.channelStarts[channel] = start
+			return true, nil
+		}
+		if stream := p.secondaryStream(line); stream != nil {
+			stream.currentStart = &start
+			return true, nil
+		}
+		p.currentStart = &start
+		return true, nil
+	}
+
+	if matches := silenceEndPattern.FindStringSubmatch(line); len(matches) == 3 {
+		end, err := parseFFmpegNumber(matches[1], "silence end")
+		if err != nil {
+			return true, err
+		}
+		var duration *float64
+		if matches[2] != "" {
+			parsed, err := parseFFmpegNumber(matches[2], "silence duration")
 			if err != nil {
-				return nil, 0, fmt.Errorf("parse silence duration: %w", err)
+				return true, err
 			}
+			duration = &parsed
+		}
+
+		if end > p.maxEnd {
+			p.maxEnd = end
+		}
 
-			start := end - duration
-			if currentStart != nil {
-				start = *currentStart
+		if channel >= 0 {
+			var start *float64
+			if channelStart, ok := p.channelStarts[channel]; ok {
+				start = &channelStart
+				delete(p.channelStarts, channel)
 			}
+			if interval, ok := endedInterval(start, end, duration); ok {
+				p.addChannelInterval(channel, interval)
+			}
+			return true, nil
+		}
+		if stream := p.secondaryStream(line); stream != nil {
+			if interval, ok := endedInterval(stream.currentStart, end, duration); ok {
+				stream.intervals = append(stream.intervals, interval)
+			}
+			stream.currentStart = nil
+			return true, nil
+		}
 
-			intervals = append(intervals, SilenceInterval{
-				Start:    start,
-				End:      end,
-				Duration: duration,
-			})
+		if interval, ok := endedInterval(p.currentStart, end, duration); ok {
+			p.intervals = append(p.intervals, interval)
+		}
 
-			if end > maxEnd {
-				maxEnd = end
-			}
+		p.currentStart = nil
+		return true, nil
+	}
 
-			currentStart = nil
-			continue
+	if matches := meanVolumePattern.FindStringSubmatch(line); len(matches) == 2 {
+		volume, err := parseFFmpegNumber(matches[1], "mean volume")
+		if err != nil {
+			return true, err
 		}
+		p.meanVolume = &volume
+		return true, nil
+	}
 
-		if matches := progressTimePattern.FindStringSubmatch(line); len(matches) == 4 {
-			hours, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, 0, fmt.Errorf("parse progress hours: %w", err)
-			}
-			minutes, err := strconv.Atoi(matches[2])
-			if err != nil {
-				return nil, 0, fmt.Errorf("parse progress minutes: %w", err)
-			}
-			seconds, err := strconv.ParseFloat(matches[3], 64)
+	if matches := maxVolumePattern.FindStringSubmatch(line); len(matches) == 2 {
+		volume, err := parseFFmpegNumber(matches[1], "max volume")
+		if err != nil {
+			return true, err
+		}
+		p.maxVolume = &volume
+		return true, nil
+	}
+
+	if matches := progressTimePattern.FindAllStringSubmatch(line, -1); len(matches) > 0 {
+		// A line can hold several progress updates when their carriage returns were lost, and progress can go
+		// backwards after a seek, so the furthest time reached is kept.
+		for _, match := range matches {
+			progress, err := parseProgressTime(match)
 			if err != nil {
-				return nil, 0, fmt.Errorf("parse progress seconds: %w", err)
+				return true, err
 			}
+			p.lastProgress = max(p.lastProgress, progress)
+		}
+		p.progressSeen = true
+		return true, nil
+	}
+
+	// volumedetect reports -inf for inputs without samples, which the volume patterns leave out.
+	if strings.Contains(line, "mean_volume:") || strings.Contains(line, "max_volume:") {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// partialResult returns what the parser gathered from a run that failed, with Partial set. The input duration known
+// from ffprobe is ignored, so InputDuration and the end of a silence still open are the furthest point ffmpeg
+// reported. ok is false when ffmpeg reported neither progress nor silence.
+func (p *silenceParser) partialResult() (result DetectionResult, ok bool) {
+	p.knownDuration = 0
+	result = p.finish()
+	if result.InputDuration <= 0 && len(result.Intervals) == 0 {
+		return DetectionResult{}, false
+	}
+	result.Partial = true
+	return result, true
+}
+
+// unrecognizedSnippetLines is the number of output lines an UnrecognizedOutputError quotes.
+const unrecognizedSnippetLines = 5
+
+// checkRecognized returns an *UnrecognizedOutputError when ffmpeg's output held no line the parser recognised, so
+// that output in an unexpected format, or none at all, is not mistaken for an input without silence. Runs the parser
+// stopped early are exempt.
+func (p *silenceParser) checkRecognized() error {
+	if p.parsedLines > 0 || p.stopped {
+		return nil
+	}
+	return &UnrecognizedOutputError{Snippet: strings.Join(p.snippet, "\n")}
+}
+
+// parseProgressTime converts the submatches of progressTimePattern to seconds. Hours may have any number of digits,
+// as in "time=120:00:01.00" for inputs lasting days. ffmpeg prints negative times, often huge ones, for streams with
+// broken timestamps; they convert to negative seconds, which callers keeping the furthest progress ignore. A time of
+// "N/A" does not match the pattern at all.
+func parseProgressTime(matches []string) (float64, error) {
+	negative := strings.HasPrefix(matches[1], "-")
+	hours, err := strconv.ParseInt(strings.TrimPrefix(matches[1], "-"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse progress hours: %w", err)
+	}
+	minutes, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return 0, fmt.Errorf("parse progress minutes: %w", err)
+	}
+	seconds, err := strconv.ParseFloat(matches[3], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse progress seconds: %w", err)
+	}
+	total := float64(hours)*3600 + float64(minutes*60) + seconds
+	if negative {
+		return -total, nil
+	}
+	return total, nil
+}
+
+// endedInterval builds the interval closed by a silence_end line at end. The tracked silence_start takes precedence
+// over the start implied by the reported duration. Older and some patched ffmpeg builds omit the duration, which is
+// then nil; without a tracked start the interval cannot be placed and ok is false.
+func endedInterval(start *float64, end float64, duration *float64) (interval SilenceInterval, ok bool) {
+	switch {
+	case start != nil && duration != nil:
+		return clampedInterval(*start, end, *duration), true
+	case start != nil:
+		return clampedInterval(*start, end, end-*start), true
+	case duration != nil:
+		return clampedInterval(end-*duration, end, *duration), true
+	}
+	return SilenceInterval{}, false
+}
+
+// clampedInterval builds an interval whose timestamps are clamped to be non-negative.
+//
+// ffmpeg reports negative timestamps for streams that start before zero; the reported duration is preserved so
+// callers still see how long the silence lasted.
+func clampedInterval(start, end, duration float64) SilenceInterval {
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	return SilenceInterval{Start: start, End: end, Duration: duration}
+}
+
+// silenceStream holds the state of a silencedetect instance other than the first one to report silence.
+type silenceStream struct {
+	instance     string
+	intervals    []SilenceInterval
+	currentStart *float64
+}
 
-			lastProgress = float64(hours*3600+minutes*60) + seconds
+// secondaryStream returns the state of the silencedetect instance that printed line, or nil if the line belongs to
+// the primary instance or has no instance tag.
+func (p *silenceParser) secondaryStream(line string) *silenceStream {
+	matches := silenceInstanceTag.FindStringSubmatch(line)
+	if len(matches) != 2 {
+		return nil
+	}
+	instance := matches[1]
+	if p.primary == "" {
+		p.primary = instance
+	}
+	if instance == p.primary {
+		return nil
+	}
+	for _, stream := range p.streams {
+		if stream.instance == instance {
+			return stream
 		}
 	}
+	stream := &silenceStream{instance: instance}
+	p.streams = append(p.streams, stream)
+	return stream
+}
+
+func (p *silenceParser) addChannelInterval(channel int, interval SilenceInterval) {
+	if p.channelIntervals == nil {
+		p.channelIntervals = make(map[int][]SilenceInterval)
+	}
+	p.channelIntervals[channel] = append(p.channelIntervals[channel], interval)
+}
+
+// intervalCount returns the number of complete intervals parsed so far, across all channels.
+func (p *silenceParser) intervalCount() int {
+	count := len(p.intervals)
+	for _, intervals := range p.channelIntervals {
+		count += len(intervals)
+	}
+	for _, stream := range p.streams {
+		count += len(stream.intervals)
+	}
+	return count
+}
+
+// finish synthesizes trailing intervals for any unterminated silence and returns the parsed result. When reading
+// was stopped early, unterminated silence is dropped instead, as it is not known to last until the end.
+func (p *silenceParser) finish() DetectionResult {
+	intervals := p.intervals
 
-	if currentStart != nil && lastProgress > *currentStart {
-		start := *currentStart
-		end := lastProgress
-		intervals = append(intervals, SilenceInterval{
-			Start:    start,
-			End:      end,
-			Duration: end - start,
-		})
+	end := p.lastProgress
+	if p.knownDuration > 0 {
+		end = p.knownDuration
 	}
 
-	if maxEnd > lastProgress {
-		lastProgress = maxEnd
+	if p.currentStart != nil && end > *p.currentStart && !p.stopped {
+		start := *p.currentStart
+		intervals = append(intervals, clampedInterval(start, end, end-start))
+	}
+
+	streamIntervals := [][]SilenceInterval{intervals}
+	for _, stream := range p.streams {
+		streamIntervals = append(streamIntervals, stream.intervals)
+		if stream.currentStart != nil && end > *stream.currentStart && !p.stopped {
+			start := *stream.currentStart
+			streamIntervals[len(streamIntervals)-1] = append(stream.intervals, clampedInterval(start, end, end-start))
+		}
+	}
+
+	for channel, start := range p.channelStarts {
+		if end > start && !p.stopped {
+			p.addChannelInterval(channel, clampedInterval(start, end, end-start))
+		}
 	}
+	p.channelStarts = nil
 
-	return intervals, lastProgress, nil
+	duration := p.knownDuration
+	if duration <= 0 {
+		duration = p.lastProgress
+		if p.maxEnd > duration {
+			duration = p.maxEnd
+		}
+	}
+
+	result := DetectionResult{
+		Intervals:     intervals,
+		InputDuration: duration,
+		MeanVolumeDB:  p.meanVolume,
+		MaxVolumeDB:   p.maxVolume,
+		AudioInfo:     p.audio.result(),
+		OutputLines:   p.outputLines,
+		ParsedLines:   p.parsedLines,
+		DurationKnown: p.knownDuration > 0 || p.progressSeen,
+	}
+	if len(p.streams) > 0 {
+		result.StreamIntervals = streamIntervals
+	}
+	if p.capture != nil {
+		result.RawOutput = p.capture.String()
+	}
+	if p.loudness != nil {
+		result.Loudness = p.loudness.result()
+	}
+
+	if len(p.channelIntervals) > 0 {
+		result.ChannelIntervals = p.channelIntervals
+		// A channel that never reported silence was never silent. When ffmpeg did not describe the stream, at least
+		// two channels are assumed, so that the silence of one channel does not pass for the silence of the input.
+		channels := 2
+		for channel := range p.channelIntervals {
+			channels = max(channels, channel+1)
+		}
+		if result.AudioInfo != nil && result.AudioInfo.Channels > 0 {
+			channels = result.AudioInfo.Channels
+		}
+		result.Intervals = intersectChannels(p.channelIntervals, channels)
+	}
+
+	return result
+}
+
+// outputCapture retains the last limit bytes of the lines written to it.
+type outputCapture struct {
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (c *outputCapture) writeLine(line string) {
+	c.buf = append(c.buf, line...)
+	c.buf = append(c.buf, '\n')
+	// Compacting only once the buffer holds twice the limit keeps the copying amortised.
+	if len(c.buf) > 2*c.limit {
+		c.buf = append(c.buf[:0], c.buf[len(c.buf)-c.limit:]...)
+		c.truncated = true
+	}
+}
+
+// String returns the retained output. Like truncateOutput, it marks truncated output with a leading "..." and
+// starts it at a line boundary when possible.
+func (c *outputCapture) String() string {
+	if !c.truncated && len(c.buf) <= c.limit {
+		return string(c.buf)
+	}
+
+	tail := c.buf
+	if len(tail) > c.limit {
+		tail = tail[len(tail)-c.limit:]
+	}
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return "..." + string(tail)
 }
 
 func defaultCommandRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = CommandStdin(ctx)
 	return cmd.CombinedOutput()
 }
@@ -3,13 +3,25 @@ package detector
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 const floatTolerance = 1e-6
 
+// statsOutput is the final statistics line ffmpeg prints for an input without silence.
+const statsOutput = "size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"
+
 func assertFloatEqual(t *testing.T, got, want float64) {
 	t.Helper()
 	if math.Abs(got-want) > floatTolerance {
@@ -53,7 +65,7 @@ frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:12.00 bitrate=   0.0kbits/
 	}
 
 	expectedFilter := "silencedetect=noise=-25.5dB:d=1.2"
-	expectedArgs := []string{"-i", "video.mp4", "-af", expectedFilter, "-f", "null", "-"}
+	expectedArgs := []string{"-hide_banner", "-nostdin", "-i", "video.mp4", "-vn", "-sn", "-dn", "-af", expectedFilter, "-f", "null", "-"}
 	if len(capturedArgs) != len(expectedArgs) {
 		t.Fatalf("unexpected number of arguments: got %d, want %d (%v)", len(capturedArgs), len(expectedArgs), capturedArgs)
 	}
@@ -179,6 +191,339 @@ frame=  400 fps=0.0 q=-0.0 size=       0kB time=00:00:20.73 bitrate=   0.0kbits/
 	assertFloatEqual(t, interval.Duration, 20.735)
 }
 
+func TestParseSilenceOutputRejectsUnrecognizedOutput(t *testing.T) {
+	if _, _, err := parseSilenceOutput(""); !errors.Is(err, ErrUnrecognizedOutput) || err.Error() != "ffmpeg output was not recognised: ffmpeg printed nothing" {
+		t.Fatalf("expected ErrUnrecognizedOutput for empty output, got %v", err)
+	}
+
+	_, _, err := parseSilenceOutput("silence begins at 2.0\nsilence ends at 4.0\n")
+	var unrecognized *UnrecognizedOutputError
+	if !errors.As(err, &unrecognized) || unrecognized.Snippet != "silence begins at 2.0\nsilence ends at 4.0" {
+		t.Fatalf("expected an UnrecognizedOutputError quoting the output, got %v", err)
+	}
+
+	intervals, duration, err := parseSilenceOutput(statsOutput)
+	if err != nil || len(intervals) != 0 || duration != 10 {
+		t.Fatalf("expected a loud input to parse without intervals, got %v, %v, %v", intervals, duration, err)
+	}
+}
+
+func TestParseSilenceOutputDialects(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []SilenceInterval
+	}{
+		{
+			fixture: "silencedetect_full.log",
+			want:    []SilenceInterval{{Start: 1.5, End: 3, Duration: 1.5}, {Start: 7.25, End: 9, Duration: 1.75}},
+		},
+		{
+			// Older and some patched builds print silence_end without the duration.
+			fixture: "silencedetect_short.log",
+			want:    []SilenceInterval{{Start: 1.5, End: 3, Duration: 1.5}, {Start: 7.25, End: 9, Duration: 1.75}},
+		},
+		{
+			// An end without a duration or a tracked start cannot be placed and is skipped.
+			fixture: "silencedetect_mixed.log",
+			want:    []SilenceInterval{{Start: 1.5, End: 3, Duration: 1.5}, {Start: 7.25, End: 9, Duration: 1.75}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			output, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			intervals, duration, err := parseSilenceOutput(string(output))
+			if err != nil {
+				t.Fatalf("parseSilenceOutput returned error: %v", err)
+			}
+			assertIntervals(t, intervals, tt.want)
+			assertFloatEqual(t, duration, 10)
+		})
+	}
+}
+
+func TestParseFFmpegNumber(t *testing.T) {
+	pattern := regexp.MustCompile(`^` + ffmpegNumber + `$`)
+	tests := []struct {
+		text  string
+		want  float64
+		match bool
+	}{
+		{text: "12", want: 12, match: true},
+		{text: "12.5", want: 12.5, match: true},
+		{text: "12.", want: 12, match: true},
+		{text: ".25", want: 0.25, match: true},
+		{text: "-0.00133", want: -0.00133, match: true},
+		{text: "+3", want: 3, match: true},
+		{text: "1.5e-05", want: 1.5e-05, match: true},
+		{text: "3e+01", want: 30, match: true},
+		{text: "2E2", want: 200, match: true},
+		{text: "-4.5E-1", want: -0.45, match: true},
+		{text: ""},
+		{text: "."},
+		{text: "e5"},
+		{text: "1e"},
+		{text: "1,5"},
+		{text: "inf"},
+		{text: "0x10"},
+	}
+
+	for _, tt := range tests {
+		if got := pattern.MatchString(tt.text); got != tt.match {
+			t.Errorf("ffmpegNumber matching %q = %v, want %v", tt.text, got, tt.match)
+			continue
+		}
+		if !tt.match {
+			continue
+		}
+		got, err := parseFFmpegNumber(tt.text, "value")
+		if err != nil {
+			t.Errorf("parseFFmpegNumber(%q) returned error: %v", tt.text, err)
+			continue
+		}
+		assertFloatEqual(t, got, tt.want)
+	}
+
+	if _, err := parseFFmpegNumber("1e999", "silence end"); err == nil || !strings.HasPrefix(err.Error(), "parse silence end: ") {
+		t.Errorf("expected an out of range error naming the value, got %v", err)
+	}
+}
+
+func TestParseSilenceOutputScientificNotation(t *testing.T) {
+	output := `[silencedetect @ 0x1] silence_start: 1e+00
+[silencedetect @ 0x1] silence_end: 1.000015 | silence_duration: 1.5e-05
+[silencedetect @ 0x1] silence_start: 5
+[silencedetect @ 0x1] silence_end: 3.5e+01 | silence_duration: 3e+01
+[Parsed_volumedetect_1 @ 0x2] mean_volume: -2.5e+01 dB
+`
+	var parser silenceParser
+	if err := parser.parseOutput(output); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	result := parser.finish()
+	assertIntervals(t, result.Intervals, []SilenceInterval{
+		{Start: 1, End: 1.000015, Duration: 1.5e-05},
+		{Start: 5, End: 35, Duration: 30},
+	})
+	if result.MeanVolumeDB == nil || *result.MeanVolumeDB != -25 {
+		t.Fatalf("expected a mean volume of -25dB, got %v", result.MeanVolumeDB)
+	}
+
+	black, err := parseBlackOutput("[blackdetect @ 0x1] black_start:0 black_end:2e+00 black_duration:2\n")
+	if err != nil {
+		t.Fatalf("parseBlackOutput returned error: %v", err)
+	}
+	assertIntervals(t, black, []Interval{{Start: 0, End: 2, Duration: 2}})
+}
+
+func TestParseSilenceOutputProgressTimes(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   float64
+	}{
+		{name: "hours beyond two digits", output: "size=N/A time=120:00:01.00 bitrate=N/A speed=900x\n", want: 432001},
+		{name: "single hour digit", output: "size=N/A time=1:02:03.50 bitrate=N/A\n", want: 3723.5},
+		{name: "unavailable time", output: "size=N/A time=00:00:04.00 bitrate=N/A\nsize=N/A time=N/A bitrate=N/A\n", want: 4},
+		{name: "negative time", output: "size=N/A time=00:00:04.00 bitrate=N/A\nsize=N/A time=-577014:32:22.77 bitrate=N/A\n", want: 4},
+		{name: "progress going backwards", output: "size=N/A time=00:00:09.00 bitrate=N/A\nsize=N/A time=00:00:02.00 bitrate=N/A\n", want: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, duration, err := parseSilenceOutput(tt.output)
+			if err != nil {
+				t.Fatalf("parseSilenceOutput returned error: %v", err)
+			}
+			assertFloatEqual(t, duration, tt.want)
+		})
+	}
+
+	// The trailing silence of a days-long input runs to its last progress time.
+	intervals, _, err := parseSilenceOutput("[silencedetect @ 0x1] silence_start: 432000\nsize=N/A time=120:00:01.00 bitrate=N/A\n")
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+	assertIntervals(t, intervals, []SilenceInterval{{Start: 432000, End: 432001, Duration: 1}})
+}
+
+func TestParseSilenceOutputCarriageReturns(t *testing.T) {
+	want := []SilenceInterval{{Start: 12.0049, End: 35.5, Duration: 23.4951}, {Start: 58.25, End: 65, Duration: 6.75}}
+	for _, fixture := range []string{"silencedetect_progress_cr.log", "silencedetect_progress_crlf.log"} {
+		output, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+
+		t.Run(fixture+"/buffered", func(t *testing.T) {
+			intervals, duration, err := parseSilenceOutput(string(output))
+			if err != nil {
+				t.Fatalf("parseSilenceOutput returned error: %v", err)
+			}
+			assertIntervals(t, intervals, want)
+			assertFloatEqual(t, duration, 65)
+		})
+		t.Run(fixture+"/streamed", func(t *testing.T) {
+			var parser silenceParser
+			if _, parseErr, scanErr := scanSilenceOutput(strings.NewReader(string(output)), &parser); parseErr != nil || scanErr != nil {
+				t.Fatalf("scanSilenceOutput returned errors: %v, %v", parseErr, scanErr)
+			}
+			result := parser.finish()
+			assertIntervals(t, result.Intervals, want)
+			assertFloatEqual(t, result.InputDuration, 65)
+		})
+	}
+
+	// Progress updates whose carriage returns were lost share a line; the last and furthest one wins.
+	_, duration, err := parseSilenceOutput("size=N/A time=00:00:02.00 bitrate=N/A speed=1x size=N/A time=00:00:07.50 bitrate=N/A speed=1x\n")
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+	assertFloatEqual(t, duration, 7.5)
+}
+
+func TestDetectSilenceAllowPartial(t *testing.T) {
+	output := "[silencedetect @ 0x1] silence_start: 2\n" +
+		"[silencedetect @ 0x1] silence_end: 4 | silence_duration: 2\n" +
+		"[silencedetect @ 0x1] silence_start: 6\n" +
+		"size=N/A time=00:00:07.50 bitrate=N/A speed=1x\n" +
+		"[aac @ 0x2] Input buffer exhausted before END element found\n" +
+		"Error while decoding stream #0:0: Invalid data found when processing input\n"
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" {
+			return []byte(`{"format":{"duration":"60.0"}}`), nil
+		}
+		return []byte(output), errors.New("signal: killed")
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1}
+
+	if result, err := d.DetectSilence(context.Background(), "corrupt.m4a", options); err == nil || errors.Is(err, ErrPartialResult) || result.Partial {
+		t.Fatalf("expected a plain failure without AllowPartial, got %+v, %v", result, err)
+	}
+
+	options.AllowPartial = true
+	result, err := d.DetectSilence(context.Background(), "corrupt.m4a", options)
+	var ffErr *FFmpegError
+	if !errors.Is(err, ErrPartialResult) || !errors.As(err, &ffErr) {
+		t.Fatalf("expected ErrPartialResult wrapping the ffmpeg failure, got %v", err)
+	}
+	if !result.Partial {
+		t.Fatalf("expected a partial result")
+	}
+	// The silence still open when ffmpeg died lasted at least until its last progress time.
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 2, End: 4, Duration: 2}, {Start: 6, End: 7.5, Duration: 1.5}})
+	assertFloatEqual(t, result.InputDuration, 7.5)
+
+	// Nothing is kept when ffmpeg failed before reporting progress or silence.
+	output = "corrupt.m4a: Invalid data found when processing input\n"
+	if result, err := d.DetectSilence(context.Background(), "corrupt.m4a", options); err == nil || errors.Is(err, ErrPartialResult) || result.Partial {
+		t.Fatalf("expected a plain failure without progress, got %+v, %v", result, err)
+	}
+}
+
+func TestAllowPartialKeepsProgressLine(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) { return nil, nil }))
+	args := d.commandArgs("audio.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, AllowPartial: true}, true)
+	if slices.Contains(args, "-nostats") {
+		t.Fatalf("expected the progress line to be kept for a partial result, got %v", args)
+	}
+}
+
+func TestParseSilenceOutputOneDeadChannel(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		stream string
+		want   []SilenceInterval
+	}{
+		{name: "stream not described", want: nil},
+		{name: "stereo", stream: "  Stream #0:0: Audio: pcm_s16le, 48000 Hz, stereo, s16, 1536 kb/s\n", want: nil},
+		{name: "mono", stream: "  Stream #0:0: Audio: pcm_s16le, 48000 Hz, mono, s16, 768 kb/s\n", want: []SilenceInterval{{Start: 0, End: 10, Duration: 10}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			parser := silenceParser{knownDuration: 10}
+			if err := parser.parseOutput(test.stream + "[silencedetect @ 0x1] channel: 0 | silence_start: 0\n"); err != nil {
+				t.Fatalf("parseOutput returned error: %v", err)
+			}
+			result := parser.finish()
+			assertIntervals(t, result.ChannelIntervals[0], []SilenceInterval{{Start: 0, End: 10, Duration: 10}})
+			assertIntervals(t, result.Intervals, test.want)
+		})
+	}
+}
+
+func TestParseSilenceOutputShortEndsPerChannel(t *testing.T) {
+	var parser silenceParser
+	output := "[silencedetect @ 0x1] channel: 1 | silence_start: 2\n" +
+		"[silencedetect @ 0x1] channel: 1 | silence_end: 5\n" +
+		"[silencedetect @ 0x1] channel: 0 | silence_end: 6\n"
+	if err := parser.parseOutput(output); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	result := parser.finish()
+	assertIntervals(t, result.ChannelIntervals[1], []SilenceInterval{{Start: 2, End: 5, Duration: 3}})
+	if intervals, ok := result.ChannelIntervals[0]; ok {
+		t.Fatalf("expected the unplaceable end of channel 0 to be skipped, got %v", intervals)
+	}
+}
+
+func TestDetectSilenceCountsParsedLines(t *testing.T) {
+	output := "[mp3 @ 0x1] Estimating duration from bitrate, this may be inaccurate\n" +
+		"[silencedetect @ 0x2] silence_start: 2\n" +
+		"[silencedetect @ 0x2] silence_end: 4 | silence_duration: 2\n\n" +
+		statsOutput
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(output), nil
+	}))
+
+	result, err := d.DetectSilence(context.Background(), "audio.mp3", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.OutputLines != 4 || result.ParsedLines != 3 {
+		t.Fatalf("expected 3 of 4 lines parsed, got %d of %d", result.ParsedLines, result.OutputLines)
+	}
+}
+
+func TestParseSilenceOutputSeparatesInterleavedStreams(t *testing.T) {
+	// Two silencedetect instances, one per audio stream, print their lines interleaved. Tracked with a single
+	// pending start, the first end would be paired with the second stream's start, yielding 2-3 with a duration of 2.
+	output := `
+[silencedetect @ 0x7f01] silence_start: 1.000000
+[silencedetect @ 0x7f02] silence_start: 2.000000
+[silencedetect @ 0x7f01] silence_end: 3.000000 | silence_duration: 2.000000
+[silencedetect @ 0x7f02] silence_end: 6.000000 | silence_duration: 4.000000
+[silencedetect @ 0x7f02] silence_start: 8.000000
+[silencedetect @ 0x7f01] silence_start: 9.000000
+frame=  400 fps=0.0 q=-0.0 size=       0kB time=00:00:10.00 bitrate=   0.0kbits/s speed=1x
+`
+
+	var parser silenceParser
+	if err := parser.parseOutput(output); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	result := parser.finish()
+
+	assertFloatEqual(t, result.InputDuration, 10)
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 3, Duration: 2}, {Start: 9, End: 10, Duration: 1}})
+	if len(result.StreamIntervals) != 2 {
+		t.Fatalf("expected intervals of 2 streams, got %v", result.StreamIntervals)
+	}
+	assertIntervals(t, result.StreamIntervals[0], result.Intervals)
+	assertIntervals(t, result.StreamIntervals[1], []SilenceInterval{{Start: 2, End: 6, Duration: 4}, {Start: 8, End: 10, Duration: 2}})
+
+	parser = silenceParser{}
+	if err := parser.parseOutput("[silencedetect @ 0x7f01] silence_end: 3.000000 | silence_duration: 2.000000\n"); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	if streams := parser.finish().StreamIntervals; streams != nil {
+		t.Fatalf("expected no stream intervals for a single instance, got %v", streams)
+	}
+}
+
 func TestDetectionResultFullySilent(t *testing.T) {
 	result := DetectionResult{
 		InputDuration: 6,
@@ -204,3 +549,1181 @@ func TestDetectionResultFullySilent(t *testing.T) {
 		t.Fatalf("expected not fully silent input")
 	}
 }
+
+func TestDetectionResultFullySilentStatus(t *testing.T) {
+	silence := []SilenceInterval{{Start: 0, End: 10, Duration: 10}}
+	tests := []struct {
+		name            string
+		result          DetectionResult
+		wantSilent      bool
+		wantDeterminate bool
+	}{
+		{name: "silent", result: DetectionResult{InputDuration: 10, Intervals: silence}, wantSilent: true, wantDeterminate: true},
+		{name: "sound", result: DetectionResult{InputDuration: 10}, wantDeterminate: true},
+		{name: "unknown duration", result: DetectionResult{Intervals: silence}},
+		{name: "empty input", result: DetectionResult{DurationKnown: true}, wantSilent: true, wantDeterminate: true},
+		{name: "truncated silence", result: DetectionResult{InputDuration: 20, Intervals: silence, Truncated: true}},
+		{
+			name:            "truncated after sound",
+			result:          DetectionResult{InputDuration: 20, Intervals: []SilenceInterval{{Start: 2, End: 4, Duration: 2}}, Truncated: true},
+			wantDeterminate: true,
+		},
+		{name: "partial silence", result: DetectionResult{InputDuration: 10, Intervals: silence, Partial: true}},
+		{name: "partial with sound", result: DetectionResult{InputDuration: 12, Intervals: silence, Partial: true}, wantDeterminate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silent, determinate := tt.result.FullySilentStatus(1e-3)
+			if silent != tt.wantSilent || determinate != tt.wantDeterminate {
+				t.Fatalf("got silent=%v determinate=%v, want %v and %v", silent, determinate, tt.wantSilent, tt.wantDeterminate)
+			}
+			if got := tt.result.FullySilent(1e-3); got != (tt.wantSilent && tt.wantDeterminate) {
+				t.Fatalf("FullySilent returned %v", got)
+			}
+		})
+	}
+}
+
+func TestDetectionResultFullySilentWithin(t *testing.T) {
+	// 30 ms of encoder priming delay before silencedetect reports the silence.
+	primed := DetectionResult{InputDuration: 10, Intervals: []SilenceInterval{{Start: 0.03, End: 10, Duration: 9.97}}}
+	if primed.FullySilent(1e-3) {
+		t.Fatal("expected the lead-in to fail the default tolerance")
+	}
+	if !primed.FullySilent(0.05) || !primed.FullySilentWithin(FullySilentOpts{Head: 0.05, Tail: 1e-3, Gap: 1e-3}) {
+		t.Fatal("expected the lead-in to pass a 50 ms head tolerance")
+	}
+
+	gapped := DetectionResult{InputDuration: 10, Intervals: []SilenceInterval{{Start: 0, End: 5, Duration: 5}, {Start: 5.03, End: 10, Duration: 4.97}}}
+	if gapped.FullySilentWithin(FullySilentOpts{Head: 0.05, Tail: 0.05, Gap: 1e-3}) {
+		t.Fatal("expected the head tolerance not to excuse a gap")
+	}
+	if !gapped.FullySilentWithin(FullySilentOpts{Gap: 0.05}) {
+		t.Fatal("expected the gap tolerance to excuse the gap")
+	}
+
+	perChannel := DetectionResult{InputDuration: 10, ChannelIntervals: map[int][]SilenceInterval{0: primed.Intervals}}
+	if perChannel.ChannelFullySilent(0, 1e-3) || !perChannel.ChannelFullySilentWithin(0, FullySilentOpts{Head: 0.05}) {
+		t.Fatal("expected the channel verdict to use the head tolerance")
+	}
+}
+
+func TestSilenceParserKnowsEmptyInputs(t *testing.T) {
+	var parser silenceParser
+	if err := parser.parseOutput("size=N/A time=00:00:00.00 bitrate=N/A speed=N/A\n"); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	result := parser.finish()
+	if !result.DurationKnown || result.InputDuration != 0 {
+		t.Fatalf("expected a known zero duration, got %+v", result)
+	}
+	if silent, determinate := result.FullySilentStatus(1e-3); !silent || !determinate {
+		t.Fatal("expected an empty input to be silent")
+	}
+}
+
+func TestDetectSilenceMergesIntervalsWithinGap(t *testing.T) {
+	fakeOutput := `
+[silencedetect @ 0x123] silence_start: 1.000000
+[silencedetect @ 0x123] silence_end: 3.000000 | silence_duration: 2.000000
+[silencedetect @ 0x123] silence_start: 3.030000
+[silencedetect @ 0x123] silence_end: 5.000000 | silence_duration: 1.970000
+frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:08.00 bitrate=   0.0kbits/s speed=1x
+`
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(fakeOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 0.5,
+		MergeGap:           0.05,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(result.Intervals) != 1 {
+		t.Fatalf("expected 1 merged interval, got %d (%v)", len(result.Intervals), result.Intervals)
+	}
+	assertFloatEqual(t, result.Intervals[0].Start, 1)
+	assertFloatEqual(t, result.Intervals[0].End, 5)
+	assertFloatEqual(t, result.Intervals[0].Duration, 4)
+}
+
+func TestDetectSilencePerChannel(t *testing.T) {
+	fakeOutput := `
+[silencedetect @ 0x123] channel: 0 | silence_start: 0
+[silencedetect @ 0x123] channel: 1 | silence_start: 2.5
+[silencedetect @ 0x123] channel: 1 | silence_end: 4 | silence_duration: 1.5
+[silencedetect @ 0x123] channel: 1 | silence_start: 8
+frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:10.00 bitrate=   0.0kbits/s speed=1x
+`
+
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		capturedArgs = append([]string(nil), args...)
+		return []byte(fakeOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		PerChannel:         true,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if capturedArgs[8] != "silencedetect=noise=-30dB:d=1:mono=1" {
+		t.Fatalf("unexpected filter %q", capturedArgs[8])
+	}
+
+	if len(result.ChannelIntervals) != 2 {
+		t.Fatalf("expected 2 channels, got %v", result.ChannelIntervals)
+	}
+	assertIntervals(t, result.ChannelIntervals[0], []SilenceInterval{
+		{Start: 0, End: 10, Duration: 10},
+	})
+	assertIntervals(t, result.ChannelIntervals[1], []SilenceInterval{
+		{Start: 2.5, End: 4, Duration: 1.5},
+		{Start: 8, End: 10, Duration: 2},
+	})
+	assertIntervals(t, result.Intervals, []SilenceInterval{
+		{Start: 2.5, End: 4, Duration: 1.5},
+		{Start: 8, End: 10, Duration: 2},
+	})
+
+	if !result.ChannelFullySilent(0, 1e-6) {
+		t.Fatalf("expected channel 0 to be fully silent")
+	}
+	if result.ChannelFullySilent(1, 1e-6) {
+		t.Fatalf("expected channel 1 to not be fully silent")
+	}
+	if result.ChannelFullySilent(2, 1e-6) {
+		t.Fatalf("expected unknown channel to not be fully silent")
+	}
+}
+
+func TestDetectSilenceSelectsAudioStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		index        *int
+		expectedArgs []string
+	}{
+		{
+			name:         "default stream",
+			expectedArgs: []string{"-hide_banner", "-nostdin", "-i", "video.mp4", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1", "-f", "null", "-"},
+		},
+		{
+			name:         "first stream",
+			index:        intPtr(0),
+			expectedArgs: []string{"-hide_banner", "-nostdin", "-i", "video.mp4", "-map", "0:a:0", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1", "-f", "null", "-"},
+		},
+		{
+			name:         "second stream",
+			index:        intPtr(2),
+			expectedArgs: []string{"-hide_banner", "-nostdin", "-i", "video.mp4", "-map", "0:a:2", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1", "-f", "null", "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedArgs []string
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				capturedArgs = append([]string(nil), args...)
+				return []byte(statsOutput), nil
+			}
+
+			d := NewDetector(WithCommandRunner(runner))
+			_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+				NoiseLevel:         -30,
+				MinSilenceDuration: 1,
+				AudioStreamIndex:   tt.index,
+			})
+			if err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+
+			if strings.Join(capturedArgs, " ") != strings.Join(tt.expectedArgs, " ") {
+				t.Fatalf("unexpected args: got %v want %v", capturedArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestDetectSilenceReportsMissingAudioStream(t *testing.T) {
+	output := "Stream map '0:a:3' matches no streams.\nTo ignore this, add a trailing '?' to the map.\n"
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(output), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		AudioStreamIndex:   intPtr(3),
+	})
+	if !errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("expected ErrStreamNotFound, got %v", err)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestDetectSilenceAnalyzesWindow(t *testing.T) {
+	fakeOutput := `
+[silencedetect @ 0x123] silence_start: 0.000000
+[silencedetect @ 0x123] silence_end: 2.000000 | silence_duration: 2.000000
+[silencedetect @ 0x123] silence_start: 25.000000
+frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:30.00 bitrate=   0.0kbits/s speed=1x
+`
+
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		capturedArgs = append([]string(nil), args...)
+		return []byte(fakeOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		StartOffset:        60,
+		AnalyzeDuration:    30,
+		AudioStreamIndex:   intPtr(1),
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	expectedArgs := []string{"-hide_banner", "-nostdin", "-ss", "60", "-i", "video.mp4", "-t", "30", "-map", "0:a:1", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1", "-f", "null", "-"}
+	if strings.Join(capturedArgs, " ") != strings.Join(expectedArgs, " ") {
+		t.Fatalf("unexpected args: got %v want %v", capturedArgs, expectedArgs)
+	}
+
+	assertFloatEqual(t, result.WindowStart, 60)
+	assertFloatEqual(t, result.InputDuration, 30)
+	assertIntervals(t, result.Intervals, []SilenceInterval{
+		{Start: 60, End: 62, Duration: 2},
+		{Start: 85, End: 90, Duration: 5},
+	})
+
+	assertFloatEqual(t, result.LeadingSilence(1e-3), 2)
+	assertFloatEqual(t, result.TrailingSilence(1e-3), 5)
+	assertIntervals(t, result.NonSilentIntervals(), []SilenceInterval{
+		{Start: 62, End: 85, Duration: 23},
+	})
+	if result.FullySilent(1e-3) {
+		t.Fatalf("expected window to not be fully silent")
+	}
+}
+
+func TestDetectionResultFullySilentWithinWindow(t *testing.T) {
+	result := DetectionResult{
+		WindowStart:   10,
+		InputDuration: 5,
+		Intervals:     []SilenceInterval{{Start: 10, End: 15, Duration: 5}},
+	}
+
+	if !result.FullySilent(1e-6) {
+		t.Fatalf("expected window to be fully silent")
+	}
+}
+
+func TestParseSilenceOutputClampsNegativeTimestamps(t *testing.T) {
+	output := `
+[silencedetect @ 0x123] silence_start: -0.011610
+[silencedetect @ 0x123] silence_end: 2.000000 | silence_duration: 2.011610
+[silencedetect @ 0x123] silence_start: 5.000000
+[silencedetect @ 0x123] silence_end: 6.500000 | silence_duration: 1.500000
+`
+
+	intervals, _, err := parseSilenceOutput(output)
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+
+	assertIntervals(t, intervals, []SilenceInterval{
+		{Start: 0, End: 2, Duration: 2.01161},
+		{Start: 5, End: 6.5, Duration: 1.5},
+	})
+}
+
+func TestParseSilenceOutputClampsNegativeTrailingStart(t *testing.T) {
+	output := `
+[silencedetect @ 0x123] silence_start: -0.020000
+frame=   10 fps=0.0 q=-0.0 size=       0kB time=00:00:00.50 bitrate=   0.0kbits/s speed=1x
+`
+
+	intervals, _, err := parseSilenceOutput(output)
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+
+	assertIntervals(t, intervals, []SilenceInterval{
+		{Start: 0, End: 0.5, Duration: 0.52},
+	})
+}
+
+func TestParseSilenceOutputClampsNegativeEnd(t *testing.T) {
+	output := `
+[silencedetect @ 0x123] silence_start: -0.500000
+[silencedetect @ 0x123] silence_end: -0.100000 | silence_duration: 0.400000
+`
+
+	intervals, _, err := parseSilenceOutput(output)
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+
+	assertIntervals(t, intervals, []SilenceInterval{
+		{Start: 0, End: 0, Duration: 0.4},
+	})
+}
+
+func TestDetectSilencePassesHeadersForRemoteInputs(t *testing.T) {
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		capturedArgs = append([]string(nil), args...)
+		return []byte(statsOutput), nil
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Cdn-Token", "abc")
+	headers.Set("Authorization", "Bearer secret")
+
+	d := NewDetector(WithCommandRunner(runner))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, InputHeaders: headers}
+
+	if _, err := d.DetectSilence(context.Background(), "https://cdn.example.com/a.mp4", options); err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	expectedArgs := []string{
+		"-hide_banner", "-nostdin",
+		"-headers", "Authorization: Bearer secret\r\nX-Cdn-Token: abc\r\n",
+		"-i", "https://cdn.example.com/a.mp4", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1", "-f", "null", "-",
+	}
+	if strings.Join(capturedArgs, "|") != strings.Join(expectedArgs, "|") {
+		t.Fatalf("unexpected args: got %q want %q", capturedArgs, expectedArgs)
+	}
+
+	if _, err := d.DetectSilence(context.Background(), "local.mp4", options); err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if capturedArgs[2] != "-i" {
+		t.Fatalf("expected no headers for local input, got %q", capturedArgs)
+	}
+}
+
+func TestDetectSilenceRejectsHeaderInjection(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatalf("runner should not be called")
+		return nil, nil
+	}))
+
+	headers := http.Header{"X-Token": []string{"abc\r\nX-Injected: 1"}}
+	_, err := d.DetectSilence(context.Background(), "https://cdn.example.com/a.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		InputHeaders:       headers,
+	})
+	if err == nil || !strings.Contains(err.Error(), "X-Token") {
+		t.Fatalf("expected header validation error, got %v", err)
+	}
+}
+
+func TestDetectSilenceReadsFromStdin(t *testing.T) {
+	stdin := strings.NewReader("RIFF....WAVE")
+
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if CommandStdin(ctx) != stdin {
+			t.Errorf("expected runner to receive the stdin reader")
+		}
+		return []byte("frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:04.00 bitrate=   0.0kbits/s speed=1x\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "-", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		Stdin:              stdin,
+		InputFormat:        "s16le",
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("expected ffprobe to be skipped for stdin, got calls %q", calls)
+	}
+	if want := "ffmpeg -hide_banner -nostdin -f s16le -i pipe:0 -vn -sn -dn -af silencedetect=noise=-30dB:d=1 -f null -"; calls[0] != want {
+		t.Fatalf("unexpected command: got %q want %q", calls[0], want)
+	}
+	assertFloatEqual(t, result.InputDuration, 4)
+}
+
+func TestDetectSilenceSkipsProbeForHLSPlaylists(t *testing.T) {
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name)
+		return []byte("[silencedetect @ 0x1] silence_start: 3\nsize=N/A time=00:00:06.00 bitrate=N/A\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "https://cdn.example.com/live/index.m3u8?token=abc", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 1 || calls[0] != "ffmpeg" {
+		t.Fatalf("expected ffprobe to be skipped for HLS playlists, got calls %q", calls)
+	}
+	// The duration, and the end of the trailing silence, come from ffmpeg's progress.
+	assertFloatEqual(t, result.InputDuration, 6)
+	assertIntervals(t, result.Intervals, []Interval{{Start: 3, End: 6, Duration: 3}})
+}
+
+func TestDetectSilenceKnownDuration(t *testing.T) {
+	var calls []string
+	progress := "00:00:09.98"
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return []byte("[silencedetect @ 0x1] silence_start: 6\nsize=N/A time=" + progress + " bitrate=N/A\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, StartOffset: 2, KnownDuration: 12}
+	result, err := d.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 1 || !strings.HasPrefix(calls[0], "ffmpeg ") {
+		t.Fatalf("expected ffprobe to be skipped for a known duration, got calls %q", calls)
+	}
+	if strings.Contains(calls[0], "-nostats") {
+		t.Fatalf("expected the progress line to be kept to check the known duration, got %q", calls[0])
+	}
+	// The trailing silence ends at the known duration rather than at the last progress update.
+	assertFloatEqual(t, result.InputDuration, 10)
+	assertIntervals(t, result.Intervals, []Interval{{Start: 8, End: 12, Duration: 4}})
+	if len(result.Warnings) != 0 {
+		t.Fatalf("expected no warnings, got %+v", result.Warnings)
+	}
+
+	progress = "00:00:11.00"
+	result, err = d.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != WarningDurationMismatch {
+		t.Fatalf("expected a duration mismatch warning, got %+v", result.Warnings)
+	}
+	assertFloatEqual(t, result.InputDuration, 10)
+
+	if err := (DetectionOptions{MinSilenceDuration: 1, KnownDuration: -1}).Validate(); err == nil {
+		t.Fatal("expected a negative known duration to be rejected")
+	}
+}
+
+func TestDetectSilenceLive(t *testing.T) {
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return []byte("[silencedetect @ 0x1] silence_start: 0\nsize=N/A time=00:00:30.00 bitrate=N/A\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, AnalyzeDuration: 30, Live: true}
+	result, err := d.DetectSilence(context.Background(), "rtmp://live.example.com/app/key", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 1 || !strings.HasPrefix(calls[0], "ffmpeg ") || !strings.Contains(calls[0], "-t 30") {
+		t.Fatalf("expected a single ffmpeg run bounded by the window, got calls %q", calls)
+	}
+	if !result.FullySilent(0.001) {
+		t.Fatalf("expected the window to be fully silent, got %+v", result)
+	}
+
+	for _, invalid := range []DetectionOptions{{StartOffset: 5}, {AdaptiveThreshold: true}} {
+		invalid.NoiseLevel, invalid.MinSilenceDuration, invalid.Live = -30, 1, true
+		if _, err := d.DetectSilence(context.Background(), "rtmp://live.example.com/app/key", invalid); err == nil {
+			t.Fatalf("expected %+v to be rejected for a live input", invalid)
+		}
+	}
+}
+
+func TestDetectSilenceValidatesStdinOptions(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatalf("runner should not be called")
+		return nil, nil
+	}))
+
+	if _, err := d.DetectSilence(context.Background(), "-", DetectionOptions{MinSilenceDuration: 1}); err == nil {
+		t.Fatalf("expected error when reading stdin without a reader")
+	}
+
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1, InputFormat: "-y"})
+	if err == nil || !strings.Contains(err.Error(), "input format") {
+		t.Fatalf("expected input format error, got %v", err)
+	}
+}
+
+func TestDetectSilenceFromReader(t *testing.T) {
+	media := strings.NewReader("fake wav bytes")
+
+	var (
+		args     []string
+		attached io.Reader
+	)
+	runner := func(ctx context.Context, name string, a ...string) (io.ReadCloser, func() error, error) {
+		args = a
+		attached = CommandStdin(ctx)
+		output := "[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 2 | silence_duration: 1\n"
+		return io.NopCloser(strings.NewReader(output)), func() error { return nil }, nil
+	}
+
+	d := NewDetector(WithStreamingRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilenceFromReader(context.Background(), media, "wav", DetectionOptions{
+		NoiseLevel:         -40,
+		MinSilenceDuration: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilenceFromReader returned error: %v", err)
+	}
+
+	if attached != media {
+		t.Fatalf("expected the reader to be attached to stdin")
+	}
+	if want := "-hide_banner -nostdin -f wav -i pipe:0 -vn -sn -dn -af silencedetect=noise=-40dB:d=0.5 -f null -"; strings.Join(args, " ") != want {
+		t.Fatalf("unexpected args: got %q want %q", strings.Join(args, " "), want)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 2, Duration: 1}})
+
+	if _, err := d.DetectSilenceFromReader(context.Background(), nil, "wav", DetectionOptions{MinSilenceDuration: 1}); err == nil {
+		t.Fatalf("expected error for nil reader")
+	}
+}
+
+func TestDetectorIsSafeForConcurrentUse(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		switch {
+		case name == "ffprobe":
+			return []byte(`{"format":{"duration":"15.0"}}`), nil
+		case len(args) == 1 && args[0] == "-version":
+			return []byte("ffmpeg version 7.0 Copyright\n"), nil
+		case len(args) == 2 && args[1] == "-filters":
+			return []byte(fakeFiltersOutput), nil
+		}
+		return []byte(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(streamFixture)), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	d.lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	const workers = 16
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := d.Check(context.Background()); err != nil {
+				errs <- err
+				return
+			}
+
+			result, err := d.DetectSilence(context.Background(), fmt.Sprintf("video-%d.mp4", i), DetectionOptions{
+				NoiseLevel:         -30,
+				MinSilenceDuration: 0.5,
+				MergeGap:           0.1,
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.InputDuration != 15 || len(result.Intervals) != 3 {
+				errs <- fmt.Errorf("unexpected result %+v", result)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDetectSilenceGlobalArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		ffprobe    string
+		probeFails bool
+		raw        bool
+		stats      StatsMode
+		wantPrefix string
+	}{
+		{name: "auto without ffprobe keeps stats", wantPrefix: "-hide_banner -nostdin -i"},
+		{name: "auto with probed duration drops stats", ffprobe: "ffprobe", wantPrefix: "-hide_banner -nostdin -nostats -i"},
+		{name: "auto keeps stats when probing fails", ffprobe: "ffprobe", probeFails: true, wantPrefix: "-hide_banner -nostdin -i"},
+		{name: "keep", ffprobe: "ffprobe", stats: StatsKeep, wantPrefix: "-hide_banner -nostdin -i"},
+		{name: "drop", stats: StatsDrop, wantPrefix: "-hide_banner -nostdin -nostats -i"},
+		{name: "raw output", ffprobe: "ffprobe", raw: true, stats: StatsDrop, wantPrefix: "-i"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ffmpegArgs []string
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if name == "ffprobe" {
+					if tt.probeFails {
+						return nil, errors.New("exit status 1")
+					}
+					return []byte(`{"format": {"duration": "10.0"}}`), nil
+				}
+				ffmpegArgs = args
+				return []byte("size=N/A time=00:00:10.00 bitrate=N/A speed=1x"), nil
+			}
+
+			opts := []Option{WithCommandRunner(runner), WithFFprobePath(tt.ffprobe)}
+			if tt.raw {
+				opts = append(opts, WithRawFFmpegOutput())
+			}
+			d := NewDetector(opts...)
+			_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+				NoiseLevel:         -30,
+				MinSilenceDuration: 1,
+				Stats:              tt.stats,
+			})
+			if err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+
+			if got := strings.Join(ffmpegArgs, " "); !strings.HasPrefix(got, tt.wantPrefix+" video.mp4 ") {
+				t.Fatalf("unexpected args %q, want prefix %q", got, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestDetectSilencePassesExtraArgs(t *testing.T) {
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		capturedArgs = append([]string(nil), args...)
+		return []byte("size=N/A time=00:00:10.00 bitrate=N/A speed=1x"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "broken.ts", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		StartOffset:        5,
+		ExtraInputArgs:     []string{"-analyzeduration", "100M", "-probesize", "50M"},
+		ExtraOutputArgs:    []string{"-metadata", "title=a b; rm -rf /"},
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	expectedArgs := []string{
+		"-hide_banner", "-nostdin", "-ss", "5",
+		"-analyzeduration", "100M", "-probesize", "50M",
+		"-i", "broken.ts", "-vn", "-sn", "-dn", "-af", "silencedetect=noise=-30dB:d=1",
+		"-metadata", "title=a b; rm -rf /",
+		"-f", "null", "-",
+	}
+	if strings.Join(capturedArgs, "|") != strings.Join(expectedArgs, "|") {
+		t.Fatalf("unexpected args: got %q want %q", capturedArgs, expectedArgs)
+	}
+}
+
+func TestSilenceArgsAudioOnlyAndFormatConversion(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DetectionOptions
+		want    string
+	}{
+		{
+			name:    "audio only by default",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1},
+			want:    "-i video.mp4 -vn -sn -dn -af silencedetect=noise=-30dB:d=1 -f null -",
+		},
+		{
+			name:    "all streams",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, DecodeAllStreams: true},
+			want:    "-i video.mp4 -af silencedetect=noise=-30dB:d=1 -f null -",
+		},
+		{
+			name:    "downmix and resample before the filter",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, DownmixMono: true, SampleRate: 16000, AudioStreamIndex: intPtr(1)},
+			want:    "-i video.mp4 -map 0:a:1 -vn -sn -dn -af aformat=sample_rates=16000:channel_layouts=mono,silencedetect=noise=-30dB:d=1 -f null -",
+		},
+		{
+			name:    "noise ratio",
+			options: DetectionOptions{NoiseRatio: float64Ptr(0.001), MinSilenceDuration: 1},
+			want:    "-i video.mp4 -vn -sn -dn -af silencedetect=noise=0.001:d=1 -f null -",
+		},
+		{
+			name:    "resample per channel",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1, SampleRate: 8000, PerChannel: true},
+			want:    "-i video.mp4 -vn -sn -dn -af aformat=sample_rates=8000,silencedetect=noise=-30dB:d=1:mono=1 -f null -",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(silenceArgs("video.mp4", tt.options), " "); got != tt.want {
+				t.Fatalf("unexpected args:\ngot  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSilenceRejectsDownmixPerChannel(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg must not run")
+		return nil, nil
+	}))
+
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		PerChannel:         true,
+		DownmixMono:        true,
+	})
+	if err == nil {
+		t.Fatal("expected an error for per-channel detection with downmixing")
+	}
+}
+
+func TestBuildArgsMatchesExecutedArgs(t *testing.T) {
+	index := 1
+	options := DetectionOptions{
+		NoiseLevel:         -35,
+		MinSilenceDuration: 0.75,
+		PerChannel:         true,
+		StartOffset:        5,
+		AnalyzeDuration:    60,
+		AudioStreamIndex:   &index,
+		SampleRate:         16000,
+		ExtraInputArgs:     []string{"-fflags", "+genpts"},
+		ExtraOutputArgs:    []string{"-threads", "2"},
+	}
+	output := "size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"
+
+	tests := []struct {
+		name   string
+		option func(captured *[]string) Option
+	}{
+		{
+			name: "command runner",
+			option: func(captured *[]string) Option {
+				return WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					*captured = append([]string(nil), args...)
+					return []byte(output), nil
+				})
+			},
+		},
+		{
+			name: "streaming runner",
+			option: func(captured *[]string) Option {
+				return WithStreamingRunner(func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+					*captured = append([]string(nil), args...)
+					return io.NopCloser(strings.NewReader(output)), func() error { return nil }, nil
+				})
+			},
+		},
+		{
+			name: "split streaming runner",
+			option: func(captured *[]string) Option {
+				return WithSplitStreamingRunner(func(ctx context.Context, name string, args ...string) (io.ReadCloser, io.ReadCloser, func() error, error) {
+					*captured = append([]string(nil), args...)
+					return io.NopCloser(strings.NewReader("progress=end\n")), io.NopCloser(strings.NewReader(output)), func() error { return nil }, nil
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured []string
+			d := NewDetector(tt.option(&captured))
+
+			built, err := d.BuildArgs("video.mp4", options)
+			if err != nil {
+				t.Fatalf("BuildArgs returned error: %v", err)
+			}
+			if _, err := d.DetectSilence(context.Background(), "video.mp4", options); err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+
+			if strings.Join(built, " ") != strings.Join(captured, " ") {
+				t.Fatalf("BuildArgs returned %v, but ffmpeg ran with %v", built, captured)
+			}
+		})
+	}
+}
+
+func TestBuildArgsValidatesNoiseRatio(t *testing.T) {
+	d := NewDetector()
+
+	for _, ratio := range []float64{0, -0.5, 1.5, math.NaN()} {
+		if _, err := d.BuildArgs("video.mp4", DetectionOptions{NoiseRatio: float64Ptr(ratio), MinSilenceDuration: 1}); err == nil {
+			t.Fatalf("expected an error for noise ratio %v", ratio)
+		}
+	}
+	if _, err := d.BuildArgs("video.mp4", DetectionOptions{NoiseLevel: -30, NoiseRatio: float64Ptr(0.01), MinSilenceDuration: 1}); err == nil {
+		t.Fatal("expected an error for both a noise level and a noise ratio")
+	}
+	if _, err := d.BuildArgs("video.mp4", DetectionOptions{NoiseRatio: float64Ptr(0.01), MinSilenceDuration: 1, AdaptiveThreshold: true}); err == nil {
+		t.Fatal("expected an error for an adaptive threshold with a noise ratio")
+	}
+
+	args, err := d.BuildArgs("video.mp4", DetectionOptions{NoiseRatio: float64Ptr(1), MinSilenceDuration: 1})
+	if err != nil {
+		t.Fatalf("BuildArgs returned error: %v", err)
+	}
+	if !slices.Contains(args, "silencedetect=noise=1:d=1") {
+		t.Fatalf("expected the ratio without a dB suffix, got %v", args)
+	}
+}
+
+func TestDetectionOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DetectionOptions
+		want    []string
+	}{
+		{
+			name:    "valid",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5},
+		},
+		{
+			name:    "zero threshold",
+			options: DetectionOptions{NoiseLevel: 0, MinSilenceDuration: 0.5},
+		},
+		{
+			name:    "positive noise level",
+			options: DetectionOptions{NoiseLevel: 30, MinSilenceDuration: 0.5},
+			want:    []string{"noise level must be at most 0dB, got 30dB"},
+		},
+		{
+			name:    "non-finite noise level",
+			options: DetectionOptions{NoiseLevel: math.Inf(-1), MinSilenceDuration: 0.5},
+			want:    []string{"noise level must be finite"},
+		},
+		{
+			name:    "NaN minimum silence duration",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: math.NaN()},
+			want:    []string{"minimum silence duration must be finite"},
+		},
+		{
+			name:    "infinite minimum silence duration",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: math.Inf(1)},
+			want:    []string{"minimum silence duration must be finite"},
+		},
+		{
+			name:    "non-finite merge gap",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, MergeGap: math.Inf(1)},
+			want:    []string{"merge gap must be finite"},
+		},
+		{
+			name:    "non-finite start offset",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, StartOffset: math.NaN()},
+			want:    []string{"start offset must be finite"},
+		},
+		{
+			name:    "non-finite analyze duration",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, AnalyzeDuration: math.Inf(1)},
+			want:    []string{"analyze duration must be finite"},
+		},
+		{
+			name:    "non-finite adaptive offset",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, AdaptiveThreshold: true, AdaptiveOffsetDB: math.NaN()},
+			want:    []string{"adaptive offset must be finite"},
+		},
+		{
+			name:    "NaN hysteresis",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, HysteresisDB: math.NaN()},
+			want:    []string{"hysteresis must be a finite, non-negative number of dB"},
+		},
+		{
+			name:    "infinite known duration",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, KnownDuration: math.Inf(1)},
+			want:    []string{"known duration must be finite"},
+		},
+		{
+			name:    "NaN known duration",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, KnownDuration: math.NaN()},
+			want:    []string{"known duration must be finite"},
+		},
+		{
+			name:    "negative hysteresis",
+			options: DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, HysteresisDB: -5},
+			want:    []string{"hysteresis must be a finite, non-negative number of dB"},
+		},
+		{
+			name:    "hysteresis above 0 dB",
+			options: DetectionOptions{NoiseLevel: -3, MinSilenceDuration: 0.5, HysteresisDB: 5},
+			want:    []string{"hysteresis of 5dB raises the closing threshold above 0dB"},
+		},
+		{
+			name:    "every problem",
+			options: DetectionOptions{NoiseLevel: 30, NoiseRatio: float64Ptr(2), StartOffset: 5, Live: true, DownmixMono: true, PerChannel: true},
+			want: []string{
+				"minimum silence duration must be greater than zero",
+				"noise level must be at most 0dB",
+				"noise level and noise ratio both set the noise threshold",
+				"noise ratio must be greater than zero and at most 1",
+				"live inputs cannot be sought or read twice",
+				"per-channel detection cannot be combined with downmixing to mono",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate returned error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			problems := strings.Split(err.Error(), "\n")
+			if len(problems) != len(tt.want) {
+				t.Fatalf("expected %d problems, got %q", len(tt.want), problems)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("problem %d: got %q, want prefix %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildArgsValidatesOptions(t *testing.T) {
+	d := NewDetector()
+
+	if _, err := d.BuildArgs("video.mp4", DetectionOptions{NoiseLevel: -30}); err == nil {
+		t.Fatal("expected an error for a zero minimum silence duration")
+	}
+	if _, err := d.BuildArgs("", DetectionOptions{MinSilenceDuration: 1}); err == nil {
+		t.Fatal("expected an error for an empty input path")
+	}
+	if _, err := d.BuildArgs("-", DetectionOptions{MinSilenceDuration: 1}); err == nil {
+		t.Fatal("expected an error for stdin input without DetectionOptions.Stdin")
+	}
+
+	args, err := d.BuildArgs("-", DetectionOptions{MinSilenceDuration: 1, Stdin: strings.NewReader("")})
+	if err != nil {
+		t.Fatalf("BuildArgs returned error: %v", err)
+	}
+	if !strings.Contains(strings.Join(args, " "), " -i pipe:0 ") {
+		t.Fatalf("expected stdin to be read from pipe:0, got %v", args)
+	}
+}
+
+func TestDetectSilenceCapturesRawOutput(t *testing.T) {
+	output := "[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 2 | silence_duration: 1\nsize=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(output), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.RawOutput != "" {
+		t.Fatalf("expected no raw output by default, got %q", result.RawOutput)
+	}
+
+	result, err = d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1, CaptureRawOutput: true, StartOffset: 5})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.RawOutput != output {
+		t.Fatalf("unexpected raw output %q", result.RawOutput)
+	}
+}
+
+func TestDetectSilenceCapsRawOutputWhileStreaming(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	lines := 3 * MaxRawOutputBytes / len(line)
+	runner := func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			for range lines {
+				io.WriteString(pw, line)
+			}
+			io.WriteString(pw, "size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n")
+			pw.Close()
+		}()
+		return pr, func() error { return nil }, nil
+	}
+	d := NewDetector(WithStreamingRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1, CaptureRawOutput: true})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(result.RawOutput) > MaxRawOutputBytes+len("...") {
+		t.Fatalf("raw output of %d bytes exceeds the cap", len(result.RawOutput))
+	}
+	if !strings.HasPrefix(result.RawOutput, "...x") || !strings.HasSuffix(result.RawOutput, "speed=1x\n") {
+		t.Fatalf("expected the truncated tail starting at a line boundary, got %q...%q", result.RawOutput[:10], result.RawOutput[len(result.RawOutput)-10:])
+	}
+}
+
+func TestOutputCaptureStaysBounded(t *testing.T) {
+	capture := outputCapture{limit: 1024}
+	for i := range 10000 {
+		capture.writeLine(fmt.Sprintf("line %d", i))
+		if cap(capture.buf) > 4*capture.limit {
+			t.Fatalf("buffer grew to %d bytes", cap(capture.buf))
+		}
+	}
+	if got := capture.String(); !strings.HasSuffix(got, "line 9999\n") || len(got) > capture.limit+3 {
+		t.Fatalf("unexpected capture %q", got)
+	}
+}
+
+func TestDetectSilenceMeasuresVolumeInTheSamePass(t *testing.T) {
+	output := `[silencedetect @ 0x1] silence_start: 1
+[silencedetect @ 0x1] silence_end: 2 | silence_duration: 1
+size=N/A time=00:00:10.00 bitrate=N/A speed=1x
+[Parsed_volumedetect_1 @ 0x2] n_samples: 441000
+[Parsed_volumedetect_1 @ 0x2] mean_volume: -27.3 dB
+[Parsed_volumedetect_1 @ 0x2] max_volume: -4.1 dB
+[Parsed_volumedetect_1 @ 0x2] histogram_4db: 12
+`
+	var runs int
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		runs++
+		capturedArgs = append([]string(nil), args...)
+		return []byte(output), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		SampleRate:         16000,
+		MeasureVolume:      true,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if runs != 1 {
+		t.Fatalf("expected a single ffmpeg run, got %d", runs)
+	}
+	if !strings.Contains(strings.Join(capturedArgs, " "), " -af aformat=sample_rates=16000,silencedetect=noise=-30dB:d=1,volumedetect -f null -") {
+		t.Fatalf("expected volumedetect chained after silencedetect, got %v", capturedArgs)
+	}
+	if result.MeanVolumeDB == nil || result.MaxVolumeDB == nil {
+		t.Fatalf("expected volume measurements, got mean=%v max=%v", result.MeanVolumeDB, result.MaxVolumeDB)
+	}
+	assertFloatEqual(t, *result.MeanVolumeDB, -27.3)
+	assertFloatEqual(t, *result.MaxVolumeDB, -4.1)
+	if len(result.Intervals) != 1 {
+		t.Fatalf("expected the silence interval to be parsed as well, got %v", result.Intervals)
+	}
+}
+
+func TestDetectSilenceIgnoresNonFiniteVolume(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("[Parsed_volumedetect_1 @ 0x2] mean_volume: -inf dB\n[Parsed_volumedetect_1 @ 0x2] max_volume: -inf dB\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1, MeasureVolume: true})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.MeanVolumeDB != nil || result.MaxVolumeDB != nil {
+		t.Fatalf("expected no volume for -inf dB, got mean=%v max=%v", result.MeanVolumeDB, result.MaxVolumeDB)
+	}
+}
+
+func TestDetectSilenceStopsFFmpegAtMaxIntervals(t *testing.T) {
+	var output strings.Builder
+	for i := range 5 {
+		fmt.Fprintf(&output, `[silencedetect @ 0x1] silence_start: %d\n[silencedetect @ 0x1] silence_end: %d.001 | silence_duration: 0.001\n`, i, i)
+	}
+	ffmpeg := writeFakeFFmpeg(t, output.String())
+	d := NewDetector(WithFFmpegPath(ffmpeg), WithFFprobePath(""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	result, err := d.DetectSilence(ctx, "corrupt.mp4", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.001, MaxIntervals: 3})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected ffmpeg to be killed at the maximum interval count, took %s", elapsed)
+	}
+	if !result.Truncated || len(result.Intervals) != 3 {
+		t.Fatalf("expected 3 intervals of a truncated result, got %d (truncated=%v)", len(result.Intervals), result.Truncated)
+	}
+}
+
+func TestDetectSilenceTruncatesBufferedOutput(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(`[silencedetect @ 0x1] silence_start: 0
+[silencedetect @ 0x1] silence_end: 1 | silence_duration: 1
+[silencedetect @ 0x1] silence_start: 1
+[silencedetect @ 0x1] silence_end: 2 | silence_duration: 1
+size=N/A time=00:00:02.00 bitrate=N/A
+`), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 0.5, MaxIntervals: 1})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if !result.Truncated || len(result.Intervals) != 1 {
+		t.Fatalf("expected a single interval of a truncated result, got %v (truncated=%v)", result.Intervals, result.Truncated)
+	}
+	if result.FullySilent(floatTolerance) {
+		t.Fatal("a truncated result must not be reported as fully silent")
+	}
+
+	result, err = d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 0.5, MaxIntervals: 2})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if result.Truncated {
+		t.Fatal("expected a result at the limit not to be truncated")
+	}
+
+	if _, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 0.5, MaxIntervals: -1}); err == nil {
+		t.Fatal("expected a negative MaxIntervals to be rejected")
+	}
+}
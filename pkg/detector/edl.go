@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// defaultReelName is the reel of EDL events when EDLOptions.ReelName is empty; AX conventionally marks an auxiliary
+// source.
+const defaultReelName = "AX"
+
+// maxEDLEvents is the number of events a CMX3600 EDL can number.
+const maxEDLEvents = 999
+
+// EDLOptions configures WriteEDL.
+type EDLOptions struct {
+	// Title is the TITLE line of the EDL. "silence" is used when it is empty.
+	Title string
+	// ReelName is the source reel of every event, at most 8 characters without spaces. "AX" is used when it is empty.
+	ReelName string
+	// ClipName names the events "ClipName N" in their FROM CLIP NAME comments. "silence" is used when it is empty.
+	ClipName string
+	// DropFrame writes drop-frame timecode. It requires an NTSC frame rate, 29.97 or 59.94.
+	DropFrame bool
+}
+
+// WriteEDL writes intervals as a CMX3600 edit decision list at fps frames per second. Each interval becomes a cut on
+// the audio track, in start order, whose source timecodes are the interval's start and end rounded to the nearest
+// frame. The events are assembled back to back on the record side from 00:00:00:00, so an EDL of the regions
+// returned by DetectionResult.NonSilentIntervals is the input with its silences cut out. Measured levels follow an
+// event in a COMMENT line. Intervals shorter than a frame are left out.
+//
+// WriteEDL returns an error without writing anything if fps is not positive and finite, if DropFrame is set at a
+// rate other than 29.97 or 59.94, if the reel name is invalid, or if there are more than 999 events.
+func WriteEDL(w io.Writer, intervals []SilenceInterval, fps float64, opts EDLOptions) error {
+	if err := checkTimecodeRate(fps, opts.DropFrame); err != nil {
+		return err
+	}
+	reel := opts.ReelName
+	if reel == "" {
+		reel = defaultReelName
+	}
+	if len(reel) > 8 || strings.ContainsAny(reel, " \t\r\n") {
+		return errors.New("EDL reel names must be at most 8 characters without spaces")
+	}
+	title := opts.Title
+	if title == "" {
+		title = "silence"
+	}
+	clip := opts.ClipName
+	if clip == "" {
+		clip = "silence"
+	}
+
+	var b strings.Builder
+	fcm := "NON-DROP FRAME"
+	if opts.DropFrame {
+		fcm = "DROP FRAME"
+	}
+	fmt.Fprintf(&b, "TITLE: %s\nFCM: %s\n", strings.TrimSpace(title), fcm)
+
+	timecode := func(frames int64) string {
+		return formatTimecode(frames, fps, opts.DropFrame)
+	}
+	var event, record int64
+	for _, interval := range sortedIntervals(intervals) {
+		in := int64(math.Round(math.Max(interval.Start, 0) * fps))
+		out := int64(math.Round(math.Max(interval.End, 0) * fps))
+		if out <= in {
+			continue
+		}
+		event++
+		if event > maxEDLEvents {
+			return fmt.Errorf("a CMX3600 EDL holds at most %d events", maxEDLEvents)
+		}
+		fmt.Fprintf(&b, "\n%03d  %-8s A     C        %s %s %s %s\n", event, reel, timecode(in), timecode(out), timecode(record), timecode(record+out-in))
+		fmt.Fprintf(&b, "* FROM CLIP NAME: %s %d\n", clip, event)
+		if levels := levelSummary(interval); levels != "" {
+			fmt.Fprintf(&b, "* COMMENT: %s\n", levels)
+		}
+		record += out - in
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
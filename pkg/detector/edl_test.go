@@ -0,0 +1,100 @@
+package detector
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with testdata/name, rewriting the file instead with -update.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match:\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteEDL(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 4000,
+		Intervals: []SilenceInterval{
+			{Start: 3599.5, End: 3601, Duration: 1.5},
+			{Start: 0, End: 2.5, Duration: 2.5},
+			// Shorter than a frame, so it is left out.
+			{Start: 10, End: 10.01, Duration: 0.01},
+			{Start: 59.9, End: 60.2, Duration: 0.3},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		intervals []SilenceInterval
+		fps       float64
+		opts      EDLOptions
+	}{
+		{name: "silence_25.edl", intervals: result.Intervals, fps: 25},
+		{name: "silence_2997_ndf.edl", intervals: result.Intervals, fps: 29.97, opts: EDLOptions{Title: "interview", ReelName: "TAPE01"}},
+		{name: "silence_2997_df.edl", intervals: result.Intervals, fps: 29.97, opts: EDLOptions{Title: "interview", ReelName: "TAPE01", DropFrame: true}},
+		{name: "audio_25.edl", intervals: result.NonSilentIntervals(), fps: 25, opts: EDLOptions{ClipName: "audio"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteEDL(&buf, tt.intervals, tt.fps, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestWriteEDLRejectsInvalidOptions(t *testing.T) {
+	intervals := []SilenceInterval{{Start: 0, End: 1, Duration: 1}}
+	tests := []struct {
+		name string
+		fps  float64
+		opts EDLOptions
+		want string
+	}{
+		{name: "zero fps", fps: 0, want: "invalid frame rate"},
+		{name: "drop frame at 25", fps: 25, opts: EDLOptions{DropFrame: true}, want: "drop-frame timecode requires"},
+		{name: "long reel", fps: 25, opts: EDLOptions{ReelName: "REEL-0001"}, want: "reel names"},
+		{name: "reel with spaces", fps: 25, opts: EDLOptions{ReelName: "A B"}, want: "reel names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteEDL(&buf, intervals, tt.fps, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("expected nothing to be written, got %q", buf.String())
+			}
+		})
+	}
+
+	many := make([]SilenceInterval, maxEDLEvents+1)
+	for i := range many {
+		many[i] = SilenceInterval{Start: float64(i), End: float64(i) + 0.5, Duration: 0.5}
+	}
+	var buf bytes.Buffer
+	if err := WriteEDL(&buf, many, 25, EDLOptions{}); err == nil || buf.Len() != 0 {
+		t.Fatalf("expected too many events to be rejected, got %v", err)
+	}
+}
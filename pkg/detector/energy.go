@@ -0,0 +1,230 @@
+package detector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinEnergyWindow is the length, in seconds, that an energy timeline window must exceed. Shorter windows hold too
+// few samples for a meaningful RMS level and make ffmpeg print a line per handful of samples.
+const MinEnergyWindow = 0.01
+
+// energySampleRate is the rate the audio is resampled to for an energy timeline, so that every window holds the
+// same whole number of samples whatever the input's rate.
+const energySampleRate = 48000
+
+// energyKey is the ametadata key holding the RMS level that astats sets for every window.
+const energyKey = "lavfi.astats.Overall.RMS_level"
+
+// EnergySample is the RMS level of one window of an energy timeline.
+type EnergySample struct {
+	// Time is the start of the window in seconds.
+	Time float64 `json:"time"`
+	// RMSdB is the RMS level of the window in dBFS, across all channels. It is nil when the window is digital
+	// silence, for which ffmpeg reports -inf.
+	RMSdB *float64 `json:"rms_db"`
+}
+
+// EnergyTimeline measures the RMS level of consecutive windows of window seconds of the audio of inputPath, for
+// plotting a coarse loudness timeline. It returns a sample per window, in time order, together with the input
+// duration, which is zero when neither ffprobe nor ffmpeg's progress reported one. The last window may be shorter
+// than the others.
+//
+// ffmpeg's output is parsed as it arrives, so that long inputs do not have their filter output buffered in memory;
+// detectors created with WithCommandRunner buffer it. window must exceed MinEnergyWindow, and standard input is not
+// supported.
+func (d *Detector) EnergyTimeline(ctx context.Context, inputPath string, window float64) ([]EnergySample, float64, error) {
+	if err := validateEnergyWindow(inputPath, window); err != nil {
+		return nil, 0, err
+	}
+
+	var knownDuration float64
+	if d.probesDuration(inputPath, DetectionOptions{}) {
+		if duration, err := d.probeDuration(ctx, inputPath, DetectionOptions{}); err == nil {
+			knownDuration = duration
+		}
+	}
+
+	args := d.energyArgs(inputPath, window, knownDuration > 0)
+	d.debug(ctx, "running ffmpeg", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	parser := &energyParser{}
+	var err error
+	if d.stream != nil {
+		err = d.streamEnergy(ctx, args, parser)
+	} else {
+		var output []byte
+		output, err = d.run(ctx, d.ffmpegPath, args...)
+		if err != nil {
+			err = newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
+		} else {
+			for _, line := range strings.Split(outputLineEndings.Replace(string(output)), "\n") {
+				if err = parser.parseLine(line); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	d.debug(ctx, "ffmpeg finished", "elapsed", time.Since(start), "energy_samples", len(parser.samples))
+
+	duration := knownDuration
+	if duration <= 0 {
+		duration = parser.lastProgress
+	}
+	return parser.samples, duration, nil
+}
+
+// BuildEnergyTimelineArgs returns the ffmpeg arguments EnergyTimeline would run for inputPath and window, after the
+// same validation, assuming ffprobe cannot determine the duration. The ffmpeg binary itself is not included.
+func (d *Detector) BuildEnergyTimelineArgs(inputPath string, window float64) ([]string, error) {
+	if err := validateEnergyWindow(inputPath, window); err != nil {
+		return nil, err
+	}
+	return d.energyArgs(inputPath, window, false), nil
+}
+
+// validateEnergyWindow checks the input and window of an energy timeline before ffmpeg runs.
+func validateEnergyWindow(inputPath string, window float64) error {
+	if inputPath == "" {
+		return errors.New("input path is required")
+	}
+	if isPipeInput(inputPath) {
+		return errors.New("energy timelines do not support reading from stdin")
+	}
+	if math.IsNaN(window) || math.IsInf(window, 0) || window <= MinEnergyWindow {
+		return fmt.Errorf("energy timeline window must be longer than %gs, got %g", MinEnergyWindow, window)
+	}
+	return nil
+}
+
+// energyArgs constructs the ffmpeg arguments of an energy timeline run. The audio is cut into frames of one window
+// each, astats measures every frame on its own, and ametadata prints the RMS level with the frame's time. Unless
+// durationKnown, ffmpeg's progress line is kept to determine the duration.
+func (d *Detector) energyArgs(inputPath string, window float64, durationKnown bool) []string {
+	samples := max(int(math.Round(window*energySampleRate)), 1)
+	filter := fmt.Sprintf("aresample=%d,asetnsamples=n=%d:p=0,astats=metadata=1:reset=1,ametadata=print:key=%s", energySampleRate, samples, energyKey)
+
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin")
+		if durationKnown {
+			args = append(args, "-nostats")
+		}
+	}
+	return append(args, filterArgs(inputPath, DetectionOptions{}, filter)...)
+}
+
+// streamEnergy executes ffmpeg through the StreamingRunner and parses its output as it arrives.
+func (d *Detector) streamEnergy(ctx context.Context, args []string, parser *energyParser) error {
+	reader, wait, err := d.stream(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return newFFmpegError(ctx, d.ffmpegPath, args, err, "")
+	}
+
+	tail, parseErr, scanErr := scanEnergyOutput(reader, parser)
+
+	reader.Close()
+	waitErr := wait()
+
+	if waitErr != nil {
+		return newFFmpegError(ctx, d.ffmpegPath, args, waitErr, strings.Join(tail, "\n"))
+	}
+	if parseErr != nil {
+		return parseErr
+	}
+	if scanErr != nil {
+		return fmt.Errorf("read ffmpeg output: %w", scanErr)
+	}
+	return nil
+}
+
+// scanEnergyOutput feeds ffmpeg output from reader to parser line by line, stopping at the first parse error. Like
+// scanSilenceOutput, it returns the last few non-empty lines for error messages along with any parse and read
+// errors.
+func scanEnergyOutput(reader io.Reader, parser *energyParser) (tail []string, parseErr, scanErr error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLineLength)
+	scanner.Split(scanOutputLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) != "" {
+			if len(tail) == outputTailLines {
+				tail = tail[1:]
+			}
+			tail = append(tail, line)
+		}
+
+		if err := parser.parseLine(line); err != nil {
+			parseErr = err
+			break
+		}
+	}
+	return tail, parseErr, scanner.Err()
+}
+
+var (
+	energyTimePattern  = regexp.MustCompile(`pts_time:\s*(` + ffmpegNumber + `)`)
+	energyLevelPattern = regexp.MustCompile(regexp.QuoteMeta(energyKey) + `=(\S+)`)
+)
+
+// energyParser collects the samples ametadata prints, a "frame:N pts:N pts_time:T" line followed by the RMS level
+// of that frame.
+type energyParser struct {
+	samples []EnergySample
+	// frameTime is the time of the frame whose level is expected next, if any.
+	frameTime *float64
+	// lastProgress is the furthest time reported by ffmpeg's progress line.
+	lastProgress float64
+}
+
+// parseLine processes a single line of ffmpeg output.
+func (p *energyParser) parseLine(line string) error {
+	if matches := energyTimePattern.FindStringSubmatch(line); len(matches) == 2 {
+		start, err := parseFFmpegNumber(matches[1], "energy window time")
+		if err != nil {
+			return err
+		}
+		p.frameTime = &start
+		return nil
+	}
+
+	if matches := energyLevelPattern.FindStringSubmatch(line); len(matches) == 2 {
+		if p.frameTime == nil {
+			return fmt.Errorf("ffmpeg reported an RMS level without a time: %q", strings.TrimSpace(line))
+		}
+		// ParseFloat accepts the "-inf" astats reports for digital silence.
+		level, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid RMS level %q in ffmpeg output", matches[1])
+		}
+		sample := EnergySample{Time: *p.frameTime}
+		if !math.IsInf(level, 0) && !math.IsNaN(level) {
+			sample.RMSdB = &level
+		}
+		p.frameTime = nil
+		p.samples = append(p.samples, sample)
+		return nil
+	}
+
+	for _, match := range progressTimePattern.FindAllStringSubmatch(line, -1) {
+		progress, err := parseProgressTime(match)
+		if err != nil {
+			return err
+		}
+		p.lastProgress = max(p.lastProgress, progress)
+	}
+	return nil
+}
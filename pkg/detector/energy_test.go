@@ -0,0 +1,104 @@
+package detector
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+const energyOutput = "[Parsed_ametadata_3 @ 0x1] frame:0    pts:0       pts_time:0\n" +
+	"[Parsed_ametadata_3 @ 0x1] lavfi.astats.Overall.RMS_level=-20.5\n" +
+	"[Parsed_ametadata_3 @ 0x1] frame:1    pts:24000   pts_time:0.5\n" +
+	"[Parsed_ametadata_3 @ 0x1] lavfi.astats.Overall.RMS_level=-inf\n" +
+	"size=N/A time=00:00:00.80 bitrate=N/A speed=1x\n" +
+	"[Parsed_ametadata_3 @ 0x1] frame:2    pts:48000   pts_time:1\n" +
+	"[Parsed_ametadata_3 @ 0x1] lavfi.astats.Overall.RMS_level=-33.25\n"
+
+func assertEnergySamples(t *testing.T, samples []EnergySample) {
+	t.Helper()
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %+v", samples)
+	}
+	for i, want := range []float64{0, 0.5, 1} {
+		assertFloatEqual(t, samples[i].Time, want)
+	}
+	if samples[0].RMSdB == nil || samples[1].RMSdB != nil || samples[2].RMSdB == nil {
+		t.Fatalf("expected only the second window to be digital silence, got %+v", samples)
+	}
+	assertFloatEqual(t, *samples[0].RMSdB, -20.5)
+	assertFloatEqual(t, *samples[2].RMSdB, -33.25)
+}
+
+func TestEnergyTimeline(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(energyOutput), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath(""))
+
+	samples, duration, err := d.EnergyTimeline(context.Background(), "audio.wav", 0.5)
+	if err != nil {
+		t.Fatalf("EnergyTimeline returned error: %v", err)
+	}
+	assertEnergySamples(t, samples)
+	assertFloatEqual(t, duration, 0.8)
+
+	filter := argAfter(gotArgs, "-af")
+	if !strings.Contains(filter, "asetnsamples=n=24000:p=0") || !strings.Contains(filter, "astats=metadata=1:reset=1") {
+		t.Errorf("unexpected filter %q", filter)
+	}
+}
+
+func TestEnergyTimelineStreamsOutput(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+		return io.NopCloser(strings.NewReader(energyOutput)), func() error { return nil }, nil
+	}
+	d := NewDetector(WithStreamingRunner(runner), WithFFprobePath(""))
+
+	samples, _, err := d.EnergyTimeline(context.Background(), "audio.wav", 0.5)
+	if err != nil {
+		t.Fatalf("EnergyTimeline returned error: %v", err)
+	}
+	assertEnergySamples(t, samples)
+}
+
+func TestEnergyTimelineRejectsLevelWithoutTime(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("[Parsed_ametadata_3 @ 0x1] lavfi.astats.Overall.RMS_level=-20.5\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath(""))
+
+	if _, _, err := d.EnergyTimeline(context.Background(), "audio.wav", 0.5); err == nil {
+		t.Fatal("expected an error for a level without a time")
+	}
+}
+
+func TestBuildEnergyTimelineArgsValidatesWindow(t *testing.T) {
+	d := NewDetector()
+	for _, tc := range []struct {
+		name   string
+		input  string
+		window float64
+	}{
+		{name: "missing input", window: 0.5},
+		{name: "stdin", input: "-", window: 0.5},
+		{name: "too short", input: "audio.wav", window: MinEnergyWindow},
+		{name: "zero", input: "audio.wav"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := d.BuildEnergyTimelineArgs(tc.input, tc.window); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	args, err := d.BuildEnergyTimelineArgs("audio.wav", 0.02)
+	if err != nil {
+		t.Fatalf("BuildEnergyTimelineArgs returned error: %v", err)
+	}
+	if filter := argAfter(args, "-af"); !strings.Contains(filter, "asetnsamples=n=960:") {
+		t.Errorf("expected 960-sample windows, got %q", filter)
+	}
+}
@@ -0,0 +1,218 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrStreamNotFound is returned when the requested audio stream does not exist in the input.
+var ErrStreamNotFound = errors.New("audio stream not found")
+
+// ErrLanguageNotFound is returned when no audio stream is tagged with DetectionOptions.AudioLanguage.
+var ErrLanguageNotFound = errors.New("no audio stream with the requested language")
+
+// ErrNoAudioStream is returned when the input does not contain any audio stream to analyse.
+var ErrNoAudioStream = errors.New("input has no audio stream")
+
+// ErrNoVideoStream is returned by DetectBlack when the input does not contain any video stream to analyse.
+var ErrNoVideoStream = errors.New("input has no video stream")
+
+// ErrProtocolUnsupported is returned when ffmpeg cannot read the input URL because its protocol is unavailable.
+var ErrProtocolUnsupported = errors.New("ffmpeg does not support the input protocol")
+
+// ErrInputUnreachable is returned when ffmpeg cannot connect to a network input, such as a live stream whose server
+// refuses the connection or answers with an HTTP error.
+var ErrInputUnreachable = errors.New("input is unreachable")
+
+// ErrFFmpegNotFound is returned when the ffmpeg binary cannot be found or executed.
+var ErrFFmpegNotFound = errors.New("ffmpeg binary not found")
+
+// ErrFilterUnavailable is returned when the ffmpeg build lacks the silencedetect filter.
+var ErrFilterUnavailable = errors.New("ffmpeg silencedetect filter unavailable")
+
+// ErrCodecParameters is returned when ffmpeg cannot determine the codec parameters of an input stream, typically
+// because the stream starts late and lies beyond ffmpeg's probing limits.
+var ErrCodecParameters = errors.New("ffmpeg could not find codec parameters")
+
+// ErrNativeUnsupported is returned when DetectionOptions.Engine selects the native engine for an input other than a
+// local WAV file holding PCM or float samples. In auto mode such inputs are analysed with ffmpeg instead.
+var ErrNativeUnsupported = errors.New("native engine cannot read the input")
+
+// ErrTimeout is returned when ffmpeg is stopped because the context deadline passed.
+var ErrTimeout = errors.New("ffmpeg timed out")
+
+// ErrCanceled is returned when ffmpeg is stopped because the context was cancelled.
+var ErrCanceled = errors.New("ffmpeg was cancelled")
+
+// ErrUnrecognizedOutput is returned when ffmpeg succeeded but printed no silencedetect, progress or stream listing
+// line, so the absence of silence intervals cannot be trusted. It is matched by *UnrecognizedOutputError.
+var ErrUnrecognizedOutput = errors.New("ffmpeg output was not recognised")
+
+// ErrPartialResult is wrapped, together with the ffmpeg failure, by the error DetectSilence returns alongside a
+// partial result when DetectionOptions.AllowPartial is set and ffmpeg failed partway.
+var ErrPartialResult = errors.New("ffmpeg failed partway; the result covers only part of the input")
+
+// ErrNothingToKeep is returned by DetectionResult.RemovalFilter when the whole input is silent, so removing the
+// silence would leave nothing.
+var ErrNothingToKeep = errors.New("input is entirely silent; nothing to keep")
+
+// failurePatterns maps ffmpeg diagnostics to the sentinel errors that classify them.
+var failurePatterns = []struct {
+	substring string
+	kind      error
+}{
+	{substring: "matches no streams", kind: ErrStreamNotFound},
+	{substring: "does not contain any stream", kind: ErrNoAudioStream},
+	{substring: "Could not find codec parameters", kind: ErrCodecParameters},
+	{substring: "Protocol not found", kind: ErrProtocolUnsupported},
+	{substring: "protocol not found", kind: ErrProtocolUnsupported},
+	{substring: "not on whitelist", kind: ErrProtocolUnsupported},
+	{substring: "Connection refused", kind: ErrInputUnreachable},
+	{substring: "Connection timed out", kind: ErrInputUnreachable},
+	{substring: "Network is unreachable", kind: ErrInputUnreachable},
+	{substring: "Failed to resolve hostname", kind: ErrInputUnreachable},
+	{substring: "Cannot open connection", kind: ErrInputUnreachable},
+	{substring: "Server returned", kind: ErrInputUnreachable},
+}
+
+// maxErrorOutputBytes bounds the amount of ffmpeg output retained in an FFmpegError.
+const maxErrorOutputBytes = 8 * 1024
+
+// FFmpegError describes a failed ffmpeg invocation.
+//
+// Recognised failures additionally match a sentinel such as ErrStreamNotFound via errors.Is. Failures caused by the
+// context exceeding its deadline match ErrTimeout and context.DeadlineExceeded, and those caused by cancellation
+// match ErrCanceled and context.Canceled.
+type FFmpegError struct {
+	// Path is the ffmpeg binary that was executed.
+	Path string
+	// Args are the arguments passed to ffmpeg.
+	Args []string
+	// ExitCode is the process exit code, or -1 when the process did not exit normally.
+	ExitCode int
+	// Stderr holds the tail of ffmpeg's output, truncated to the last few kilobytes.
+	Stderr string
+	// Err is the underlying execution error.
+	Err error
+
+	kind   error
+	detail string
+}
+
+func (e *FFmpegError) Error() string {
+	var msg string
+	if e.kind != nil {
+		msg = fmt.Sprintf("%v: %s", e.kind, e.detail)
+	} else {
+		msg = fmt.Sprintf("ffmpeg execution failed: %v", e.Err)
+		if e.Stderr != "" {
+			msg += ": " + e.Stderr
+		}
+	}
+	return msg
+}
+
+// Unwrap exposes the underlying error and, when recognised, the sentinel classifying the failure.
+func (e *FFmpegError) Unwrap() []error {
+	if e.kind != nil {
+		return []error{e.kind, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// newFFmpegError classifies a failed ffmpeg run using the context state and the output it produced.
+func newFFmpegError(ctx context.Context, path string, args []string, err error, output string) *FFmpegError {
+	ffErr := &FFmpegError{
+		Path:     path,
+		Args:     append([]string(nil), args...),
+		ExitCode: -1,
+		Stderr:   truncateOutput(strings.TrimSpace(output), maxErrorOutputBytes),
+		Err:      err,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		ffErr.ExitCode = exitErr.ExitCode()
+	}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		ffErr.kind = ErrFFmpegNotFound
+		ffErr.detail = fmt.Sprintf("%q", path)
+		return ffErr
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		ffErr.Err = fmt.Errorf("%w (%v)", ctxErr, err)
+		ffErr.kind = ErrCanceled
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			ffErr.kind = ErrTimeout
+		}
+		ffErr.detail = "process stopped"
+		if deadline, ok := ctx.Deadline(); ok && ffErr.kind == ErrTimeout {
+			ffErr.detail = fmt.Sprintf("process stopped at deadline %s", deadline.Format(time.RFC3339))
+		}
+		return ffErr
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		for _, pattern := range failurePatterns {
+			if strings.Contains(line, pattern.substring) {
+				ffErr.kind = pattern.kind
+				ffErr.detail = strings.TrimSpace(line)
+				return ffErr
+			}
+		}
+	}
+
+	return ffErr
+}
+
+// StreamError reports the failure to analyse one audio stream in DetectSilenceAllStreams.
+type StreamError struct {
+	Stream StreamInfo
+	Err    error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("audio stream %d: %v", e.Stream.Index, e.Err)
+}
+
+// Unwrap exposes the error of the stream's DetectSilence run.
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// truncateOutput keeps at most limit bytes from the end of output, starting at a line boundary when possible.
+func truncateOutput(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+
+	tail := output[len(output)-limit:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return "..." + tail
+}
+
+// UnrecognizedOutputError is returned when ffmpeg's output contains nothing the detector recognises, typically because
+// its format changed or a -loglevel in DetectionOptions.ExtraInputArgs silenced it. It matches ErrUnrecognizedOutput.
+type UnrecognizedOutputError struct {
+	// Snippet holds the first lines of the output, or is empty when ffmpeg printed nothing.
+	Snippet string
+}
+
+func (e *UnrecognizedOutputError) Error() string {
+	if e.Snippet == "" {
+		return fmt.Sprintf("%v: ffmpeg printed nothing", ErrUnrecognizedOutput)
+	}
+	return fmt.Sprintf("%v: %s", ErrUnrecognizedOutput, e.Snippet)
+}
+
+func (e *UnrecognizedOutputError) Unwrap() error {
+	return ErrUnrecognizedOutput
+}
@@ -0,0 +1,250 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectSilenceReturnsFFmpegError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "sh", "-c", "echo 'video.mp4: Invalid data found when processing input' >&2; exit 3").CombinedOutput()
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) {
+		t.Fatalf("expected FFmpegError, got %T: %v", err, err)
+	}
+	if ffErr.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d", ffErr.ExitCode)
+	}
+	if ffErr.Path != "ffmpeg" || ffErr.Args[3] != "video.mp4" {
+		t.Fatalf("unexpected command recorded: %s %v", ffErr.Path, ffErr.Args)
+	}
+	if !strings.Contains(ffErr.Stderr, "Invalid data found") {
+		t.Fatalf("expected stderr to be captured, got %q", ffErr.Stderr)
+	}
+}
+
+func TestDetectSilenceClassifiesContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, errors.New("signal: killed")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(ctx, "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if !errors.Is(err, ErrTimeout) || errors.Is(err, ErrCanceled) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "ffmpeg timed out: process stopped at deadline") {
+		t.Fatalf("expected a timeout message, got %q", err.Error())
+	}
+
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) || ffErr.ExitCode != -1 {
+		t.Fatalf("expected FFmpegError without exit code, got %v", err)
+	}
+}
+
+func TestDetectSilenceClassifiesCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("video.mp4: Invalid data found when processing input"), errors.New("signal: killed")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(ctx, "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrCanceled and context.Canceled, got %v", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Fatalf("cancellation must not be reported as a timeout: %v", err)
+	}
+	if err.Error() != "ffmpeg was cancelled: process stopped" {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+func TestTruncateOutputKeepsTail(t *testing.T) {
+	output := strings.Repeat("progress line\n", 1000) + "final error"
+
+	truncated := truncateOutput(output, 64)
+	if len(truncated) > 67 {
+		t.Fatalf("expected output to be truncated, got %d bytes", len(truncated))
+	}
+	if !strings.HasPrefix(truncated, "...") || !strings.HasSuffix(truncated, "final error") {
+		t.Fatalf("unexpected truncated output %q", truncated)
+	}
+
+	if got := truncateOutput("short", 64); got != "short" {
+		t.Fatalf("expected short output to be unchanged, got %q", got)
+	}
+}
+
+func TestDetectSilenceReportsMissingAudio(t *testing.T) {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'video-only.mp4':
+  Stream #0:0(und): Video: h264 (High) (avc1 / 0x31637661), yuv420p, 1920x1080, 30 fps
+Output #0, null, to 'pipe:':
+Output file #0 does not contain any stream
+`
+
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(output), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "video-only.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+
+	if !errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("expected ErrNoAudioStream, got %v", err)
+	}
+	if errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("did not expect ErrStreamNotFound, got %v", err)
+	}
+
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) {
+		t.Fatalf("expected FFmpegError, got %T", err)
+	}
+}
+
+func TestDetectSilenceReportsUnsupportedProtocol(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("https://example.com/a.mp4: Protocol not found\nDid you mean file:https://example.com/a.mp4?\n"), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "https://example.com/a.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if !errors.Is(err, ErrProtocolUnsupported) {
+		t.Fatalf("expected ErrProtocolUnsupported, got %v", err)
+	}
+}
+
+func TestDetectSilenceReportsUnreachableInput(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("[tcp @ 0x1] Connection to tcp://live.example.com:1935 failed: Connection refused\nrtmp://live.example.com/app/key: Connection refused\n"), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "rtmp://live.example.com/app/key", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		Live:               true,
+	})
+	if !errors.Is(err, ErrInputUnreachable) {
+		t.Fatalf("expected ErrInputUnreachable, got %v", err)
+	}
+}
+
+func TestDetectSilenceRetriesWithLargerProbesize(t *testing.T) {
+	var calls [][]string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string(nil), args...))
+		if len(calls) == 1 {
+			return []byte("[mpegts @ 0x1] Could not find codec parameters for stream 1 (Audio: aac, 0 channels): unspecified sample format"), errors.New("exit status 1")
+		}
+		return []byte("[silencedetect @ 0x1] silence_start: 0\n[silencedetect @ 0x1] silence_end: 2 | silence_duration: 2\nsize=N/A time=00:00:10.00 bitrate=N/A speed=1x"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "capture.ts", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		ExtraInputArgs:     []string{"-fflags", "+genpts"},
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected two attempts, got %d", len(calls))
+	}
+	if want := "-hide_banner -nostdin -fflags +genpts -i capture.ts"; !strings.HasPrefix(strings.Join(calls[0], " "), want) {
+		t.Fatalf("unexpected first attempt %q", calls[0])
+	}
+	if want := "-hide_banner -nostdin -analyzeduration 2147483647 -probesize 2147483647 -fflags +genpts -i capture.ts"; !strings.HasPrefix(strings.Join(calls[1], " "), want) {
+		t.Fatalf("unexpected retry %q", calls[1])
+	}
+	if !result.ProbeRetried {
+		t.Fatal("expected the result to record the probe retry")
+	}
+	if len(result.Intervals) != 1 {
+		t.Fatalf("expected the retry's intervals, got %v", result.Intervals)
+	}
+}
+
+func TestDetectSilenceProbeRetryCanBeDisabled(t *testing.T) {
+	calls := 0
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls++
+		return []byte("[mpegts @ 0x1] Could not find codec parameters for stream 1 (Audio: aac, 0 channels): unspecified sample format"), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "capture.ts", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		DisableProbeRetry:  true,
+	})
+
+	if !errors.Is(err, ErrCodecParameters) {
+		t.Fatalf("expected ErrCodecParameters, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestDetectSilenceProbeRetryRespectsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls++
+		cancel()
+		return []byte("[mpegts @ 0x1] Could not find codec parameters for stream 1 (Audio: aac, 0 channels): unspecified sample format"), errors.New("exit status 1")
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	_, err := d.DetectSilence(ctx, "capture.ts", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1})
+
+	if err == nil || calls != 1 {
+		t.Fatalf("expected no retry after cancellation, got %d calls and error %v", calls, err)
+	}
+}
@@ -0,0 +1,409 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryBackoff is the delay before the first download retry when DownloadOptions.RetryBackoff is zero.
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// maxRetryBackoff caps the exponential delay between download retries.
+const maxRetryBackoff = 30 * time.Second
+
+// DownloadOptions configures FetchRemoteInput.
+type DownloadOptions struct {
+	// Client is the HTTP client used for requests. When nil, a client without a per-request timeout is used so
+	// that large downloads are bounded only by the context and Timeout.
+	Client *http.Client
+	// Retries is the number of additional attempts made after a transient failure such as a network error or a
+	// 5xx response.
+	Retries int
+	// RetryBackoff is the delay before the first retry; it doubles after every subsequent failure. Zero uses a
+	// default of 500ms.
+	RetryBackoff time.Duration
+	// Timeout bounds the whole download including retries. Zero means the download is bounded only by the
+	// context.
+	Timeout time.Duration
+	// Headers are added to every request, including range requests made when resuming. Header values are never
+	// included in errors.
+	Headers http.Header
+	// MaxBytes aborts the download with ErrDownloadTooLarge once the file would exceed this many bytes. Zero
+	// means unlimited.
+	MaxBytes int64
+	// AllowManifests downloads HLS playlists and DASH manifests like any other file. By default they fail with
+	// ErrManifest, because their segment URIs are usually relative to the manifest URL and do not resolve against
+	// the temporary file.
+	AllowManifests bool
+	// Recorder, when set, is told about the bytes received.
+	Recorder Recorder
+	// TracerProvider, when set, records a span for the download with the URL (redacted), the bytes received and,
+	// on failure, the last HTTP status and the number of attempts.
+	TracerProvider TracerProvider
+}
+
+// ErrDownloadTooLarge is returned when a remote input exceeds DownloadOptions.MaxBytes.
+var ErrDownloadTooLarge = errors.New("remote input exceeds maximum download size")
+
+// ErrManifest is returned when a remote input is an HLS playlist or DASH manifest, recognised by its extension or by
+// the Content-Type of the response, and DownloadOptions.AllowManifests is not set. Such inputs should be passed to
+// ffmpeg by URL instead.
+var ErrManifest = errors.New("remote input is a streaming manifest")
+
+// ErrObjectNotFound matches a *DownloadError whose server responded 404 Not Found.
+var ErrObjectNotFound = errors.New("remote object not found")
+
+// RemoteFile describes a remote input that has been downloaded to a temporary file.
+type RemoteFile struct {
+	// Path is the location of the temporary file. The caller is responsible for removing it.
+	Path string
+	// Bytes is the size of the downloaded file.
+	Bytes int64
+	// FinalURL is the URL the content was served from after following redirects.
+	FinalURL string
+}
+
+// Remove deletes the temporary file.
+func (f RemoteFile) Remove() error {
+	return os.Remove(f.Path)
+}
+
+// DownloadError describes a failed remote download.
+type DownloadError struct {
+	// URL is the requested URL.
+	URL string
+	// FinalURL is the URL of the last response after following redirects, if any response was received.
+	FinalURL string
+	// StatusCode is the HTTP status code of the last response, or 0 when no response was received.
+	StatusCode int
+	// Status is the HTTP status line of the last response.
+	Status string
+	// Bytes is the number of bytes received before giving up.
+	Bytes int64
+	// Attempts is the number of requests made.
+	Attempts int
+	// Err is the underlying error, if the failure was not an unexpected HTTP status.
+	Err error
+}
+
+// Error describes the failure with the query parameters and passwords of the URLs redacted, since presigned and
+// signed CDN URLs carry credentials there.
+func (e *DownloadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "download %s", redactURL(e.URL))
+	if e.FinalURL != "" && e.FinalURL != e.URL {
+		fmt.Fprintf(&b, " (redirected to %s)", redactURL(e.FinalURL))
+	}
+	fmt.Fprintf(&b, " failed after %d attempt(s)", e.Attempts)
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %s", RedactText(e.Err.Error()))
+	} else if e.Status != "" {
+		fmt.Fprintf(&b, ": unexpected HTTP status %s", e.Status)
+	}
+	if e.Bytes > 0 {
+		fmt.Fprintf(&b, " (%d bytes received)", e.Bytes)
+	}
+	return b.String()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the failure is classified by target, such as ErrObjectNotFound.
+func (e *DownloadError) Is(target error) bool {
+	return target == ErrObjectNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// FetchRemoteInput downloads rawURL to a temporary file.
+//
+// Transient failures are retried with exponential backoff. When the server advertises byte-range support, retries
+// resume from the bytes already received instead of starting over. Failures are reported as a *DownloadError; on
+// failure no temporary file is left behind.
+func FetchRemoteInput(ctx context.Context, rawURL string, options DownloadOptions) (RemoteFile, error) {
+	ctx, span := startSpan(ctx, providerTracer(options.TracerProvider), spanDownload)
+	defer span.End()
+	span.SetAttributes(slog.String("url.full", redactURL(rawURL)))
+
+	file, err := fetchRemoteInput(ctx, rawURL, options)
+	if err != nil {
+		var downloadErr *DownloadError
+		if errors.As(err, &downloadErr) {
+			span.SetAttributes(
+				slog.Int64("silence_detector.download.bytes", downloadErr.Bytes),
+				slog.Int("silence_detector.download.attempts", downloadErr.Attempts),
+			)
+			if downloadErr.StatusCode != 0 {
+				span.SetAttributes(slog.Int("http.response.status_code", downloadErr.StatusCode))
+			}
+		}
+		recordSpanError(span, err)
+		return file, err
+	}
+	span.SetAttributes(slog.Int64("silence_detector.download.bytes", file.Bytes))
+	return file, nil
+}
+
+func fetchRemoteInput(ctx context.Context, rawURL string, options DownloadOptions) (RemoteFile, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return RemoteFile{}, fmt.Errorf("invalid URL: %w", err)
+	}
+	if options.Retries < 0 {
+		return RemoteFile{}, errors.New("download retries must not be negative")
+	}
+	if options.MaxBytes < 0 {
+		return RemoteFile{}, errors.New("maximum download size must not be negative")
+	}
+	if err := validateHeaders(options.Headers); err != nil {
+		return RemoteFile{}, err
+	}
+	if isManifest(rawURL) && !options.AllowManifests {
+		return RemoteFile{}, fmt.Errorf("%w: %s", ErrManifest, rawURL)
+	}
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	client := options.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	backoff := options.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	tmpFile, err := os.CreateTemp("", "silence-detector-*"+filepath.Ext(parsed.Path))
+	if err != nil {
+		return RemoteFile{}, err
+	}
+
+	state := &downloadState{
+		client:   client,
+		url:      rawURL,
+		header:   options.Headers,
+		file:     tmpFile,
+		maxBytes: options.MaxBytes,
+
+		allowManifests: options.AllowManifests,
+		recorder:       options.Recorder,
+	}
+	for attempt := 1; ; attempt++ {
+		retry, err := state.fetch(ctx)
+		if err == nil {
+			break
+		}
+
+		if attempt > options.Retries || !retry || ctx.Err() != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return RemoteFile{}, state.error(attempt, err)
+		}
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return RemoteFile{}, state.error(attempt, err)
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return RemoteFile{}, err
+	}
+
+	return RemoteFile{Path: tmpFile.Name(), Bytes: state.written, FinalURL: state.finalURL}, nil
+}
+
+// errUnexpectedStatus marks an attempt that failed because of the response status rather than an I/O error.
+var errUnexpectedStatus = errors.New("unexpected HTTP status")
+
+// downloadState tracks a download across retry attempts.
+type downloadState struct {
+	client   *http.Client
+	url      string
+	header   http.Header
+	file     *os.File
+	maxBytes int64
+
+	allowManifests bool
+	recorder       Recorder
+
+	written    int64
+	resumable  bool
+	finalURL   string
+	statusCode int
+	status     string
+}
+
+// fetch performs a single request, appending to the file when the server honours a range request. It reports
+// whether a failure is worth retrying.
+func (s *downloadState) fetch(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	for name, values := range s.header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resuming := s.written > 0 && s.resumable
+	if resuming {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", s.written))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	s.finalURL = resp.Request.URL.String()
+	s.statusCode = resp.StatusCode
+	s.status = resp.Status
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && resuming:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != s.written {
+			// The server answered with a range we did not ask for; start over without resuming.
+			s.resumable = false
+			return true, fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		if err := s.reset(); err != nil {
+			return false, err
+		}
+	default:
+		return retryableStatus(resp.StatusCode), errUnexpectedStatus
+	}
+
+	if !s.allowManifests && manifestContentType(resp.Header.Get("Content-Type")) {
+		return false, ErrManifest
+	}
+
+	s.resumable = resp.StatusCode == http.StatusPartialContent || resp.Header.Get("Accept-Ranges") == "bytes"
+
+	body := io.Reader(resp.Body)
+	if s.maxBytes > 0 {
+		if resp.ContentLength >= 0 && s.written+resp.ContentLength > s.maxBytes {
+			return false, s.tooLarge(s.written + resp.ContentLength)
+		}
+		// Read one byte past the limit so that an oversized body without a Content-Length is detected.
+		body = io.LimitReader(resp.Body, s.maxBytes-s.written+1)
+	}
+
+	n, err := io.Copy(s.file, body)
+	s.written += n
+	if s.recorder != nil {
+		s.recorder.Downloaded(n)
+	}
+	if s.maxBytes > 0 && s.written > s.maxBytes {
+		return false, s.tooLarge(s.written)
+	}
+	if err != nil {
+		return true, err
+	}
+
+	return false, nil
+}
+
+func (s *downloadState) tooLarge(seen int64) error {
+	return fmt.Errorf("%w: limit is %d bytes, saw at least %d", ErrDownloadTooLarge, s.maxBytes, seen)
+}
+
+// reset discards any partially downloaded content.
+func (s *downloadState) reset() error {
+	if s.written == 0 {
+		return nil
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.written = 0
+	return nil
+}
+
+func (s *downloadState) error(attempts int, err error) *DownloadError {
+	downloadErr := &DownloadError{
+		URL:        s.url,
+		FinalURL:   s.finalURL,
+		StatusCode: s.statusCode,
+		Status:     s.status,
+		Bytes:      s.written,
+		Attempts:   attempts,
+	}
+	if !errors.Is(err, errUnexpectedStatus) {
+		downloadErr.Err = err
+	}
+	return downloadErr
+}
+
+// manifestContentType reports whether a Content-Type header names an HLS playlist or DASH manifest.
+func manifestContentType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/vnd.apple.mpegurl", "application/x-mpegurl", "audio/mpegurl", "audio/x-mpegurl", "application/dash+xml":
+		return true
+	}
+	return false
+}
+
+// retryableStatus reports whether a response status indicates a transient server-side failure.
+func retryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// contentRangeStart extracts the first byte position from a "bytes start-end/size" Content-Range header.
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,383 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const fetchPayload = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func readRemoteFile(t *testing.T, file RemoteFile) string {
+	t.Helper()
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		t.Fatalf("read downloaded file: %v", err)
+	}
+	return string(data)
+}
+
+func TestFetchRemoteInputFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old.mp4", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new.mp4", http.StatusFound)
+	})
+	mux.HandleFunc("/new.mp4", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fetchPayload)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/old.mp4", DownloadOptions{})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer file.Remove()
+
+	if got := readRemoteFile(t, file); got != fetchPayload {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if file.Bytes != int64(len(fetchPayload)) {
+		t.Fatalf("expected %d bytes, got %d", len(fetchPayload), file.Bytes)
+	}
+	if file.FinalURL != server.URL+"/new.mp4" {
+		t.Fatalf("unexpected final URL %q", file.FinalURL)
+	}
+	if !strings.HasSuffix(file.Path, ".mp4") {
+		t.Fatalf("expected temporary file to keep the extension, got %q", file.Path)
+	}
+}
+
+func TestFetchRemoteInputRetriesTransientFailures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, fetchPayload)
+	}))
+	defer server.Close()
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer file.Remove()
+
+	if got := readRemoteFile(t, file); got != fetchPayload {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestFetchRemoteInputResumesWithRange(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("Accept-Ranges", "bytes")
+
+		if r.Header.Get("Range") == "" {
+			// Promise the whole payload but drop the connection halfway through.
+			w.Header().Set("Content-Length", fmt.Sprint(len(fetchPayload)))
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, fetchPayload[:10])
+			return
+		}
+
+		var start int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(fetchPayload)-1, len(fetchPayload)))
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, fetchPayload[start:])
+	}))
+	defer server.Close()
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer file.Remove()
+
+	if got := readRemoteFile(t, file); got != fetchPayload {
+		t.Fatalf("unexpected content %q", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ranges) != 2 || ranges[0] != "" || ranges[1] != "bytes=10-" {
+		t.Fatalf("unexpected Range headers %q", ranges)
+	}
+}
+
+func TestFetchRemoteInputReportsStatus(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := FetchRemoteInput(context.Background(), server.URL+"/missing.mp4", DownloadOptions{
+		Retries:      3,
+		RetryBackoff: time.Millisecond,
+	})
+
+	var downloadErr *DownloadError
+	if !errors.As(err, &downloadErr) {
+		t.Fatalf("expected DownloadError, got %v", err)
+	}
+	if downloadErr.StatusCode != http.StatusNotFound || downloadErr.Attempts != 1 {
+		t.Fatalf("unexpected error details: %+v", downloadErr)
+	}
+	if !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "/missing.mp4") {
+		t.Fatalf("expected status and URL in error, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("expected a 404 not to be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestFetchRemoteInputHonorsTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	_, err := FetchRemoteInput(context.Background(), server.URL+"/slow.mp4", DownloadOptions{
+		Retries: 5,
+		Timeout: 50 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+func TestFetchRemoteInputEnforcesMaxBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "content length",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", fmt.Sprint(len(fetchPayload)))
+				fmt.Fprint(w, fetchPayload)
+			},
+		},
+		{
+			name: "chunked",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, fetchPayload[:10])
+				w.(http.Flusher).Flush()
+				fmt.Fprint(w, fetchPayload[10:])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			tmpDir := t.TempDir()
+			t.Setenv("TMPDIR", tmpDir)
+
+			_, err := FetchRemoteInput(context.Background(), server.URL+"/big.mp4", DownloadOptions{
+				Retries:  2,
+				MaxBytes: 16,
+			})
+			if !errors.Is(err, ErrDownloadTooLarge) {
+				t.Fatalf("expected ErrDownloadTooLarge, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "16 bytes") {
+				t.Fatalf("expected limit in error, got %v", err)
+			}
+
+			if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+				t.Fatalf("expected temporary file to be removed, found %v", entries)
+			}
+		})
+	}
+}
+
+func TestFetchRemoteInputWithinMaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fetchPayload)
+	}))
+	defer server.Close()
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{
+		MaxBytes: int64(len(fetchPayload)),
+	})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer file.Remove()
+
+	if got := readRemoteFile(t, file); got != fetchPayload {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func TestFetchRemoteInputSendsHeadersOnEveryRequest(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		auths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auths = append(auths, r.Header.Get("Authorization")+"|"+r.Header.Get("X-Cdn-Token"))
+		mu.Unlock()
+
+		w.Header().Set("Accept-Ranges", "bytes")
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("Content-Length", fmt.Sprint(len(fetchPayload)))
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, fetchPayload[:10])
+			return
+		}
+
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(fetchPayload)-1, len(fetchPayload)))
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, fetchPayload[10:])
+	}))
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret")
+	headers.Set("X-Cdn-Token", "abc")
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+		Headers:      headers,
+	})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer file.Remove()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(auths) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(auths))
+	}
+	for _, got := range auths {
+		if got != "Bearer secret|abc" {
+			t.Fatalf("expected headers on every request, got %q", got)
+		}
+	}
+}
+
+func TestFetchRemoteInputErrorsOmitHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret")
+
+	_, err := FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{Headers: headers})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected 403 error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected error to omit header values, got %v", err)
+	}
+
+	headers.Set("Authorization", "Bearer secret\r\nX-Injected: 1")
+	_, err = FetchRemoteInput(context.Background(), server.URL+"/a.mp4", DownloadOptions{Headers: headers})
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected validation error without header value, got %v", err)
+	}
+}
+
+func TestFetchRemoteInputRejectsManifests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/live" {
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl; charset=utf-8")
+		}
+		fmt.Fprint(w, "#EXTM3U\n")
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/live/index.m3u8?token=abc", "/vod/manifest.MPD"} {
+		if _, err := FetchRemoteInput(context.Background(), server.URL+path, DownloadOptions{}); !errors.Is(err, ErrManifest) {
+			t.Fatalf("expected ErrManifest for %s, got %v", path, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("expected manifests with an extension to be rejected without a request, got %d", n)
+	}
+
+	// Without an extension, the manifest is recognised by its Content-Type and no file is left behind.
+	_, err := FetchRemoteInput(context.Background(), server.URL+"/live", DownloadOptions{Retries: 2})
+	if !errors.Is(err, ErrManifest) {
+		t.Fatalf("expected ErrManifest, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("expected a single request, got %d", n)
+	}
+
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/live/index.m3u8", DownloadOptions{AllowManifests: true})
+	if err != nil {
+		t.Fatalf("expected the download to be allowed, got %v", err)
+	}
+	defer file.Remove()
+	if got := readRemoteFile(t, file); got != "#EXTM3U\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func TestDownloadErrorMatchesObjectNotFound(t *testing.T) {
+	if !errors.Is(&DownloadError{StatusCode: http.StatusNotFound}, ErrObjectNotFound) {
+		t.Fatalf("expected a 404 to match ErrObjectNotFound")
+	}
+	if errors.Is(&DownloadError{StatusCode: http.StatusForbidden}, ErrObjectNotFound) {
+		t.Fatalf("expected a 403 not to match ErrObjectNotFound")
+	}
+}
+
+func TestDownloadErrorRedactsURLs(t *testing.T) {
+	err := &DownloadError{
+		URL:      "https://bucket.s3.amazonaws.com/a.wav?X-Amz-Signature=SIG123",
+		FinalURL: "https://cdn.example.com/a.wav?token=TOKEN456",
+		Attempts: 1,
+		Err:      &url.Error{Op: "Get", URL: "https://cdn.example.com/a.wav?token=TOKEN456", Err: errors.New("connection refused")},
+	}
+	message := err.Error()
+	for _, secret := range []string{"SIG123", "TOKEN456"} {
+		if strings.Contains(message, secret) {
+			t.Errorf("expected %q to be redacted from %q", secret, message)
+		}
+	}
+	if !strings.Contains(message, "connection refused") {
+		t.Errorf("expected the cause in %q", message)
+	}
+}
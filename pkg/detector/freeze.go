@@ -0,0 +1,223 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FreezeDetectionOptions configures how ffmpeg's freezedetect filter finds frozen video. Zero values select
+// ffmpeg's defaults.
+type FreezeDetectionOptions struct {
+	// NoiseDB is the noise tolerance in dB below which consecutive frames count as identical (freezedetect's n,
+	// -60dB by default). It must be negative.
+	NoiseDB float64
+	// MinDuration is the minimum length, in seconds, of a reported freeze (freezedetect's d, 2 by default).
+	MinDuration float64
+	// StartOffset skips this many seconds of the input before analysis begins.
+	StartOffset float64
+	// AnalyzeDuration limits analysis to this many seconds of input. Zero analyses until the end of the input.
+	AnalyzeDuration float64
+	// InputHeaders are sent as HTTP request headers when inputPath is an HTTP(S) URL that ffmpeg reads directly.
+	InputHeaders http.Header
+}
+
+// DetectFreeze executes ffmpeg with the freezedetect video filter and returns the periods of frozen picture, with
+// absolute timestamps. A freeze that lasts until the end of the input is closed at the input duration, which comes
+// from ffprobe or, without it, from ffmpeg's progress output. Inputs without video fail with ErrNoVideoStream.
+// Standard input is not supported.
+func (d *Detector) DetectFreeze(ctx context.Context, inputPath string, options FreezeDetectionOptions) ([]Interval, error) {
+	if err := validateFreezeOptions(inputPath, options); err != nil {
+		return nil, err
+	}
+
+	var knownDuration float64
+	probeOptions := DetectionOptions{StartOffset: options.StartOffset, AnalyzeDuration: options.AnalyzeDuration, InputHeaders: options.InputHeaders}
+	if d.probesDuration(inputPath, probeOptions) {
+		if duration, err := d.probeDuration(ctx, inputPath, probeOptions); err == nil {
+			knownDuration = windowDuration(duration, probeOptions)
+		}
+	}
+
+	args := d.freezeArgs(inputPath, options, knownDuration > 0)
+	start := time.Now()
+	output, err := d.runVideoFilter(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := freezeParser{knownDuration: knownDuration}
+	if err := parser.parseOutput(string(output)); err != nil {
+		return nil, err
+	}
+	intervals := parser.finish()
+	d.debug(ctx, "ffmpeg finished", "elapsed", time.Since(start), "freeze_intervals", len(intervals))
+
+	if options.StartOffset > 0 {
+		intervals = shiftIntervals(intervals, options.StartOffset)
+	}
+	return intervals, nil
+}
+
+// BuildFreezeArgs returns the ffmpeg arguments DetectFreeze would run for inputPath and options, after the same
+// validation, assuming ffprobe cannot determine the duration. The ffmpeg binary itself is not included.
+func (d *Detector) BuildFreezeArgs(inputPath string, options FreezeDetectionOptions) ([]string, error) {
+	if err := validateFreezeOptions(inputPath, options); err != nil {
+		return nil, err
+	}
+	return d.freezeArgs(inputPath, options, false), nil
+}
+
+// validateFreezeOptions checks the input and options of a freeze detection before ffmpeg runs.
+func validateFreezeOptions(inputPath string, options FreezeDetectionOptions) error {
+	if inputPath == "" {
+		return errors.New("input path is required")
+	}
+	if isPipeInput(inputPath) {
+		return errors.New("freeze detection does not support reading from stdin")
+	}
+	if options.NoiseDB > 0 {
+		return fmt.Errorf("freeze noise tolerance must be negative, got %fdB", options.NoiseDB)
+	}
+	if options.MinDuration < 0 {
+		return fmt.Errorf("minimum freeze duration must not be negative, got %f", options.MinDuration)
+	}
+	if options.StartOffset < 0 {
+		return fmt.Errorf("start offset must not be negative, got %f", options.StartOffset)
+	}
+	if options.AnalyzeDuration < 0 {
+		return fmt.Errorf("analyze duration must not be negative, got %f", options.AnalyzeDuration)
+	}
+	return validateHeaders(options.InputHeaders)
+}
+
+// freezeArgs constructs the ffmpeg arguments of a freezedetect run. The progress line is kept unless durationKnown,
+// because it is the only other source of the end time of a freeze that runs to the end of the input.
+func (d *Detector) freezeArgs(inputPath string, options FreezeDetectionOptions, durationKnown bool) []string {
+	params := []string{}
+	if options.NoiseDB < 0 {
+		params = append(params, "n="+strconv.FormatFloat(options.NoiseDB, 'f', -1, 64)+"dB")
+	}
+	if options.MinDuration > 0 {
+		params = append(params, "d="+strconv.FormatFloat(options.MinDuration, 'f', -1, 64))
+	}
+	filter := "freezedetect"
+	if len(params) > 0 {
+		filter += "=" + strings.Join(params, ":")
+	}
+
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin")
+		if durationKnown {
+			args = append(args, "-nostats")
+		}
+	}
+	return append(args, videoFilterArgs(inputPath, options.InputHeaders, options.StartOffset, options.AnalyzeDuration, filter)...)
+}
+
+var (
+	freezeStartPattern    = regexp.MustCompile(`freeze_start:\s*(` + ffmpegNumber + `)`)
+	freezeDurationPattern = regexp.MustCompile(`freeze_duration:\s*(` + ffmpegNumber + `)`)
+	freezeEndPattern      = regexp.MustCompile(`freeze_end:\s*(` + ffmpegNumber + `)`)
+)
+
+// freezeParser consumes ffmpeg freezedetect output, which reports each freeze on three lines:
+//
+//	[freezedetect @ 0x...] lavfi.freezedetect.freeze_start: 5.005
+//	[freezedetect @ 0x...] lavfi.freezedetect.freeze_duration: 2.002
+//	[freezedetect @ 0x...] lavfi.freezedetect.freeze_end: 7.007
+type freezeParser struct {
+	intervals    []Interval
+	currentStart *float64
+	duration     *float64
+	lastProgress float64
+	// knownDuration, when positive, is an authoritative duration that takes precedence over progress output.
+	knownDuration float64
+}
+
+// parseOutput parses ffmpeg output. Progress lines are separated by carriage returns, so those split lines too.
+func (p *freezeParser) parseOutput(output string) error {
+	for _, line := range strings.FieldsFunc(output, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if err := p.parseLine(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *freezeParser) parseLine(line string) error {
+	if matches := freezeStartPattern.FindStringSubmatch(line); len(matches) == 2 {
+		start, err := parseFFmpegNumber(matches[1], "freeze start")
+		if err != nil {
+			return err
+		}
+		p.currentStart = &start
+		p.duration = nil
+		return nil
+	}
+
+	if matches := freezeDurationPattern.FindStringSubmatch(line); len(matches) == 2 {
+		duration, err := parseFFmpegNumber(matches[1], "freeze duration")
+		if err != nil {
+			return err
+		}
+		p.duration = &duration
+		return nil
+	}
+
+	if matches := freezeEndPattern.FindStringSubmatch(line); len(matches) == 2 {
+		end, err := parseFFmpegNumber(matches[1], "freeze end")
+		if err != nil {
+			return err
+		}
+
+		start, duration := end, 0.0
+		switch {
+		case p.currentStart != nil:
+			start = *p.currentStart
+			duration = end - start
+		case p.duration != nil:
+			start = end - *p.duration
+		}
+		if p.duration != nil {
+			duration = *p.duration
+		}
+		p.intervals = append(p.intervals, clampedInterval(start, end, duration))
+
+		p.currentStart = nil
+		p.duration = nil
+		return nil
+	}
+
+	for _, match := range progressTimePattern.FindAllStringSubmatch(line, -1) {
+		progress, err := parseProgressTime(match)
+		if err != nil {
+			return err
+		}
+		p.lastProgress = max(p.lastProgress, progress)
+	}
+
+	return nil
+}
+
+// finish closes a freeze that was still open when the output ended at the input duration, as freezedetect does not
+// report the end of a freeze that lasts until the end of the input, and returns the parsed intervals.
+func (p *freezeParser) finish() []Interval {
+	intervals := p.intervals
+
+	end := p.lastProgress
+	if p.knownDuration > 0 {
+		end = p.knownDuration
+	}
+	if p.currentStart != nil && end > *p.currentStart {
+		start := *p.currentStart
+		intervals = append(intervals, clampedInterval(start, end, end-start))
+	}
+	return intervals
+}
@@ -0,0 +1,107 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFreezeParser(t *testing.T) {
+	output := `[freezedetect @ 0x55f1] lavfi.freezedetect.freeze_start: 1.001
+[freezedetect @ 0x55f1] lavfi.freezedetect.freeze_duration: 2.002
+[freezedetect @ 0x55f1] lavfi.freezedetect.freeze_end: 3.003
+frame=  120 fps=0.0 q=-0.0 size=N/A time=00:00:04.00 bitrate=N/A speed=8x` + "\r" +
+		`[freezedetect @ 0x55f1] lavfi.freezedetect.freeze_start: 6.5
+frame=  250 fps=0.0 q=-0.0 size=N/A time=00:00:10.00 bitrate=N/A speed=8x` + "\r" +
+		`frame=  251 fps=0.0 q=-0.0 size=N/A time=-577014:32:22.77 bitrate=N/A speed=8x` + "\r\n"
+
+	var parser freezeParser
+	if err := parser.parseOutput(output); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+
+	// The second freeze runs to the end of the input, so it is closed at the last progress time.
+	assertIntervals(t, parser.finish(), []Interval{
+		{Start: 1.001, End: 3.003, Duration: 2.002},
+		{Start: 6.5, End: 10, Duration: 3.5},
+	})
+}
+
+func TestFreezeParserPrefersKnownDuration(t *testing.T) {
+	parser := freezeParser{knownDuration: 12}
+	if err := parser.parseOutput("[freezedetect @ 0x1] lavfi.freezedetect.freeze_start: 8\nsize=N/A time=00:00:10.00 bitrate=N/A\n"); err != nil {
+		t.Fatalf("parseOutput returned error: %v", err)
+	}
+	assertIntervals(t, parser.finish(), []Interval{{Start: 8, End: 12, Duration: 4}})
+
+	var empty freezeParser
+	if intervals := empty.finish(); intervals != nil {
+		t.Fatalf("expected no intervals without output, got %v", intervals)
+	}
+}
+
+func TestDetectFreeze(t *testing.T) {
+	var calls [][]string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		if name == "ffprobe" {
+			return []byte(`{"format":{"duration":"40.0"}}`), nil
+		}
+		return []byte("[freezedetect @ 0x1] lavfi.freezedetect.freeze_start: 25\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+
+	intervals, err := d.DetectFreeze(context.Background(), "video.mp4", FreezeDetectionOptions{NoiseDB: -50, MinDuration: 1, StartOffset: 10})
+	if err != nil {
+		t.Fatalf("DetectFreeze returned error: %v", err)
+	}
+
+	want := []string{"ffmpeg", "-hide_banner", "-nostdin", "-nostats", "-ss", "10", "-i", "video.mp4", "-an", "-sn", "-dn",
+		"-vf", "freezedetect=n=-50dB:d=1", "-f", "null", "-"}
+	if len(calls) != 2 || !slices.Equal(calls[1], want) {
+		t.Fatalf("unexpected ffmpeg run\n got %q\nwant %q", calls, want)
+	}
+	// The open freeze is closed at the end of the 30 seconds analysed, then shifted by the start offset.
+	assertIntervals(t, intervals, []Interval{{Start: 35, End: 40, Duration: 5}})
+}
+
+func TestDetectFreezeKeepsProgressWithoutProbe(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return nil, nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath(""))
+
+	if _, err := d.DetectFreeze(context.Background(), "video.mp4", FreezeDetectionOptions{}); err != nil {
+		t.Fatalf("DetectFreeze returned error: %v", err)
+	}
+	if slices.Contains(gotArgs, "-nostats") {
+		t.Fatalf("expected the progress line to be kept, got %q", gotArgs)
+	}
+	if !strings.Contains(strings.Join(gotArgs, " "), "-vf freezedetect -f null -") {
+		t.Fatalf("expected ffmpeg's defaults, got %q", gotArgs)
+	}
+}
+
+func TestDetectFreezeErrors(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Output file #0 does not contain any stream"), &exec.ExitError{}
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath(""))
+
+	if _, err := d.DetectFreeze(context.Background(), "audio.wav", FreezeDetectionOptions{}); !errors.Is(err, ErrNoVideoStream) {
+		t.Fatalf("expected ErrNoVideoStream, got %v", err)
+	}
+	for _, options := range []FreezeDetectionOptions{{NoiseDB: 3}, {MinDuration: -1}, {AnalyzeDuration: -1}} {
+		if _, err := d.DetectFreeze(context.Background(), "video.mp4", options); err == nil || errors.Is(err, ErrNoVideoStream) {
+			t.Fatalf("expected %+v to be rejected, got %v", options, err)
+		}
+	}
+	if _, err := d.DetectFreeze(context.Background(), "-", FreezeDetectionOptions{}); err == nil {
+		t.Fatal("expected stdin to be rejected")
+	}
+}
@@ -0,0 +1,147 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// fullSilenceTolerance is the slack, in seconds, IsFullySilent allows between interval boundaries and the start
+// and end of the input.
+const fullSilenceTolerance = 1e-3
+
+// progressSlack is how far, in seconds, ffmpeg's progress may run past a point before IsFullySilent concludes that
+// audio was decoded there. Progress timestamps are coarser than silencedetect's.
+const progressSlack = 0.5
+
+// IsFullySilent reports whether the whole input is silent, as DetectionResult.FullySilent does with a tolerance of
+// one millisecond, but stops ffmpeg as soon as the answer is known to be false: when sound is found at the start of
+// the input or when sound resumes after a silence. A silent input is still read to the end.
+//
+// Early termination needs the streaming runner; detectors created with WithCommandRunner analyse the whole input.
+// PerChannel is not supported, and MeasureVolume, MeasureLoudness, CaptureRawOutput and MaxIntervals are ignored.
+func (d *Detector) IsFullySilent(ctx context.Context, inputPath string, options DetectionOptions) (bool, error) {
+	if options.PerChannel {
+		return false, errors.New("IsFullySilent does not support PerChannel")
+	}
+	options.MeasureVolume = false
+	options.MeasureLoudness = false
+	options.CaptureRawOutput = false
+	options.MaxIntervals = 0
+
+	if d.stream == nil {
+		result, err := d.DetectSilence(ctx, inputPath, options)
+		if err != nil {
+			return false, err
+		}
+		return result.FullySilent(fullSilenceTolerance), nil
+	}
+
+	inputPath, err := prepareInput(inputPath, options)
+	if err != nil {
+		return false, err
+	}
+	if options.Stdin != nil {
+		ctx = context.WithValue(ctx, stdinKey{}, options.Stdin)
+	}
+
+	if options.AdaptiveThreshold {
+		floor, err := d.measureNoiseFloor(ctx, inputPath, options)
+		if err != nil {
+			return false, err
+		}
+		options.NoiseLevel = adaptiveThreshold(floor, options.AdaptiveOffsetDB, options.NoiseLevel)
+	}
+
+	knownDuration := windowDuration(options.KnownDuration, options)
+	if d.probesDuration(inputPath, options) {
+		if duration, err := d.probeDuration(ctx, inputPath, options); err == nil {
+			knownDuration = windowDuration(duration, options)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parser := &silenceParser{knownDuration: knownDuration}
+	watch := fullSilenceWatch{parser: parser, minDuration: options.MinSilenceDuration, knownDuration: knownDuration}
+	parser.stop = func() bool {
+		if !watch.soundFound() {
+			return false
+		}
+		cancel()
+		return true
+	}
+
+	// The progress line is what reveals sound before the first silencedetect report, so it is always kept. The
+	// output is read through the streaming runner, which combines -progress records with the silencedetect lines.
+	options.Stats = StatsKeep
+	args := d.commandArgs(inputPath, options, knownDuration > 0)
+
+	d.debug(ctx, "running ffmpeg", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	result, err := d.streamSilence(runCtx, args, parser)
+	if watch.found {
+		d.debug(ctx, "ffmpeg stopped early", "elapsed", time.Since(start), "reason", watch.reason)
+		return false, nil
+	}
+	d.logRunFinished(ctx, start, parser, result, err)
+	if err != nil {
+		return false, err
+	}
+	return result.FullySilent(fullSilenceTolerance), nil
+}
+
+// fullSilenceWatch inspects the state of a silenceParser as output streams in and decides whether the input has
+// already been shown to contain sound.
+type fullSilenceWatch struct {
+	parser        *silenceParser
+	minDuration   float64
+	knownDuration float64
+
+	// found and reason record the decision once sound was found.
+	found  bool
+	reason string
+}
+
+func (w *fullSilenceWatch) soundFound() bool {
+	if !w.found {
+		w.reason = w.check()
+		w.found = w.reason != ""
+	}
+	return w.found
+}
+
+// check returns why the parsed output proves the input is not fully silent, or "" while it does not.
+func (w *fullSilenceWatch) check() string {
+	p := w.parser
+
+	if len(p.intervals) == 0 {
+		switch {
+		case p.currentStart != nil && *p.currentStart > fullSilenceTolerance:
+			return "silence starts after the beginning"
+		case p.currentStart == nil && p.lastProgress > w.minDuration+progressSlack:
+			// Silence at the start would have been reported once it lasted the minimum duration.
+			return "no silence at the beginning"
+		}
+		return ""
+	}
+
+	if first := p.intervals[0]; first.Start > fullSilenceTolerance {
+		return "silence starts after the beginning"
+	}
+
+	// silencedetect reports the end of a silence when sound resumes, and newer versions also at the end of the
+	// input. An end before the end of the input, or followed by further output, means sound resumed.
+	end := p.intervals[len(p.intervals)-1].End
+	switch {
+	case p.currentStart != nil && *p.currentStart-end > fullSilenceTolerance:
+		return "sound between silences"
+	case w.knownDuration > 0 && w.knownDuration-end > fullSilenceTolerance:
+		return "sound after silence"
+	case p.lastProgress > end+progressSlack:
+		return "sound after silence"
+	}
+	return ""
+}
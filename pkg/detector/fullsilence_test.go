@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFullSilenceWatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		output        string
+		knownDuration float64
+		want          bool
+	}{
+		{name: "no output yet", output: "", want: false},
+		{name: "silence from the start", output: "silence_start: 0\nsize=N/A time=00:00:05.00 bitrate=N/A", want: false},
+		{name: "silence starts late", output: "silence_start: 2.5", want: true},
+		{name: "no silence reported in time", output: "size=N/A time=00:00:03.00 bitrate=N/A", want: true},
+		{name: "progress within minimum duration", output: "size=N/A time=00:00:00.80 bitrate=N/A", want: false},
+		{name: "progress records", output: "out_time=00:00:03.000000", want: true},
+		{name: "silence ends before known end", output: "silence_start: 0\nsilence_end: 4 | silence_duration: 4", knownDuration: 10, want: true},
+		{name: "silence ends at known end", output: "silence_start: 0\nsilence_end: 10 | silence_duration: 10", knownDuration: 10, want: false},
+		{name: "silence end without duration", output: "silence_start: 0\nsilence_end: 4 | silence_duration: 4", want: false},
+		{name: "progress after silence end", output: "silence_start: 0\nsilence_end: 4 | silence_duration: 4\nsize=N/A time=00:00:06.00 bitrate=N/A", want: true},
+		{name: "silence resumes after sound", output: "silence_start: 0\nsilence_end: 4 | silence_duration: 4\nsilence_start: 5", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &silenceParser{knownDuration: tt.knownDuration}
+			watch := fullSilenceWatch{parser: parser, minDuration: 0.5, knownDuration: tt.knownDuration}
+			if err := parser.parseOutput(tt.output); err != nil {
+				t.Fatalf("parseOutput returned error: %v", err)
+			}
+			if got := watch.soundFound(); got != tt.want {
+				t.Fatalf("soundFound() = %v, want %v (%s)", got, tt.want, watch.reason)
+			}
+		})
+	}
+}
+
+// writeFakeFFmpeg writes a shell script that prints output to stderr and then hangs, standing in for an ffmpeg
+// decoding a long input.
+func writeFakeFFmpeg(t *testing.T, output string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nprintf '" + output + "' >&2\nexec sleep 30\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIsFullySilentStopsFFmpegEarly(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{name: "sound at the start", output: `[silencedetect @ 0x1] silence_start: 3\n`},
+		{name: "sound after silence", output: `[silencedetect @ 0x1] silence_start: 0\n[silencedetect @ 0x1] silence_end: 2 | silence_duration: 2\nsize=N/A time=00:00:04.00 bitrate=N/A\n`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ffmpeg := writeFakeFFmpeg(t, tt.output)
+			d := NewDetector(WithFFmpegPath(ffmpeg), WithFFprobePath(""))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			silent, err := d.IsFullySilent(ctx, "input.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5})
+			if err != nil {
+				t.Fatalf("IsFullySilent returned error: %v", err)
+			}
+			if silent {
+				t.Fatal("expected the input not to be fully silent")
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Fatalf("expected ffmpeg to be killed early, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestIsFullySilentReadsSilentInputToTheEnd(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+		gotArgs = args
+		output := "[silencedetect @ 0x1] silence_start: 0\nsize=N/A time=00:00:10.00 bitrate=N/A\n"
+		return io.NopCloser(strings.NewReader(output)), func() error { return nil }, nil
+	}
+	d := NewDetector(WithStreamingRunner(runner), WithFFprobePath(""))
+
+	silent, err := d.IsFullySilent(context.Background(), "input.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, MeasureLoudness: true})
+	if err != nil {
+		t.Fatalf("IsFullySilent returned error: %v", err)
+	}
+	if !silent {
+		t.Fatal("expected the input to be fully silent")
+	}
+	joined := strings.Join(gotArgs, " ")
+	if strings.Contains(joined, "-nostats") || strings.Contains(joined, "ebur128") {
+		t.Fatalf("expected the progress line to be kept and measurements to be dropped, got %q", gotArgs)
+	}
+}
+
+func TestIsFullySilentWithCommandRunner(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("[silencedetect @ 0x1] silence_start: 0\n[silencedetect @ 0x1] silence_end: 4 | silence_duration: 4\nsize=N/A time=00:00:10.00 bitrate=N/A\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath(""))
+
+	silent, err := d.IsFullySilent(context.Background(), "input.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5})
+	if err != nil {
+		t.Fatalf("IsFullySilent returned error: %v", err)
+	}
+	if silent {
+		t.Fatal("expected the input not to be fully silent")
+	}
+
+	if _, err := d.IsFullySilent(context.Background(), "input.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, PerChannel: true}); err == nil {
+		t.Fatal("expected PerChannel to be rejected")
+	}
+}
@@ -0,0 +1,288 @@
+package detector
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultGCSEndpoint is the Cloud Storage JSON API endpoint.
+const defaultGCSEndpoint = "https://storage.googleapis.com"
+
+// gcsReadScope is the OAuth scope requested for Application Default Credentials.
+const gcsReadScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// defaultGoogleTokenURL is the OAuth token endpoint used when credentials do not name one.
+const defaultGoogleTokenURL = "https://oauth2.googleapis.com/token"
+
+// defaultMetadataHost is the GCE metadata server, overridable with GCE_METADATA_HOST.
+const defaultMetadataHost = "metadata.google.internal"
+
+// ErrGCSCredentialsNotFound is returned when no Application Default Credentials are available.
+var ErrGCSCredentialsNotFound = errors.New("Google Application Default Credentials not found")
+
+// GCSOptions configures GCSObjectRequest.
+type GCSOptions struct {
+	// Endpoint is the base URL of the Cloud Storage API, for example an emulator at http://localhost:4443. When
+	// empty, STORAGE_EMULATOR_HOST or the public endpoint is used. Requests to a custom endpoint are
+	// unauthenticated unless Token is set.
+	Endpoint string
+	// Token is an OAuth access token. When empty, one is obtained with LoadGCSAccessToken.
+	Token string
+	// Client is used to obtain access tokens. When nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// GCSLocation identifies an object in a gs://bucket/object URL.
+type GCSLocation struct {
+	Bucket string
+	Object string
+}
+
+// ParseGCSURL splits a gs://bucket/object URL into its bucket and object name.
+func ParseGCSURL(raw string) (GCSLocation, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return GCSLocation{}, fmt.Errorf("invalid GCS URL: %w", err)
+	}
+	if !strings.EqualFold(parsed.Scheme, "gs") {
+		return GCSLocation{}, fmt.Errorf("invalid GCS URL %q: scheme must be gs", raw)
+	}
+	if parsed.Host == "" {
+		return GCSLocation{}, fmt.Errorf("invalid GCS URL %q: missing bucket", raw)
+	}
+
+	object := strings.TrimPrefix(parsed.Path, "/")
+	if object == "" {
+		return GCSLocation{}, fmt.Errorf("invalid GCS URL %q: missing object name", raw)
+	}
+
+	return GCSLocation{Bucket: parsed.Host, Object: object}, nil
+}
+
+// GCSObjectRequest converts a gs://bucket/object URL into an HTTPS media download URL and the headers needed to
+// fetch it.
+func GCSObjectRequest(ctx context.Context, rawURL string, options GCSOptions) (string, http.Header, error) {
+	location, err := ParseGCSURL(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	endpoint := options.Endpoint
+	if endpoint == "" {
+		if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+			endpoint = host
+			if !strings.Contains(endpoint, "://") {
+				endpoint = "http://" + endpoint
+			}
+		}
+	}
+
+	token := options.Token
+	if endpoint == "" {
+		endpoint = defaultGCSEndpoint
+		if token == "" {
+			token, err = LoadGCSAccessToken(ctx, options.Client)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return "", nil, fmt.Errorf("invalid GCS endpoint %q: must be an http or https URL", endpoint)
+	}
+
+	objectURL := strings.TrimSuffix(base.String(), "/") + "/storage/v1/b/" + url.PathEscape(location.Bucket) +
+		"/o/" + url.PathEscape(location.Object) + "?alt=media"
+
+	var header http.Header
+	if token != "" {
+		header = http.Header{"Authorization": []string{"Bearer " + token}}
+	}
+	return objectURL, header, nil
+}
+
+// googleCredentials is the subset of an Application Default Credentials file used here.
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// LoadGCSAccessToken obtains a read-only Cloud Storage access token from Application Default Credentials.
+//
+// The credentials file named by GOOGLE_APPLICATION_CREDENTIALS is used if set, then the gcloud application default
+// credentials file, and finally the GCE metadata server. Service account and authorized user credentials are
+// supported.
+func LoadGCSAccessToken(ctx context.Context, client *http.Client) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			candidate := filepath.Join(dir, "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+			}
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrGCSCredentialsNotFound, err)
+		}
+		var creds googleCredentials
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return "", fmt.Errorf("parse credentials file %s: %w", path, err)
+		}
+		return creds.accessToken(ctx, client)
+	}
+
+	token, err := metadataAccessToken(ctx, client)
+	if err != nil {
+		return "", fmt.Errorf("%w: set GOOGLE_APPLICATION_CREDENTIALS or run on GCE (%v)", ErrGCSCredentialsNotFound, err)
+	}
+	return token, nil
+}
+
+func (c googleCredentials) accessToken(ctx context.Context, client *http.Client) (string, error) {
+	tokenURL := c.TokenURI
+	if tokenURL == "" {
+		tokenURL = defaultGoogleTokenURL
+	}
+
+	form := url.Values{}
+	switch c.Type {
+	case "service_account":
+		assertion, err := c.jwtAssertion(tokenURL, time.Now())
+		if err != nil {
+			return "", err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+		form.Set("refresh_token", c.RefreshToken)
+	default:
+		return "", fmt.Errorf("unsupported credentials type %q", c.Type)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return requestAccessToken(client, req)
+}
+
+// jwtAssertion builds the signed JWT a service account exchanges for an access token.
+func (c googleCredentials) jwtAssertion(audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(c.PrivateKey))
+	if block == nil {
+		return "", errors.New("service account private key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("parse service account private key: %w", err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("service account private key is not an RSA key")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   c.ClientEmail,
+		"scope": gcsReadScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign service account assertion: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// metadataAccessToken asks the GCE metadata server for the default service account's token.
+func metadataAccessToken(ctx context.Context, client *http.Client) (string, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = defaultMetadataHost
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tokenURL := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token?scopes=" + url.QueryEscape(gcsReadScope)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	return requestAccessToken(client, req)
+}
+
+// requestAccessToken performs an OAuth token request and extracts the access token.
+func requestAccessToken(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s failed: %s", req.URL.Host, resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token response did not include an access token")
+	}
+	return token.AccessToken, nil
+}
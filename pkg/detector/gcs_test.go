@@ -0,0 +1,155 @@
+package detector
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGoogleCredentials(t *testing.T, creds map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatalf("marshal credentials: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+	return path
+}
+
+func TestGCSObjectRequest(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+
+	objectURL, header, err := GCSObjectRequest(context.Background(), "gs://media/videos/My Clip.mp4", GCSOptions{Token: "ya29.token"})
+	if err != nil {
+		t.Fatalf("GCSObjectRequest returned error: %v", err)
+	}
+	if want := "https://storage.googleapis.com/storage/v1/b/media/o/videos%2FMy%20Clip.mp4?alt=media"; objectURL != want {
+		t.Fatalf("unexpected URL: got %s want %s", objectURL, want)
+	}
+	if header.Get("Authorization") != "Bearer ya29.token" {
+		t.Fatalf("unexpected headers %v", header)
+	}
+}
+
+func TestGCSObjectRequestWithEmulator(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "localhost:4443")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	objectURL, header, err := GCSObjectRequest(context.Background(), "gs://media/a.mp4", GCSOptions{})
+	if err != nil {
+		t.Fatalf("GCSObjectRequest returned error: %v", err)
+	}
+	if objectURL != "http://localhost:4443/storage/v1/b/media/o/a.mp4?alt=media" {
+		t.Fatalf("unexpected URL %s", objectURL)
+	}
+	if header != nil {
+		t.Fatalf("expected emulator requests to be unauthenticated, got %v", header)
+	}
+
+	objectURL, _, err = GCSObjectRequest(context.Background(), "gs://media/a.mp4", GCSOptions{Endpoint: "http://127.0.0.1:9023/"})
+	if err != nil || objectURL != "http://127.0.0.1:9023/storage/v1/b/media/o/a.mp4?alt=media" {
+		t.Fatalf("unexpected endpoint override result %s, %v", objectURL, err)
+	}
+}
+
+func TestLoadGCSAccessTokenServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.PostForm.Get("assertion"), ".") != 2 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"sa-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeGoogleCredentials(t, map[string]string{
+		"type":         "service_account",
+		"client_email": "detector@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL,
+	}))
+
+	token, err := LoadGCSAccessToken(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("LoadGCSAccessToken returned error: %v", err)
+	}
+	if token != "sa-token" {
+		t.Fatalf("unexpected token %q", token)
+	}
+}
+
+func TestLoadGCSAccessTokenAuthorizedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "refresh" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"user-token"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeGoogleCredentials(t, map[string]string{
+		"type":          "authorized_user",
+		"client_id":     "id",
+		"client_secret": "secret",
+		"refresh_token": "refresh",
+		"token_uri":     server.URL,
+	}))
+
+	token, err := LoadGCSAccessToken(context.Background(), nil)
+	if err != nil || token != "user-token" {
+		t.Fatalf("unexpected result %q, %v", token, err)
+	}
+}
+
+func TestLoadGCSAccessTokenFromMetadataServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"access_token":"metadata-token"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	token, err := LoadGCSAccessToken(context.Background(), nil)
+	if err != nil || token != "metadata-token" {
+		t.Fatalf("unexpected result %q, %v", token, err)
+	}
+
+	server.Close()
+	if _, err := LoadGCSAccessToken(context.Background(), nil); !errors.Is(err, ErrGCSCredentialsNotFound) {
+		t.Fatalf("expected ErrGCSCredentialsNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bucket is one duration class of a silence histogram: the silence intervals lasting at least Min seconds and less
+// than Max.
+type Bucket struct {
+	Min float64 `json:"min"`
+	// Max is nil for the last bucket, which has no upper bound.
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+	// TotalDuration is the summed duration, in seconds, of the intervals in the bucket.
+	TotalDuration float64 `json:"total_duration"`
+}
+
+// Histogram sorts the silence intervals into duration classes split at bounds, in seconds, such as 2 and 10 for
+// pauses under 2 seconds, long pauses of 2 to 10 seconds and dead air of 10 seconds or more. It returns
+// len(bounds)+1 buckets, the first starting at zero and the last without an upper bound. Buckets include their
+// lower bound, so an interval lasting exactly a bound falls into the longer class.
+//
+// Every bucket is returned, with zero counts when no interval falls into it. Histogram returns an error when the
+// bounds are not positive and strictly increasing.
+func (r DetectionResult) Histogram(bounds []float64) ([]Bucket, error) {
+	for i, bound := range bounds {
+		if bound <= 0 || math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return nil, fmt.Errorf("invalid histogram bound %v: bounds must be positive", bound)
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return nil, fmt.Errorf("histogram bounds must be strictly increasing, got %v after %v", bound, bounds[i-1])
+		}
+	}
+
+	bounds = append([]float64(nil), bounds...)
+	buckets := make([]Bucket, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			buckets[i].Max = &bounds[i]
+		}
+	}
+	for _, interval := range r.Intervals {
+		i := 0
+		for i < len(bounds) && interval.Duration >= bounds[i] {
+			i++
+		}
+		buckets[i].Count++
+		buckets[i].TotalDuration += interval.Duration
+	}
+	return buckets, nil
+}
@@ -0,0 +1,66 @@
+package detector
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	result := DetectionResult{Intervals: []SilenceInterval{
+		{Start: 0, End: 0.5, Duration: 0.5},
+		{Start: 5, End: 7, Duration: 2},
+		{Start: 10, End: 14.5, Duration: 4.5},
+		{Start: 20, End: 30, Duration: 10},
+		{Start: 40, End: 41.5, Duration: 1.5},
+	}}
+
+	buckets, err := result.Histogram([]float64{2, 10})
+	if err != nil {
+		t.Fatalf("Histogram: %v", err)
+	}
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %+v", buckets)
+	}
+	// Intervals lasting exactly a bound, 2 and 10 seconds, fall into the longer class.
+	want := []struct {
+		min   float64
+		count int
+		total float64
+	}{{0, 2, 2}, {2, 2, 6.5}, {10, 1, 10}}
+	for i, w := range want {
+		assertFloatEqual(t, buckets[i].Min, w.min)
+		assertFloatEqual(t, buckets[i].TotalDuration, w.total)
+		if buckets[i].Count != w.count {
+			t.Errorf("bucket %d: expected %d intervals, got %d", i, w.count, buckets[i].Count)
+		}
+	}
+	if buckets[0].Max == nil || *buckets[0].Max != 2 || buckets[2].Max != nil {
+		t.Fatalf("unexpected bucket bounds %+v", buckets)
+	}
+
+	data, err := json.Marshal(buckets[2])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `{"min":10,"max":null,"count":1,"total_duration":10}`; string(data) != want {
+		t.Fatalf("unexpected JSON: got %s want %s", data, want)
+	}
+}
+
+func TestHistogramWithoutIntervals(t *testing.T) {
+	buckets, err := (DetectionResult{}).Histogram(nil)
+	if err != nil {
+		t.Fatalf("Histogram: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Count != 0 || buckets[0].Max != nil {
+		t.Fatalf("expected a single empty bucket, got %+v", buckets)
+	}
+}
+
+func TestHistogramRejectsInvalidBounds(t *testing.T) {
+	for _, bounds := range [][]float64{{10, 2}, {2, 2}, {0, 2}, {-1}} {
+		if _, err := (DetectionResult{}).Histogram(bounds); err == nil {
+			t.Errorf("expected bounds %v to be rejected", bounds)
+		}
+	}
+}
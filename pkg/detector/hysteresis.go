@@ -0,0 +1,75 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// HysteresisIntervals combines the intervals detected at two thresholds into silences with hysteresis. strict holds
+// the intervals below the opening threshold and loose those below the higher closing threshold, so every strict
+// interval lies within a loose one. A silence opens where the audio first dips below the opening threshold, at the
+// start of a strict interval, and closes only where it rises above the closing threshold, at the end of the loose
+// interval containing it. Loose intervals without a strict interval never dipped low enough and are dropped, and
+// strict intervals separated only by audio between the thresholds come out as a single silence.
+//
+// Both slices may be unsorted or overlapping; the returned slice is a new, start-ordered slice.
+func HysteresisIntervals(strict, loose []SilenceInterval) []SilenceInterval {
+	strict = unionIntervals(strict)
+
+	var combined []SilenceInterval
+	i := 0
+	for _, outer := range unionIntervals(loose) {
+		for i < len(strict) && strict[i].End <= outer.Start {
+			i++
+		}
+		if i == len(strict) {
+			break
+		}
+		if strict[i].Start >= outer.End {
+			continue
+		}
+
+		// A strict interval can only extend past its loose one through ffmpeg's rounding, which the bounds absorb.
+		start := strict[i].Start
+		end := outer.End
+		for i < len(strict) && strict[i].Start < outer.End {
+			end = math.Max(end, strict[i].End)
+			i++
+		}
+		combined = append(combined, SilenceInterval{Start: start, End: end, Duration: end - start})
+	}
+	return combined
+}
+
+// closingThreshold returns the noise level above which a silence closes in hysteresis mode. Validate rejects fixed
+// thresholds that would exceed 0 dB, so the cap only applies to adaptive ones.
+func (o DetectionOptions) closingThreshold() float64 {
+	return math.Min(o.thresholdDB()+o.HysteresisDB, 0)
+}
+
+// applyHysteresis runs the second, closing-threshold pass of a hysteresis detection and combines its intervals with
+// those of result, which were detected at the opening threshold. Both passes report intervals relative to the
+// analysed window, before they are shifted and normalized.
+func (d *Detector) applyHysteresis(ctx context.Context, inputPath string, options DetectionOptions, knownDuration float64, result DetectionResult) (DetectionResult, error) {
+	closing := options
+	closing.NoiseLevel = options.closingThreshold()
+	closing.NoiseRatio = nil
+	closing.MeasureVolume = false
+	closing.MeasureLoudness = false
+	closing.CaptureRawOutput = false
+	closing.AllowPartial = false
+
+	d.debug(ctx, "detecting silence at the closing threshold", "noise_db", closing.NoiseLevel)
+	loose, err := d.runDetection(ctx, inputPath, closing, knownDuration)
+	if err != nil {
+		return DetectionResult{}, fmt.Errorf("closing threshold %gdB: %w", closing.NoiseLevel, err)
+	}
+
+	result.Intervals = HysteresisIntervals(result.Intervals, loose.Intervals)
+	for channel, intervals := range result.ChannelIntervals {
+		result.ChannelIntervals[channel] = HysteresisIntervals(intervals, loose.ChannelIntervals[channel])
+	}
+	result.Truncated = result.Truncated || loose.Truncated
+	return result, nil
+}
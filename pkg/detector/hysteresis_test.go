@@ -0,0 +1,106 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHysteresisIntervals(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict []SilenceInterval
+		loose  []SilenceInterval
+		want   []SilenceInterval
+	}{
+		{
+			name:   "flapping around the opening threshold becomes one silence",
+			strict: []SilenceInterval{{Start: 2, End: 3}, {Start: 3.2, End: 4}, {Start: 4.1, End: 5}},
+			loose:  []SilenceInterval{{Start: 1.5, End: 6}},
+			want:   []SilenceInterval{{Start: 2, End: 6, Duration: 4}},
+		},
+		{
+			name:   "closes only above the closing threshold",
+			strict: []SilenceInterval{{Start: 2, End: 3}, {Start: 7, End: 8}},
+			loose:  []SilenceInterval{{Start: 1, End: 4}, {Start: 6, End: 9}},
+			want:   []SilenceInterval{{Start: 2, End: 4, Duration: 2}, {Start: 7, End: 9, Duration: 2}},
+		},
+		{
+			name:   "never dipping below the opening threshold",
+			strict: []SilenceInterval{{Start: 7, End: 8}},
+			loose:  []SilenceInterval{{Start: 1, End: 4}, {Start: 6, End: 9}},
+			want:   []SilenceInterval{{Start: 7, End: 9, Duration: 2}},
+		},
+		{
+			name:   "rounding past the loose bounds",
+			strict: []SilenceInterval{{Start: 0.9995, End: 4.0005}},
+			loose:  []SilenceInterval{{Start: 1, End: 4}},
+			want:   []SilenceInterval{{Start: 0.9995, End: 4.0005, Duration: 3.001}},
+		},
+		{
+			name:   "unsorted input",
+			strict: []SilenceInterval{{Start: 7, End: 8}, {Start: 2, End: 3}},
+			loose:  []SilenceInterval{{Start: 6, End: 9}, {Start: 1, End: 4}},
+			want:   []SilenceInterval{{Start: 2, End: 4, Duration: 2}, {Start: 7, End: 9, Duration: 2}},
+		},
+		{
+			name:  "no strict silence",
+			loose: []SilenceInterval{{Start: 1, End: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertIntervals(t, HysteresisIntervals(tt.strict, tt.loose), tt.want)
+		})
+	}
+}
+
+func TestDetectSilenceHysteresis(t *testing.T) {
+	var filters []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		filter := args[len(args)-4]
+		filters = append(filters, filter)
+		if strings.Contains(filter, "noise=-25dB") {
+			return []byte("[silencedetect @ 0x1] silence_start: 1.5\n[silencedetect @ 0x1] silence_end: 6 | silence_duration: 4.5\nsize=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+		}
+		return []byte("[silencedetect @ 0x1] silence_start: 2\n[silencedetect @ 0x1] silence_end: 3 | silence_duration: 1\n" +
+			"[silencedetect @ 0x1] silence_start: 3.2\n[silencedetect @ 0x1] silence_end: 5 | silence_duration: 1.8\n" +
+			"size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "audio.wav", DetectionOptions{
+		NoiseLevel:         -35,
+		MinSilenceDuration: 0.5,
+		HysteresisDB:       10,
+		MeasureVolume:      true,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	want := []string{"silencedetect=noise=-35dB:d=0.5,volumedetect", "silencedetect=noise=-25dB:d=0.5"}
+	if len(filters) != len(want) || filters[0] != want[0] || filters[1] != want[1] {
+		t.Fatalf("unexpected filter passes %v", filters)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 2, End: 6, Duration: 4}})
+	assertFloatEqual(t, result.ThresholdDB, -35)
+}
+
+func TestDetectSilenceHysteresisRejectsStdin(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg should not run")
+		return nil, nil
+	}))
+
+	_, err := d.DetectSilence(context.Background(), "-", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		HysteresisDB:       6,
+		Stdin:              strings.NewReader(""),
+	})
+	if err == nil || !strings.Contains(err.Error(), "seekable") {
+		t.Fatalf("expected stdin to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// InputKind classifies where a media input is located.
+type InputKind int
+
+const (
+	// InputLocal is a file on a local or mounted filesystem.
+	InputLocal InputKind = iota
+	// InputRemote is an HTTP(S) URL that must be fetched before or during analysis.
+	InputRemote
+	// InputS3 is an s3://bucket/key URL that must be presigned with PresignS3URL before it can be fetched.
+	InputS3
+	// InputGCS is a gs://bucket/object URL that must be resolved with GCSObjectRequest before it can be fetched.
+	InputGCS
+	// InputStream is the URL of a live stream in a protocol only ffmpeg reads, such as rtmp://, rtsp:// or srt://.
+	// It is passed to ffmpeg unchanged.
+	InputStream
+)
+
+// Input describes a classified media input.
+type Input struct {
+	// Raw is the input as supplied by the caller, with surrounding whitespace removed.
+	Raw string
+	// Kind reports where the input is located.
+	Kind InputKind
+	// Location is the filesystem path for local inputs and the URL for remote, cloud storage and stream inputs.
+	Location string
+}
+
+// IsRemote reports whether the input is an HTTP(S) URL.
+func (in Input) IsRemote() bool {
+	return in.Kind == InputRemote
+}
+
+// IsHLS reports whether the input is an HLS playlist, recognised by its .m3u8 extension.
+func (in Input) IsHLS() bool {
+	return isHLSPlaylist(in.Location)
+}
+
+// IsManifest reports whether the input is a streaming manifest: an HLS playlist (.m3u8) or a DASH manifest (.mpd).
+//
+// A manifest refers to its segments by URIs that are usually relative to the manifest, so it must be passed to
+// ffmpeg by its original location rather than downloaded to a temporary file.
+func (in Input) IsManifest() bool {
+	return isManifest(in.Location)
+}
+
+// isHLSPlaylist reports whether location, a path or URL, names an HLS playlist.
+func isHLSPlaylist(location string) bool {
+	return strings.EqualFold(locationExt(location), ".m3u8")
+}
+
+// isManifest reports whether location, a path or URL, names an HLS playlist or a DASH manifest.
+func isManifest(location string) bool {
+	return isHLSPlaylist(location) || strings.EqualFold(locationExt(location), ".mpd")
+}
+
+// locationExt returns the extension of a path, or of the path of a URL, ignoring its query and fragment.
+func locationExt(location string) string {
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" && parsed.Path != "" && !windowsDrivePattern.MatchString(location) {
+		location = parsed.Path
+	}
+	return filepath.Ext(location)
+}
+
+// Display returns a human-friendly representation of the input for reports.
+//
+// URLs, including file:// URLs, are shown as supplied; plain paths are cleaned.
+func (in Input) Display() string {
+	if in.Kind != InputLocal || in.Raw != in.Location {
+		return in.Raw
+	}
+	return filepath.Clean(in.Location)
+}
+
+var windowsDrivePattern = regexp.MustCompile(`^[A-Za-z]:([\\/]|$)`)
+
+// ResolveInput classifies a raw input string as a local path or a remote URL.
+//
+// Windows drive-letter paths (C:\media\a.mp4), UNC paths (\\server\share\a.mp4), relative paths, and file:// URLs
+// are local; only http and https URLs with a host are remote, and s3:// and gs:// URLs must name a bucket and object.
+// rtmp, rtmps, rtsp, rtsps, srt, udp and rtp URLs with a host are streams. Unknown URL schemes are treated as local
+// paths. file:// URLs are converted to filesystem paths with FileURLToPath.
+func ResolveInput(raw string) (Input, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Input{}, errors.New("input is required")
+	}
+
+	local := Input{Raw: raw, Kind: InputLocal, Location: raw}
+
+	if windowsDrivePattern.MatchString(raw) || strings.HasPrefix(raw, `\\`) {
+		return local, nil
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return local, nil
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		if parsed.Host == "" {
+			return Input{}, errors.New("remote input URL is missing a host")
+		}
+		return Input{Raw: raw, Kind: InputRemote, Location: raw}, nil
+	case "s3":
+		if _, err := ParseS3URL(raw); err != nil {
+			return Input{}, err
+		}
+		return Input{Raw: raw, Kind: InputS3, Location: raw}, nil
+	case "gs":
+		if _, err := ParseGCSURL(raw); err != nil {
+			return Input{}, err
+		}
+		return Input{Raw: raw, Kind: InputGCS, Location: raw}, nil
+	case "rtmp", "rtmps", "rtsp", "rtsps", "srt", "udp", "rtp":
+		if parsed.Host == "" {
+			return Input{}, errors.New("stream URL is missing a host")
+		}
+		return Input{Raw: raw, Kind: InputStream, Location: raw}, nil
+	case "file":
+		path, err := FileURLToPath(raw)
+		if err != nil {
+			return Input{}, err
+		}
+		return Input{Raw: raw, Kind: InputLocal, Location: path}, nil
+	default:
+		return local, nil
+	}
+}
+
+var windowsDriveOnlyPattern = regexp.MustCompile(`^[A-Za-z]:$`)
+
+// FileURLToPath converts a file:// URL into a filesystem path.
+//
+// Percent-encoded characters are decoded and Windows forms such as file:///C:/media/a.mp4 become C:/media/a.mp4
+// (with separators converted for the current platform). URLs naming a host other than localhost are rejected,
+// since they cannot be read from the local filesystem.
+func FileURLToPath(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+
+	if !strings.EqualFold(parsed.Scheme, "file") {
+		return "", fmt.Errorf("invalid file URL %q: scheme must be file", raw)
+	}
+
+	if parsed.Opaque != "" {
+		path, err := url.PathUnescape(parsed.Opaque)
+		if err != nil {
+			return "", fmt.Errorf("invalid file URL %q: %w", raw, err)
+		}
+		return filepath.FromSlash(path), nil
+	}
+
+	path := parsed.Path
+	switch host := parsed.Host; {
+	case host == "" || strings.EqualFold(host, "localhost"):
+	case windowsDriveOnlyPattern.MatchString(host):
+		// Tolerate the malformed file://C:/media/a.mp4 form.
+		path = host + path
+	default:
+		return "", fmt.Errorf("invalid file URL %q: remote host %q is not supported", raw, host)
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("invalid file URL %q: missing path", raw)
+	}
+
+	if len(path) >= 3 && path[0] == '/' && windowsDrivePattern.MatchString(path[1:]) {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path), nil
+}
@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		kind     InputKind
+		location string
+	}{
+		{name: "relative path", raw: "media/a.mp4", kind: InputLocal, location: "media/a.mp4"},
+		{name: "absolute posix path", raw: "/mnt/media/a.mp4", kind: InputLocal, location: "/mnt/media/a.mp4"},
+		{name: "path with colon", raw: "clips/take:1.wav", kind: InputLocal, location: "clips/take:1.wav"},
+		{name: "windows drive backslash", raw: `C:\media\a.mp4`, kind: InputLocal, location: `C:\media\a.mp4`},
+		{name: "windows drive forward slash", raw: "d:/media/a.mp4", kind: InputLocal, location: "d:/media/a.mp4"},
+		{name: "windows drive root", raw: "C:", kind: InputLocal, location: "C:"},
+		{name: "unc path", raw: `\\server\share\a.mp4`, kind: InputLocal, location: `\\server\share\a.mp4`},
+		{name: "file url", raw: "file:///mnt/media/a.mp4", kind: InputLocal, location: "/mnt/media/a.mp4"},
+		{name: "http url", raw: "http://example.com/a.mp4", kind: InputRemote, location: "http://example.com/a.mp4"},
+		{name: "s3 url", raw: "s3://media-bucket/videos/a.mp4", kind: InputS3, location: "s3://media-bucket/videos/a.mp4"},
+		{name: "gcs url", raw: "gs://media-bucket/videos/a.mp4", kind: InputGCS, location: "gs://media-bucket/videos/a.mp4"},
+		{name: "rtmp url", raw: "rtmp://live.example.com/app/key", kind: InputStream, location: "rtmp://live.example.com/app/key"},
+		{name: "srt url", raw: "srt://203.0.113.5:9000?mode=caller", kind: InputStream, location: "srt://203.0.113.5:9000?mode=caller"},
+		{name: "https url with whitespace", raw: "  HTTPS://example.com/a.mp4?sig=1 ", kind: InputRemote, location: "HTTPS://example.com/a.mp4?sig=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := ResolveInput(tt.raw)
+			if err != nil {
+				t.Fatalf("ResolveInput returned error: %v", err)
+			}
+			if input.Kind != tt.kind {
+				t.Fatalf("unexpected kind: got %v want %v", input.Kind, tt.kind)
+			}
+			if input.Location != tt.location {
+				t.Fatalf("unexpected location: got %q want %q", input.Location, tt.location)
+			}
+		})
+	}
+}
+
+func TestResolveInputRejectsInvalidInputs(t *testing.T) {
+	for _, raw := range []string{"", "   ", "https:///a.mp4", "s3://bucket", "s3:///key.mp4", "gs://bucket/", "rtmp:///app/key"} {
+		if _, err := ResolveInput(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestInputDisplay(t *testing.T) {
+	local, _ := ResolveInput("media/../media/a.mp4")
+	if got := local.Display(); got != "media/a.mp4" {
+		t.Fatalf("unexpected local display %q", got)
+	}
+
+	fileURL, _ := ResolveInput("file:///mnt/media/a%20b.mp4")
+	if got := fileURL.Display(); got != "file:///mnt/media/a%20b.mp4" {
+		t.Fatalf("unexpected file URL display %q", got)
+	}
+
+	remote, _ := ResolveInput("https://example.com/a/../a.mp4")
+	if got := remote.Display(); got != "https://example.com/a/../a.mp4" {
+		t.Fatalf("unexpected remote display %q", got)
+	}
+}
+
+func TestInputIsManifest(t *testing.T) {
+	tests := []struct {
+		raw      string
+		hls      bool
+		manifest bool
+	}{
+		{raw: "live/index.m3u8", hls: true, manifest: true},
+		{raw: "https://cdn.example.com/live/INDEX.M3U8", hls: true, manifest: true},
+		{raw: "https://cdn.example.com/a.m3u8?token=abc", hls: true, manifest: true},
+		{raw: "s3://bucket/hls/master.m3u8", hls: true, manifest: true},
+		{raw: "https://cdn.example.com/vod/manifest.mpd", manifest: true},
+		{raw: "https://cdn.example.com/a.mp4?list=a.m3u8"},
+		{raw: "https://cdn.example.com/playlist.m3u8.json"},
+		{raw: "a.wav"},
+	}
+	for _, tt := range tests {
+		input, err := ResolveInput(tt.raw)
+		if err != nil {
+			t.Fatalf("ResolveInput(%q) returned error: %v", tt.raw, err)
+		}
+		if got := input.IsHLS(); got != tt.hls {
+			t.Errorf("IsHLS() for %q = %v, want %v", tt.raw, got, tt.hls)
+		}
+		if got := input.IsManifest(); got != tt.manifest {
+			t.Errorf("IsManifest() for %q = %v, want %v", tt.raw, got, tt.manifest)
+		}
+	}
+}
+
+func TestFileURLToPath(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "file:///mnt/media/foo.mov", want: "/mnt/media/foo.mov"},
+		{raw: "file://localhost/mnt/media/foo.mov", want: "/mnt/media/foo.mov"},
+		{raw: "file:///mnt/My%20Media/caf%C3%A9%231.mov", want: "/mnt/My Media/café#1.mov"},
+		{raw: "file:///C:/media/a.mp4", want: "C:/media/a.mp4"},
+		{raw: "FILE:///c:/Users/me/a%20b.wav", want: "c:/Users/me/a b.wav"},
+		{raw: "file://C:/media/a.mp4", want: "C:/media/a.mp4"},
+		{raw: "file:media/a.mp4", want: "media/a.mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := FileURLToPath(tt.raw)
+			if err != nil {
+				t.Fatalf("FileURLToPath returned error: %v", err)
+			}
+			if want := filepath.FromSlash(tt.want); got != want {
+				t.Fatalf("unexpected path: got %q want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFileURLToPathRejectsInvalidURLs(t *testing.T) {
+	for _, raw := range []string{"file://fileserver/share/a.mp4", "file://", "https://example.com/a.mp4", "file:///bad%zzescape"} {
+		if _, err := FileURLToPath(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+
+	if _, err := ResolveInput("file://fileserver/share/a.mp4"); err == nil {
+		t.Fatalf("expected ResolveInput to reject a file URL with a remote host")
+	}
+}
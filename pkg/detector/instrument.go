@@ -0,0 +1,97 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os/exec"
+	"sync"
+)
+
+// instrumentRunners wraps the detector's runners so that the recorder and the tracer see every ffmpeg process,
+// whichever runner executes it. Options may install runners in any order, so this runs once they are all applied.
+func (d *Detector) instrumentRunners() {
+	if d.recorder == nil && d.tracer == nil {
+		return
+	}
+
+	if run := d.run; run != nil {
+		d.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != d.ffmpegPath {
+				return run(ctx, name, args...)
+			}
+			finish := d.processStarted(ctx, args)
+			output, err := run(ctx, name, args...)
+			finish(err)
+			return output, err
+		}
+	}
+
+	if stream := d.stream; stream != nil {
+		d.stream = func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+			if name != d.ffmpegPath {
+				return stream(ctx, name, args...)
+			}
+			finish := d.processStarted(ctx, args)
+			reader, wait, err := stream(ctx, name, args...)
+			if err != nil {
+				finish(err)
+				return reader, wait, err
+			}
+			return reader, finishOnWait(finish, wait), nil
+		}
+	}
+
+	if split := d.split; split != nil {
+		d.split = func(ctx context.Context, name string, args ...string) (io.ReadCloser, io.ReadCloser, func() error, error) {
+			if name != d.ffmpegPath {
+				return split(ctx, name, args...)
+			}
+			finish := d.processStarted(ctx, args)
+			stdout, stderr, wait, err := split(ctx, name, args...)
+			if err != nil {
+				finish(err)
+				return stdout, stderr, wait, err
+			}
+			return stdout, stderr, finishOnWait(finish, wait), nil
+		}
+	}
+}
+
+// processStarted reports the start of an ffmpeg process run with args and returns the function that reports its
+// exit, given the error its runner returned.
+func (d *Detector) processStarted(ctx context.Context, args []string) func(error) {
+	if d.recorder != nil {
+		d.recorder.FFmpegStarted()
+	}
+	_, span := startSpan(ctx, d.tracer, spanFFmpeg)
+	span.SetAttributes(ffmpegArgsAttr(args))
+
+	return func(err error) {
+		if d.recorder != nil {
+			d.recorder.FFmpegFinished()
+		}
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			span.SetAttributes(slog.Int("ffmpeg.exit_code", 0))
+		case errors.As(err, &exitErr):
+			span.SetAttributes(slog.Int("ffmpeg.exit_code", exitErr.ExitCode()))
+		}
+		if err != nil {
+			recordSpanError(span, err)
+		}
+		span.End()
+	}
+}
+
+// finishOnWait reports the exit of a streamed ffmpeg process when its wait function first returns.
+func finishOnWait(finish func(error), wait func() error) func() error {
+	var once sync.Once
+	return func() error {
+		err := wait()
+		once.Do(func() { finish(err) })
+		return err
+	}
+}
@@ -0,0 +1,212 @@
+//go:build integration
+
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// generateWAV returns a 16-bit mono PCM WAV file containing a tone for toneSeconds followed by silenceSeconds of
+// digital silence.
+func generateWAV(sampleRate int, toneSeconds, silenceSeconds float64) []byte {
+	toneSamples := int(toneSeconds * float64(sampleRate))
+	totalSamples := toneSamples + int(silenceSeconds*float64(sampleRate))
+
+	var buf bytes.Buffer
+	dataSize := uint32(totalSamples * 2)
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+
+	for i := 0; i < totalSamples; i++ {
+		var sample int16
+		if i < toneSamples {
+			sample = int16(16000 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+		}
+		binary.Write(&buf, binary.LittleEndian, sample)
+	}
+
+	return buf.Bytes()
+}
+
+func TestIntegrationDetectSilenceFromReader(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not available")
+	}
+
+	wav := generateWAV(8000, 1, 2)
+
+	d := NewDetector()
+	result, err := d.DetectSilenceFromReader(context.Background(), bytes.NewReader(wav), "wav", DetectionOptions{
+		NoiseLevel:         -50,
+		MinSilenceDuration: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilenceFromReader returned error: %v", err)
+	}
+
+	if len(result.Intervals) != 1 {
+		t.Fatalf("expected one silence interval, got %v", result.Intervals)
+	}
+	interval := result.Intervals[0]
+	if math.Abs(interval.Start-1) > 0.05 || math.Abs(interval.End-3) > 0.05 {
+		t.Fatalf("unexpected interval %+v", interval)
+	}
+	if trailing := result.TrailingSilence(0.05); math.Abs(trailing-2) > 0.05 {
+		t.Fatalf("expected about 2s of trailing silence, got %f", trailing)
+	}
+}
+
+func TestIntegrationDetectSilenceInHLSPlaylist(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not available")
+	}
+
+	// Segment a tone followed by silence into a playlist whose segment URIs are relative, as packagers write them.
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.wav")
+	if err := os.WriteFile(source, generateWAV(8000, 2, 4), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hlsDir := filepath.Join(dir, "hls")
+	if err := os.Mkdir(hlsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	segment := exec.Command(ffmpeg, "-hide_banner", "-loglevel", "error", "-i", source, "-c:a", "aac", "-f", "hls",
+		"-hls_time", "2", "-hls_list_size", "0", "-hls_segment_filename", filepath.Join(hlsDir, "segment%d.ts"),
+		filepath.Join(hlsDir, "index.m3u8"))
+	if output, err := segment.CombinedOutput(); err != nil {
+		t.Fatalf("segment the input: %v\n%s", err, output)
+	}
+
+	server := httptest.NewServer(http.FileServer(http.Dir(hlsDir)))
+	defer server.Close()
+
+	// ffprobe is skipped for playlists; the duration comes from ffmpeg's progress.
+	d := NewDetector()
+	result, err := d.DetectSilence(context.Background(), server.URL+"/index.m3u8", DetectionOptions{
+		NoiseLevel:         -50,
+		MinSilenceDuration: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(result.Intervals) != 1 {
+		t.Fatalf("expected one silence interval, got %v", result.Intervals)
+	}
+	// The AAC encoder delays and pads the audio slightly.
+	interval := result.Intervals[0]
+	if math.Abs(interval.Start-2) > 0.1 || math.Abs(interval.End-6) > 0.1 {
+		t.Fatalf("unexpected interval %+v", interval)
+	}
+	if math.Abs(result.InputDuration-6) > 0.1 {
+		t.Fatalf("expected a duration of about 6s, got %f", result.InputDuration)
+	}
+}
+
+func TestIntegrationNativeEngineMatchesFFmpeg(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not available")
+	}
+
+	// Speech-like bursts separated by digital silence, hiss below the threshold and hiss just above it.
+	const rate = 44100
+	sections := func(channels int, level float64) [][]float64 {
+		amplitudes := func(amplitude float64) []float64 {
+			values := make([]float64, channels)
+			for c := range values {
+				values[c] = amplitude
+			}
+			// The last channel is quieter, so per-channel detection differs from the combined one.
+			values[channels-1] *= level
+			return values
+		}
+		return concatFrames(
+			toneFrames(rate, 1.3, amplitudes(0.6)...),
+			toneFrames(rate, 0.8, amplitudes(0)...),
+			toneFrames(rate, 0.7, amplitudes(0.4)...),
+			toneFrames(rate, 1.1, amplitudes(0.002)...),
+			toneFrames(rate, 0.9, amplitudes(0.5)...),
+			toneFrames(rate, 0.6, amplitudes(0.02)...),
+			toneFrames(rate, 0.4, amplitudes(0.3)...),
+			toneFrames(rate, 1.2, amplitudes(0)...),
+		)
+	}
+
+	for _, tc := range []struct {
+		name string
+		spec wavSpec
+	}{
+		{name: "16-bit mono", spec: wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: rate}},
+		{name: "16-bit stereo", spec: wavSpec{code: wavFormatPCM, bits: 16, channels: 2, sampleRate: rate}},
+		{name: "24-bit stereo", spec: wavSpec{code: wavFormatPCM, bits: 24, channels: 2, sampleRate: rate}},
+		{name: "32-bit mono", spec: wavSpec{code: wavFormatPCM, bits: 32, channels: 1, sampleRate: rate}},
+		{name: "float stereo", spec: wavSpec{code: wavFormatFloat, bits: 32, channels: 2, sampleRate: rate}},
+		{name: "double mono", spec: wavSpec{code: wavFormatFloat, bits: 64, channels: 1, sampleRate: rate}},
+	} {
+		for _, perChannel := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s per-channel=%t", tc.name, perChannel), func(t *testing.T) {
+				path := writeWAV(t, tc.spec, sections(tc.spec.channels, 0.01))
+				options := DetectionOptions{NoiseLevel: -40, MinSilenceDuration: 0.5, PerChannel: perChannel}
+
+				d := NewDetector()
+				options.Engine = EngineFFmpeg
+				want, err := d.DetectSilence(context.Background(), path, options)
+				if err != nil {
+					t.Fatalf("ffmpeg engine: %v", err)
+				}
+				options.Engine = EngineNative
+				got, err := d.DetectSilence(context.Background(), path, options)
+				if err != nil {
+					t.Fatalf("native engine: %v", err)
+				}
+
+				// ffmpeg prints times with six significant digits.
+				assertIntervalsNear(t, got.Intervals, want.Intervals, 1e-4)
+				for channel, intervals := range want.ChannelIntervals {
+					assertIntervalsNear(t, got.ChannelIntervals[channel], intervals, 1e-4)
+				}
+				if len(got.ChannelIntervals) != len(want.ChannelIntervals) {
+					t.Errorf("native channels %v, ffmpeg channels %v", got.ChannelIntervals, want.ChannelIntervals)
+				}
+				if math.Abs(got.InputDuration-want.InputDuration) > 1e-3 {
+					t.Errorf("native duration %f, ffmpeg duration %f", got.InputDuration, want.InputDuration)
+				}
+			})
+		}
+	}
+}
+
+// assertIntervalsNear fails unless got and want hold the same number of intervals with bounds within tolerance.
+func assertIntervalsNear(t *testing.T, got, want []SilenceInterval, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("native intervals %v, ffmpeg intervals %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i].Start-want[i].Start) > tolerance || math.Abs(got[i].End-want[i].End) > tolerance {
+			t.Errorf("interval %d: native %+v, ffmpeg %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package detector
+
+import (
+	"math"
+	"sort"
+)
+
+// NonSilentIntervals returns the complement of the detected silence intervals over the analysed span,
+// [WindowStart, WindowStart+InputDuration].
+//
+// When InputDuration is unknown (zero or negative) the end of the last silence interval is used as the upper
+// bound, so no trailing non-silent segment is reported. Touching or overlapping silence intervals never produce
+// zero-length segments.
+func (r DetectionResult) NonSilentIntervals() []SilenceInterval {
+	sorted := sortedIntervals(r.Intervals)
+
+	end := r.WindowStart + r.InputDuration
+	if r.InputDuration <= 0 {
+		end = r.WindowStart
+		for _, interval := range sorted {
+			if interval.End > end {
+				end = interval.End
+			}
+		}
+	}
+
+	if end <= r.WindowStart {
+		return nil
+	}
+
+	var gaps []SilenceInterval
+	cursor := r.WindowStart
+	for _, interval := range sorted {
+		if interval.Start > cursor {
+			gapEnd := interval.Start
+			if gapEnd > end {
+				gapEnd = end
+			}
+			if gapEnd > cursor {
+				gaps = append(gaps, SilenceInterval{Start: cursor, End: gapEnd, Duration: gapEnd - cursor})
+			}
+		}
+		if interval.End > cursor {
+			cursor = interval.End
+		}
+		if cursor >= end {
+			return gaps
+		}
+	}
+
+	if end > cursor {
+		gaps = append(gaps, SilenceInterval{Start: cursor, End: end, Duration: end - cursor})
+	}
+
+	return gaps
+}
+
+// MergeIntervals coalesces intervals separated by gaps of at most gap seconds.
+//
+// The input may be unsorted or overlapping; the returned slice is a new, start-ordered slice with Start, End, and
+// Duration recomputed for every merged interval. A negative gap is treated as zero, which still combines
+// overlapping and touching intervals.
+func MergeIntervals(intervals []SilenceInterval, gap float64) []SilenceInterval {
+	if gap < 0 {
+		gap = 0
+	}
+
+	sorted := sortedIntervals(intervals)
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	merged := []SilenceInterval{sorted[0]}
+	for _, interval := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if interval.Start-last.End <= gap {
+			if interval.End > last.End {
+				last.End = interval.End
+			}
+			last.Duration = last.End - last.Start
+			// The levels measured for a part do not describe the merged interval.
+			last.MeanVolumeDB, last.MaxVolumeDB = nil, nil
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// PadIntervals moves the start of every interval startPad seconds later and its end endPad seconds earlier, so that
+// cuts made at the returned boundaries keep a little silence around the speech. Negative pads grow the intervals
+// instead. The intervals are clamped to [0, clampTo], or only to 0 when clampTo is zero or less, and those left
+// without duration are dropped. Pads must be finite; a NaN or infinite pad is treated as zero rather than dropping
+// every interval.
+//
+// The returned slice is a new, start-ordered slice in which intervals that overlap or touch after padding are
+// merged, with Duration recomputed for every interval.
+func PadIntervals(intervals []SilenceInterval, startPad, endPad, clampTo float64) []SilenceInterval {
+	if math.IsNaN(startPad) || math.IsInf(startPad, 0) {
+		startPad = 0
+	}
+	if math.IsNaN(endPad) || math.IsInf(endPad, 0) {
+		endPad = 0
+	}
+
+	var padded []SilenceInterval
+	for _, interval := range intervals {
+		start := math.Max(interval.Start+startPad, 0)
+		end := interval.End - endPad
+		if clampTo > 0 {
+			end = math.Min(end, clampTo)
+		}
+		if !(end > start) {
+			continue
+		}
+		padded = append(padded, SilenceInterval{Start: start, End: end, Duration: end - start})
+	}
+	return MergeIntervals(padded, 0)
+}
+
+// FilterIntervals returns a copy of the result that keeps only intervals whose duration lies within [min, max].
+//
+// A max of zero (or less) means there is no upper bound. InputDuration is preserved, so FullySilent and the
+// summary statistics reflect the filtered intervals against the original input.
+func (r DetectionResult) FilterIntervals(min, max float64) DetectionResult {
+	filtered := r
+	filtered.Intervals = filterIntervals(r.Intervals, min, max)
+
+	if r.ChannelIntervals != nil {
+		filtered.ChannelIntervals = make(map[int][]SilenceInterval, len(r.ChannelIntervals))
+		for channel, intervals := range r.ChannelIntervals {
+			filtered.ChannelIntervals[channel] = filterIntervals(intervals, min, max)
+		}
+	}
+
+	return filtered
+}
+
+func filterIntervals(intervals []SilenceInterval, min, max float64) []SilenceInterval {
+	var filtered []SilenceInterval
+	for _, interval := range intervals {
+		duration := interval.End - interval.Start
+		if duration < min {
+			continue
+		}
+		if max > 0 && duration > max {
+			continue
+		}
+		interval.Duration = duration
+		filtered = append(filtered, interval)
+	}
+	return filtered
+}
+
+// sortedIntervals returns a copy of intervals ordered by start time.
+func sortedIntervals(intervals []SilenceInterval) []SilenceInterval {
+	sorted := append([]SilenceInterval(nil), intervals...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	return sorted
+}
+
+// TotalSilence returns the number of seconds covered by silence intervals.
+//
+// Overlapping intervals are counted once.
+func (r DetectionResult) TotalSilence() float64 {
+	var total float64
+	for _, interval := range unionIntervals(r.Intervals) {
+		total += interval.End - interval.Start
+	}
+	return total
+}
+
+// SilenceRatio returns the fraction of the input duration covered by silence, in the range [0, 1].
+//
+// It returns 0 when InputDuration is unknown (zero or negative), since the ratio cannot be determined.
+func (r DetectionResult) SilenceRatio() float64 {
+	if r.InputDuration <= 0 {
+		return 0
+	}
+
+	ratio := r.TotalSilence() / r.InputDuration
+	if ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// SilenceCoverage returns the fraction of the analysed span, from WindowStart for InputDuration seconds, that is
+// covered by silence, in the range [0, 1]. Overlapping intervals are counted once, and the parts of intervals
+// outside the span are ignored. It returns 0 when InputDuration is unknown (zero or negative).
+func (r DetectionResult) SilenceCoverage() float64 {
+	if r.InputDuration <= 0 {
+		return 0
+	}
+	return r.silenceWithin(r.WindowStart, r.WindowStart+r.InputDuration) / r.InputDuration
+}
+
+// silenceWithin returns the number of seconds between start and end that are covered by silence.
+func (r DetectionResult) silenceWithin(start, end float64) float64 {
+	var covered float64
+	for _, interval := range unionIntervals(r.Intervals) {
+		if overlap := min(interval.End, end) - max(interval.Start, start); overlap > 0 {
+			covered += overlap
+		}
+	}
+	return covered
+}
+
+// EffectivelySilent reports whether silence covers at least minCoverage of the input, as a fraction such as 0.99,
+// so that a few short pops do not make an otherwise dead input count as sound. It returns false when the answer is
+// unknown; EffectivelySilentStatus tells the two apart.
+func (r DetectionResult) EffectivelySilent(minCoverage float64) bool {
+	silent, determinate := r.EffectivelySilentStatus(minCoverage)
+	return silent && determinate
+}
+
+// EffectivelySilentStatus reports whether silence covers at least minCoverage of the input, and whether that could
+// be determined. As with FullySilentStatus, an unknown input duration and a partial result are indeterminate, and an
+// input whose duration is known to be zero counts as silent. A truncated result is determinate when the answer holds
+// whether or not the input is silent after its last interval.
+func (r DetectionResult) EffectivelySilentStatus(minCoverage float64) (silent, determinate bool) {
+	if r.InputDuration <= 0 {
+		return r.DurationKnown, r.DurationKnown
+	}
+	if r.Partial {
+		return false, false
+	}
+
+	coverage := r.SilenceCoverage()
+	if !r.Truncated {
+		return coverage >= minCoverage, true
+	}
+
+	// Silence after the last interval of a truncated result is unknown, so coverage is only a lower bound.
+	end := r.WindowStart + r.InputDuration
+	lastEnd := r.WindowStart
+	for _, interval := range r.Intervals {
+		lastEnd = max(lastEnd, min(interval.End, end))
+	}
+	switch {
+	case coverage >= minCoverage:
+		return true, true
+	case coverage+(end-lastEnd)/r.InputDuration < minCoverage:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// LongestInterval returns the silence interval with the greatest duration.
+//
+// The boolean result is false when no intervals were detected.
+func (r DetectionResult) LongestInterval() (SilenceInterval, bool) {
+	if len(r.Intervals) == 0 {
+		return SilenceInterval{}, false
+	}
+
+	longest := r.Intervals[0]
+	for _, interval := range r.Intervals[1:] {
+		if interval.Duration > longest.Duration {
+			longest = interval
+		}
+	}
+	return longest, true
+}
+
+// IntervalAt returns the silence interval that contains t, boundaries included, found by binary search.
+//
+// Detection reports intervals in start order without overlaps, which the search relies on; intervals that are not
+// in start order are searched in a sorted copy. The boolean result is false when t lies in no interval.
+func (r DetectionResult) IntervalAt(t float64) (SilenceInterval, bool) {
+	return intervalNear(r.Intervals, t, 0)
+}
+
+// IsSilentAt reports whether t lies within a silence interval, or within tolerance seconds of one, such as a player
+// position that is only known to the frame. A negative tolerance is treated as zero.
+func (r DetectionResult) IsSilentAt(t, tolerance float64) bool {
+	_, ok := intervalNear(r.Intervals, t, max(tolerance, 0))
+	return ok
+}
+
+// intervalNear returns the interval that contains t once widened by tolerance on both sides. It is the last one to
+// start by t+tolerance: without overlaps, that one also ends last.
+func intervalNear(intervals []SilenceInterval, t, tolerance float64) (SilenceInterval, bool) {
+	if !sort.SliceIsSorted(intervals, func(i, j int) bool { return intervals[i].Start < intervals[j].Start }) {
+		intervals = sortedIntervals(intervals)
+	}
+
+	i := sort.Search(len(intervals), func(i int) bool { return intervals[i].Start > t+tolerance }) - 1
+	if i < 0 || intervals[i].End+tolerance < t {
+		return SilenceInterval{}, false
+	}
+	return intervals[i], true
+}
+
+// unionIntervals returns the sorted union of intervals with overlapping or touching entries combined.
+func unionIntervals(intervals []SilenceInterval) []SilenceInterval {
+	return MergeIntervals(intervals, 0)
+}
+
+// intersectChannels returns the periods where all of channels channels are silent, given the silence intervals of
+// each channel keyed by index. A channel without intervals is never silent, so the intersection is then empty.
+func intersectChannels(channelIntervals map[int][]SilenceInterval, channels int) []SilenceInterval {
+	if channels <= 0 || len(channelIntervals) < channels {
+		return nil
+	}
+	var common []SilenceInterval
+	for channel := range channels {
+		intervals := unionIntervals(channelIntervals[channel])
+		if channel == 0 {
+			common = intervals
+			continue
+		}
+		var next []SilenceInterval
+		for i, j := 0, 0; i < len(common) && j < len(intervals); {
+			start, end := max(common[i].Start, intervals[j].Start), min(common[i].End, intervals[j].End)
+			if end > start {
+				next = append(next, SilenceInterval{Start: start, End: end, Duration: end - start})
+			}
+			if common[i].End < intervals[j].End {
+				i++
+			} else {
+				j++
+			}
+		}
+		common = next
+	}
+	return common
+}
+
+// LeadingSilence returns the duration of silence anchored at the start of the input.
+//
+// Intervals separated by gaps no larger than tolerance are treated as contiguous. It returns 0 when the input
+// does not start with silence.
+func (r DetectionResult) LeadingSilence(tolerance float64) float64 {
+	cursor := r.WindowStart
+	for _, interval := range unionIntervals(r.Intervals) {
+		if interval.Start-cursor > tolerance {
+			break
+		}
+		if interval.End > cursor {
+			cursor = interval.End
+		}
+	}
+
+	leading := cursor - r.WindowStart
+	if r.InputDuration > 0 && leading > r.InputDuration {
+		return r.InputDuration
+	}
+	return leading
+}
+
+// TrailingSilence returns the duration of silence anchored at the end of the input.
+//
+// Intervals separated by gaps no larger than tolerance are treated as contiguous. It returns 0 when the input
+// does not end with silence or when InputDuration is unknown.
+func (r DetectionResult) TrailingSilence(tolerance float64) float64 {
+	if r.InputDuration <= 0 {
+		return 0
+	}
+
+	end := r.WindowStart + r.InputDuration
+	union := unionIntervals(r.Intervals)
+	cursor := end
+	for i := len(union) - 1; i >= 0; i-- {
+		interval := union[i]
+		if cursor-interval.End > tolerance {
+			break
+		}
+		if interval.Start < cursor {
+			cursor = interval.Start
+		}
+	}
+
+	if cursor < r.WindowStart {
+		cursor = r.WindowStart
+	}
+	return end - cursor
+}
+
+// Shift returns a copy of the result with WindowStart and every interval, including those of every channel and
+// stream, moved offset seconds later, such as to the position of a part within a longer recording. A negative offset
+// moves them earlier. InputDuration is unchanged.
+func (r DetectionResult) Shift(offset float64) DetectionResult {
+	shifted := r
+	shifted.WindowStart += offset
+	shifted.Intervals = shiftIntervals(r.Intervals, offset)
+
+	if r.ChannelIntervals != nil {
+		shifted.ChannelIntervals = make(map[int][]SilenceInterval, len(r.ChannelIntervals))
+		for channel, intervals := range r.ChannelIntervals {
+			shifted.ChannelIntervals[channel] = shiftIntervals(intervals, offset)
+		}
+	}
+	if r.StreamIntervals != nil {
+		shifted.StreamIntervals = make([][]SilenceInterval, len(r.StreamIntervals))
+		for i, intervals := range r.StreamIntervals {
+			shifted.StreamIntervals[i] = shiftIntervals(intervals, offset)
+		}
+	}
+
+	return shifted
+}
+
+func shiftIntervals(intervals []SilenceInterval, offset float64) []SilenceInterval {
+	if intervals == nil {
+		return nil
+	}
+
+	shifted := make([]SilenceInterval, len(intervals))
+	for i, interval := range intervals {
+		interval.Start += offset
+		interval.End += offset
+		shifted[i] = interval
+	}
+	return shifted
+}
+
+// Round returns a copy of the result with the input duration, the window start and every interval rounded to
+// precision decimal places, so that reports do not carry floating-point noise such as 3.5000000000000004. The
+// intervals of the copy are sorted by start time. A negative precision leaves the times unrounded.
+func (r DetectionResult) Round(precision int) DetectionResult {
+	rounded := r
+	rounded.InputDuration = roundTo(r.InputDuration, precision)
+	rounded.WindowStart = roundTo(r.WindowStart, precision)
+	rounded.Intervals = roundIntervals(r.Intervals, precision)
+
+	if r.ChannelIntervals != nil {
+		rounded.ChannelIntervals = make(map[int][]SilenceInterval, len(r.ChannelIntervals))
+		for channel, intervals := range r.ChannelIntervals {
+			rounded.ChannelIntervals[channel] = roundIntervals(intervals, precision)
+		}
+	}
+
+	return rounded
+}
+
+// roundIntervals returns a copy of intervals, sorted by start time, with their times rounded to precision decimal
+// places. The duration is rounded on its own rather than recomputed, so that it stays within rounding of the
+// measured value.
+func roundIntervals(intervals []SilenceInterval, precision int) []SilenceInterval {
+	if intervals == nil {
+		return nil
+	}
+
+	rounded := sortedIntervals(intervals)
+	for i := range rounded {
+		rounded[i].Start = roundTo(rounded[i].Start, precision)
+		rounded[i].End = roundTo(rounded[i].End, precision)
+		rounded[i].Duration = roundTo(rounded[i].Duration, precision)
+	}
+	return rounded
+}
+
+// roundTo rounds v to precision decimal places. A negative precision returns v unchanged.
+func roundTo(v float64, precision int) float64 {
+	if precision < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	scale := math.Pow10(precision)
+	rounded := math.Round(v*scale) / scale
+	if math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		return v
+	}
+	return rounded
+}
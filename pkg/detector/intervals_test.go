@@ -0,0 +1,437 @@
+package detector
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func assertIntervals(t *testing.T, got, want []SilenceInterval) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d intervals, got %d (%v)", len(want), len(got), got)
+	}
+	for i := range want {
+		assertFloatEqual(t, got[i].Start, want[i].Start)
+		assertFloatEqual(t, got[i].End, want[i].End)
+		assertFloatEqual(t, got[i].Duration, want[i].Duration)
+	}
+}
+
+func TestNonSilentIntervals(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 20,
+		Intervals: []SilenceInterval{
+			{Start: 2, End: 4, Duration: 2},
+			{Start: 10, End: 12, Duration: 2},
+		},
+	}
+
+	assertIntervals(t, result.NonSilentIntervals(), []SilenceInterval{
+		{Start: 0, End: 2, Duration: 2},
+		{Start: 4, End: 10, Duration: 6},
+		{Start: 12, End: 20, Duration: 8},
+	})
+}
+
+func TestNonSilentIntervalsLeadingAndTouchingSilence(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 10,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 3, Duration: 3},
+			{Start: 3, End: 5, Duration: 2},
+			{Start: 8, End: 10, Duration: 2},
+		},
+	}
+
+	assertIntervals(t, result.NonSilentIntervals(), []SilenceInterval{
+		{Start: 5, End: 8, Duration: 3},
+	})
+}
+
+func TestNonSilentIntervalsUnknownDuration(t *testing.T) {
+	result := DetectionResult{
+		Intervals: []SilenceInterval{
+			{Start: 1, End: 2, Duration: 1},
+			{Start: 5, End: 6, Duration: 1},
+		},
+	}
+
+	assertIntervals(t, result.NonSilentIntervals(), []SilenceInterval{
+		{Start: 0, End: 1, Duration: 1},
+		{Start: 2, End: 5, Duration: 3},
+	})
+
+	if gaps := (DetectionResult{}).NonSilentIntervals(); gaps != nil {
+		t.Fatalf("expected no intervals for empty result, got %v", gaps)
+	}
+}
+
+func TestNonSilentIntervalsWithoutSilence(t *testing.T) {
+	result := DetectionResult{InputDuration: 7.5}
+
+	assertIntervals(t, result.NonSilentIntervals(), []SilenceInterval{
+		{Start: 0, End: 7.5, Duration: 7.5},
+	})
+}
+
+func TestSummaryStatistics(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 20,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 4, Duration: 4},
+			{Start: 3, End: 5, Duration: 2},
+			{Start: 10, End: 16, Duration: 6},
+		},
+	}
+
+	assertFloatEqual(t, result.TotalSilence(), 11)
+	assertFloatEqual(t, result.SilenceRatio(), 0.55)
+
+	longest, ok := result.LongestInterval()
+	if !ok {
+		t.Fatalf("expected a longest interval")
+	}
+	assertFloatEqual(t, longest.Start, 10)
+	assertFloatEqual(t, longest.Duration, 6)
+}
+
+func TestSummaryStatisticsWithoutData(t *testing.T) {
+	result := DetectionResult{
+		Intervals: []SilenceInterval{{Start: 1, End: 3, Duration: 2}},
+	}
+
+	assertFloatEqual(t, result.TotalSilence(), 2)
+	assertFloatEqual(t, result.SilenceRatio(), 0)
+
+	if _, ok := (DetectionResult{}).LongestInterval(); ok {
+		t.Fatalf("expected no longest interval for empty result")
+	}
+}
+
+func TestIntervalAt(t *testing.T) {
+	result := DetectionResult{Intervals: []SilenceInterval{
+		{Start: 30, End: 32, Duration: 2},
+		{Start: 0, End: 1.5, Duration: 1.5},
+		{Start: 10, End: 14, Duration: 4},
+	}}
+
+	tests := []struct {
+		at        float64
+		wantStart float64
+		wantOK    bool
+	}{
+		{at: -1},
+		{at: 0, wantStart: 0, wantOK: true},
+		{at: 1.5, wantStart: 0, wantOK: true},
+		{at: 5},
+		{at: 12.25, wantStart: 10, wantOK: true},
+		{at: 31, wantStart: 30, wantOK: true},
+		{at: 40},
+	}
+	for _, tt := range tests {
+		interval, ok := result.IntervalAt(tt.at)
+		if ok != tt.wantOK || (ok && interval.Start != tt.wantStart) {
+			t.Fatalf("IntervalAt(%v) = %+v, %v; want start %v, %v", tt.at, interval, ok, tt.wantStart, tt.wantOK)
+		}
+	}
+	if result.Intervals[0].Start != 30 {
+		t.Fatalf("expected the intervals to be left unsorted, got %+v", result.Intervals)
+	}
+
+	if result.IsSilentAt(9.9, 0) || !result.IsSilentAt(9.9, 0.1) || !result.IsSilentAt(14.05, 0.1) || result.IsSilentAt(14.05, -1) {
+		t.Fatal("unexpected IsSilentAt verdicts near the interval at 10-14")
+	}
+	if (DetectionResult{}).IsSilentAt(0, 1) {
+		t.Fatal("expected no silence without intervals")
+	}
+}
+
+func TestSilenceCoverage(t *testing.T) {
+	result := DetectionResult{
+		WindowStart:   10,
+		InputDuration: 20,
+		Intervals: []SilenceInterval{
+			{Start: 5, End: 14, Duration: 9},
+			{Start: 12, End: 16, Duration: 4},
+			{Start: 28, End: 35, Duration: 7},
+		},
+	}
+
+	// Silence before and after the window is ignored, and the overlap is counted once.
+	assertFloatEqual(t, result.SilenceCoverage(), 0.4)
+	assertFloatEqual(t, (DetectionResult{Intervals: result.Intervals}).SilenceCoverage(), 0)
+}
+
+func TestEffectivelySilentStatus(t *testing.T) {
+	// An hour of silence broken by a 20 ms pop.
+	popped := []SilenceInterval{{Start: 0, End: 1800, Duration: 1800}, {Start: 1800.02, End: 3600, Duration: 1799.98}}
+	tests := []struct {
+		name            string
+		result          DetectionResult
+		wantSilent      bool
+		wantDeterminate bool
+	}{
+		{name: "pop in an hour", result: DetectionResult{InputDuration: 3600, Intervals: popped}, wantSilent: true, wantDeterminate: true},
+		{name: "mostly sound", result: DetectionResult{InputDuration: 3600, Intervals: popped[:1]}, wantDeterminate: true},
+		{name: "unknown duration", result: DetectionResult{Intervals: popped}},
+		{name: "empty input", result: DetectionResult{DurationKnown: true}, wantSilent: true, wantDeterminate: true},
+		{name: "partial", result: DetectionResult{InputDuration: 3600, Intervals: popped, Partial: true}},
+		{name: "truncated but covered", result: DetectionResult{InputDuration: 3600, Intervals: popped, Truncated: true}, wantSilent: true, wantDeterminate: true},
+		{name: "truncated with enough sound", result: DetectionResult{InputDuration: 3600, Intervals: []SilenceInterval{{Start: 100, End: 200, Duration: 100}}, Truncated: true}, wantDeterminate: true},
+		{name: "truncated with the rest unknown", result: DetectionResult{InputDuration: 3600, Intervals: popped[:1], Truncated: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silent, determinate := tt.result.EffectivelySilentStatus(0.99)
+			if silent != tt.wantSilent || determinate != tt.wantDeterminate {
+				t.Fatalf("got silent=%v determinate=%v, want %v and %v", silent, determinate, tt.wantSilent, tt.wantDeterminate)
+			}
+			if got := tt.result.EffectivelySilent(0.99); got != (tt.wantSilent && tt.wantDeterminate) {
+				t.Fatalf("EffectivelySilent returned %v", got)
+			}
+		})
+	}
+}
+
+func TestLeadingAndTrailingSilence(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 30,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 2, Duration: 2},
+			{Start: 2.0005, End: 3, Duration: 0.9995},
+			{Start: 10, End: 12, Duration: 2},
+			{Start: 25, End: 30, Duration: 5},
+		},
+	}
+
+	assertFloatEqual(t, result.LeadingSilence(1e-3), 3)
+	assertFloatEqual(t, result.TrailingSilence(1e-3), 5)
+
+	loud := DetectionResult{
+		InputDuration: 30,
+		Intervals:     []SilenceInterval{{Start: 10, End: 12, Duration: 2}},
+	}
+
+	assertFloatEqual(t, loud.LeadingSilence(1e-3), 0)
+	assertFloatEqual(t, loud.TrailingSilence(1e-3), 0)
+}
+
+func TestTrailingSilenceFromSynthesizedInterval(t *testing.T) {
+	output := `
+[silencedetect @ 0x123] silence_start: 7.500000
+frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:10.00 bitrate=   0.0kbits/s speed=1x
+`
+
+	intervals, duration, err := parseSilenceOutput(output)
+	if err != nil {
+		t.Fatalf("parseSilenceOutput returned error: %v", err)
+	}
+
+	result := DetectionResult{Intervals: intervals, InputDuration: duration}
+	assertFloatEqual(t, result.TrailingSilence(1e-3), 2.5)
+	assertFloatEqual(t, result.LeadingSilence(1e-3), 0)
+}
+
+func TestTrailingSilenceUnknownDuration(t *testing.T) {
+	result := DetectionResult{
+		Intervals: []SilenceInterval{{Start: 0, End: 3, Duration: 3}},
+	}
+
+	assertFloatEqual(t, result.TrailingSilence(1e-3), 0)
+	assertFloatEqual(t, result.LeadingSilence(1e-3), 3)
+}
+
+func TestMergeIntervals(t *testing.T) {
+	intervals := []SilenceInterval{
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 0, End: 2, Duration: 2},
+		{Start: 2.03, End: 4, Duration: 1.97},
+		{Start: 3, End: 5, Duration: 2},
+		{Start: 12.5, End: 13, Duration: 0.5},
+	}
+
+	assertIntervals(t, MergeIntervals(intervals, 0.05), []SilenceInterval{
+		{Start: 0, End: 5, Duration: 5},
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 12.5, End: 13, Duration: 0.5},
+	})
+
+	if merged := MergeIntervals(nil, 1); merged != nil {
+		t.Fatalf("expected nil for empty input, got %v", merged)
+	}
+}
+
+func TestMergeIntervalsPreservesFullySilent(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 6,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 2, Duration: 2},
+			{Start: 2.04, End: 4, Duration: 1.96},
+			{Start: 4.05, End: 6, Duration: 1.95},
+		},
+	}
+
+	const tolerance = 0.05
+	merged := result
+	merged.Intervals = MergeIntervals(result.Intervals, tolerance)
+
+	if len(merged.Intervals) != 1 {
+		t.Fatalf("expected intervals to merge into one, got %v", merged.Intervals)
+	}
+	if result.FullySilent(tolerance) != merged.FullySilent(tolerance) {
+		t.Fatalf("FullySilent changed after merging: before %v after %v", result.FullySilent(tolerance), merged.FullySilent(tolerance))
+	}
+}
+
+func TestPadIntervals(t *testing.T) {
+	intervals := []SilenceInterval{
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 0, End: 3, Duration: 3},
+		{Start: 5, End: 5.3, Duration: 0.3},
+		{Start: 19, End: 20, Duration: 1},
+	}
+
+	assertIntervals(t, PadIntervals(intervals, 0.2, 0.2, 20), []SilenceInterval{
+		{Start: 0.2, End: 2.8, Duration: 2.6},
+		{Start: 10.2, End: 11.8, Duration: 1.6},
+		{Start: 19.2, End: 19.8, Duration: 0.6},
+	})
+	assertIntervals(t, PadIntervals(intervals, -1, -1.5, 20), []SilenceInterval{
+		{Start: 0, End: 6.8, Duration: 6.8},
+		{Start: 9, End: 13.5, Duration: 4.5},
+		{Start: 18, End: 20, Duration: 2},
+	})
+	assertIntervals(t, PadIntervals(intervals, 0, -1, 0), []SilenceInterval{
+		{Start: 0, End: 4, Duration: 4},
+		{Start: 5, End: 6.3, Duration: 1.3},
+		{Start: 10, End: 13, Duration: 3},
+		{Start: 19, End: 21, Duration: 2},
+	})
+
+	if padded := PadIntervals(intervals, 2, 2, 20); padded != nil {
+		t.Fatalf("expected every interval to be dropped, got %v", padded)
+	}
+
+	// Non-finite pads are ignored instead of dropping every interval.
+	assertIntervals(t, PadIntervals(intervals, math.NaN(), math.Inf(1), 20), PadIntervals(intervals, 0, 0, 20))
+}
+
+func TestPadIntervalsStaysWithinClamp(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 1000; i++ {
+		clampTo := random.Float64() * 100
+		intervals := make([]SilenceInterval, random.IntN(8))
+		for j := range intervals {
+			start := random.Float64()*120 - 10
+			end := start + random.Float64()*20
+			intervals[j] = SilenceInterval{Start: start, End: end, Duration: end - start}
+		}
+		startPad, endPad := random.Float64()*4-2, random.Float64()*4-2
+
+		padded := PadIntervals(intervals, startPad, endPad, clampTo)
+		for j, interval := range padded {
+			if interval.Start < 0 || interval.End > clampTo || interval.End <= interval.Start {
+				t.Fatalf("PadIntervals(%v, %v, %v, %v) returned %v outside [0, %v]", intervals, startPad, endPad, clampTo, interval, clampTo)
+			}
+			assertFloatEqual(t, interval.Duration, interval.End-interval.Start)
+			if j > 0 && interval.Start <= padded[j-1].End {
+				t.Fatalf("PadIntervals(%v, %v, %v, %v) returned overlapping intervals %v", intervals, startPad, endPad, clampTo, padded)
+			}
+		}
+	}
+}
+
+func TestFilterIntervals(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 30,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 0.3, Duration: 0.3},
+			{Start: 5, End: 7, Duration: 2},
+			{Start: 10, End: 20, Duration: 10},
+		},
+	}
+
+	filtered := result.FilterIntervals(1, 5)
+	assertFloatEqual(t, filtered.InputDuration, 30)
+	assertIntervals(t, filtered.Intervals, []SilenceInterval{
+		{Start: 5, End: 7, Duration: 2},
+	})
+
+	unbounded := result.FilterIntervals(1, 0)
+	assertIntervals(t, unbounded.Intervals, []SilenceInterval{
+		{Start: 5, End: 7, Duration: 2},
+		{Start: 10, End: 20, Duration: 10},
+	})
+
+	if len(result.Intervals) != 3 {
+		t.Fatalf("expected original result to be unchanged, got %v", result.Intervals)
+	}
+}
+
+func TestFilterIntervalsKeepsFullySilentConsistent(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 6,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 3, Duration: 3},
+			{Start: 3, End: 6, Duration: 3},
+		},
+	}
+
+	if !result.FilterIntervals(1, 0).FullySilent(1e-6) {
+		t.Fatalf("expected filtered result to remain fully silent")
+	}
+	if result.FilterIntervals(4, 0).FullySilent(1e-6) {
+		t.Fatalf("expected result without intervals to not be fully silent")
+	}
+}
+
+func TestDetectionResultRound(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 12.00049,
+		WindowStart:   1.23456,
+		Intervals: []SilenceInterval{
+			{Start: 7.1, End: 10.6000000000000005, Duration: 3.5000000000000004},
+			{Start: 1.0004, End: 3.0006, Duration: 2.0002},
+		},
+		ChannelIntervals: map[int][]SilenceInterval{1: {{Start: 0.12345, End: 0.5, Duration: 0.37655}}},
+	}
+
+	rounded := result.Round(3)
+
+	assertFloatEqual(t, rounded.InputDuration, 12)
+	assertFloatEqual(t, rounded.WindowStart, 1.235)
+	want := []SilenceInterval{{Start: 1, End: 3.001, Duration: 2}, {Start: 7.1, End: 10.6, Duration: 3.5}}
+	if !reflect.DeepEqual(rounded.Intervals, want) {
+		t.Fatalf("expected sorted, rounded intervals %v, got %v", want, rounded.Intervals)
+	}
+	if got := rounded.ChannelIntervals[1][0]; got != (SilenceInterval{Start: 0.123, End: 0.5, Duration: 0.377}) {
+		t.Fatalf("unexpected channel interval %v", got)
+	}
+	if result.Intervals[0].Start != 7.1 || result.ChannelIntervals[1][0].Start != 0.12345 {
+		t.Fatal("Round modified the original result")
+	}
+
+	if whole := result.Round(0); whole.Intervals[0] != (SilenceInterval{Start: 1, End: 3, Duration: 2}) {
+		t.Fatalf("unexpected interval at precision 0: %v", whole.Intervals[0])
+	}
+	if unrounded := result.Round(-1); unrounded.WindowStart != result.WindowStart || unrounded.Intervals[1] != result.Intervals[0] {
+		t.Fatalf("expected a negative precision to keep the times, got %+v", unrounded)
+	}
+}
+
+func TestIntervalLevelsSurviveRoundingButNotMerging(t *testing.T) {
+	measured := SilenceInterval{Start: 1.00004, End: 2, Duration: 0.99996, MeanVolumeDB: float64Ptr(-60), MaxVolumeDB: float64Ptr(-50)}
+
+	rounded := DetectionResult{Intervals: []SilenceInterval{measured}}.Round(3)
+	if rounded.Intervals[0].MeanVolumeDB == nil || rounded.Intervals[0].MaxVolumeDB == nil {
+		t.Fatal("expected rounding to keep the levels")
+	}
+
+	merged := MergeIntervals([]SilenceInterval{measured, {Start: 2.1, End: 3, Duration: 0.9}}, 0.5)
+	if len(merged) != 1 || merged[0].MeanVolumeDB != nil || merged[0].MaxVolumeDB != nil {
+		t.Fatalf("expected a merged interval without levels, got %+v", merged)
+	}
+}
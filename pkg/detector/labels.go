@@ -0,0 +1,29 @@
+package detector
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteAudacityLabels writes intervals as an Audacity label track, one "start\tend\tsilence N" line per interval in
+// start order, numbered from 1, with times in seconds. Measured levels are added to the label, as in "silence 1 (mean
+// -61.2dB, max -48.0dB)". Audacity imports the file with File > Import > Labels.
+func WriteAudacityLabels(w io.Writer, intervals []SilenceInterval) error {
+	return WriteAudacityLabelsNamed(w, intervals, "silence")
+}
+
+// WriteAudacityLabelsNamed is like WriteAudacityLabels but labels the intervals "name N", for example "audio 1" for
+// the regions returned by DetectionResult.NonSilentIntervals.
+func WriteAudacityLabelsNamed(w io.Writer, intervals []SilenceInterval, name string) error {
+	for i, interval := range sortedIntervals(intervals) {
+		label := fmt.Sprintf("%s %d", name, i+1)
+		if levels := levelSummary(interval); levels != "" {
+			label += " (" + levels + ")"
+		}
+		// Audacity itself writes labels with microsecond precision.
+		if _, err := fmt.Fprintf(w, "%.6f\t%.6f\t%s\n", interval.Start, interval.End, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
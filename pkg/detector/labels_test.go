@@ -0,0 +1,55 @@
+package detector
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriteAudacityLabels(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteAudacityLabels(&buf, []SilenceInterval{
+		{Start: 12.5, End: 14, Duration: 1.5},
+		{Start: 0, End: 0.30000000000000004, Duration: 0.30000000000000004},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "0.000000\t0.300000\tsilence 1\n12.500000\t14.000000\tsilence 2\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected labels %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteAudacityLabelsNamed(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 10,
+		Intervals:     []SilenceInterval{{Start: 2, End: 4, Duration: 2}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAudacityLabelsNamed(&buf, result.NonSilentIntervals(), "audio"); err != nil {
+		t.Fatal(err)
+	}
+	want := "0.000000\t2.000000\taudio 1\n4.000000\t10.000000\taudio 2\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected labels %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteAudacityLabels(&buf, nil); err != nil || buf.Len() != 0 {
+		t.Fatalf("expected no labels, got %q and %v", buf.String(), err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteAudacityLabelsReturnsWriteErrors(t *testing.T) {
+	err := WriteAudacityLabels(failingWriter{}, []SilenceInterval{{Start: 0, End: 1, Duration: 1}})
+	if err == nil || err.Error() != "disk full" {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+}
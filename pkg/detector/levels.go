@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MaxMeasuredIntervals bounds the number of intervals DetectionOptions.MeasureIntervalLevels measures, since each
+// costs an ffmpeg run. Later intervals are left without levels.
+const MaxMeasuredIntervals = 100
+
+// intervalLevelWorkers is the number of ffmpeg runs measuring interval levels at the same time.
+const intervalLevelWorkers = 4
+
+// measureIntervalLevels sets the mean and peak volume of the first MaxMeasuredIntervals of intervals, which hold
+// absolute times in inputPath. The first failing run cancels the others and its error is returned.
+func (d *Detector) measureIntervalLevels(ctx context.Context, inputPath string, options DetectionOptions, intervals []SilenceInterval) error {
+	count := min(len(intervals), MaxMeasuredIntervals)
+	if count == 0 {
+		return nil
+	}
+	if len(intervals) > count {
+		d.debug(ctx, "measuring the levels of the first intervals only", "intervals", len(intervals), "measured", count)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(intervalLevelWorkers, count) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mean, peak, err := d.measureIntervalLevel(runCtx, inputPath, options, intervals[i])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("measuring the level of silence %d: %w", i+1, err)
+					}
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				intervals[i].MeanVolumeDB, intervals[i].MaxVolumeDB = mean, peak
+			}
+		}()
+	}
+
+schedule:
+	for i := range count {
+		select {
+		case jobs <- i:
+		case <-runCtx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// A cancelled ctx is reported as such rather than as the ffmpeg run it happened to kill.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// measureIntervalLevel runs ffmpeg's volumedetect filter over interval of inputPath, seeking to its start, and
+// returns the mean and peak volume. Either is nil when ffmpeg did not report a finite value.
+func (d *Detector) measureIntervalLevel(ctx context.Context, inputPath string, options DetectionOptions, interval SilenceInterval) (mean, peak *float64, err error) {
+	window := options
+	window.StartOffset = interval.Start
+	window.AnalyzeDuration = interval.End - interval.Start
+	if window.AnalyzeDuration <= 0 {
+		return nil, nil, nil
+	}
+
+	filter := "volumedetect"
+	if format := audioFormat(options); format != "" {
+		filter = format + "," + filter
+	}
+
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin", "-nostats")
+	}
+	args = append(args, filterArgs(inputPath, window, filter)...)
+
+	output, err := d.run(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return nil, nil, newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
+	}
+	return parseVolumes(string(output))
+}
+
+// parseVolumes returns the mean and peak volume of volumedetect output. Both are nil when the filter saw no samples,
+// for which it reports nothing.
+func parseVolumes(output string) (mean, peak *float64, err error) {
+	for _, line := range strings.Split(output, "\n") {
+		if matches := meanVolumePattern.FindStringSubmatch(line); len(matches) == 2 {
+			volume, err := parseFFmpegNumber(matches[1], "mean volume")
+			if err != nil {
+				return nil, nil, err
+			}
+			mean = &volume
+		}
+		if matches := maxVolumePattern.FindStringSubmatch(line); len(matches) == 2 {
+			volume, err := parseFFmpegNumber(matches[1], "max volume")
+			if err != nil {
+				return nil, nil, err
+			}
+			peak = &volume
+		}
+	}
+	return mean, peak, nil
+}
+
+// levelSummary describes the measured levels of interval, such as "mean -61.2dB, max -48.0dB", or returns "" when
+// they were not measured.
+func levelSummary(interval Interval) string {
+	if interval.MeanVolumeDB == nil && interval.MaxVolumeDB == nil {
+		return ""
+	}
+	format := func(volume *float64) string {
+		if volume == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1fdB", *volume)
+	}
+	return fmt.Sprintf("mean %s, max %s", format(interval.MeanVolumeDB), format(interval.MaxVolumeDB))
+}
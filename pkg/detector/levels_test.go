@@ -0,0 +1,148 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// argAfter returns the argument following flag in args, or "" when it is missing.
+func argAfter(args []string, flag string) string {
+	if i := slices.Index(args, flag); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
+
+func TestDetectSilenceMeasuresIntervalLevels(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		windows []string
+	)
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !strings.Contains(args[len(args)-4], "volumedetect") {
+			return []byte("[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 3 | silence_duration: 2\n" +
+				"[silencedetect @ 0x1] silence_start: 6\n[silencedetect @ 0x1] silence_end: 6.5 | silence_duration: 0.5\n" +
+				"size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+		}
+		mu.Lock()
+		windows = append(windows, argAfter(args, "-ss")+"+"+argAfter(args, "-t"))
+		mu.Unlock()
+		if argAfter(args, "-ss") == "11" {
+			return []byte("[Parsed_volumedetect_0 @ 0x1] mean_volume: -61.2 dB\n[Parsed_volumedetect_0 @ 0x1] max_volume: -48.0 dB\n"), nil
+		}
+		return []byte("[Parsed_volumedetect_0 @ 0x1] mean_volume: -33.5 dB\n[Parsed_volumedetect_0 @ 0x1] max_volume: -30.1 dB\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "audio.wav", DetectionOptions{
+		NoiseLevel:            -30,
+		MinSilenceDuration:    0.5,
+		StartOffset:           10,
+		MeasureIntervalLevels: true,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	slices.Sort(windows)
+	if want := []string{"11+2", "16+0.5"}; !slices.Equal(windows, want) {
+		t.Fatalf("measured windows %v, want %v", windows, want)
+	}
+	if len(result.Intervals) != 2 || result.Intervals[0].MeanVolumeDB == nil || result.Intervals[1].MaxVolumeDB == nil {
+		t.Fatalf("expected levels on every interval, got %+v", result.Intervals)
+	}
+	assertFloatEqual(t, *result.Intervals[0].MeanVolumeDB, -61.2)
+	assertFloatEqual(t, *result.Intervals[0].MaxVolumeDB, -48)
+	assertFloatEqual(t, *result.Intervals[1].MeanVolumeDB, -33.5)
+}
+
+func TestDetectSilenceMeasuresAtMostMaxMeasuredIntervals(t *testing.T) {
+	var silences strings.Builder
+	for i := range MaxMeasuredIntervals + 1 {
+		fmt.Fprintf(&silences, "[silencedetect @ 0x1] silence_start: %d\n[silencedetect @ 0x1] silence_end: %d.5 | silence_duration: 0.5\n", i, i)
+	}
+	var (
+		mu   sync.Mutex
+		runs int
+	)
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !strings.Contains(args[len(args)-4], "volumedetect") {
+			return []byte(silences.String()), nil
+		}
+		mu.Lock()
+		runs++
+		mu.Unlock()
+		return []byte("[Parsed_volumedetect_0 @ 0x1] mean_volume: -70.0 dB\n[Parsed_volumedetect_0 @ 0x1] max_volume: -60.0 dB\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "audio.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, MeasureIntervalLevels: true})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if runs != MaxMeasuredIntervals {
+		t.Fatalf("expected %d measurements, got %d", MaxMeasuredIntervals, runs)
+	}
+	if result.Intervals[MaxMeasuredIntervals-1].MeanVolumeDB == nil || result.Intervals[MaxMeasuredIntervals].MeanVolumeDB != nil {
+		t.Fatal("expected only the first intervals to be measured")
+	}
+}
+
+func TestDetectSilenceFailsWhenAnIntervalLevelFails(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if !strings.Contains(args[len(args)-4], "volumedetect") {
+			return []byte("[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 3 | silence_duration: 2\n"), nil
+		}
+		return []byte("Invalid data found when processing input\n"), errors.New("exit status 1")
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	_, err := d.DetectSilence(context.Background(), "audio.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5, MeasureIntervalLevels: true})
+	if err == nil || !strings.Contains(err.Error(), "measuring the level of silence 1") {
+		t.Fatalf("expected the measurement to fail detection, got %v", err)
+	}
+}
+
+func TestDetectSilenceIntervalLevelsRejectStdin(t *testing.T) {
+	d := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg should not run")
+		return nil, nil
+	}))
+
+	_, err := d.DetectSilence(context.Background(), "-", DetectionOptions{
+		MinSilenceDuration:    1,
+		MeasureIntervalLevels: true,
+		Stdin:                 strings.NewReader(""),
+	})
+	if err == nil || !strings.Contains(err.Error(), "seekable") {
+		t.Fatalf("expected stdin to be rejected, got %v", err)
+	}
+}
+
+func TestWritersIncludeIntervalLevels(t *testing.T) {
+	intervals := []SilenceInterval{{Start: 1, End: 3, Duration: 2, MeanVolumeDB: float64Ptr(-61.2), MaxVolumeDB: float64Ptr(-48)}}
+	const levels = "mean -61.2dB, max -48.0dB"
+
+	writers := map[string]func(*bytes.Buffer) error{
+		"audacity": func(b *bytes.Buffer) error { return WriteAudacityLabels(b, intervals) },
+		"srt":      func(b *bytes.Buffer) error { return WriteSRT(b, intervals, SubtitleOptions{}) },
+		"edl":      func(b *bytes.Buffer) error { return WriteEDL(b, intervals, 25, EDLOptions{}) },
+		"fcpxml":   func(b *bytes.Buffer) error { return WriteFCPXMLMarkers(b, intervals, 25, MarkerOptions{}) },
+		"resolve":  func(b *bytes.Buffer) error { return WriteResolveMarkers(b, intervals, 25, MarkerOptions{}) },
+	}
+	for name, write := range writers {
+		var b bytes.Buffer
+		if err := write(&b); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(b.String(), levels) {
+			t.Errorf("%s: expected %q, got:\n%s", name, levels, b.String())
+		}
+	}
+}
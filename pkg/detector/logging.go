@@ -0,0 +1,164 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WithLogger makes the detector log its ffmpeg and ffprobe runs at debug level: the command line, the elapsed
+// time, the amount of output parsed, the intervals found and, on failure, the classified error with the tail of
+// ffmpeg's output. Credentials in URLs and sensitive request headers are redacted before logging.
+//
+// Without a logger, which is the default, the detector does not log.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Detector) {
+		d.logger = logger
+	}
+}
+
+// debug logs msg at debug level when the detector has a logger.
+func (d *Detector) debug(ctx context.Context, msg string, attrs ...any) {
+	if d.logger == nil {
+		return
+	}
+	d.logger.DebugContext(ctx, msg, attrs...)
+}
+
+// warn logs msg at warning level when the detector has a logger.
+func (d *Detector) warn(ctx context.Context, msg string, attrs ...any) {
+	if d.logger == nil {
+		return
+	}
+	d.logger.WarnContext(ctx, msg, attrs...)
+}
+
+// logRunFinished logs the outcome of a silencedetect run that started at start.
+func (d *Detector) logRunFinished(ctx context.Context, start time.Time, parser *silenceParser, result DetectionResult, err error) {
+	if d.logger == nil {
+		return
+	}
+
+	attrs := []any{
+		"elapsed", time.Since(start),
+		"output_bytes", parser.outputBytes,
+	}
+	if err == nil {
+		attrs = append(attrs, "intervals", len(result.Intervals), "duration", result.InputDuration)
+		d.debug(ctx, "ffmpeg finished", attrs...)
+		return
+	}
+
+	attrs = append(attrs, "error", RedactText(err.Error()))
+	if kind := errorKind(err); kind != nil {
+		attrs = append(attrs, "kind", kind.Error())
+	}
+	var ffErr *FFmpegError
+	if errors.As(err, &ffErr) {
+		attrs = append(attrs, "exit_code", ffErr.ExitCode)
+		if ffErr.Stderr != "" {
+			attrs = append(attrs, "output_tail", RedactText(ffErr.Stderr))
+		}
+	}
+	d.debug(ctx, "ffmpeg failed", attrs...)
+}
+
+// errorKinds are the sentinels reported as the kind of a failed run, in order of precedence.
+var errorKinds = []error{
+	ErrFFmpegNotFound,
+	ErrTimeout,
+	ErrCanceled,
+	ErrNoAudioStream,
+	ErrNoVideoStream,
+	ErrStreamNotFound,
+	ErrCodecParameters,
+	ErrProtocolUnsupported,
+	ErrInputUnreachable,
+}
+
+// errorKind returns the sentinel classifying err, or nil when the failure was not recognised.
+func errorKind(err error) error {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// redacted replaces secrets in logged values.
+const redacted = "REDACTED"
+
+// sensitiveHeaderWords mark request headers, by a case-insensitive substring of their name, whose values are
+// redacted.
+var sensitiveHeaderWords = []string{"authorization", "cookie", "token", "secret", "key", "signature", "credential"}
+
+// RedactArgs returns a copy of ffmpeg or ffprobe args that is safe to log or print: the passwords and query
+// parameters of URLs are redacted, and so are the values of sensitive -headers entries, such as Authorization.
+func RedactArgs(args []string) []string {
+	safe := make([]string, len(args))
+	for i, arg := range args {
+		if i > 0 && args[i-1] == "-headers" {
+			safe[i] = redactHeaders(arg)
+			continue
+		}
+		safe[i] = RedactText(arg)
+	}
+	return safe
+}
+
+// redactHeaders replaces the values of sensitive headers in an ffmpeg -headers value.
+func redactHeaders(value string) string {
+	lines := strings.Split(value, "\r\n")
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		lower := strings.ToLower(name)
+		for _, word := range sensitiveHeaderWords {
+			if strings.Contains(lower, word) {
+				lines[i] = name + ": " + redacted
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// urlPattern matches URLs embedded in arguments and ffmpeg diagnostics.
+var urlPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s'"]+`)
+
+// RedactText redacts every URL in s. Trailing punctuation, such as the colon ffmpeg puts between a URL and its
+// diagnostic, is not considered part of the URL.
+func RedactText(s string) string {
+	return urlPattern.ReplaceAllStringFunc(s, func(match string) string {
+		trimmed := strings.TrimRight(match, ":;,.)")
+		return redactURL(trimmed) + match[len(trimmed):]
+	})
+}
+
+// redactURL hides the password of a URL and the values of its query parameters, which carry the tokens and
+// signatures of presigned URLs.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			query[name] = []string{redacted}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
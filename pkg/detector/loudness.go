@@ -0,0 +1,109 @@
+package detector
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Loudness is the EBU R128 summary reported by ffmpeg's ebur128 filter.
+type Loudness struct {
+	// IntegratedLUFS is the integrated loudness (I) of the analysed audio.
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	// RangeLU is the loudness range (LRA).
+	RangeLU float64 `json:"range_lu"`
+	// TruePeakDBFS is the highest true peak, or nil when ffmpeg did not report one.
+	TruePeakDBFS *float64 `json:"true_peak_dbfs,omitempty"`
+}
+
+// loudnessFilter measures EBU R128 loudness including the true peak. Per-frame measurements are logged at verbose
+// level so that only the summary appears in ffmpeg's default output.
+const loudnessFilter = "ebur128=peak=true:framelog=verbose"
+
+// logPrefixPattern matches the "[Parsed_ebur128_0 @ 0x...]" context that newer ffmpeg versions put in front of every
+// summary line, while older versions only prefix the "Summary:" line.
+var logPrefixPattern = regexp.MustCompile(`^\[[^\]]*\]\s*`)
+
+// loudnessParser extracts the ebur128 summary block from ffmpeg output. Lines before "Summary:", such as per-frame
+// measurements, are ignored.
+type loudnessParser struct {
+	inSummary  bool
+	section    string
+	integrated *float64
+	lra        *float64
+	truePeak   *float64
+}
+
+// parseLine consumes a line of the summary block and reports whether it belonged to it.
+func (p *loudnessParser) parseLine(line string) bool {
+	line = strings.TrimSpace(logPrefixPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+	if line == "Summary:" {
+		*p = loudnessParser{inSummary: true}
+		return true
+	}
+	if !p.inSummary || line == "" {
+		return false
+	}
+
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return false
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		// A section header such as "Integrated loudness:" or "True peak:".
+		p.section = key
+		return true
+	}
+
+	switch {
+	case p.section == "Integrated loudness" && key == "I":
+		p.integrated = parseLoudnessValue(value)
+	case p.section == "Loudness range" && key == "LRA":
+		p.lra = parseLoudnessValue(value)
+	case p.section == "True peak" && key == "Peak":
+		p.truePeak = parseLoudnessValue(value)
+	case key == "Threshold" || key == "LRA low" || key == "LRA high" || (p.section == "Sample peak" && key == "Peak"):
+		// Thresholds, LRA bounds and sample peaks are not reported.
+	default:
+		return false
+	}
+	return true
+}
+
+// parseLoudnessValue parses the number at the start of a summary value such as "-23.0 LUFS". It returns nil for
+// values that are not finite, which ffmpeg prints for inputs without enough audio to measure.
+func parseLoudnessValue(value string) *float64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+		return nil
+	}
+	return &parsed
+}
+
+// result returns the parsed summary, or nil when ffmpeg did not report the integrated loudness.
+func (p *loudnessParser) result() *Loudness {
+	if p.integrated == nil {
+		return nil
+	}
+
+	loudness := &Loudness{IntegratedLUFS: *p.integrated, TruePeakDBFS: p.truePeak}
+	if p.lra != nil {
+		loudness.RangeLU = *p.lra
+	}
+	return loudness
+}
+
+// missingFilter reports whether err is an ffmpeg failure caused by the named filter being absent from the build.
+func missingFilter(err error, name string) bool {
+	var ffErr *FFmpegError
+	return errors.As(err, &ffErr) && strings.Contains(ffErr.Stderr, "No such filter: '"+name+"'")
+}
@@ -0,0 +1,164 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// ffmpeg 4 prefixes only the "Summary:" line with the filter context.
+const ebur128SummaryV4 = `[Parsed_ebur128_1 @ 0x55d0] t: 9.9       TARGET:-23 LUFS    M: -21.0 S: -22.0     I: -19.5 LUFS       LRA:   4.0 LU  FTPK: -3.0 dBFS  TPK: -1.0 dBFS
+[Parsed_ebur128_1 @ 0x55d0] Summary:
+
+  Integrated loudness:
+    I:         -23.4 LUFS
+    Threshold: -33.6 LUFS
+
+  Loudness range:
+    LRA:         5.2 LU
+    Threshold: -43.8 LUFS
+    LRA low:   -26.7 LUFS
+    LRA high:  -21.5 LUFS
+
+  True peak:
+    Peak:       -1.3 dBFS
+size=N/A time=00:00:10.00 bitrate=N/A speed=1x
+`
+
+// ffmpeg 6 prefixes every summary line with the filter context.
+const ebur128SummaryV6 = `[Parsed_ebur128_1 @ 0x6000] Summary:
+[Parsed_ebur128_1 @ 0x6000] 
+[Parsed_ebur128_1 @ 0x6000]   Integrated loudness:
+[Parsed_ebur128_1 @ 0x6000]     I:         -16.0 LUFS
+[Parsed_ebur128_1 @ 0x6000]     Threshold: -26.1 LUFS
+[Parsed_ebur128_1 @ 0x6000] 
+[Parsed_ebur128_1 @ 0x6000]   Loudness range:
+[Parsed_ebur128_1 @ 0x6000]     LRA:         7.9 LU
+[Parsed_ebur128_1 @ 0x6000]     Threshold: -36.2 LUFS
+[Parsed_ebur128_1 @ 0x6000]     LRA low:   -22.3 LUFS
+[Parsed_ebur128_1 @ 0x6000]     LRA high:  -14.4 LUFS
+[Parsed_ebur128_1 @ 0x6000] 
+[Parsed_ebur128_1 @ 0x6000]   True peak:
+[Parsed_ebur128_1 @ 0x6000]     Peak:        0.4 dBFS
+[out#0/null @ 0x6100] video:0KiB audio:1875KiB subtitle:0KiB other streams:0KiB global headers:0KiB muxing overhead: unknown
+size=N/A time=00:00:10.00 bitrate=N/A speed=1x
+`
+
+func TestLoudnessParserHandlesSummaryFormats(t *testing.T) {
+	tests := []struct {
+		name           string
+		output         string
+		wantIntegrated float64
+		wantRange      float64
+		wantPeak       *float64
+	}{
+		{name: "ffmpeg 4", output: ebur128SummaryV4, wantIntegrated: -23.4, wantRange: 5.2, wantPeak: float64Ptr(-1.3)},
+		{name: "ffmpeg 6", output: ebur128SummaryV6, wantIntegrated: -16, wantRange: 7.9, wantPeak: float64Ptr(0.4)},
+		{
+			name:           "without true peak",
+			output:         "[Parsed_ebur128_0 @ 0x1] Summary:\n\n  Integrated loudness:\n    I:  -20.0 LUFS\n\n  Loudness range:\n    LRA:  3.0 LU\nsize=N/A time=00:00:10.00 bitrate=N/A speed=1x\n",
+			wantIntegrated: -20,
+			wantRange:      3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := silenceParser{loudness: &loudnessParser{}}
+			if err := parser.parseOutput(tt.output); err != nil {
+				t.Fatalf("parseOutput returned error: %v", err)
+			}
+			result := parser.finish()
+
+			if result.Loudness == nil {
+				t.Fatal("expected a loudness summary")
+			}
+			assertFloatEqual(t, result.Loudness.IntegratedLUFS, tt.wantIntegrated)
+			assertFloatEqual(t, result.Loudness.RangeLU, tt.wantRange)
+			if (tt.wantPeak == nil) != (result.Loudness.TruePeakDBFS == nil) {
+				t.Fatalf("unexpected true peak %v", result.Loudness.TruePeakDBFS)
+			}
+			if tt.wantPeak != nil {
+				assertFloatEqual(t, *result.Loudness.TruePeakDBFS, *tt.wantPeak)
+			}
+			assertFloatEqual(t, result.InputDuration, 10)
+		})
+	}
+}
+
+func TestLoudnessParserIgnoresIncompleteSummaries(t *testing.T) {
+	for _, output := range []string{
+		"[Parsed_ebur128_0 @ 0x1] t: 1.0 TARGET:-23 LUFS M: -20.0 S: -20.0 I: -20.0 LUFS LRA: 0.0 LU\n",
+		"[Parsed_ebur128_0 @ 0x1] Summary:\n\n  Integrated loudness:\n    I:  -inf LUFS\n",
+	} {
+		parser := silenceParser{loudness: &loudnessParser{}}
+		if err := parser.parseOutput(output); err != nil {
+			t.Fatalf("parseOutput returned error: %v", err)
+		}
+		if loudness := parser.finish().Loudness; loudness != nil {
+			t.Fatalf("expected no loudness for %q, got %+v", output, loudness)
+		}
+	}
+}
+
+func TestDetectSilenceMeasuresLoudness(t *testing.T) {
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		capturedArgs = append([]string(nil), args...)
+		return []byte(ebur128SummaryV6), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		MeasureVolume:      true,
+		MeasureLoudness:    true,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if !strings.Contains(strings.Join(capturedArgs, " "), " -af silencedetect=noise=-30dB:d=1,volumedetect,ebur128=peak=true:framelog=verbose -f") {
+		t.Fatalf("expected ebur128 in the filter chain, got %v", capturedArgs)
+	}
+	if result.Loudness == nil || result.Loudness.IntegratedLUFS != -16 {
+		t.Fatalf("unexpected loudness %+v", result.Loudness)
+	}
+}
+
+func TestDetectSilenceWithoutEBUR128Filter(t *testing.T) {
+	var filters []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		filter := args[len(args)-4]
+		filters = append(filters, filter)
+		if strings.Contains(filter, "ebur128") {
+			return []byte("[AVFilterGraph @ 0x1] No such filter: 'ebur128'\nError reinitializing filters!"), &exec.ExitError{}
+		}
+		return []byte("[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 2 | silence_duration: 1\nsize=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1, MeasureLoudness: true})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(filters) != 2 || strings.Contains(filters[1], "ebur128") {
+		t.Fatalf("expected a second run without ebur128, got filters %v", filters)
+	}
+	if result.Loudness != nil || len(result.Intervals) != 1 {
+		t.Fatalf("expected silence intervals without loudness, got %+v", result)
+	}
+
+	filters = nil
+	_, err = d.DetectSilence(context.Background(), "-", DetectionOptions{MinSilenceDuration: 1, MeasureLoudness: true, Stdin: strings.NewReader("")})
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) || len(filters) != 1 {
+		t.Fatalf("expected stdin input to fail without a second run, got %v after %d run(s)", err, len(filters))
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
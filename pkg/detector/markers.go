@@ -0,0 +1,238 @@
+package detector
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// MarkerOptions configures WriteFCPXMLMarkers and WriteResolveMarkers.
+type MarkerOptions struct {
+	// TimelineName names the timeline the markers are imported into. "silence" is used when it is empty.
+	TimelineName string
+	// TimelineStart is the timecode of the first frame of the timeline, such as 01:00:00:00, where editors usually
+	// start timelines. Timelines start at 00:00:00:00 when it is empty.
+	TimelineStart string
+	// Duration is the length of the timeline in seconds, typically the duration of the input. The end of the last
+	// interval is used when it is shorter.
+	Duration float64
+	// DropFrame writes drop-frame timecode. It requires an NTSC frame rate, 29.97 or 59.94.
+	DropFrame bool
+}
+
+// markerFrames is an interval converted to timeline frames for a marker.
+type markerFrames struct {
+	start, duration int64
+	// seconds is the interval's duration and levels its measured levels, if any, both shown in the marker's note.
+	seconds float64
+	levels  string
+}
+
+// markerTimeline checks the frame rate and timeline start of opts, and converts intervals to marker frames at fps,
+// in start order. It returns them with the first frame of the timeline and its length in frames. Intervals shorter
+// than a frame are left out.
+func markerTimeline(intervals []SilenceInterval, fps float64, opts MarkerOptions) (markers []markerFrames, timelineStart, length int64, err error) {
+	if err := checkTimecodeRate(fps, opts.DropFrame); err != nil {
+		return nil, 0, 0, err
+	}
+	if opts.TimelineStart != "" {
+		if timelineStart, err = parseTimecode(opts.TimelineStart, fps, opts.DropFrame); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	length = int64(math.Round(math.Max(opts.Duration, 0) * fps))
+	for _, interval := range sortedIntervals(intervals) {
+		start := int64(math.Round(math.Max(interval.Start, 0) * fps))
+		end := int64(math.Round(math.Max(interval.End, 0) * fps))
+		if end <= start {
+			continue
+		}
+		markers = append(markers, markerFrames{start: start, duration: end - start, seconds: interval.Duration, levels: levelSummary(interval)})
+		length = max(length, end)
+	}
+	return markers, timelineStart, length, nil
+}
+
+// markerNote describes a silence in a marker, such as "silence 2.3s" or, with measured levels, "silence 2.3s, mean
+// -61.2dB, max -48.0dB".
+func markerNote(marker markerFrames) string {
+	note := fmt.Sprintf("silence %.1fs", marker.seconds)
+	if marker.levels != "" {
+		note += ", " + marker.levels
+	}
+	return note
+}
+
+// WriteFCPXMLMarkers writes intervals as an FCPXML 1.10 document: a project named after the timeline, holding a gap
+// as long as the timeline with a marker at the start of each interval, in start order. Each marker lasts as long as
+// its interval and is named "silence N", with a note such as "silence 2.3s". Intervals shorter than a frame are left
+// out. The project's 1920x1080 format is a placeholder required by importers and does not affect the markers.
+//
+// WriteFCPXMLMarkers returns an error without writing anything if fps is not positive and finite, if DropFrame is
+// set at a rate other than 29.97 or 59.94, or if TimelineStart is not a valid timecode.
+func WriteFCPXMLMarkers(w io.Writer, intervals []SilenceInterval, fps float64, opts MarkerOptions) error {
+	markers, timelineStart, length, err := markerTimeline(intervals, fps, opts)
+	if err != nil {
+		return err
+	}
+	frame := newFCPXMLFrameDuration(fps)
+	tcFormat := "NDF"
+	if opts.DropFrame {
+		tcFormat = "DF"
+	}
+
+	gap := fcpxmlGap{Name: "Gap", Offset: frame.time(timelineStart), Start: "0s", Duration: frame.time(length)}
+	for i, marker := range markers {
+		gap.Markers = append(gap.Markers, fcpxmlMarker{
+			Start:    frame.time(marker.start),
+			Duration: frame.time(marker.duration),
+			Value:    fmt.Sprintf("silence %d", i+1),
+			Note:     markerNote(marker),
+		})
+	}
+	doc := fcpxmlDocument{
+		Version: "1.10",
+		Format:  fcpxmlFormat{ID: "r1", FrameDuration: frame.time(1), Width: 1920, Height: 1080},
+		Event: fcpxmlEvent{
+			Name: "silence-detector",
+			Project: fcpxmlProject{
+				Name: timelineName(opts),
+				Sequence: fcpxmlSequence{
+					Format:   "r1",
+					Duration: frame.time(length),
+					TCStart:  frame.time(timelineStart),
+					TCFormat: tcFormat,
+					Gap:      gap,
+				},
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xml.Header+"<!DOCTYPE fcpxml>\n"+string(data)+"\n")
+	return err
+}
+
+// WriteResolveMarkers writes intervals as the timeline marker EDL that DaVinci Resolve imports with Timelines >
+// Import > Timeline Markers from EDL. Each interval becomes a one-frame event at its start, in start order, whose
+// comment gives the marker's color, its name, such as "silence 2.3s", and its duration in frames. Intervals shorter
+// than a frame are left out.
+//
+// WriteResolveMarkers returns an error without writing anything if fps is not positive and finite, if DropFrame is
+// set at a rate other than 29.97 or 59.94, if TimelineStart is not a valid timecode, or if there are more than 999
+// markers.
+func WriteResolveMarkers(w io.Writer, intervals []SilenceInterval, fps float64, opts MarkerOptions) error {
+	markers, timelineStart, _, err := markerTimeline(intervals, fps, opts)
+	if err != nil {
+		return err
+	}
+	if len(markers) > maxEDLEvents {
+		return fmt.Errorf("a marker EDL holds at most %d markers", maxEDLEvents)
+	}
+
+	var b strings.Builder
+	fcm := "NON-DROP FRAME"
+	if opts.DropFrame {
+		fcm = "DROP FRAME"
+	}
+	fmt.Fprintf(&b, "TITLE: %s\nFCM: %s\n", timelineName(opts), fcm)
+
+	for i, marker := range markers {
+		in := formatTimecode(timelineStart+marker.start, fps, opts.DropFrame)
+		out := formatTimecode(timelineStart+marker.start+1, fps, opts.DropFrame)
+		fmt.Fprintf(&b, "\n%03d  001      V     C        %s %s %s %s\n", i+1, in, out, in, out)
+		fmt.Fprintf(&b, " |C:ResolveColorBlue |M:%s |D:%d\n", markerNote(marker), marker.duration)
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// timelineName returns the timeline name of opts, on a single line.
+func timelineName(opts MarkerOptions) string {
+	name := strings.Join(strings.Fields(opts.TimelineName), " ")
+	if name == "" {
+		return "silence"
+	}
+	return name
+}
+
+// fcpxmlFrameDuration is the duration of a frame as the rational number of seconds num/den, as FCPXML writes times.
+type fcpxmlFrameDuration struct {
+	num, den int64
+}
+
+// newFCPXMLFrameDuration returns the frame duration at fps: 1001/30000 for 29.97, 1/25 for 25, and 100/1250 for
+// rates such as 12.5.
+func newFCPXMLFrameDuration(fps float64) fcpxmlFrameDuration {
+	base := timecodeBase(fps)
+	switch {
+	case math.Abs(fps-float64(base)) < 1e-9:
+		return fcpxmlFrameDuration{num: 1, den: base}
+	case math.Abs(fps-float64(base)*1000/1001) < 0.005:
+		return fcpxmlFrameDuration{num: 1001, den: base * 1000}
+	default:
+		return fcpxmlFrameDuration{num: 100, den: int64(math.Round(fps * 100))}
+	}
+}
+
+// time formats a number of frames as an FCPXML time, such as 50/25s.
+func (d fcpxmlFrameDuration) time(frames int64) string {
+	if frames == 0 {
+		return "0s"
+	}
+	return fmt.Sprintf("%d/%ds", frames*d.num, d.den)
+}
+
+type fcpxmlDocument struct {
+	XMLName xml.Name     `xml:"fcpxml"`
+	Version string       `xml:"version,attr"`
+	Format  fcpxmlFormat `xml:"resources>format"`
+	Event   fcpxmlEvent  `xml:"library>event"`
+}
+
+type fcpxmlFormat struct {
+	ID            string `xml:"id,attr"`
+	FrameDuration string `xml:"frameDuration,attr"`
+	Width         int    `xml:"width,attr"`
+	Height        int    `xml:"height,attr"`
+}
+
+type fcpxmlEvent struct {
+	Name    string        `xml:"name,attr"`
+	Project fcpxmlProject `xml:"project"`
+}
+
+type fcpxmlProject struct {
+	Name     string         `xml:"name,attr"`
+	Sequence fcpxmlSequence `xml:"sequence"`
+}
+
+type fcpxmlSequence struct {
+	Format   string    `xml:"format,attr"`
+	Duration string    `xml:"duration,attr"`
+	TCStart  string    `xml:"tcStart,attr"`
+	TCFormat string    `xml:"tcFormat,attr"`
+	Gap      fcpxmlGap `xml:"spine>gap"`
+}
+
+type fcpxmlGap struct {
+	Name     string         `xml:"name,attr"`
+	Offset   string         `xml:"offset,attr"`
+	Start    string         `xml:"start,attr"`
+	Duration string         `xml:"duration,attr"`
+	Markers  []fcpxmlMarker `xml:"marker"`
+}
+
+type fcpxmlMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+	Note     string `xml:"note,attr,omitempty"`
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var markerIntervals = []SilenceInterval{
+	{Start: 65.5, End: 67.8, Duration: 2.3},
+	{Start: 0, End: 1.2, Duration: 1.2},
+	// Shorter than a frame, so it is left out.
+	{Start: 30, End: 30.01, Duration: 0.01},
+}
+
+func TestWriteFCPXMLMarkers(t *testing.T) {
+	tests := []struct {
+		name string
+		fps  float64
+		opts MarkerOptions
+	}{
+		{name: "markers_25.fcpxml", fps: 25, opts: MarkerOptions{TimelineName: "Interview & B-roll", Duration: 90}},
+		{name: "markers_2997_df.fcpxml", fps: 29.97, opts: MarkerOptions{TimelineName: "Interview", TimelineStart: "01:00:00;00", Duration: 90, DropFrame: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFCPXMLMarkers(&buf, markerIntervals, tt.fps, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name, buf.Bytes())
+
+			var doc fcpxmlDocument
+			if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("invalid XML: %v", err)
+			}
+			if len(doc.Event.Project.Sequence.Gap.Markers) != 2 {
+				t.Errorf("expected two markers, got %+v", doc.Event.Project.Sequence.Gap.Markers)
+			}
+		})
+	}
+}
+
+func TestWriteResolveMarkers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResolveMarkers(&buf, markerIntervals, 25, MarkerOptions{TimelineName: "Interview", TimelineStart: "01:00:00:00"}); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "markers_25.resolve.edl", buf.Bytes())
+}
+
+func TestMarkerWritersRejectInvalidRates(t *testing.T) {
+	for _, write := range []func(*bytes.Buffer) error{
+		func(buf *bytes.Buffer) error { return WriteFCPXMLMarkers(buf, markerIntervals, 0, MarkerOptions{}) },
+		func(buf *bytes.Buffer) error { return WriteResolveMarkers(buf, markerIntervals, 0, MarkerOptions{}) },
+		func(buf *bytes.Buffer) error {
+			return WriteFCPXMLMarkers(buf, markerIntervals, 25, MarkerOptions{DropFrame: true})
+		},
+	} {
+		var buf bytes.Buffer
+		if err := write(&buf); err == nil || buf.Len() != 0 {
+			t.Errorf("expected an error and no output, got %v and %q", err, buf.String())
+		}
+	}
+}
+
+func TestFCPXMLFrameDuration(t *testing.T) {
+	tests := []struct {
+		fps  float64
+		want string
+	}{
+		{fps: 25, want: "1/25s"},
+		{fps: 29.97, want: "1001/30000s"},
+		{fps: 23.976, want: "1001/24000s"},
+		{fps: 59.94, want: "1001/60000s"},
+		{fps: 12.5, want: "100/1250s"},
+	}
+	for _, tt := range tests {
+		if got := newFCPXMLFrameDuration(tt.fps).time(1); got != tt.want {
+			t.Errorf("frame duration at %v = %q, want %q", tt.fps, got, tt.want)
+		}
+	}
+	if got := newFCPXMLFrameDuration(25).time(0); !strings.EqualFold(got, "0s") {
+		t.Errorf("expected 0s, got %q", got)
+	}
+}
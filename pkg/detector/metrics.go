@@ -0,0 +1,79 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Outcome classifies a finished DetectSilence call for metrics.
+type Outcome string
+
+const (
+	// OutcomeOK is a detection that produced a result.
+	OutcomeOK Outcome = "ok"
+	// OutcomeNoAudio is a detection that failed with ErrNoAudioStream.
+	OutcomeNoAudio Outcome = "no_audio"
+	// OutcomeTimeout is a detection stopped by a context deadline.
+	OutcomeTimeout Outcome = "timeout"
+	// OutcomeCanceled is a detection stopped by a canceled context.
+	OutcomeCanceled Outcome = "canceled"
+	// OutcomeUnrecognizedOutput is a detection that failed with ErrUnrecognizedOutput, typically because the ffmpeg
+	// in use prints its output in a format the detector does not know.
+	OutcomeUnrecognizedOutput Outcome = "unrecognized_output"
+	// OutcomeFFmpegError is any other failure of ffmpeg, or of parsing its output.
+	OutcomeFFmpegError Outcome = "ffmpeg_error"
+)
+
+// Recorder receives instrumentation events from a Detector, so that library users can feed them to the metrics
+// system of their choice. A Detector may run several detections at once, so implementations must be safe for
+// concurrent use, and they should return quickly since they are called on the detection path.
+type Recorder interface {
+	// FFmpegStarted and FFmpegFinished bracket every ffmpeg process the detector runs, including those of health
+	// checks and of black, freeze and noise-floor passes. ffprobe runs are not reported.
+	FFmpegStarted()
+	FFmpegFinished()
+	// DetectionFinished reports a DetectSilence call that passed validation: its wall time, its outcome and the
+	// seconds of silence it found.
+	DetectionFinished(elapsed time.Duration, outcome Outcome, silence float64)
+	// Downloaded reports bytes received by FetchRemoteInput when DownloadOptions.Recorder is set. It is called as
+	// data arrives, so bytes of failed attempts that are retried are included.
+	Downloaded(bytes int64)
+}
+
+// WithMetrics makes the detector report its ffmpeg processes and detections to recorder.
+func WithMetrics(recorder Recorder) Option {
+	return func(d *Detector) {
+		d.recorder = recorder
+	}
+}
+
+// DetectionOutcome classifies the error of a DetectSilence call.
+func DetectionOutcome(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrNoAudioStream):
+		return OutcomeNoAudio
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(err, ErrCanceled), errors.Is(err, context.Canceled):
+		return OutcomeCanceled
+	case errors.Is(err, ErrUnrecognizedOutput):
+		return OutcomeUnrecognizedOutput
+	default:
+		return OutcomeFFmpegError
+	}
+}
+
+// recordDetection reports a DetectSilence call that started at start.
+func (d *Detector) recordDetection(start time.Time, result DetectionResult, err error) {
+	if d.recorder == nil {
+		return
+	}
+	var silence float64
+	for _, interval := range result.Intervals {
+		silence += interval.Duration
+	}
+	d.recorder.DetectionFinished(time.Since(start), DetectionOutcome(err), silence)
+}
@@ -0,0 +1,171 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRecorder records the events it receives.
+type fakeRecorder struct {
+	mu         sync.Mutex
+	inFlight   int
+	started    int
+	outcomes   []Outcome
+	silence    float64
+	downloaded int64
+}
+
+func (r *fakeRecorder) FFmpegStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight++
+	r.started++
+}
+
+func (r *fakeRecorder) FFmpegFinished() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight--
+}
+
+func (r *fakeRecorder) DetectionFinished(elapsed time.Duration, outcome Outcome, silence float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, outcome)
+	r.silence += silence
+}
+
+func (r *fakeRecorder) Downloaded(bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.downloaded += bytes
+}
+
+func (r *fakeRecorder) ffmpegRuns() (started, inFlight int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.started, r.inFlight
+}
+
+func TestWithMetricsRecordsDetections(t *testing.T) {
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1}
+
+	tests := []struct {
+		name   string
+		option func(*fakeRecorder) Option
+	}{
+		{
+			name: "buffered",
+			option: func(*fakeRecorder) Option {
+				return WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte(streamFixture), nil
+				})
+			},
+		},
+		{
+			name: "streaming",
+			option: func(recorder *fakeRecorder) Option {
+				return WithStreamingRunner(func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+					if _, inFlight := recorder.ffmpegRuns(); inFlight != 1 {
+						t.Errorf("expected ffmpeg to be in flight while it runs, got %d", inFlight)
+					}
+					return fakeStreamingRunner(streamFixture, nil)(ctx, name, args...)
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &fakeRecorder{}
+			d := NewDetector(WithMetrics(recorder), tt.option(recorder))
+
+			result, err := d.DetectSilence(context.Background(), "input.wav", options)
+			if err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+
+			if started, inFlight := recorder.ffmpegRuns(); started != 1 || inFlight != 0 {
+				t.Fatalf("expected one finished ffmpeg run, got %d started and %d in flight", started, inFlight)
+			}
+			var silence float64
+			for _, interval := range result.Intervals {
+				silence += interval.Duration
+			}
+			if len(recorder.outcomes) != 1 || recorder.outcomes[0] != OutcomeOK {
+				t.Fatalf("expected one ok detection, got %v", recorder.outcomes)
+			}
+			assertFloatEqual(t, recorder.silence, silence)
+		})
+	}
+}
+
+func TestWithMetricsRecordsFailures(t *testing.T) {
+	recorder := &fakeRecorder{}
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("Output file #0 does not contain any stream\n"), errors.New("exit status 1")
+	}
+	d := NewDetector(WithMetrics(recorder), WithCommandRunner(runner))
+
+	if _, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1}); !errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("expected ErrNoAudioStream, got %v", err)
+	}
+	// Invalid options never reach ffmpeg and are not recorded.
+	if _, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{NoiseLevel: -30}); err == nil {
+		t.Fatal("expected a validation error")
+	}
+
+	if len(recorder.outcomes) != 1 || recorder.outcomes[0] != OutcomeNoAudio {
+		t.Fatalf("expected one no_audio detection, got %v", recorder.outcomes)
+	}
+	if started, inFlight := recorder.ffmpegRuns(); started != 1 || inFlight != 0 {
+		t.Fatalf("expected one finished ffmpeg run, got %d started and %d in flight", started, inFlight)
+	}
+}
+
+func TestDetectionOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Outcome
+	}{
+		{err: nil, want: OutcomeOK},
+		{err: fmt.Errorf("run: %w", ErrNoAudioStream), want: OutcomeNoAudio},
+		{err: fmt.Errorf("run: %w", context.DeadlineExceeded), want: OutcomeTimeout},
+		{err: fmt.Errorf("run: %w", context.Canceled), want: OutcomeCanceled},
+		{err: &UnrecognizedOutputError{}, want: OutcomeUnrecognizedOutput},
+		{err: errors.New("exit status 1"), want: OutcomeFFmpegError},
+	}
+	for _, tt := range tests {
+		if got := DetectionOutcome(tt.err); got != tt.want {
+			t.Errorf("DetectionOutcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFetchRemoteInputRecordsDownloadedBytes(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	recorder := &fakeRecorder{}
+	file, err := FetchRemoteInput(context.Background(), server.URL+"/input.wav", DownloadOptions{Recorder: recorder})
+	if err != nil {
+		t.Fatalf("FetchRemoteInput returned error: %v", err)
+	}
+	defer os.Remove(file.Path)
+
+	if recorder.downloaded != int64(len(body)) {
+		t.Fatalf("expected %d downloaded bytes, got %d", len(body), recorder.downloaded)
+	}
+}
@@ -0,0 +1,328 @@
+package detector
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Engine selects what analyses the audio of a DetectSilence run.
+type Engine string
+
+const (
+	// EngineAuto analyses local PCM and float WAV files natively when the options allow it, and everything else
+	// with ffmpeg. The zero value selects it too.
+	EngineAuto Engine = "auto"
+	// EngineFFmpeg always runs ffmpeg's silencedetect filter.
+	EngineFFmpeg Engine = "ffmpeg"
+	// EngineNative analyses WAV files in Go, without ffmpeg or ffprobe. Other inputs fail with ErrNativeUnsupported,
+	// and options it cannot honour, listed by Validate, are rejected.
+	EngineNative Engine = "native"
+)
+
+// nativeBufferFrames is the number of sample frames the native engine reads at a time.
+const nativeBufferFrames = 4096
+
+// nativeUnsupported returns the names of the options set in o that the native engine cannot honour, since they
+// need ffmpeg's demuxers, filters or protocols.
+func (o DetectionOptions) nativeUnsupported() []string {
+	var names []string
+	add := func(set bool, name string) {
+		if set {
+			names = append(names, name)
+		}
+	}
+	add(o.AudioStreamIndex != nil && *o.AudioStreamIndex != 0, "AudioStreamIndex")
+	add(o.AudioLanguage != "", "AudioLanguage")
+	add(o.InputFormat != "", "InputFormat")
+	add(o.RawPCM != nil, "RawPCM")
+	add(len(o.ExtraInputArgs) > 0, "ExtraInputArgs")
+	add(len(o.ExtraOutputArgs) > 0, "ExtraOutputArgs")
+	add(o.DownmixMono, "DownmixMono")
+	add(o.SampleRate > 0, "SampleRate")
+	add(o.MeasureVolume, "MeasureVolume")
+	add(o.MeasureLoudness, "MeasureLoudness")
+	add(o.AdaptiveThreshold, "AdaptiveThreshold")
+	add(o.MeasureIntervalLevels, "MeasureIntervalLevels")
+	add(o.HysteresisDB > 0, "HysteresisDB")
+	add(o.CaptureRawOutput, "CaptureRawOutput")
+	add(o.Live, "Live")
+	return names
+}
+
+// triesNative reports whether DetectSilence attempts the native engine for inputPath before, in auto mode, falling
+// back to ffmpeg.
+func (o DetectionOptions) triesNative(inputPath string) bool {
+	switch o.Engine {
+	case EngineNative:
+		return true
+	case EngineFFmpeg:
+		return false
+	}
+	return !isPipeInput(inputPath) && len(o.nativeUnsupported()) == 0
+}
+
+// detectNative analyses the WAV file at inputPath in Go. Like runDetection, it reports intervals relative to the
+// analysed window, which detectSilence then shifts and normalizes. Inputs that are not local WAV files with
+// supported samples fail with ErrNativeUnsupported.
+func (d *Detector) detectNative(ctx context.Context, inputPath string, options DetectionOptions) (DetectionResult, error) {
+	input, err := ResolveInput(inputPath)
+	if err != nil || input.Kind != InputLocal {
+		return DetectionResult{}, fmt.Errorf("%w: not a local file", ErrNativeUnsupported)
+	}
+	file, err := os.Open(input.Location)
+	if err != nil {
+		return DetectionResult{}, fmt.Errorf("%w: %w", ErrNativeUnsupported, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	format, err := readWAVHeader(reader)
+	if err != nil {
+		return DetectionResult{}, err
+	}
+
+	d.debug(ctx, "analysing natively", "codec", format.codec(), "sample_rate", format.sampleRate, "channels", format.channels)
+	start := time.Now()
+
+	result, err := scanWAV(ctx, reader, format, options)
+	if err != nil {
+		return DetectionResult{}, fmt.Errorf("read %s: %w", RedactText(inputPath), err)
+	}
+	result.AudioInfo = &AudioInfo{
+		Codec:      format.codec(),
+		SampleRate: format.sampleRate,
+		Channels:   format.channels,
+		BitRate:    int64(format.sampleRate) * int64(format.frameSize()) * 8,
+	}
+	d.debug(ctx, "native analysis finished", "elapsed", time.Since(start), "intervals", len(result.Intervals), "duration", result.InputDuration)
+	return result, nil
+}
+
+// scanWAV runs a silenceScanner over the sample data of r, which starts at the first sample, within the window of
+// options.
+func scanWAV(ctx context.Context, r io.Reader, format wavFormat, options DetectionOptions) (DetectionResult, error) {
+	rate := float64(format.sampleRate)
+	frameSize := int64(format.frameSize())
+
+	data := r
+	if format.dataSize >= 0 {
+		data = io.LimitReader(r, format.dataSize-format.dataSize%frameSize)
+	}
+
+	skip := int64(math.Round(options.StartOffset * rate))
+	if _, err := io.CopyN(io.Discard, data, skip*frameSize); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return DetectionResult{}, err
+		}
+		// A start offset beyond the end leaves nothing to analyse.
+		return DetectionResult{DurationKnown: true}, nil
+	}
+	limit := int64(-1)
+	if options.AnalyzeDuration > 0 {
+		limit = int64(math.Round(options.AnalyzeDuration * rate))
+	}
+
+	scanner := newSilenceScanner(format, options)
+	decode := sampleDecoder(format)
+	buf := make([]byte, nativeBufferFrames*frameSize)
+	var frames int64
+	for limit < 0 || frames < limit {
+		if err := ctx.Err(); err != nil {
+			return DetectionResult{}, err
+		}
+
+		n, err := io.ReadFull(data, buf)
+		if errors.Is(err, io.ErrUnexpectedEOF) && int64(n)%frameSize != 0 {
+			// A file cut partway through a frame keeps its whole frames, as ffmpeg does.
+			n -= int(int64(n) % frameSize)
+		}
+		chunk := buf[:n]
+		if limit >= 0 {
+			chunk = chunk[:min(int64(len(chunk)), (limit-frames)*frameSize)]
+		}
+		for offset := 0; offset < len(chunk); offset += format.bits / 8 {
+			scanner.update(decode(chunk[offset:]))
+			if scanner.truncated {
+				break
+			}
+		}
+		frames += int64(len(chunk)) / frameSize
+
+		if scanner.truncated || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return DetectionResult{}, err
+		}
+	}
+
+	result := scanner.finish(frames)
+	result.InputDuration = float64(frames) / rate
+	if result.Truncated && format.dataSize >= 0 {
+		// Like ffprobe for an ffmpeg run, the header tells the duration of the window the scan stopped in.
+		result.InputDuration = windowDuration(float64(format.dataSize/frameSize)/rate, options)
+	}
+	result.DurationKnown = true
+	return result, nil
+}
+
+// sampleDecoder returns a function that decodes the sample at the start of its argument into the value ffmpeg's
+// silencedetect compares: the sample converted to the format ffmpeg decodes the codec to. 8-bit samples become
+// signed 16-bit ones and 24-bit samples are widened to 32 bits, as ffmpeg does.
+func sampleDecoder(format wavFormat) func([]byte) float64 {
+	switch {
+	case format.float && format.bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	case format.float:
+		return func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case format.bits == 8:
+		return func(b []byte) float64 { return float64((int16(b[0]) - 128) << 8) }
+	case format.bits == 16:
+		return func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) }
+	case format.bits == 24:
+		return func(b []byte) float64 { return float64(int32(uint32(b[0])<<8 | uint32(b[1])<<16 | uint32(b[2])<<24)) }
+	default:
+		return func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) }
+	}
+}
+
+// sampleThreshold converts the noise amplitude ratio into the scale of the decoded samples, truncated to their type
+// as silencedetect does.
+func sampleThreshold(format wavFormat, noise float64) float64 {
+	switch {
+	case format.float && format.bits == 64:
+		return noise
+	case format.float:
+		return float64(float32(noise))
+	case format.bits <= 16:
+		return float64(int16(noise * math.MaxInt16))
+	default:
+		return float64(int32(noise * math.MaxInt32))
+	}
+}
+
+// silenceScanner mirrors ffmpeg's silencedetect filter over interleaved samples. A sample is silent when its
+// magnitude lies below the threshold, and a silence starts once enough consecutive samples are: every sample of
+// minFrames frames, or with PerChannel every sample of a single channel, which is then tracked on its own. A
+// silence ends at the frame of the first sample that is not silent.
+type silenceScanner struct {
+	threshold float64
+	channels  int
+	// tracked is the number of independently tracked channels: all of them with PerChannel, otherwise one.
+	tracked int
+	// notify is the number of consecutive silent samples, per tracked channel, that start a silence.
+	notify    int64
+	minFrames int64
+	rate      float64
+	// sample is the index of the next interleaved sample.
+	sample int64
+	// silent counts the consecutive silent samples, and starts holds the start frame of the current silence or -1,
+	// per tracked channel.
+	silent    []int64
+	starts    []int64
+	intervals [][]SilenceInterval
+	// maxIntervals, when positive, stops the scan with truncated set once this many intervals have ended.
+	maxIntervals int
+	count        int
+	truncated    bool
+}
+
+func newSilenceScanner(format wavFormat, options DetectionOptions) *silenceScanner {
+	noise := math.Pow(10, options.NoiseLevel/20)
+	if options.NoiseRatio != nil {
+		noise = *options.NoiseRatio
+	}
+	// silencedetect rounds the minimum duration to whole frames.
+	minFrames := max(int64(math.Round(options.MinSilenceDuration*float64(format.sampleRate))), 1)
+
+	s := &silenceScanner{
+		threshold:    sampleThreshold(format, noise),
+		channels:     format.channels,
+		tracked:      1,
+		notify:       minFrames * int64(format.channels),
+		minFrames:    minFrames,
+		rate:         float64(format.sampleRate),
+		maxIntervals: options.MaxIntervals,
+	}
+	if options.PerChannel {
+		s.tracked, s.notify = format.channels, minFrames
+	}
+	s.silent = make([]int64, s.tracked)
+	s.starts = make([]int64, s.tracked)
+	s.intervals = make([][]SilenceInterval, s.tracked)
+	for i := range s.starts {
+		s.starts[i] = -1
+	}
+	return s
+}
+
+// update processes the next interleaved sample.
+func (s *silenceScanner) update(value float64) {
+	channel := s.sample % int64(s.tracked)
+	frame := s.sample / int64(s.channels)
+	s.sample++
+
+	if value < s.threshold && value > -s.threshold {
+		if s.starts[channel] < 0 {
+			s.silent[channel]++
+			if s.silent[channel] >= s.notify {
+				s.starts[channel] = max(frame+1-s.minFrames, 0)
+			}
+		}
+		return
+	}
+
+	if s.starts[channel] >= 0 {
+		s.end(channel, frame)
+	}
+	s.silent[channel] = 0
+}
+
+// end closes the silence of channel at frame.
+func (s *silenceScanner) end(channel, frame int64) {
+	start, end := float64(s.starts[channel])/s.rate, float64(frame)/s.rate
+	s.intervals[channel] = append(s.intervals[channel], SilenceInterval{Start: start, End: end, Duration: end - start})
+	s.starts[channel] = -1
+
+	s.count++
+	if s.maxIntervals > 0 && s.count >= s.maxIntervals {
+		s.truncated = true
+	}
+}
+
+// finish closes the silences still open after frames frames and returns the intervals. A truncated scan stopped
+// early, so its open silences are dropped like those of an ffmpeg run stopped at DetectionOptions.MaxIntervals.
+func (s *silenceScanner) finish(frames int64) DetectionResult {
+	if !s.truncated {
+		for channel := range s.starts {
+			if s.starts[channel] >= 0 && frames > s.starts[channel] {
+				s.end(int64(channel), frames)
+			}
+		}
+	}
+
+	result := DetectionResult{Truncated: s.truncated}
+	if s.tracked == 1 {
+		result.Intervals = s.intervals[0]
+		return result
+	}
+
+	for channel, intervals := range s.intervals {
+		if len(intervals) == 0 {
+			continue
+		}
+		if result.ChannelIntervals == nil {
+			result.ChannelIntervals = make(map[int][]SilenceInterval)
+		}
+		result.ChannelIntervals[channel] = intervals
+	}
+	result.Intervals = intersectChannels(result.ChannelIntervals, s.tracked)
+	return result
+}
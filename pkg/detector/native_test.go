@@ -0,0 +1,333 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wavSpec describes the sample encoding of a test WAV file.
+type wavSpec struct {
+	code       uint16
+	bits       int
+	channels   int
+	sampleRate int
+}
+
+// encodeWAV returns a WAV file holding frames, one slice of channel values in [-1, 1] per frame.
+func encodeWAV(spec wavSpec, frames [][]float64) []byte {
+	var data bytes.Buffer
+	for _, frame := range frames {
+		for _, value := range frame {
+			switch {
+			case spec.code == wavFormatFloat && spec.bits == 64:
+				binary.Write(&data, binary.LittleEndian, value)
+			case spec.code == wavFormatFloat:
+				binary.Write(&data, binary.LittleEndian, float32(value))
+			case spec.bits == 8:
+				data.WriteByte(byte(int(math.Round(value*127)) + 128))
+			case spec.bits == 16:
+				binary.Write(&data, binary.LittleEndian, int16(math.Round(value*math.MaxInt16)))
+			case spec.bits == 24:
+				sample := int32(math.Round(value * (1<<23 - 1)))
+				data.Write([]byte{byte(sample), byte(sample >> 8), byte(sample >> 16)})
+			default:
+				binary.Write(&data, binary.LittleEndian, int32(math.Round(value*math.MaxInt32)))
+			}
+		}
+	}
+
+	blockAlign := spec.channels * spec.bits / 8
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+8+16+8+data.Len()))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, spec.code)
+	binary.Write(&buf, binary.LittleEndian, uint16(spec.channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(spec.sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(spec.sampleRate*blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(spec.bits))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// toneFrames returns seconds of frames at rate, where channel c plays a 440 Hz tone of the given peak amplitude
+// when amplitudes[c] is positive and digital silence otherwise.
+func toneFrames(rate int, seconds float64, amplitudes ...float64) [][]float64 {
+	frames := make([][]float64, int(seconds*float64(rate)))
+	for i := range frames {
+		frames[i] = make([]float64, len(amplitudes))
+		for c, amplitude := range amplitudes {
+			frames[i][c] = amplitude * math.Cos(2*math.Pi*440*float64(i)/float64(rate))
+		}
+	}
+	return frames
+}
+
+// writeWAV writes a WAV file of frames to a temporary directory and returns its path.
+func writeWAV(t *testing.T, spec wavSpec, frames [][]float64) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.wav")
+	if err := os.WriteFile(path, encodeWAV(spec, frames), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// noFFmpeg is a CommandRunner that fails the test when the detector runs anything.
+func noFFmpeg(t *testing.T) CommandRunner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Errorf("unexpected run of %s %v", name, args)
+		return nil, errors.New("unexpected run")
+	}
+}
+
+// concatFrames joins the frames of consecutive sections.
+func concatFrames(sections ...[][]float64) [][]float64 {
+	var frames [][]float64
+	for _, section := range sections {
+		frames = append(frames, section...)
+	}
+	return frames
+}
+
+func TestDetectSilenceNativeFormats(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec wavSpec
+	}{
+		{name: "8-bit", spec: wavSpec{code: wavFormatPCM, bits: 8, channels: 1, sampleRate: 8000}},
+		{name: "16-bit", spec: wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}},
+		{name: "16-bit stereo", spec: wavSpec{code: wavFormatPCM, bits: 16, channels: 2, sampleRate: 8000}},
+		{name: "24-bit", spec: wavSpec{code: wavFormatPCM, bits: 24, channels: 2, sampleRate: 8000}},
+		{name: "32-bit", spec: wavSpec{code: wavFormatPCM, bits: 32, channels: 1, sampleRate: 8000}},
+		{name: "float", spec: wavSpec{code: wavFormatFloat, bits: 32, channels: 2, sampleRate: 8000}},
+		{name: "double", spec: wavSpec{code: wavFormatFloat, bits: 64, channels: 1, sampleRate: 8000}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tone := make([]float64, tc.spec.channels)
+			for c := range tone {
+				tone[c] = 0.5
+			}
+			silence := make([]float64, tc.spec.channels)
+			frames := concatFrames(toneFrames(8000, 1, tone...), toneFrames(8000, 2, silence...), toneFrames(8000, 1, tone...))
+			path := writeWAV(t, tc.spec, frames)
+
+			result, err := NewDetector(WithCommandRunner(noFFmpeg(t))).DetectSilence(context.Background(), path, DetectionOptions{
+				NoiseLevel:         -50,
+				MinSilenceDuration: 0.5,
+			})
+			if err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+			assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 3, Duration: 2}})
+			assertFloatEqual(t, result.InputDuration, 4)
+			if !result.DurationKnown || result.ThresholdDB != -50 {
+				t.Errorf("expected a known duration and the threshold, got %+v", result)
+			}
+			if result.AudioInfo == nil || result.AudioInfo.Channels != tc.spec.channels || result.AudioInfo.SampleRate != 8000 {
+				t.Errorf("expected the audio description of the header, got %+v", result.AudioInfo)
+			}
+		})
+	}
+}
+
+func TestDetectSilenceNativeThreshold(t *testing.T) {
+	// -40 dB is an amplitude of 0.01, so hiss peaking at 0.009 is silence and at 0.011 is not.
+	frames := concatFrames(toneFrames(8000, 1, 0.5), toneFrames(8000, 1, 0.009), toneFrames(8000, 1, 0.5), toneFrames(8000, 1, 0.011))
+	path := writeWAV(t, wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}, frames)
+
+	result, err := NewDetector(WithCommandRunner(noFFmpeg(t))).DetectSilence(context.Background(), path, DetectionOptions{
+		NoiseLevel:         -40,
+		MinSilenceDuration: 0.5,
+		Engine:             EngineNative,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 2, Duration: 1}})
+}
+
+func TestDetectSilenceNativeChannels(t *testing.T) {
+	// The right channel falls silent for two seconds while the left one keeps playing.
+	frames := concatFrames(toneFrames(8000, 1, 0.5, 0.5), toneFrames(8000, 2, 0.5, 0), toneFrames(8000, 1, 0.5, 0.5))
+	path := writeWAV(t, wavSpec{code: wavFormatPCM, bits: 16, channels: 2, sampleRate: 8000}, frames)
+	d := NewDetector(WithCommandRunner(noFFmpeg(t)))
+
+	result, err := d.DetectSilence(context.Background(), path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(result.Intervals) != 0 {
+		t.Errorf("expected no silence while a channel plays, got %v", result.Intervals)
+	}
+
+	result, err = d.DetectSilence(context.Background(), path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, PerChannel: true})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if _, ok := result.ChannelIntervals[0]; ok {
+		t.Errorf("expected no silence on the left channel, got %v", result.ChannelIntervals)
+	}
+	assertIntervals(t, result.ChannelIntervals[1], []SilenceInterval{{Start: 1, End: 3, Duration: 2}})
+	if len(result.Intervals) != 0 {
+		t.Errorf("expected no silence while the left channel plays, got %v", result.Intervals)
+	}
+}
+
+func TestDetectSilenceNativeWindow(t *testing.T) {
+	frames := concatFrames(toneFrames(8000, 1, 0.5), toneFrames(8000, 2, 0), toneFrames(8000, 1, 0.5), toneFrames(8000, 1, 0))
+	path := writeWAV(t, wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}, frames)
+	d := NewDetector(WithCommandRunner(noFFmpeg(t)))
+
+	result, err := d.DetectSilence(context.Background(), path, DetectionOptions{
+		NoiseLevel:         -50,
+		MinSilenceDuration: 0.5,
+		StartOffset:        0.5,
+		AnalyzeDuration:    2,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 2.5, Duration: 1.5}})
+	assertFloatEqual(t, result.WindowStart, 0.5)
+	assertFloatEqual(t, result.InputDuration, 2)
+
+	result, err = d.DetectSilence(context.Background(), path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, MaxIntervals: 1})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 3, Duration: 2}})
+	assertFloatEqual(t, result.InputDuration, 5)
+	if !result.Truncated {
+		t.Error("expected a truncated result")
+	}
+}
+
+func TestDetectSilenceNativeTruncatedFile(t *testing.T) {
+	wav := encodeWAV(wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}, concatFrames(toneFrames(8000, 1, 0.5), toneFrames(8000, 1, 0)))
+	// Cutting the file partway through a sample leaves the header claiming more data than there is.
+	path := filepath.Join(t.TempDir(), "cut.wav")
+	if err := os.WriteFile(path, wav[:len(wav)-4001], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewDetector(WithCommandRunner(noFFmpeg(t))).DetectSilence(context.Background(), path, DetectionOptions{
+		NoiseLevel:         -50,
+		MinSilenceDuration: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 13999.0 / 8000, Duration: 5999.0 / 8000}})
+}
+
+func TestDetectSilenceNativeKnownDuration(t *testing.T) {
+	path := writeWAV(t, wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}, concatFrames(toneFrames(8000, 1, 0.5), toneFrames(8000, 2, 0)))
+	d := NewDetector(WithCommandRunner(noFFmpeg(t)))
+
+	result, err := d.DetectSilence(context.Background(), path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, KnownDuration: 3.2})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Fatalf("expected no warnings within the tolerance, got %+v", result.Warnings)
+	}
+
+	result, err = d.DetectSilence(context.Background(), path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, KnownDuration: 2})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != WarningDurationMismatch {
+		t.Fatalf("expected a duration mismatch warning, got %+v", result.Warnings)
+	}
+	// The native engine keeps the duration it read from the samples.
+	assertFloatEqual(t, result.InputDuration, 3)
+}
+
+func TestDetectSilenceAutoFallsBackToFFmpeg(t *testing.T) {
+	wavPath := writeWAV(t, wavSpec{code: wavFormatPCM, bits: 16, channels: 1, sampleRate: 8000}, toneFrames(8000, 1, 0))
+	mp3Path := filepath.Join(t.TempDir(), "input.mp3")
+	if err := os.WriteFile(mp3Path, []byte("ID3\x04\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		options DetectionOptions
+	}{
+		{name: "not a wav file", path: mp3Path, options: DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5}},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.wav"), options: DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5}},
+		{name: "option needing ffmpeg", path: wavPath, options: DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, MeasureVolume: true}},
+		{name: "ffmpeg engine", path: wavPath, options: DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, Engine: EngineFFmpeg}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ran := false
+			runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				ran = true
+				return []byte("size=N/A time=00:00:01.00 bitrate=N/A speed=1x\n"), nil
+			}
+			if _, err := NewDetector(WithCommandRunner(runner)).DetectSilence(context.Background(), tc.path, tc.options); err != nil {
+				t.Fatalf("DetectSilence returned error: %v", err)
+			}
+			if !ran {
+				t.Error("expected ffmpeg to run")
+			}
+		})
+	}
+}
+
+func TestDetectSilenceNativeEngineErrors(t *testing.T) {
+	mp3Path := filepath.Join(t.TempDir(), "input.mp3")
+	if err := os.WriteFile(mp3Path, []byte("ID3\x04\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDetector(WithCommandRunner(noFFmpeg(t)))
+
+	_, err := d.DetectSilence(context.Background(), mp3Path, DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, Engine: EngineNative})
+	if !errors.Is(err, ErrNativeUnsupported) {
+		t.Errorf("expected ErrNativeUnsupported, got %v", err)
+	}
+
+	_, err = d.DetectSilence(context.Background(), "-", DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, Engine: EngineNative, Stdin: strings.NewReader("")})
+	if err == nil || !strings.Contains(err.Error(), "stdin") {
+		t.Errorf("expected a stdin error, got %v", err)
+	}
+
+	err = DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, Engine: EngineNative, MeasureVolume: true, SampleRate: 16000}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "native engine does not support SampleRate, MeasureVolume") {
+		t.Errorf("expected the unsupported options, got %v", err)
+	}
+	err = DetectionOptions{NoiseLevel: -50, MinSilenceDuration: 0.5, Engine: "sox"}.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown engine "sox"`) {
+		t.Errorf("expected an unknown engine error, got %v", err)
+	}
+}
+
+func TestReadWAVHeaderRejectsUnsupportedFormats(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec wavSpec
+	}{
+		{name: "a-law", spec: wavSpec{code: 6, bits: 8, channels: 1, sampleRate: 8000}},
+		{name: "12-bit", spec: wavSpec{code: wavFormatPCM, bits: 12, channels: 1, sampleRate: 8000}},
+		{name: "16-bit float", spec: wavSpec{code: wavFormatFloat, bits: 16, channels: 1, sampleRate: 8000}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readWAVHeader(bytes.NewReader(encodeWAV(tc.spec, nil))); !errors.Is(err, ErrNativeUnsupported) {
+				t.Errorf("expected ErrNativeUnsupported, got %v", err)
+			}
+		})
+	}
+}
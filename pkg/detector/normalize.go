@@ -0,0 +1,108 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// WarningCode identifies the kind of adjustment reported by a Warning.
+type WarningCode string
+
+const (
+	// WarningUnsorted reports intervals that were not in start order and have been sorted.
+	WarningUnsorted WarningCode = "unsorted"
+	// WarningClampedEnd reports an interval that ended after the analysed span and has been cut at its end.
+	WarningClampedEnd WarningCode = "clamped_end"
+	// WarningEmpty reports an interval without duration, possibly after clamping, that has been dropped.
+	WarningEmpty WarningCode = "empty"
+	// WarningOverlap reports an interval that started before the end of the previous one and has been merged into
+	// it.
+	WarningOverlap WarningCode = "overlap"
+	// WarningDurationMismatch reports that ffmpeg's progress went more than KnownDurationTolerance past
+	// DetectionOptions.KnownDuration. The known duration is still used, so silence past it is missing or clamped.
+	WarningDurationMismatch WarningCode = "duration_mismatch"
+)
+
+// KnownDurationTolerance is how many seconds ffmpeg's progress may exceed DetectionOptions.KnownDuration, as it does
+// by up to a frame of audio, before the result gets a WarningDurationMismatch.
+const KnownDurationTolerance = 0.5
+
+// Warning describes an adjustment Normalize made to the intervals of a detection result, or a WarningDurationMismatch.
+type Warning struct {
+	Code WarningCode `json:"code"`
+	// Channel is the channel whose intervals were adjusted in a per-channel result, or nil for Intervals.
+	Channel *int `json:"channel,omitempty"`
+	// Interval is the adjusted interval as ffmpeg reported it. It is nil for WarningUnsorted.
+	Interval *Interval `json:"interval,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// Normalize returns a copy of result whose intervals, and those of every channel, are safe to hand to consumers that
+// expect well-formed data: they are sorted by start, ends past the analysed span [WindowStart,
+// WindowStart+InputDuration] are clamped to it when InputDuration is known, intervals without duration are dropped,
+// and overlapping intervals are merged. Touching intervals are kept apart. ffmpeg has been seen to report a
+// silence_end a few milliseconds past the duration, for example.
+//
+// The copy has Normalized set, and the returned warnings, which describe every adjustment, are appended to its
+// Warnings. result itself is not modified, so callers can compare the raw and cleaned data. DetectSilence normalizes
+// its results unless DetectionOptions.DisableNormalize is set.
+func Normalize(result DetectionResult) (DetectionResult, []Warning) {
+	end := math.Inf(1)
+	if result.InputDuration > 0 {
+		end = result.WindowStart + result.InputDuration
+	}
+
+	var warnings []Warning
+	normalized := result
+	normalized.Intervals = normalizeIntervals(result.Intervals, end, nil, &warnings)
+	if result.ChannelIntervals != nil {
+		normalized.ChannelIntervals = make(map[int][]SilenceInterval, len(result.ChannelIntervals))
+		for _, channel := range result.Channels() {
+			normalized.ChannelIntervals[channel] = normalizeIntervals(result.ChannelIntervals[channel], end, &channel, &warnings)
+		}
+	}
+
+	normalized.Normalized = true
+	normalized.Warnings = append(result.Warnings[:len(result.Warnings):len(result.Warnings)], warnings...)
+	return normalized, warnings
+}
+
+// normalizeIntervals returns the normalized copy of intervals, which belong to channel, or to no channel when it is
+// nil, for an analysed span ending at end. A warning is appended to warnings for every adjustment.
+func normalizeIntervals(intervals []SilenceInterval, end float64, channel *int, warnings *[]Warning) []SilenceInterval {
+	warn := func(code WarningCode, interval *Interval, format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		if channel != nil {
+			message = fmt.Sprintf("channel %d: %s", *channel, message)
+		}
+		*warnings = append(*warnings, Warning{Code: code, Channel: channel, Interval: interval, Message: message})
+	}
+
+	if !sort.SliceIsSorted(intervals, func(i, j int) bool { return intervals[i].Start < intervals[j].Start }) {
+		warn(WarningUnsorted, nil, "silence intervals were not in start order")
+	}
+
+	var normalized []SilenceInterval
+	for _, interval := range sortedIntervals(intervals) {
+		reported := interval
+		if interval.End > end {
+			interval.End = end
+			interval.Duration = math.Max(interval.End-interval.Start, 0)
+			warn(WarningClampedEnd, &reported, "silence from %.3fs to %.3fs ends after the input at %.3fs", reported.Start, reported.End, end)
+		}
+		if !(interval.End > interval.Start) {
+			warn(WarningEmpty, &reported, "silence from %.3fs to %.3fs has no duration", reported.Start, reported.End)
+			continue
+		}
+		if n := len(normalized); n > 0 && interval.Start < normalized[n-1].End {
+			last := &normalized[n-1]
+			last.End = math.Max(last.End, interval.End)
+			last.Duration = last.End - last.Start
+			warn(WarningOverlap, &reported, "silence from %.3fs to %.3fs overlaps the previous one", reported.Start, reported.End)
+			continue
+		}
+		normalized = append(normalized, interval)
+	}
+	return normalized
+}
@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 10,
+		Intervals: []SilenceInterval{
+			{Start: 4, End: 5, Duration: 1},
+			{Start: 0, End: 2, Duration: 2},
+			{Start: 4.5, End: 6, Duration: 1.5},
+			{Start: 6, End: 6, Duration: 0},
+			{Start: 9, End: 10.004, Duration: 1.004},
+			{Start: 10.2, End: 10.5, Duration: 0.3},
+		},
+	}
+
+	normalized, warnings := Normalize(result)
+	assertIntervals(t, normalized.Intervals, []SilenceInterval{
+		{Start: 0, End: 2, Duration: 2},
+		{Start: 4, End: 6, Duration: 2},
+		{Start: 9, End: 10, Duration: 1},
+	})
+	if !normalized.Normalized {
+		t.Errorf("expected the result to be marked as normalized")
+	}
+
+	var codes []WarningCode
+	for _, warning := range warnings {
+		codes = append(codes, warning.Code)
+	}
+	want := []WarningCode{WarningUnsorted, WarningOverlap, WarningEmpty, WarningClampedEnd, WarningClampedEnd, WarningEmpty}
+	if len(codes) != len(want) {
+		t.Fatalf("unexpected warnings %v, want %v", codes, want)
+	}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("unexpected warnings %v, want %v", codes, want)
+		}
+	}
+	if got := warnings[3]; got.Interval == nil || got.Interval.End != 10.004 || got.Message != "silence from 9.000s to 10.004s ends after the input at 10.000s" {
+		t.Errorf("unexpected clamp warning %+v", got)
+	}
+	if len(normalized.Warnings) != len(warnings) {
+		t.Errorf("expected the warnings to be recorded in the result, got %v", normalized.Warnings)
+	}
+
+	if len(result.Intervals) != 6 || result.Intervals[4].End != 10.004 || result.Normalized {
+		t.Errorf("Normalize modified its input: %+v", result)
+	}
+	if again, warnings := Normalize(normalized); len(warnings) != 0 || len(again.Warnings) != len(normalized.Warnings) {
+		t.Errorf("expected a normalized result to stay unchanged, got warnings %v", warnings)
+	}
+}
+
+func TestNormalizeChannelsAndWindow(t *testing.T) {
+	result := DetectionResult{
+		WindowStart:   30,
+		InputDuration: 10,
+		Intervals:     []SilenceInterval{{Start: 35, End: 40.01, Duration: 5.01}},
+		ChannelIntervals: map[int][]SilenceInterval{
+			0: {{Start: 35, End: 40.01, Duration: 5.01}},
+			1: {{Start: 36, End: 38, Duration: 2}},
+		},
+	}
+
+	normalized, warnings := Normalize(result)
+	assertIntervals(t, normalized.Intervals, []SilenceInterval{{Start: 35, End: 40, Duration: 5}})
+	assertIntervals(t, normalized.ChannelIntervals[0], []SilenceInterval{{Start: 35, End: 40, Duration: 5}})
+	assertIntervals(t, normalized.ChannelIntervals[1], []SilenceInterval{{Start: 36, End: 38, Duration: 2}})
+	if len(warnings) != 2 || warnings[0].Channel != nil || warnings[1].Channel == nil || *warnings[1].Channel != 0 {
+		t.Fatalf("unexpected warnings %+v", warnings)
+	}
+	if result.ChannelIntervals[0][0].End != 40.01 {
+		t.Errorf("Normalize modified the channel intervals of its input")
+	}
+
+	// Without a known duration, ends are not clamped.
+	result.InputDuration = 0
+	if _, warnings := Normalize(result); len(warnings) != 0 {
+		t.Errorf("expected no warnings without a duration, got %+v", warnings)
+	}
+}
+
+func TestDetectSilenceNormalizesIntervals(t *testing.T) {
+	fakeOutput := `
+[silencedetect @ 0x123] silence_start: 1.000000
+[silencedetect @ 0x123] silence_end: 3.000000 | silence_duration: 2.000000
+[silencedetect @ 0x123] silence_start: 8.000000
+[silencedetect @ 0x123] silence_end: 10.005000 | silence_duration: 2.005000
+`
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" {
+			return []byte(`{"format":{"duration":"10.0"}}`), nil
+		}
+		return []byte(fakeOutput), nil
+	}
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}
+
+	result, err := d.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 1, End: 3, Duration: 2}, {Start: 8, End: 10, Duration: 2}})
+	if !result.Normalized || len(result.Warnings) != 1 || result.Warnings[0].Code != WarningClampedEnd {
+		t.Fatalf("expected a clamp warning, got normalized=%v warnings=%+v", result.Normalized, result.Warnings)
+	}
+
+	options.DisableNormalize = true
+	raw, err := d.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	assertIntervals(t, raw.Intervals, []SilenceInterval{{Start: 1, End: 3, Duration: 2}, {Start: 8, End: 10.005, Duration: 2.005}})
+	if raw.Normalized || raw.Warnings != nil {
+		t.Fatalf("expected raw intervals, got normalized=%v warnings=%+v", raw.Normalized, raw.Warnings)
+	}
+}
@@ -0,0 +1,65 @@
+package detector
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RawPCMSampleFormats lists the sample formats of headerless PCM input, named after ffmpeg's raw PCM demuxers.
+var RawPCMSampleFormats = []string{
+	"u8", "s8",
+	"s16le", "s16be", "u16le", "u16be",
+	"s24le", "s24be", "u24le", "u24be",
+	"s32le", "s32be", "u32le", "u32be",
+	"f32le", "f32be", "f64le", "f64be",
+	"alaw", "mulaw",
+}
+
+// RawPCMFormat describes headerless PCM audio, such as a dump of a DSP stage, which ffmpeg cannot probe. All three
+// fields are required.
+type RawPCMFormat struct {
+	// SampleRate is the number of sample frames per second, such as 48000.
+	SampleRate int
+	// Channels is the number of interleaved channels.
+	Channels int
+	// SampleFormat is one of RawPCMSampleFormats, such as "s16le".
+	SampleFormat string
+}
+
+// validate returns the problems of f, for DetectionOptions.Validate.
+func (f RawPCMFormat) validate() []error {
+	var (
+		problems []error
+		missing  []string
+	)
+	switch {
+	case f.SampleRate == 0:
+		missing = append(missing, "sample rate")
+	case f.SampleRate < 0:
+		problems = append(problems, fmt.Errorf("raw PCM sample rate must be positive, got %d", f.SampleRate))
+	}
+	switch {
+	case f.Channels == 0:
+		missing = append(missing, "channel count")
+	case f.Channels < 0:
+		problems = append(problems, fmt.Errorf("raw PCM channel count must be positive, got %d", f.Channels))
+	}
+	switch {
+	case f.SampleFormat == "":
+		missing = append(missing, "sample format")
+	case !slices.Contains(RawPCMSampleFormats, f.SampleFormat):
+		problems = append(problems, fmt.Errorf("unknown raw PCM sample format %q; supported formats are %s", f.SampleFormat, strings.Join(RawPCMSampleFormats, ", ")))
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Errorf("raw PCM input needs a sample rate, channel count and sample format together; missing %s", strings.Join(missing, ", ")))
+	}
+	return problems
+}
+
+// inputArgs returns the ffmpeg input options that read f: the demuxer, the sample rate and the channel count.
+func (f RawPCMFormat) inputArgs() []string {
+	return []string{"-f", f.SampleFormat, "-ar", strconv.Itoa(f.SampleRate), "-ac", strconv.Itoa(f.Channels)}
+}
@@ -0,0 +1,80 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectSilenceRawPCM(t *testing.T) {
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return []byte("[silencedetect @ 0x1] silence_start: 2\nsize=N/A time=00:00:05.00 bitrate=N/A\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "dsp.pcm", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		StartOffset:        1,
+		RawPCM:             &RawPCMFormat{SampleRate: 48000, Channels: 2, SampleFormat: "s16le"},
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("expected ffprobe to be skipped for raw PCM, got calls %q", calls)
+	}
+	if want := "ffmpeg -hide_banner -nostdin -ss 1 -f s16le -ar 48000 -ac 2 -i dsp.pcm -vn -sn -dn -af silencedetect=noise=-30dB:d=1 -f null -"; calls[0] != want {
+		t.Fatalf("unexpected command: got %q want %q", calls[0], want)
+	}
+	assertIntervals(t, result.Intervals, []Interval{{Start: 3, End: 6, Duration: 3}})
+}
+
+func TestDetectSilenceFromReaderRawPCM(t *testing.T) {
+	var args []string
+	runner := func(ctx context.Context, name string, a ...string) ([]byte, error) {
+		args = a
+		return []byte("size=N/A time=00:00:02.00 bitrate=N/A\n"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	options := DetectionOptions{MinSilenceDuration: 1, RawPCM: &RawPCMFormat{SampleRate: 8000, Channels: 1, SampleFormat: "f32le"}}
+	if _, err := d.DetectSilenceFromReader(context.Background(), strings.NewReader("samples"), "", options); err != nil {
+		t.Fatalf("DetectSilenceFromReader returned error: %v", err)
+	}
+	if got := strings.Join(args, " "); !strings.Contains(got, " -f f32le -ar 8000 -ac 1 -i pipe:0 ") {
+		t.Fatalf("expected the raw PCM format before the stdin input, got %q", got)
+	}
+}
+
+func TestDetectionOptionsValidateRawPCM(t *testing.T) {
+	tests := []struct {
+		name   string
+		format RawPCMFormat
+		input  string
+		want   string
+	}{
+		{name: "missing rate", format: RawPCMFormat{Channels: 2, SampleFormat: "s16le"}, want: "missing sample rate"},
+		{name: "missing all", format: RawPCMFormat{}, want: "missing sample rate, channel count, sample format"},
+		{name: "negative channels", format: RawPCMFormat{SampleRate: 48000, Channels: -1, SampleFormat: "s16le"}, want: "channel count must be positive"},
+		{name: "unknown format", format: RawPCMFormat{SampleRate: 48000, Channels: 2, SampleFormat: "s17le"}, want: `unknown raw PCM sample format "s17le"; supported formats are u8, s8, s16le`},
+		{name: "input format", format: RawPCMFormat{SampleRate: 48000, Channels: 2, SampleFormat: "s16le"}, input: "wav", want: "do not also set the input format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := tt.format
+			err := DetectionOptions{MinSilenceDuration: 1, InputFormat: tt.input, RawPCM: &format}.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	valid := DetectionOptions{MinSilenceDuration: 1, RawPCM: &RawPCMFormat{SampleRate: 44100, Channels: 1, SampleFormat: "mulaw"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected a complete raw PCM format to be valid, got %v", err)
+	}
+}
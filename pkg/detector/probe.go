@@ -0,0 +1,136 @@
+package detector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeDuration runs ffprobe to determine the duration of the input in seconds.
+func (d *Detector) probeDuration(ctx context.Context, inputPath string, options DetectionOptions) (float64, error) {
+	duration, _, err := d.probeInput(ctx, inputPath, options)
+	return duration, err
+}
+
+// probeInput runs ffprobe to determine the duration of the input in seconds and describe its audio streams. The audio
+// streams are returned even when ffprobe reported no duration.
+func (d *Detector) probeInput(ctx context.Context, inputPath string, options DetectionOptions) (float64, []AudioInfo, error) {
+	start := time.Now()
+	output, err := d.runFFprobe(ctx, inputPath, options, "-show_entries", "format=duration:"+probeStreamEntries)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	audio := parseProbeAudioInfo(output)
+	duration, err := parseProbeDuration(output)
+	if err != nil {
+		d.debug(ctx, "ffprobe failed", "elapsed", time.Since(start), "error", err.Error())
+		return 0, audio, err
+	}
+
+	d.debug(ctx, "ffprobe finished", "elapsed", time.Since(start), "duration", duration)
+	return duration, audio, nil
+}
+
+// probeAudioStreams runs ffprobe to list the audio streams of the input, in order.
+func (d *Detector) probeAudioStreams(ctx context.Context, inputPath string, options DetectionOptions) ([]StreamInfo, error) {
+	start := time.Now()
+	output, err := d.runFFprobe(ctx, inputPath, options, "-select_streams", "a", "-show_entries", "stream=codec_name,channels:stream_tags=language")
+	if err != nil {
+		return nil, err
+	}
+
+	streams, err := parseProbeAudioStreams(output)
+	if err != nil {
+		d.debug(ctx, "ffprobe failed", "elapsed", time.Since(start), "error", err.Error())
+		return nil, err
+	}
+
+	d.debug(ctx, "ffprobe finished", "elapsed", time.Since(start), "audio_streams", len(streams))
+	return streams, nil
+}
+
+// runFFprobe runs ffprobe on the input with the given entry selection arguments and returns its JSON output.
+func (d *Detector) runFFprobe(ctx context.Context, inputPath string, options DetectionOptions, entries ...string) ([]byte, error) {
+	args := append([]string{"-v", "error"}, entries...)
+	args = append(args, "-of", "json")
+	args = append(args, headerArgs(inputPath, options.InputHeaders)...)
+	args = append(args, inputPath)
+
+	d.debug(ctx, "running ffprobe", "path", d.ffprobePath, "args", RedactArgs(args))
+	start := time.Now()
+
+	output, err := d.run(ctx, d.ffprobePath, args...)
+	if err != nil {
+		err = fmt.Errorf("ffprobe execution failed: %w: %s", err, strings.TrimSpace(string(output)))
+		d.debug(ctx, "ffprobe failed", "elapsed", time.Since(start), "error", RedactText(err.Error()))
+		return nil, err
+	}
+	return output, nil
+}
+
+func parseProbeDuration(output []byte) (float64, error) {
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return 0, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	if probe.Format.Duration == "" || probe.Format.Duration == "N/A" {
+		return 0, errors.New("ffprobe did not report a duration")
+	}
+
+	duration, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse ffprobe duration: %w", err)
+	}
+	if !isFinite(duration) {
+		// ParseFloat accepts "inf" and "nan", which broken live and unknown-length containers can report.
+		return 0, fmt.Errorf("ffprobe reported non-finite duration %f", duration)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("ffprobe reported non-positive duration %f", duration)
+	}
+
+	return duration, nil
+}
+
+func parseProbeAudioStreams(output []byte) ([]StreamInfo, error) {
+	var probe struct {
+		Streams []struct {
+			CodecName string `json:"codec_name"`
+			Channels  int    `json:"channels"`
+			Tags      struct {
+				Language string `json:"language"`
+			} `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	streams := make([]StreamInfo, len(probe.Streams))
+	for i, stream := range probe.Streams {
+		streams[i] = StreamInfo{Index: i, Codec: stream.CodecName, Channels: stream.Channels, Language: stream.Tags.Language}
+	}
+	return streams, nil
+}
+
+// windowDuration converts a full input duration into the length of the window selected by options.
+func windowDuration(duration float64, options DetectionOptions) float64 {
+	remaining := duration - options.StartOffset
+	if remaining <= 0 {
+		return 0
+	}
+	if options.AnalyzeDuration > 0 && options.AnalyzeDuration < remaining {
+		return options.AnalyzeDuration
+	}
+	return remaining
+}
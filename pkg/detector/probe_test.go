@@ -0,0 +1,120 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDetectSilencePrefersFFprobeDuration(t *testing.T) {
+	ffmpegOutput := `
+[silencedetect @ 0x123] silence_start: 0.000000
+frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:11.50 bitrate=   0.0kbits/s speed=1x
+`
+
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		if name == "/opt/ffprobe" {
+			return []byte(`{"format": {"duration": "12.000000"}}`), nil
+		}
+		return []byte(ffmpegOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("/opt/ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if len(calls) != 2 || calls[0] != "/opt/ffprobe -v error -show_entries format=duration:"+probeStreamEntries+" -of json video.mp4" {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+
+	assertFloatEqual(t, result.InputDuration, 12)
+	assertIntervals(t, result.Intervals, []SilenceInterval{
+		{Start: 0, End: 12, Duration: 12},
+	})
+	if !result.FullySilent(1e-3) {
+		t.Fatalf("expected input to be fully silent")
+	}
+}
+
+func TestDetectSilenceFallsBackWhenFFprobeFails(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" {
+			return []byte("ffprobe: not found"), errors.New("exit status 127")
+		}
+		return []byte("frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:11.50 bitrate=   0.0kbits/s speed=1x"), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	assertFloatEqual(t, result.InputDuration, 11.5)
+}
+
+func TestDetectSilenceUsesFFprobeDurationForWindow(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "ffprobe" {
+			return []byte(`{"format": {"duration": "100.0"}}`), nil
+		}
+		return []byte(statsOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner), WithFFprobePath("ffprobe"))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		StartOffset:        90,
+		AnalyzeDuration:    30,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	assertFloatEqual(t, result.WindowStart, 90)
+	assertFloatEqual(t, result.InputDuration, 10)
+}
+
+func TestParseProbeDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    float64
+		wantErr bool
+	}{
+		{name: "valid", output: `{"format": {"duration": "3.250000"}}`, want: 3.25},
+		{name: "not available", output: `{"format": {"duration": "N/A"}}`, wantErr: true},
+		{name: "missing", output: `{"format": {}}`, wantErr: true},
+		{name: "infinite", output: `{"format": {"duration": "inf"}}`, wantErr: true},
+		{name: "NaN", output: `{"format": {"duration": "nan"}}`, wantErr: true},
+		{name: "invalid json", output: `duration=3.25`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProbeDuration([]byte(tt.output))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got duration %f", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProbeDuration returned error: %v", err)
+			}
+			assertFloatEqual(t, got, tt.want)
+		})
+	}
+}
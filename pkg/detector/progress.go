@@ -0,0 +1,144 @@
+package detector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SplitStreamingRunner starts an external command and returns separate readers over its standard output and
+// standard error together with a wait function.
+//
+// The detector passes ffmpeg "-progress pipe:1" so that machine-readable progress arrives on standard output while
+// silencedetect reports on standard error. Both readers must be read until EOF (or closed to stop early) before
+// wait is called.
+type SplitStreamingRunner func(ctx context.Context, name string, args ...string) (stdout, stderr io.ReadCloser, wait func() error, err error)
+
+// WithSplitStreamingRunner overrides the command execution function used for progress-reporting runs.
+func WithSplitStreamingRunner(runner SplitStreamingRunner) Option {
+	return func(d *Detector) {
+		d.split = runner
+	}
+}
+
+// progressArgs are the ffmpeg options that write machine-readable progress to standard output.
+var progressArgs = []string{"-progress", "pipe:1"}
+
+// splitStreamSilence executes ffmpeg through the SplitStreamingRunner, parsing silencedetect output from standard
+// error and -progress records from standard output as they arrive.
+func (d *Detector) splitStreamSilence(ctx context.Context, args []string, parser *silenceParser) (DetectionResult, error) {
+	stdout, stderr, wait, err := d.split(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return DetectionResult{}, newFFmpegError(ctx, d.ffmpegPath, args, err, "")
+	}
+
+	var progress progressParser
+	progressDone := make(chan error, 1)
+	go func() {
+		err := progress.read(stdout)
+		stdout.Close()
+		progressDone <- err
+	}()
+
+	tail, parseErr, scanErr := scanSilenceOutput(stderr, parser)
+
+	stderr.Close()
+	waitErr := wait()
+	progressErr := <-progressDone
+
+	if waitErr != nil && !parser.stopped {
+		return DetectionResult{}, newFFmpegError(ctx, d.ffmpegPath, args, waitErr, strings.Join(tail, "\n"))
+	}
+	if parseErr != nil {
+		return DetectionResult{}, parseErr
+	}
+	if scanErr != nil {
+		return DetectionResult{}, fmt.Errorf("read ffmpeg output: %w", scanErr)
+	}
+	if progressErr != nil && !parser.stopped {
+		return DetectionResult{}, fmt.Errorf("read ffmpeg progress: %w", progressErr)
+	}
+
+	if progress.seen {
+		parser.lastProgress = max(parser.lastProgress, progress.outTime)
+		parser.progressSeen = true
+	}
+	// Progress records show that ffmpeg ran even if its standard error said nothing recognisable.
+	if !progress.seen {
+		if err := parser.checkRecognized(); err != nil {
+			return DetectionResult{}, err
+		}
+	}
+
+	return parser.finish(), nil
+}
+
+// progressParser consumes the key=value records ffmpeg writes with -progress.
+type progressParser struct {
+	// outTime is the furthest output timestamp reported, in seconds.
+	outTime float64
+	// seen reports whether any timestamp was reported.
+	seen bool
+}
+
+// read parses progress records from r until EOF.
+func (p *progressParser) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxOutputLineLength)
+	for scanner.Scan() {
+		p.parseLine(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// parseLine records the output timestamp from a single key=value progress line. Other keys, such as the
+// "progress=continue" and "progress=end" record terminators, and unavailable values ("N/A") are ignored.
+func (p *progressParser) parseLine(line string) {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok || key != "out_time_us" {
+		return
+	}
+
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros < 0 {
+		return
+	}
+	p.outTime = max(p.outTime, float64(micros)/1e6)
+	p.seen = true
+}
+
+func defaultSplitStreamingRunner(ctx context.Context, name string, args ...string) (io.ReadCloser, io.ReadCloser, func() error, error) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = CommandStdin(ctx)
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	if err := cmd.Start(); err != nil {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		stdoutReader.Close()
+		stderrReader.Close()
+		return nil, nil, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		done <- err
+	}()
+
+	wait := func() error {
+		return <-done
+	}
+
+	return stdoutReader, stderrReader, wait, nil
+}
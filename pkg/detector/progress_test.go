@@ -0,0 +1,127 @@
+package detector
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestProgressParserReadsOutTime(t *testing.T) {
+	var p progressParser
+	for _, line := range []string{
+		"frame=0",
+		"out_time_us=N/A",
+		"progress=continue",
+		"out_time_us=5000000",
+		"out_time=00:00:05.000000",
+		"progress=continue",
+		"out_time_us=12500000",
+		"progress=continue",
+		"out_time_us=9000000",
+		"progress=end",
+	} {
+		p.parseLine(line)
+	}
+
+	if !p.seen {
+		t.Fatal("expected a timestamp to be recorded")
+	}
+	assertFloatEqual(t, p.outTime, 12.5)
+}
+
+func TestProgressParserIgnoresUnavailableValues(t *testing.T) {
+	var p progressParser
+	p.parseLine("out_time_us=N/A")
+	p.parseLine("out_time_us=-5000")
+	p.parseLine("progress=end")
+
+	if p.seen {
+		t.Fatalf("expected no timestamp, got %f", p.outTime)
+	}
+}
+
+func TestDetectSilenceReadsProgressPipe(t *testing.T) {
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) (io.ReadCloser, io.ReadCloser, func() error, error) {
+		capturedArgs = args
+		stdout := "out_time_us=4000000\nprogress=continue\nout_time_us=12500000\nprogress=end\n"
+		stderr := "[silencedetect @ 0x1] silence_start: 0\n" +
+			"[silencedetect @ 0x1] silence_end: 2 | silence_duration: 2\n" +
+			"[silencedetect @ 0x1] silence_start: 10\n"
+		return io.NopCloser(strings.NewReader(stdout)), io.NopCloser(strings.NewReader(stderr)), func() error { return nil }, nil
+	}
+
+	d := NewDetector(WithSplitStreamingRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if want := "-progress pipe:1 -hide_banner -nostdin -nostats -i video.mp4 "; !strings.HasPrefix(strings.Join(capturedArgs, " "), want) {
+		t.Fatalf("unexpected args %q, want prefix %q", capturedArgs, want)
+	}
+	assertFloatEqual(t, result.InputDuration, 12.5)
+	assertIntervals(t, result.Intervals, []SilenceInterval{
+		{Start: 0, End: 2, Duration: 2},
+		{Start: 10, End: 12.5, Duration: 2.5},
+	})
+}
+
+func TestDetectSilenceFallsBackToProgressLine(t *testing.T) {
+	var capturedArgs []string
+	runner := func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+		capturedArgs = args
+		output := "[silencedetect @ 0x1] silence_start: 10\n" +
+			"size=N/A time=00:00:12.50 bitrate=N/A speed=1x\n"
+		return io.NopCloser(strings.NewReader(output)), func() error { return nil }, nil
+	}
+
+	d := NewDetector(WithStreamingRunner(runner))
+	result, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+	if err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+
+	if got := strings.Join(capturedArgs, " "); strings.Contains(got, "-progress") || strings.Contains(got, "-nostats") {
+		t.Fatalf("expected the progress line to be kept without -progress, got %q", got)
+	}
+	assertFloatEqual(t, result.InputDuration, 12.5)
+	assertIntervals(t, result.Intervals, []SilenceInterval{{Start: 10, End: 12.5, Duration: 2.5}})
+}
+
+func TestDefaultSplitStreamingRunnerSeparatesOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	stdout, stderr, wait, err := defaultSplitStreamingRunner(context.Background(), "sh", "-c", `printf 'out\n'; printf 'err\n' >&2`)
+	if err != nil {
+		t.Fatalf("defaultSplitStreamingRunner returned error: %v", err)
+	}
+
+	errDone := make(chan string, 1)
+	go func() {
+		data, _ := io.ReadAll(stderr)
+		errDone <- string(data)
+	}()
+	out, err := io.ReadAll(stdout)
+	if err != nil {
+		t.Fatalf("read stdout: %v", err)
+	}
+	errOutput := <-errDone
+	if err := wait(); err != nil {
+		t.Fatalf("wait returned error: %v", err)
+	}
+
+	if string(out) != "out\n" || errOutput != "err\n" {
+		t.Fatalf("expected separate streams, got stdout %q and stderr %q", out, errOutput)
+	}
+}
@@ -0,0 +1,179 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClippingLevelDB is the peak level, in dBFS, from which the samples at a channel's peak count as clipped. It sits
+// just below 0 dBFS so that the largest positive value of integer formats, such as 32767 in 16-bit audio, qualifies.
+const ClippingLevelDB = -0.01
+
+// QualityReport describes capture problems in the audio of an input, measured by ffmpeg's astats filter.
+type QualityReport struct {
+	// PeakDB is the highest sample level of any channel in dBFS. It is nil when the audio is digital silence.
+	PeakDB *float64 `json:"peak_db"`
+	// ClippedSamples is the number of samples, across all channels, at a peak of ClippingLevelDB or more.
+	ClippedSamples int64 `json:"clipped_samples"`
+	// DCOffset is the largest absolute DC offset of any channel, as a fraction of full scale.
+	DCOffset float64 `json:"dc_offset"`
+	// FlatFactor is the highest flat factor of any channel: how long, in dB, the runs of consecutive samples at the
+	// channel's peak level are. Sustained clipping shows as a high flat factor, isolated full-scale samples as 0.
+	FlatFactor float64 `json:"flat_factor"`
+	// Channels holds the measurements of every channel, in channel order.
+	Channels []ChannelQuality `json:"channels"`
+}
+
+// ChannelQuality holds the astats measurements of a single channel.
+type ChannelQuality struct {
+	// Channel is the 1-based channel number astats reports.
+	Channel        int      `json:"channel"`
+	PeakDB         *float64 `json:"peak_db"`
+	ClippedSamples int64    `json:"clipped_samples"`
+	DCOffset       float64  `json:"dc_offset"`
+	FlatFactor     float64  `json:"flat_factor"`
+}
+
+// AnalyzeQuality executes ffmpeg with the astats filter over the audio of inputPath and reports clipping and DC
+// offset.
+//
+// astats has no count of clipped samples, so ClippedSamples adds up the "Peak count" of the channels whose "Peak
+// level dB" reaches ClippingLevelDB: the number of samples at the channel's extremes when those extremes are full
+// scale. Standard input is not supported.
+func (d *Detector) AnalyzeQuality(ctx context.Context, inputPath string) (QualityReport, error) {
+	if err := validateQualityInput(inputPath); err != nil {
+		return QualityReport{}, err
+	}
+
+	args := d.qualityArgs(inputPath)
+	d.debug(ctx, "running ffmpeg", "path", d.ffmpegPath, "args", RedactArgs(args))
+	start := time.Now()
+
+	output, err := d.run(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return QualityReport{}, newFFmpegError(ctx, d.ffmpegPath, args, err, string(output))
+	}
+
+	report, err := parseQualityOutput(string(output))
+	if err != nil {
+		return QualityReport{}, err
+	}
+	d.debug(ctx, "ffmpeg finished", "elapsed", time.Since(start), "clipped_samples", report.ClippedSamples, "dc_offset", report.DCOffset)
+	return report, nil
+}
+
+// BuildQualityArgs returns the ffmpeg arguments AnalyzeQuality would run for inputPath, after the same validation.
+// The ffmpeg binary itself is not included.
+func (d *Detector) BuildQualityArgs(inputPath string) ([]string, error) {
+	if err := validateQualityInput(inputPath); err != nil {
+		return nil, err
+	}
+	return d.qualityArgs(inputPath), nil
+}
+
+// validateQualityInput checks the input of a quality analysis before ffmpeg runs.
+func validateQualityInput(inputPath string) error {
+	if inputPath == "" {
+		return errors.New("input path is required")
+	}
+	if isPipeInput(inputPath) {
+		return errors.New("quality analysis does not support reading from stdin")
+	}
+	return nil
+}
+
+// qualityArgs constructs the ffmpeg arguments of an astats run.
+func (d *Detector) qualityArgs(inputPath string) []string {
+	var args []string
+	if !d.rawOutput {
+		args = append(args, "-hide_banner", "-nostdin", "-nostats")
+	}
+	return append(args, filterArgs(inputPath, DetectionOptions{}, "astats")...)
+}
+
+// parseQualityOutput collects the per-channel astats summary from ffmpeg output. The "Overall" section is skipped,
+// since it averages some fields across channels; the report derives its totals from the channels instead.
+func parseQualityOutput(output string) (QualityReport, error) {
+	var (
+		report  QualityReport
+		channel *ChannelQuality
+	)
+	finish := func() {
+		if channel == nil {
+			return
+		}
+		if channel.PeakDB == nil || *channel.PeakDB < ClippingLevelDB {
+			channel.ClippedSamples = 0
+		}
+		report.Channels = append(report.Channels, *channel)
+		channel = nil
+	}
+
+	for _, line := range strings.Split(outputLineEndings.Replace(output), "\n") {
+		line = strings.TrimSpace(logPrefixPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line == "Overall" {
+			finish()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if key == "Channel" {
+			finish()
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return QualityReport{}, fmt.Errorf("invalid astats channel %q in ffmpeg output", value)
+			}
+			channel = &ChannelQuality{Channel: number}
+			continue
+		}
+		if channel == nil {
+			continue
+		}
+
+		switch key {
+		case "Peak level dB", "DC offset", "Flat factor":
+			// ParseFloat accepts the "-inf" and "nan" astats reports for silent channels.
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return QualityReport{}, fmt.Errorf("invalid astats %s %q in ffmpeg output", strings.ToLower(key), value)
+			}
+			finite := !math.IsInf(number, 0) && !math.IsNaN(number)
+			switch {
+			case key == "Peak level dB" && finite:
+				channel.PeakDB = &number
+			case key == "DC offset" && finite:
+				channel.DCOffset = number
+			case key == "Flat factor" && finite:
+				channel.FlatFactor = number
+			}
+		case "Peak count":
+			count, err := strconv.ParseFloat(value, 64)
+			if err != nil || count < 0 {
+				return QualityReport{}, fmt.Errorf("invalid astats peak count %q in ffmpeg output", value)
+			}
+			channel.ClippedSamples = int64(count)
+		}
+	}
+	finish()
+
+	if len(report.Channels) == 0 {
+		return QualityReport{}, errors.New("ffmpeg output did not include audio statistics")
+	}
+	for _, ch := range report.Channels {
+		if ch.PeakDB != nil && (report.PeakDB == nil || *ch.PeakDB > *report.PeakDB) {
+			report.PeakDB = ch.PeakDB
+		}
+		report.ClippedSamples += ch.ClippedSamples
+		report.DCOffset = max(report.DCOffset, math.Abs(ch.DCOffset))
+		report.FlatFactor = max(report.FlatFactor, ch.FlatFactor)
+	}
+	return report, nil
+}
@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const qualityOutput = `[Parsed_astats_0 @ 0x1] Channel: 1
+[Parsed_astats_0 @ 0x1] DC offset: 0.000120
+[Parsed_astats_0 @ 0x1] Min level: -1.000000
+[Parsed_astats_0 @ 0x1] Max level: 0.999969
+[Parsed_astats_0 @ 0x1] Peak level dB: 0.000000
+[Parsed_astats_0 @ 0x1] RMS level dB: -12.100000
+[Parsed_astats_0 @ 0x1] Flat factor: 18.061800
+[Parsed_astats_0 @ 0x1] Peak count: 40
+[Parsed_astats_0 @ 0x1] Channel: 2
+[Parsed_astats_0 @ 0x1] DC offset: -0.031000
+[Parsed_astats_0 @ 0x1] Peak level dB: -3.500000
+[Parsed_astats_0 @ 0x1] Flat factor: 0.000000
+[Parsed_astats_0 @ 0x1] Peak count: 2
+[Parsed_astats_0 @ 0x1] Channel: 3
+[Parsed_astats_0 @ 0x1] DC offset: 0.000000
+[Parsed_astats_0 @ 0x1] Peak level dB: -inf
+[Parsed_astats_0 @ 0x1] Flat factor: nan
+[Parsed_astats_0 @ 0x1] Peak count: 96000
+[Parsed_astats_0 @ 0x1] Overall
+[Parsed_astats_0 @ 0x1] DC offset: 0.031000
+[Parsed_astats_0 @ 0x1] Peak level dB: 0.000000
+[Parsed_astats_0 @ 0x1] Peak count: 32032.000000
+`
+
+func TestAnalyzeQuality(t *testing.T) {
+	var gotArgs []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(qualityOutput), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	report, err := d.AnalyzeQuality(context.Background(), "audio.wav")
+	if err != nil {
+		t.Fatalf("AnalyzeQuality returned error: %v", err)
+	}
+	if filter := argAfter(gotArgs, "-af"); filter != "astats" {
+		t.Errorf("expected the astats filter, got %q", filter)
+	}
+
+	if len(report.Channels) != 3 {
+		t.Fatalf("expected 3 channels, got %+v", report.Channels)
+	}
+	// Only the first channel peaks at full scale, and the silent third has no peak at all.
+	if report.ClippedSamples != 40 {
+		t.Errorf("expected 40 clipped samples, got %d", report.ClippedSamples)
+	}
+	if report.Channels[1].ClippedSamples != 0 || report.Channels[2].ClippedSamples != 0 {
+		t.Errorf("expected no clipping below full scale, got %+v", report.Channels)
+	}
+	if report.PeakDB == nil || report.Channels[2].PeakDB != nil {
+		t.Fatalf("expected a peak for all but the silent channel, got %+v", report)
+	}
+	assertFloatEqual(t, *report.PeakDB, 0)
+	assertFloatEqual(t, report.DCOffset, 0.031)
+	assertFloatEqual(t, report.Channels[1].DCOffset, -0.031)
+	assertFloatEqual(t, report.FlatFactor, 18.0618)
+	assertFloatEqual(t, report.Channels[2].FlatFactor, 0)
+}
+
+func TestAnalyzeQualityWithoutStatistics(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	if _, err := d.AnalyzeQuality(context.Background(), "audio.wav"); err == nil || !strings.Contains(err.Error(), "audio statistics") {
+		t.Fatalf("expected a missing statistics error, got %v", err)
+	}
+}
+
+func TestBuildQualityArgsRejectsStdin(t *testing.T) {
+	if _, err := NewDetector().BuildQualityArgs("-"); err == nil {
+		t.Fatal("expected an error for stdin")
+	}
+}
@@ -0,0 +1,48 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RemovalFilter returns an ffmpeg audio filter that removes the detected silence, keeping only the non-silent
+// regions of the analysed span, such as
+//
+//	aselect='between(t,2.5,7)+between(t,9,12.25)',asetpts=N/SR/TB
+//
+// for use with -af, where the regions are the KeepSegments for padding. Without silence the filter is anull, which
+// passes the audio through unchanged. Audio outside the analysed span, before a StartOffset or after an
+// AnalyzeDuration, is dropped.
+//
+// RemovalFilter returns ErrNothingToKeep when the whole span is silent, and an error when padding is negative or
+// the span's duration is unknown.
+func (r DetectionResult) RemovalFilter(padding float64) (string, error) {
+	if padding < 0 || math.IsNaN(padding) || math.IsInf(padding, 0) {
+		return "", fmt.Errorf("invalid padding %v", padding)
+	}
+	if len(r.Intervals) == 0 {
+		return "anull", nil
+	}
+	if r.InputDuration <= 0 {
+		return "", errors.New("the input duration is unknown, so the audio after the last silence cannot be kept")
+	}
+
+	kept := r.KeepSegments(padding)
+	if len(kept) == 0 {
+		return "", ErrNothingToKeep
+	}
+
+	terms := make([]string, len(kept))
+	for i, region := range kept {
+		terms[i] = fmt.Sprintf("between(t,%s,%s)", filterSeconds(region.Start), filterSeconds(region.End))
+	}
+	return fmt.Sprintf("aselect='%s',asetpts=N/SR/TB", strings.Join(terms, "+")), nil
+}
+
+// filterSeconds formats seconds for a filter expression, to the microsecond and without trailing zeros.
+func filterSeconds(v float64) string {
+	return strconv.FormatFloat(roundTo(v, 6), 'f', -1, 64)
+}
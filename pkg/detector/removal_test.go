@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemovalFilter(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 20,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 2.5, Duration: 2.5},
+			{Start: 7, End: 7.4, Duration: 0.4},
+			{Start: 12.25, End: 20, Duration: 7.75},
+		},
+	}
+
+	tests := []struct {
+		padding float64
+		want    string
+	}{
+		{padding: 0, want: "aselect='between(t,2.5,7)+between(t,7.4,12.25)',asetpts=N/SR/TB"},
+		// The padding merges the regions around the short silence and is clamped to the start of the input.
+		{padding: 0.25, want: "aselect='between(t,2.25,12.5)',asetpts=N/SR/TB"},
+		{padding: 5, want: "aselect='between(t,0,17.25)',asetpts=N/SR/TB"},
+	}
+	for _, tt := range tests {
+		got, err := result.RemovalFilter(tt.padding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("RemovalFilter(%v) = %q, want %q", tt.padding, got, tt.want)
+		}
+	}
+}
+
+func TestRemovalFilterDegenerateInputs(t *testing.T) {
+	if got, err := (DetectionResult{InputDuration: 10}).RemovalFilter(0.5); err != nil || got != "anull" {
+		t.Errorf("expected an identity filter without silence, got %q and %v", got, err)
+	}
+
+	silent := DetectionResult{InputDuration: 10, Intervals: []SilenceInterval{{Start: 0, End: 6, Duration: 6}, {Start: 5, End: 10, Duration: 5}}}
+	if _, err := silent.RemovalFilter(1); !errors.Is(err, ErrNothingToKeep) {
+		t.Errorf("expected ErrNothingToKeep for a silent input, got %v", err)
+	}
+
+	unknown := DetectionResult{Intervals: []SilenceInterval{{Start: 1, End: 2, Duration: 1}}}
+	if _, err := unknown.RemovalFilter(0); err == nil {
+		t.Error("expected an error without the input duration")
+	}
+	if _, err := (DetectionResult{InputDuration: 10}).RemovalFilter(-1); err == nil {
+		t.Error("expected an error for a negative padding")
+	}
+}
+
+func TestRemovalFilterKeepsTheAnalysedWindow(t *testing.T) {
+	result := DetectionResult{
+		WindowStart:   60,
+		InputDuration: 30,
+		Intervals:     []SilenceInterval{{Start: 70, End: 75, Duration: 5}},
+	}
+	got, err := result.RemovalFilter(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "aselect='between(t,60,71)+between(t,74,90)',asetpts=N/SR/TB"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
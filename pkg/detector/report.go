@@ -0,0 +1,248 @@
+package detector
+
+import "math"
+
+// ReportSchemaVersion is the version of the JSON layout of Report and of the CLI's reports. It is incremented when a
+// field is renamed or removed or its meaning changes; fields may be added without a new version.
+const ReportSchemaVersion = 1
+
+// Report is the JSON representation of a detection result shared by the CLI and library users. Optional sections
+// are omitted when they were not requested.
+type Report struct {
+	SchemaVersion int    `json:"schema_version"`
+	Input         string `json:"input"`
+	// CheckedAt is when a live stream was checked, in RFC 3339 format.
+	CheckedAt string  `json:"checked_at,omitempty"`
+	NoiseDB   float64 `json:"noise_db"`
+	// NoiseRatio is DetectionOptions.NoiseRatio, when the threshold was given as an amplitude ratio; NoiseDB is then
+	// its value in dB.
+	NoiseRatio *float64 `json:"noise_ratio,omitempty"`
+	// HysteresisDB is DetectionOptions.HysteresisDB: silences closed only above NoiseDB plus this many dB.
+	HysteresisDB float64 `json:"hysteresis_db,omitempty"`
+	// NoiseFloorDB is the measured noise floor in adaptive mode, when it is finite.
+	NoiseFloorDB  *float64 `json:"noise_floor_db,omitempty"`
+	MinDuration   float64  `json:"min_duration"`
+	NoAudioStream bool     `json:"no_audio_stream,omitempty"`
+	// Audio describes the analysed audio stream, when ffprobe or ffmpeg reported it.
+	Audio *AudioInfo `json:"audio,omitempty"`
+	// FullSilenceUnknown replaces FullySilent when the full-silence verdict was requested but could not be
+	// determined, as DetectionResult.FullySilentStatus reports.
+	FullSilenceUnknown bool `json:"full_silence_unknown,omitempty"`
+	// MinSilenceCoverage is ReportOptions.MinSilenceCoverage, and EffectivelySilent the verdict of
+	// DetectionResult.EffectivelySilentStatus for it, which is left out when it could not be determined.
+	MinSilenceCoverage float64 `json:"min_silence_coverage,omitempty"`
+	EffectivelySilent  *bool   `json:"effectively_silent,omitempty"`
+	// Duration is the input duration in seconds, or zero when it is unknown.
+	Duration      float64    `json:"duration"`
+	WindowStart   float64    `json:"window_start,omitempty"`
+	FullySilent   *bool      `json:"fully_silent,omitempty"`
+	Leading       *float64   `json:"leading_silence,omitempty"`
+	Trailing      *float64   `json:"trailing_silence,omitempty"`
+	MeanVolumeDB  *float64   `json:"mean_volume_db,omitempty"`
+	MaxVolumeDB   *float64   `json:"max_volume_db,omitempty"`
+	Loudness      *Loudness  `json:"loudness,omitempty"`
+	IntervalCount int        `json:"interval_count"`
+	Truncated     bool       `json:"truncated,omitempty"`
+	Partial       bool       `json:"partial,omitempty"`
+	TotalSilence  float64    `json:"total_silence"`
+	SilenceRatio  float64    `json:"silence_ratio"`
+	Longest       *Interval  `json:"longest_interval,omitempty"`
+	Intervals     []Interval `json:"intervals"`
+	// Warnings lists the adjustments Normalize made to the intervals ffmpeg reported, and a mismatch with the known
+	// duration.
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Channels holds the per-channel results of a PerChannel detection.
+	Channels      []ChannelReport `json:"channels,omitempty"`
+	NoVideoStream bool            `json:"no_video_stream,omitempty"`
+	// Black, DeadAir and Freeze are the video sections added by callers that also ran DetectBlack and DetectFreeze.
+	Black   *IntervalsReport `json:"black,omitempty"`
+	DeadAir *IntervalsReport `json:"dead_air,omitempty"`
+	Freeze  *IntervalsReport `json:"freeze,omitempty"`
+	// EnergyWindow and Samples are the window length, in seconds, and the RMS levels of the energy timeline added by
+	// callers that also ran Detector.EnergyTimeline.
+	EnergyWindow float64        `json:"energy_window,omitempty"`
+	Samples      []EnergySample `json:"samples,omitempty"`
+	// Quality is the clipping and DC offset report added by callers that also ran Detector.AnalyzeQuality.
+	Quality *QualityReport `json:"quality,omitempty"`
+	// Histogram holds the duration classes of DetectionResult.Histogram, added by callers that requested them.
+	Histogram []Bucket `json:"histogram,omitempty"`
+}
+
+// ChannelReport is the JSON representation of a single channel's result in a per-channel detection.
+type ChannelReport struct {
+	Channel     int        `json:"channel"`
+	FullySilent *bool      `json:"fully_silent,omitempty"`
+	Intervals   []Interval `json:"intervals"`
+}
+
+// IntervalsReport is the JSON representation of the black frame, dead air and freeze sections of a Report.
+type IntervalsReport struct {
+	Count     int        `json:"count"`
+	Total     float64    `json:"total"`
+	Intervals []Interval `json:"intervals"`
+}
+
+// NewIntervalsReport summarises intervals, listing them by start time. A nil slice is reported as an empty list.
+func NewIntervalsReport(intervals []Interval) *IntervalsReport {
+	intervals = sortedIntervals(intervals)
+	if intervals == nil {
+		intervals = []Interval{}
+	}
+	var total float64
+	for _, interval := range intervals {
+		total += interval.Duration
+	}
+	return &IntervalsReport{Count: len(intervals), Total: total, Intervals: intervals}
+}
+
+// ReportOptions selects the verdicts included in a Report.
+type ReportOptions struct {
+	// FullSilence, LeadingSilence and TrailingSilence add the fully_silent, leading_silence and trailing_silence
+	// fields, evaluated with Tolerance.
+	FullSilence     bool
+	LeadingSilence  bool
+	TrailingSilence bool
+	// Tolerance is the slack, in seconds, passed to DetectionResult.FullySilent, LeadingSilence and TrailingSilence.
+	Tolerance float64
+	// FullSilenceTolerance, when non-nil, replaces Tolerance for the fully_silent fields.
+	FullSilenceTolerance *FullySilentOpts
+	// MinSilenceCoverage, when positive, adds the effectively_silent field, evaluated with
+	// DetectionResult.EffectivelySilentStatus.
+	MinSilenceCoverage float64
+}
+
+func (o ReportOptions) fullSilenceTolerance() FullySilentOpts {
+	if o.FullSilenceTolerance != nil {
+		return *o.FullSilenceTolerance
+	}
+	return uniformTolerance(o.Tolerance)
+}
+
+// NewReport builds the report of result, which was detected in input with options. The volume, loudness and
+// per-channel sections are included when options requested them. Intervals are listed by start time.
+func NewReport(input string, options DetectionOptions, result DetectionResult, reportOptions ReportOptions) *Report {
+	intervals := sortedIntervals(result.Intervals)
+	if intervals == nil {
+		intervals = []Interval{}
+	}
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		Input:         input,
+		NoiseDB:       options.thresholdDB(),
+		NoiseRatio:    options.NoiseRatio,
+		HysteresisDB:  options.HysteresisDB,
+		MinDuration:   options.MinSilenceDuration,
+		Audio:         result.AudioInfo,
+		Duration:      result.InputDuration,
+		WindowStart:   result.WindowStart,
+		IntervalCount: len(result.Intervals),
+		Truncated:     result.Truncated,
+		Partial:       result.Partial,
+		Warnings:      result.Warnings,
+		TotalSilence:  result.TotalSilence(),
+		SilenceRatio:  result.SilenceRatio(),
+		Intervals:     intervals,
+	}
+
+	if longest, ok := result.LongestInterval(); ok {
+		report.Longest = &longest
+	}
+
+	// The threshold used in adaptive mode depends on the input. A floor of -Inf (digital silence) has no JSON
+	// representation and is omitted; noise_db then holds the fallback threshold.
+	if options.AdaptiveThreshold {
+		report.NoiseDB = result.ThresholdDB
+		if result.NoiseFloorDB != nil && !math.IsInf(*result.NoiseFloorDB, 0) {
+			report.NoiseFloorDB = result.NoiseFloorDB
+		}
+	}
+
+	if reportOptions.FullSilence {
+		if fullySilent, determinate := result.FullySilentStatusWithin(reportOptions.fullSilenceTolerance()); determinate {
+			report.FullySilent = &fullySilent
+		} else {
+			report.FullSilenceUnknown = true
+		}
+	}
+	if reportOptions.MinSilenceCoverage > 0 {
+		report.MinSilenceCoverage = reportOptions.MinSilenceCoverage
+		if silent, determinate := result.EffectivelySilentStatus(reportOptions.MinSilenceCoverage); determinate {
+			report.EffectivelySilent = &silent
+		}
+	}
+	if reportOptions.LeadingSilence {
+		leading := result.LeadingSilence(reportOptions.Tolerance)
+		report.Leading = &leading
+	}
+	if reportOptions.TrailingSilence {
+		trailing := result.TrailingSilence(reportOptions.Tolerance)
+		report.Trailing = &trailing
+	}
+
+	if options.MeasureVolume {
+		report.MeanVolumeDB = result.MeanVolumeDB
+		report.MaxVolumeDB = result.MaxVolumeDB
+	}
+	if options.MeasureLoudness {
+		report.Loudness = result.Loudness
+	}
+
+	if options.PerChannel {
+		for _, channel := range result.Channels() {
+			channelReport := ChannelReport{Channel: channel, Intervals: sortedIntervals(result.ChannelIntervals[channel])}
+			if reportOptions.FullSilence && !report.FullSilenceUnknown {
+				fullySilent := result.ChannelFullySilentWithin(channel, reportOptions.fullSilenceTolerance())
+				channelReport.FullySilent = &fullySilent
+			}
+			report.Channels = append(report.Channels, channelReport)
+		}
+	}
+
+	return report
+}
+
+// Round rounds the times and durations of the report, including those of its channel, video and energy sections, to
+// precision decimal places, and sorts its intervals by start time. Verdicts such as FullySilent keep the values
+// computed from the measured times. Levels and ratios are left as they are. A negative precision does nothing.
+func (r *Report) Round(precision int) {
+	r.Duration = roundTo(r.Duration, precision)
+	r.WindowStart = roundTo(r.WindowStart, precision)
+	r.Leading = roundPointer(r.Leading, precision)
+	r.Trailing = roundPointer(r.Trailing, precision)
+	r.TotalSilence = roundTo(r.TotalSilence, precision)
+	if r.Longest != nil {
+		longest := roundIntervals([]Interval{*r.Longest}, precision)[0]
+		r.Longest = &longest
+	}
+	if r.Intervals != nil {
+		r.Intervals = roundIntervals(r.Intervals, precision)
+	}
+	for i := range r.Channels {
+		r.Channels[i].Intervals = roundIntervals(r.Channels[i].Intervals, precision)
+	}
+	for _, section := range []*IntervalsReport{r.Black, r.DeadAir, r.Freeze} {
+		if section != nil {
+			section.Total = roundTo(section.Total, precision)
+			section.Intervals = roundIntervals(section.Intervals, precision)
+		}
+	}
+	if r.Samples != nil {
+		samples := make([]EnergySample, len(r.Samples))
+		for i, sample := range r.Samples {
+			sample.Time = roundTo(sample.Time, precision)
+			samples[i] = sample
+		}
+		r.Samples = samples
+	}
+	for i := range r.Histogram {
+		r.Histogram[i].TotalDuration = roundTo(r.Histogram[i].TotalDuration, precision)
+	}
+}
+
+func roundPointer(v *float64, precision int) *float64 {
+	if v == nil {
+		return nil
+	}
+	rounded := roundTo(*v, precision)
+	return &rounded
+}
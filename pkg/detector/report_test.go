@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntervalJSONFieldNames(t *testing.T) {
+	data, err := json.Marshal(Interval{Start: 1, End: 3, Duration: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"start":1,"end":3,"duration":2}` {
+		t.Fatalf("unexpected interval JSON %s", data)
+	}
+}
+
+func TestNewReportSections(t *testing.T) {
+	peak := -1.5
+	floor := math.Inf(-1)
+	result := DetectionResult{
+		InputDuration: 10,
+		Intervals:     []Interval{{Start: 0, End: 2, Duration: 2}, {Start: 6, End: 10, Duration: 4}},
+		ThresholdDB:   -60,
+		NoiseFloorDB:  &floor,
+		Loudness:      &Loudness{IntegratedLUFS: -23, RangeLU: 4, TruePeakDBFS: &peak},
+		ChannelIntervals: map[int][]Interval{
+			0: {{Start: 0, End: 10, Duration: 10}},
+			1: {{Start: 0, End: 2, Duration: 2}},
+		},
+	}
+	options := DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+		PerChannel:         true,
+		MeasureLoudness:    true,
+		AdaptiveThreshold:  true,
+	}
+
+	report := NewReport("a.wav", options, result, ReportOptions{FullSilence: true, LeadingSilence: true, Tolerance: 1e-3})
+
+	if report.SchemaVersion != ReportSchemaVersion || report.Input != "a.wav" || report.IntervalCount != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.NoiseDB != -60 || report.NoiseFloorDB != nil {
+		t.Errorf("expected the adaptive threshold without an infinite floor, got %g and %v", report.NoiseDB, report.NoiseFloorDB)
+	}
+	if report.Longest == nil || *report.Longest != result.Intervals[1] {
+		t.Errorf("expected the longest interval, got %v", report.Longest)
+	}
+	if report.FullySilent == nil || *report.FullySilent || report.Leading == nil || *report.Leading != 2 || report.Trailing != nil {
+		t.Errorf("unexpected verdicts %+v", report)
+	}
+	if report.Loudness != result.Loudness || report.MeanVolumeDB != nil {
+		t.Errorf("expected only the loudness section, got %+v", report)
+	}
+	if len(report.Channels) != 2 || !*report.Channels[0].FullySilent || *report.Channels[1].FullySilent {
+		t.Errorf("unexpected channels %+v", report.Channels)
+	}
+
+	empty := NewReport("b.wav", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 1}, DetectionResult{}, ReportOptions{})
+	data, err := json.Marshal(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"intervals":[]`) || strings.Contains(string(data), "longest_interval") {
+		t.Errorf("expected an empty interval list and no longest interval, got %s", data)
+	}
+
+	ratio := 0.001
+	byRatio := NewReport("c.wav", DetectionOptions{NoiseRatio: &ratio, MinSilenceDuration: 1}, DetectionResult{}, ReportOptions{})
+	if byRatio.NoiseRatio != &ratio || math.Abs(byRatio.NoiseDB+60) > 1e-9 {
+		t.Errorf("expected the ratio and its dB value, got %v and %g", byRatio.NoiseRatio, byRatio.NoiseDB)
+	}
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	peak := -1.5
+	volume := -20.0
+	fullySilent := false
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		Input:         "a.mp4",
+		CheckedAt:     "2024-05-01T12:00:00Z",
+		NoiseDB:       -30,
+		MinDuration:   0.5,
+		Duration:      10,
+		WindowStart:   1,
+		FullySilent:   &fullySilent,
+		MeanVolumeDB:  &volume,
+		Loudness:      &Loudness{IntegratedLUFS: -23, RangeLU: 4, TruePeakDBFS: &peak},
+		IntervalCount: 1,
+		TotalSilence:  2,
+		SilenceRatio:  0.2,
+		Longest:       &Interval{Start: 1, End: 3, Duration: 2},
+		Intervals:     []Interval{{Start: 1, End: 3, Duration: 2}},
+		Channels:      []ChannelReport{{Channel: 0, FullySilent: &fullySilent, Intervals: []Interval{{Start: 1, End: 3, Duration: 2}}}},
+		Black:         NewIntervalsReport([]Interval{{Start: 2, End: 3, Duration: 1}}),
+		DeadAir:       NewIntervalsReport([]Interval{{Start: 2, End: 3, Duration: 1}}),
+		Freeze:        NewIntervalsReport(nil),
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, report) {
+		t.Fatalf("report changed in a round trip:\n got %+v\nwant %+v\nJSON %s", decoded, *report, data)
+	}
+}
+
+func TestReportRound(t *testing.T) {
+	leading := 0.30000000000000004
+	report := NewReport("a.mp4", DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.1}, DetectionResult{
+		InputDuration: 10,
+		Intervals:     []Interval{{Start: 6.1, End: 9.2, Duration: 3.0999999999999996}, {Start: 0, End: 0.30000000000000004, Duration: 0.30000000000000004}},
+	}, ReportOptions{FullSilence: true})
+	report.Leading = &leading
+	report.Black = NewIntervalsReport([]Interval{{Start: 0.1, End: 0.2, Duration: 0.1}, {Start: 0.3, End: 0.5, Duration: 0.2}})
+
+	report.Round(3)
+
+	if report.TotalSilence != 3.4 || *report.Leading != 0.3 || report.Longest.Duration != 3.1 {
+		t.Errorf("unexpected totals %v, %v and %v", report.TotalSilence, *report.Leading, *report.Longest)
+	}
+	if report.Intervals[0] != (Interval{Start: 0, End: 0.3, Duration: 0.3}) || report.Intervals[1].Duration != 3.1 {
+		t.Errorf("expected sorted, rounded intervals, got %v", report.Intervals)
+	}
+	if report.Black.Total != 0.3 {
+		t.Errorf("expected the black total to be rounded, got %v", report.Black.Total)
+	}
+}
@@ -0,0 +1,502 @@
+package detector
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultS3Region is used when the bucket region cannot be determined.
+const defaultS3Region = "us-east-1"
+
+// defaultS3Expiry is the lifetime of presigned URLs when S3Options.Expires is zero.
+const defaultS3Expiry = time.Hour
+
+// maxS3Expiry is the longest lifetime S3 accepts for a presigned URL.
+const maxS3Expiry = 7 * 24 * time.Hour
+
+const (
+	// defaultIMDSEndpoint is the EC2 instance metadata service, overridable with AWS_EC2_METADATA_SERVICE_ENDPOINT.
+	defaultIMDSEndpoint = "http://169.254.169.254"
+	// ecsCredentialsHost serves the credentials at AWS_CONTAINER_CREDENTIALS_RELATIVE_URI in ECS tasks.
+	ecsCredentialsHost = "http://169.254.170.2"
+)
+
+// ErrS3CredentialsNotFound is returned when no AWS credentials are available to presign an S3 URL.
+var ErrS3CredentialsNotFound = errors.New("AWS credentials not found")
+
+// S3Credentials holds the AWS access key used to sign S3 requests.
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+}
+
+// S3Options configures PresignS3URL.
+type S3Options struct {
+	// Region is the bucket region. When empty, AWS_REGION and AWS_DEFAULT_REGION are consulted and, for AWS
+	// endpoints, the region is discovered from the bucket itself.
+	Region string
+	// Endpoint is the base URL of an S3-compatible store such as MinIO (for example http://localhost:9000).
+	// Objects on a custom endpoint are addressed path-style. When empty, AWS endpoints are used.
+	Endpoint string
+	// Credentials signs the URL. When nil, credentials are loaded with LoadS3CredentialsContext, using Client.
+	Credentials *S3Credentials
+	// Expires is the lifetime of the presigned URL. Zero uses one hour; S3 caps it at seven days.
+	Expires time.Duration
+	// Client is used for bucket region discovery and credential endpoints. When nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// S3Location identifies an object in an s3://bucket/key URL.
+type S3Location struct {
+	Bucket string
+	Key    string
+}
+
+// ParseS3URL splits an s3://bucket/key URL into its bucket and key.
+func ParseS3URL(raw string) (S3Location, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return S3Location{}, fmt.Errorf("invalid S3 URL: %w", err)
+	}
+	if !strings.EqualFold(parsed.Scheme, "s3") {
+		return S3Location{}, fmt.Errorf("invalid S3 URL %q: scheme must be s3", raw)
+	}
+	if parsed.Host == "" {
+		return S3Location{}, fmt.Errorf("invalid S3 URL %q: missing bucket", raw)
+	}
+
+	key := strings.TrimPrefix(parsed.Path, "/")
+	if key == "" {
+		return S3Location{}, fmt.Errorf("invalid S3 URL %q: missing object key", raw)
+	}
+
+	return S3Location{Bucket: parsed.Host, Key: key}, nil
+}
+
+// LoadS3Credentials reads AWS credentials with the default credential chain. See LoadS3CredentialsContext.
+func LoadS3Credentials() (S3Credentials, error) {
+	return LoadS3CredentialsContext(context.Background(), nil)
+}
+
+// LoadS3CredentialsContext reads AWS credentials with the default credential chain, using client, or
+// http.DefaultClient when nil, for the credential endpoints.
+//
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN take precedence; otherwise the profile named by
+// AWS_PROFILE (or "default") is read from the shared credentials file at AWS_SHARED_CREDENTIALS_FILE or
+// ~/.aws/credentials. Without either, the role credentials of an ECS task or EKS pod are fetched from the container
+// credentials endpoint named by AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or AWS_CONTAINER_CREDENTIALS_FULL_URI, and
+// finally those of an EC2 instance from the instance metadata service (IMDSv2) unless AWS_EC2_METADATA_DISABLED is
+// true. SSO credentials are not supported.
+func LoadS3CredentialsContext(ctx context.Context, client *http.Client) (S3Credentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return S3Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".aws", "credentials")
+		}
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	fileErr := errors.New("no home directory")
+	if path != "" {
+		var creds S3Credentials
+		if creds, fileErr = readSharedCredentials(path, profile); fileErr == nil {
+			return creds, nil
+		}
+	}
+
+	if relative, full := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"), os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); relative != "" || full != "" {
+		creds, err := containerCredentials(ctx, client, relative, full)
+		if err != nil {
+			return S3Credentials{}, fmt.Errorf("%w: container credentials endpoint: %v", ErrS3CredentialsNotFound, err)
+		}
+		return creds, nil
+	}
+
+	metadataErr := errors.New("disabled by AWS_EC2_METADATA_DISABLED")
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		var creds S3Credentials
+		if creds, metadataErr = instanceCredentials(ctx, client); metadataErr == nil {
+			return creds, nil
+		}
+	}
+	return S3Credentials{}, fmt.Errorf("%w: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, configure profile %q in %s, or run with an instance or task role (%v; instance metadata: %v)",
+		ErrS3CredentialsNotFound, profile, path, fileErr, metadataErr)
+}
+
+// readSharedCredentials extracts a profile from an AWS shared credentials file.
+func readSharedCredentials(path, profile string) (S3Credentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+	defer file.Close()
+
+	var (
+		creds   S3Credentials
+		section string
+	)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return S3Credentials{}, err
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return S3Credentials{}, fmt.Errorf("profile %q has no access key", profile)
+	}
+	return creds, nil
+}
+
+// containerCredentials fetches the role credentials of an ECS task, from the relative URI on the ECS agent, or of
+// an EKS pod, from the full URI, sending the authorization token the environment provides.
+func containerCredentials(ctx context.Context, client *http.Client, relative, full string) (S3Credentials, error) {
+	endpoint := full
+	if relative != "" {
+		endpoint = ecsCredentialsHost + relative
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return S3Credentials{}, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return requestS3Credentials(client, req)
+}
+
+// instanceCredentials fetches the role credentials of an EC2 instance from the instance metadata service, with an
+// IMDSv2 session token.
+func instanceCredentials(ctx context.Context, client *http.Client) (S3Credentials, error) {
+	endpoint := strings.TrimSuffix(firstNonEmpty(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), defaultIMDSEndpoint), "/")
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	get := func(path string, token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err == nil {
+			req.Header.Set("X-aws-ec2-metadata-token", token)
+		}
+		return req, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := readMetadata(client, req)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+
+	if req, err = get("/latest/meta-data/iam/security-credentials/", token); err != nil {
+		return S3Credentials{}, err
+	}
+	roles, err := readMetadata(client, req)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if role == "" {
+		return S3Credentials{}, errors.New("the instance has no IAM role")
+	}
+
+	if req, err = get("/latest/meta-data/iam/security-credentials/"+url.PathEscape(role), token); err != nil {
+		return S3Credentials{}, err
+	}
+	return requestS3Credentials(client, req)
+}
+
+// readMetadata performs a metadata request and returns the response body.
+func readMetadata(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request to %s failed: %s", req.URL.Host, resp.Status)
+	}
+	return string(body), nil
+}
+
+// requestS3Credentials performs a credentials request to a container or instance metadata endpoint and extracts the
+// temporary credentials.
+func requestS3Credentials(client *http.Client, req *http.Request) (S3Credentials, error) {
+	body, err := readMetadata(client, req)
+	if err != nil {
+		return S3Credentials{}, err
+	}
+
+	var creds struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := json.Unmarshal([]byte(body), &creds); err != nil {
+		return S3Credentials{}, fmt.Errorf("parse credentials response: %w", err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return S3Credentials{}, errors.New("credentials response did not include an access key")
+	}
+	return S3Credentials{AccessKeyID: creds.AccessKeyID, SecretAccessKey: creds.SecretAccessKey, SessionToken: creds.Token}, nil
+}
+
+// PresignS3URL converts an s3://bucket/key URL into a time-limited HTTPS URL that can be fetched without further
+// authentication, using AWS Signature Version 4 query signing.
+func PresignS3URL(ctx context.Context, rawURL string, options S3Options) (string, error) {
+	location, err := ParseS3URL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	expires := options.Expires
+	if expires == 0 {
+		expires = defaultS3Expiry
+	}
+	if expires < time.Second || expires > maxS3Expiry {
+		return "", fmt.Errorf("presigned URL lifetime must be between 1s and %s", maxS3Expiry)
+	}
+
+	creds := options.Credentials
+	if creds == nil {
+		loaded, err := LoadS3CredentialsContext(ctx, options.Client)
+		if err != nil {
+			return "", err
+		}
+		creds = &loaded
+	}
+
+	region := options.Region
+	if region == "" {
+		region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	}
+	if region == "" && options.Endpoint == "" {
+		region = discoverBucketRegion(ctx, options.Client, location.Bucket)
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	objectURL, err := s3ObjectURL(location, region, options.Endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	return presignS3(objectURL, *creds, region, time.Now(), expires), nil
+}
+
+// s3ObjectURL returns the unsigned HTTPS URL of an object.
+//
+// Custom endpoints and bucket names containing dots use path-style addressing; other AWS buckets use
+// virtual-hosted-style addressing.
+func s3ObjectURL(location S3Location, region, endpoint string) (*url.URL, error) {
+	if endpoint != "" {
+		base, err := url.Parse(endpoint)
+		if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+			return nil, fmt.Errorf("invalid S3 endpoint %q: must be an http or https URL", endpoint)
+		}
+		return &url.URL{
+			Scheme: base.Scheme,
+			Host:   base.Host,
+			Path:   strings.TrimSuffix(base.Path, "/") + "/" + location.Bucket + "/" + location.Key,
+		}, nil
+	}
+
+	host := "s3." + region + ".amazonaws.com"
+	if region == defaultS3Region {
+		host = "s3.amazonaws.com"
+	}
+
+	if strings.Contains(location.Bucket, ".") {
+		return &url.URL{Scheme: "https", Host: host, Path: "/" + location.Bucket + "/" + location.Key}, nil
+	}
+	return &url.URL{Scheme: "https", Host: location.Bucket + "." + host, Path: "/" + location.Key}, nil
+}
+
+// presignS3 signs a GET request for objectURL with Signature Version 4 query parameters.
+func presignS3(objectURL *url.URL, creds S3Credentials, region string, now time.Time, expires time.Duration) string {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + region + "/s3/aws4_request"
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       fmt.Sprint(int64(expires / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		query["X-Amz-Security-Token"] = creds.SessionToken
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(query[name], true))
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+	canonicalPath := s3Escape(objectURL.Path, false)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalPath,
+		canonicalQuery,
+		"host:" + objectURL.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return objectURL.Scheme + "://" + objectURL.Host + canonicalPath + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// discoverBucketRegion asks S3 which region hosts the bucket. It returns "" when the region cannot be determined.
+func discoverBucketRegion(ctx context.Context, client *http.Client, bucket string) string {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	// S3 reports the region on redirects and access-denied responses too, so do not follow them.
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://s3.amazonaws.com/"+url.PathEscape(bucket), nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("X-Amz-Bucket-Region")
+}
+
+// s3Escape percent-encodes every byte except the unreserved characters, as Signature Version 4 requires. Slashes
+// are kept when encoding a path.
+func s3Escape(value string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
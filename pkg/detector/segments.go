@@ -0,0 +1,67 @@
+package detector
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Segment is a non-silent range of the input to keep when the silence is removed.
+type Segment struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+}
+
+// KeepSegments returns the non-silent ranges of the analysed span, in order, each widened by padding seconds on both
+// sides so that cuts do not clip the start and end of speech. Widened ranges are clamped to the span and merged
+// where they meet; a negative padding is treated as zero. A fully silent span has no segments. As with
+// NonSilentIntervals, the segments end at the last silence when InputDuration is unknown.
+func (r DetectionResult) KeepSegments(padding float64) []Segment {
+	padding = math.Max(padding, 0)
+	start := r.WindowStart
+	end := math.Inf(1)
+	if r.InputDuration > 0 {
+		end = r.WindowStart + r.InputDuration
+	}
+
+	kept := r.NonSilentIntervals()
+	for i, region := range kept {
+		region.Start = math.Max(region.Start-padding, start)
+		region.End = math.Min(region.End+padding, end)
+		kept[i] = region
+	}
+
+	var segments []Segment
+	for _, region := range MergeIntervals(kept, 0) {
+		segments = append(segments, Segment{Start: region.Start, End: region.End, Duration: region.End - region.Start})
+	}
+	return segments
+}
+
+// DropShortSegments returns the segments lasting at least minDuration seconds, such as to leave out brief noises
+// between two silences.
+func DropShortSegments(segments []Segment, minDuration float64) []Segment {
+	var kept []Segment
+	for _, segment := range segments {
+		if segment.Duration >= minDuration {
+			kept = append(kept, segment)
+		}
+	}
+	return kept
+}
+
+// WriteConcatList writes an ffmpeg concat demuxer script that plays the segments of input in order, for example
+// with ffmpeg -f concat -safe 0 -i list.ffconcat -c copy out.wav. ffmpeg resolves a relative input against the
+// directory of the script, so input is usually an absolute path or a URL.
+func WriteConcatList(w io.Writer, input string, segments []Segment) error {
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	file := "'" + strings.ReplaceAll(input, "'", `'\''`) + "'"
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "file %s\ninpoint %s\noutpoint %s\n", file, filterSeconds(segment.Start), filterSeconds(segment.End))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
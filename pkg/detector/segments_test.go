@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestKeepSegments(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 20,
+		Intervals: []SilenceInterval{
+			{Start: 0, End: 2.5, Duration: 2.5},
+			{Start: 7, End: 7.4, Duration: 0.4},
+			{Start: 12.25, End: 20, Duration: 7.75},
+		},
+	}
+
+	want := []Segment{{Start: 2.5, End: 7, Duration: 4.5}, {Start: 7.4, End: 12.25, Duration: 4.85}}
+	if got := result.KeepSegments(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected segments %+v, want %+v", got, want)
+	}
+	want = []Segment{{Start: 2.25, End: 12.5, Duration: 10.25}}
+	if got := result.KeepSegments(0.25); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected padded segments to merge, got %+v", got)
+	}
+
+	if got := (DetectionResult{InputDuration: 5}).KeepSegments(1); !reflect.DeepEqual(got, []Segment{{Start: 0, End: 5, Duration: 5}}) {
+		t.Errorf("expected the whole input without silence, got %+v", got)
+	}
+	silent := DetectionResult{InputDuration: 5, Intervals: []SilenceInterval{{Start: 0, End: 5, Duration: 5}}}
+	if got := silent.KeepSegments(1); len(got) != 0 {
+		t.Errorf("expected no segments for a silent input, got %+v", got)
+	}
+}
+
+func TestDropShortSegments(t *testing.T) {
+	segments := []Segment{{Start: 0, End: 4, Duration: 4}, {Start: 5, End: 5.2, Duration: 0.2}, {Start: 6, End: 7, Duration: 1}}
+	want := []Segment{{Start: 0, End: 4, Duration: 4}, {Start: 6, End: 7, Duration: 1}}
+	if got := DropShortSegments(segments, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected segments %+v, want %+v", got, want)
+	}
+}
+
+func TestSegmentJSON(t *testing.T) {
+	data, err := json.Marshal(Segment{Start: 1, End: 3.5, Duration: 2.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"start":1,"end":3.5,"duration":2.5}` {
+		t.Errorf("unexpected segment JSON %s", data)
+	}
+}
+
+func TestWriteConcatList(t *testing.T) {
+	var buf bytes.Buffer
+	segments := []Segment{{Start: 2.5, End: 7, Duration: 4.5}, {Start: 7.4, End: 12.250000001, Duration: 4.85}}
+	if err := WriteConcatList(&buf, "/media/Bob's show.wav", segments); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "segments.ffconcat", buf.Bytes())
+}
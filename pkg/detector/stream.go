@@ -0,0 +1,139 @@
+package detector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// StreamingRunner starts an external command and returns a reader over its combined output together with a wait
+// function.
+//
+// The caller reads the output until EOF (or closes the reader to stop early) and then calls wait, which blocks
+// until the command exits and reports its error, if any.
+type StreamingRunner func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error)
+
+// maxOutputLineLength bounds the size of a single output line the streaming parser will buffer.
+const maxOutputLineLength = 1 << 20
+
+// outputTailLines is the number of trailing output lines retained for error messages in streaming mode.
+const outputTailLines = 20
+
+// streamSilence executes ffmpeg through the StreamingRunner and parses its output as it arrives.
+func (d *Detector) streamSilence(ctx context.Context, args []string, parser *silenceParser) (DetectionResult, error) {
+	reader, wait, err := d.stream(ctx, d.ffmpegPath, args...)
+	if err != nil {
+		return DetectionResult{}, newFFmpegError(ctx, d.ffmpegPath, args, err, "")
+	}
+
+	tail, parseErr, scanErr := scanSilenceOutput(reader, parser)
+
+	reader.Close()
+	waitErr := wait()
+
+	if waitErr != nil && !parser.stopped {
+		return DetectionResult{}, newFFmpegError(ctx, d.ffmpegPath, args, waitErr, strings.Join(tail, "\n"))
+	}
+	if parseErr != nil {
+		return DetectionResult{}, parseErr
+	}
+	if scanErr != nil {
+		return DetectionResult{}, fmt.Errorf("read ffmpeg output: %w", scanErr)
+	}
+	if err := parser.checkRecognized(); err != nil {
+		return DetectionResult{}, err
+	}
+
+	return parser.finish(), nil
+}
+
+// scanSilenceOutput feeds ffmpeg output from reader to parser line by line, stopping at the first parse error or when
+// parser.stop asks to. It returns the last few non-empty lines for error messages along with any parse and read
+// errors.
+func scanSilenceOutput(reader io.Reader, parser *silenceParser) (tail []string, parseErr, scanErr error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputLineLength)
+	scanner.Split(scanOutputLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) != "" {
+			if len(tail) == outputTailLines {
+				tail = tail[1:]
+			}
+			tail = append(tail, line)
+		}
+
+		if err := parser.parseLine(line); err != nil {
+			parseErr = err
+			break
+		}
+		if parser.stop != nil && parser.stop() {
+			parser.stopped = true
+			break
+		}
+	}
+	return tail, parseErr, scanner.Err()
+}
+
+// scanOutputLines is a bufio.SplitFunc that splits on "\n", "\r\n", and bare "\r", which ffmpeg uses to overwrite
+// progress lines in place.
+func scanOutputLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
+				}
+				return i + 1, data[:i], nil
+			}
+			if !atEOF {
+				// Wait for more data to find out whether this is a CRLF pair.
+				return 0, nil, nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+func defaultStreamingRunner(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	pr, pw := io.Pipe()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = CommandStdin(ctx)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		pr.Close()
+		return nil, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		done <- err
+	}()
+
+	wait := func() error {
+		return <-done
+	}
+
+	return pr, wait, nil
+}
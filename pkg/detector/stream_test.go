@@ -0,0 +1,156 @@
+package detector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const streamFixture = "[silencedetect @ 0x123] silence_start: 0.000000\r\n" +
+	"[silencedetect @ 0x123] silence_end: 3.500000 | silence_duration: 3.500000\r\n" +
+	"frame=   50 fps=0.0 q=-0.0 size=       0kB time=00:00:05.00 bitrate=   0.0kbits/s speed=1x\r" +
+	"frame=  100 fps=0.0 q=-0.0 size=       0kB time=00:00:09.00 bitrate=   0.0kbits/s speed=1x\r" +
+	"[silencedetect @ 0x123] silence_start: 10.000000\n" +
+	"[silencedetect @ 0x123] silence_end: 12.000000 | silence_duration: 2.000000\n" +
+	"[silencedetect @ 0x123] silence_start: 14.000000\n" +
+	"frame=  150 fps=0.0 q=-0.0 size=       0kB time=00:00:15.00 bitrate=   0.0kbits/s speed=1x"
+
+func fakeStreamingRunner(output string, waitErr error) StreamingRunner {
+	return func(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+		reader := io.NopCloser(iotest.OneByteReader(strings.NewReader(output)))
+		return reader, func() error { return waitErr }, nil
+	}
+}
+
+func TestStreamingParserMatchesBufferedParser(t *testing.T) {
+	options := DetectionOptions{NoiseLevel: -30, MinSilenceDuration: 0.5}
+
+	streaming := NewDetector(WithStreamingRunner(fakeStreamingRunner(streamFixture, nil)))
+	streamed, err := streaming.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("streaming DetectSilence returned error: %v", err)
+	}
+
+	bufferedOutput := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(streamFixture)
+	buffered := NewDetector(WithCommandRunner(func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(bufferedOutput), nil
+	}))
+	expected, err := buffered.DetectSilence(context.Background(), "video.mp4", options)
+	if err != nil {
+		t.Fatalf("buffered DetectSilence returned error: %v", err)
+	}
+
+	assertFloatEqual(t, streamed.InputDuration, expected.InputDuration)
+	assertFloatEqual(t, streamed.InputDuration, 15)
+	assertIntervals(t, streamed.Intervals, expected.Intervals)
+	assertIntervals(t, streamed.Intervals, []SilenceInterval{
+		{Start: 0, End: 3.5, Duration: 3.5},
+		{Start: 10, End: 12, Duration: 2},
+		{Start: 14, End: 15, Duration: 1},
+	})
+}
+
+func TestStreamingRunnerErrorIncludesOutputTail(t *testing.T) {
+	runner := fakeStreamingRunner("first line\nvideo.mp4: No such file or directory\n", errors.New("exit status 1"))
+
+	d := NewDetector(WithStreamingRunner(runner))
+	_, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{
+		NoiseLevel:         -30,
+		MinSilenceDuration: 1,
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "ffmpeg execution failed") {
+		t.Fatalf("expected wrapped error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "No such file or directory") {
+		t.Fatalf("expected error to include ffmpeg output, got %v", err)
+	}
+}
+
+func TestWithCommandRunnerDisablesStreaming(t *testing.T) {
+	var called bool
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		called = true
+		return []byte(statsOutput), nil
+	}
+
+	d := NewDetector(WithCommandRunner(runner))
+	if _, err := d.DetectSilence(context.Background(), "video.mp4", DetectionOptions{MinSilenceDuration: 1}); err != nil {
+		t.Fatalf("DetectSilence returned error: %v", err)
+	}
+	if !called {
+		t.Fatalf("expected command runner to be used")
+	}
+}
+
+func TestScanOutputLines(t *testing.T) {
+	input := "one\r\ntwo\rthree\nfour"
+
+	scanner := bufio.NewScanner(iotest.HalfReader(strings.NewReader(input)))
+	scanner.Split(scanOutputLines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanner returned error: %v", err)
+	}
+
+	expected := []string{"one", "two", "three", "four"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected lines: got %q want %q", lines, expected)
+	}
+}
+
+func TestDefaultStreamingRunnerCombinesOutput(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	reader, wait, err := defaultStreamingRunner(context.Background(), "sh", "-c", `printf 'out\n'; printf 'err\n' >&2`)
+	if err != nil {
+		t.Fatalf("defaultStreamingRunner returned error: %v", err)
+	}
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if err := wait(); err != nil {
+		t.Fatalf("wait returned error: %v", err)
+	}
+
+	if !strings.Contains(string(output), "out") || !strings.Contains(string(output), "err") {
+		t.Fatalf("expected combined stdout and stderr, got %q", output)
+	}
+}
+
+func TestDefaultStreamingRunnerConnectsStdin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	ctx := context.WithValue(context.Background(), stdinKey{}, strings.NewReader("piped media"))
+	reader, wait, err := defaultStreamingRunner(ctx, "sh", "-c", "cat")
+	if err != nil {
+		t.Fatalf("defaultStreamingRunner returned error: %v", err)
+	}
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if err := wait(); err != nil {
+		t.Fatalf("wait returned error: %v", err)
+	}
+
+	if string(output) != "piped media" {
+		t.Fatalf("expected stdin to be echoed, got %q", output)
+	}
+}
@@ -0,0 +1,78 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// defaultCueText is the text of subtitle cues when SubtitleOptions.CueText is empty.
+const defaultCueText = "SILENCE"
+
+// SubtitleOptions configures WriteSRT and WriteVTT.
+type SubtitleOptions struct {
+	// CueText is the text shown during each interval. {index} is replaced by the cue number, from 1, and {duration}
+	// by the interval's duration in seconds, such as 2.5. "SILENCE" is used when it is empty.
+	CueText string
+}
+
+// WriteSRT writes intervals as SubRip subtitles, one numbered cue per interval in start order, with HH:MM:SS,mmm
+// timestamps. Measured levels are added as a last line of the cue. Intervals shorter than a millisecond are left out.
+// It returns an error without writing anything if the cue text is invalid: it must not contain "-->" or a blank
+// line, which would end the cue.
+func WriteSRT(w io.Writer, intervals []SilenceInterval, opts SubtitleOptions) error {
+	return writeSubtitles(w, "", ",", intervals, opts)
+}
+
+// WriteVTT is like WriteSRT but writes a WebVTT file, whose timestamps separate the milliseconds with a dot.
+func WriteVTT(w io.Writer, intervals []SilenceInterval, opts SubtitleOptions) error {
+	return writeSubtitles(w, "WEBVTT\n\n", ".", intervals, opts)
+}
+
+// writeSubtitles writes header followed by a cue per interval, with separator before the milliseconds.
+func writeSubtitles(w io.Writer, header, separator string, intervals []SilenceInterval, opts SubtitleOptions) error {
+	text := opts.CueText
+	if text == "" {
+		text = defaultCueText
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.Contains(text, "-->") {
+		return errors.New(`subtitle cue text must not contain "-->"`)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			return errors.New("subtitle cue text must not be blank or contain a blank line")
+		}
+	}
+
+	timestamp := func(millis int64) string {
+		return strings.Replace(FormatClock(float64(millis)/1000), ".", separator, 1)
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	cue := 0
+	for _, interval := range sortedIntervals(intervals) {
+		start := int64(math.Round(math.Max(interval.Start, 0) * 1000))
+		end := int64(math.Round(math.Max(interval.End, 0) * 1000))
+		if end <= start {
+			continue
+		}
+		cue++
+		replacer := strings.NewReplacer(
+			"{index}", strconv.Itoa(cue),
+			"{duration}", strconv.FormatFloat(float64(end-start)/1000, 'f', -1, 64),
+		)
+		cueText := replacer.Replace(text)
+		if levels := levelSummary(interval); levels != "" {
+			cueText += "\n" + levels
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", cue, timestamp(start), timestamp(end), cueText)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
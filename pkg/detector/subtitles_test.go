@@ -0,0 +1,101 @@
+package detector
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var subtitleIntervals = []SilenceInterval{
+	{Start: 3723.5, End: 3725.0004, Duration: 1.5004},
+	{Start: 0, End: 2.5, Duration: 2.5},
+	// Shorter than a millisecond, so it is left out.
+	{Start: 10, End: 10.0004, Duration: 0.0004},
+	{Start: 59.9996, End: 61.25, Duration: 1.2504},
+}
+
+func TestWriteSRT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSRT(&buf, subtitleIntervals, SubtitleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "silence.srt", buf.Bytes())
+}
+
+func TestWriteVTT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVTT(&buf, subtitleIntervals, SubtitleOptions{CueText: "SILENCE #{index}\n{duration}s"}); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "silence.vtt", buf.Bytes())
+
+	cues, err := parseVTT(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cues) != 3 || cues[1].text != "SILENCE #2\n1.25s" || cues[2].start != 3723.5 || cues[2].end != 3725 {
+		t.Errorf("unexpected cues %+v", cues)
+	}
+}
+
+func TestWriteSubtitlesRejectsInvalidCueText(t *testing.T) {
+	for _, text := range []string{"a --> b", " ", "first\n\nsecond", "first\r\n\r\nsecond"} {
+		var buf bytes.Buffer
+		if err := WriteVTT(&buf, subtitleIntervals, SubtitleOptions{CueText: text}); err == nil || buf.Len() != 0 {
+			t.Errorf("expected cue text %q to be rejected, got %v and %q", text, err, buf.String())
+		}
+	}
+}
+
+// vttCue is a cue read back by parseVTT.
+type vttCue struct {
+	id         string
+	start, end float64
+	text       string
+}
+
+var vttTiming = regexp.MustCompile(`^(\d{2,}):([0-5]\d):([0-5]\d)\.(\d{3}) --> (\d{2,}):([0-5]\d):([0-5]\d)\.(\d{3})$`)
+
+// parseVTT parses a WebVTT file made only of cues, enforcing the rules of the WebVTT specification that the writer
+// must follow: the WEBVTT signature, cue blocks separated by blank lines, well-formed timings whose end follows the
+// start, cue start times in order, and cue text that does not contain "-->".
+func parseVTT(data string) ([]vttCue, error) {
+	body, ok := strings.CutPrefix(data, "WEBVTT\n\n")
+	if !ok {
+		return nil, fmt.Errorf("missing WEBVTT signature")
+	}
+
+	var cues []vttCue
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		lines := strings.Split(block, "\n")
+		if len(lines) < 3 {
+			return nil, fmt.Errorf("cue %q needs an identifier, a timing and text", block)
+		}
+		match := vttTiming.FindStringSubmatch(lines[1])
+		if match == nil {
+			return nil, fmt.Errorf("malformed cue timing %q", lines[1])
+		}
+		var parts [8]float64
+		for i := range parts {
+			fmt.Sscan(match[i+1], &parts[i])
+		}
+		cue := vttCue{
+			id:    lines[0],
+			start: parts[0]*3600 + parts[1]*60 + parts[2] + parts[3]/1000,
+			end:   parts[4]*3600 + parts[5]*60 + parts[6] + parts[7]/1000,
+			text:  strings.Join(lines[2:], "\n"),
+		}
+		switch {
+		case strings.Contains(cue.id, "-->") || strings.Contains(cue.text, "-->"):
+			return nil, fmt.Errorf("cue %q contains -->", block)
+		case cue.end <= cue.start:
+			return nil, fmt.Errorf("cue %q ends before it starts", block)
+		case len(cues) > 0 && cue.start < cues[len(cues)-1].start:
+			return nil, fmt.Errorf("cue %q is out of order", block)
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
@@ -0,0 +1,47 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DetectSilenceSweep runs DetectSilence once for each entry of sweep, typically the same options with different
+// NoiseLevel values, and returns the results keyed by NoiseLevel. It is meant for tuning the threshold of an input.
+//
+// Every entry is validated before ffmpeg runs, and the thresholds must be distinct. Each threshold gets its own
+// ffmpeg run: silencedetect instances in a single filter graph log under the same name, so their output could not
+// be attributed to a threshold reliably. Since the input is read several times, it must be seekable, and adaptive
+// thresholds are not supported. The first failing run stops the sweep.
+func (d *Detector) DetectSilenceSweep(ctx context.Context, inputPath string, sweep []DetectionOptions) (map[float64]DetectionResult, error) {
+	if len(sweep) == 0 {
+		return nil, errors.New("sweep requires at least one set of detection options")
+	}
+	if isPipeInput(inputPath) {
+		return nil, errors.New("a sweep reads the input several times and needs a seekable input, which stdin is not")
+	}
+
+	for i, options := range sweep {
+		if options.AdaptiveThreshold {
+			return nil, errors.New("a sweep compares fixed thresholds and cannot use AdaptiveThreshold")
+		}
+		for _, previous := range sweep[:i] {
+			if previous.NoiseLevel == options.NoiseLevel {
+				return nil, fmt.Errorf("threshold %gdB appears more than once in the sweep", options.NoiseLevel)
+			}
+		}
+		if _, err := prepareInput(inputPath, options); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make(map[float64]DetectionResult, len(sweep))
+	for _, options := range sweep {
+		result, err := d.DetectSilence(ctx, inputPath, options)
+		if err != nil {
+			return nil, fmt.Errorf("threshold %gdB: %w", options.NoiseLevel, err)
+		}
+		results[options.NoiseLevel] = result
+	}
+	return results, nil
+}
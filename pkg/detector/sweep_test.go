@@ -0,0 +1,94 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDetectSilenceSweep(t *testing.T) {
+	var filters []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		filter := args[len(args)-4]
+		filters = append(filters, filter)
+		output := "size=N/A time=00:00:10.00 bitrate=N/A speed=1x\n"
+		if strings.Contains(filter, "noise=-20dB") {
+			output = "[silencedetect @ 0x1] silence_start: 1\n[silencedetect @ 0x1] silence_end: 3 | silence_duration: 2\n" + output
+		}
+		return []byte(output), nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	base := DetectionOptions{MinSilenceDuration: 0.5}
+	var sweep []DetectionOptions
+	for _, level := range []float64{-20, -30, -40} {
+		options := base
+		options.NoiseLevel = level
+		sweep = append(sweep, options)
+	}
+
+	results, err := d.DetectSilenceSweep(context.Background(), "video.mp4", sweep)
+	if err != nil {
+		t.Fatalf("DetectSilenceSweep returned error: %v", err)
+	}
+
+	if len(filters) != 3 || filters[0] != "silencedetect=noise=-20dB:d=0.5" || filters[2] != "silencedetect=noise=-40dB:d=0.5" {
+		t.Fatalf("unexpected runs %v", filters)
+	}
+	if len(results) != 3 || len(results[-20].Intervals) != 1 || len(results[-30].Intervals) != 0 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[-40].ThresholdDB != -40 {
+		t.Fatalf("expected each result to record its threshold, got %f", results[-40].ThresholdDB)
+	}
+}
+
+func TestDetectSilenceSweepValidatesBeforeRunning(t *testing.T) {
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		t.Fatal("ffmpeg should not run")
+		return nil, nil
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	tests := []struct {
+		name  string
+		input string
+		sweep []DetectionOptions
+	}{
+		{name: "empty", input: "video.mp4"},
+		{name: "duplicate threshold", input: "video.mp4", sweep: []DetectionOptions{{NoiseLevel: -30, MinSilenceDuration: 1}, {NoiseLevel: -30, MinSilenceDuration: 1}}},
+		{name: "invalid entry", input: "video.mp4", sweep: []DetectionOptions{{NoiseLevel: -30, MinSilenceDuration: 1}, {NoiseLevel: -40}}},
+		{name: "adaptive", input: "video.mp4", sweep: []DetectionOptions{{MinSilenceDuration: 1, AdaptiveThreshold: true}}},
+		{name: "stdin", input: "-", sweep: []DetectionOptions{{MinSilenceDuration: 1, Stdin: strings.NewReader("")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.DetectSilenceSweep(context.Background(), tt.input, tt.sweep); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDetectSilenceSweepStopsAtFirstFailure(t *testing.T) {
+	runs := 0
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		runs++
+		return []byte("Output file #0 does not contain any stream"), &exec.ExitError{}
+	}
+	d := NewDetector(WithCommandRunner(runner))
+
+	_, err := d.DetectSilenceSweep(context.Background(), "video.mp4", []DetectionOptions{
+		{NoiseLevel: -20, MinSilenceDuration: 1},
+		{NoiseLevel: -30, MinSilenceDuration: 1},
+	})
+	if !errors.Is(err, ErrNoAudioStream) || runs != 1 {
+		t.Fatalf("expected ErrNoAudioStream after one run, got %v after %d run(s)", err, runs)
+	}
+	if !strings.HasPrefix(err.Error(), "threshold -20dB: ") {
+		t.Fatalf("expected the threshold in the error, got %q", err)
+	}
+}
@@ -0,0 +1,106 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+)
+
+// FormatClock formats a time or duration in seconds as HH:MM:SS.mmm, rounded to the nearest millisecond. Hours are
+// not wrapped at 24, and negative values are prefixed with a minus sign.
+func FormatClock(seconds float64) string {
+	sign, millis := splitSign(math.Round(seconds * 1000))
+	return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, millis/3_600_000, millis/60_000%60, millis/1000%60, millis%1000)
+}
+
+// FormatSMPTE formats a time or duration in seconds as an HH:MM:SS:FF timecode at fps frames per second, counting
+// to the nearest frame. The timecode is non-drop-frame: fractional rates such as 29.97 number their frames at the
+// nominal whole rate (30), so the timecode runs slightly slower than the clock. Hours are not wrapped at 24, and
+// negative values are prefixed with a minus sign. FormatSMPTE returns an error if fps is not positive and finite.
+func FormatSMPTE(seconds, fps float64) (string, error) {
+	if err := checkTimecodeRate(fps, false); err != nil {
+		return "", err
+	}
+	sign, frames := splitSign(math.Round(seconds * fps))
+	return sign + formatTimecode(frames, fps, false), nil
+}
+
+// validFrameRate reports whether fps is positive and finite.
+func validFrameRate(fps float64) bool {
+	return fps > 0 && !math.IsInf(fps, 0)
+}
+
+// checkTimecodeRate returns an error if fps is not positive and finite, or if dropFrame is set at a rate drop-frame
+// timecode does not apply to.
+func checkTimecodeRate(fps float64, dropFrame bool) error {
+	if !validFrameRate(fps) {
+		return fmt.Errorf("invalid frame rate %v", fps)
+	}
+	if dropFrame && !isDropFrameRate(fps) {
+		return fmt.Errorf("drop-frame timecode requires a frame rate of 29.97 or 59.94, not %v", fps)
+	}
+	return nil
+}
+
+// timecodeBase is the number of frames per timecode second at fps: the rate rounded to a whole number.
+func timecodeBase(fps float64) int64 {
+	return max(int64(math.Round(fps)), 1)
+}
+
+// isDropFrameRate reports whether fps is an NTSC rate, 29.97 or 59.94, that drop-frame timecode applies to.
+func isDropFrameRate(fps float64) bool {
+	base := timecodeBase(fps)
+	return (base == 30 || base == 60) && math.Abs(fps-float64(base)*1000/1001) < 0.005
+}
+
+// formatTimecode formats a non-negative frame count as HH:MM:SS:FF. With dropFrame, which requires an NTSC rate, the
+// timecode skips the first frame numbers of every minute but each tenth, 2 at 29.97 and 4 at 59.94, so that it keeps
+// up with the clock, and the frames are separated by a semicolon as in HH:MM:SS;FF.
+func formatTimecode(frames int64, fps float64, dropFrame bool) string {
+	base := timecodeBase(fps)
+	separator := ":"
+	if dropFrame {
+		dropped := base / 15
+		perMinute := 60*base - dropped
+		perTenMinutes := 10*perMinute + dropped
+		tens, rest := frames/perTenMinutes, frames%perTenMinutes
+		frames += 9 * dropped * tens
+		if rest > dropped {
+			frames += dropped * ((rest - dropped) / perMinute)
+		}
+		separator = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", frames/(3600*base), frames/(60*base)%60, frames/base%60, separator, frames%base)
+}
+
+// parseTimecode returns the frame count of an HH:MM:SS:FF timecode at fps, or HH:MM:SS;FF with dropFrame, which
+// requires an NTSC rate. Either separator is accepted before the frames.
+func parseTimecode(timecode string, fps float64, dropFrame bool) (int64, error) {
+	var hours, minutes, seconds, frames int64
+	var separator rune
+	if _, err := fmt.Sscanf(timecode, "%d:%d:%d%c%d", &hours, &minutes, &seconds, &separator, &frames); err != nil || (separator != ':' && separator != ';') {
+		return 0, fmt.Errorf("invalid timecode %q: use HH:MM:SS:FF", timecode)
+	}
+	base := timecodeBase(fps)
+	if hours < 0 || minutes < 0 || minutes > 59 || seconds < 0 || seconds > 59 || frames < 0 || frames >= base {
+		return 0, fmt.Errorf("invalid timecode %q at %v fps", timecode, fps)
+	}
+
+	total := (hours*3600+minutes*60+seconds)*base + frames
+	if dropFrame {
+		dropped := base / 15
+		if seconds == 0 && frames < dropped && minutes%10 != 0 {
+			return 0, fmt.Errorf("drop-frame timecode %q does not exist", timecode)
+		}
+		elapsed := 60*hours + minutes
+		total -= dropped * (elapsed - elapsed/10)
+	}
+	return total, nil
+}
+
+// splitSign returns the sign prefix and the magnitude of a whole number of units.
+func splitSign(units float64) (string, int64) {
+	if units < 0 {
+		return "-", int64(-units)
+	}
+	return "", int64(units)
+}
@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{seconds: 0, want: "00:00:00.000"},
+		{seconds: 83.456, want: "00:01:23.456"},
+		{seconds: 3.5000000000000004, want: "00:00:03.500"},
+		{seconds: 59.9996, want: "00:01:00.000"},
+		{seconds: 3599.9994, want: "00:59:59.999"},
+		{seconds: 3723.5, want: "01:02:03.500"},
+		{seconds: 90000, want: "25:00:00.000"},
+		{seconds: -1.25, want: "-00:00:01.250"},
+	}
+	for _, tt := range tests {
+		if got := FormatClock(tt.seconds); got != tt.want {
+			t.Errorf("FormatClock(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSMPTE(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		fps     float64
+		want    string
+	}{
+		{seconds: 0, fps: 25, want: "00:00:00:00"},
+		{seconds: 0.12, fps: 25, want: "00:00:00:03"},
+		// 24.475 frames round down and 24.5 frames round up to the next second.
+		{seconds: 0.979, fps: 25, want: "00:00:00:24"},
+		{seconds: 0.98, fps: 25, want: "00:00:01:00"},
+		{seconds: 59.99, fps: 25, want: "00:01:00:00"},
+		{seconds: 3723.5, fps: 24, want: "01:02:03:12"},
+		{seconds: 3600, fps: 50, want: "01:00:00:00"},
+		// At 29.97 fps an hour of clock time is 107892 frames, numbered at 30 frames per timecode second.
+		{seconds: 3600, fps: 29.97, want: "00:59:56:12"},
+		{seconds: -0.5, fps: 30, want: "-00:00:00:15"},
+	}
+	for _, tt := range tests {
+		if got, err := FormatSMPTE(tt.seconds, tt.fps); err != nil || got != tt.want {
+			t.Errorf("FormatSMPTE(%v, %v) = %q, %v, want %q", tt.seconds, tt.fps, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatSMPTERejectsInvalidFrameRate(t *testing.T) {
+	for _, fps := range []float64{0, -25, math.Inf(1), math.NaN()} {
+		if _, err := FormatSMPTE(1, fps); err == nil {
+			t.Errorf("FormatSMPTE(1, %v): expected an error", fps)
+		}
+	}
+}
+
+func TestFormatTimecodeDropFrame(t *testing.T) {
+	tests := []struct {
+		frames int64
+		fps    float64
+		want   string
+	}{
+		{frames: 1799, fps: 29.97, want: "00:00:59;29"},
+		// Frames 00 and 01 of every minute but each tenth are skipped.
+		{frames: 1800, fps: 29.97, want: "00:01:00;02"},
+		{frames: 3598, fps: 29.97, want: "00:02:00;02"},
+		{frames: 17981, fps: 29.97, want: "00:09:59;29"},
+		{frames: 17982, fps: 29.97, want: "00:10:00;00"},
+		{frames: 107892, fps: 29.97, want: "01:00:00;00"},
+		{frames: 3600, fps: 59.94, want: "00:01:00;04"},
+		{frames: 215784, fps: 59.94, want: "01:00:00;00"},
+	}
+	for _, tt := range tests {
+		if got := formatTimecode(tt.frames, tt.fps, true); got != tt.want {
+			t.Errorf("formatTimecode(%d, %v, true) = %q, want %q", tt.frames, tt.fps, got, tt.want)
+		}
+	}
+
+	if !isDropFrameRate(29.97) || !isDropFrameRate(30000.0/1001) || !isDropFrameRate(59.94) || isDropFrameRate(30) || isDropFrameRate(23.976) {
+		t.Error("unexpected drop-frame rates")
+	}
+}
+
+func TestParseTimecode(t *testing.T) {
+	tests := []struct {
+		timecode  string
+		fps       float64
+		dropFrame bool
+		want      int64
+	}{
+		{timecode: "00:00:00:00", fps: 25, want: 0},
+		{timecode: "01:00:00:00", fps: 25, want: 90000},
+		{timecode: "01:00:00:00", fps: 29.97, want: 108000},
+		{timecode: "01:00:00;00", fps: 29.97, dropFrame: true, want: 107892},
+		{timecode: "00:01:00;02", fps: 29.97, dropFrame: true, want: 1800},
+		{timecode: "00:10:00;00", fps: 29.97, dropFrame: true, want: 17982},
+	}
+	for _, tt := range tests {
+		got, err := parseTimecode(tt.timecode, tt.fps, tt.dropFrame)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimecode(%q, %v, %v) = %d, %v; want %d", tt.timecode, tt.fps, tt.dropFrame, got, err, tt.want)
+			continue
+		}
+		if back := formatTimecode(got, tt.fps, tt.dropFrame); back != tt.timecode {
+			t.Errorf("formatTimecode(%d, %v, %v) = %q, want %q", got, tt.fps, tt.dropFrame, back, tt.timecode)
+		}
+	}
+
+	for _, invalid := range []string{"1:00:00", "01:00:00:25", "01:60:00:00", "01:00:00.00", "a"} {
+		if _, err := parseTimecode(invalid, 25, false); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+	if _, err := parseTimecode("00:01:00;00", 29.97, true); err == nil {
+		t.Error("expected a timecode that drop-frame skips to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultTimelineWidth is the width, in characters, of a timeline rendered with a width of zero.
+const DefaultTimelineWidth = 80
+
+// TimelineStyle selects the characters of a rendered timeline.
+type TimelineStyle string
+
+const (
+	// TimelineASCII draws silence as "." and sound as "#". It is the default.
+	TimelineASCII TimelineStyle = "ascii"
+	// TimelineBlocks draws silence as a light shade and sound as a full block, which needs a Unicode terminal.
+	TimelineBlocks TimelineStyle = "blocks"
+)
+
+// timelineSteps are the spacings, in minutes, considered for the ticks of a timeline's scale.
+var timelineSteps = []int{1, 2, 5, 10, 15, 30, 60, 120, 240, 480, 720, 1440}
+
+// TimelineOptions sets the width and style of RenderTimelineWith.
+type TimelineOptions struct {
+	// Width is the number of characters of the bar; zero selects DefaultTimelineWidth.
+	Width int
+	// Style selects the characters of the bar; empty selects TimelineASCII.
+	Style TimelineStyle
+}
+
+// RenderTimeline draws where the silence of result falls as an ASCII bar width characters wide, "." for silence and
+// "#" for sound, with a scale line underneath that marks the minutes. See RenderTimelineWith.
+func RenderTimeline(result DetectionResult, width int) string {
+	return RenderTimelineWith(result, TimelineOptions{Width: width})
+}
+
+// RenderTimelineWith draws where the silence of result falls as a bar of opts.Width characters over the analysed
+// span, [WindowStart, WindowStart+InputDuration], followed by a scale line with a tick, labelled in minutes from
+// the start of the input, at every minute, or at wider steps when minute ticks would crowd the scale. Each character
+// covers an equal share of the span and shows silence when silence covers at least half of it.
+//
+// When InputDuration is unknown, the bar ends at the end of the last silence interval and a third line says so.
+// Without a duration or any silence there is nothing to scale, and the result is empty; so it is for an infinite
+// span, which cannot be divided into columns. Every line ends with a
+// newline. A negative width or an unknown style is treated as the default.
+func RenderTimelineWith(result DetectionResult, opts TimelineOptions) string {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultTimelineWidth
+	}
+	silent, sound := '.', '#'
+	if opts.Style == TimelineBlocks {
+		silent, sound = '░', '█'
+	}
+
+	start := result.WindowStart
+	end := start + result.InputDuration
+	durationKnown := result.InputDuration > 0
+	if !durationKnown {
+		for _, interval := range result.Intervals {
+			end = max(end, interval.End)
+		}
+	}
+	span := end - start
+	if !(span > 0) || math.IsInf(span, 0) {
+		return ""
+	}
+	cell := span / float64(width)
+
+	var b strings.Builder
+	silence := DetectionResult{Intervals: unionIntervals(result.Intervals)}
+	for i := range width {
+		from := start + float64(i)*cell
+		if silence.silenceWithin(from, from+cell) >= cell/2 {
+			b.WriteRune(silent)
+		} else {
+			b.WriteRune(sound)
+		}
+	}
+	b.WriteByte('\n')
+	b.WriteString(timelineScale(start, end, width))
+	b.WriteByte('\n')
+	if !durationKnown {
+		fmt.Fprintf(&b, "Input duration unknown: the timeline ends with the last silence, at %.3fs.\n", end)
+	}
+	return b.String()
+}
+
+// timelineScale returns the scale line of a timeline of width characters over [start, end]: a "|" tick at each
+// step of whole minutes, followed by its label, such as "|5m", unless the label runs past the end of the line.
+func timelineScale(start, end float64, width int) string {
+	columnsPerMinute := float64(width) * 60 / (end - start)
+	label := func(minute int) string { return fmt.Sprintf("%dm", minute) }
+
+	step := timelineSteps[len(timelineSteps)-1]
+	for _, candidate := range timelineSteps {
+		// Leave room for the widest label and a space after it.
+		if float64(candidate)*columnsPerMinute >= float64(len(label(int(end/60)))+2) {
+			step = candidate
+			break
+		}
+	}
+
+	line := []rune(strings.Repeat(" ", width))
+	first := int(math.Ceil(start/60/float64(step))) * step
+	// No more than width ticks fit on the line. Counting them also ends the loop when the span is so long that even
+	// the widest step rounds to less than a column, in which case the ticks that would overwrite another are left out.
+	last := -1
+	for tick := range width {
+		minute := first + tick*step
+		if float64(minute)*60 > end {
+			break
+		}
+		column := int(math.Round((float64(minute)*60 - start) * columnsPerMinute / 60))
+		if column >= width {
+			break
+		}
+		if column <= last {
+			continue
+		}
+		last = column
+		line[column] = '|'
+		if text := label(minute); column+len(text) < width {
+			copy(line[column+1:], []rune(text))
+		}
+	}
+	return strings.TrimRight(string(line), " ")
+}
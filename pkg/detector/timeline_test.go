@@ -0,0 +1,61 @@
+package detector
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRenderTimeline(t *testing.T) {
+	result := DetectionResult{
+		InputDuration: 180,
+		Intervals:     []SilenceInterval{{Start: 0, End: 18, Duration: 18}, {Start: 90, End: 108, Duration: 18}},
+	}
+
+	want := "" +
+		"..#######..#######\n" +
+		"|0m   |1m   |2m\n"
+	if got := RenderTimeline(result, 18); got != want {
+		t.Fatalf("unexpected timeline:\n%s\nwant:\n%s", got, want)
+	}
+
+	blocks := RenderTimelineWith(result, TimelineOptions{Width: 18, Style: TimelineBlocks})
+	if !strings.HasPrefix(blocks, "░░███████░░███████\n") {
+		t.Fatalf("unexpected block timeline:\n%s", blocks)
+	}
+	if lines := strings.Split(RenderTimeline(result, 0), "\n"); len([]rune(lines[0])) != DefaultTimelineWidth {
+		t.Fatalf("expected the default width, got %q", lines[0])
+	}
+}
+
+func TestRenderTimelineWidensScaleSteps(t *testing.T) {
+	result := DetectionResult{InputDuration: 3600}
+	scale := strings.Split(RenderTimeline(result, 40), "\n")[1]
+	if want := "|0m    |10m  |20m   |30m   |40m  |50m"; scale != want {
+		t.Fatalf("unexpected scale %q, want %q", scale, want)
+	}
+}
+
+func TestRenderTimelineWithoutDuration(t *testing.T) {
+	result := DetectionResult{Intervals: []SilenceInterval{{Start: 5, End: 10, Duration: 5}}}
+	got := RenderTimeline(result, 10)
+	if !strings.HasPrefix(got, "#####.....\n") || !strings.Contains(got, "Input duration unknown") {
+		t.Fatalf("unexpected timeline:\n%s", got)
+	}
+	if got := RenderTimeline(DetectionResult{}, 10); got != "" {
+		t.Fatalf("expected nothing to render, got %q", got)
+	}
+}
+
+func TestRenderTimelineWithInfiniteDuration(t *testing.T) {
+	result := DetectionResult{InputDuration: math.Inf(1), Intervals: []SilenceInterval{{Start: 5, End: 10, Duration: 5}}}
+	if got := RenderTimeline(result, 10); got != "" {
+		t.Fatalf("expected nothing to render, got %q", got)
+	}
+
+	// A span too long for the widest step to reach a column must still end the scale.
+	result.InputDuration = 1e15
+	if got := RenderTimeline(result, 10); !strings.HasPrefix(got, "##########\n|0m") {
+		t.Fatalf("unexpected timeline:\n%s", got)
+	}
+}
@@ -0,0 +1,151 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// TracerProvider creates the Tracers that a Detector and FetchRemoteInput record spans with. Together with Tracer
+// and Span, it mirrors the part of OpenTelemetry's tracing API the library needs. The library therefore does not
+// depend on OpenTelemetry; an adapter over an OpenTelemetry TracerProvider only has to convert attributes.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// Tracer starts spans. The returned context carries the new span, so that spans started from it become its
+// children.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttributes records attributes. Their values are strings, booleans, 64-bit integers or floats.
+	SetAttributes(attrs ...slog.Attr)
+	// RecordError records err as an exception and marks the span as failed. URLs in the message are redacted.
+	RecordError(err error)
+	// End completes the span.
+	End()
+}
+
+// tracerName is the instrumentation scope of the library's spans.
+const tracerName = "github.com/wistia/silence-detector/pkg/detector"
+
+// Span names.
+const (
+	spanDetect   = "silence_detector.detect"
+	spanDownload = "silence_detector.download"
+	spanFFmpeg   = "ffmpeg"
+)
+
+// WithTracerProvider makes the detector record a span for every DetectSilence call that passes validation, with
+// the input (redacted), the main options, the number of intervals found and the input duration. Every ffmpeg
+// process the detector runs gets a child span with its arguments (redacted) and exit code.
+func WithTracerProvider(provider TracerProvider) Option {
+	return func(d *Detector) {
+		d.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// startSpan starts a span with tracer, or a span that records nothing when tracer is nil.
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+// providerTracer returns the library's tracer from provider, or nil when tracing is disabled.
+func providerTracer(provider TracerProvider) Tracer {
+	if provider == nil {
+		return nil
+	}
+	return provider.Tracer(tracerName)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// recordSpanError records err on span with secrets in URLs redacted.
+func recordSpanError(span Span, err error) {
+	span.RecordError(redactedError{err: err})
+}
+
+// redactedError presents an error with secrets redacted as by RedactText, while still matching its sentinels.
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return RedactText(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// detectionAttrs describes the input and main options of a detection.
+func detectionAttrs(inputPath string, options DetectionOptions) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("silence_detector.input", RedactText(inputPath)),
+		slog.Float64("silence_detector.noise_db", options.thresholdDB()),
+		slog.Float64("silence_detector.min_duration", options.MinSilenceDuration),
+	}
+	if options.HysteresisDB > 0 {
+		attrs = append(attrs, slog.Float64("silence_detector.hysteresis_db", options.HysteresisDB))
+	}
+	if options.StartOffset > 0 {
+		attrs = append(attrs, slog.Float64("silence_detector.start_offset", options.StartOffset))
+	}
+	if options.AnalyzeDuration > 0 {
+		attrs = append(attrs, slog.Float64("silence_detector.analyze_duration", options.AnalyzeDuration))
+	}
+	if options.AudioStreamIndex != nil {
+		attrs = append(attrs, slog.Int("silence_detector.audio_stream", *options.AudioStreamIndex))
+	}
+	if options.AudioLanguage != "" {
+		attrs = append(attrs, slog.String("silence_detector.audio_language", options.AudioLanguage))
+	}
+	if options.PerChannel {
+		attrs = append(attrs, slog.Bool("silence_detector.per_channel", true))
+	}
+	if options.AdaptiveThreshold {
+		attrs = append(attrs, slog.Bool("silence_detector.adaptive", true))
+	}
+	if options.Live {
+		attrs = append(attrs, slog.Bool("silence_detector.live", true))
+	}
+	return attrs
+}
+
+// endDetectSpan records the outcome of a detection on its span and ends it.
+func endDetectSpan(span Span, result DetectionResult, err error) {
+	defer span.End()
+
+	if err != nil {
+		var ffErr *FFmpegError
+		if errors.As(err, &ffErr) {
+			span.SetAttributes(slog.Int("ffmpeg.exit_code", ffErr.ExitCode))
+		}
+		recordSpanError(span, err)
+		return
+	}
+	span.SetAttributes(
+		slog.Int("silence_detector.interval_count", len(result.Intervals)),
+		slog.Float64("silence_detector.input_duration", result.InputDuration),
+		slog.Float64("silence_detector.threshold_db", result.ThresholdDB),
+	)
+	if result.Truncated {
+		span.SetAttributes(slog.Bool("silence_detector.truncated", true))
+	}
+}
+
+// ffmpegArgsAttr describes the arguments of an ffmpeg process, redacted.
+func ffmpegArgsAttr(args []string) slog.Attr {
+	return slog.String("ffmpeg.args", strings.Join(RedactArgs(args), " "))
+}
@@ -0,0 +1,129 @@
+package detector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAV format codes of the fmt chunk.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavUnknownSize is the data chunk size written by encoders that stream a WAV file without seeking back to fill it
+// in; the data then runs to the end of the file.
+const wavUnknownSize = math.MaxUint32
+
+// wavFormat describes the samples of a WAV file the native engine can read.
+type wavFormat struct {
+	// float reports IEEE float samples; otherwise they are integer PCM, unsigned for 8 bits and signed above.
+	float      bool
+	channels   int
+	sampleRate int
+	// bits is the size of a sample: 8, 16, 24 or 32 for PCM, 32 or 64 for float.
+	bits int
+	// dataSize is the length of the sample data in bytes, or -1 when it runs to the end of the file.
+	dataSize int64
+}
+
+// frameSize returns the size in bytes of one sample of every channel.
+func (f wavFormat) frameSize() int {
+	return f.channels * f.bits / 8
+}
+
+// codec returns the name ffmpeg gives the sample encoding, such as "pcm_s16le".
+func (f wavFormat) codec() string {
+	switch {
+	case f.float:
+		return fmt.Sprintf("pcm_f%dle", f.bits)
+	case f.bits == 8:
+		return "pcm_u8"
+	default:
+		return fmt.Sprintf("pcm_s%dle", f.bits)
+	}
+}
+
+// readWAVHeader reads the RIFF/WAVE header of r up to the start of the sample data, skipping chunks other than fmt
+// and data. Files that are not WAV, or hold samples other than 8 to 32-bit PCM and 32 or 64-bit float, fail with
+// ErrNativeUnsupported.
+func readWAVHeader(r io.Reader) (wavFormat, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return wavFormat{}, fmt.Errorf("%w: no RIFF header", ErrNativeUnsupported)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return wavFormat{}, fmt.Errorf("%w: not a RIFF/WAVE file", ErrNativeUnsupported)
+	}
+
+	var format *wavFormat
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return wavFormat{}, fmt.Errorf("%w: no data chunk", ErrNativeUnsupported)
+		}
+		id, size := string(header[0:4]), int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return wavFormat{}, fmt.Errorf("%w: invalid fmt chunk size %d", ErrNativeUnsupported, size)
+			}
+			chunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return wavFormat{}, fmt.Errorf("%w: truncated fmt chunk", ErrNativeUnsupported)
+			}
+			parsed, err := parseWAVFormat(chunk[:size])
+			if err != nil {
+				return wavFormat{}, err
+			}
+			format = &parsed
+		case "data":
+			if format == nil {
+				return wavFormat{}, fmt.Errorf("%w: data chunk before fmt chunk", ErrNativeUnsupported)
+			}
+			format.dataSize = size
+			if size == wavUnknownSize {
+				format.dataSize = -1
+			}
+			return *format, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return wavFormat{}, fmt.Errorf("%w: truncated %q chunk", ErrNativeUnsupported, id)
+			}
+		}
+	}
+}
+
+// parseWAVFormat interprets a fmt chunk.
+func parseWAVFormat(chunk []byte) (wavFormat, error) {
+	code := binary.LittleEndian.Uint16(chunk[0:2])
+	if code == wavFormatExtensible && len(chunk) >= 26 {
+		// The first two bytes of the SubFormat GUID hold the format code.
+		code = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+	format := wavFormat{
+		float:      code == wavFormatFloat,
+		channels:   int(binary.LittleEndian.Uint16(chunk[2:4])),
+		sampleRate: int(binary.LittleEndian.Uint32(chunk[4:8])),
+		bits:       int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+	blockAlign := int(binary.LittleEndian.Uint16(chunk[12:14]))
+
+	switch {
+	case code != wavFormatPCM && code != wavFormatFloat:
+		return wavFormat{}, fmt.Errorf("%w: WAV format code %#x", ErrNativeUnsupported, code)
+	case format.float && format.bits != 32 && format.bits != 64:
+		return wavFormat{}, fmt.Errorf("%w: %d-bit float samples", ErrNativeUnsupported, format.bits)
+	case !format.float && format.bits != 8 && format.bits != 16 && format.bits != 24 && format.bits != 32:
+		return wavFormat{}, fmt.Errorf("%w: %d-bit PCM samples", ErrNativeUnsupported, format.bits)
+	case format.channels < 1 || format.sampleRate < 1:
+		return wavFormat{}, fmt.Errorf("%w: %d channel(s) at %d Hz", ErrNativeUnsupported, format.channels, format.sampleRate)
+	case blockAlign != format.frameSize():
+		return wavFormat{}, fmt.Errorf("%w: block size %d for %d %d-bit channel(s)", ErrNativeUnsupported, blockAlign, format.channels, format.bits)
+	}
+	return format, nil
+}